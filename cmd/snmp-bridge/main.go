@@ -2,26 +2,31 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"snmp-mqtt-bridge/internal/api"
+	"snmp-mqtt-bridge/internal/buildinfo"
 	"snmp-mqtt-bridge/internal/config"
-	"snmp-mqtt-bridge/internal/domain"
 	embedfs "snmp-mqtt-bridge/internal/embed"
+	"snmp-mqtt-bridge/internal/eventbus"
+	"snmp-mqtt-bridge/internal/lifecycle"
 	"snmp-mqtt-bridge/internal/mqtt"
-	"snmp-mqtt-bridge/internal/repository/sqlite"
+	"snmp-mqtt-bridge/internal/repository/factory"
 	"snmp-mqtt-bridge/internal/service"
+	"snmp-mqtt-bridge/internal/timefmt"
+	"snmp-mqtt-bridge/internal/units"
 	"snmp-mqtt-bridge/internal/worker"
 )
 
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	log.Println("Starting SNMP-MQTT Bridge...")
+	build := buildinfo.Get()
+	log.Printf("Starting SNMP-MQTT Bridge %s (commit %s, built %s)...", build.Version, build.Commit, build.BuildDate)
 
 	// Load configuration
 	cfg, err := config.Load()
@@ -29,23 +34,61 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Initialize database
-	db, err := sqlite.NewDB(&cfg.Database)
+	// Apply timestamp settings before anything is serialized
+	if err := timefmt.Configure(cfg.Time.Timezone, cfg.Time.Format); err != nil {
+		log.Fatalf("Invalid time configuration: %v", err)
+	}
+
+	// Apply the temperature unit before any value is converted
+	if err := units.Configure(cfg.Units.Temperature); err != nil {
+		log.Fatalf("Invalid units configuration: %v", err)
+	}
+
+	// Limit SNMP sockets and apply the local bind address used by devices
+	// without their own
+	if err := service.ConfigureSNMPConnections(service.SNMPConnOptions{
+		MaxOpen:     cfg.SNMP.MaxConnections,
+		IdleTimeout: cfg.SNMP.IdleTimeout,
+		BindAddress: cfg.SNMP.BindAddress,
+	}); err != nil {
+		log.Fatalf("Invalid SNMP bind address: %v", err)
+	}
+
+	// Create repositories for the configured database driver
+	repos, err := factory.New(&cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
+	if cfg.Database.Driver == factory.DriverMemory {
+		log.Println("Warning: using in-memory storage, data will be lost on restart")
+	}
+
+	// Create event bus shared by all subsystems
+	bus := eventbus.NewBus()
 
-	// Create repositories
-	deviceRepo := sqlite.NewDeviceRepository(db)
-	profileRepo := sqlite.NewProfileRepository(db)
-	trapRepo := sqlite.NewTrapLogRepository(db)
-	settingRepo := sqlite.NewSettingRepository(db)
+	// Profiles are read per device by most subsystems; cache them
+	profileCache := service.NewProfileCache(repos.Profile, bus)
+
+	deviceRepo := repos.Device
+	profileRepo := profileCache
+	trapRepo := repos.TrapLog
+	settingRepo := repos.Setting
+	eventRepo := repos.Event
+	sceneRepo := repos.Scene
+	credentialRepo := repos.Credential
 
 	// Create services
-	deviceService := service.NewDeviceService(deviceRepo)
-	profileService := service.NewProfileService(profileRepo)
+	deviceService := service.NewDeviceService(deviceRepo, credentialRepo, bus)
+	deviceService.SetSite(cfg.Site)
+	credentialService := service.NewCredentialService(credentialRepo, deviceService)
+	deviceImporter := service.NewDeviceImporter(deviceService, profileRepo)
+	profileService := service.NewProfileService(profileRepo, deviceService, bus)
 	trapLogService := service.NewTrapLogService(trapRepo)
 	settingService := service.NewSettingService(settingRepo)
+	eventService := service.NewEventService(eventRepo, deviceRepo, profileRepo, bus)
+	notificationService := service.NewNotificationService(settingService, deviceService, bus)
+	statisticsAudit := service.NewStatisticsAuditService(deviceRepo, profileRepo)
+	statisticsAudit.SetAutoFix(cfg.MQTT.FixStateClasses)
 
 	// Load built-in profiles
 	if err := profileService.LoadBuiltinProfiles(context.Background(), "profiles"); err != nil {
@@ -53,80 +96,434 @@ func main() {
 	}
 
 	// Create poller service
-	pollerService := service.NewPollerService(deviceRepo, profileRepo, cfg.SNMP.PollInterval)
+	pollerService := service.NewPollerService(deviceRepo, profileRepo, bus, cfg.SNMP.PollInterval)
+	pollerService.SetFastPolling(cfg.SNMP.FastPollInterval, cfg.SNMP.FastPollDuration)
+	pollerService.SetOIDBudget(cfg.SNMP.MaxOIDsPerPoll)
+	pollerService.SetStaleAfter(cfg.SNMP.StaleAfterPolls)
+	pollerService.SetReconcileInterval(cfg.SNMP.ReconcileInterval)
+	pollerService.SetStartupWarmup(cfg.SNMP.StartupWarmup)
+
+	// Restore each device's last trap time for its diagnostic entity
+	if lastTraps, err := trapRepo.LastReceived(context.Background()); err == nil {
+		for deviceID, at := range lastTraps {
+			pollerService.RecordTrap(deviceID, at)
+		}
+	} else {
+		log.Printf("Warning: Failed to load last trap times: %v", err)
+	}
 
 	// Create SNMP service for commands
-	snmpService := service.NewSNMPService(deviceRepo, profileRepo)
+	snmpService := service.NewSNMPService(deviceRepo, profileRepo, bus)
+	writeLock := service.NewWriteLockService(settingService, bus)
+	localeService := service.NewLocaleService(settingService, profileRepo, bus)
+	unitService := service.NewUnitService(settingService, bus, cfg.Units.Temperature)
+	if err := unitService.Load(context.Background()); err != nil {
+		log.Printf("Warning: %v, keeping units.temperature", err)
+	}
+
+	// Create per-device queue every SET goes through
+	commandQueue := service.NewCommandQueue(snmpService, pollerService)
+	commandQueue.SetCooldown(service.NewCommandCooldown(cfg.SNMP.CommandCooldown))
+	commandQueue.SetWriteLock(writeLock)
+
+	// Create UPS battery self-test scheduler
+	selfTestService := service.NewSelfTestService(deviceRepo, profileRepo, snmpService, commandQueue, eventService, pollerService)
+
+	// Create hardware inventory report
+	inventoryService := service.NewInventoryService(deviceService, profileRepo, pollerService)
+
+	// Create configuration drift tracking
+	configDrift := service.NewConfigDriftService(repos.ConfigSnapshot, deviceRepo, profileRepo, pollerService, eventService, bus, cfg.SNMP.ConfigSnapshotInterval)
+
+	// Create service for multi-device scenes
+	sceneService := service.NewSceneService(sceneRepo, deviceRepo, profileRepo, commandQueue, bus)
+
+	// Create service for profile-defined actions
+	actionService := service.NewActionService(deviceRepo, profileRepo, commandQueue, pollerService)
+
+	// Create database maintenance for drivers with storage to compact
+	var maintenanceService *service.MaintenanceService
+	if repos.Maintenance != nil {
+		maintenanceService = service.NewMaintenanceService(repos.Maintenance, cfg.Database.VacuumInterval)
+	}
+
+	// Create optional audit trail of API changes and MQTT commands
+	var auditService *service.AuditService
+	if cfg.Server.Audit.Enabled {
+		auditService = service.NewAuditService(repos.Audit, bus)
+	}
+
+	// Create optional release checker
+	var updateChecker *service.UpdateChecker
+	if cfg.Update.CheckEnabled && cfg.Update.FeedURL != "" {
+		updateChecker = service.NewUpdateChecker(cfg.Update.FeedURL, cfg.Update.CheckInterval, bus)
+	}
 
 	// Create MQTT client
 	mqttClient := mqtt.NewClient(&cfg.MQTT)
-	if err := mqttClient.Connect(); err != nil {
-		log.Printf("Warning: Failed to connect to MQTT broker: %v", err)
-	}
+	mqttClient.SetEventBus(bus)
 
 	// Create MQTT discovery and publisher
-	discovery := mqtt.NewDiscovery(mqttClient, cfg.MQTT.DiscoveryPrefix, cfg.MQTT.TopicPrefix)
-	publisher := mqtt.NewPublisher(mqttClient, discovery, pollerService, profileRepo)
+	discovery := mqtt.NewDiscovery(mqttClient, cfg.MQTT.DiscoveryPrefix, mqttClient.TopicPrefix())
+	discovery.SetInstanceID(cfg.MQTT.InstanceID)
+	discovery.SetSuggestedArea(cfg.MQTT.SuggestedArea)
+	discovery.SetDeviceAvailability(cfg.MQTT.DeviceAvailability)
+	discovery.SetFixStateClasses(cfg.MQTT.FixStateClasses)
+	discovery.SetLocale(localeService.Locale(context.Background()))
+	publisher := mqtt.NewPublisher(mqttClient, discovery, pollerService, commandQueue, profileRepo, bus)
+	publisher.SetDeviceAvailability(cfg.MQTT.DeviceAvailability)
+	publisher.SetClearStatesOnShutdown(cfg.MQTT.ClearStatesOnShutdown)
+	publisher.SetStateExpiry(cfg.MQTT.StateExpiry)
+	publisher.SetConfirmTimeout(cfg.MQTT.ConfirmTimeout)
+	publisher.SetDiscoveryCheckInterval(cfg.MQTT.DiscoveryCheckInterval)
+	publisher.SetStatsInterval(cfg.MQTT.StatsInterval)
+	publisher.SetVersion(build.Version)
+	publisher.SetWriteLock(writeLock)
+	publisher.SetMaintenance(maintenanceService)
+	publisher.SetAdapters(mqtt.NewAdapters(mqttClient, &cfg.MQTT))
+	scenePublisher := mqtt.NewScenePublisher(mqttClient, discovery, sceneService, bus)
 
 	// Create trap receiver
-	trapReceiver := worker.NewTrapReceiver(cfg.SNMP.TrapPort, deviceRepo, trapRepo, pollerService)
+	trapReceiver := worker.NewTrapReceiver(cfg.SNMP.TrapPort, cfg.SNMP.BindAddress, deviceRepo, trapRepo, pollerService, bus)
+
+	// Create optional SNMP agent
+	var snmpAgent *worker.SNMPAgent
+	if cfg.SNMP.Agent.Enabled {
+		snmpAgent = worker.NewSNMPAgent(cfg.SNMP.Agent, cfg.SNMP.BindAddress, deviceRepo, pollerService, mqttClient, bus)
+	}
+
+	// Create optional in-memory history of polled values for Grafana
+	var historyService *service.StateHistoryService
+	if cfg.History.Retention > 0 {
+		historyService = service.NewStateHistoryService(bus, cfg.History.Retention)
+	}
+
+	// Create optional mDNS advertisement of the web UI and API
+	var mdnsResponder *worker.MDNSResponder
+	if cfg.Server.MDNS.Enabled {
+		mdnsResponder = worker.NewMDNSResponder(cfg.Server.MDNS.InstanceName, cfg.Server.MDNS.Hostname, cfg.Server.Port, []string{
+			"path=/",
+			"api=/api/v" + api.APIVersion,
+			"version=" + build.Version,
+		})
+		mdnsResponder.SetBindAddress(cfg.Server.Host)
+	}
 
-	// Trap event handler - publish to MQTT
-	trapReceiver.OnTrap(func(trapLog *domain.TrapLog) {
-		if mqttClient.IsConnected() {
-			topic := fmt.Sprintf("%s/traps", cfg.MQTT.TopicPrefix)
-			mqttClient.Publish(topic, trapLog, false)
+	// Create optional leader election for a warm standby pair
+	var leaderElector *service.LeaderElector
+	if cfg.Cluster.Mode == config.ClusterModeStandby {
+		if cfg.Database.Driver != "postgres" {
+			log.Printf("Warning: cluster.mode standby needs a database shared by all instances, such as PostgreSQL; %s is not", cfg.Database.Driver)
 		}
-	})
+		leaderElector = service.NewLeaderElector(repos.Lease, cfg.Cluster.NodeID, cfg.Cluster.LeaseTTL)
+	}
+
+	// Create optional device sharding for a cluster splitting the poll load;
+	// scenes and release checks run on an elected coordinator only
+	var shardService *service.ShardService
+	var coordinator *service.LeaderElector
+	if cfg.Cluster.Mode == config.ClusterModeSharded {
+		if cfg.Database.Driver != "postgres" {
+			log.Printf("Warning: cluster.mode sharded needs a database shared by all instances, such as PostgreSQL; %s is not", cfg.Database.Driver)
+		}
+		shardService = service.NewShardService(repos.ClusterNode, cfg.Cluster.NodeID, cfg.Cluster.Tags, cfg.Cluster.LeaseTTL, cfg.Cluster.SyncInterval)
+		coordinator = service.NewLeaderElector(repos.Lease, cfg.Cluster.NodeID, cfg.Cluster.LeaseTTL)
+		shardService.SetCoordinator(coordinator)
+		pollerService.SetOwnership(shardService.Owns)
+		publisher.SetOwnership(shardService.Owns)
+		mqttClient.SetNode(cfg.Cluster.NodeID)
+	}
 
 	// Create API server
 	services := &api.Services{
-		Device:     deviceService,
-		Profile:    profileService,
-		TrapLog:    trapLogService,
-		Setting:    settingService,
-		Poller:     pollerService,
-		SNMP:       snmpService,
-		MQTTClient: mqttClient,
+		Device:       deviceService,
+		Importer:     deviceImporter,
+		Profile:      profileService,
+		TrapLog:      trapLogService,
+		Setting:      settingService,
+		Event:        eventService,
+		Poller:       pollerService,
+		SNMP:         snmpService,
+		SelfTest:     selfTestService,
+		Action:       actionService,
+		CommandQueue: commandQueue,
+		Scene:        sceneService,
+		Credential:   credentialService,
+		Notification: notificationService,
+		Statistics:   statisticsAudit,
+		History:      historyService,
+		ConfigDrift:  configDrift,
+		Inventory:    inventoryService,
+		Leader:       leaderElector,
+		Shard:        shardService,
+		Update:       updateChecker,
+		MQTTClient:   mqttClient,
+		EventBus:     bus,
+		Database:     repos.Health,
+		TrapInjector: trapReceiver,
+		Maintenance:  maintenanceService,
+		Audit:        auditService,
+		WriteLock:    writeLock,
+		Locale:       localeService,
+		Units:        unitService,
+		Discovery:    publisher,
 	}
 
 	server := api.NewServer(cfg, services, embedfs.FrontendFS)
 
-	// Start services
+	// Register subsystems in dependency order; they are stopped in reverse,
+	// so the MQTT client publishes its offline status after all publishers
+	// have stopped and the database is closed last
 	ctx := context.Background()
+	lc := lifecycle.NewManager()
 
-	if err := pollerService.Start(ctx); err != nil {
-		log.Fatalf("Failed to start poller: %v", err)
+	if repos.Health != nil {
+		lc.Add(lifecycle.Component{
+			Name:  "database health monitor",
+			Start: func() error { repos.Health.Start(); return nil },
+			Stop:  repos.Health.Stop,
+		})
+	}
+	lc.Add(lifecycle.Component{Name: "event bus", Stop: bus.Close})
+	lc.Add(lifecycle.Component{
+		Name:  "profile cache",
+		Start: func() error { profileCache.Start(); return nil },
+		Stop:  profileCache.Stop,
+	})
+	lc.Add(lifecycle.Component{
+		Name:  "event service",
+		Start: func() error { eventService.Start(); return nil },
+		Stop:  eventService.Stop,
+	})
+	if auditService != nil {
+		lc.Add(lifecycle.Component{
+			Name:  "audit trail",
+			Start: func() error { auditService.Start(); return nil },
+			Stop:  auditService.Stop,
+		})
 	}
+	if historyService != nil {
+		lc.Add(lifecycle.Component{
+			Name:  "state history",
+			Start: func() error { historyService.Start(); return nil },
+			Stop:  historyService.Stop,
+		})
+	}
+	lc.Add(lifecycle.Component{
+		Name:  "notification service",
+		Start: func() error { notificationService.Start(); return nil },
+		Stop:  notificationService.Stop,
+	})
+	lc.Add(lifecycle.Component{
+		Name:  "SNMP connection manager",
+		Start: func() error { service.StartSNMPConnectionReaper(); return nil },
+		Stop:  service.StopSNMPConnectionReaper,
+	})
 
-	if err := publisher.Start(); err != nil {
-		log.Printf("Warning: Failed to start MQTT publisher: %v", err)
+	// Subsystems that poll devices, publish to MQTT or answer SNMP run only on
+	// the leader of a standby pair; the MQTT client publishes its offline
+	// status after all publishers have stopped
+	active := lifecycle.NewManager()
+	active.Add(lifecycle.Component{
+		Name: "MQTT client",
+		Start: func() error {
+			if err := mqttClient.Connect(); err != nil {
+				log.Printf("Warning: Failed to connect to MQTT broker: %v", err)
+			}
+			return nil
+		},
+		Stop: mqttClient.Disconnect,
+	})
+	active.Add(lifecycle.Component{
+		Name:  "poller",
+		Start: func() error { return pollerService.Start(ctx) },
+		Stop:  pollerService.Stop,
+	})
+	active.Add(lifecycle.Component{Name: "command queue", Stop: commandQueue.Stop})
+	active.Add(lifecycle.Component{
+		Name:  "self-test scheduler",
+		Start: func() error { selfTestService.Start(); return nil },
+		Stop:  selfTestService.Stop,
+	})
+	active.Add(lifecycle.Component{
+		Name:  "configuration drift",
+		Start: func() error { configDrift.Start(); return nil },
+		Stop:  configDrift.Stop,
+	})
+	active.Add(lifecycle.Component{
+		Name:     "MQTT publisher",
+		Start:    publisher.Start,
+		Stop:     publisher.Stop,
+		Optional: true,
+	})
+	// Scenes, vacuums and release checks run once per cluster, on the
+	// coordinator of a sharded cluster
+	coordinated := active
+	if coordinator != nil {
+		coordinated = lifecycle.NewManager()
+	}
+	coordinated.Add(lifecycle.Component{
+		Name:     "MQTT scene publisher",
+		Start:    scenePublisher.Start,
+		Stop:     scenePublisher.Stop,
+		Optional: true,
+	})
+	active.Add(lifecycle.Component{
+		Name: "MQTT device registration",
+		Start: func() error {
+			// Register existing devices with MQTT publisher
+			devices, _ := deviceService.GetEnabled(ctx)
+			publisher.SyncDevices(devices)
+			return nil
+		},
+	})
+	if maintenanceService != nil {
+		coordinated.Add(lifecycle.Component{
+			Name:  "database maintenance",
+			Start: func() error { maintenanceService.Start(); return nil },
+			Stop:  maintenanceService.Stop,
+		})
+	}
+	if updateChecker != nil {
+		coordinated.Add(lifecycle.Component{
+			Name:  "update checker",
+			Start: func() error { updateChecker.Start(); return nil },
+			Stop:  updateChecker.Stop,
+		})
+	}
+	active.Add(lifecycle.Component{
+		Name:     "trap receiver",
+		Start:    trapReceiver.Start,
+		Stop:     trapReceiver.Stop,
+		Optional: true,
+	})
+	if snmpAgent != nil {
+		active.Add(lifecycle.Component{
+			Name:     "SNMP agent",
+			Start:    snmpAgent.Start,
+			Stop:     snmpAgent.Stop,
+			Optional: true,
+		})
 	}
 
-	// Register existing devices with MQTT publisher
-	devices, _ := deviceService.GetEnabled(ctx)
-	for i := range devices {
-		if err := publisher.RegisterDevice(&devices[i]); err != nil {
-			log.Printf("Warning: Failed to register device %s with MQTT: %v", devices[i].ID, err)
+	// leaderLost is closed when a standby pair's active instance loses the
+	// leader lease
+	leaderLost := make(chan struct{})
+	var shutdownCtx context.Context
+	if leaderElector != nil {
+		leaderElector.SetCallbacks(active.Start, func() { close(leaderLost) })
+		lc.Add(lifecycle.Component{
+			Name:  "leader election",
+			Start: func() error { leaderElector.Start(); return nil },
+			Stop: func() {
+				// Stop the active subsystems before the standby may take over
+				leaderElector.Stop()
+				active.Stop(shutdownCtx)
+				leaderElector.Release()
+			},
+		})
+	} else {
+		if shardService != nil {
+			// Joined before polling starts, so each node polls only its share
+			lc.Add(lifecycle.Component{
+				Name:  "cluster membership",
+				Start: shardService.Join,
+				Stop:  shardService.Leave,
+			})
 		}
+		lc.Add(lifecycle.Component{
+			Name:  "active subsystems",
+			Start: active.Start,
+			Stop:  func() { active.Stop(shutdownCtx) },
+		})
+	}
+	if shardService != nil {
+		lc.Add(lifecycle.Component{
+			Name: "cluster sync",
+			Start: func() error {
+				shardService.Start(func() {
+					// Take over and hand off devices as nodes come and go, and
+					// pick up devices changed through other nodes
+					pollerService.Reconcile()
+					devices, err := deviceService.GetEnabled(ctx)
+					if err != nil {
+						log.Printf("Cluster sync failed to load devices: %v", err)
+						return
+					}
+					publisher.SyncDevices(devices)
+				}, func() {
+					// The other nodes have taken over; the database is
+					// likely unreachable, so nothing is read from it
+					pollerService.ReleaseAll()
+					publisher.ReleaseAll()
+				})
+				return nil
+			},
+			Stop: shardService.Stop,
+		})
+		coordinator.SetCallbacks(coordinated.Start, func() {
+			// Polling and publishing carry on; only the coordinator's
+			// subsystems stop, and another node takes the role
+			stopCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			coordinated.Stop(stopCtx)
+			log.Printf("Cluster node %s stepped down as coordinator", cfg.Cluster.NodeID)
+		})
+		lc.Add(lifecycle.Component{
+			Name:  "coordinator election",
+			Start: func() error { coordinator.Start(); return nil },
+			Stop: func() {
+				coordinator.Stop()
+				coordinated.Stop(shutdownCtx)
+				coordinator.Release()
+			},
+		})
 	}
 
-	if err := trapReceiver.Start(); err != nil {
-		log.Printf("Warning: Failed to start trap receiver: %v", err)
+	// The HTTP server stops first so no request reaches a stopped subsystem
+	lc.Add(lifecycle.Component{
+		Name: "HTTP server",
+		Start: func() error {
+			go func() {
+				log.Printf("HTTP server listening on %s:%d", cfg.Server.Host, cfg.Server.Port)
+				if err := server.Start(); err != nil && err != http.ErrServerClosed {
+					log.Printf("HTTP server error: %v", err)
+				}
+			}()
+			return nil
+		},
+		Stop: func() {
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				log.Printf("Server shutdown error: %v", err)
+			}
+		},
+	})
+
+	// Advertised once the HTTP server is up, withdrawn before it stops
+	if mdnsResponder != nil {
+		lc.Add(lifecycle.Component{
+			Name:     "mDNS responder",
+			Start:    mdnsResponder.Start,
+			Stop:     mdnsResponder.Stop,
+			Optional: true,
+		})
 	}
 
-	// Start HTTP server in goroutine
-	go func() {
-		log.Printf("HTTP server listening on %s:%d", cfg.Server.Host, cfg.Server.Port)
-		if err := server.Start(); err != nil {
-			log.Printf("HTTP server error: %v", err)
-		}
-	}()
+	if err := lc.Start(); err != nil {
+		log.Fatalf("Startup failed: %v", err)
+	}
 
 	// Wait for shutdown signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	lost := false
+	select {
+	case <-quit:
+	case <-leaderLost:
+		// Services cannot be restarted in place; the supervisor restarts the
+		// instance, which rejoins as standby or as a plain node
+		lost = true
+	}
 
 	log.Println("Shutting down...")
 
@@ -134,15 +531,10 @@ func main() {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// Stop services in reverse order
-	trapReceiver.Stop()
-	publisher.Stop()
-	pollerService.Stop()
-	mqttClient.Disconnect()
-
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Printf("Server shutdown error: %v", err)
-	}
+	lc.Stop(shutdownCtx)
 
 	log.Println("Shutdown complete")
+	if lost {
+		os.Exit(1)
+	}
 }
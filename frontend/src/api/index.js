@@ -40,6 +40,8 @@ export const api = {
   testConnection: (id) => request('POST', `/devices/${id}/test`),
   testNewConnection: (data) => request('POST', '/test-connection', data),
   getDeviceState: (id) => request('GET', `/devices/${id}/state`),
+  retryDiscovery: (id) => request('POST', `/devices/${id}/discovery/retry`),
+  previewMapping: (id, mapping) => request('POST', `/devices/${id}/preview-mapping`, mapping),
 
   // Device commands
   setDeviceValue: (id, oid, value) => request('POST', `/devices/${id}/set`, { oid, value }),
@@ -59,12 +61,19 @@ export const api = {
   updateProfile: (id, data) => request('PUT', `/profiles/${id}`, data),
   deleteProfile: (id) => request('DELETE', `/profiles/${id}`),
 
+  // Shared credentials
+  getCredentials: () => request('GET', '/credentials'),
+  createCredential: (data) => request('POST', '/credentials', data),
+  updateCredential: (id, data) => request('PUT', `/credentials/${id}`, data),
+  deleteCredential: (id) => request('DELETE', `/credentials/${id}`),
+
   // Traps
   getTraps: (params = {}) => {
     const query = new URLSearchParams(params).toString()
     return request('GET', `/traps${query ? '?' + query : ''}`)
   },
   getTrap: (id) => request('GET', `/traps/${id}`),
+  trapExportUrl: (format = 'csv') => `${getApiBase()}/traps/export?format=${format}`,
   cleanupTraps: (days = 30) => request('DELETE', `/traps/cleanup?days=${days}`),
 
   // Settings
@@ -73,6 +82,18 @@ export const api = {
   setSetting: (key, value) => request('PUT', `/settings/${key}`, { value }),
   deleteSetting: (key) => request('DELETE', `/settings/${key}`),
 
+  // Language of entity names and enum labels in Home Assistant
+  getLocale: () => request('GET', '/locale'),
+  setLocale: (locale) => request('PUT', '/locale', { locale }),
+
+  // Unit temperatures are published in
+  getUnits: () => request('GET', '/units'),
+  setUnits: (data) => request('PUT', '/units', data),
+
+  // Notifications
+  getNotificationStatus: () => request('GET', '/notifications/status'),
+  testNotification: (channel) => request('POST', `/notifications/${channel}/test`),
+
   // MQTT
   getMQTTStatus: () => request('GET', '/mqtt/status'),
   reconnectMQTT: () => request('POST', '/mqtt/reconnect'),
@@ -49,6 +49,17 @@ export const useDeviceStore = defineStore('devices', () => {
     delete deviceStates.value[id]
   }
 
+  async function retryDiscovery(id) {
+    updateDiscovery(await api.retryDiscovery(id))
+  }
+
+  function updateDiscovery(status) {
+    const device = devices.value.find(d => d.id === status.device_id)
+    if (device) {
+      device.discovery = status
+    }
+  }
+
   function updateDeviceState(deviceId, state) {
     deviceStates.value[deviceId] = state
   }
@@ -72,6 +83,8 @@ export const useDeviceStore = defineStore('devices', () => {
     createDevice,
     updateDevice,
     deleteDevice,
+    retryDiscovery,
+    updateDiscovery,
     updateDeviceState,
     setAllStates,
     getDeviceState,
@@ -0,0 +1,41 @@
+// Package actor carries who an API request or command acts for through
+// contexts, so changes and the device state they cause can be attributed in
+// the audit trail and the device timeline
+package actor
+
+import (
+	"context"
+	"strings"
+)
+
+// maxLength bounds names taken from request headers
+const maxLength = 128
+
+type contextKey struct{}
+
+// WithName returns a copy of ctx acting for name
+func WithName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, contextKey{}, name)
+}
+
+// FromContext returns the name ctx acts for, or "" when unknown, e.g. for
+// scheduled jobs
+func FromContext(ctx context.Context) string {
+	name, _ := ctx.Value(contextKey{}).(string)
+	return name
+}
+
+// Sanitize trims a name sent by a client or proxy to printable characters
+// and a length safe to store and log
+func Sanitize(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if len(name) > maxLength {
+		name = name[:maxLength]
+	}
+	return name
+}
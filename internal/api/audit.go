@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"snmp-mqtt-bridge/internal/actor"
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/dryrun"
+	"snmp-mqtt-bridge/internal/requestid"
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readOnlyPosts are POST routes that test or query without changing anything,
+// left out of the audit trail
+var readOnlyPosts = map[string]bool{
+	"/devices/:id/test":            true,
+	"/devices/:id/preview-mapping": true,
+	"/test-connection":             true,
+	"/mqtt/test":                   true,
+	"/grafana/search":              true,
+	"/grafana/query":               true,
+}
+
+// actorMiddleware names who a request acts for: the user in userHeader, set
+// by a trusted reverse proxy, or else the client IP. Commands sent by the
+// request carry the name on to the device timeline.
+func actorMiddleware(userHeader string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := ""
+		if userHeader != "" {
+			name = actor.Sanitize(c.GetHeader(userHeader))
+		}
+		if name == "" {
+			name = c.ClientIP()
+		}
+		c.Request = c.Request.WithContext(actor.WithName(c.Request.Context(), name))
+		c.Next()
+	}
+}
+
+// auditMiddleware records each change made through the API once it has been
+// handled, whether or not it succeeded
+func auditMiddleware(audit *service.AuditService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			return
+		}
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+		route = strings.TrimPrefix(route, "/api/v"+APIVersion)
+		route = strings.TrimPrefix(route, "/api")
+		if c.Request.Method == http.MethodPost && readOnlyPosts[route] {
+			return
+		}
+
+		ctx := c.Request.Context()
+		status := c.Writer.Status()
+		entry := &domain.AuditEntry{
+			Actor:      actor.FromContext(ctx),
+			Source:     domain.AuditSourceAPI,
+			Method:     c.Request.Method,
+			Route:      route,
+			Target:     auditTarget(c),
+			Status:     status,
+			Success:    status < http.StatusBadRequest,
+			RemoteAddr: c.ClientIP(),
+			RequestID:  requestid.FromContext(ctx),
+		}
+		if report := dryrun.FromContext(ctx).Report(); report != nil {
+			entry.Detail = fmt.Sprintf("dry run, %d SNMP SETs held back", len(report.Sets))
+		}
+		if err := audit.Record(context.WithoutCancel(ctx), entry); err != nil {
+			log.Printf("Failed to record audit entry for %s %s: %v", entry.Method, entry.Route, err)
+		}
+	}
+}
+
+// auditTarget returns the ID or key in the route of what a request changed
+func auditTarget(c *gin.Context) string {
+	for _, param := range []string{"id", "key", "channel"} {
+		if v := c.Param(param); v != "" {
+			if action := c.Param("action"); action != "" {
+				return v + "/" + action
+			}
+			return v
+		}
+	}
+	return ""
+}
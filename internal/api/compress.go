@@ -0,0 +1,118 @@
+package api
+
+import (
+	"compress/gzip"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// compressibleTypes lists content type prefixes worth compressing.
+// Images (except SVG) and fonts are already compressed.
+var compressibleTypes = []string{
+	"text/",
+	"application/json",
+	"application/x-ndjson",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+}
+
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	},
+}
+
+// gzipResponseWriter lazily wraps the response in gzip once the first body
+// bytes are written, so empty responses (204, 304) stay empty.
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	gz       *gzip.Writer
+	decided  bool
+	compress bool
+}
+
+func (w *gzipResponseWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return
+	}
+
+	contentType := h.Get("Content-Type")
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			w.compress = true
+			break
+		}
+	}
+	if !w.compress {
+		return
+	}
+
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	h.Del("Accept-Ranges")
+
+	w.gz = gzipWriterPool.Get().(*gzip.Writer)
+	w.gz.Reset(w.ResponseWriter)
+}
+
+func (w *gzipResponseWriter) Write(data []byte) (int, error) {
+	w.decide()
+	if w.compress {
+		return w.gz.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *gzipResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *gzipResponseWriter) close() {
+	if w.gz == nil {
+		return
+	}
+	w.gz.Close()
+	gzipWriterPool.Put(w.gz)
+	w.gz = nil
+}
+
+// gzipMiddleware compresses responses for clients that accept gzip encoding
+func gzipMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") ||
+			c.Request.Method == "HEAD" ||
+			strings.EqualFold(c.GetHeader("Connection"), "upgrade") ||
+			c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+
+		gw := &gzipResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = gw
+		defer func() {
+			gw.close()
+			c.Writer = gw.ResponseWriter
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,19 @@
+package api
+
+import (
+	"strconv"
+
+	"snmp-mqtt-bridge/internal/dryrun"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dryRunMiddleware collects the SNMP SETs held back while handling a request,
+// by its dry_run=true query flag or read-only mode, for the response to report
+func dryRunMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requested, _ := strconv.ParseBool(c.Query("dry_run"))
+		c.Request = c.Request.WithContext(dryrun.WithRecorder(c.Request.Context(), dryrun.NewRecorder(requested)))
+		c.Next()
+	}
+}
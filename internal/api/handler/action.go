@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActionHandler handles profile-defined device actions
+type ActionHandler struct {
+	actionService *service.ActionService
+}
+
+// NewActionHandler creates a new action handler
+func NewActionHandler(actionService *service.ActionService) *ActionHandler {
+	return &ActionHandler{actionService: actionService}
+}
+
+// RunActionRequest represents a request to run a device action
+type RunActionRequest struct {
+	Confirm bool `json:"confirm"`
+}
+
+// List returns the actions available on a device
+func (h *ActionHandler) List(c *gin.Context) {
+	actions, err := h.actionService.List(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondOK(c, actions)
+}
+
+// Run executes a named action on a device
+func (h *ActionHandler) Run(c *gin.Context) {
+	var req RunActionRequest
+	// Body is optional; confirmation can also be given as ?confirm=true
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			RespondBindError(c, err)
+			return
+		}
+	}
+	if c.Query("confirm") == "true" {
+		req.Confirm = true
+	}
+
+	action, err := h.actionService.Run(c.Request.Context(), c.Param("id"), c.Param("action"), req.Confirm)
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrDeviceNotFound):
+		RespondDeviceNotFound(c)
+		return
+	case errors.Is(err, service.ErrActionNotFound):
+		RespondNotFound(c, "Action not found")
+		return
+	case errors.Is(err, service.ErrConfirmationRequired):
+		RespondError(c, http.StatusPreconditionRequired, "Action \""+action.Name+"\" requires confirmation: resend with {\"confirm\": true}")
+		return
+	default:
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondOK(c, gin.H{
+		"success": true,
+		"action":  action.Key(),
+		"message": action.Name + " executed",
+	})
+}
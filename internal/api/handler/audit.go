@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler handles audit trail HTTP requests
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// List returns audit entries, newest first, with pagination
+func (h *AuditHandler) List(c *gin.Context) {
+	filter := domain.AuditFilter{
+		Actor:  c.Query("actor"),
+		Target: c.Query("target"),
+		Source: c.Query("source"),
+		Limit:  50,
+		Offset: 0,
+	}
+
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit > 0 {
+		filter.Limit = limit
+	}
+
+	if offset, err := strconv.Atoi(c.Query("offset")); err == nil && offset >= 0 {
+		filter.Offset = offset
+	}
+
+	if startStr := c.Query("start"); startStr != "" {
+		if t, err := time.Parse(time.RFC3339, startStr); err == nil {
+			filter.StartTime = &t
+		}
+	}
+
+	if endStr := c.Query("end"); endStr != "" {
+		if t, err := time.Parse(time.RFC3339, endStr); err == nil {
+			filter.EndTime = &t
+		}
+	}
+
+	entries, total, err := h.auditService.GetAll(c.Request.Context(), filter)
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondWithMeta(c, entries, total, filter.Limit, filter.Offset)
+}
+
+// Cleanup deletes old audit entries
+func (h *AuditHandler) Cleanup(c *gin.Context) {
+	days := 90
+	if daysStr := c.Query("days"); daysStr != "" {
+		if d, err := strconv.Atoi(daysStr); err == nil && d > 0 {
+			days = d
+		}
+	}
+
+	deleted, err := h.auditService.DeleteOlderThan(c.Request.Context(), days)
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"deleted": deleted,
+	})
+}
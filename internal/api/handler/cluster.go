@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClusterHandler handles warm standby and sharded cluster status requests
+type ClusterHandler struct {
+	leader *service.LeaderElector
+	shard  *service.ShardService
+	poller *service.PollerService
+}
+
+// NewClusterHandler creates a new cluster handler; leader is set for a
+// standby pair, shard for a sharded cluster
+func NewClusterHandler(leader *service.LeaderElector, shard *service.ShardService, poller *service.PollerService) *ClusterHandler {
+	return &ClusterHandler{leader: leader, shard: shard, poller: poller}
+}
+
+// Status returns whether this instance is the leader and which one is, or
+// the nodes of a sharded cluster and this node's share of the devices
+func (h *ClusterHandler) Status(c *gin.Context) {
+	if h.shard != nil {
+		status, err := h.shard.Status(c.Request.Context(), h.poller.PolledDevices())
+		if err != nil {
+			RespondServiceError(c, err)
+			return
+		}
+		RespondOK(c, status)
+		return
+	}
+
+	status, err := h.leader.Status(c.Request.Context())
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondOK(c, status)
+}
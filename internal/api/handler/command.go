@@ -1,9 +1,15 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"snmp-mqtt-bridge/internal/domain"
 	"snmp-mqtt-bridge/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -11,24 +17,34 @@ import (
 
 // CommandHandler handles device command requests
 type CommandHandler struct {
-	snmpService   *service.SNMPService
-	pollerService *service.PollerService
-	deviceService *service.DeviceService
+	snmpService    *service.SNMPService
+	pollerService  *service.PollerService
+	deviceService  *service.DeviceService
+	profileService *service.ProfileService
+	commandQueue   *service.CommandQueue
+	confirmations  *service.ConfirmationTokens
 }
 
+// confirmationTTL is how long a group command confirmation token stays valid
+const confirmationTTL = 2 * time.Minute
+
 // NewCommandHandler creates a new command handler
-func NewCommandHandler(snmpService *service.SNMPService, pollerService *service.PollerService, deviceService *service.DeviceService) *CommandHandler {
+func NewCommandHandler(snmpService *service.SNMPService, pollerService *service.PollerService, deviceService *service.DeviceService, profileService *service.ProfileService, commandQueue *service.CommandQueue) *CommandHandler {
 	return &CommandHandler{
-		snmpService:   snmpService,
-		pollerService: pollerService,
-		deviceService: deviceService,
+		snmpService:    snmpService,
+		pollerService:  pollerService,
+		deviceService:  deviceService,
+		profileService: profileService,
+		commandQueue:   commandQueue,
+		confirmations:  service.NewConfirmationTokens(confirmationTTL),
 	}
 }
 
 // SetValueRequest represents a request to set an SNMP value
 type SetValueRequest struct {
-	OID   string      `json:"oid" binding:"required"`
-	Value interface{} `json:"value" binding:"required"`
+	OID   string         `json:"oid" binding:"required,oid"`
+	Value interface{}    `json:"value" binding:"required"`
+	Type  domain.PDUType `json:"type,omitempty"` // Optional explicit PDU type (integer, gauge32, ipaddress, ...)
 }
 
 // SetValue sets an SNMP value on a device
@@ -37,12 +53,18 @@ func (h *CommandHandler) SetValue(c *gin.Context) {
 
 	var req SetValueRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondBindError(c, err)
+		return
+	}
+
+	if _, err := service.BuildSetPDU(req.OID, req.Value, req.Type); err != nil {
 		RespondBadRequest(c, err.Error())
 		return
 	}
 
-	if err := h.snmpService.SetValue(c.Request.Context(), deviceID, req.OID, req.Value); err != nil {
-		RespondError(c, 500, err.Error())
+	cmd := service.QueuedCommand{Description: "Set " + req.OID, OID: req.OID, Value: req.Value, Type: req.Type}
+	if err := h.commandQueue.Send(c.Request.Context(), deviceID, cmd); err != nil {
+		RespondServiceError(c, err)
 		return
 	}
 
@@ -57,7 +79,9 @@ func (h *CommandHandler) SetValue(c *gin.Context) {
 	})
 }
 
-// GetValue gets an SNMP value from a device
+// GetValue gets an SNMP value from a device. With ?max_age (e.g. 30s) a
+// value polled at most that long ago is served from the poller's cache
+// instead of querying the device.
 func (h *CommandHandler) GetValue(c *gin.Context) {
 	deviceID := c.Param("id")
 	oid := c.Query("oid")
@@ -67,21 +91,101 @@ func (h *CommandHandler) GetValue(c *gin.Context) {
 		return
 	}
 
+	if raw := c.Query("max_age"); raw != "" {
+		maxAge, err := time.ParseDuration(raw)
+		if err != nil || maxAge < 0 {
+			RespondBadRequest(c, "Invalid max_age: "+raw)
+			return
+		}
+		if h.pollerService != nil {
+			if value, polledAt, ok := h.pollerService.CachedValue(deviceID, oid, maxAge); ok {
+				RespondOK(c, gin.H{
+					"oid":       oid,
+					"value":     value,
+					"source":    "cache",
+					"polled_at": polledAt,
+				})
+				return
+			}
+		}
+	}
+
 	value, err := h.snmpService.GetValue(c.Request.Context(), deviceID, oid)
 	if err != nil {
-		RespondError(c, 500, err.Error())
+		RespondServiceError(c, err)
 		return
 	}
 
 	RespondOK(c, gin.H{
-		"oid":   oid,
-		"value": value,
+		"oid":    oid,
+		"value":  value,
+		"source": "device",
 	})
 }
 
+// capabilities resolves a device and its profile capabilities, responding
+// with an error and returning nil if the device is unknown
+func (h *CommandHandler) capabilities(c *gin.Context, deviceID string) (*domain.Device, *domain.Capabilities) {
+	device, err := h.deviceService.GetByID(c.Request.Context(), deviceID)
+	if err != nil {
+		RespondDeviceNotFound(c)
+		return nil, nil
+	}
+
+	if device.ProfileID != "" {
+		if profile, err := h.profileService.GetByID(c.Request.Context(), device.ProfileID); err == nil {
+			return device, &profile.Capabilities
+		}
+	}
+	return device, &domain.Capabilities{}
+}
+
+// polledValues returns the latest polled values of a device, keyed by mapping name
+func (h *CommandHandler) polledValues(deviceID string) map[string]interface{} {
+	if h.pollerService == nil {
+		return nil
+	}
+	if state := h.pollerService.GetDeviceState(deviceID); state != nil {
+		return state.Values
+	}
+	return nil
+}
+
+// resolveOutlet turns an outlet reference (number or label) into an outlet
+// number, responding with an error and returning 0 if it cannot be resolved
+func (h *CommandHandler) resolveOutlet(c *gin.Context, device *domain.Device, caps *domain.Capabilities, ref OutletRef) int {
+	if ref.Number > 0 {
+		return ref.Number
+	}
+	if ref.Label == "" {
+		RespondBadRequest(c, "outlet is required")
+		return 0
+	}
+	if caps.OutletControl == nil {
+		respondCapabilityError(c, fmt.Errorf("outlet control %w", domain.ErrCapabilityUnsupported))
+		return 0
+	}
+
+	number, ok := caps.OutletControl.FindOutlet(ref.Label, device.Labels, h.polledValues(device.ID))
+	if !ok {
+		RespondBadRequest(c, fmt.Sprintf("no outlet named '%s'", ref.Label))
+		return 0
+	}
+	return number
+}
+
+// respondCapabilityError reports a command that the device profile cannot resolve
+func respondCapabilityError(c *gin.Context, err error) {
+	if errors.Is(err, domain.ErrCapabilityUnsupported) {
+		RespondError(c, http.StatusNotImplemented, err.Error())
+		return
+	}
+	RespondBadRequest(c, err.Error())
+}
+
 // SwitchSourceRequest for switching ATS source
 type SwitchSourceRequest struct {
-	Source int `json:"source" binding:"required,oneof=1 2"` // 1 = Source A, 2 = Source B
+	Source int `json:"source" binding:"required,min=1"` // Source value from the profile, e.g. 1 = Source A, 2 = Source B
 }
 
 // SwitchSource switches the ATS preferred source
@@ -90,15 +194,24 @@ func (h *CommandHandler) SwitchSource(c *gin.Context) {
 
 	var req SwitchSourceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		RespondBadRequest(c, err.Error())
+		RespondBindError(c, err)
 		return
 	}
 
-	// ATS switch command OID
-	switchOID := ".1.3.6.1.4.1.318.1.1.8.4.2.0"
+	_, caps := h.capabilities(c, deviceID)
+	if caps == nil {
+		return
+	}
+
+	switchOID, value, sourceName, err := caps.SourceSwitch.SwitchCommand(req.Source)
+	if err != nil {
+		respondCapabilityError(c, err)
+		return
+	}
 
-	if err := h.snmpService.SetValue(c.Request.Context(), deviceID, switchOID, req.Source); err != nil {
-		RespondError(c, 500, err.Error())
+	cmd := service.QueuedCommand{Description: "Switch to " + sourceName, OID: switchOID, Value: value}
+	if err := h.commandQueue.Send(c.Request.Context(), deviceID, cmd); err != nil {
+		RespondServiceError(c, err)
 		return
 	}
 
@@ -107,11 +220,6 @@ func (h *CommandHandler) SwitchSource(c *gin.Context) {
 		h.pollerService.TriggerPoll(deviceID)
 	}
 
-	sourceName := "Source A"
-	if req.Source == 2 {
-		sourceName = "Source B"
-	}
-
 	RespondOK(c, gin.H{
 		"success": true,
 		"message": "Switched to " + sourceName,
@@ -120,7 +228,7 @@ func (h *CommandHandler) SwitchSource(c *gin.Context) {
 
 // SetSourceNameRequest for setting source name
 type SetSourceNameRequest struct {
-	Source int    `json:"source" binding:"required,oneof=1 2"` // 1 = Source A, 2 = Source B
+	Source int    `json:"source" binding:"required,min=1"` // Source value from the profile, e.g. 1 = Source A, 2 = Source B
 	Name   string `json:"name" binding:"required"`
 }
 
@@ -130,22 +238,24 @@ func (h *CommandHandler) SetSourceName(c *gin.Context) {
 
 	var req SetSourceNameRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		RespondBadRequest(c, err.Error())
+		RespondBindError(c, err)
 		return
 	}
 
-	// Source name OIDs
-	// Source A: .1.3.6.1.4.1.318.1.1.8.5.3.2.1.6.1
-	// Source B: .1.3.6.1.4.1.318.1.1.8.5.3.2.1.6.2
-	var nameOID string
-	if req.Source == 1 {
-		nameOID = ".1.3.6.1.4.1.318.1.1.8.5.3.2.1.6.1"
-	} else {
-		nameOID = ".1.3.6.1.4.1.318.1.1.8.5.3.2.1.6.2"
+	_, caps := h.capabilities(c, deviceID)
+	if caps == nil {
+		return
+	}
+
+	nameOID, err := caps.SourceSwitch.NameCommand(req.Source)
+	if err != nil {
+		respondCapabilityError(c, err)
+		return
 	}
 
-	if err := h.snmpService.SetValue(c.Request.Context(), deviceID, nameOID, req.Name); err != nil {
-		RespondError(c, 500, err.Error())
+	cmd := service.QueuedCommand{Description: "Name source", OID: nameOID, Value: req.Name}
+	if err := h.commandQueue.Send(c.Request.Context(), deviceID, cmd); err != nil {
+		RespondServiceError(c, err)
 		return
 	}
 
@@ -162,10 +272,56 @@ func (h *CommandHandler) SetSourceName(c *gin.Context) {
 
 // PDU Outlet Control
 
+// OutletRef identifies an outlet by number (1, "1") or by its label or device name ("Server A")
+type OutletRef struct {
+	Number int
+	Label  string
+}
+
+// UnmarshalJSON accepts either a number or a string
+func (r *OutletRef) UnmarshalJSON(data []byte) error {
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		if number < 1 {
+			return errors.New("outlet must be 1 or greater")
+		}
+		r.Number = number
+		return nil
+	}
+
+	var label string
+	if err := json.Unmarshal(data, &label); err != nil {
+		return errors.New("outlet must be a number or a label")
+	}
+	label = strings.TrimSpace(label)
+	if n, err := strconv.Atoi(label); err == nil && n > 0 {
+		r.Number = n
+	} else {
+		r.Label = label
+	}
+	return nil
+}
+
+// ListOutlets returns the number, name, state and current draw of every outlet
+func (h *CommandHandler) ListOutlets(c *gin.Context) {
+	deviceID := c.Param("id")
+
+	device, caps := h.capabilities(c, deviceID)
+	if caps == nil {
+		return
+	}
+	if caps.OutletControl == nil {
+		respondCapabilityError(c, fmt.Errorf("outlet control %w", domain.ErrCapabilityUnsupported))
+		return
+	}
+
+	RespondOK(c, caps.OutletControl.Outlets(device.Labels, h.polledValues(deviceID)))
+}
+
 // SetOutletStateRequest for setting PDU outlet state
 type SetOutletStateRequest struct {
-	Outlet int    `json:"outlet" binding:"required,min=1,max=48"` // Outlet number (1-48)
-	State  string `json:"state" binding:"required,oneof=on off"`  // "on" or "off"
+	Outlet OutletRef `json:"outlet"`                                // Outlet number or label
+	State  string    `json:"state" binding:"required,oneof=on off"` // "on" or "off"
 }
 
 // SetOutletState turns a PDU outlet on or off
@@ -174,48 +330,29 @@ func (h *CommandHandler) SetOutletState(c *gin.Context) {
 
 	var req SetOutletStateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		RespondBadRequest(c, err.Error())
+		RespondBindError(c, err)
 		return
 	}
 
-	ctx := c.Request.Context()
-
-	// Look up device to get its profile
-	device, err := h.deviceService.GetByID(ctx, deviceID)
-	if err != nil {
-		RespondError(c, 404, "Device not found")
+	device, caps := h.capabilities(c, deviceID)
+	if caps == nil {
 		return
 	}
 
-	var controlOID string
-	var value interface{}
-
-	// Check if this is an Energenie PDU
-	if strings.HasPrefix(device.ProfileID, "energenie") {
-		// Energenie PDU: OID is .1.3.6.1.4.1.17420.1.2.9.<outlet>.13.0
-		// Value is comma-separated string: "1,-1,-1,-1,-1,-1,-1,-1" where first position is state
-		controlOID = fmt.Sprintf(".1.3.6.1.4.1.17420.1.2.9.%d.13.0", req.Outlet)
-
-		stateValue := "0"
-		if req.State == "on" {
-			stateValue = "1"
-		}
-		// Format: state,-1,-1,-1,-1,-1,-1,-1
-		value = fmt.Sprintf("%s,-1,-1,-1,-1,-1,-1,-1", stateValue)
-	} else {
-		// APC PDU: OID is .1.3.6.1.4.1.318.1.1.12.3.3.1.1.4.<outlet>
-		// Values: 1 = immediateOn, 2 = immediateOff, 3 = immediateReboot
-		controlOID = fmt.Sprintf(".1.3.6.1.4.1.318.1.1.12.3.3.1.1.4.%d", req.Outlet)
+	outlet := h.resolveOutlet(c, device, caps, req.Outlet)
+	if outlet == 0 {
+		return
+	}
 
-		if req.State == "on" {
-			value = 1 // immediateOn
-		} else {
-			value = 2 // immediateOff
-		}
+	controlOID, value, err := caps.OutletControl.StateCommand(outlet, req.State == "on")
+	if err != nil {
+		respondCapabilityError(c, err)
+		return
 	}
 
-	if err := h.snmpService.SetValue(ctx, deviceID, controlOID, value); err != nil {
-		RespondError(c, 500, err.Error())
+	cmd := service.QueuedCommand{Description: fmt.Sprintf("Turn outlet %d %s", outlet, req.State), OID: controlOID, Value: value, Type: caps.OutletControl.Type}
+	if err := h.commandQueue.Send(c.Request.Context(), deviceID, cmd); err != nil {
+		RespondServiceError(c, err)
 		return
 	}
 
@@ -226,14 +363,14 @@ func (h *CommandHandler) SetOutletState(c *gin.Context) {
 
 	RespondOK(c, gin.H{
 		"success": true,
-		"message": fmt.Sprintf("Outlet %d turned %s", req.Outlet, req.State),
+		"message": fmt.Sprintf("Outlet %d turned %s", outlet, req.State),
 	})
 }
 
 // SetOutletNameRequest for setting PDU outlet name
 type SetOutletNameRequest struct {
-	Outlet int    `json:"outlet" binding:"required,min=1,max=48"` // Outlet number (1-48)
-	Name   string `json:"name" binding:"required"`
+	Outlet OutletRef `json:"outlet"` // Outlet number or label
+	Name   string    `json:"name" binding:"required"`
 }
 
 // SetOutletName sets the name of a PDU outlet
@@ -242,36 +379,29 @@ func (h *CommandHandler) SetOutletName(c *gin.Context) {
 
 	var req SetOutletNameRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		RespondBadRequest(c, err.Error())
+		RespondBindError(c, err)
 		return
 	}
 
-	ctx := c.Request.Context()
-
-	// Look up device to get its profile
-	device, err := h.deviceService.GetByID(ctx, deviceID)
-	if err != nil {
-		RespondError(c, 404, "Device not found")
+	device, caps := h.capabilities(c, deviceID)
+	if caps == nil {
 		return
 	}
 
-	var nameOID string
-	var value interface{}
+	outlet := h.resolveOutlet(c, device, caps, req.Outlet)
+	if outlet == 0 {
+		return
+	}
 
-	// Check if this is an Energenie PDU
-	if strings.HasPrefix(device.ProfileID, "energenie") {
-		// Energenie PDU: OID is .1.3.6.1.4.1.17420.1.2.9.<outlet>.14.1.0
-		// Value is comma-separated string: "Name,0,0,0,0" where first position is name
-		nameOID = fmt.Sprintf(".1.3.6.1.4.1.17420.1.2.9.%d.14.1.0", req.Outlet)
-		value = fmt.Sprintf("%s,0,0,0,0", req.Name)
-	} else {
-		// APC PDU: OID is .1.3.6.1.4.1.318.1.1.12.3.4.1.1.2.<outlet>
-		nameOID = fmt.Sprintf(".1.3.6.1.4.1.318.1.1.12.3.4.1.1.2.%d", req.Outlet)
-		value = req.Name
+	nameOID, value, err := caps.OutletControl.NameCommand(outlet, req.Name)
+	if err != nil {
+		respondCapabilityError(c, err)
+		return
 	}
 
-	if err := h.snmpService.SetValue(ctx, deviceID, nameOID, value); err != nil {
-		RespondError(c, 500, err.Error())
+	cmd := service.QueuedCommand{Description: fmt.Sprintf("Name outlet %d", outlet), OID: nameOID, Value: value}
+	if err := h.commandQueue.Send(c.Request.Context(), deviceID, cmd); err != nil {
+		RespondServiceError(c, err)
 		return
 	}
 
@@ -282,13 +412,13 @@ func (h *CommandHandler) SetOutletName(c *gin.Context) {
 
 	RespondOK(c, gin.H{
 		"success": true,
-		"message": fmt.Sprintf("Outlet %d name set to '%s'", req.Outlet, req.Name),
+		"message": fmt.Sprintf("Outlet %d name set to '%s'", outlet, req.Name),
 	})
 }
 
 // RebootOutletRequest for rebooting a PDU outlet
 type RebootOutletRequest struct {
-	Outlet int `json:"outlet" binding:"required,min=1,max=48"` // Outlet number (1-48)
+	Outlet OutletRef `json:"outlet"` // Outlet number or label
 }
 
 // RebootOutlet reboots a PDU outlet (turns off then on)
@@ -297,15 +427,29 @@ func (h *CommandHandler) RebootOutlet(c *gin.Context) {
 
 	var req RebootOutletRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		RespondBadRequest(c, err.Error())
+		RespondBindError(c, err)
+		return
+	}
+
+	device, caps := h.capabilities(c, deviceID)
+	if caps == nil {
 		return
 	}
 
-	// PDU outlet control OID with value 3 = immediateReboot
-	controlOID := fmt.Sprintf(".1.3.6.1.4.1.318.1.1.12.3.3.1.1.4.%d", req.Outlet)
+	outlet := h.resolveOutlet(c, device, caps, req.Outlet)
+	if outlet == 0 {
+		return
+	}
 
-	if err := h.snmpService.SetValue(c.Request.Context(), deviceID, controlOID, 3); err != nil {
-		RespondError(c, 500, err.Error())
+	controlOID, value, err := caps.OutletControl.RebootCommand(outlet)
+	if err != nil {
+		respondCapabilityError(c, err)
+		return
+	}
+
+	cmd := service.QueuedCommand{Description: fmt.Sprintf("Reboot outlet %d", outlet), OID: controlOID, Value: value, Type: caps.OutletControl.Type}
+	if err := h.commandQueue.Send(c.Request.Context(), deviceID, cmd); err != nil {
+		RespondServiceError(c, err)
 		return
 	}
 
@@ -316,6 +460,139 @@ func (h *CommandHandler) RebootOutlet(c *gin.Context) {
 
 	RespondOK(c, gin.H{
 		"success": true,
-		"message": fmt.Sprintf("Outlet %d rebooting", req.Outlet),
+		"message": fmt.Sprintf("Outlet %d rebooting", outlet),
+	})
+}
+
+// AllOutletsRequest for switching every outlet of a PDU
+type AllOutletsRequest struct {
+	Action       string      `json:"action" binding:"required,oneof=on off reboot"`
+	Exclude      []OutletRef `json:"exclude"`       // Outlets to leave untouched, by number or label
+	DelaySeconds *float64    `json:"delay_seconds"` // Pause between outlets, default 1
+	ConfirmToken string      `json:"confirm_token"` // Token from the preview response
+}
+
+// Outlet group sequencing bounds
+const (
+	defaultOutletDelay = time.Second
+	maxOutletDelay     = time.Minute
+)
+
+// AllOutlets switches or reboots every outlet of a PDU, one after another.
+// The first call returns a preview with a confirmation token; repeating the
+// request with that token queues the commands.
+func (h *CommandHandler) AllOutlets(c *gin.Context) {
+	deviceID := c.Param("id")
+
+	var req AllOutletsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondBindError(c, err)
+		return
+	}
+
+	delay := defaultOutletDelay
+	if req.DelaySeconds != nil {
+		delay = time.Duration(*req.DelaySeconds * float64(time.Second))
+		if delay < 0 || delay > maxOutletDelay {
+			RespondBadRequest(c, fmt.Sprintf("delay_seconds must be between 0 and %d", int(maxOutletDelay.Seconds())))
+			return
+		}
+	}
+
+	device, caps := h.capabilities(c, deviceID)
+	if caps == nil {
+		return
+	}
+	control := caps.OutletControl
+	if control == nil || control.Count == 0 {
+		respondCapabilityError(c, fmt.Errorf("outlet control %w", domain.ErrCapabilityUnsupported))
+		return
+	}
+
+	excluded := make(map[int]bool, len(req.Exclude))
+	for _, ref := range req.Exclude {
+		outlet := h.resolveOutlet(c, device, caps, ref)
+		if outlet == 0 {
+			return
+		}
+		excluded[outlet] = true
+	}
+
+	outlets := make([]int, 0, control.Count)
+	commands := make([]service.QueuedCommand, 0, control.Count)
+	for n := 1; n <= control.Count; n++ {
+		if excluded[n] {
+			continue
+		}
+
+		var oid string
+		var value interface{}
+		var err error
+		if req.Action == "reboot" {
+			oid, value, err = control.RebootCommand(n)
+		} else {
+			oid, value, err = control.StateCommand(n, req.Action == "on")
+		}
+		if err != nil {
+			respondCapabilityError(c, err)
+			return
+		}
+
+		cmd := service.QueuedCommand{
+			Description: fmt.Sprintf("Outlet %d %s", n, req.Action),
+			OID:         oid,
+			Value:       value,
+			Type:        control.Type,
+		}
+		if len(commands) > 0 {
+			cmd.Delay = delay
+		}
+		outlets = append(outlets, n)
+		commands = append(commands, cmd)
+	}
+
+	if len(commands) == 0 {
+		RespondBadRequest(c, "all outlets are excluded")
+		return
+	}
+
+	plan := gin.H{
+		"action":        req.Action,
+		"outlets":       outlets,
+		"delay_seconds": delay.Seconds(),
+	}
+
+	// A dry run switches nothing, so it needs no confirmation. The token is
+	// bound to this exact plan so it cannot confirm a different one.
+	dryRun := h.commandQueue.DryRun(c.Request.Context())
+	scope := fmt.Sprintf("%s|%s|%v|%s", deviceID, req.Action, outlets, delay)
+	if !dryRun && (req.ConfirmToken == "" || !h.confirmations.Consume(req.ConfirmToken, scope)) {
+		token, expires := h.confirmations.Issue(scope)
+		plan["confirm_token"] = token
+		plan["expires_at"] = expires
+		c.JSON(http.StatusPreconditionRequired, APIResponse{
+			Success: false,
+			Data:    plan,
+			Error:   "confirmation required: repeat the request with confirm_token",
+		})
+		return
+	}
+
+	job, err := h.commandQueue.Enqueue(c.Request.Context(), deviceID, commands)
+	if err != nil {
+		RespondError(c, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	plan["job_id"] = job.ID
+	if dryRun {
+		// Delays are skipped in a dry run, so the held back SETs are reported at once
+		plan["results"], _ = job.Wait(c.Request.Context())
+		RespondOK(c, plan)
+		return
+	}
+	c.JSON(http.StatusAccepted, APIResponse{
+		Success: true,
+		Data:    plan,
 	})
 }
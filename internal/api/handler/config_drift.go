@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"strconv"
+
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigDriftHandler handles device configuration drift requests
+type ConfigDriftHandler struct {
+	driftService *service.ConfigDriftService
+}
+
+// NewConfigDriftHandler creates a new configuration drift handler
+func NewConfigDriftHandler(driftService *service.ConfigDriftService) *ConfigDriftHandler {
+	return &ConfigDriftHandler{driftService: driftService}
+}
+
+// Get returns a device's current configuration and its changes between
+// snapshots, newest first; ?limit= caps the changes (default 50)
+func (h *ConfigDriftHandler) Get(c *gin.Context) {
+	limit := 50
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	drift, err := h.driftService.Drift(c.Request.Context(), c.Param("id"), limit)
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondOK(c, drift)
+}
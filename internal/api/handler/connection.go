@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConnectionHandler reports the SNMP sockets held by the bridge
+type ConnectionHandler struct{}
+
+// NewConnectionHandler creates a new connection handler
+func NewConnectionHandler() *ConnectionHandler {
+	return &ConnectionHandler{}
+}
+
+// Stats returns the open SNMP connections, limits, leak counters and the
+// process's file descriptor count
+func (h *ConnectionHandler) Stats(c *gin.Context) {
+	RespondOK(c, service.SNMPConnectionStats())
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CredentialHandler handles shared credential set HTTP requests
+type CredentialHandler struct {
+	credentialService *service.CredentialService
+}
+
+// NewCredentialHandler creates a new credential handler
+func NewCredentialHandler(credentialService *service.CredentialService) *CredentialHandler {
+	return &CredentialHandler{credentialService: credentialService}
+}
+
+// List returns all credential sets
+func (h *CredentialHandler) List(c *gin.Context) {
+	credentials, err := h.credentialService.GetAll(c.Request.Context())
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondOK(c, credentials)
+}
+
+// Get returns a credential set by ID
+func (h *CredentialHandler) Get(c *gin.Context) {
+	credential, err := h.credentialService.GetByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		RespondNotFound(c, "Credential not found")
+		return
+	}
+
+	RespondOK(c, credential)
+}
+
+// Create creates a new credential set
+func (h *CredentialHandler) Create(c *gin.Context) {
+	var req domain.CredentialCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondBindError(c, err)
+		return
+	}
+
+	credential, err := h.credentialService.Create(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredential) {
+			RespondBadRequest(c, err.Error())
+			return
+		}
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondCreated(c, credential)
+}
+
+// Update updates a credential set and the devices that reference it
+func (h *CredentialHandler) Update(c *gin.Context) {
+	var req domain.CredentialUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondBindError(c, err)
+		return
+	}
+
+	credential, err := h.credentialService.Update(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredential) {
+			RespondBadRequest(c, err.Error())
+			return
+		}
+		RespondNotFound(c, "Credential not found")
+		return
+	}
+
+	RespondOK(c, credential)
+}
+
+// Delete deletes a credential set no device references
+func (h *CredentialHandler) Delete(c *gin.Context) {
+	if err := h.credentialService.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		if errors.Is(err, service.ErrCredentialInUse) {
+			RespondError(c, http.StatusConflict, err.Error())
+			return
+		}
+		RespondNotFound(c, "Credential not found")
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
@@ -1,18 +1,33 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/mqtt"
+	"snmp-mqtt-bridge/internal/repository"
 	"snmp-mqtt-bridge/internal/service"
+	"snmp-mqtt-bridge/internal/timefmt"
 
 	"github.com/gin-gonic/gin"
 )
 
+// DiscoveryPublisher reports and retries the Home Assistant discovery
+// publishes of devices
+type DiscoveryPublisher interface {
+	DiscoveryStatus(deviceID string) (*domain.DiscoveryStatus, bool)
+	RetryDiscovery(deviceID string) (*domain.DiscoveryStatus, error)
+}
+
 // DeviceHandler handles device-related HTTP requests
 type DeviceHandler struct {
 	deviceService *service.DeviceService
 	pollerService *service.PollerService
+	importer      *service.DeviceImporter
+	discovery     DiscoveryPublisher
 }
 
 // NewDeviceHandler creates a new device handler
@@ -25,25 +40,56 @@ func NewDeviceHandler(deviceService *service.DeviceService, pollerService *servi
 
 // List returns all devices
 func (h *DeviceHandler) List(c *gin.Context) {
-	devices, err := h.deviceService.GetAll(c.Request.Context())
+	var devices []domain.Device
+	var err error
+	if site := c.Query("site"); site != "" {
+		devices, err = h.deviceService.GetBySite(c.Request.Context(), site)
+	} else {
+		devices, err = h.deviceService.GetAll(c.Request.Context())
+	}
 	if err != nil {
-		RespondInternalError(c, err.Error())
+		RespondServiceError(c, err)
 		return
 	}
 
+	for i := range devices {
+		h.attachDiscovery(&devices[i])
+	}
 	RespondOK(c, devices)
 }
 
+// SetImporter enables bulk device import from seed lists
+func (h *DeviceHandler) SetImporter(importer *service.DeviceImporter) {
+	h.importer = importer
+}
+
+// SetDiscovery enables reporting and retrying discovery publishes
+func (h *DeviceHandler) SetDiscovery(discovery DiscoveryPublisher) {
+	h.discovery = discovery
+}
+
+// attachDiscovery fills in a device's discovery publish status, if this
+// bridge publishes it
+func (h *DeviceHandler) attachDiscovery(device *domain.Device) {
+	if h.discovery == nil {
+		return
+	}
+	if status, ok := h.discovery.DiscoveryStatus(device.ID); ok {
+		device.Discovery = status
+	}
+}
+
 // Get returns a device by ID
 func (h *DeviceHandler) Get(c *gin.Context) {
 	id := c.Param("id")
 
 	device, err := h.deviceService.GetByID(c.Request.Context(), id)
 	if err != nil {
-		RespondNotFound(c, "Device not found")
+		RespondDeviceNotFound(c)
 		return
 	}
 
+	h.attachDiscovery(device)
 	RespondOK(c, device)
 }
 
@@ -51,22 +97,61 @@ func (h *DeviceHandler) Get(c *gin.Context) {
 func (h *DeviceHandler) Create(c *gin.Context) {
 	var req domain.DeviceCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondBindError(c, err)
+		return
+	}
+
+	if err := validateCustomMappings(req.CustomMappings); err != nil {
+		RespondBadRequest(c, err.Error())
+		return
+	}
+	if err := validateAlarms(req.Alarms); err != nil {
+		RespondBadRequest(c, err.Error())
+		return
+	}
+	if err := validateTransport(req.BindAddress, req.ContextEngineID); err != nil {
 		RespondBadRequest(c, err.Error())
 		return
 	}
 
 	device, err := h.deviceService.Create(c.Request.Context(), &req)
 	if err != nil {
-		RespondInternalError(c, err.Error())
+		if errors.Is(err, service.ErrCredentialNotFound) {
+			RespondBadRequest(c, err.Error())
+			return
+		}
+		RespondServiceError(c, err)
 		return
 	}
 
-	// Notify poller about new device
-	if h.pollerService != nil && device.Enabled {
-		h.pollerService.AddDevice(device)
+	RespondCreated(c, device)
+}
+
+// Import bulk-creates devices from a seed list exported by another SNMP
+// monitoring tool
+func (h *DeviceHandler) Import(c *gin.Context) {
+	if h.importer == nil {
+		RespondError(c, http.StatusServiceUnavailable, "Device import is not available")
+		return
 	}
 
-	RespondCreated(c, device)
+	var req domain.DeviceImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondBindError(c, err)
+		return
+	}
+
+	result, err := h.importer.Import(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSeedList) {
+			RespondBadRequest(c, err.Error())
+			return
+		}
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondOK(c, result)
 }
 
 // Update updates an existing device
@@ -75,21 +160,40 @@ func (h *DeviceHandler) Update(c *gin.Context) {
 
 	var req domain.DeviceUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondBindError(c, err)
+		return
+	}
+
+	if err := validateCustomMappings(req.CustomMappings); err != nil {
+		RespondBadRequest(c, err.Error())
+		return
+	}
+	if err := validateAlarms(req.Alarms); err != nil {
+		RespondBadRequest(c, err.Error())
+		return
+	}
+	var bindAddress, engineID string
+	if req.BindAddress != nil {
+		bindAddress = *req.BindAddress
+	}
+	if req.ContextEngineID != nil {
+		engineID = *req.ContextEngineID
+	}
+	if err := validateTransport(bindAddress, engineID); err != nil {
 		RespondBadRequest(c, err.Error())
 		return
 	}
 
 	device, err := h.deviceService.Update(c.Request.Context(), id, &req)
 	if err != nil {
-		RespondNotFound(c, "Device not found")
+		if errors.Is(err, service.ErrCredentialNotFound) {
+			RespondBadRequest(c, err.Error())
+			return
+		}
+		RespondDeviceNotFound(c)
 		return
 	}
 
-	// Notify poller about device update
-	if h.pollerService != nil {
-		h.pollerService.UpdateDevice(device)
-	}
-
 	RespondOK(c, device)
 }
 
@@ -98,16 +202,35 @@ func (h *DeviceHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
 
 	if err := h.deviceService.Delete(c.Request.Context(), id); err != nil {
-		RespondNotFound(c, "Device not found")
+		RespondDeviceNotFound(c)
 		return
 	}
 
-	// Notify poller to remove device
-	if h.pollerService != nil {
-		h.pollerService.RemoveDevice(id)
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// Enable starts polling and publishing a device
+func (h *DeviceHandler) Enable(c *gin.Context) {
+	h.setEnabled(c, true)
+}
+
+// Disable stops polling a device and removes its entities from Home Assistant
+func (h *DeviceHandler) Disable(c *gin.Context) {
+	h.setEnabled(c, false)
+}
+
+func (h *DeviceHandler) setEnabled(c *gin.Context, enabled bool) {
+	device, err := h.deviceService.SetEnabled(c.Request.Context(), c.Param("id"), enabled)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			RespondDeviceNotFound(c)
+			return
+		}
+		RespondServiceError(c, err)
+		return
 	}
 
-	c.JSON(http.StatusNoContent, nil)
+	RespondOK(c, device)
 }
 
 // TestConnection tests SNMP connection to an existing device
@@ -116,20 +239,26 @@ func (h *DeviceHandler) TestConnection(c *gin.Context) {
 
 	device, err := h.deviceService.GetByID(c.Request.Context(), id)
 	if err != nil {
-		RespondNotFound(c, "Device not found")
+		RespondDeviceNotFound(c)
 		return
 	}
 
 	req := &domain.TestConnectionRequest{
-		IPAddress:   device.IPAddress,
-		Port:        device.Port,
-		Community:   device.Community,
-		SNMPVersion: device.SNMPVersion,
+		IPAddress:       device.IPAddress,
+		Port:            device.Port,
+		Community:       device.Community,
+		SNMPVersion:     device.SNMPVersion,
+		BindAddress:     device.BindAddress,
+		ProxyHost:       device.ProxyHost,
+		ProxyPort:       device.ProxyPort,
+		ProxyCommunity:  device.ProxyCommunity,
+		ContextName:     device.ContextName,
+		ContextEngineID: device.ContextEngineID,
 	}
 
 	result, err := h.deviceService.TestConnection(c.Request.Context(), req)
 	if err != nil {
-		RespondInternalError(c, err.Error())
+		RespondServiceError(c, err)
 		return
 	}
 
@@ -140,19 +269,86 @@ func (h *DeviceHandler) TestConnection(c *gin.Context) {
 func (h *DeviceHandler) TestNewConnection(c *gin.Context) {
 	var req domain.TestConnectionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondBindError(c, err)
+		return
+	}
+	if err := validateTransport(req.BindAddress, req.ContextEngineID); err != nil {
 		RespondBadRequest(c, err.Error())
 		return
 	}
 
 	result, err := h.deviceService.TestConnection(c.Request.Context(), &req)
 	if err != nil {
-		RespondInternalError(c, err.Error())
+		RespondServiceError(c, err)
 		return
 	}
 
 	RespondOK(c, result)
 }
 
+// PreviewMapping polls a single OID mapping and returns its raw and
+// transformed value, for live previews in the profile editor
+func (h *DeviceHandler) PreviewMapping(c *gin.Context) {
+	if h.pollerService == nil {
+		RespondInternalError(c, "Poller service not available")
+		return
+	}
+
+	var mapping domain.OIDMapping
+	if err := c.ShouldBindJSON(&mapping); err != nil {
+		RespondBindError(c, err)
+		return
+	}
+
+	preview, err := h.pollerService.PreviewMapping(c.Request.Context(), c.Param("id"), &mapping)
+	switch {
+	case errors.Is(err, service.ErrInvalidOID):
+		RespondBadRequest(c, "Invalid OID")
+	case errors.Is(err, service.ErrDeviceNotFound):
+		RespondDeviceNotFound(c)
+	case err != nil:
+		RespondServiceError(c, err)
+	default:
+		RespondOK(c, preview)
+	}
+}
+
+// Snapshot walks a device and returns a sanitized snapshot of its objects as
+// a download, to attach to bug reports. Repeat ?oid= to walk other subtrees
+// than the system group and those of the profile.
+func (h *DeviceHandler) Snapshot(c *gin.Context) {
+	if h.pollerService == nil {
+		RespondInternalError(c, "Poller service not available")
+		return
+	}
+
+	snapshot, err := h.pollerService.Snapshot(c.Request.Context(), c.Param("id"), c.QueryArray("oid"))
+	switch {
+	case errors.Is(err, service.ErrInvalidOID):
+		RespondBadRequest(c, "Invalid OID")
+		return
+	case errors.Is(err, service.ErrDeviceNotFound):
+		RespondDeviceNotFound(c)
+		return
+	case err != nil:
+		RespondServiceError(c, err)
+		return
+	}
+
+	body, err := timefmt.Marshal(snapshot)
+	if err != nil {
+		RespondInternalError(c, "Failed to encode snapshot")
+		return
+	}
+	name := snapshot.ProfileID
+	if name == "" {
+		name = "device"
+	}
+	filename := fmt.Sprintf("snapshot-%s-%s.json", name, time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
 // GetState returns the current state of a device
 func (h *DeviceHandler) GetState(c *gin.Context) {
 	id := c.Param("id")
@@ -170,3 +366,69 @@ func (h *DeviceHandler) GetState(c *gin.Context) {
 
 	RespondOK(c, state)
 }
+
+// GetDiscovery returns the outcome of the last discovery publish of a device
+func (h *DeviceHandler) GetDiscovery(c *gin.Context) {
+	status, ok := h.discovery.DiscoveryStatus(c.Param("id"))
+	if !ok {
+		RespondServiceError(c, mqtt.ErrNotRegistered)
+		return
+	}
+
+	RespondOK(c, status)
+}
+
+// RetryDiscovery publishes the discovery configs of a device again
+func (h *DeviceHandler) RetryDiscovery(c *gin.Context) {
+	status, err := h.discovery.RetryDiscovery(c.Param("id"))
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondOK(c, status)
+}
+
+// GetOIDHealth reports which profile mappings of a device return data
+func (h *DeviceHandler) GetOIDHealth(c *gin.Context) {
+	if h.pollerService == nil {
+		RespondInternalError(c, "Poller service not available")
+		return
+	}
+
+	report, err := h.pollerService.OIDHealth(c.Param("id"))
+	if err != nil {
+		RespondNotFound(c, "Device is not being polled")
+		return
+	}
+
+	RespondOK(c, report)
+}
+
+func validateAlarms(alarms []domain.AlarmThreshold) error {
+	for _, alarm := range alarms {
+		if err := alarm.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateTransport checks the local bind address and SNMPv3 context engine
+// ID a device's requests are sent with
+func validateTransport(bindAddress, contextEngineID string) error {
+	if _, err := service.ResolveBindAddress(bindAddress); err != nil {
+		return err
+	}
+	_, err := domain.DecodeEngineID(contextEngineID)
+	return err
+}
+
+func validateCustomMappings(mappings []domain.OIDMapping) error {
+	for _, mapping := range mappings {
+		if err := mapping.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
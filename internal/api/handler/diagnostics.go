@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DiagnosticsHandler reports problems with what the bridge publishes
+type DiagnosticsHandler struct {
+	statistics *service.StatisticsAuditService
+}
+
+// NewDiagnosticsHandler creates a new diagnostics handler
+func NewDiagnosticsHandler(statistics *service.StatisticsAuditService) *DiagnosticsHandler {
+	return &DiagnosticsHandler{statistics: statistics}
+}
+
+// HAStatistics returns the published sensors Home Assistant keeps no
+// long-term statistics for, with the state class that would fix each
+func (h *DiagnosticsHandler) HAStatistics(c *gin.Context) {
+	report, err := h.statistics.Audit(c.Request.Context())
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+	RespondOK(c, report)
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/mqtt"
+	"snmp-mqtt-bridge/internal/repository"
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ErrorCode is the machine-readable reason carried by error responses
+type ErrorCode string
+
+const (
+	CodeValidation           ErrorCode = "VALIDATION_ERROR"
+	CodeUnauthorized         ErrorCode = "UNAUTHORIZED"
+	CodeNotFound             ErrorCode = "NOT_FOUND"
+	CodeDeviceNotFound       ErrorCode = "DEVICE_NOT_FOUND"
+	CodeConflict             ErrorCode = "CONFLICT"
+	CodeConfirmationRequired ErrorCode = "CONFIRMATION_REQUIRED"
+	CodePayloadTooLarge      ErrorCode = "PAYLOAD_TOO_LARGE"
+	CodeRateLimited          ErrorCode = "RATE_LIMITED"
+	CodeInternal             ErrorCode = "INTERNAL_ERROR"
+	CodeNotImplemented       ErrorCode = "NOT_IMPLEMENTED"
+	CodeSNMPError            ErrorCode = "SNMP_ERROR"   // The device answered with an error, or could not be reached
+	CodeSNMPTimeout          ErrorCode = "SNMP_TIMEOUT" // The device did not answer in time
+	CodeMQTTDisconnected     ErrorCode = "MQTT_DISCONNECTED"
+	CodeUnavailable          ErrorCode = "SERVICE_UNAVAILABLE"
+	CodeStandby              ErrorCode = "STANDBY" // This instance is the standby of a pair and accepts no changes
+
+	CodeCommandCooldown ErrorCode = "COMMAND_COOLDOWN" // The OID was written less than snmp.command_cooldown ago
+)
+
+// statusCodes is the code of errors responded with a status and no explicit code
+var statusCodes = map[int]ErrorCode{
+	http.StatusBadRequest:            CodeValidation,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusConflict:              CodeConflict,
+	http.StatusRequestEntityTooLarge: CodePayloadTooLarge,
+	http.StatusPreconditionRequired:  CodeConfirmationRequired,
+	http.StatusTooManyRequests:       CodeRateLimited,
+	http.StatusInternalServerError:   CodeInternal,
+	http.StatusNotImplemented:        CodeNotImplemented,
+	http.StatusBadGateway:            CodeSNMPError,
+	http.StatusServiceUnavailable:    CodeUnavailable,
+	http.StatusGatewayTimeout:        CodeSNMPTimeout,
+}
+
+// codeForStatus returns the default code of an error status
+func codeForStatus(status int) ErrorCode {
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeValidation
+}
+
+// classifyError maps an error returned by a service to a status, code and
+// message safe to show users. Unrecognized errors are internal: their
+// details are only logged.
+func classifyError(err error) (int, ErrorCode, string) {
+	var snmpErr *service.SNMPError
+	switch {
+	case errors.Is(err, service.ErrDeviceNotFound):
+		return http.StatusNotFound, CodeDeviceNotFound, "Device not found"
+	case errors.Is(err, repository.ErrNotFound),
+		errors.Is(err, service.ErrCredentialNotFound),
+		errors.Is(err, service.ErrActionNotFound),
+		errors.Is(err, mqtt.ErrNotRegistered):
+		return http.StatusNotFound, CodeNotFound, err.Error()
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return http.StatusConflict, CodeConflict, "Already exists"
+	case errors.Is(err, service.ErrProfileInUse),
+		errors.Is(err, service.ErrCredentialInUse),
+		errors.Is(err, service.ErrSelfTestRunning),
+		errors.Is(err, service.ErrVacuumRunning):
+		return http.StatusConflict, CodeConflict, err.Error()
+	case errors.Is(err, service.ErrConfirmationRequired):
+		return http.StatusPreconditionRequired, CodeConfirmationRequired, err.Error()
+	case errors.Is(err, service.ErrInvalidOID),
+		errors.Is(err, service.ErrInvalidCredential),
+		errors.Is(err, service.ErrInvalidScene),
+		errors.Is(err, service.ErrInvalidSeedList),
+		errors.Is(err, service.ErrUnknownChannel),
+		errors.Is(err, service.ErrInvalidLocale),
+		errors.Is(err, service.ErrInvalidUnit):
+		return http.StatusBadRequest, CodeValidation, err.Error()
+	case errors.Is(err, domain.ErrCapabilityUnsupported),
+		errors.Is(err, service.ErrSelfTestUnsupported):
+		return http.StatusNotImplemented, CodeNotImplemented, err.Error()
+	case errors.Is(err, service.ErrSNMPConnectionLimit),
+		errors.Is(err, service.ErrCommandQueueFull),
+		errors.Is(err, service.ErrCommandQueueStopped),
+		errors.Is(err, service.ErrWriteLockUnavailable):
+		return http.StatusServiceUnavailable, CodeUnavailable, err.Error()
+	case errors.Is(err, service.ErrCommandCooldown):
+		return http.StatusTooManyRequests, CodeCommandCooldown, err.Error()
+	case errors.Is(err, mqtt.ErrNotConnected):
+		return http.StatusServiceUnavailable, CodeMQTTDisconnected, "Not connected to the MQTT broker"
+	case errors.As(err, &snmpErr) && snmpErr.Timeout():
+		return http.StatusGatewayTimeout, CodeSNMPTimeout, "Device did not respond to SNMP in time"
+	case errors.As(err, &snmpErr):
+		// SNMP errors carry the device's error status, e.g. noAccess
+		return http.StatusBadGateway, CodeSNMPError, snmpErr.Error()
+	}
+	return http.StatusInternalServerError, CodeInternal, "Internal server error"
+}
+
+// RespondServiceError responds with the status, code and user-safe message of
+// an error returned by a service; the full error is logged for server errors
+func RespondServiceError(c *gin.Context, err error) {
+	status, code, message := classifyError(err)
+	c.Set(ErrorMessageKey, err.Error())
+	var cooldownErr *service.CooldownError
+	if errors.As(err, &cooldownErr) {
+		c.Header("Retry-After", strconv.Itoa(max(1, int(math.Ceil(cooldownErr.RetryAfter.Seconds())))))
+	}
+	if status >= http.StatusInternalServerError {
+		logRequestError(c, err.Error())
+	}
+	writeError(c, status, code, message)
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EventHandler handles device timeline HTTP requests
+type EventHandler struct {
+	eventService  *service.EventService
+	deviceService *service.DeviceService
+}
+
+// NewEventHandler creates a new event handler
+func NewEventHandler(eventService *service.EventService, deviceService *service.DeviceService) *EventHandler {
+	return &EventHandler{eventService: eventService, deviceService: deviceService}
+}
+
+// List returns events across all devices with pagination
+func (h *EventHandler) List(c *gin.Context) {
+	h.list(c, parseEventFilter(c, c.Query("device_id")))
+}
+
+// ListByDevice returns the event timeline of a single device
+func (h *EventHandler) ListByDevice(c *gin.Context) {
+	h.list(c, parseEventFilter(c, c.Param("id")))
+}
+
+func (h *EventHandler) list(c *gin.Context, filter domain.EventFilter) {
+	if site := c.Query("site"); site != "" {
+		ids, err := h.deviceService.DeviceIDsBySite(c.Request.Context(), site)
+		if err != nil {
+			RespondServiceError(c, err)
+			return
+		}
+		if len(ids) == 0 {
+			RespondWithMeta(c, []domain.DeviceEvent{}, 0, filter.Limit, filter.Offset)
+			return
+		}
+		filter.DeviceIDs = ids
+	}
+
+	events, total, err := h.eventService.GetAll(c.Request.Context(), filter)
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondWithMeta(c, events, total, filter.Limit, filter.Offset)
+}
+
+// Cleanup deletes old events
+func (h *EventHandler) Cleanup(c *gin.Context) {
+	days := 90
+	if daysStr := c.Query("days"); daysStr != "" {
+		if d, err := strconv.Atoi(daysStr); err == nil && d > 0 {
+			days = d
+		}
+	}
+
+	deleted, err := h.eventService.DeleteOlderThan(c.Request.Context(), days)
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"deleted": deleted,
+	})
+}
+
+func parseEventFilter(c *gin.Context, deviceID string) domain.EventFilter {
+	filter := domain.EventFilter{
+		DeviceID: deviceID,
+		Type:     domain.EventType(c.Query("type")),
+		Limit:    50,
+		Offset:   0,
+	}
+
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit > 0 {
+		filter.Limit = limit
+	}
+
+	if offset, err := strconv.Atoi(c.Query("offset")); err == nil && offset >= 0 {
+		filter.Offset = offset
+	}
+
+	if startStr := c.Query("start"); startStr != "" {
+		if t, err := time.Parse(time.RFC3339, startStr); err == nil {
+			filter.StartTime = &t
+		}
+	}
+
+	if endStr := c.Query("end"); endStr != "" {
+		if t, err := time.Parse(time.RFC3339, endStr); err == nil {
+			filter.EndTime = &t
+		}
+	}
+
+	return filter
+}
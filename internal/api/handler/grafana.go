@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// grafanaSeparator joins device names and metrics in target names
+const grafanaSeparator = " / "
+
+// GrafanaHandler implements the SimpleJSON datasource contract on top of the
+// state history, so Grafana can chart recent values without a time series
+// database. Targets are named "<device> / <metric>".
+type GrafanaHandler struct {
+	devices *service.DeviceService
+	history *service.StateHistoryService
+}
+
+// NewGrafanaHandler creates a Grafana datasource handler
+func NewGrafanaHandler(devices *service.DeviceService, history *service.StateHistoryService) *GrafanaHandler {
+	return &GrafanaHandler{devices: devices, history: history}
+}
+
+// GrafanaSearchRequest filters the targets offered by the query editor
+type GrafanaSearchRequest struct {
+	Target string `json:"target"`
+}
+
+// GrafanaQueryRequest is a SimpleJSON query
+type GrafanaQueryRequest struct {
+	Range struct {
+		From time.Time `json:"from" binding:"required"`
+		To   time.Time `json:"to" binding:"required"`
+	} `json:"range"`
+	MaxDataPoints int `json:"maxDataPoints"`
+	Targets       []struct {
+		Target string `json:"target"`
+		RefID  string `json:"refId"`
+	} `json:"targets"`
+}
+
+// GrafanaSeries is one time series of a query response; each datapoint is
+// [value, unix milliseconds]
+type GrafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// grafanaTarget is the series a target name refers to
+type grafanaTarget struct {
+	deviceID string
+	metric   string
+}
+
+// Test answers Grafana's connection test
+func (h *GrafanaHandler) Test(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// targets returns all recorded series by target name. Devices sharing a name
+// are told apart by their ID.
+func (h *GrafanaHandler) targets(c *gin.Context) (map[string]grafanaTarget, error) {
+	devices, err := h.devices.GetAll(c.Request.Context())
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(devices, func(i, j int) bool { return devices[i].CreatedAt.Before(devices[j].CreatedAt) })
+
+	names := make(map[string]bool, len(devices))
+	targets := make(map[string]grafanaTarget)
+	for i := range devices {
+		device := &devices[i]
+		name := device.Name
+		if names[name] {
+			name += " (" + device.ID + ")"
+		}
+		names[name] = true
+		for _, metric := range h.history.Metrics(device.ID) {
+			targets[name+grafanaSeparator+metric] = grafanaTarget{deviceID: device.ID, metric: metric}
+		}
+	}
+	return targets, nil
+}
+
+// Search lists the target names containing the requested text
+func (h *GrafanaHandler) Search(c *gin.Context) {
+	var req GrafanaSearchRequest
+	// Grafana may send an empty body to list everything
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			RespondBindError(c, err)
+			return
+		}
+	}
+
+	targets, err := h.targets(c)
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	filter := strings.ToLower(req.Target)
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		if strings.Contains(strings.ToLower(name), filter) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	c.JSON(http.StatusOK, names)
+}
+
+// Query returns the recorded points of each target in the time range,
+// averaged down to at most maxDataPoints per series
+func (h *GrafanaHandler) Query(c *gin.Context) {
+	var req GrafanaQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondBindError(c, err)
+		return
+	}
+
+	targets, err := h.targets(c)
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	series := make([]GrafanaSeries, 0, len(req.Targets))
+	for _, t := range req.Targets {
+		target, ok := targets[t.Target]
+		if !ok {
+			continue
+		}
+		points := h.history.Query(target.deviceID, target.metric, req.Range.From, req.Range.To)
+		points = downsample(points, req.MaxDataPoints)
+
+		datapoints := make([][2]float64, len(points))
+		for i, point := range points {
+			datapoints[i] = [2]float64{point.Value, float64(point.Time.UnixMilli())}
+		}
+		series = append(series, GrafanaSeries{Target: t.Target, Datapoints: datapoints})
+	}
+	c.JSON(http.StatusOK, series)
+}
+
+// downsample averages consecutive points into at most max points; max 0
+// keeps all of them
+func downsample(points []domain.HistoryPoint, max int) []domain.HistoryPoint {
+	if max <= 0 || len(points) <= max {
+		return points
+	}
+
+	result := make([]domain.HistoryPoint, 0, max)
+	for bucket := 0; bucket < max; bucket++ {
+		start := bucket * len(points) / max
+		end := (bucket + 1) * len(points) / max
+		if start == end {
+			continue
+		}
+		var sum float64
+		for _, point := range points[start:end] {
+			sum += point.Value
+		}
+		// Buckets are stamped with their last point
+		result = append(result, domain.HistoryPoint{Time: points[end-1].Time, Value: sum / float64(end-start)})
+	}
+	return result
+}
@@ -3,27 +3,44 @@ package handler
 import (
 	"net/http"
 
+	"snmp-mqtt-bridge/internal/buildinfo"
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+
 	"github.com/gin-gonic/gin"
 )
 
-type HealthHandler struct{}
+type HealthHandler struct {
+	database repository.HealthMonitor
+}
 
-func NewHealthHandler() *HealthHandler {
-	return &HealthHandler{}
+// NewHealthHandler creates a health handler; database may be nil
+func NewHealthHandler(database repository.HealthMonitor) *HealthHandler {
+	return &HealthHandler{database: database}
 }
 
 type HealthResponse struct {
-	Status  string `json:"status"`
-	Version string `json:"version"`
+	Status   string                 `json:"status"` // ok, or degraded while the database is unavailable
+	Version  string                 `json:"version"`
+	Database *domain.DatabaseStatus `json:"database,omitempty"`
 }
 
 func (h *HealthHandler) Health(c *gin.Context) {
-	c.JSON(http.StatusOK, HealthResponse{
+	response := HealthResponse{
 		Status:  "ok",
-		Version: "1.0.0",
-	})
+		Version: buildinfo.Get().Version,
+	}
+	if h.database != nil {
+		status := h.database.Status()
+		response.Database = &status
+		if !status.Healthy {
+			response.Status = "degraded"
+		}
+	}
+	c.JSON(http.StatusOK, response)
 }
 
+// Ready stays true during a database outage: polling and MQTT keep working
 func (h *HealthHandler) Ready(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"ready": true})
 }
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"snmp-mqtt-bridge/internal/service"
+	"snmp-mqtt-bridge/internal/timefmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InventoryHandler handles hardware inventory requests
+type InventoryHandler struct {
+	inventoryService *service.InventoryService
+}
+
+// NewInventoryHandler creates a new inventory handler
+func NewInventoryHandler(inventoryService *service.InventoryService) *InventoryHandler {
+	return &InventoryHandler{inventoryService: inventoryService}
+}
+
+// List returns the model, firmware, serial number and sysDescr of all
+// devices; ?format=csv downloads it as CSV and ?site= limits it to one site
+func (h *InventoryHandler) List(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		RespondBadRequest(c, "format must be json or csv")
+		return
+	}
+
+	items, err := h.inventoryService.List(c.Request.Context(), c.Query("site"))
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	if format == "json" {
+		RespondOK(c, items)
+		return
+	}
+
+	filename := fmt.Sprintf("inventory-%s.csv", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{
+		"name", "ip_address", "site", "location", "rack", "asset_tag", "profile_id", "manufacturer",
+		"model", "firmware", "serial", "sys_descr", "online", "updated_at", "device_id",
+	})
+	for _, item := range items {
+		updatedAt := ""
+		if item.UpdatedAt != nil {
+			updatedAt = timefmt.Format(*item.UpdatedAt)
+		}
+		w.Write(csvRow(
+			item.Name, item.IPAddress, item.Site, item.Location, item.Rack, item.AssetTag, item.ProfileID, item.Manufacturer,
+			item.Model, item.Firmware, item.Serial, item.SysDescr, strconv.FormatBool(item.Online), updatedAt, item.DeviceID,
+		))
+	}
+	w.Flush()
+}
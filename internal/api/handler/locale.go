@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LocaleHandler handles requests for the language entities are published in
+type LocaleHandler struct {
+	locale *service.LocaleService
+}
+
+// NewLocaleHandler creates a new locale handler
+func NewLocaleHandler(locale *service.LocaleService) *LocaleHandler {
+	return &LocaleHandler{locale: locale}
+}
+
+// SetLocaleRequest represents a request to select a locale
+type SetLocaleRequest struct {
+	Locale string `json:"locale" binding:"max=35"` // Empty for English
+}
+
+// Get returns the selected locale and the locales profiles are translated to
+func (h *LocaleHandler) Get(c *gin.Context) {
+	status, err := h.locale.Status(c.Request.Context())
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondOK(c, status)
+}
+
+// Set selects the locale entity names and enum labels are published in
+func (h *LocaleHandler) Set(c *gin.Context) {
+	var req SetLocaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondBindError(c, err)
+		return
+	}
+
+	status, err := h.locale.Set(c.Request.Context(), req.Locale)
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondOK(c, status)
+}
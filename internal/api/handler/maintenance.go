@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenanceHandler handles database maintenance requests
+type MaintenanceHandler struct {
+	maintenanceService *service.MaintenanceService
+}
+
+// NewMaintenanceHandler creates a new maintenance handler
+func NewMaintenanceHandler(maintenanceService *service.MaintenanceService) *MaintenanceHandler {
+	return &MaintenanceHandler{maintenanceService: maintenanceService}
+}
+
+// Status returns the database size and the last and next scheduled vacuum
+func (h *MaintenanceHandler) Status(c *gin.Context) {
+	status, err := h.maintenanceService.Status(c.Request.Context())
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondOK(c, status)
+}
+
+// Vacuum reclaims free database space and reports the size before and after
+func (h *MaintenanceHandler) Vacuum(c *gin.Context) {
+	result, err := h.maintenanceService.Vacuum(c.Request.Context())
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondOK(c, result)
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler handles notification channel requests
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(notificationService *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+
+// Status returns the notification policy and the settings and delivery
+// counters of every channel
+func (h *NotificationHandler) Status(c *gin.Context) {
+	channels, err := h.notificationService.Channels(c.Request.Context())
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	stats := h.notificationService.Stats()
+	result := make([]gin.H, 0, len(channels))
+	for _, channel := range channels {
+		result = append(result, gin.H{
+			"name":         channel.Name,
+			"enabled":      channel.Enabled,
+			"filter":       channel.Filter,
+			"max_per_hour": channel.MaxPerHour,
+			"stats":        stats[channel.Name],
+		})
+	}
+
+	policy, err := h.notificationService.Policy(c.Request.Context())
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondOK(c, gin.H{
+		"channels": result,
+		"policy": gin.H{
+			"quiet_hours":        policy.QuietHours,
+			"quiet_start":        formatClock(policy.QuietStart),
+			"quiet_end":          formatClock(policy.QuietEnd),
+			"quiet_min_severity": policy.QuietMinSeverity,
+			"in_quiet_hours":     policy.InQuietHours(time.Now()),
+			"dedup_minutes":      int(policy.DedupWindow / time.Minute),
+			"escalation_minutes": int(policy.EscalateAfter / time.Minute),
+			"stats":              h.notificationService.PolicyStats(),
+		},
+	})
+}
+
+// Test sends a test notification over one channel with the saved settings
+func (h *NotificationHandler) Test(c *gin.Context) {
+	err := h.notificationService.Test(c.Request.Context(), c.Param("channel"))
+	if errors.Is(err, service.ErrUnknownChannel) {
+		RespondNotFound(c, "Notification channel not found")
+		return
+	}
+	if err != nil {
+		RespondOK(c, gin.H{
+			"success": false,
+			"message": "Failed to send: " + err.Error(),
+		})
+		return
+	}
+
+	RespondOK(c, gin.H{
+		"success": true,
+		"message": "Test notification sent",
+	})
+}
+
+// formatClock formats an offset from midnight as "15:04"
+func formatClock(offset time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(offset/time.Hour), int(offset%time.Hour/time.Minute))
+}
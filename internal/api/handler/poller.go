@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PollerHandler reports the poller's progress
+type PollerHandler struct {
+	poller *service.PollerService
+}
+
+// NewPollerHandler creates a new poller handler
+func NewPollerHandler(poller *service.PollerService) *PollerHandler {
+	return &PollerHandler{poller: poller}
+}
+
+// Status returns how many devices were polled since startup and whether the
+// first polls are still being spread over the warmup window
+func (h *PollerHandler) Status(c *gin.Context) {
+	RespondOK(c, h.poller.Status())
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"snmp-mqtt-bridge/internal/domain"
@@ -23,7 +24,7 @@ func NewProfileHandler(profileService *service.ProfileService) *ProfileHandler {
 func (h *ProfileHandler) List(c *gin.Context) {
 	profiles, err := h.profileService.GetAll(c.Request.Context())
 	if err != nil {
-		RespondInternalError(c, err.Error())
+		RespondServiceError(c, err)
 		return
 	}
 
@@ -47,12 +48,12 @@ func (h *ProfileHandler) Get(c *gin.Context) {
 func (h *ProfileHandler) Create(c *gin.Context) {
 	var profile domain.Profile
 	if err := c.ShouldBindJSON(&profile); err != nil {
-		RespondBadRequest(c, err.Error())
+		RespondBindError(c, err)
 		return
 	}
 
 	if err := h.profileService.Create(c.Request.Context(), &profile); err != nil {
-		RespondInternalError(c, err.Error())
+		RespondServiceError(c, err)
 		return
 	}
 
@@ -65,7 +66,7 @@ func (h *ProfileHandler) Update(c *gin.Context) {
 
 	var profile domain.Profile
 	if err := c.ShouldBindJSON(&profile); err != nil {
-		RespondBadRequest(c, err.Error())
+		RespondBindError(c, err)
 		return
 	}
 
@@ -79,11 +80,25 @@ func (h *ProfileHandler) Update(c *gin.Context) {
 	RespondOK(c, profile)
 }
 
-// Delete deletes a profile
+// Delete deletes a profile. A profile devices still use is only deleted with
+// cascade=detach, which clears their profile, or cascade=delete, which
+// deletes them too.
 func (h *ProfileHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
 
-	if err := h.profileService.Delete(c.Request.Context(), id); err != nil {
+	cascade := service.ProfileCascade(c.Query("cascade"))
+	switch cascade {
+	case service.ProfileCascadeNone, service.ProfileCascadeDetach, service.ProfileCascadeDelete:
+	default:
+		RespondBadRequest(c, "cascade must be detach or delete")
+		return
+	}
+
+	if err := h.profileService.Delete(c.Request.Context(), id, cascade); err != nil {
+		if errors.Is(err, service.ErrProfileInUse) {
+			RespondError(c, http.StatusConflict, err.Error()+"; use cascade=detach or cascade=delete")
+			return
+		}
 		RespondNotFound(c, "Profile not found")
 		return
 	}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PublicStatusHandler serves the unauthenticated status endpoint. It exposes
+// device names, whether they answer polls and allowlisted metrics only: no
+// IDs, addresses or credentials.
+type PublicStatusHandler struct {
+	devices  *service.DeviceService
+	profiles *service.ProfileService
+	poller   *service.PollerService
+	metrics  []string
+}
+
+// NewPublicStatusHandler creates a public status handler exposing the given
+// mapping names
+func NewPublicStatusHandler(devices *service.DeviceService, profiles *service.ProfileService, poller *service.PollerService, metrics []string) *PublicStatusHandler {
+	return &PublicStatusHandler{devices: devices, profiles: profiles, poller: poller, metrics: metrics}
+}
+
+// PublicStatus is the public status of all enabled devices
+type PublicStatus struct {
+	Online    int                  `json:"online"`
+	Total     int                  `json:"total"`
+	Devices   []PublicDeviceStatus `json:"devices"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+// PublicDeviceStatus is the public status of one device
+type PublicDeviceStatus struct {
+	Name     string                  `json:"name"`
+	Online   bool                    `json:"online"`
+	LastPoll *time.Time              `json:"last_poll,omitempty"`
+	Metrics  map[string]PublicMetric `json:"metrics,omitempty"`
+}
+
+// PublicMetric is an allowlisted value of a device
+type PublicMetric struct {
+	Value interface{} `json:"value"`
+	Unit  string      `json:"unit,omitempty"`
+}
+
+// Status returns the public status of all enabled devices, sorted by name.
+// Any origin may read it, so status pages can fetch it from the browser.
+func (h *PublicStatusHandler) Status(c *gin.Context) {
+	devices, err := h.devices.GetEnabled(c.Request.Context())
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	profiles := make(map[string]*domain.Profile)
+	status := PublicStatus{Devices: make([]PublicDeviceStatus, 0, len(devices)), UpdatedAt: time.Now()}
+	for i := range devices {
+		device := &devices[i]
+		entry := PublicDeviceStatus{Name: device.Name}
+		if state := h.poller.GetDeviceState(device.ID); state != nil && !state.LastPoll.IsZero() {
+			lastPoll := state.LastPoll
+			entry.Online = state.Online
+			entry.LastPoll = &lastPoll
+			entry.Metrics = h.deviceMetrics(c, device, profiles)
+		}
+		if entry.Online {
+			status.Online++
+		}
+		status.Devices = append(status.Devices, entry)
+	}
+	status.Total = len(status.Devices)
+	sort.SliceStable(status.Devices, func(i, j int) bool { return status.Devices[i].Name < status.Devices[j].Name })
+
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Writer.Header().Del("Access-Control-Allow-Credentials")
+	c.JSON(http.StatusOK, status)
+}
+
+// deviceMetrics returns a device's allowlisted values with their units;
+// profiles caches profiles by ID across devices
+func (h *PublicStatusHandler) deviceMetrics(c *gin.Context, device *domain.Device, profiles map[string]*domain.Profile) map[string]PublicMetric {
+	if len(h.metrics) == 0 {
+		return nil
+	}
+
+	profile, cached := profiles[device.ProfileID]
+	if !cached && device.ProfileID != "" {
+		profile, _ = h.profiles.GetByID(c.Request.Context(), device.ProfileID)
+		profiles[device.ProfileID] = profile
+	}
+	profile = profile.ForDevice(device)
+
+	values := h.poller.GetDeviceValues(device.ID)
+	metrics := make(map[string]PublicMetric)
+	for _, name := range h.metrics {
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+		metric := PublicMetric{Value: value}
+		if profile != nil {
+			for j := range profile.OIDMappings {
+				if mapping := &profile.OIDMappings[j]; mapping.Name == name && mapping.PublishedUnit() != "" {
+					metric.Unit = mapping.PublishedUnit()
+					break
+				}
+			}
+		}
+		metrics[name] = metric
+	}
+	return metrics
+}
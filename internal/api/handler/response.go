@@ -1,17 +1,31 @@
 package handler
 
 import (
+	"log"
 	"net/http"
 
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/dryrun"
+	"snmp-mqtt-bridge/internal/requestid"
+
 	"github.com/gin-gonic/gin"
 )
 
+// ErrorMessageKey is the gin context key under which error responses store
+// their message for the access log
+const ErrorMessageKey = "error_message"
+
 // APIResponse is the standard API response wrapper
 type APIResponse struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
-	Meta    *Meta       `json:"meta,omitempty"`
+	Success   bool         `json:"success"`
+	Data      interface{}  `json:"data,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	Code      ErrorCode    `json:"code,omitempty"`       // Set on errors
+	Fields    []FieldError `json:"fields,omitempty"`     // Invalid request body fields
+	RequestID string       `json:"request_id,omitempty"` // Set on errors, to find them in the server logs
+	Meta      *Meta        `json:"meta,omitempty"`
+
+	DryRun *domain.DryRun `json:"dry_run,omitempty"` // SNMP SETs held back instead of sent
 }
 
 // Meta contains pagination metadata
@@ -21,11 +35,13 @@ type Meta struct {
 	Offset int   `json:"offset"`
 }
 
-// RespondOK sends a successful response with data
+// RespondOK sends a successful response with data, and the SNMP SETs a dry
+// run or read-only mode held back while handling the request
 func RespondOK(c *gin.Context, data interface{}) {
 	c.JSON(http.StatusOK, APIResponse{
 		Success: true,
 		Data:    data,
+		DryRun:  dryrun.FromContext(c.Request.Context()).Report(),
 	})
 }
 
@@ -37,6 +53,15 @@ func RespondCreated(c *gin.Context, data interface{}) {
 	})
 }
 
+// RespondAccepted sends a 202 response for work that continues in the
+// background
+func RespondAccepted(c *gin.Context, data interface{}) {
+	c.JSON(http.StatusAccepted, APIResponse{
+		Success: true,
+		Data:    data,
+	})
+}
+
 // RespondWithMeta sends a successful response with pagination metadata
 func RespondWithMeta(c *gin.Context, data interface{}, total int64, limit, offset int) {
 	c.JSON(http.StatusOK, APIResponse{
@@ -50,14 +75,36 @@ func RespondWithMeta(c *gin.Context, data interface{}, total int64, limit, offse
 	})
 }
 
-// RespondError sends an error response
+// RespondError sends an error response with the status's default code and
+// the request ID. Server errors are also logged with it.
 func RespondError(c *gin.Context, status int, message string) {
+	RespondErrorCode(c, status, codeForStatus(status), message)
+}
+
+// RespondErrorCode sends an error response with an explicit code
+func RespondErrorCode(c *gin.Context, status int, code ErrorCode, message string) {
+	c.Set(ErrorMessageKey, message)
+	if status >= http.StatusInternalServerError {
+		logRequestError(c, message)
+	}
+	writeError(c, status, code, message)
+}
+
+func writeError(c *gin.Context, status int, code ErrorCode, message string) {
 	c.JSON(status, APIResponse{
-		Success: false,
-		Error:   message,
+		Success:   false,
+		Error:     message,
+		Code:      code,
+		RequestID: requestid.FromContext(c.Request.Context()),
 	})
 }
 
+// logRequestError logs a server error with the request it failed
+func logRequestError(c *gin.Context, message string) {
+	log.Printf("Request %s %s %s failed: %s",
+		requestid.FromContext(c.Request.Context()), c.Request.Method, c.Request.URL.Path, message)
+}
+
 // RespondBadRequest sends a 400 error response
 func RespondBadRequest(c *gin.Context, message string) {
 	RespondError(c, http.StatusBadRequest, message)
@@ -68,6 +115,11 @@ func RespondNotFound(c *gin.Context, message string) {
 	RespondError(c, http.StatusNotFound, message)
 }
 
+// RespondDeviceNotFound sends a 404 error response for a missing device
+func RespondDeviceNotFound(c *gin.Context) {
+	RespondErrorCode(c, http.StatusNotFound, CodeDeviceNotFound, "Device not found")
+}
+
 // RespondInternalError sends a 500 error response
 func RespondInternalError(c *gin.Context, message string) {
 	RespondError(c, http.StatusInternalServerError, message)
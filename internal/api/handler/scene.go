@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SceneHandler handles scene-related HTTP requests
+type SceneHandler struct {
+	sceneService *service.SceneService
+}
+
+// NewSceneHandler creates a new scene handler
+func NewSceneHandler(sceneService *service.SceneService) *SceneHandler {
+	return &SceneHandler{sceneService: sceneService}
+}
+
+// List returns all scenes
+func (h *SceneHandler) List(c *gin.Context) {
+	scenes, err := h.sceneService.GetAll(c.Request.Context())
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondOK(c, scenes)
+}
+
+// Get returns a scene by ID
+func (h *SceneHandler) Get(c *gin.Context) {
+	scene, err := h.sceneService.GetByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		RespondNotFound(c, "Scene not found")
+		return
+	}
+
+	RespondOK(c, scene)
+}
+
+// Create creates a new scene
+func (h *SceneHandler) Create(c *gin.Context) {
+	var req domain.SceneCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondBindError(c, err)
+		return
+	}
+
+	scene, err := h.sceneService.Create(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidScene) {
+			RespondBadRequest(c, err.Error())
+			return
+		}
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondCreated(c, scene)
+}
+
+// Update updates an existing scene
+func (h *SceneHandler) Update(c *gin.Context) {
+	var req domain.SceneUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondBindError(c, err)
+		return
+	}
+
+	scene, err := h.sceneService.Update(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidScene) {
+			RespondBadRequest(c, err.Error())
+			return
+		}
+		RespondNotFound(c, "Scene not found")
+		return
+	}
+
+	RespondOK(c, scene)
+}
+
+// Delete deletes a scene
+func (h *SceneHandler) Delete(c *gin.Context) {
+	if err := h.sceneService.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		RespondNotFound(c, "Scene not found")
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// Run executes a scene and returns the result of every step
+func (h *SceneHandler) Run(c *gin.Context) {
+	run, err := h.sceneService.Run(c.Request.Context(), c.Param("id"), eventbus.CommandSourceAPI)
+	if err != nil {
+		RespondNotFound(c, "Scene not found")
+		return
+	}
+
+	RespondAccepted(c, run)
+}
+
+// GetRun returns the progress or result of a recent scene run
+func (h *SceneHandler) GetRun(c *gin.Context) {
+	run, err := h.sceneService.GetRun(c.Param("id"), c.Param("run_id"))
+	if err != nil {
+		RespondNotFound(c, "Scene run not found")
+		return
+	}
+
+	RespondOK(c, run)
+}
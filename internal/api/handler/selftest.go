@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"snmp-mqtt-bridge/internal/dryrun"
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SelfTestHandler handles UPS battery self-test requests
+type SelfTestHandler struct {
+	selfTestService *service.SelfTestService
+}
+
+// NewSelfTestHandler creates a new self-test handler
+func NewSelfTestHandler(selfTestService *service.SelfTestService) *SelfTestHandler {
+	return &SelfTestHandler{selfTestService: selfTestService}
+}
+
+// Status returns the self-test status and last result of a device
+func (h *SelfTestHandler) Status(c *gin.Context) {
+	status, err := h.selfTestService.Status(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		RespondDeviceNotFound(c)
+		return
+	}
+
+	RespondOK(c, status)
+}
+
+// Run triggers a battery self-test on a device
+func (h *SelfTestHandler) Run(c *gin.Context) {
+	err := h.selfTestService.Run(c.Request.Context(), c.Param("id"))
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrSelfTestUnsupported):
+		RespondBadRequest(c, err.Error())
+		return
+	case errors.Is(err, service.ErrSelfTestRunning):
+		RespondError(c, http.StatusConflict, err.Error())
+		return
+	default:
+		RespondServiceError(c, err)
+		return
+	}
+	if dryrun.FromContext(c.Request.Context()).Report() != nil {
+		RespondOK(c, gin.H{
+			"message": "Self-test trigger held back by dry run",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, APIResponse{
+		Success: true,
+		Data: gin.H{
+			"message": "Self-test started",
+		},
+	})
+}
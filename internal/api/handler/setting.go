@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"snmp-mqtt-bridge/internal/config"
+	"snmp-mqtt-bridge/internal/domain"
 	"snmp-mqtt-bridge/internal/mqtt"
 	"snmp-mqtt-bridge/internal/service"
 
@@ -17,6 +18,8 @@ type MQTTReconnector interface {
 	Reconnect(cfg *config.MQTTConfig) error
 	IsConnected() bool
 	GetConfig() *config.MQTTConfig
+	PublishStats() mqtt.PublishStats
+	ConnectionStats() mqtt.ConnectionStats
 }
 
 // SettingHandler handles setting-related HTTP requests
@@ -39,7 +42,7 @@ func (h *SettingHandler) SetMQTTClient(client *mqtt.Client) {
 func (h *SettingHandler) List(c *gin.Context) {
 	settings, err := h.settingService.GetAll(c.Request.Context())
 	if err != nil {
-		RespondInternalError(c, err.Error())
+		RespondServiceError(c, err)
 		return
 	}
 
@@ -58,7 +61,7 @@ func (h *SettingHandler) Get(c *gin.Context) {
 
 	value, err := h.settingService.Get(c.Request.Context(), key)
 	if err != nil {
-		RespondInternalError(c, err.Error())
+		RespondServiceError(c, err)
 		return
 	}
 
@@ -70,20 +73,28 @@ func (h *SettingHandler) Get(c *gin.Context) {
 	RespondOK(c, gin.H{"key": key, "value": value})
 }
 
+// writeLockSettingMessage answers attempts to change the write lock through
+// the settings
+const writeLockSettingMessage = "The write lock is switched with PUT /api/v1/bridge/lock"
+
 // Set creates or updates a setting
 func (h *SettingHandler) Set(c *gin.Context) {
 	key := c.Param("key")
+	if domain.IsWriteLockSetting(key) {
+		RespondBadRequest(c, writeLockSettingMessage)
+		return
+	}
 
 	var req struct {
 		Value string `json:"value" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		RespondBadRequest(c, err.Error())
+		RespondBindError(c, err)
 		return
 	}
 
 	if err := h.settingService.Set(c.Request.Context(), key, req.Value); err != nil {
-		RespondInternalError(c, err.Error())
+		RespondServiceError(c, err)
 		return
 	}
 
@@ -93,9 +104,13 @@ func (h *SettingHandler) Set(c *gin.Context) {
 // Delete deletes a setting
 func (h *SettingHandler) Delete(c *gin.Context) {
 	key := c.Param("key")
+	if domain.IsWriteLockSetting(key) {
+		RespondBadRequest(c, writeLockSettingMessage)
+		return
+	}
 
 	if err := h.settingService.Delete(c.Request.Context(), key); err != nil {
-		RespondInternalError(c, err.Error())
+		RespondServiceError(c, err)
 		return
 	}
 
@@ -112,7 +127,7 @@ func (h *SettingHandler) ReconnectMQTT(c *gin.Context) {
 	// Load MQTT settings from database
 	cfg, err := h.loadMQTTConfig(c.Request.Context())
 	if err != nil {
-		RespondInternalError(c, "Failed to load MQTT settings: "+err.Error())
+		RespondServiceError(c, err)
 		return
 	}
 
@@ -144,11 +159,19 @@ func (h *SettingHandler) GetMQTTStatus(c *gin.Context) {
 	}
 
 	cfg := h.mqttClient.GetConfig()
-	RespondOK(c, gin.H{
-		"connected": h.mqttClient.IsConnected(),
-		"broker":    cfg.Broker,
-		"port":      cfg.Port,
-	})
+	stats := h.mqttClient.PublishStats()
+	conn := h.mqttClient.ConnectionStats()
+	status := gin.H{
+		"connected":  h.mqttClient.IsConnected(),
+		"broker":     cfg.Broker,
+		"port":       cfg.Port,
+		"connection": conn,
+		"publish":    stats,
+	}
+	if conn.CollisionSuspected {
+		status["warning"] = mqtt.CollisionWarning(conn.ClientID)
+	}
+	RespondOK(c, status)
 }
 
 // TestMQTTConnection tests MQTT connection with provided settings (without saving)
@@ -161,7 +184,7 @@ func (h *SettingHandler) TestMQTTConnection(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		RespondBadRequest(c, err.Error())
+		RespondBindError(c, err)
 		return
 	}
 
@@ -200,6 +223,8 @@ func (h *SettingHandler) TestMQTTConnection(c *gin.Context) {
 
 // loadMQTTConfig loads MQTT configuration from database settings
 func (h *SettingHandler) loadMQTTConfig(ctx context.Context) (*config.MQTTConfig, error) {
+	// Settings only cover the connection; retention, keep-alive and the rest
+	// of the running configuration are kept
 	cfg := &config.MQTTConfig{
 		Broker:          "localhost",
 		Port:            1883,
@@ -208,6 +233,10 @@ func (h *SettingHandler) loadMQTTConfig(ctx context.Context) (*config.MQTTConfig
 		Discovery:       true,
 		DiscoveryPrefix: "homeassistant",
 	}
+	if h.mqttClient != nil {
+		running := *h.mqttClient.GetConfig()
+		cfg = &running
+	}
 
 	if broker, _ := h.settingService.Get(ctx, "mqtt.broker"); broker != "" {
 		cfg.Broker = broker
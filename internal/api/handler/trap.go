@@ -1,32 +1,150 @@
 package handler
 
 import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"snmp-mqtt-bridge/internal/domain"
 	"snmp-mqtt-bridge/internal/service"
+	"snmp-mqtt-bridge/internal/timefmt"
 
 	"github.com/gin-gonic/gin"
 )
 
+// TrapInjector runs synthesized traps through the trap receiver pipeline
+type TrapInjector interface {
+	InjectTrap(ctx context.Context, deviceID, trapOID string, variables domain.TrapVariables) (*domain.TrapLog, error)
+}
+
 // TrapHandler handles trap-related HTTP requests
 type TrapHandler struct {
-	trapService *service.TrapLogService
+	trapService   *service.TrapLogService
+	deviceService *service.DeviceService
+	injector      TrapInjector
 }
 
 // NewTrapHandler creates a new trap handler
-func NewTrapHandler(trapService *service.TrapLogService) *TrapHandler {
-	return &TrapHandler{trapService: trapService}
+func NewTrapHandler(trapService *service.TrapLogService, deviceService *service.DeviceService) *TrapHandler {
+	return &TrapHandler{trapService: trapService, deviceService: deviceService}
+}
+
+// SetInjector enables test trap injection
+func (h *TrapHandler) SetInjector(injector TrapInjector) {
+	h.injector = injector
 }
 
 // List returns all trap logs with pagination
 func (h *TrapHandler) List(c *gin.Context) {
+	filter, ok, err := h.filterFromQuery(c, 50)
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+	if !ok {
+		RespondWithMeta(c, []domain.TrapLog{}, 0, filter.Limit, filter.Offset)
+		return
+	}
+
+	traps, total, err := h.trapService.GetAll(c.Request.Context(), filter)
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondWithMeta(c, traps, total, filter.Limit, filter.Offset)
+}
+
+// Export streams trap logs matching the list filters as CSV or NDJSON
+// (?format=csv|ndjson). Without ?limit= the whole history is exported.
+func (h *TrapHandler) Export(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "ndjson" {
+		RespondBadRequest(c, "format must be csv or ndjson")
+		return
+	}
+
+	filter, ok, err := h.filterFromQuery(c, 0)
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("traps-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	var write func(*domain.TrapLog) error
+	var flush func()
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		w := csv.NewWriter(c.Writer)
+		w.Write([]string{"received_at", "severity", "device_id", "source_ip", "trap_oid", "message", "variables", "id"})
+		write = func(trap *domain.TrapLog) error {
+			deviceID := ""
+			if trap.DeviceID != nil {
+				deviceID = *trap.DeviceID
+			}
+			variables, err := json.Marshal(trap.Variables)
+			if err != nil {
+				return err
+			}
+			return w.Write(csvRow(
+				timefmt.Format(trap.ReceivedAt), string(trap.Severity), deviceID, trap.SourceIP,
+				trap.TrapOID, trap.Message, string(variables), trap.ID,
+			))
+		}
+		flush = w.Flush
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+		write = func(trap *domain.TrapLog) error {
+			line, err := timefmt.Marshal(trap)
+			if err != nil {
+				return err
+			}
+			_, err = c.Writer.Write(append(line, '\n'))
+			return err
+		}
+		flush = func() {}
+	}
+	c.Status(http.StatusOK)
+
+	if ok {
+		var n int
+		err = h.trapService.Stream(c.Request.Context(), filter, func(trap *domain.TrapLog) error {
+			if err := write(trap); err != nil {
+				return err
+			}
+			// Push rows out in chunks rather than buffering the whole export
+			if n++; n%100 == 0 {
+				flush()
+				c.Writer.Flush()
+			}
+			return nil
+		})
+		if err != nil {
+			// Headers are already sent, so the client only sees a truncated file
+			log.Printf("Trap export aborted: %v", err)
+		}
+	}
+	flush()
+	c.Writer.Flush()
+}
+
+// filterFromQuery builds a trap filter from the device_id, severity, start,
+// end, site, limit and offset query parameters. ok is false when ?site=
+// matches no devices, so no trap can match either.
+func (h *TrapHandler) filterFromQuery(c *gin.Context, defaultLimit int) (domain.TrapFilter, bool, error) {
 	filter := domain.TrapFilter{
 		DeviceID: c.Query("device_id"),
 		Severity: domain.TrapSeverity(c.Query("severity")),
-		Limit:    50,
+		Limit:    defaultLimit,
 		Offset:   0,
 	}
 
@@ -50,13 +168,70 @@ func (h *TrapHandler) List(c *gin.Context) {
 		}
 	}
 
-	traps, total, err := h.trapService.GetAll(c.Request.Context(), filter)
+	if site := c.Query("site"); site != "" {
+		ids, err := h.deviceService.DeviceIDsBySite(c.Request.Context(), site)
+		if err != nil {
+			return filter, false, err
+		}
+		if len(ids) == 0 {
+			return filter, false, nil
+		}
+		filter.DeviceIDs = ids
+	}
+
+	return filter, true, nil
+}
+
+// Stats returns trap counts by severity, device and trap OID over a window
+// (?window=24h by default; Go durations or days such as 7d)
+func (h *TrapHandler) Stats(c *gin.Context) {
+	window := 24 * time.Hour
+	if w := c.Query("window"); w != "" {
+		parsed, err := parseWindow(w)
+		if err != nil {
+			RespondBadRequest(c, "Invalid window: "+w)
+			return
+		}
+		window = parsed
+	}
+
+	var devices []domain.Device
+	var err error
+	site := c.Query("site")
+	if site != "" {
+		devices, err = h.deviceService.GetBySite(c.Request.Context(), site)
+	} else {
+		devices, err = h.deviceService.GetAll(c.Request.Context())
+	}
 	if err != nil {
-		RespondInternalError(c, err.Error())
+		RespondServiceError(c, err)
 		return
 	}
 
-	RespondWithMeta(c, traps, total, filter.Limit, filter.Offset)
+	stats, err := h.trapService.Stats(c.Request.Context(), time.Now().Add(-window), devices, site != "")
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondOK(c, stats)
+}
+
+// parseWindow parses a Go duration, also accepting whole days ("7d")
+func parseWindow(s string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+	if err == nil && d <= 0 {
+		err = strconv.ErrRange
+	}
+	return d, err
 }
 
 // Get returns a trap by ID
@@ -83,7 +258,7 @@ func (h *TrapHandler) Cleanup(c *gin.Context) {
 
 	deleted, err := h.trapService.DeleteOlderThan(c.Request.Context(), days)
 	if err != nil {
-		RespondInternalError(c, err.Error())
+		RespondServiceError(c, err)
 		return
 	}
 
@@ -92,3 +267,54 @@ func (h *TrapHandler) Cleanup(c *gin.Context) {
 		"deleted": deleted,
 	})
 }
+
+// Test injects a synthesized trap so severity mapping, MQTT publishing and
+// notifications can be checked without faulting real hardware
+func (h *TrapHandler) Test(c *gin.Context) {
+	if h.injector == nil {
+		RespondError(c, http.StatusServiceUnavailable, "Trap receiver not available")
+		return
+	}
+
+	var req domain.TestTrapRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondBindError(c, err)
+		return
+	}
+
+	if req.DeviceID != "" {
+		if _, err := h.deviceService.GetByID(c.Request.Context(), req.DeviceID); err != nil {
+			RespondDeviceNotFound(c)
+			return
+		}
+	}
+
+	// JSON numbers decode as float64 while SNMP integers arrive as int, which
+	// profile enums are keyed on
+	for oid, value := range req.Variables {
+		if f, ok := value.(float64); ok && f == math.Trunc(f) && math.Abs(f) <= math.MaxInt32 {
+			req.Variables[oid] = int(f)
+		}
+	}
+
+	trap, err := h.injector.InjectTrap(c.Request.Context(), req.DeviceID, req.TrapOID, req.Variables)
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondCreated(c, trap)
+}
+
+// csvRow returns the cells of an exported CSV row, with a ' put before those
+// a spreadsheet would run as a formula. Trap messages and variables come from
+// the network, so a crafted trap could otherwise run one on the reader's
+// machine.
+func csvRow(cells ...string) []string {
+	for i, cell := range cells {
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			cells[i] = "'" + cell
+		}
+	}
+	return cells
+}
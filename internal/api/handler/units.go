@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UnitHandler handles requests for the units values are published in
+type UnitHandler struct {
+	units *service.UnitService
+}
+
+// NewUnitHandler creates a new unit handler
+func NewUnitHandler(units *service.UnitService) *UnitHandler {
+	return &UnitHandler{units: units}
+}
+
+// SetUnitsRequest represents a request to change the published units
+type SetUnitsRequest struct {
+	Temperature string `json:"temperature" binding:"max=16"` // C or F; empty for the configured unit
+}
+
+// Get returns the units values are published in
+func (h *UnitHandler) Get(c *gin.Context) {
+	RespondOK(c, h.units.Preferences())
+}
+
+// Set changes the unit temperatures are published in
+func (h *UnitHandler) Set(c *gin.Context) {
+	var req SetUnitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondBindError(c, err)
+		return
+	}
+
+	preferences, err := h.units.SetTemperature(c.Request.Context(), req.Temperature)
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondOK(c, preferences)
+}
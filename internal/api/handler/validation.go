@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/requestid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes one invalid field of a request body, so the UI can
+// highlight it
+type FieldError struct {
+	Field   string `json:"field"` // JSON path, e.g. custom_mappings[0].oid
+	Rule    string `json:"rule"`  // Failed rule, e.g. required or oid
+	Message string `json:"message"`
+}
+
+var registerOnce sync.Once
+
+// RegisterValidators adds the bridge's rules to gin's binding validator:
+//
+//	oid            numeric OID, e.g. .1.3.6.1.2.1.1.5.0
+//	port           UDP port 1-65535
+//	poll_interval  0 for the default, or at least domain.MinPollInterval seconds
+//
+// and checks that SNMPv3-only settings come with snmp_version v3. Field
+// errors are reported by their JSON names.
+func RegisterValidators() {
+	registerOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+		v.RegisterValidation("oid", func(fl validator.FieldLevel) bool {
+			return domain.ValidOID(fl.Field().String())
+		})
+		v.RegisterValidation("port", func(fl validator.FieldLevel) bool {
+			port := fl.Field().Int()
+			return port >= 1 && port <= 65535
+		})
+		v.RegisterValidation("poll_interval", func(fl validator.FieldLevel) bool {
+			seconds := fl.Field().Int()
+			return seconds == 0 || seconds >= domain.MinPollInterval
+		})
+		v.RegisterStructValidation(validateDeviceCreate, domain.DeviceCreateRequest{})
+		v.RegisterStructValidation(validateTestConnection, domain.TestConnectionRequest{})
+	})
+}
+
+// validateDeviceCreate rejects SNMPv3 context settings on v1/v2c devices;
+// devices using a credential set take their version from it
+func validateDeviceCreate(sl validator.StructLevel) {
+	req := sl.Current().Interface().(domain.DeviceCreateRequest)
+	if req.CredentialID == "" {
+		checkV3Only(sl, req.SNMPVersion, req.ContextName, req.ContextEngineID)
+	}
+	checkProxy(sl, req.ProxyHost, req.ProxyPort, req.ProxyCommunity)
+}
+
+func validateTestConnection(sl validator.StructLevel) {
+	req := sl.Current().Interface().(domain.TestConnectionRequest)
+	checkV3Only(sl, req.SNMPVersion, req.ContextName, req.ContextEngineID)
+	checkProxy(sl, req.ProxyHost, req.ProxyPort, req.ProxyCommunity)
+}
+
+func checkV3Only(sl validator.StructLevel, version domain.SNMPVersion, contextName, contextEngineID string) {
+	if version == domain.SNMPv3 {
+		return
+	}
+	if contextName != "" {
+		sl.ReportError(contextName, "context_name", "ContextName", "snmp_v3", "")
+	}
+	if contextEngineID != "" {
+		sl.ReportError(contextEngineID, "context_engine_id", "ContextEngineID", "snmp_v3", "")
+	}
+}
+
+// checkProxy rejects proxy settings without a proxy host
+func checkProxy(sl validator.StructLevel, host string, port int, community string) {
+	if host != "" {
+		return
+	}
+	if port != 0 {
+		sl.ReportError(port, "proxy_port", "ProxyPort", "proxy_host", "")
+	}
+	if community != "" {
+		sl.ReportError(community, "proxy_community", "ProxyCommunity", "proxy_host", "")
+	}
+}
+
+// fieldMessage explains a failed rule to users
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_without":
+		return fmt.Sprintf("is required unless %s is set", jsonName(fe.Param()))
+	case "oid":
+		return "must be a numeric OID, e.g. .1.3.6.1.2.1.1.5.0"
+	case "port":
+		return "must be a port between 1 and 65535"
+	case "poll_interval":
+		return fmt.Sprintf("must be 0 for the default, or at least %d seconds", domain.MinPollInterval)
+	case "snmp_v3":
+		return "is only supported with snmp_version v3"
+	case "proxy_host":
+		return "requires proxy_host"
+	case "ip":
+		return "must be an IP address"
+	case "hostname|ip":
+		return "must be a host name or IP address"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "min":
+		return "must be at least " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param()
+	}
+	return "is invalid (" + fe.Tag() + ")"
+}
+
+// jsonName converts a Go field name used in a rule parameter, e.g.
+// CredentialID, to its JSON name, e.g. credential_id
+func jsonName(field string) string {
+	var b strings.Builder
+	runes := []rune(field)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			// A new word starts after a lowercase letter, or at the last
+			// capital of an acronym followed by a lowercase letter
+			if unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// fieldErrors converts validation errors into field errors; ok is false when
+// err is not a validation error, e.g. malformed JSON
+func fieldErrors(err error) ([]FieldError, bool) {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return nil, false
+	}
+	fields := make([]FieldError, 0, len(errs))
+	for _, fe := range errs {
+		// Drop the request type from the namespace
+		_, field, found := strings.Cut(fe.Namespace(), ".")
+		if !found {
+			field = fe.Field()
+		}
+		fields = append(fields, FieldError{Field: field, Rule: fe.Tag(), Message: fieldMessage(fe)})
+	}
+	return fields, true
+}
+
+// RespondBindError responds to a request body that failed to bind. Failed
+// validation rules are listed per field.
+func RespondBindError(c *gin.Context, err error) {
+	fields, ok := fieldErrors(err)
+	if !ok {
+		RespondBadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Field + " " + field.Message
+	}
+	message := strings.Join(messages, "; ")
+	c.Set(ErrorMessageKey, message)
+	c.JSON(http.StatusBadRequest, APIResponse{
+		Success:   false,
+		Error:     message,
+		Code:      CodeValidation,
+		Fields:    fields,
+		RequestID: requestid.FromContext(c.Request.Context()),
+	})
+}
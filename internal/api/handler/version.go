@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"snmp-mqtt-bridge/internal/buildinfo"
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VersionHandler reports the running build and the last release check
+type VersionHandler struct {
+	updateChecker *service.UpdateChecker
+}
+
+// NewVersionHandler creates a version handler; updateChecker may be nil
+func NewVersionHandler(updateChecker *service.UpdateChecker) *VersionHandler {
+	return &VersionHandler{updateChecker: updateChecker}
+}
+
+// VersionResponse is the build information plus the update status when
+// release checks are enabled
+type VersionResponse struct {
+	buildinfo.Info
+	Update *domain.UpdateStatus `json:"update,omitempty"`
+}
+
+// Get returns the running version
+func (h *VersionHandler) Get(c *gin.Context) {
+	response := VersionResponse{Info: buildinfo.Get()}
+	if h.updateChecker != nil {
+		status := h.updateChecker.Status()
+		response.Update = &status
+	}
+	RespondOK(c, response)
+}
@@ -1,61 +1,201 @@
 package handler
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
 	"snmp-mqtt-bridge/internal/service"
+	"snmp-mqtt-bridge/internal/timefmt"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins for development
-	},
-}
+// wsAuthTimeout is how long a client may take to send its auth message
+const wsAuthTimeout = 10 * time.Second
 
 // WebSocketHandler handles WebSocket connections for real-time updates
 type WebSocketHandler struct {
 	pollerService *service.PollerService
+	bus           *eventbus.Bus
+	upgrader      websocket.Upgrader
+	allowAll      bool            // Any origin may connect
+	origins       map[string]bool // Origins allowed besides the server's own
+	token         string          // Required from clients when set
 	clients       map[*websocket.Conn]bool
 	mu            sync.RWMutex
 	broadcast     chan []byte
+	done          chan struct{}
+	closeOnce     sync.Once
+	wg            sync.WaitGroup
 }
 
 // NewWebSocketHandler creates a new WebSocket handler
-func NewWebSocketHandler(pollerService *service.PollerService) *WebSocketHandler {
+func NewWebSocketHandler(pollerService *service.PollerService, bus *eventbus.Bus) *WebSocketHandler {
 	h := &WebSocketHandler{
 		pollerService: pollerService,
+		bus:           bus,
 		clients:       make(map[*websocket.Conn]bool),
 		broadcast:     make(chan []byte, 256),
+		done:          make(chan struct{}),
+	}
+	h.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     h.checkOrigin,
 	}
 
 	// Start broadcast handler
+	h.wg.Add(1)
 	go h.handleBroadcasts()
 
-	// Subscribe to poller events if available
-	if pollerService != nil {
-		go h.subscribeToPollerEvents()
+	// Forward bus events to connected clients
+	if bus != nil {
+		h.wg.Add(1)
+		go h.subscribeToEvents()
 	}
 
 	return h
 }
 
+// Close stops broadcasting and disconnects all clients with a going-away
+// close frame. Connections upgraded by the HTTP server are not closed by
+// http.Server.Shutdown, so this must be called on shutdown.
+func (h *WebSocketHandler) Close() {
+	h.closeOnce.Do(func() {
+		close(h.done)
+		h.wg.Wait()
+
+		message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+		deadline := time.Now().Add(time.Second)
+
+		h.mu.Lock()
+		for client := range h.clients {
+			client.WriteControl(websocket.CloseMessage, message, deadline)
+			client.Close()
+			delete(h.clients, client)
+		}
+		h.mu.Unlock()
+	})
+}
+
+// SetAllowedOrigins limits the browser origins allowed to connect; "*" allows
+// any. Pages served by the bridge itself are always allowed.
+func (h *WebSocketHandler) SetAllowedOrigins(origins []string) {
+	h.allowAll = false
+	h.origins = make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		if origin == "*" {
+			h.allowAll = true
+		}
+		h.origins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+}
+
+// SetToken requires clients to present token, either as the token query
+// parameter, as a bearer Authorization header or in an auth message sent
+// first: {"type": "auth", "token": "..."}
+func (h *WebSocketHandler) SetToken(token string) {
+	h.token = token
+}
+
+func (h *WebSocketHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	// Requests without an Origin header do not come from browsers
+	if origin == "" || h.allowAll {
+		return true
+	}
+	if h.origins[strings.ToLower(strings.TrimSuffix(origin, "/"))] {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// validToken compares a presented token with the configured one in constant time
+func (h *WebSocketHandler) validToken(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+// requestToken returns the token sent with the upgrade request, if any
+func requestToken(c *gin.Context) string {
+	if token := c.Query("token"); token != "" {
+		return token
+	}
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return ""
+}
+
+// authenticate waits for the client's auth message and reports whether it
+// carries the configured token
+func (h *WebSocketHandler) authenticate(conn *websocket.Conn) bool {
+	conn.SetReadDeadline(time.Now().Add(wsAuthTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		return false
+	}
+	var msg struct {
+		Type  string `json:"type"`
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(message, &msg); err != nil || msg.Type != "auth" {
+		return false
+	}
+	return h.validToken(msg.Token)
+}
+
 // HandleWebSocket upgrades HTTP connection to WebSocket
 func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	select {
+	case <-h.done:
+		c.AbortWithStatus(http.StatusServiceUnavailable)
+		return
+	default:
+	}
+
+	// A token sent with the request is checked before upgrading
+	token := requestToken(c)
+	if h.token != "" && token != "" && !h.validToken(token) {
+		RespondError(c, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
 		return
 	}
 
+	// Browsers cannot set headers on WebSocket requests, so the token may
+	// also come in the first message
+	if h.token != "" && token == "" {
+		if !h.authenticate(conn) {
+			message := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "unauthorized")
+			conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+			conn.Close()
+			log.Printf("WebSocket client %s failed to authenticate", c.ClientIP())
+			return
+		}
+		data, _ := json.Marshal(map[string]string{"type": "auth_ok"})
+		conn.WriteMessage(websocket.TextMessage, data)
+	}
+
 	h.mu.Lock()
 	h.clients[conn] = true
 	h.mu.Unlock()
@@ -75,7 +215,7 @@ func (h *WebSocketHandler) handleClient(conn *websocket.Conn) {
 		conn.Close()
 	}()
 
-	conn.SetReadLimit(512)
+	conn.SetReadLimit(4096) // Room for preview_mapping requests
 	conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	conn.SetPongHandler(func(string) error {
 		conn.SetReadDeadline(time.Now().Add(60 * time.Second))
@@ -107,12 +247,16 @@ func (h *WebSocketHandler) handleMessage(conn *websocket.Conn, message []byte) {
 	}
 
 	switch msg.Type {
+	case "auth":
+		// Already authenticated, or no token is required
 	case "ping":
 		response := map[string]string{"type": "pong"}
 		data, _ := json.Marshal(response)
 		conn.WriteMessage(websocket.TextMessage, data)
 	case "subscribe":
 		// Handle subscription to specific device updates
+	case "preview_mapping":
+		h.previewMapping(conn, msg.Data)
 	}
 }
 
@@ -127,7 +271,7 @@ func (h *WebSocketHandler) sendInitialState(conn *websocket.Conn) {
 		"data": states,
 	}
 
-	data, err := json.Marshal(msg)
+	data, err := timefmt.Marshal(msg)
 	if err != nil {
 		return
 	}
@@ -136,11 +280,16 @@ func (h *WebSocketHandler) sendInitialState(conn *websocket.Conn) {
 }
 
 func (h *WebSocketHandler) handleBroadcasts() {
+	defer h.wg.Done()
+
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
 	for {
 		select {
+		case <-h.done:
+			return
+
 		case message := <-h.broadcast:
 			h.mu.RLock()
 			for client := range h.clients {
@@ -165,20 +314,42 @@ func (h *WebSocketHandler) handleBroadcasts() {
 	}
 }
 
-func (h *WebSocketHandler) subscribeToPollerEvents() {
-	if h.pollerService == nil {
-		return
-	}
+func (h *WebSocketHandler) subscribeToEvents() {
+	defer h.wg.Done()
+
+	sub := h.bus.Subscribe(
+		eventbus.TypeStateUpdate,
+		eventbus.TypeTrapReceived,
+		eventbus.TypeDeviceCreated,
+		eventbus.TypeDeviceUpdated,
+		eventbus.TypeDeviceDeleted,
+		eventbus.TypeMQTTStatus,
+		eventbus.TypeCommand,
+		eventbus.TypeDeviceEvent,
+		eventbus.TypeSceneRun,
+		eventbus.TypeWriteLock,
+		eventbus.TypeDiscovery,
+	)
+	defer h.bus.Unsubscribe(sub)
 
-	eventChan := h.pollerService.Subscribe()
+	for {
+		var evt eventbus.Event
+		var ok bool
+		select {
+		case <-h.done:
+			return
+		case evt, ok = <-sub.C:
+			if !ok {
+				return
+			}
+		}
 
-	for event := range eventChan {
 		msg := map[string]interface{}{
-			"type": "state_update",
-			"data": event,
+			"type": evt.Type,
+			"data": evt.Payload,
 		}
 
-		data, err := json.Marshal(msg)
+		data, err := timefmt.Marshal(msg)
 		if err != nil {
 			continue
 		}
@@ -199,3 +370,40 @@ func (h *WebSocketHandler) Broadcast(message []byte) {
 		// Channel full, skip
 	}
 }
+
+// previewMapping answers a preview_mapping message with the live value of an
+// OID mapping. The client's id is echoed so out-of-order replies to
+// previews sent while typing can be discarded.
+func (h *WebSocketHandler) previewMapping(conn *websocket.Conn, data json.RawMessage) {
+	var req struct {
+		ID       string            `json:"id"`
+		DeviceID string            `json:"device_id"`
+		Mapping  domain.OIDMapping `json:"mapping"`
+	}
+	reply := map[string]interface{}{"type": "mapping_preview"}
+
+	if err := json.Unmarshal(data, &req); err != nil {
+		reply["error"] = "invalid preview request"
+	} else if h.pollerService == nil {
+		reply["error"] = "poller service not available"
+	} else {
+		reply["id"] = req.ID
+		preview, err := h.pollerService.PreviewMapping(context.Background(), req.DeviceID, &req.Mapping)
+		if err != nil {
+			reply["error"] = err.Error()
+		} else {
+			reply["data"] = preview
+		}
+	}
+
+	message, err := json.Marshal(reply)
+	if err != nil {
+		return
+	}
+
+	// Broadcasts write under the read lock; gorilla connections allow only
+	// one concurrent writer
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	conn.WriteMessage(websocket.TextMessage, message)
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebSocketCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{"no origin header", nil, "", true},
+		{"same origin", nil, "http://bridge.local:8080", true},
+		{"other origin by default", nil, "https://evil.example", false},
+		{"listed origin", []string{"https://ha.example/"}, "https://HA.example", true},
+		{"unlisted origin", []string{"https://ha.example"}, "https://evil.example", false},
+		{"wildcard", []string{"*"}, "https://evil.example", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &WebSocketHandler{}
+			if tt.allowed != nil {
+				h.SetAllowedOrigins(tt.allowed)
+			}
+			r := httptest.NewRequest("GET", "http://bridge.local:8080/api/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := h.checkOrigin(r); got != tt.want {
+				t.Errorf("checkOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WriteLockHandler handles requests for the bridge-wide write lock
+type WriteLockHandler struct {
+	writeLock *service.WriteLockService
+}
+
+// NewWriteLockHandler creates a new write lock handler
+func NewWriteLockHandler(writeLock *service.WriteLockService) *WriteLockHandler {
+	return &WriteLockHandler{writeLock: writeLock}
+}
+
+// SetWriteLockRequest represents a request to take or release the write lock
+type SetWriteLockRequest struct {
+	Locked *bool  `json:"locked" binding:"required"`
+	Reason string `json:"reason,omitempty" binding:"max=256"`
+}
+
+// Get returns whether the write lock is held, why and since when
+func (h *WriteLockHandler) Get(c *gin.Context) {
+	lock, err := h.writeLock.Status(c.Request.Context())
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondOK(c, lock)
+}
+
+// Set takes or releases the write lock
+func (h *WriteLockHandler) Set(c *gin.Context) {
+	var req SetWriteLockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondBindError(c, err)
+		return
+	}
+
+	lock, err := h.writeLock.Set(c.Request.Context(), *req.Locked, req.Reason)
+	if err != nil {
+		RespondServiceError(c, err)
+		return
+	}
+
+	RespondOK(c, lock)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"snmp-mqtt-bridge/internal/api/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimiter is a per-client token bucket limiter
+type rateLimiter struct {
+	rate  float64 // tokens added per second
+	burst float64 // bucket capacity
+
+	buckets     map[string]*tokenBucket
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// bucketIdleTTL is how long an idle client bucket is kept before being evicted
+const bucketIdleTTL = 10 * time.Minute
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:        rate,
+		burst:       float64(burst),
+		buckets:     make(map[string]*tokenBucket),
+		lastCleanup: time.Now(),
+	}
+}
+
+// allow consumes a token for key and reports whether the request may proceed.
+// When denied, it also returns how long until the next token is available.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Evict idle buckets periodically so scanners can't grow the map forever
+	if now.Sub(l.lastCleanup) > time.Minute {
+		for k, b := range l.buckets {
+			if now.Sub(b.lastSeen) > bucketIdleTTL {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastCleanup = now
+	}
+
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &tokenBucket{tokens: l.burst, lastSeen: now}
+		l.buckets[key] = b
+	}
+
+	// Refill based on elapsed time
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.lastSeen).Seconds()*l.rate)
+	b.lastSeen = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// rateLimitMiddleware rejects requests from clients exceeding the configured rate
+func rateLimitMiddleware(rate float64, burst int) gin.HandlerFunc {
+	if rate <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	limiter := newRateLimiter(rate, burst)
+
+	return func(c *gin.Context) {
+		allowed, wait := limiter.allow(c.ClientIP())
+		if !allowed {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			handler.RespondError(c, http.StatusTooManyRequests, "Rate limit exceeded")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// bodySizeMiddleware caps request body size to protect JSON binding from oversized payloads
+func bodySizeMiddleware(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxBytes <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > maxBytes {
+			handler.RespondError(c, http.StatusRequestEntityTooLarge, "Request body too large")
+			c.Abort()
+			return
+		}
+
+		// Guard against chunked bodies without a Content-Length
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
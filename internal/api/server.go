@@ -3,16 +3,24 @@ package api
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"embed"
+	"encoding/hex"
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"snmp-mqtt-bridge/internal/api/handler"
 	"snmp-mqtt-bridge/internal/config"
+	"snmp-mqtt-bridge/internal/eventbus"
 	"snmp-mqtt-bridge/internal/mqtt"
+	"snmp-mqtt-bridge/internal/repository"
+	"snmp-mqtt-bridge/internal/requestid"
 	"snmp-mqtt-bridge/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -24,26 +32,56 @@ type Server struct {
 	router     *gin.Engine
 	httpServer *http.Server
 	services   *Services
+	ws         *handler.WebSocketHandler
 }
 
 // Services contains all service dependencies
 type Services struct {
-	Device     *service.DeviceService
-	Profile    *service.ProfileService
-	TrapLog    *service.TrapLogService
-	Setting    *service.SettingService
-	Poller     *service.PollerService
-	SNMP       *service.SNMPService
-	MQTTClient *mqtt.Client
+	Device       *service.DeviceService
+	Importer     *service.DeviceImporter
+	Profile      *service.ProfileService
+	TrapLog      *service.TrapLogService
+	Setting      *service.SettingService
+	Event        *service.EventService
+	SelfTest     *service.SelfTestService
+	Action       *service.ActionService
+	CommandQueue *service.CommandQueue
+	Scene        *service.SceneService
+	Credential   *service.CredentialService
+	Notification *service.NotificationService
+	Statistics   *service.StatisticsAuditService
+	History      *service.StateHistoryService // nil when history is disabled
+	ConfigDrift  *service.ConfigDriftService
+	Inventory    *service.InventoryService
+	Leader       *service.LeaderElector // nil unless running as a standby pair
+	Shard        *service.ShardService  // nil unless running as a sharded cluster
+	Update       *service.UpdateChecker // nil when release checks are disabled
+	Poller       *service.PollerService
+	SNMP         *service.SNMPService
+	MQTTClient   *mqtt.Client
+	EventBus     *eventbus.Bus
+	Database     repository.HealthMonitor // nil for the memory driver
+	TrapInjector handler.TrapInjector
+	Maintenance  *service.MaintenanceService // nil for the memory driver
+	Audit        *service.AuditService       // nil when the audit trail is disabled
+	WriteLock    *service.WriteLockService
+	Locale       *service.LocaleService
+	Units        *service.UnitService
+	Discovery    handler.DiscoveryPublisher
 }
 
 // NewServer creates a new HTTP server
 func NewServer(cfg *config.Config, services *Services, frontendFS embed.FS) *Server {
 	gin.SetMode(gin.ReleaseMode)
+	handler.RegisterValidators()
+	useTimestampCodec()
 	router := gin.New()
+	router.Use(requestIDMiddleware())
 	router.Use(gin.Recovery())
-	router.Use(corsMiddleware())
-	router.Use(loggerMiddleware())
+	router.Use(corsMiddleware(cfg.Server.CORS))
+	router.Use(bodySizeMiddleware(cfg.Server.MaxBodySize))
+	router.Use(gzipMiddleware())
+	router.Use(loggerMiddleware(cfg.Logging.Format))
 
 	s := &Server{
 		cfg:      cfg,
@@ -57,86 +95,363 @@ func NewServer(cfg *config.Config, services *Services, frontendFS embed.FS) *Ser
 
 func (s *Server) setupRoutes(frontendFS embed.FS) {
 	// Health endpoints
-	health := handler.NewHealthHandler()
+	health := handler.NewHealthHandler(s.services.Database)
 	s.router.GET("/health", health.Health)
 	s.router.GET("/ready", health.Ready)
 
-	// API routes
-	api := s.router.Group("/api")
+	// Rate limiting applies to the API only, not to health checks or frontend assets
+	rl := s.cfg.Server.RateLimit
+	apiLimit := func(c *gin.Context) { c.Next() }
+	commandLimit := apiLimit
+	if rl.Enabled {
+		apiLimit = rateLimitMiddleware(rl.RequestsPerSecond, rl.Burst)
+		commandLimit = rateLimitMiddleware(rl.CommandRequestsPerSecond, rl.CommandBurst)
+	}
+
+	// Optional unauthenticated status for status pages
+	if ps := s.cfg.Server.PublicStatus; ps.Enabled {
+		public := handler.NewPublicStatusHandler(s.services.Device, s.services.Profile, s.services.Poller, ps.Metrics)
+		s.router.GET("/api/public/status", apiLimit, public.Status)
+	}
+
+	// Handlers are shared between the versioned API and the legacy alias
+	settingHandler := handler.NewSettingHandler(s.services.Setting)
+	if s.services.MQTTClient != nil {
+		settingHandler.SetMQTTClient(s.services.MQTTClient)
+	}
+	h := &apiHandlers{
+		device:  handler.NewDeviceHandler(s.services.Device, s.services.Poller),
+		profile: handler.NewProfileHandler(s.services.Profile),
+		trap:    handler.NewTrapHandler(s.services.TrapLog, s.services.Device),
+		event:   handler.NewEventHandler(s.services.Event, s.services.Device),
+		setting: settingHandler,
+		ws:      handler.NewWebSocketHandler(s.services.Poller, s.services.EventBus),
+		conn:    handler.NewConnectionHandler(),
+		version: handler.NewVersionHandler(s.services.Update),
+	}
+	h.ws.SetAllowedOrigins(s.cfg.Server.WebSocket.AllowedOrigins)
+	h.ws.SetToken(s.cfg.Server.WebSocket.Token)
+	if s.services.Importer != nil {
+		h.device.SetImporter(s.services.Importer)
+	}
+	if s.services.TrapInjector != nil {
+		h.trap.SetInjector(s.services.TrapInjector)
+	}
+	if s.services.Discovery != nil {
+		h.device.SetDiscovery(s.services.Discovery)
+	}
+	if s.services.SNMP != nil {
+		h.command = handler.NewCommandHandler(s.services.SNMP, s.services.Poller, s.services.Device, s.services.Profile, s.services.CommandQueue)
+	}
+	if s.services.SelfTest != nil {
+		h.selfTest = handler.NewSelfTestHandler(s.services.SelfTest)
+	}
+	if s.services.Action != nil {
+		h.action = handler.NewActionHandler(s.services.Action)
+	}
+	if s.services.Scene != nil {
+		h.scene = handler.NewSceneHandler(s.services.Scene)
+	}
+	if s.services.Credential != nil {
+		h.credential = handler.NewCredentialHandler(s.services.Credential)
+	}
+	if s.services.Notification != nil {
+		h.notification = handler.NewNotificationHandler(s.services.Notification)
+	}
+	if s.services.Statistics != nil {
+		h.diagnostics = handler.NewDiagnosticsHandler(s.services.Statistics)
+	}
+	if s.services.Leader != nil || s.services.Shard != nil {
+		h.cluster = handler.NewClusterHandler(s.services.Leader, s.services.Shard, s.services.Poller)
+	}
+	if s.services.Inventory != nil {
+		h.inventory = handler.NewInventoryHandler(s.services.Inventory)
+	}
+	if s.services.Maintenance != nil {
+		h.maintenance = handler.NewMaintenanceHandler(s.services.Maintenance)
+	}
+	if s.services.Audit != nil {
+		h.audit = handler.NewAuditHandler(s.services.Audit)
+	}
+	if s.services.WriteLock != nil {
+		h.writeLock = handler.NewWriteLockHandler(s.services.WriteLock)
+	}
+	if s.services.Locale != nil {
+		h.locale = handler.NewLocaleHandler(s.services.Locale)
+	}
+	if s.services.Units != nil {
+		h.units = handler.NewUnitHandler(s.services.Units)
+	}
+	if s.services.Poller != nil {
+		h.poller = handler.NewPollerHandler(s.services.Poller)
+	}
+	if s.services.ConfigDrift != nil {
+		h.configDrift = handler.NewConfigDriftHandler(s.services.ConfigDrift)
+	}
+	if s.services.History != nil {
+		h.grafana = handler.NewGrafanaHandler(s.services.Device, s.services.History)
+	}
+	s.ws = h.ws
+
+	// The standby of a pair serves reads only
+	standby := func(c *gin.Context) { c.Next() }
+	if s.services.Leader != nil {
+		standby = standbyMiddleware(s.services.Leader)
+	}
+
+	// Changes are attributed to the proxy user or client IP, and audited;
+	// SETs held back by dry runs are collected for the response
+	audited := []gin.HandlerFunc{actorMiddleware(s.cfg.Server.Audit.UserHeader), dryRunMiddleware()}
+	if s.services.Audit != nil {
+		audited = append(audited, auditMiddleware(s.services.Audit))
+	}
+
+	// Versioned API routes
+	versioned := s.router.Group("/api/v"+APIVersion, apiLimit, apiVersionMiddleware(false), standby)
+	versioned.Use(audited...)
+	s.registerAPIRoutes(versioned, h, commandLimit)
+
+	// Legacy unversioned alias, kept until clients migrate to /api/v1
+	legacy := s.router.Group("/api", apiLimit, apiVersionMiddleware(true), standby)
+	legacy.Use(audited...)
+	s.registerAPIRoutes(legacy, h, commandLimit)
+
+	// Serve embedded frontend
+	s.serveFrontend(frontendFS)
+}
+
+// apiHandlers holds the HTTP handlers mounted on each API route group
+type apiHandlers struct {
+	device   *handler.DeviceHandler
+	profile  *handler.ProfileHandler
+	trap     *handler.TrapHandler
+	event    *handler.EventHandler
+	setting  *handler.SettingHandler
+	ws       *handler.WebSocketHandler
+	conn     *handler.ConnectionHandler
+	version  *handler.VersionHandler
+	command  *handler.CommandHandler
+	selfTest *handler.SelfTestHandler
+	action   *handler.ActionHandler
+	scene    *handler.SceneHandler
+
+	notification *handler.NotificationHandler
+	credential   *handler.CredentialHandler
+	diagnostics  *handler.DiagnosticsHandler
+	grafana      *handler.GrafanaHandler
+	configDrift  *handler.ConfigDriftHandler
+	inventory    *handler.InventoryHandler
+	cluster      *handler.ClusterHandler
+	maintenance  *handler.MaintenanceHandler
+	audit        *handler.AuditHandler
+	writeLock    *handler.WriteLockHandler
+	locale       *handler.LocaleHandler
+	units        *handler.UnitHandler
+	poller       *handler.PollerHandler
+}
+
+// registerAPIRoutes mounts all API endpoints on the given group
+func (s *Server) registerAPIRoutes(api *gin.RouterGroup, h *apiHandlers, commandLimit gin.HandlerFunc) {
+	// Devices
+	devices := api.Group("/devices")
 	{
-		// Devices
-		deviceHandler := handler.NewDeviceHandler(s.services.Device, s.services.Poller)
-		devices := api.Group("/devices")
-		{
-			devices.GET("", deviceHandler.List)
-			devices.POST("", deviceHandler.Create)
-			devices.GET("/:id", deviceHandler.Get)
-			devices.PUT("/:id", deviceHandler.Update)
-			devices.DELETE("/:id", deviceHandler.Delete)
-			devices.POST("/:id/test", deviceHandler.TestConnection)
-			devices.GET("/:id/state", deviceHandler.GetState)
+		devices.GET("", h.device.List)
+		devices.POST("", h.device.Create)
+		devices.POST("/import", h.device.Import)
+		devices.GET("/:id", h.device.Get)
+		devices.PUT("/:id", h.device.Update)
+		devices.DELETE("/:id", h.device.Delete)
+		devices.POST("/:id/enable", h.device.Enable)
+		devices.POST("/:id/disable", h.device.Disable)
+		devices.POST("/:id/test", h.device.TestConnection)
+		devices.GET("/:id/state", h.device.GetState)
+		devices.GET("/:id/oid-health", h.device.GetOIDHealth)
+		devices.GET("/:id/events", h.event.ListByDevice)
+		devices.POST("/:id/preview-mapping", commandLimit, h.device.PreviewMapping)
+		devices.GET("/:id/snapshot", commandLimit, h.device.Snapshot)
+		if s.services.Discovery != nil {
+			devices.GET("/:id/discovery", h.device.GetDiscovery)
+			devices.POST("/:id/discovery/retry", commandLimit, h.device.RetryDiscovery)
 		}
-		api.POST("/test-connection", deviceHandler.TestNewConnection)
+	}
+	api.POST("/test-connection", h.device.TestNewConnection)
 
-		// Profiles
-		profileHandler := handler.NewProfileHandler(s.services.Profile)
-		profiles := api.Group("/profiles")
-		{
-			profiles.GET("", profileHandler.List)
-			profiles.GET("/:id", profileHandler.Get)
-			profiles.POST("", profileHandler.Create)
-			profiles.PUT("/:id", profileHandler.Update)
-			profiles.DELETE("/:id", profileHandler.Delete)
-		}
+	// Profiles
+	profiles := api.Group("/profiles")
+	{
+		profiles.GET("", h.profile.List)
+		profiles.GET("/:id", h.profile.Get)
+		profiles.POST("", h.profile.Create)
+		profiles.PUT("/:id", h.profile.Update)
+		profiles.DELETE("/:id", h.profile.Delete)
+	}
+
+	// Traps
+	traps := api.Group("/traps")
+	{
+		traps.GET("", h.trap.List)
+		traps.GET("/stats", h.trap.Stats)
+		traps.GET("/export", h.trap.Export)
+		traps.GET("/:id", h.trap.Get)
+		traps.POST("/test", h.trap.Test)
+		traps.DELETE("/cleanup", h.trap.Cleanup)
+	}
 
-		// Traps
-		trapHandler := handler.NewTrapHandler(s.services.TrapLog)
-		traps := api.Group("/traps")
+	// Device events (timeline)
+	events := api.Group("/events")
+	{
+		events.GET("", h.event.List)
+		events.DELETE("/cleanup", h.event.Cleanup)
+	}
+
+	// Settings
+	settings := api.Group("/settings")
+	{
+		settings.GET("", h.setting.List)
+		settings.GET("/:key", h.setting.Get)
+		settings.PUT("/:key", h.setting.Set)
+		settings.DELETE("/:key", h.setting.Delete)
+	}
+
+	// Warm standby role or sharded cluster nodes
+	if h.cluster != nil {
+		api.GET("/cluster", h.cluster.Status)
+	}
+
+	// Hardware and firmware inventory
+	if h.inventory != nil {
+		api.GET("/inventory", h.inventory.List)
+	}
+
+	// Database size and vacuum
+	if h.maintenance != nil {
+		api.GET("/maintenance", h.maintenance.Status)
+		api.POST("/maintenance/vacuum", h.maintenance.Vacuum)
+	}
+
+	// Who changed what, through the API or MQTT
+	if h.audit != nil {
+		api.GET("/audit", h.audit.List)
+		api.DELETE("/audit/cleanup", h.audit.Cleanup)
+	}
+
+	// Bridge-wide write lock for change freezes
+	if h.writeLock != nil {
+		api.GET("/bridge/lock", h.writeLock.Get)
+		api.PUT("/bridge/lock", h.writeLock.Set)
+	}
+
+	// Language of entity names and enum labels in Home Assistant
+	if h.locale != nil {
+		api.GET("/locale", h.locale.Get)
+		api.PUT("/locale", h.locale.Set)
+	}
+
+	// Temperature unit values are published in
+	if h.units != nil {
+		api.GET("/units", h.units.Get)
+		api.PUT("/units", h.units.Set)
+	}
+
+	// Running build and release check
+	api.GET("/version", h.version.Get)
+
+	// SNMP socket diagnostics
+	api.GET("/snmp/connections", h.conn.Stats)
+
+	// Progress of the first polls after startup
+	if h.poller != nil {
+		api.GET("/poller/status", h.poller.Status)
+	}
+
+	// MQTT management
+	api.GET("/mqtt/status", h.setting.GetMQTTStatus)
+	api.POST("/mqtt/reconnect", h.setting.ReconnectMQTT)
+	api.POST("/mqtt/test", h.setting.TestMQTTConnection)
+
+	// WebSocket for real-time updates
+	api.GET("/ws", h.ws.HandleWebSocket)
+
+	// Device commands (SNMP SET)
+	if h.command != nil {
+		devices.POST("/:id/set", commandLimit, h.command.SetValue)
+		devices.GET("/:id/get", commandLimit, h.command.GetValue)
+		// ATS commands
+		devices.POST("/:id/switch-source", commandLimit, h.command.SwitchSource)
+		devices.POST("/:id/set-source-name", commandLimit, h.command.SetSourceName)
+		// PDU commands
+		devices.GET("/:id/outlets", h.command.ListOutlets)
+		devices.POST("/:id/outlet/state", commandLimit, h.command.SetOutletState)
+		devices.POST("/:id/outlet/name", commandLimit, h.command.SetOutletName)
+		devices.POST("/:id/outlet/reboot", commandLimit, h.command.RebootOutlet)
+		devices.POST("/:id/outlet/all", commandLimit, h.command.AllOutlets)
+	}
+
+	// UPS battery self-test
+	if h.selfTest != nil {
+		devices.GET("/:id/self-test", h.selfTest.Status)
+		devices.POST("/:id/self-test", commandLimit, h.selfTest.Run)
+	}
+
+	// Configuration snapshots and their changes
+	if h.configDrift != nil {
+		devices.GET("/:id/config-drift", h.configDrift.Get)
+	}
+
+	// Profile-defined actions
+	if h.action != nil {
+		devices.GET("/:id/actions", h.action.List)
+		devices.POST("/:id/actions/:action", commandLimit, h.action.Run)
+	}
+
+	// Multi-device scenes
+	if h.scene != nil {
+		scenes := api.Group("/scenes")
 		{
-			traps.GET("", trapHandler.List)
-			traps.GET("/:id", trapHandler.Get)
-			traps.DELETE("/cleanup", trapHandler.Cleanup)
+			scenes.GET("", h.scene.List)
+			scenes.POST("", h.scene.Create)
+			scenes.GET("/:id", h.scene.Get)
+			scenes.PUT("/:id", h.scene.Update)
+			scenes.DELETE("/:id", h.scene.Delete)
+			scenes.POST("/:id/run", commandLimit, h.scene.Run)
+			scenes.GET("/:id/runs/:run_id", h.scene.GetRun)
 		}
+	}
 
-		// Settings
-		settingHandler := handler.NewSettingHandler(s.services.Setting)
-		if s.services.MQTTClient != nil {
-			settingHandler.SetMQTTClient(s.services.MQTTClient)
-		}
-		settings := api.Group("/settings")
+	// Shared credential sets referenced by devices
+	if h.credential != nil {
+		credentials := api.Group("/credentials")
 		{
-			settings.GET("", settingHandler.List)
-			settings.GET("/:key", settingHandler.Get)
-			settings.PUT("/:key", settingHandler.Set)
-			settings.DELETE("/:key", settingHandler.Delete)
+			credentials.GET("", h.credential.List)
+			credentials.POST("", h.credential.Create)
+			credentials.GET("/:id", h.credential.Get)
+			credentials.PUT("/:id", h.credential.Update)
+			credentials.DELETE("/:id", h.credential.Delete)
 		}
+	}
 
-		// MQTT management
-		api.GET("/mqtt/status", settingHandler.GetMQTTStatus)
-		api.POST("/mqtt/reconnect", settingHandler.ReconnectMQTT)
-		api.POST("/mqtt/test", settingHandler.TestMQTTConnection)
-
-		// WebSocket for real-time updates
-		wsHandler := handler.NewWebSocketHandler(s.services.Poller)
-		api.GET("/ws", wsHandler.HandleWebSocket)
-
-		// Device commands (SNMP SET)
-		if s.services.SNMP != nil {
-			commandHandler := handler.NewCommandHandler(s.services.SNMP, s.services.Poller, s.services.Device)
-			devices.POST("/:id/set", commandHandler.SetValue)
-			devices.GET("/:id/get", commandHandler.GetValue)
-			// ATS commands
-			devices.POST("/:id/switch-source", commandHandler.SwitchSource)
-			devices.POST("/:id/set-source-name", commandHandler.SetSourceName)
-			// PDU commands
-			devices.POST("/:id/outlet/state", commandHandler.SetOutletState)
-			devices.POST("/:id/outlet/name", commandHandler.SetOutletName)
-			devices.POST("/:id/outlet/reboot", commandHandler.RebootOutlet)
-		}
+	// Home Assistant compatibility checks
+	if h.diagnostics != nil {
+		api.GET("/diagnostics/ha-statistics", h.diagnostics.HAStatistics)
 	}
 
-	// Serve embedded frontend
-	s.serveFrontend(frontendFS)
+	// Notification channels, configured through the notify.* settings
+	if h.notification != nil {
+		api.GET("/notifications/status", h.notification.Status)
+		api.POST("/notifications/:channel/test", commandLimit, h.notification.Test)
+	}
+
+	// Grafana SimpleJSON datasource over the state history; Grafana tests
+	// the connection with GET on the datasource URL
+	if h.grafana != nil {
+		grafana := api.Group("/grafana")
+		{
+			grafana.GET("", h.grafana.Test)
+			grafana.GET("/", h.grafana.Test)
+			grafana.POST("/search", h.grafana.Search)
+			grafana.POST("/query", h.grafana.Query)
+		}
+	}
 }
 
 func (s *Server) serveFrontend(frontendFS embed.FS) {
@@ -160,6 +475,7 @@ func (s *Server) serveFrontend(frontendFS embed.FS) {
 	}
 
 	staticServer := http.FileServer(http.FS(distFS))
+	etags := computeETags(distFS)
 
 	// Serve static files
 	s.router.NoRoute(func(c *gin.Context) {
@@ -173,22 +489,69 @@ func (s *Server) serveFrontend(frontendFS embed.FS) {
 
 		// Serve index.html with injected base path for SPA routes
 		if path == "/" || path == "/index.html" || !strings.Contains(path[1:], ".") {
-			// Inject base path into HTML
-			html := s.injectBasePath(indexHTML, ingressPath)
-			c.Data(http.StatusOK, "text/html; charset=utf-8", html)
+			s.serveIndex(c, indexHTML, ingressPath)
 			return
 		}
 
 		// Check if static file exists
 		if _, err := fs.Stat(distFS, path[1:]); err == nil {
+			// Vite emits content-hashed filenames under assets/, so they never change
+			if strings.HasPrefix(path, "/assets/") {
+				c.Header("Cache-Control", "public, max-age=31536000, immutable")
+			} else {
+				c.Header("Cache-Control", "no-cache")
+			}
+			// http.FileServer handles If-None-Match against this ETag
+			if etag, ok := etags[path[1:]]; ok {
+				c.Header("ETag", etag)
+			}
 			staticServer.ServeHTTP(c.Writer, c.Request)
 			return
 		}
 
 		// Fallback to index.html for SPA routing
-		html := s.injectBasePath(indexHTML, ingressPath)
-		c.Data(http.StatusOK, "text/html; charset=utf-8", html)
+		s.serveIndex(c, indexHTML, ingressPath)
+	})
+}
+
+// serveIndex serves index.html with the injected base path, revalidated via ETag.
+// The ETag covers the injected HTML so different ingress paths never share a cached copy.
+func (s *Server) serveIndex(c *gin.Context, indexHTML []byte, ingressPath string) {
+	html := s.injectBasePath(indexHTML, ingressPath)
+	etag := contentETag(html)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("ETag", etag)
+
+	if match := c.GetHeader("If-None-Match"); match != "" && strings.Contains(match, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", html)
+}
+
+// computeETags hashes every embedded frontend file once at startup
+func computeETags(distFS fs.FS) map[string]string {
+	etags := make(map[string]string)
+	_ = fs.WalkDir(distFS, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		data, err := fs.ReadFile(distFS, path)
+		if err != nil {
+			return nil
+		}
+		etags[path] = contentETag(data)
+		return nil
 	})
+	return etags
+}
+
+// contentETag returns a strong ETag derived from the content hash
+func contentETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
 }
 
 // injectBasePath injects the base path into the HTML for frontend routing
@@ -219,16 +582,62 @@ func (s *Server) Start() error {
 	return s.httpServer.ListenAndServe()
 }
 
-// Shutdown gracefully shuts down the server
+// Shutdown gracefully shuts down the server and disconnects WebSocket
+// clients, which http.Server.Shutdown does not track
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
+	var err error
+	if s.httpServer != nil {
+		err = s.httpServer.Shutdown(ctx)
+	}
+	if s.ws != nil {
+		s.ws.Close()
+	}
+	return err
 }
 
-func corsMiddleware() gin.HandlerFunc {
+func corsMiddleware(cfg config.CORSConfig) gin.HandlerFunc {
+	allowAll := false
+	allowed := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			allowAll = true
+			continue
+		}
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+
 	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
+		origin := c.GetHeader("Origin")
+
+		// Requests without an Origin header are not cross-origin browser requests
+		if origin != "" {
+			switch {
+			case allowAll && !cfg.AllowCredentials:
+				c.Header("Access-Control-Allow-Origin", "*")
+			case allowAll || allowed[origin]:
+				// Credentials require the exact origin to be echoed back
+				c.Header("Access-Control-Allow-Origin", origin)
+				c.Header("Vary", "Origin")
+			}
+
+			if c.Writer.Header().Get("Access-Control-Allow-Origin") != "" {
+				c.Header("Access-Control-Allow-Methods", methods)
+				c.Header("Access-Control-Allow-Headers", headers)
+				if exposed != "" {
+					c.Header("Access-Control-Expose-Headers", exposed)
+				}
+				if cfg.AllowCredentials {
+					c.Header("Access-Control-Allow-Credentials", "true")
+				}
+				if cfg.MaxAge > 0 {
+					c.Header("Access-Control-Max-Age", strconv.Itoa(cfg.MaxAge))
+				}
+			}
+		}
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -239,20 +648,54 @@ func corsMiddleware() gin.HandlerFunc {
 	}
 }
 
-func loggerMiddleware() gin.HandlerFunc {
+// requestIDMiddleware assigns every request an ID, reusing a valid one sent
+// by the client, returns it in the X-Request-ID header and passes it on in
+// the request context
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		c.Header(requestid.Header, id)
+		c.Request = c.Request.WithContext(requestid.WithID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// loggerMiddleware writes one structured access log line per request, as
+// JSON or logfmt text depending on logging.format
+func loggerMiddleware(format string) gin.HandlerFunc {
+	var h slog.Handler
+	if format == "text" {
+		h = slog.NewTextHandler(os.Stdout, nil)
+	} else {
+		h = slog.NewJSONHandler(os.Stdout, nil)
+	}
+	logger := slog.New(h)
+
 	return func(c *gin.Context) {
 		start := time.Now()
 		c.Next()
-		latency := time.Since(start)
-
-		if c.Request.URL.Path != "/health" && c.Request.URL.Path != "/ready" {
-			fmt.Printf("[%s] %s %s %d %v\n",
-				time.Now().Format(time.RFC3339),
-				c.Request.Method,
-				c.Request.URL.Path,
-				c.Writer.Status(),
-				latency,
-			)
+
+		if c.Request.URL.Path == "/health" || c.Request.URL.Path == "/ready" {
+			return
+		}
+		attrs := []slog.Attr{
+			slog.String("request_id", requestid.FromContext(c.Request.Context())),
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", c.Writer.Status()),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("bytes", c.Writer.Size()),
+		}
+		if route := c.FullPath(); route != "" {
+			attrs = append(attrs, slog.String("route", route))
+		}
+		if msg, ok := c.Get(handler.ErrorMessageKey); ok {
+			attrs = append(attrs, slog.Any("error", msg))
 		}
+		logger.LogAttrs(c.Request.Context(), slog.LevelInfo, "http request", attrs...)
 	}
 }
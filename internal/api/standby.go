@@ -0,0 +1,35 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"snmp-mqtt-bridge/internal/api/handler"
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// standbyMiddleware rejects changes on the standby of a pair: it neither
+// polls nor publishes, so devices it changed would not be picked up by the
+// leader until its next reconcile. Reads are served from the shared database.
+func standbyMiddleware(leader *service.LeaderElector) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if leader.IsLeader() {
+			c.Next()
+			return
+		}
+
+		message := "This bridge instance is the standby; send changes to the leader"
+		if status, err := leader.Status(c.Request.Context()); err == nil && status.Holder != "" {
+			message = fmt.Sprintf("%s (%s)", message, status.Holder)
+		}
+		handler.RespondErrorCode(c, http.StatusServiceUnavailable, handler.CodeStandby, message)
+		c.Abort()
+	}
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"snmp-mqtt-bridge/internal/timefmt"
+
+	ginjson "github.com/gin-gonic/gin/codec/json"
+)
+
+// timestampCodec wraps gin's JSON codec so every c.JSON response carries
+// timestamps in the configured timezone and format
+type timestampCodec struct {
+	ginjson.Core
+}
+
+func (c timestampCodec) Marshal(v any) ([]byte, error) {
+	data, err := c.Core.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return timefmt.Normalize(data), nil
+}
+
+func (c timestampCodec) MarshalIndent(v any, prefix, indent string) ([]byte, error) {
+	data, err := c.Core.MarshalIndent(v, prefix, indent)
+	if err != nil {
+		return nil, err
+	}
+	return timefmt.Normalize(data), nil
+}
+
+// useTimestampCodec installs timestampCodec as gin's JSON codec once
+func useTimestampCodec() {
+	if _, installed := ginjson.API.(timestampCodec); !installed {
+		ginjson.API = timestampCodec{Core: ginjson.API}
+	}
+}
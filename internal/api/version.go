@@ -0,0 +1,49 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"snmp-mqtt-bridge/internal/api/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIVersion is the current REST API version served under /api/v<version>
+const APIVersion = "1"
+
+// apiVersionHeader is used both to request a version and to report the served one
+const apiVersionHeader = "X-API-Version"
+
+// supportedAPIVersions lists versions clients may request via the X-API-Version header
+var supportedAPIVersions = map[string]bool{
+	"1": true,
+}
+
+// apiVersionMiddleware reports the served API version and rejects requests for
+// unsupported versions. Routes mounted on the legacy unversioned /api prefix are
+// marked deprecated and point to their versioned successor.
+func apiVersionMiddleware(legacy bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if requested := c.GetHeader(apiVersionHeader); requested != "" {
+			requested = strings.TrimPrefix(strings.ToLower(requested), "v")
+			if !supportedAPIVersions[requested] {
+				handler.RespondError(c, http.StatusBadRequest,
+					fmt.Sprintf("Unsupported API version %q (current: %s)", requested, APIVersion))
+				c.Abort()
+				return
+			}
+		}
+
+		c.Header(apiVersionHeader, APIVersion)
+
+		if legacy {
+			successor := "/api/v" + APIVersion + strings.TrimPrefix(c.Request.URL.Path, "/api")
+			c.Header("Deprecation", "true")
+			c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,57 @@
+// Package buildinfo holds the version of the running bridge, set at build
+// time with -ldflags "-X snmp-mqtt-bridge/internal/buildinfo.Version=...".
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Set at build time; Commit and BuildDate fall back to the VCS information
+// Go records when building from a git checkout
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+	Modified  bool   `json:"modified,omitempty"` // Built from a checkout with uncommitted changes
+}
+
+// Get returns the build information
+func Get() Info {
+	info := Info{
+		Version:   strings.TrimPrefix(Version, "v"),
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	if len(info.Commit) > 12 {
+		info.Commit = info.Commit[:12]
+	}
+	return info
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// addonOptionsPath is where the Home Assistant Supervisor writes the options
+// set in the add-on configuration panel
+const addonOptionsPath = "/data/options.json"
+
+// addonOption maps one add-on option onto a config key
+type addonOption struct {
+	key     string
+	seconds bool // Option is a number of seconds for a duration setting
+}
+
+// addonOptions lists the options declared in the add-on schema
+var addonOptions = map[string]addonOption{
+	"mqtt_host":             {key: "mqtt.broker"},
+	"mqtt_port":             {key: "mqtt.port"},
+	"mqtt_username":         {key: "mqtt.username"},
+	"mqtt_password":         {key: "mqtt.password"},
+	"mqtt_discovery_prefix": {key: "mqtt.discovery_prefix"},
+	"mqtt_topic_prefix":     {key: "mqtt.topic_prefix"},
+	"poll_interval":         {key: "snmp.poll_interval", seconds: true},
+	"trap_port":             {key: "snmp.trap_port"},
+	"log_level":             {key: "logging.level"},
+}
+
+// runningUnderSupervisor reports whether the bridge runs as a Home Assistant
+// add-on; the Supervisor passes every add-on an API token
+func runningUnderSupervisor() bool {
+	return os.Getenv("SUPERVISOR_TOKEN") != ""
+}
+
+// readAddonOptions reads the add-on options file and returns the settings it
+// sets as a nested config map. Options left empty in the panel are skipped so
+// they keep their defaults; unknown options are logged and ignored.
+func readAddonOptions(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var options map[string]interface{}
+	if err := json.Unmarshal(data, &options); err != nil {
+		return nil, fmt.Errorf("invalid add-on options %s: %w", path, err)
+	}
+
+	settings := make(map[string]interface{})
+	for name, value := range options {
+		option, ok := addonOptions[name]
+		if !ok {
+			log.Printf("Ignoring unknown add-on option %q", name)
+			continue
+		}
+		if value == nil || value == "" {
+			continue
+		}
+		if option.seconds {
+			seconds, ok := value.(float64)
+			if !ok {
+				return nil, fmt.Errorf("add-on option %s must be a number of seconds", name)
+			}
+			value = fmt.Sprintf("%gs", seconds)
+		}
+		setNested(settings, option.key, value)
+	}
+	return settings, nil
+}
+
+// setNested stores value under a dotted key, e.g. "mqtt.port"
+func setNested(settings map[string]interface{}, key string, value interface{}) {
+	section, name, found := strings.Cut(key, ".")
+	if !found {
+		settings[key] = value
+		return
+	}
+	child, ok := settings[section].(map[string]interface{})
+	if !ok {
+		child = make(map[string]interface{})
+		settings[section] = child
+	}
+	setNested(child, name, value)
+}
@@ -1,6 +1,9 @@
 package config
 
 import (
+	"fmt"
+	"log"
+	"os"
 	"strings"
 	"time"
 
@@ -8,49 +11,250 @@ import (
 )
 
 type Config struct {
+	Site     string         `mapstructure:"site"` // Site this bridge reports from, e.g. "warsaw-dc1"
 	Server   ServerConfig   `mapstructure:"server"`
 	Database DatabaseConfig `mapstructure:"database"`
 	MQTT     MQTTConfig     `mapstructure:"mqtt"`
 	SNMP     SNMPConfig     `mapstructure:"snmp"`
 	Logging  LoggingConfig  `mapstructure:"logging"`
+	Time     TimeConfig     `mapstructure:"time"`
+	Units    UnitsConfig    `mapstructure:"units"`
+	Update   UpdateConfig   `mapstructure:"update"`
+	History  HistoryConfig  `mapstructure:"history"`
+	Cluster  ClusterConfig  `mapstructure:"cluster"`
 }
 
 type ServerConfig struct {
-	Host        string `mapstructure:"host"`
-	Port        int    `mapstructure:"port"`
-	IngressPath string `mapstructure:"ingress_path"` // Base path for HA Ingress (e.g., /api/hassio_ingress/xxx)
+	Host        string          `mapstructure:"host"`
+	Port        int             `mapstructure:"port"`
+	IngressPath string          `mapstructure:"ingress_path"` // Base path for HA Ingress (e.g., /api/hassio_ingress/xxx)
+	CORS        CORSConfig      `mapstructure:"cors"`
+	RateLimit   RateLimitConfig `mapstructure:"rate_limit"`
+	MaxBodySize int64           `mapstructure:"max_body_size"` // Maximum request body size in bytes, 0 = unlimited
+	MDNS        MDNSConfig      `mapstructure:"mdns"`
+	WebSocket   WebSocketConfig `mapstructure:"websocket"`
+
+	PublicStatus PublicStatusConfig `mapstructure:"public_status"`
+	Audit        AuditConfig        `mapstructure:"audit"`
+}
+
+// AuditConfig controls the audit trail of API changes and MQTT commands
+type AuditConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	UserHeader string `mapstructure:"user_header"` // Header naming the user, set by a trusted reverse proxy; empty records the client IP
+}
+
+// PublicStatusConfig controls the unauthenticated status endpoint for
+// embedding in status pages
+type PublicStatusConfig struct {
+	Enabled bool     `mapstructure:"enabled"`
+	Metrics []string `mapstructure:"metrics"` // Mapping names exposed, e.g. "Battery Capacity"; other values are left out
+}
+
+// WebSocketConfig controls who may open the real-time updates WebSocket
+type WebSocketConfig struct {
+	AllowedOrigins []string `mapstructure:"allowed_origins"` // Empty uses server.cors.allowed_origins when configured, else same-origin only
+	Token          string   `mapstructure:"token"`           // Required from clients when set
+}
+
+// MDNSConfig controls advertising the web UI over multicast DNS
+type MDNSConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	Hostname     string `mapstructure:"hostname"`      // Advertised as <hostname>.local; empty derives it from the instance ID
+	InstanceName string `mapstructure:"instance_name"` // Service name shown in browsers; empty uses the bridge device name
+}
+
+// CORSConfig controls Cross-Origin Resource Sharing headers on API responses
+type CORSConfig struct {
+	AllowedOrigins   []string `mapstructure:"allowed_origins"` // "*" allows any origin
+	AllowedMethods   []string `mapstructure:"allowed_methods"`
+	AllowedHeaders   []string `mapstructure:"allowed_headers"`
+	ExposedHeaders   []string `mapstructure:"exposed_headers"`
+	AllowCredentials bool     `mapstructure:"allow_credentials"`
+	MaxAge           int      `mapstructure:"max_age"` // Preflight cache duration in seconds, 0 = not sent
+}
+
+// RateLimitConfig controls per-client-IP request rate limiting
+type RateLimitConfig struct {
+	Enabled           bool    `mapstructure:"enabled"`
+	RequestsPerSecond float64 `mapstructure:"requests_per_second"`
+	Burst             int     `mapstructure:"burst"`
+	// Stricter limits for endpoints that issue SNMP SET commands to devices
+	CommandRequestsPerSecond float64 `mapstructure:"command_requests_per_second"`
+	CommandBurst             int     `mapstructure:"command_burst"`
 }
 
 type DatabaseConfig struct {
-	Driver   string `mapstructure:"driver"` // sqlite or postgres
+	Driver   string `mapstructure:"driver"` // sqlite, postgres or memory
 	DSN      string `mapstructure:"dsn"`
 	Host     string `mapstructure:"host"`
 	Port     int    `mapstructure:"port"`
 	User     string `mapstructure:"user"`
 	Password string `mapstructure:"password"`
 	DBName   string `mapstructure:"dbname"`
+
+	// Outage handling
+	HealthCheckInterval time.Duration `mapstructure:"health_check_interval"` // How often connectivity is checked
+	RetryAttempts       int           `mapstructure:"retry_attempts"`        // Retries for transient errors while the database is up
+	WriteBufferSize     int           `mapstructure:"write_buffer_size"`     // Event and trap writes held while the database is down
+
+	VacuumInterval time.Duration `mapstructure:"vacuum_interval"` // How often free space is reclaimed; 0 disables it
 }
 
 type MQTTConfig struct {
-	Broker       string `mapstructure:"broker"`
-	Port         int    `mapstructure:"port"`
-	Username     string `mapstructure:"username"`
-	Password     string `mapstructure:"password"`
-	ClientID     string `mapstructure:"client_id"`
-	TopicPrefix  string `mapstructure:"topic_prefix"`
-	Discovery    bool   `mapstructure:"discovery"`
+	Broker          string `mapstructure:"broker"`
+	Port            int    `mapstructure:"port"`
+	Username        string `mapstructure:"username"`
+	Password        string `mapstructure:"password"`
+	ClientID        string `mapstructure:"client_id"`
+	ClientIDSuffix  bool   `mapstructure:"client_id_suffix"` // Append a random suffix so instances never share an ID
+	TopicPrefix     string `mapstructure:"topic_prefix"`
+	InstanceID      string `mapstructure:"instance_id"` // Scopes topics and discovery IDs when several bridges share a broker
+	Discovery       bool   `mapstructure:"discovery"`
 	DiscoveryPrefix string `mapstructure:"discovery_prefix"`
+	SuggestedArea   bool   `mapstructure:"suggested_area"` // Send device location as HA suggested_area
+	StateFormat     string `mapstructure:"state_format"`   // Full state payload layout: raw, flat-names or nested
+
+	DeviceAvailability    bool `mapstructure:"device_availability"`      // Per-device availability topic, set offline on shutdown
+	ClearStatesOnShutdown bool `mapstructure:"clear_states_on_shutdown"` // Clear retained entity states on shutdown
+
+	Retain      RetainConfig  `mapstructure:"retain"`
+	StateExpiry time.Duration `mapstructure:"state_expiry"` // Clear retained states of devices without fresh values this long, in place of MQTT 5 message expiry, which the 3.1.1 client cannot send; 0 keeps them
+
+	KeepAlive time.Duration `mapstructure:"keep_alive"` // Ping the broker after this long without traffic; 0 keeps the client default
+
+	RetryQueueSize int `mapstructure:"retry_queue_size"` // Failed publishes kept for retry; 0 disables retrying
+	PublishRetries int `mapstructure:"publish_retries"`  // Attempts per queued publish before it is dropped
+
+	DiscoveryCheckInterval time.Duration `mapstructure:"discovery_check_interval"` // Republish discovery configs missing from the broker; 0 disables the check
+	StatsInterval          time.Duration `mapstructure:"stats_interval"`           // Publish bridge statistics; 0 disables them
+
+	FixStateClasses bool `mapstructure:"fix_state_classes"` // Publish the state class HA long-term statistics need when a sensor's is wrong or missing
+
+	ConfirmTimeout time.Duration `mapstructure:"confirm_timeout"` // How long a command to a device with confirm_writes waits for Confirm Command
+
+	Adapters []string       `mapstructure:"adapters"` // Output layouts published besides HA discovery: openhab, domoticz
+	OpenHAB  OpenHABConfig  `mapstructure:"openhab"`
+	Domoticz DomoticzConfig `mapstructure:"domoticz"`
+}
+
+// OpenHABConfig configures the openhab adapter, which follows the Homie
+// convention openHAB's MQTT binding discovers Things from
+type OpenHABConfig struct {
+	BaseTopic string `mapstructure:"base_topic"`
+}
+
+// DomoticzConfig configures the domoticz adapter
+type DomoticzConfig struct {
+	InTopic  string `mapstructure:"in_topic"`  // Topic Domoticz reads device updates from
+	OutTopic string `mapstructure:"out_topic"` // Topic Domoticz publishes device changes to, for commands
+}
+
+// RetainConfig selects which message classes the broker retains. Bridge
+// status and discovery configs are always retained. Unset fields take their
+// defaults, so the zero value retains everything but the full device state.
+type RetainConfig struct {
+	EntityStates *bool `mapstructure:"entity_states"` // <prefix>/<device>/<entity>/state, default true
+	DeviceState  *bool `mapstructure:"device_state"`  // Full state at <prefix>/<device>/state, default false
+	Availability *bool `mapstructure:"availability"`  // Per-device availability, default true
+	Stats        *bool `mapstructure:"stats"`         // Bridge statistics and update status, default true
+}
+
+// EntityStatesRetained reports whether entity states are retained
+func (r RetainConfig) EntityStatesRetained() bool {
+	return boolOr(r.EntityStates, true)
+}
+
+// DeviceStateRetained reports whether the full device state is retained
+func (r RetainConfig) DeviceStateRetained() bool {
+	return boolOr(r.DeviceState, false)
+}
+
+// AvailabilityRetained reports whether per-device availability is retained
+func (r RetainConfig) AvailabilityRetained() bool {
+	return boolOr(r.Availability, true)
+}
+
+// StatsRetained reports whether bridge statistics and update status are retained
+func (r RetainConfig) StatsRetained() bool {
+	return boolOr(r.Stats, true)
+}
+
+func boolOr(b *bool, fallback bool) bool {
+	if b == nil {
+		return fallback
+	}
+	return *b
 }
 
 type SNMPConfig struct {
-	DefaultCommunity string        `mapstructure:"default_community"`
-	DefaultVersion   string        `mapstructure:"default_version"`
-	DefaultTimeout   time.Duration `mapstructure:"default_timeout"`
-	DefaultRetries   int           `mapstructure:"default_retries"`
-	TrapPort         int           `mapstructure:"trap_port"`
-	PollInterval     time.Duration `mapstructure:"poll_interval"`
+	DefaultCommunity       string          `mapstructure:"default_community"`
+	DefaultVersion         string          `mapstructure:"default_version"`
+	DefaultTimeout         time.Duration   `mapstructure:"default_timeout"`
+	DefaultRetries         int             `mapstructure:"default_retries"`
+	TrapPort               int             `mapstructure:"trap_port"`
+	BindAddress            string          `mapstructure:"bind_address"` // Local IP or interface for SNMP requests, the trap listener and the agent
+	PollInterval           time.Duration   `mapstructure:"poll_interval"`
+	FastPollInterval       time.Duration   `mapstructure:"fast_poll_interval"`       // Interval after commands and state changes
+	FastPollDuration       time.Duration   `mapstructure:"fast_poll_duration"`       // How long fast polling lasts; 0 disables it
+	MaxOIDsPerPoll         int             `mapstructure:"max_oids_per_poll"`        // OIDs requested per poll; 0 is unlimited
+	MaxConnections         int             `mapstructure:"max_connections"`          // Open SNMP sockets at once; 0 is unlimited
+	IdleTimeout            time.Duration   `mapstructure:"idle_timeout"`             // Close poll connections unused this long
+	ReconcileInterval      time.Duration   `mapstructure:"reconcile_interval"`       // Check running pollers against enabled devices; 0 disables it
+	ConfigSnapshotInterval time.Duration   `mapstructure:"config_snapshot_interval"` // Snapshot configuration values to detect drift; 0 disables it
+	CommandCooldown        time.Duration   `mapstructure:"command_cooldown"`         // Minimum time between writes to one OID of a device; 0 disables it
+	StaleAfterPolls        int             `mapstructure:"stale_after_polls"`        // Polls an OID may go unanswered before its value is stale; 0 disables it
+	StartupWarmup          time.Duration   `mapstructure:"startup_warmup"`           // Spread the first polls after startup over this window; 0 polls all at once
+	Agent                  SNMPAgentConfig `mapstructure:"agent"`
 }
 
+// SNMPAgentConfig controls the embedded read-only SNMP agent
+type SNMPAgentConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`
+	Port          int    `mapstructure:"port"`
+	Community     string `mapstructure:"community"`
+	EnterpriseOID string `mapstructure:"enterprise_oid"` // Root OID under which bridge data is exposed
+}
+
+// TimeConfig controls how timestamps are serialized in MQTT, WebSocket and API payloads
+type TimeConfig struct {
+	Timezone string `mapstructure:"timezone"` // IANA name, e.g. "UTC" or "Europe/Warsaw"
+	Format   string `mapstructure:"format"`   // rfc3339, rfc3339ms or rfc3339nano
+}
+
+type UnitsConfig struct {
+	Temperature string `mapstructure:"temperature"` // C or F; empty keeps each profile's unit
+}
+
+// UpdateConfig controls the periodic check for newer bridge releases
+type UpdateConfig struct {
+	CheckEnabled  bool          `mapstructure:"check_enabled"`
+	FeedURL       string        `mapstructure:"feed_url"` // GitHub "latest release" API URL or compatible JSON
+	CheckInterval time.Duration `mapstructure:"check_interval"`
+}
+
+// HistoryConfig controls the in-memory history of polled values that backs
+// the Grafana datasource endpoints
+type HistoryConfig struct {
+	Retention time.Duration `mapstructure:"retention"` // How long values are kept; 0 disables the history
+}
+
+// ClusterConfig controls running several bridge instances against one
+// shared database
+type ClusterConfig struct {
+	Mode         string        `mapstructure:"mode"`          // "" for a single instance, "standby" for an active/standby pair or "sharded"
+	NodeID       string        `mapstructure:"node_id"`       // Unique per instance; empty uses the host name
+	LeaseTTL     time.Duration `mapstructure:"lease_ttl"`     // How long the leader's lease, or a node's heartbeat, lasts without renewal
+	Tags         []string      `mapstructure:"tags"`          // Sharded: device shards this node polls besides its node ID
+	SyncInterval time.Duration `mapstructure:"sync_interval"` // Sharded: how often devices changed through other nodes are picked up
+}
+
+// Cluster modes
+const (
+	ClusterModeStandby = "standby" // One active instance; the others wait to take over
+	ClusterModeSharded = "sharded" // All instances poll, each its share of the devices
+)
+
 type LoggingConfig struct {
 	Level  string `mapstructure:"level"`
 	Format string `mapstructure:"format"`
@@ -81,30 +285,158 @@ func Load() (*Config, error) {
 		}
 	}
 
+	// Under the Supervisor the add-on panel options override defaults and
+	// the config file; environment variables still win
+	if runningUnderSupervisor() {
+		options, err := readAddonOptions(addonOptionsPath)
+		if err != nil {
+			return nil, err
+		}
+		if len(options) > 0 {
+			if err := v.MergeConfigMap(options); err != nil {
+				return nil, err
+			}
+			log.Printf("Loaded add-on options from %s", addonOptionsPath)
+		}
+	}
+
 	var cfg Config
 	if err := v.Unmarshal(&cfg); err != nil {
 		return nil, err
 	}
 
+	// The WebSocket only follows CORS origins that were configured; the "*"
+	// default would otherwise let any web page open it
+	if len(cfg.Server.WebSocket.AllowedOrigins) == 0 && corsOriginsConfigured(v) {
+		cfg.Server.WebSocket.AllowedOrigins = cfg.Server.CORS.AllowedOrigins
+	}
+
+	// A site doubles as the instance ID so bridges at different sites never collide
+	if cfg.MQTT.InstanceID == "" && cfg.Site != "" {
+		cfg.MQTT.InstanceID = siteInstanceID(cfg.Site)
+	}
+	if strings.ContainsAny(cfg.MQTT.InstanceID, "/+# ") {
+		return nil, fmt.Errorf("mqtt.instance_id %q must not contain '/', '+', '#' or spaces", cfg.MQTT.InstanceID)
+	}
+
+	// Instances sharing a LAN advertise distinct mDNS names
+	if cfg.Server.MDNS.Hostname == "" {
+		cfg.Server.MDNS.Hostname = "snmp-bridge"
+		if cfg.MQTT.InstanceID != "" {
+			cfg.Server.MDNS.Hostname += "-" + strings.ReplaceAll(siteInstanceID(cfg.MQTT.InstanceID), "_", "-")
+		}
+	}
+	if cfg.Server.MDNS.InstanceName == "" {
+		cfg.Server.MDNS.InstanceName = "SNMP-MQTT Bridge"
+		if cfg.MQTT.InstanceID != "" {
+			cfg.Server.MDNS.InstanceName = fmt.Sprintf("SNMP-MQTT Bridge (%s)", cfg.MQTT.InstanceID)
+		}
+	}
+
+	switch cfg.Cluster.Mode {
+	case "", ClusterModeStandby, ClusterModeSharded:
+	default:
+		return nil, fmt.Errorf("cluster.mode %q must be empty, %q or %q", cfg.Cluster.Mode, ClusterModeStandby, ClusterModeSharded)
+	}
+	if cfg.Cluster.NodeID == "" {
+		cfg.Cluster.NodeID, _ = os.Hostname()
+	}
+	if cfg.Cluster.Mode != "" && cfg.Cluster.LeaseTTL < time.Second {
+		return nil, fmt.Errorf("cluster.lease_ttl must be at least 1s")
+	}
+	if cfg.Cluster.Mode == ClusterModeSharded && strings.ContainsAny(cfg.Cluster.NodeID, "/+# ") {
+		return nil, fmt.Errorf("cluster.node_id %q must not contain '/', '+', '#' or spaces", cfg.Cluster.NodeID)
+	}
+
 	return &cfg, nil
 }
 
+// corsOriginsConfigured reports whether server.cors.allowed_origins was set in
+// the config file, the add-on options or the environment rather than defaulted
+func corsOriginsConfigured(v *viper.Viper) bool {
+	return v.InConfig("server.cors.allowed_origins") || os.Getenv("SNMP_BRIDGE_SERVER_CORS_ALLOWED_ORIGINS") != ""
+}
+
+// siteInstanceID turns a site name into a topic-safe ID, e.g. "Warsaw DC1" -> "warsaw-dc1"
+func siteInstanceID(site string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(site) {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_':
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('-')
+		}
+	}
+	return strings.Trim(sb.String(), "-")
+}
+
 func setDefaults(v *viper.Viper) {
+	v.SetDefault("site", "")
+
 	// Server defaults
 	v.SetDefault("server.host", "0.0.0.0")
 	v.SetDefault("server.port", 8080)
+	v.SetDefault("server.cors.allowed_origins", []string{"*"})
+	v.SetDefault("server.cors.allowed_methods", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
+	v.SetDefault("server.cors.allowed_headers", []string{"Origin", "Content-Type", "Authorization"})
+	v.SetDefault("server.cors.exposed_headers", []string{"X-API-Version", "Deprecation", "Link", "X-Request-ID"})
+	v.SetDefault("server.cors.allow_credentials", false)
+	v.SetDefault("server.cors.max_age", 0)
+	v.SetDefault("server.rate_limit.enabled", true)
+	v.SetDefault("server.rate_limit.requests_per_second", 20)
+	v.SetDefault("server.rate_limit.burst", 40)
+	v.SetDefault("server.rate_limit.command_requests_per_second", 1)
+	v.SetDefault("server.rate_limit.command_burst", 5)
+	v.SetDefault("server.max_body_size", 1<<20)
+	v.SetDefault("server.mdns.enabled", true)
+	v.SetDefault("server.mdns.hostname", "")
+	v.SetDefault("server.mdns.instance_name", "")
+	v.SetDefault("server.websocket.allowed_origins", []string{})
+	v.SetDefault("server.websocket.token", "")
+	v.SetDefault("server.public_status.enabled", false)
+	v.SetDefault("server.public_status.metrics", []string{})
+	v.SetDefault("server.audit.enabled", true)
+	v.SetDefault("server.audit.user_header", "")
 
 	// Database defaults
 	v.SetDefault("database.driver", "sqlite")
 	v.SetDefault("database.dsn", "./data/snmp-bridge.db")
+	v.SetDefault("database.health_check_interval", "15s")
+	v.SetDefault("database.retry_attempts", 3)
+	v.SetDefault("database.write_buffer_size", 1000)
+	v.SetDefault("database.vacuum_interval", "168h")
 
 	// MQTT defaults
 	v.SetDefault("mqtt.broker", "localhost")
 	v.SetDefault("mqtt.port", 1883)
 	v.SetDefault("mqtt.client_id", "snmp-mqtt-bridge")
+	v.SetDefault("mqtt.client_id_suffix", false)
 	v.SetDefault("mqtt.topic_prefix", "snmp-bridge")
+	v.SetDefault("mqtt.instance_id", "")
+	v.SetDefault("mqtt.state_format", "raw")
 	v.SetDefault("mqtt.discovery", true)
 	v.SetDefault("mqtt.discovery_prefix", "homeassistant")
+	v.SetDefault("mqtt.suggested_area", true)
+	v.SetDefault("mqtt.device_availability", false)
+	v.SetDefault("mqtt.clear_states_on_shutdown", false)
+	// Same as the RetainConfig defaults; listed so environment variables apply
+	v.SetDefault("mqtt.retain.entity_states", true)
+	v.SetDefault("mqtt.retain.device_state", false)
+	v.SetDefault("mqtt.retain.availability", true)
+	v.SetDefault("mqtt.retain.stats", true)
+	v.SetDefault("mqtt.state_expiry", "0s")
+	v.SetDefault("mqtt.keep_alive", "30s")
+	v.SetDefault("mqtt.retry_queue_size", 1000)
+	v.SetDefault("mqtt.publish_retries", 5)
+	v.SetDefault("mqtt.discovery_check_interval", "15m")
+	v.SetDefault("mqtt.stats_interval", "60s")
+	v.SetDefault("mqtt.fix_state_classes", false)
+	v.SetDefault("mqtt.confirm_timeout", "30s")
+	v.SetDefault("mqtt.adapters", []string{})
+	v.SetDefault("mqtt.openhab.base_topic", "homie")
+	v.SetDefault("mqtt.domoticz.in_topic", "domoticz/in")
+	v.SetDefault("mqtt.domoticz.out_topic", "domoticz/out")
 
 	// SNMP defaults
 	v.SetDefault("snmp.default_community", "public")
@@ -112,11 +444,44 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("snmp.default_timeout", "5s")
 	v.SetDefault("snmp.default_retries", 3)
 	v.SetDefault("snmp.trap_port", 162)
+	v.SetDefault("snmp.bind_address", "")
 	v.SetDefault("snmp.poll_interval", "30s")
+	v.SetDefault("snmp.fast_poll_interval", "2s")
+	v.SetDefault("snmp.fast_poll_duration", "30s")
+	v.SetDefault("snmp.max_connections", 512)
+	v.SetDefault("snmp.idle_timeout", "5m")
+	v.SetDefault("snmp.reconcile_interval", "5m")
+	v.SetDefault("snmp.config_snapshot_interval", "6h")
+	v.SetDefault("snmp.command_cooldown", "3s")
+	v.SetDefault("snmp.stale_after_polls", 3)
+	v.SetDefault("snmp.startup_warmup", "0s")
+	v.SetDefault("snmp.agent.enabled", false)
+	v.SetDefault("snmp.agent.port", 1161)
+	v.SetDefault("snmp.agent.community", "public")
+	v.SetDefault("snmp.agent.enterprise_oid", ".1.3.6.1.4.1.99999.1")
 
 	// Logging defaults
 	v.SetDefault("logging.level", "info")
 	v.SetDefault("logging.format", "json")
+
+	// Timestamp defaults
+	v.SetDefault("time.timezone", "UTC")
+	v.SetDefault("time.format", "rfc3339")
+
+	// Release check defaults
+	v.SetDefault("update.check_enabled", false)
+	v.SetDefault("update.feed_url", "https://api.github.com/repos/twopoint71/snmp-mqtt-bridge/releases/latest")
+	v.SetDefault("update.check_interval", "24h")
+
+	// History
+	v.SetDefault("history.retention", "24h")
+
+	// Cluster
+	v.SetDefault("cluster.mode", "")
+	v.SetDefault("cluster.node_id", "")
+	v.SetDefault("cluster.lease_ttl", "10s")
+	v.SetDefault("cluster.tags", []string{})
+	v.SetDefault("cluster.sync_interval", "30s")
 }
 
 // GetDSN returns the database connection string
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWebSocketOriginsIgnoreDefaultCORSWildcard(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.Server.WebSocket.AllowedOrigins) != 0 {
+		t.Errorf("websocket origins = %v, want none (same-origin only)", cfg.Server.WebSocket.AllowedOrigins)
+	}
+}
+
+func TestWebSocketOriginsFollowConfiguredCORS(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("SNMP_BRIDGE_SERVER_CORS_ALLOWED_ORIGINS", "https://ha.example")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := []string{"https://ha.example"}
+	if !reflect.DeepEqual(cfg.Server.WebSocket.AllowedOrigins, want) {
+		t.Errorf("websocket origins = %v, want %v", cfg.Server.WebSocket.AllowedOrigins, want)
+	}
+}
+
+func TestRetainDefaults(t *testing.T) {
+	var zero MQTTConfig
+	if !zero.Retain.EntityStatesRetained() || zero.Retain.DeviceStateRetained() ||
+		!zero.Retain.AvailabilityRetained() || !zero.Retain.StatsRetained() {
+		t.Errorf("zero-value retain config = %+v, want all but the device state retained", zero.Retain)
+	}
+
+	t.Chdir(t.TempDir())
+	t.Setenv("SNMP_BRIDGE_MQTT_RETAIN_ENTITY_STATES", "false")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.MQTT.Retain.EntityStatesRetained() {
+		t.Error("entity states retained although the environment turned it off")
+	}
+	if !cfg.MQTT.Retain.StatsRetained() {
+		t.Error("stats not retained by default")
+	}
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ProfileAction is a named one-shot SNMP SET declared by a profile,
+// e.g. "Mute Alarm" or "Reboot Management Card"
+type ProfileAction struct {
+	ID          string      `json:"id,omitempty" yaml:"id,omitempty"` // URL-safe key, derived from the name when empty
+	Name        string      `json:"name" yaml:"name"`
+	Description string      `json:"description,omitempty" yaml:"description,omitempty"`
+	OID         string      `json:"oid" yaml:"oid"`
+	Value       interface{} `json:"value" yaml:"value"`
+	Type        PDUType     `json:"type,omitempty" yaml:"type,omitempty"`       // PDU type, guessed from the value when empty
+	Confirm     bool        `json:"confirm,omitempty" yaml:"confirm,omitempty"` // Require explicit confirmation before running
+	Icon        string      `json:"icon,omitempty" yaml:"icon,omitempty"`
+}
+
+// Key returns the identifier used in API paths and MQTT topics
+func (a ProfileAction) Key() string {
+	if a.ID != "" {
+		return a.ID
+	}
+
+	var sb strings.Builder
+	for _, r := range strings.ToLower(a.Name) {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'):
+			sb.WriteRune(r)
+		case r == ' ' || r == '-' || r == '_' || r == '.':
+			sb.WriteRune('_')
+		}
+	}
+	return sb.String()
+}
+
+// ProfileActions is a slice of profile actions that can be stored in the database
+type ProfileActions []ProfileAction
+
+func (a ProfileActions) Value() (driver.Value, error) {
+	if a == nil {
+		return "[]", nil
+	}
+	return json.Marshal(a)
+}
+
+func (a *ProfileActions) Scan(value interface{}) error {
+	if value == nil {
+		*a = make(ProfileActions, 0)
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for ProfileActions")
+	}
+
+	return json.Unmarshal(data, a)
+}
+
+// Action returns the profile action with the given key
+func (p *Profile) Action(key string) (*ProfileAction, bool) {
+	if p == nil {
+		return nil, false
+	}
+	for i := range p.Actions {
+		if p.Actions[i].Key() == key {
+			return &p.Actions[i], true
+		}
+	}
+	return nil, false
+}
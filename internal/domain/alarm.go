@@ -0,0 +1,196 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// AlarmSeverity represents how serious a threshold alarm is
+type AlarmSeverity string
+
+const (
+	AlarmSeverityWarning  AlarmSeverity = "warning"
+	AlarmSeverityCritical AlarmSeverity = "critical"
+)
+
+// AlarmThreshold defines a bridge-computed alarm on a numeric mapping.
+// The alarm becomes active when the value rises above Above (or falls below Below)
+// and only clears once it has moved back past the threshold by Hysteresis.
+type AlarmThreshold struct {
+	Name       string        `json:"name,omitempty" yaml:"name,omitempty"`     // Entity name, e.g. "Load High"
+	Source     string        `json:"source,omitempty" yaml:"source,omitempty"` // Mapping name (required for device-level alarms)
+	Severity   AlarmSeverity `json:"severity,omitempty" yaml:"severity,omitempty"`
+	Above      *float64      `json:"above,omitempty" yaml:"above,omitempty"`
+	Below      *float64      `json:"below,omitempty" yaml:"below,omitempty"`
+	Hysteresis float64       `json:"hysteresis,omitempty" yaml:"hysteresis,omitempty"`
+	Icon       string        `json:"icon,omitempty" yaml:"icon,omitempty"`
+}
+
+// Validate checks that a device-level alarm is complete
+func (a AlarmThreshold) Validate() error {
+	if a.Source == "" {
+		return errors.New("alarm source is required")
+	}
+	if a.Above == nil && a.Below == nil {
+		return fmt.Errorf("alarm on %s needs an above or below threshold", a.Source)
+	}
+	if a.Hysteresis < 0 {
+		return fmt.Errorf("alarm on %s has negative hysteresis", a.Source)
+	}
+	switch a.Severity {
+	case "", AlarmSeverityWarning, AlarmSeverityCritical:
+	default:
+		return fmt.Errorf("alarm on %s has unknown severity %q", a.Source, a.Severity)
+	}
+	return nil
+}
+
+// EntityName returns the alarm's entity name, deriving one from the source when unset
+func (a AlarmThreshold) EntityName() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	name := a.Source + " High"
+	if a.Above == nil {
+		name = a.Source + " Low"
+	}
+	if a.Severity == AlarmSeverityCritical {
+		name += " Critical"
+	}
+	return name
+}
+
+// Evaluate returns whether the alarm is active for value, given its previous state
+func (a AlarmThreshold) Evaluate(value float64, active bool) bool {
+	if a.Above != nil {
+		if value > *a.Above || (active && value > *a.Above-a.Hysteresis) {
+			return true
+		}
+	}
+	if a.Below != nil {
+		if value < *a.Below || (active && value < *a.Below+a.Hysteresis) {
+			return true
+		}
+	}
+	return false
+}
+
+// Mapping returns the synthetic binary_sensor mapping used to expose the alarm
+func (a AlarmThreshold) Mapping() OIDMapping {
+	icon := a.Icon
+	if icon == "" {
+		icon = "mdi:alert"
+		if a.Severity == AlarmSeverityCritical {
+			icon = "mdi:alert-octagon"
+		}
+	}
+
+	description := fmt.Sprintf("Alarm on %s", a.Source)
+	if a.Above != nil {
+		description += fmt.Sprintf(" above %g", *a.Above)
+	}
+	if a.Below != nil {
+		description += fmt.Sprintf(" below %g", *a.Below)
+	}
+
+	return OIDMapping{
+		Name:        a.EntityName(),
+		Description: description,
+		Type:        OIDTypeBool,
+		HAComponent: HAComponentBinarySensor,
+		DeviceClass: "problem",
+		Icon:        icon,
+		Computed:    true,
+	}
+}
+
+// AlarmThresholds is a slice of alarm thresholds that can be stored in the database
+type AlarmThresholds []AlarmThreshold
+
+func (a AlarmThresholds) Value() (driver.Value, error) {
+	if a == nil {
+		return "[]", nil
+	}
+	return json.Marshal(a)
+}
+
+func (a *AlarmThresholds) Scan(value interface{}) error {
+	if value == nil {
+		*a = make(AlarmThresholds, 0)
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for AlarmThresholds")
+	}
+
+	return json.Unmarshal(data, a)
+}
+
+// ResolveAlarms merges profile mapping alarms with device-level alarms.
+// A device alarm with the same entity name replaces the profile one, which
+// allows tuning thresholds per device.
+func ResolveAlarms(profile *Profile, device *Device) []AlarmThreshold {
+	var alarms []AlarmThreshold
+	index := make(map[string]int)
+
+	add := func(a AlarmThreshold) {
+		if a.Source == "" || (a.Above == nil && a.Below == nil) {
+			return
+		}
+		name := a.EntityName()
+		if i, exists := index[name]; exists {
+			alarms[i] = a
+			return
+		}
+		index[name] = len(alarms)
+		alarms = append(alarms, a)
+	}
+
+	if profile != nil {
+		for _, mapping := range profile.OIDMappings {
+			for _, a := range mapping.Alarms {
+				if a.Source == "" {
+					a.Source = mapping.Name
+				}
+				add(a)
+			}
+		}
+	}
+	if device != nil {
+		for _, a := range device.Alarms {
+			add(a)
+		}
+	}
+
+	return alarms
+}
+
+// WithAlarms returns a copy of the profile with synthetic mappings appended
+// for every alarm that applies to the device
+func (p *Profile) WithAlarms(device *Device) *Profile {
+	if p == nil {
+		return nil
+	}
+
+	alarms := ResolveAlarms(p, device)
+	if len(alarms) == 0 {
+		return p
+	}
+
+	expanded := *p
+	expanded.OIDMappings = make(OIDMappings, 0, len(p.OIDMappings)+len(alarms))
+	expanded.OIDMappings = append(expanded.OIDMappings, p.OIDMappings...)
+	for _, a := range alarms {
+		expanded.OIDMappings = append(expanded.OIDMappings, a.Mapping())
+	}
+	return &expanded
+}
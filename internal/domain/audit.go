@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+// Audit entry sources
+const (
+	AuditSourceAPI  = "api"
+	AuditSourceMQTT = "mqtt"
+)
+
+// AuditEntry records a change made through the API or a command received
+// over MQTT, and who made it
+type AuditEntry struct {
+	ID         string    `json:"id" gorm:"primaryKey;type:text"`
+	Actor      string    `json:"actor" gorm:"type:text;index"`            // User named by the proxy header, else the client IP; "mqtt" for MQTT commands
+	Source     string    `json:"source" gorm:"type:text"`                 // api or mqtt
+	Method     string    `json:"method,omitempty" gorm:"type:text"`       // HTTP method; empty for MQTT commands
+	Route      string    `json:"route" gorm:"type:text"`                  // API route, e.g. /devices/:id, or "command" for MQTT
+	Target     string    `json:"target,omitempty" gorm:"type:text;index"` // ID or key of the device, profile, setting etc. changed
+	Detail     string    `json:"detail,omitempty" gorm:"type:text"`       // Entity and value of MQTT commands, or why they failed
+	Status     int       `json:"status,omitempty"`                        // HTTP status of API changes
+	Success    bool      `json:"success"`
+	RemoteAddr string    `json:"remote_addr,omitempty" gorm:"type:text"`
+	RequestID  string    `json:"request_id,omitempty" gorm:"type:text"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName stores audit entries in the "audit_log" table
+func (AuditEntry) TableName() string {
+	return "audit_log"
+}
+
+// AuditFilter represents filter options for querying the audit trail
+type AuditFilter struct {
+	Actor     string
+	Target    string
+	Source    string
+	StartTime *time.Time
+	EndTime   *time.Time
+	Limit     int
+	Offset    int
+}
@@ -0,0 +1,152 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrCapabilityUnsupported is returned when a device profile lacks the requested capability
+var ErrCapabilityUnsupported = errors.New("not supported by device profile")
+
+// Capabilities describes the generic controls a profile supports. OIDs may
+// contain placeholders ({outlet}, {source}) that are filled in per request, so
+// the same API endpoints work across vendors.
+type Capabilities struct {
+	OutletControl *OutletControl `json:"outlet_control,omitempty" yaml:"outlet_control,omitempty"`
+	SourceSwitch  *SourceSwitch  `json:"source_switch,omitempty" yaml:"source_switch,omitempty"`
+}
+
+// OutletControl describes how to switch, reboot and rename PDU outlets
+type OutletControl struct {
+	Count       int         `json:"count" yaml:"count"`                                   // Number of outlets
+	StateOID    string      `json:"state_oid" yaml:"state_oid"`                           // e.g. ".1.3.6.1.4.1.318.1.1.12.3.3.1.1.4.{outlet}"
+	OnValue     interface{} `json:"on_value" yaml:"on_value"`                             // Value written to turn an outlet on
+	OffValue    interface{} `json:"off_value" yaml:"off_value"`                           // Value written to turn an outlet off
+	RebootValue interface{} `json:"reboot_value,omitempty" yaml:"reboot_value,omitempty"` // Value written to power-cycle an outlet, if supported
+	Type        PDUType     `json:"type,omitempty" yaml:"type,omitempty"`                 // PDU type for state writes, guessed when empty
+	NameOID     string      `json:"name_oid,omitempty" yaml:"name_oid,omitempty"`         // e.g. ".1.3.6.1.4.1.318.1.1.12.3.4.1.1.2.{outlet}"
+	NameFormat  string      `json:"name_format,omitempty" yaml:"name_format,omitempty"`   // e.g. "{name},0,0,0,0", defaults to "{name}"
+
+	// Mapping names holding the polled outlet data, default "Outlet {outlet} State" etc.
+	StateEntity   string `json:"state_entity,omitempty" yaml:"state_entity,omitempty"`
+	NameEntity    string `json:"name_entity,omitempty" yaml:"name_entity,omitempty"`
+	CurrentEntity string `json:"current_entity,omitempty" yaml:"current_entity,omitempty"`
+}
+
+// SourceSwitch describes how to select the preferred source of a transfer switch
+type SourceSwitch struct {
+	OID     string         `json:"oid" yaml:"oid"`                               // Preferred source OID
+	Sources map[int]string `json:"sources" yaml:"sources"`                       // Written value -> source label
+	NameOID string         `json:"name_oid,omitempty" yaml:"name_oid,omitempty"` // e.g. ".1.3.6.1.4.1.318.1.1.8.5.3.2.1.6.{source}"
+}
+
+// StateCommand returns the OID and value that switch an outlet on or off
+func (o *OutletControl) StateCommand(outlet int, on bool) (string, interface{}, error) {
+	if err := o.checkOutlet(outlet); err != nil {
+		return "", nil, err
+	}
+	value := o.OffValue
+	if on {
+		value = o.OnValue
+	}
+	return expandTemplate(o.StateOID, "outlet", outlet), value, nil
+}
+
+// RebootCommand returns the OID and value that power-cycle an outlet
+func (o *OutletControl) RebootCommand(outlet int) (string, interface{}, error) {
+	if err := o.checkOutlet(outlet); err != nil {
+		return "", nil, err
+	}
+	if o.RebootValue == nil {
+		return "", nil, fmt.Errorf("outlet reboot %w", ErrCapabilityUnsupported)
+	}
+	return expandTemplate(o.StateOID, "outlet", outlet), o.RebootValue, nil
+}
+
+// NameCommand returns the OID and value that rename an outlet
+func (o *OutletControl) NameCommand(outlet int, name string) (string, string, error) {
+	if err := o.checkOutlet(outlet); err != nil {
+		return "", "", err
+	}
+	if o.NameOID == "" {
+		return "", "", fmt.Errorf("outlet naming %w", ErrCapabilityUnsupported)
+	}
+	format := o.NameFormat
+	if format == "" {
+		format = "{name}"
+	}
+	return expandTemplate(o.NameOID, "outlet", outlet), strings.ReplaceAll(format, "{name}", name), nil
+}
+
+func (o *OutletControl) checkOutlet(outlet int) error {
+	if o == nil || o.StateOID == "" {
+		return fmt.Errorf("outlet control %w", ErrCapabilityUnsupported)
+	}
+	if o.Count > 0 && (outlet < 1 || outlet > o.Count) {
+		return fmt.Errorf("outlet must be between 1 and %d", o.Count)
+	}
+	return nil
+}
+
+// SwitchCommand returns the OID and value that select a source, plus its label
+func (s *SourceSwitch) SwitchCommand(source int) (string, int, string, error) {
+	label, err := s.label(source)
+	if err != nil {
+		return "", 0, "", err
+	}
+	return s.OID, source, label, nil
+}
+
+// NameCommand returns the OID that renames a source
+func (s *SourceSwitch) NameCommand(source int) (string, error) {
+	if _, err := s.label(source); err != nil {
+		return "", err
+	}
+	if s.NameOID == "" {
+		return "", fmt.Errorf("source naming %w", ErrCapabilityUnsupported)
+	}
+	return expandTemplate(s.NameOID, "source", source), nil
+}
+
+func (s *SourceSwitch) label(source int) (string, error) {
+	if s == nil || s.OID == "" {
+		return "", fmt.Errorf("source switching %w", ErrCapabilityUnsupported)
+	}
+	label, ok := s.Sources[source]
+	if !ok {
+		return "", fmt.Errorf("unknown source %d", source)
+	}
+	return label, nil
+}
+
+// expandTemplate fills a {placeholder} in an OID or entity name template with an index
+func expandTemplate(template, placeholder string, index int) string {
+	return strings.ReplaceAll(template, "{"+placeholder+"}", strconv.Itoa(index))
+}
+
+func (c Capabilities) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *Capabilities) Scan(value interface{}) error {
+	if value == nil {
+		*c = Capabilities{}
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for Capabilities")
+	}
+
+	return json.Unmarshal(data, c)
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// ClusterNode is a bridge instance of a sharded cluster, kept alive by its
+// heartbeat in the shared database
+type ClusterNode struct {
+	ID        string      `json:"id" gorm:"primaryKey;type:text"`
+	Tags      StringSlice `json:"tags" gorm:"type:text"` // Shards the node polls besides its ID, e.g. a rack or VLAN
+	StartedAt time.Time   `json:"started_at"`
+	SeenAt    time.Time   `json:"seen_at" gorm:"index"` // Last heartbeat
+}
+
+// Serves reports whether the node polls devices of the shard: its own ID or
+// one of its tags
+func (n *ClusterNode) Serves(shard string) bool {
+	if shard == n.ID {
+		return true
+	}
+	for _, tag := range n.Tags {
+		if tag == shard {
+			return true
+		}
+	}
+	return false
+}
+
+// ShardOwner returns the ID of the node that polls a device. Devices with a
+// shard go to the nodes serving it, all others, and those whose shard no
+// live node serves, are spread over all nodes. Rendezvous hashing picks the
+// node, so a node joining or leaving only moves the devices it gains or had.
+func ShardOwner(deviceID, shard string, nodes []ClusterNode) string {
+	candidates := nodes
+	if shard != "" {
+		var serving []ClusterNode
+		for i := range nodes {
+			if nodes[i].Serves(shard) {
+				serving = append(serving, nodes[i])
+			}
+		}
+		if len(serving) > 0 {
+			candidates = serving
+		}
+	}
+
+	var owner string
+	var best uint64
+	for _, node := range candidates {
+		h := fnv.New64a()
+		h.Write([]byte(node.ID))
+		h.Write([]byte{0})
+		h.Write([]byte(deviceID))
+		if weight := h.Sum64(); owner == "" || weight > best {
+			owner, best = node.ID, weight
+		}
+	}
+	return owner
+}
+
+// ClusterStatus reports the nodes of a sharded cluster and this node's share
+type ClusterStatus struct {
+	Mode        string        `json:"mode"`
+	NodeID      string        `json:"node_id"`
+	Coordinator string        `json:"coordinator,omitempty"` // Node running scenes and release checks
+	Nodes       []ClusterNode `json:"nodes"`                 // Live nodes
+	Devices     int           `json:"devices"`               // Devices this node polls
+}
@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+// TracksConfig reports whether a mapping is configuration kept in snapshots:
+// writable settings other than switches and buttons, config entities, and
+// mappings marked track_drift
+func (m *OIDMapping) TracksConfig() bool {
+	if m.Computed {
+		return false
+	}
+	if m.TrackDrift || m.Category == "config" {
+		return true
+	}
+	return m.Writable && m.HAComponent != HAComponentSwitch && m.HAComponent != HAComponentButton
+}
+
+// ConfigValues maps configuration mapping names to their values
+type ConfigValues map[string]string
+
+func (v ConfigValues) Value() (driver.Value, error) {
+	if v == nil {
+		return "{}", nil
+	}
+	return json.Marshal(v)
+}
+
+func (v *ConfigValues) Scan(value interface{}) error {
+	if value == nil {
+		*v = make(ConfigValues)
+		return nil
+	}
+
+	var data []byte
+	switch val := value.(type) {
+	case []byte:
+		data = val
+	case string:
+		data = []byte(val)
+	default:
+		return errors.New("unsupported type for ConfigValues")
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// ConfigSnapshot is a device's configuration as read at one time. A snapshot
+// is only stored when it differs from the device's previous one.
+type ConfigSnapshot struct {
+	ID        string       `json:"id" gorm:"primaryKey;type:text"`
+	DeviceID  string       `json:"device_id" gorm:"not null;type:text;index"`
+	Values    ConfigValues `json:"values" gorm:"type:text"`
+	CreatedAt time.Time    `json:"created_at" gorm:"index"`
+}
+
+// ConfigChange is a configuration value that differs between two snapshots
+type ConfigChange struct {
+	Name string  `json:"name"`
+	Old  *string `json:"old"` // nil when the value was not read before
+	New  *string `json:"new"` // nil when the value is no longer read
+}
+
+// ConfigDriftEntry lists what changed when a snapshot was taken
+type ConfigDriftEntry struct {
+	DetectedAt time.Time      `json:"detected_at"`
+	PreviousAt time.Time      `json:"previous_at"` // Snapshot compared against
+	Changes    []ConfigChange `json:"changes"`
+}
+
+// ConfigDrift is the configuration history of a device
+type ConfigDrift struct {
+	DeviceID   string             `json:"device_id"`
+	Current    ConfigValues       `json:"current"`
+	BaselineAt *time.Time         `json:"baseline_at,omitempty"` // Oldest snapshot kept
+	CheckedAt  *time.Time         `json:"checked_at,omitempty"`  // Last time the configuration was read
+	Changes    []ConfigDriftEntry `json:"changes"`               // Newest first
+}
+
+// DiffConfig returns the values that differ from old to new, by name
+func DiffConfig(old, new ConfigValues) []ConfigChange {
+	var changes []ConfigChange
+	for name, value := range new {
+		value := value
+		if previous, ok := old[name]; !ok {
+			changes = append(changes, ConfigChange{Name: name, New: &value})
+		} else if previous != value {
+			changes = append(changes, ConfigChange{Name: name, Old: &previous, New: &value})
+		}
+	}
+	for name, previous := range old {
+		previous := previous
+		if _, ok := new[name]; !ok {
+			changes = append(changes, ConfigChange{Name: name, Old: &previous})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Credential is a named set of SNMP credentials shared by devices, so a
+// community can be rotated for many devices in one update
+type Credential struct {
+	ID             string      `json:"id" gorm:"primaryKey;type:text"`
+	Name           string      `json:"name" gorm:"not null;type:text"`
+	SNMPVersion    SNMPVersion `json:"snmp_version" gorm:"not null;type:text"`
+	Community      string      `json:"community" gorm:"not null;type:text"` // v1/v2c community or v3 user name
+	WriteCommunity string      `json:"write_community" gorm:"type:text"`    // Optional community for SNMP SET
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
+
+// Validate checks that the credential can be used to reach a device
+func (c *Credential) Validate() error {
+	if c.Name == "" {
+		return errors.New("credential name is required")
+	}
+	switch c.SNMPVersion {
+	case SNMPv1, SNMPv2c, SNMPv3:
+	default:
+		return errors.New("snmp_version must be v1, v2c or v3")
+	}
+	if c.Community == "" {
+		return errors.New("community is required")
+	}
+	return nil
+}
+
+// ApplyTo copies the credential onto a device that references it
+func (c *Credential) ApplyTo(device *Device) {
+	device.SNMPVersion = c.SNMPVersion
+	device.Community = c.Community
+	device.WriteCommunity = c.WriteCommunity
+}
+
+// CredentialCreateRequest is used for creating a new credential set
+type CredentialCreateRequest struct {
+	Name           string      `json:"name" binding:"required"`
+	SNMPVersion    SNMPVersion `json:"snmp_version" binding:"required,oneof=v1 v2c v3"`
+	Community      string      `json:"community" binding:"required"`
+	WriteCommunity string      `json:"write_community"`
+}
+
+// CredentialUpdateRequest is used for updating an existing credential set
+type CredentialUpdateRequest struct {
+	Name           *string      `json:"name,omitempty"`
+	SNMPVersion    *SNMPVersion `json:"snmp_version,omitempty" binding:"omitempty,oneof=v1 v2c v3"`
+	Community      *string      `json:"community,omitempty"`
+	WriteCommunity *string      `json:"write_community,omitempty"`
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// DatabaseStatus reports database connectivity and degraded-mode write counters
+type DatabaseStatus struct {
+	Driver         string     `json:"driver"`
+	Healthy        bool       `json:"healthy"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastCheck      *time.Time `json:"last_check,omitempty"`
+	DownSince      *time.Time `json:"down_since,omitempty"`
+	Retries        uint64     `json:"retries"`         // Operations retried after a transient error
+	BufferedWrites int        `json:"buffered_writes"` // Writes waiting for the database to come back
+	FlushedWrites  uint64     `json:"flushed_writes"`  // Buffered writes stored after recovery
+	DroppedWrites  uint64     `json:"dropped_writes"`  // Writes skipped during an outage or lost from a full buffer
+}
+
+// VacuumResult reports a database vacuum and the space it reclaimed
+type VacuumResult struct {
+	StartedAt  time.Time `json:"started_at"`
+	Duration   float64   `json:"duration"`    // Seconds
+	SizeBefore int64     `json:"size_before"` // Bytes
+	SizeAfter  int64     `json:"size_after"`  // Bytes
+	Reclaimed  int64     `json:"reclaimed"`   // Bytes
+}
+
+// MaintenanceStatus reports the database size and scheduled vacuums
+type MaintenanceStatus struct {
+	Size       int64         `json:"size"` // Bytes
+	LastVacuum *VacuumResult `json:"last_vacuum,omitempty"`
+	NextVacuum *time.Time    `json:"next_vacuum,omitempty"` // Unset when not scheduled
+}
@@ -2,8 +2,11 @@ package domain
 
 import (
 	"database/sql/driver"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -45,50 +48,246 @@ func (l *Labels) Scan(value interface{}) error {
 	return json.Unmarshal(data, l)
 }
 
+// DomoticzIndexes maps mapping names to the idx of the Domoticz device
+// their values are sent to
+type DomoticzIndexes map[string]int
+
+func (d DomoticzIndexes) Value() (driver.Value, error) {
+	if d == nil {
+		return "{}", nil
+	}
+	return json.Marshal(d)
+}
+
+func (d *DomoticzIndexes) Scan(value interface{}) error {
+	if value == nil {
+		*d = make(DomoticzIndexes)
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for DomoticzIndexes")
+	}
+
+	return json.Unmarshal(data, d)
+}
+
 // Device represents an SNMP device
 type Device struct {
-	ID             string      `json:"id" gorm:"primaryKey;type:text"`
-	Name           string      `json:"name" gorm:"not null;type:text"`
-	IPAddress      string      `json:"ip_address" gorm:"not null;type:text"`
-	Port           int         `json:"port" gorm:"default:161"`
-	Community      string      `json:"community" gorm:"not null;type:text"`
-	WriteCommunity string      `json:"write_community" gorm:"type:text"` // Optional community for SNMP SET (e.g., 'private' for Energenie)
-	SNMPVersion    SNMPVersion `json:"snmp_version" gorm:"not null;type:text"`
-	ProfileID      string      `json:"profile_id" gorm:"type:text"`
-	PollInterval   int         `json:"poll_interval" gorm:"type:integer"` // seconds, 0 = use default
-	Enabled        bool        `json:"enabled" gorm:"default:true"`
-	Labels         Labels      `json:"labels" gorm:"type:text"`
-	CreatedAt      time.Time   `json:"created_at"`
-	UpdatedAt      time.Time   `json:"updated_at"`
-	LastSeen       *time.Time  `json:"last_seen,omitempty"`
+	ID              string          `json:"id" gorm:"primaryKey;type:text"`
+	Name            string          `json:"name" gorm:"not null;type:text"`
+	IPAddress       string          `json:"ip_address" gorm:"not null;type:text"`
+	Port            int             `json:"port" gorm:"default:161"`
+	Community       string          `json:"community" gorm:"not null;type:text"`
+	WriteCommunity  string          `json:"write_community" gorm:"type:text"`               // Optional community for SNMP SET (e.g., 'private' for Energenie)
+	CredentialID    string          `json:"credential_id,omitempty" gorm:"type:text;index"` // Shared credential set; overrides snmp_version, community and write_community
+	BindAddress     string          `json:"bind_address,omitempty" gorm:"type:text"`        // Local IP or interface to send SNMP requests from, overrides snmp.bind_address
+	ProxyHost       string          `json:"proxy_host,omitempty" gorm:"type:text"`          // SNMP proxy agent that relays requests to the device
+	ProxyPort       int             `json:"proxy_port,omitempty" gorm:"type:integer"`       // 0 = 161
+	ProxyCommunity  string          `json:"proxy_community,omitempty" gorm:"type:text"`     // v1/v2c community the proxy maps to this device
+	ContextName     string          `json:"context_name,omitempty" gorm:"type:text"`        // SNMPv3 context selecting the device behind a proxy
+	ContextEngineID string          `json:"context_engine_id,omitempty" gorm:"type:text"`   // SNMPv3 contextEngineID as hex, for engine ID forwarding
+	SNMPVersion     SNMPVersion     `json:"snmp_version" gorm:"not null;type:text"`
+	ProfileID       string          `json:"profile_id" gorm:"type:text"`
+	PollInterval    int             `json:"poll_interval" gorm:"type:integer"`    // seconds, 0 = use default
+	PublishInterval int             `json:"publish_interval" gorm:"type:integer"` // seconds between MQTT state publishes, 0 = every poll
+	BatchSize       int             `json:"batch_size" gorm:"type:integer"`       // OIDs per SNMP GET, 0 = 10 (1 for v1)
+	RequestDelay    int             `json:"request_delay" gorm:"type:integer"`    // milliseconds between the SNMP requests of a poll
+	MaxRepetitions  int             `json:"max_repetitions" gorm:"type:integer"`  // GETBULK max-repetitions of walks, 0 = 50
+	ConfirmWrites   bool            `json:"confirm_writes" gorm:"default:false"`  // MQTT commands wait for the Confirm Command button
+	Enabled         bool            `json:"enabled" gorm:"default:true"`
+	Labels          Labels          `json:"labels" gorm:"type:text"`
+	Notes           string          `json:"notes,omitempty" gorm:"type:text"`
+	Site            string          `json:"site,omitempty" gorm:"type:text;index"` // Site the device reports from, defaults to the bridge site
+	Location        string          `json:"location,omitempty" gorm:"type:text"`   // Room or site, used as HA suggested area
+	Rack            string          `json:"rack,omitempty" gorm:"type:text"`
+	AssetTag        string          `json:"asset_tag,omitempty" gorm:"type:text"`
+	Contact         string          `json:"contact,omitempty" gorm:"type:text"`
+	HAName          string          `json:"ha_name,omitempty" gorm:"type:text"`          // Device name shown in Home Assistant, defaults to Name
+	Manufacturer    string          `json:"manufacturer,omitempty" gorm:"type:text"`     // Overrides the profile manufacturer in discovery
+	Model           string          `json:"model,omitempty" gorm:"type:text"`            // Overrides the profile model in discovery
+	SelfTestDays    int             `json:"self_test_interval_days" gorm:"type:integer"` // Run a battery self-test every N days, 0 = never
+	CustomMappings  OIDMappings     `json:"custom_mappings" gorm:"type:text"`            // Extra OID mappings merged with the profile at poll time
+	Alarms          AlarmThresholds `json:"alarms" gorm:"type:text"`                     // Device-level threshold alarms, override profile ones by name
+	DomoticzIdx     DomoticzIndexes `json:"domoticz_idx,omitempty" gorm:"type:text"`     // Domoticz device idx per mapping name, for the domoticz adapter
+	Shard           string          `json:"shard,omitempty" gorm:"type:text"`            // Cluster node tag or ID to poll the device from in sharded mode
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	LastSeen        *time.Time      `json:"last_seen,omitempty"`
+	LastError       string          `json:"last_error,omitempty" gorm:"type:text"` // Most recent poll error, kept after recovery
+	LastErrorAt     *time.Time      `json:"last_error_at,omitempty"`
+	ErrorCount      int             `json:"error_count" gorm:"default:0"` // Failed polls since the device was created
+
+	Discovery *DiscoveryStatus `json:"discovery,omitempty" gorm:"-"` // Home Assistant discovery publish status, filled in by the API
+}
+
+// SNMPTarget returns the host and port SNMP requests are sent to: the proxy
+// when one is set, else the device itself
+func (d *Device) SNMPTarget() (string, int) {
+	if d.ProxyHost != "" {
+		port := d.ProxyPort
+		if port == 0 {
+			port = 161
+		}
+		return d.ProxyHost, port
+	}
+	port := d.Port
+	if port == 0 {
+		port = 161
+	}
+	return d.IPAddress, port
+}
+
+// ReadCommunity returns the community (v3 user name) for GET requests. When
+// polled through a v1/v2c proxy, the proxy's community for this device is used.
+func (d *Device) ReadCommunity() string {
+	if d.ProxyHost != "" && d.ProxyCommunity != "" && d.SNMPVersion != SNMPv3 {
+		return d.ProxyCommunity
+	}
+	return d.Community
+}
+
+// SetCommunity returns the community for SET requests: the write community
+// if set, otherwise the read community
+func (d *Device) SetCommunity() string {
+	if d.WriteCommunity != "" {
+		return d.WriteCommunity
+	}
+	return d.ReadCommunity()
+}
+
+// PollBatchSize returns the number of OIDs requested per SNMP GET. Unless
+// set, SNMPv1 agents get one OID at a time, as many fail whole batches with
+// packet sanity errors, and others 10.
+func (d *Device) PollBatchSize() int {
+	switch {
+	case d.BatchSize > 0:
+		return d.BatchSize
+	case d.SNMPVersion == SNMPv1:
+		return 1
+	}
+	return 10
+}
+
+// DecodeEngineID decodes a hex SNMP engine ID, with optional "0x" prefix and
+// ":" separators, into its raw bytes. Engine IDs are 5 to 32 bytes long.
+func DecodeEngineID(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	b, err := hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+	if err != nil {
+		return "", fmt.Errorf("invalid engine ID: %w", err)
+	}
+	if len(b) < 5 || len(b) > 32 {
+		return "", fmt.Errorf("invalid engine ID: must be 5 to 32 bytes, got %d", len(b))
+	}
+	return string(b), nil
+}
+
+// AssetAttributes returns the device's inventory metadata, omitting empty fields
+func (d *Device) AssetAttributes() map[string]string {
+	attrs := make(map[string]string)
+	for key, value := range map[string]string{
+		"notes":     d.Notes,
+		"site":      d.Site,
+		"location":  d.Location,
+		"rack":      d.Rack,
+		"asset_tag": d.AssetTag,
+		"contact":   d.Contact,
+	} {
+		if value != "" {
+			attrs[key] = value
+		}
+	}
+	return attrs
 }
 
 // DeviceCreateRequest is used for creating a new device
 type DeviceCreateRequest struct {
-	Name           string            `json:"name" binding:"required"`
-	IPAddress      string            `json:"ip_address" binding:"required,ip"`
-	Port           int               `json:"port"`
-	Community      string            `json:"community" binding:"required"`
-	WriteCommunity string            `json:"write_community"` // Optional community for SNMP SET
-	SNMPVersion    SNMPVersion       `json:"snmp_version" binding:"required,oneof=v1 v2c v3"`
-	ProfileID      string            `json:"profile_id"`
-	PollInterval   int               `json:"poll_interval"`
-	Enabled        bool              `json:"enabled"`
-	Labels         map[string]string `json:"labels"`
+	Name            string            `json:"name" binding:"required"`
+	IPAddress       string            `json:"ip_address" binding:"required,ip"`
+	Port            int               `json:"port" binding:"omitempty,port"`
+	Community       string            `json:"community" binding:"required_without=CredentialID"`
+	WriteCommunity  string            `json:"write_community"` // Optional community for SNMP SET
+	CredentialID    string            `json:"credential_id"`   // Shared credential set replacing snmp_version and communities
+	BindAddress     string            `json:"bind_address"`    // Optional local IP or interface for SNMP requests
+	ProxyHost       string            `json:"proxy_host" binding:"omitempty,hostname|ip"`
+	ProxyPort       int               `json:"proxy_port" binding:"min=0,max=65535"`
+	ProxyCommunity  string            `json:"proxy_community"`
+	ContextName     string            `json:"context_name"`
+	ContextEngineID string            `json:"context_engine_id"`
+	SNMPVersion     SNMPVersion       `json:"snmp_version" binding:"required_without=CredentialID,omitempty,oneof=v1 v2c v3"`
+	ProfileID       string            `json:"profile_id"`
+	PollInterval    int               `json:"poll_interval" binding:"omitempty,poll_interval"`
+	PublishInterval int               `json:"publish_interval" binding:"min=0"`
+	BatchSize       int               `json:"batch_size" binding:"min=0,max=100"`
+	RequestDelay    int               `json:"request_delay" binding:"min=0,max=10000"`
+	MaxRepetitions  int               `json:"max_repetitions" binding:"min=0,max=255"`
+	ConfirmWrites   bool              `json:"confirm_writes"`
+	Enabled         bool              `json:"enabled"`
+	Labels          map[string]string `json:"labels"`
+	Notes           string            `json:"notes"`
+	Site            string            `json:"site"`
+	Location        string            `json:"location"`
+	Rack            string            `json:"rack"`
+	AssetTag        string            `json:"asset_tag"`
+	Contact         string            `json:"contact"`
+	HAName          string            `json:"ha_name"`
+	Manufacturer    string            `json:"manufacturer"`
+	Model           string            `json:"model"`
+	SelfTestDays    int               `json:"self_test_interval_days" binding:"min=0"`
+	CustomMappings  []OIDMapping      `json:"custom_mappings" binding:"dive"`
+	Alarms          []AlarmThreshold  `json:"alarms"`
+	DomoticzIdx     map[string]int    `json:"domoticz_idx"`
+	Shard           string            `json:"shard"`
 }
 
 // DeviceUpdateRequest is used for updating an existing device
 type DeviceUpdateRequest struct {
-	Name           *string           `json:"name,omitempty"`
-	IPAddress      *string           `json:"ip_address,omitempty" binding:"omitempty,ip"`
-	Port           *int              `json:"port,omitempty"`
-	Community      *string           `json:"community,omitempty"`
-	WriteCommunity *string           `json:"write_community,omitempty"` // Optional community for SNMP SET
-	SNMPVersion    *SNMPVersion      `json:"snmp_version,omitempty" binding:"omitempty,oneof=v1 v2c v3"`
-	ProfileID      *string           `json:"profile_id,omitempty"`
-	PollInterval   *int              `json:"poll_interval,omitempty"`
-	Enabled        *bool             `json:"enabled,omitempty"`
-	Labels         map[string]string `json:"labels,omitempty"`
+	Name            *string           `json:"name,omitempty"`
+	IPAddress       *string           `json:"ip_address,omitempty" binding:"omitempty,ip"`
+	Port            *int              `json:"port,omitempty" binding:"omitempty,port"`
+	Community       *string           `json:"community,omitempty"`
+	WriteCommunity  *string           `json:"write_community,omitempty"` // Optional community for SNMP SET
+	CredentialID    *string           `json:"credential_id,omitempty"`   // Empty detaches the device from its credential set
+	BindAddress     *string           `json:"bind_address,omitempty"`    // Optional local IP or interface for SNMP requests
+	ProxyHost       *string           `json:"proxy_host,omitempty" binding:"omitempty,hostname|ip"`
+	ProxyPort       *int              `json:"proxy_port,omitempty" binding:"omitempty,min=0,max=65535"`
+	ProxyCommunity  *string           `json:"proxy_community,omitempty"`
+	ContextName     *string           `json:"context_name,omitempty"`
+	ContextEngineID *string           `json:"context_engine_id,omitempty"`
+	SNMPVersion     *SNMPVersion      `json:"snmp_version,omitempty" binding:"omitempty,oneof=v1 v2c v3"`
+	ProfileID       *string           `json:"profile_id,omitempty"`
+	PollInterval    *int              `json:"poll_interval,omitempty" binding:"omitempty,poll_interval"`
+	PublishInterval *int              `json:"publish_interval,omitempty" binding:"omitempty,min=0"`
+	BatchSize       *int              `json:"batch_size,omitempty" binding:"omitempty,min=0,max=100"`
+	RequestDelay    *int              `json:"request_delay,omitempty" binding:"omitempty,min=0,max=10000"`
+	MaxRepetitions  *int              `json:"max_repetitions,omitempty" binding:"omitempty,min=0,max=255"`
+	ConfirmWrites   *bool             `json:"confirm_writes,omitempty"`
+	Enabled         *bool             `json:"enabled,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	Notes           *string           `json:"notes,omitempty"`
+	Site            *string           `json:"site,omitempty"`
+	Location        *string           `json:"location,omitempty"`
+	Rack            *string           `json:"rack,omitempty"`
+	AssetTag        *string           `json:"asset_tag,omitempty"`
+	Contact         *string           `json:"contact,omitempty"`
+	HAName          *string           `json:"ha_name,omitempty"`
+	Manufacturer    *string           `json:"manufacturer,omitempty"`
+	Model           *string           `json:"model,omitempty"`
+	SelfTestDays    *int              `json:"self_test_interval_days,omitempty" binding:"omitempty,min=0"`
+	CustomMappings  []OIDMapping      `json:"custom_mappings,omitempty" binding:"dive"`
+	Alarms          []AlarmThreshold  `json:"alarms,omitempty"`
+	DomoticzIdx     map[string]int    `json:"domoticz_idx,omitempty"`
+	Shard           *string           `json:"shard,omitempty"`
 }
 
 // DeviceState represents the current state of a device
@@ -98,14 +297,24 @@ type DeviceState struct {
 	LastPoll   time.Time              `json:"last_poll"`
 	Values     map[string]interface{} `json:"values"`
 	Errors     []string               `json:"errors,omitempty"`
+	Attributes map[string]string      `json:"attributes,omitempty"` // Asset metadata (location, rack, ...)
+
+	UpdatedAt map[string]time.Time `json:"updated_at,omitempty"` // When each value was last polled
+	Stale     []string             `json:"stale,omitempty"`      // Values not refreshed for several polls, published as unknown
 }
 
 // TestConnectionRequest is used for testing SNMP connection
 type TestConnectionRequest struct {
-	IPAddress   string      `json:"ip_address" binding:"required,ip"`
-	Port        int         `json:"port"`
-	Community   string      `json:"community" binding:"required"`
-	SNMPVersion SNMPVersion `json:"snmp_version" binding:"required,oneof=v1 v2c v3"`
+	IPAddress       string      `json:"ip_address" binding:"required,ip"`
+	Port            int         `json:"port" binding:"omitempty,port"`
+	Community       string      `json:"community" binding:"required"`
+	SNMPVersion     SNMPVersion `json:"snmp_version" binding:"required,oneof=v1 v2c v3"`
+	BindAddress     string      `json:"bind_address"` // Optional local IP or interface for SNMP requests
+	ProxyHost       string      `json:"proxy_host" binding:"omitempty,hostname|ip"`
+	ProxyPort       int         `json:"proxy_port" binding:"min=0,max=65535"`
+	ProxyCommunity  string      `json:"proxy_community"`
+	ContextName     string      `json:"context_name"`
+	ContextEngineID string      `json:"context_engine_id"`
 }
 
 // TestConnectionResponse contains the result of a connection test
@@ -0,0 +1,59 @@
+package domain
+
+// DeviceSeed is one device from a seed list exported by another SNMP
+// monitoring tool
+type DeviceSeed struct {
+	Line        int         `json:"line,omitempty"` // Line in the seed list, for error reports
+	Hostname    string      `json:"hostname"`       // Host name or IP address
+	Name        string      `json:"name,omitempty"`
+	Community   string      `json:"community,omitempty"`
+	SNMPVersion SNMPVersion `json:"snmp_version,omitempty"`
+	Port        int         `json:"port,omitempty"`
+}
+
+// DeviceImportRequest imports a seed list in one of the supported formats:
+//
+//	text  one device per line, "hostname [community] [v1|v2c|v3] [port]"
+//	      as accepted by Observium's add_device.php and LibreNMS's addhost.php
+//	csv   a header row naming hostname, community, version, port and name columns
+//	json  an array of DeviceSeed objects
+type DeviceImportRequest struct {
+	Content       string      `json:"content" binding:"required"`
+	Format        string      `json:"format" binding:"omitempty,oneof=auto text csv json"` // Empty or auto detects the format
+	Community     string      `json:"community"`                                           // For seeds without one
+	SNMPVersion   SNMPVersion `json:"snmp_version" binding:"omitempty,oneof=v1 v2c v3"`    // For seeds without one
+	Site          string      `json:"site"`
+	Enabled       *bool       `json:"enabled"`        // Default true
+	DetectProfile *bool       `json:"detect_profile"` // Query sysObjectID to pick a profile; default true
+	DryRun        bool        `json:"dry_run"`        // Report what would be created without creating it
+}
+
+// DeviceImportStatus is the outcome for one seed
+type DeviceImportStatus string
+
+const (
+	DeviceImportCreated DeviceImportStatus = "created"
+	DeviceImportPlanned DeviceImportStatus = "planned" // Dry run
+	DeviceImportSkipped DeviceImportStatus = "skipped" // Already exists
+	DeviceImportFailed  DeviceImportStatus = "failed"
+)
+
+// DeviceImportItem reports what happened to one seed
+type DeviceImportItem struct {
+	Line      int                `json:"line,omitempty"`
+	Hostname  string             `json:"hostname"`
+	IPAddress string             `json:"ip_address,omitempty"`
+	Status    DeviceImportStatus `json:"status"`
+	DeviceID  string             `json:"device_id,omitempty"`
+	ProfileID string             `json:"profile_id,omitempty"`
+	Message   string             `json:"message,omitempty"`
+}
+
+// DeviceImportResult summarizes an import
+type DeviceImportResult struct {
+	Created int                `json:"created"`
+	Skipped int                `json:"skipped"`
+	Failed  int                `json:"failed"`
+	DryRun  bool               `json:"dry_run,omitempty"`
+	Items   []DeviceImportItem `json:"items"`
+}
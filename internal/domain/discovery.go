@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// DiscoveryStatus is the outcome of the last attempt to publish a device's
+// Home Assistant discovery configs
+type DiscoveryStatus struct {
+	DeviceID    string    `json:"device_id"`
+	Published   bool      `json:"published"`              // The last attempt published every config
+	LastAttempt time.Time `json:"last_attempt,omitempty"` // Zero until the broker was reachable
+	Error       string    `json:"error,omitempty"`
+}
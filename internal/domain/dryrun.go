@@ -0,0 +1,48 @@
+package domain
+
+import "time"
+
+// SettingReadOnly turns on read-only mode with "true": SNMP SETs from the
+// API, MQTT, scenes and schedules are logged and reported but not sent
+const SettingReadOnly = "commands.read_only"
+
+// Settings describing the last change of read-only mode through the write lock
+const (
+	SettingReadOnlyReason    = "commands.read_only_reason"
+	SettingReadOnlyChangedBy = "commands.read_only_changed_by"
+	SettingReadOnlyChangedAt = "commands.read_only_changed_at" // RFC 3339
+)
+
+// IsWriteLockSetting reports whether a setting belongs to the write lock,
+// which is only switched through the write lock so its change is recorded
+// and announced
+func IsWriteLockSetting(key string) bool {
+	switch key {
+	case SettingReadOnly, SettingReadOnlyReason, SettingReadOnlyChangedBy, SettingReadOnlyChangedAt:
+		return true
+	}
+	return false
+}
+
+// WriteLock is the bridge-wide write lock: read-only mode, with why and by
+// whom it was last switched
+type WriteLock struct {
+	Locked    bool       `json:"locked"`
+	Reason    string     `json:"reason,omitempty"`
+	ChangedBy string     `json:"changed_by,omitempty"`
+	ChangedAt *time.Time `json:"changed_at,omitempty"`
+}
+
+// PlannedSet is an SNMP SET held back by a dry run or read-only mode
+type PlannedSet struct {
+	DeviceID string      `json:"device_id"`
+	OID      string      `json:"oid"`
+	Value    interface{} `json:"value"`
+	Type     PDUType     `json:"type,omitempty"`
+}
+
+// DryRun reports the SNMP SETs a request would have sent
+type DryRun struct {
+	ReadOnly bool         `json:"read_only"` // Held back by read-only mode, with or without dry_run requested
+	Sets     []PlannedSet `json:"sets"`
+}
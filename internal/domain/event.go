@@ -0,0 +1,43 @@
+package domain
+
+import "time"
+
+// EventType represents the kind of recorded device event
+type EventType string
+
+const (
+	EventTypeStateChange EventType = "state_change" // A discrete entity changed value (outlet, source, status)
+	EventTypeOnline      EventType = "online"       // Device started responding to polls
+	EventTypeOffline     EventType = "offline"      // Device stopped responding to polls
+	EventTypeSelfTest    EventType = "self_test"    // Battery self-test started or completed
+	EventTypeConfigDrift EventType = "config_drift" // A configuration value changed between snapshots
+)
+
+// DeviceEvent is a discrete change recorded in the device timeline
+type DeviceEvent struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:text"`
+	DeviceID  string    `json:"device_id" gorm:"not null;type:text;index"`
+	Type      EventType `json:"type" gorm:"not null;type:text"`
+	Entity    string    `json:"entity,omitempty" gorm:"type:text"`
+	OldValue  string    `json:"old_value,omitempty" gorm:"type:text"`
+	NewValue  string    `json:"new_value,omitempty" gorm:"type:text"`
+	Message   string    `json:"message" gorm:"type:text"`
+	Actor     string    `json:"actor,omitempty" gorm:"type:text"` // Who sent the command that caused a state change
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName stores device events in the "events" table
+func (DeviceEvent) TableName() string {
+	return "events"
+}
+
+// EventFilter represents filter options for querying device events
+type EventFilter struct {
+	DeviceID  string
+	DeviceIDs []string // Restricts results to these devices, e.g. those of one site
+	Type      EventType
+	StartTime *time.Time
+	EndTime   *time.Time
+	Limit     int
+	Offset    int
+}
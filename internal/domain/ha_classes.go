@@ -0,0 +1,92 @@
+package domain
+
+import "fmt"
+
+// sensorDeviceClasses are the device classes Home Assistant accepts for sensors
+var sensorDeviceClasses = classSet(
+	"apparent_power", "aqi", "area", "atmospheric_pressure", "battery",
+	"blood_glucose_concentration", "carbon_dioxide", "carbon_monoxide",
+	"conductivity", "current", "data_rate", "data_size", "date", "distance",
+	"duration", "energy", "energy_distance", "energy_storage", "enum",
+	"frequency", "gas", "humidity", "illuminance", "irradiance", "moisture",
+	"monetary", "nitrogen_dioxide", "nitrogen_monoxide", "nitrous_oxide",
+	"ozone", "ph", "pm1", "pm10", "pm25", "power", "power_factor",
+	"precipitation", "precipitation_intensity", "pressure", "reactive_energy",
+	"reactive_power", "signal_strength", "sound_pressure", "speed",
+	"sulphur_dioxide", "temperature", "timestamp", "volatile_organic_compounds",
+	"volatile_organic_compounds_parts", "voltage", "volume", "volume_flow_rate",
+	"volume_storage", "water", "weight", "wind_direction", "wind_speed",
+)
+
+// haDeviceClasses are the device classes Home Assistant accepts per component.
+// Components missing from the map take no device class.
+var haDeviceClasses = map[HAComponent]map[string]bool{
+	HAComponentSensor: sensorDeviceClasses,
+	HAComponentBinarySensor: classSet(
+		"battery", "battery_charging", "carbon_monoxide", "cold", "connectivity",
+		"door", "garage_door", "gas", "heat", "light", "lock", "moisture",
+		"motion", "moving", "occupancy", "opening", "plug", "power", "presence",
+		"problem", "running", "safety", "smoke", "sound", "tamper", "update",
+		"vibration", "window",
+	),
+	HAComponentSwitch: classSet("outlet", "switch"),
+	HAComponentButton: classSet("identify", "restart", "update"),
+	HAComponentNumber: numberDeviceClasses(),
+}
+
+// haStateClasses are the state classes Home Assistant accepts; only sensors take one
+var haStateClasses = classSet("measurement", "measurement_angle", "total", "total_increasing")
+
+func classSet(classes ...string) map[string]bool {
+	set := make(map[string]bool, len(classes))
+	for _, class := range classes {
+		set[class] = true
+	}
+	return set
+}
+
+// numberDeviceClasses are the sensor classes without the date, enum and
+// timestamp ones, which numbers do not support
+func numberDeviceClasses() map[string]bool {
+	set := make(map[string]bool, len(sensorDeviceClasses))
+	for class := range sensorDeviceClasses {
+		switch class {
+		case "date", "enum", "timestamp":
+		default:
+			set[class] = true
+		}
+	}
+	return set
+}
+
+// ValidDeviceClass reports whether Home Assistant accepts the device class for the component
+func ValidDeviceClass(component HAComponent, class string) bool {
+	return haDeviceClasses[component][class]
+}
+
+// ValidStateClass reports whether Home Assistant accepts the state class for the component
+func ValidStateClass(component HAComponent, class string) bool {
+	return component == HAComponentSensor && haStateClasses[class]
+}
+
+// ClassWarnings describes device and state classes Home Assistant would reject
+func (m *OIDMapping) ClassWarnings() []string {
+	var warnings []string
+	if m.DeviceClass != "" && !ValidDeviceClass(m.HAComponent, m.DeviceClass) {
+		warnings = append(warnings, fmt.Sprintf("mapping %q: device_class %q is not valid for %s", m.Name, m.DeviceClass, m.HAComponent))
+	}
+	if m.StateClass != "" && !ValidStateClass(m.HAComponent, m.StateClass) {
+		warnings = append(warnings, fmt.Sprintf("mapping %q: state_class %q is not valid for %s", m.Name, m.StateClass, m.HAComponent))
+	}
+	return warnings
+}
+
+// ClassWarnings describes the device and state classes of all mappings that
+// Home Assistant would reject
+func (p *Profile) ClassWarnings() []string {
+	var warnings []string
+	for i := range p.OIDMappings {
+		warnings = append(warnings, p.OIDMappings[i].ClassWarnings()...)
+	}
+	return warnings
+}
@@ -0,0 +1,179 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severities of statistics issues
+const (
+	StatisticsError   = "error"   // Home Assistant rejects the setting, so no statistics are kept
+	StatisticsWarning = "warning" // Accepted, but no long-term statistics are kept
+)
+
+// totalOnlyClasses are sensor device classes that accumulate, for which
+// Home Assistant only accepts the total and total_increasing state classes
+var totalOnlyClasses = classSet("energy", "gas", "monetary", "reactive_energy", "volume", "water")
+
+// measurementOnlyClasses are sensor device classes for instantaneous values,
+// for which Home Assistant only accepts the measurement state class
+var measurementOnlyClasses = classSet(
+	"apparent_power", "battery", "current", "frequency", "humidity", "power",
+	"power_factor", "reactive_power", "signal_strength", "temperature", "voltage",
+)
+
+// nonNumericClasses are sensor device classes whose state is not a number
+var nonNumericClasses = classSet("date", "enum", "timestamp")
+
+// statisticsUnits are the units Home Assistant accepts for common sensor
+// device classes; classes missing from the map are not checked
+var statisticsUnits = map[string]map[string]bool{
+	"apparent_power":  classSet("mVA", "VA", "kVA"),
+	"battery":         classSet("%"),
+	"current":         classSet("mA", "A"),
+	"duration":        classSet("µs", "ms", "s", "min", "h", "d"),
+	"energy":          classSet("mWh", "Wh", "kWh", "MWh", "GWh", "TWh", "J", "kJ", "MJ", "GJ"),
+	"frequency":       classSet("Hz", "kHz", "MHz", "GHz"),
+	"humidity":        classSet("%"),
+	"power":           classSet("mW", "W", "kW", "MW", "GW", "TW"),
+	"power_factor":    classSet("", "%"),
+	"reactive_power":  classSet("mvar", "var", "kvar"),
+	"reactive_energy": classSet("varh", "kvarh"),
+	"signal_strength": classSet("dB", "dBm"),
+	"temperature":     classSet("°C", "°F", "K"),
+	"voltage":         classSet("µV", "mV", "V", "kV", "MV"),
+}
+
+// StatisticsIssue describes a published sensor Home Assistant keeps no
+// long-term statistics for, or a class and unit combination it rejects
+type StatisticsIssue struct {
+	DeviceID    string `json:"device_id,omitempty"`
+	DeviceName  string `json:"device_name,omitempty"`
+	Mapping     string `json:"mapping"`
+	Severity    string `json:"severity"`
+	Problem     string `json:"problem"`
+	DeviceClass string `json:"device_class,omitempty"`
+	StateClass  string `json:"state_class,omitempty"`
+	Unit        string `json:"unit,omitempty"`
+	// State class that fixes the issue; empty removes it. Absent when the
+	// issue cannot be fixed through the state class.
+	SuggestedStateClass *string `json:"suggested_state_class,omitempty"`
+	AutoFixed           bool    `json:"auto_fixed,omitempty"` // Discovery publishes the suggested state class
+}
+
+// StatisticsReport is the result of auditing all published sensors
+type StatisticsReport struct {
+	Sensors        int               `json:"sensors"`         // Sensors audited
+	WithStatistics int               `json:"with_statistics"` // Sensors Home Assistant keeps long-term statistics for
+	AutoFix        bool              `json:"auto_fix"`        // Whether discovery applies suggested state classes
+	Issues         []StatisticsIssue `json:"issues"`
+}
+
+// numericSensor reports whether the sensor publishes numbers
+func (m *OIDMapping) numericSensor() bool {
+	switch m.Type {
+	case OIDTypeString, OIDTypeEnum, OIDTypeBool, OIDTypeCompositeSwitch:
+		return false
+	}
+	return len(m.EnumValues) == 0 && !nonNumericClasses[m.DeviceClass]
+}
+
+// counterLike reports whether the sensor only ever increases
+func (m *OIDMapping) counterLike() bool {
+	return m.Type == OIDTypeCounter || totalOnlyClasses[m.DeviceClass] ||
+		strings.Contains(strings.ToLower(m.Name), "total")
+}
+
+// StatisticsIssues checks a sensor's device class, state class and unit
+// against what Home Assistant needs to keep long-term statistics
+func (m *OIDMapping) StatisticsIssues() []StatisticsIssue {
+	if m.HAComponent != HAComponentSensor {
+		return nil
+	}
+
+	stateClass := ""
+	if ValidStateClass(m.HAComponent, m.StateClass) {
+		stateClass = m.StateClass
+	}
+	deviceClass := ""
+	if ValidDeviceClass(m.HAComponent, m.DeviceClass) {
+		deviceClass = m.DeviceClass
+	}
+	unit := m.PublishedUnit()
+
+	var issues []StatisticsIssue
+	add := func(severity, problem string, suggested *string) {
+		issues = append(issues, StatisticsIssue{
+			Mapping:             m.Name,
+			Severity:            severity,
+			Problem:             problem,
+			DeviceClass:         deviceClass,
+			StateClass:          stateClass,
+			Unit:                unit,
+			SuggestedStateClass: suggested,
+		})
+	}
+	suggest := func(class string) *string { return &class }
+
+	if !m.numericSensor() {
+		if stateClass != "" {
+			add(StatisticsError, "state_class set on a sensor without numeric values", suggest(""))
+		}
+		return issues
+	}
+
+	switch {
+	case stateClass == "":
+		if m.counterLike() {
+			add(StatisticsWarning, "numeric sensor without state_class; counters need total_increasing for statistics", suggest("total_increasing"))
+		} else {
+			add(StatisticsWarning, "numeric sensor without state_class gets no long-term statistics", suggest("measurement"))
+		}
+	case stateClass == "measurement" && totalOnlyClasses[deviceClass]:
+		add(StatisticsError, fmt.Sprintf("device_class %s requires state_class total or total_increasing", deviceClass), suggest("total_increasing"))
+	case stateClass == "measurement" && m.Type == OIDTypeCounter:
+		add(StatisticsWarning, "counter published as measurement; statistics will show its raw value instead of the increase", suggest("total_increasing"))
+	case stateClass != "measurement" && measurementOnlyClasses[deviceClass]:
+		add(StatisticsError, fmt.Sprintf("device_class %s requires state_class measurement", deviceClass), suggest("measurement"))
+	}
+
+	if allowed, ok := statisticsUnits[deviceClass]; ok && !allowed[unit] {
+		if unit == "" {
+			add(StatisticsError, fmt.Sprintf("device_class %s requires a unit", deviceClass), nil)
+		} else {
+			add(StatisticsError, fmt.Sprintf("unit %q is not valid for device_class %s", unit, deviceClass), nil)
+		}
+	}
+	return issues
+}
+
+// StatisticsStateClass returns the state class that fixes the sensor's
+// state class issues, or its own state class when there are none
+func (m *OIDMapping) StatisticsStateClass() string {
+	for _, issue := range m.StatisticsIssues() {
+		if issue.SuggestedStateClass != nil {
+			return *issue.SuggestedStateClass
+		}
+	}
+	if ValidStateClass(m.HAComponent, m.StateClass) {
+		return m.StateClass
+	}
+	return ""
+}
+
+// KeepsStatistics reports whether Home Assistant keeps long-term statistics
+// for the sensor as published, optionally after fixing its state class
+func (m *OIDMapping) KeepsStatistics(autoFix bool) bool {
+	if m.HAComponent != HAComponentSensor || !m.numericSensor() {
+		return false
+	}
+	for _, issue := range m.StatisticsIssues() {
+		if issue.SuggestedStateClass == nil || !autoFix {
+			return false
+		}
+	}
+	if autoFix {
+		return m.StatisticsStateClass() != ""
+	}
+	return ValidStateClass(m.HAComponent, m.StateClass)
+}
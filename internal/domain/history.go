@@ -0,0 +1,9 @@
+package domain
+
+import "time"
+
+// HistoryPoint is a numeric value recorded from a poll
+type HistoryPoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
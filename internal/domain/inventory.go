@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Inventory report fields a mapping can fill with its inventory key
+const (
+	InventorySysDescr = "sys_descr"
+	InventoryFirmware = "firmware"
+	InventorySerial   = "serial"
+	InventoryModel    = "model"
+)
+
+// sysDescrOID is SNMPv2-MIB::sysDescr.0, reported without an inventory key
+const sysDescrOID = ".1.3.6.1.2.1.1.1.0"
+
+// InventoryField returns the inventory report field the mapping's value
+// fills, or "" when it is not part of the inventory
+func (m *OIDMapping) InventoryField() string {
+	if m.Inventory != "" {
+		return m.Inventory
+	}
+	if "."+strings.TrimPrefix(m.OID, ".") == sysDescrOID {
+		return InventorySysDescr
+	}
+	return ""
+}
+
+// InventoryItem is one device's row in the hardware inventory report
+type InventoryItem struct {
+	DeviceID     string     `json:"device_id"`
+	Name         string     `json:"name"`
+	IPAddress    string     `json:"ip_address"`
+	Site         string     `json:"site,omitempty"`
+	Location     string     `json:"location,omitempty"`
+	Rack         string     `json:"rack,omitempty"`
+	AssetTag     string     `json:"asset_tag,omitempty"`
+	ProfileID    string     `json:"profile_id,omitempty"`
+	Manufacturer string     `json:"manufacturer,omitempty"`
+	Model        string     `json:"model,omitempty"`
+	Firmware     string     `json:"firmware,omitempty"`
+	Serial       string     `json:"serial,omitempty"`
+	SysDescr     string     `json:"sys_descr,omitempty"`
+	Online       bool       `json:"online"`
+	UpdatedAt    *time.Time `json:"updated_at"` // Last successful poll the values come from; nil when none were read
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// LeaseLeader is the lease held by the active instance of a bridge pair
+const LeaseLeader = "leader"
+
+// Lease is a named lock held by one bridge instance until it expires, used
+// to elect the active instance of a warm standby pair sharing a database
+type Lease struct {
+	Name      string    `json:"name" gorm:"primaryKey;type:text"`
+	Holder    string    `json:"holder" gorm:"not null;type:text"` // Instance ID of the holder
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// LeaderStatus reports the role of this instance in a standby pair
+type LeaderStatus struct {
+	Enabled    bool       `json:"enabled"`
+	InstanceID string     `json:"instance_id"`
+	Leader     bool       `json:"leader"`
+	Holder     string     `json:"holder,omitempty"` // Instance ID of the current leader
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Since      *time.Time `json:"since,omitempty"` // When this instance took over
+}
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// SettingLocale selects the language of entity names and enum labels in Home
+// Assistant, e.g. "de". Profiles without a translation for it stay English.
+const SettingLocale = "ha.locale"
+
+// LocaleStatus is the selected locale and those the profiles are translated to
+type LocaleStatus struct {
+	Locale    string   `json:"locale"` // Empty for English
+	Available []string `json:"available"`
+}
+
+// Translation localizes a profile for one locale
+type Translation struct {
+	Names  map[string]string `json:"names,omitempty" yaml:"names,omitempty"`   // Mapping or action name -> localized entity name
+	Values map[string]string `json:"values,omitempty" yaml:"values,omitempty"` // Enum label -> localized label, e.g. "On" -> "Ein"
+}
+
+// Translations holds a profile's translations by locale
+type Translations map[string]Translation
+
+// Translation returns the translation of a profile for a locale, nil if the
+// profile has none
+func (p *Profile) Translation(locale string) *Translation {
+	if p == nil || locale == "" {
+		return nil
+	}
+	if t, ok := p.Translations[locale]; ok {
+		return &t
+	}
+	return nil
+}
+
+// Locales returns the locales the profile is translated to, sorted
+func (t Translations) Locales() []string {
+	locales := make([]string, 0, len(t))
+	for locale := range t {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Name returns the localized name of a mapping or action, or name itself
+func (t *Translation) Name(name string) string {
+	if t != nil {
+		if localized, ok := t.Names[name]; ok && localized != "" {
+			return localized
+		}
+	}
+	return name
+}
+
+// Label returns the localized enum label, or label itself
+func (t *Translation) Label(label string) string {
+	if t != nil {
+		if localized, ok := t.Values[label]; ok && localized != "" {
+			return localized
+		}
+	}
+	return label
+}
+
+// Unlabel returns the enum label a localized label stands for, so commands
+// sent with localized options reach the device; unknown labels are returned as is
+func (t *Translation) Unlabel(localized string) string {
+	if t != nil {
+		for label, l := range t.Values {
+			if l == localized {
+				return label
+			}
+		}
+	}
+	return localized
+}
+
+func (t Translations) Value() (driver.Value, error) {
+	if t == nil {
+		return "{}", nil
+	}
+	return json.Marshal(t)
+}
+
+func (t *Translations) Scan(value interface{}) error {
+	if value == nil {
+		*t = nil
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for Translations")
+	}
+
+	return json.Unmarshal(data, t)
+}
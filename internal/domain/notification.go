@@ -0,0 +1,229 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Notification is an alert sent to the configured notification channels
+type Notification struct {
+	Severity   TrapSeverity `json:"severity"`
+	Source     string       `json:"source"`     // trap, event or test
+	EventType  string       `json:"event_type"` // trap, test or the device event type (offline, online, ...)
+	Title      string       `json:"title"`
+	Message    string       `json:"message"`
+	DeviceID   string       `json:"device_id,omitempty"`
+	DeviceName string       `json:"device_name,omitempty"`
+	Site       string       `json:"site,omitempty"`
+	Time       time.Time    `json:"time"`
+	Escalated  bool         `json:"escalated,omitempty"` // Re-sent because the condition is still unresolved
+}
+
+// Notification sources
+const (
+	NotificationSourceTrap  = "trap"
+	NotificationSourceEvent = "event"
+	NotificationSourceTest  = "test"
+)
+
+// Rank orders severities from info (0) to critical (3); unknown values rank as info
+func (s TrapSeverity) Rank() int {
+	switch s {
+	case SeverityWarning:
+		return 1
+	case SeverityError:
+		return 2
+	case SeverityCritical:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// Escalate returns the next higher severity; critical stays critical
+func (s TrapSeverity) Escalate() TrapSeverity {
+	switch s.Rank() {
+	case 0:
+		return SeverityWarning
+	case 1:
+		return SeverityError
+	default:
+		return SeverityCritical
+	}
+}
+
+// Severity returns the notification severity of a device event
+func (e *DeviceEvent) Severity() TrapSeverity {
+	if e.Type == EventTypeOffline {
+		return SeverityWarning
+	}
+	return SeverityInfo
+}
+
+// Notification channels
+const (
+	NotificationChannelSMTP     = "smtp"
+	NotificationChannelTelegram = "telegram"
+	NotificationChannelPushover = "pushover"
+)
+
+// NotificationChannels lists the supported channels
+var NotificationChannels = []string{NotificationChannelSMTP, NotificationChannelTelegram, NotificationChannelPushover}
+
+// Setting names shared by all channels, stored as "notify.<channel>.<name>"
+const (
+	ChannelSettingEnabled     = "enabled"
+	ChannelSettingMinSeverity = "min_severity" // Lowest severity sent (default warning)
+	ChannelSettingDevices     = "devices"      // Comma-separated device IDs or names; empty for all
+	ChannelSettingEventTypes  = "event_types"  // Comma-separated event types; empty for all
+	ChannelSettingMaxPerHour  = "max_per_hour" // Rate limit (default 20, 0 for none)
+)
+
+// NotificationSettingKey returns the settings key of a channel setting
+func NotificationSettingKey(channel, name string) string {
+	return "notify." + channel + "." + name
+}
+
+// Notification policy setting keys, applied before any channel
+const (
+	SettingQuietHoursStart       = "notify.quiet_hours.start"        // Local time, e.g. "22:00"
+	SettingQuietHoursEnd         = "notify.quiet_hours.end"          // Local time, e.g. "07:00"
+	SettingQuietHoursMinSeverity = "notify.quiet_hours.min_severity" // Lowest severity sent during quiet hours (default critical); the rest wait for their end
+	SettingDedupMinutes          = "notify.dedup_minutes"            // Drop repeats of the same notification within the window
+	SettingEscalationMinutes     = "notify.escalation_minutes"       // Re-send unresolved offline notifications after this delay
+)
+
+// NotificationPolicy holds the rules evaluated by the notification dispatcher
+type NotificationPolicy struct {
+	QuietHours       bool
+	QuietStart       time.Duration // Offset from local midnight
+	QuietEnd         time.Duration
+	QuietMinSeverity TrapSeverity
+	DedupWindow      time.Duration
+	EscalateAfter    time.Duration
+}
+
+// InQuietHours reports whether t falls within the quiet hours. Ranges may
+// span midnight, e.g. 22:00 to 07:00.
+func (p *NotificationPolicy) InQuietHours(t time.Time) bool {
+	if !p.QuietHours || p.QuietStart == p.QuietEnd {
+		return false
+	}
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if p.QuietStart < p.QuietEnd {
+		return offset >= p.QuietStart && offset < p.QuietEnd
+	}
+	return offset >= p.QuietStart || offset < p.QuietEnd
+}
+
+// Allows reports whether the policy lets a notification through at time t,
+// ignoring deduplication. Test and critical notifications always pass.
+func (p *NotificationPolicy) Allows(n *Notification, t time.Time) bool {
+	if n.Source == NotificationSourceTest || n.Severity == SeverityCritical || !p.InQuietHours(t) {
+		return true
+	}
+	return n.Severity.Rank() >= p.QuietMinSeverity.Rank()
+}
+
+// DedupKey identifies repeats of the same notification
+func (n *Notification) DedupKey() string {
+	return n.Source + "|" + n.EventType + "|" + n.DeviceID + "|" + n.Title
+}
+
+// SMTP notification setting keys. Recipients for one severity can be
+// overridden with SettingSMTPTo + "." + severity, e.g. "notify.smtp.to.critical".
+const (
+	SettingSMTPHost     = "notify.smtp.host"
+	SettingSMTPPort     = "notify.smtp.port"
+	SettingSMTPUsername = "notify.smtp.username"
+	SettingSMTPPassword = "notify.smtp.password"
+	SettingSMTPSecurity = "notify.smtp.security" // starttls (default), tls or none
+	SettingSMTPFrom     = "notify.smtp.from"
+	SettingSMTPTo       = "notify.smtp.to"      // Comma-separated
+	SettingSMTPSubject  = "notify.smtp.subject" // Go template over Notification
+	SettingSMTPBody     = "notify.smtp.body"    // Go template over Notification
+)
+
+// Telegram notification setting keys
+const (
+	SettingTelegramBotToken = "notify.telegram.bot_token"
+	SettingTelegramChatID   = "notify.telegram.chat_id"
+)
+
+// Pushover notification setting keys
+const (
+	SettingPushoverToken  = "notify.pushover.token"  // Application API token
+	SettingPushoverUser   = "notify.pushover.user"   // User or group key
+	SettingPushoverDevice = "notify.pushover.device" // Optional device name
+)
+
+// ChannelFilter selects the notifications a channel sends
+type ChannelFilter struct {
+	MinSeverity TrapSeverity `json:"min_severity"`
+	Devices     []string     `json:"devices,omitempty"`
+	EventTypes  []string     `json:"event_types,omitempty"`
+}
+
+// Matches reports whether a notification passes the filter. Devices match by
+// ID or case-insensitive name; test notifications always pass.
+func (f *ChannelFilter) Matches(n *Notification) bool {
+	if n.Source == NotificationSourceTest {
+		return true
+	}
+	if n.Severity.Rank() < f.MinSeverity.Rank() {
+		return false
+	}
+	if len(f.EventTypes) > 0 && !slices.Contains(f.EventTypes, n.EventType) {
+		return false
+	}
+	if len(f.Devices) > 0 && !slices.ContainsFunc(f.Devices, func(d string) bool {
+		return d == n.DeviceID || (n.DeviceName != "" && strings.EqualFold(d, n.DeviceName))
+	}) {
+		return false
+	}
+	return true
+}
+
+// NotificationChannel holds the settings common to every channel
+type NotificationChannel struct {
+	Name       string        `json:"name"`
+	Enabled    bool          `json:"enabled"`
+	Filter     ChannelFilter `json:"filter"`
+	MaxPerHour int           `json:"max_per_hour"`
+}
+
+// SMTPSettings configures the email notification channel
+type SMTPSettings struct {
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	Security   string
+	From       string
+	To         []string
+	SeverityTo map[TrapSeverity][]string
+	Subject    string
+	Body       string
+}
+
+// Recipients returns the addresses for a notification of the given severity
+func (s *SMTPSettings) Recipients(severity TrapSeverity) []string {
+	if to, ok := s.SeverityTo[severity]; ok {
+		return to
+	}
+	return s.To
+}
+
+// TelegramSettings configures the Telegram bot notification channel
+type TelegramSettings struct {
+	BotToken string
+	ChatID   string
+}
+
+// PushoverSettings configures the Pushover notification channel
+type PushoverSettings struct {
+	Token  string
+	User   string
+	Device string
+}
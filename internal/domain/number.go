@@ -0,0 +1,27 @@
+package domain
+
+// Number converts a numeric value, as polled over SNMP or decoded from a
+// request, to float64. Values of any other type, strings and bools included,
+// are not numbers; callers accepting those convert them first.
+func Number(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	case int32:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case uint:
+		return float64(val), true
+	case uint32:
+		return float64(val), true
+	case uint64:
+		return float64(val), true
+	default:
+		return 0, false
+	}
+}
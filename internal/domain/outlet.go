@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Outlet is the state of a single PDU outlet assembled from the latest poll
+type Outlet struct {
+	Number  int      `json:"number"`
+	Name    string   `json:"name,omitempty"`    // Name reported by the device
+	Label   string   `json:"label,omitempty"`   // Custom label set on the bridge
+	State   string   `json:"state"`             // on, off or unknown
+	Current *float64 `json:"current,omitempty"` // Current draw in amps, when the profile polls it
+}
+
+// Entity names used when a profile does not override them
+const (
+	defaultOutletStateEntity   = "Outlet {outlet} State"
+	defaultOutletNameEntity    = "Outlet {outlet} Name"
+	defaultOutletCurrentEntity = "Outlet {outlet} Current"
+)
+
+// Outlets assembles every outlet from polled values, keyed by mapping name,
+// and the device's custom labels
+func (o *OutletControl) Outlets(labels Labels, values map[string]interface{}) []Outlet {
+	if o == nil {
+		return []Outlet{}
+	}
+
+	outlets := make([]Outlet, 0, o.Count)
+	for n := 1; n <= o.Count; n++ {
+		stateEntity := outletEntity(o.StateEntity, defaultOutletStateEntity, n)
+		outlet := Outlet{
+			Number: n,
+			Label:  labels[stateEntity],
+			State:  outletState(values[stateEntity]),
+		}
+		if name, ok := values[outletEntity(o.NameEntity, defaultOutletNameEntity, n)]; ok && name != nil {
+			outlet.Name = fmt.Sprintf("%v", name)
+		}
+		if current, ok := Number(values[outletEntity(o.CurrentEntity, defaultOutletCurrentEntity, n)]); ok {
+			outlet.Current = &current
+		}
+		outlets = append(outlets, outlet)
+	}
+	return outlets
+}
+
+// FindOutlet returns the number of the outlet whose custom label or device
+// name matches, ignoring case
+func (o *OutletControl) FindOutlet(name string, labels Labels, values map[string]interface{}) (int, bool) {
+	for _, outlet := range o.Outlets(labels, values) {
+		if strings.EqualFold(outlet.Label, name) || strings.EqualFold(outlet.Name, name) {
+			return outlet.Number, true
+		}
+	}
+	return 0, false
+}
+
+func outletEntity(template, fallback string, outlet int) string {
+	if template == "" {
+		template = fallback
+	}
+	return expandTemplate(template, "outlet", outlet)
+}
+
+func outletState(v interface{}) string {
+	switch val := v.(type) {
+	case bool:
+		if val {
+			return "on"
+		}
+		return "off"
+	case string:
+		switch strings.ToLower(val) {
+		case "on", "1":
+			return "on"
+		case "off", "0":
+			return "off"
+		}
+	default:
+		if n, ok := Number(val); ok {
+			switch n {
+			case 1:
+				return "on"
+			case 0:
+				return "off"
+			}
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Derived entity names for multi-phase devices
+const (
+	TotalPowerName   = "Total Power"
+	TotalCurrentName = "Total Current"
+)
+
+// PhasePowerName returns the derived power entity name for a phase
+func PhasePowerName(phase int) string {
+	return fmt.Sprintf("Phase %d Power", phase)
+}
+
+// PhaseGroup holds the measurement mappings belonging to one phase
+type PhaseGroup struct {
+	Phase   int
+	Voltage *OIDMapping
+	Current *OIDMapping
+	Power   *OIDMapping
+}
+
+// PhaseGroups groups the profile's voltage/current/power mappings by phase,
+// ordered by phase number. Mappings without a phase are ignored.
+func (p *Profile) PhaseGroups() []PhaseGroup {
+	if p == nil {
+		return nil
+	}
+
+	groups := make(map[int]*PhaseGroup)
+	for i := range p.OIDMappings {
+		m := &p.OIDMappings[i]
+		if m.Phase <= 0 || m.Computed {
+			continue
+		}
+		g, exists := groups[m.Phase]
+		if !exists {
+			g = &PhaseGroup{Phase: m.Phase}
+			groups[m.Phase] = g
+		}
+		switch m.DeviceClass {
+		case "voltage":
+			g.Voltage = m
+		case "current":
+			g.Current = m
+		case "power":
+			g.Power = m
+		}
+	}
+
+	result := make([]PhaseGroup, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Phase < result[j].Phase })
+	return result
+}
+
+// WithPhaseTotals returns a copy of the profile with synthetic sensors for the
+// per-phase and total values the bridge derives on phase-aware profiles
+func (p *Profile) WithPhaseTotals() *Profile {
+	groups := p.PhaseGroups()
+	if len(groups) == 0 {
+		return p
+	}
+
+	existing := make(map[string]bool, len(p.OIDMappings))
+	for _, m := range p.OIDMappings {
+		existing[m.Name] = true
+	}
+
+	var derived []OIDMapping
+	add := func(m OIDMapping) {
+		if existing[m.Name] {
+			return
+		}
+		existing[m.Name] = true
+		m.Type = OIDTypeGauge
+		m.HAComponent = HAComponentSensor
+		m.StateClass = "measurement"
+		m.Computed = true
+		derived = append(derived, m)
+	}
+
+	hasCurrent := false
+	for _, g := range groups {
+		if g.Power == nil && g.Voltage != nil && g.Current != nil {
+			add(OIDMapping{Name: PhasePowerName(g.Phase), Unit: "W", DeviceClass: "power", Phase: g.Phase})
+		}
+		if g.Current != nil {
+			hasCurrent = true
+		}
+	}
+	if len(groups) > 1 {
+		add(OIDMapping{Name: TotalPowerName, Unit: "W", DeviceClass: "power"})
+		if hasCurrent {
+			add(OIDMapping{Name: TotalCurrentName, Unit: "A", DeviceClass: "current"})
+		}
+	}
+
+	if len(derived) == 0 {
+		return p
+	}
+
+	expanded := *p
+	expanded.OIDMappings = make(OIDMappings, 0, len(p.OIDMappings)+len(derived))
+	expanded.OIDMappings = append(expanded.OIDMappings, p.OIDMappings...)
+	expanded.OIDMappings = append(expanded.OIDMappings, derived...)
+	return &expanded
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// PollerStatus reports how far the poller got through the first polls of
+// its devices after startup
+type PollerStatus struct {
+	Devices     int        `json:"devices"`    // Devices polled by this instance
+	Polled      int        `json:"polled"`     // Devices polled at least once
+	Pending     int        `json:"pending"`    // Devices waiting for their first poll
+	Progress    float64    `json:"progress"`   // Polled share of devices, 0 to 1
+	WarmingUp   bool       `json:"warming_up"` // First polls are still being spread over the warmup window
+	StartedAt   time.Time  `json:"started_at"`
+	WarmupUntil *time.Time `json:"warmup_until,omitempty"` // End of the warmup window; nil without one
+}
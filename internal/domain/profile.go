@@ -4,6 +4,9 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 )
 
 // DeviceCategory represents the type of device
@@ -28,6 +31,26 @@ const (
 	OIDTypeCompositeSwitch OIDType = "composite_switch" // For Energenie-style comma-separated outlet status
 )
 
+// PDUType is the SNMP data type used when writing a value with SET
+type PDUType string
+
+const (
+	PDUTypeInteger     PDUType = "integer"
+	PDUTypeOctetString PDUType = "octet_string"
+	PDUTypeGauge32     PDUType = "gauge32"
+	PDUTypeCounter32   PDUType = "counter32"
+	PDUTypeCounter64   PDUType = "counter64"
+	PDUTypeUnsigned32  PDUType = "unsigned32"
+	PDUTypeTimeTicks   PDUType = "timeticks"
+	PDUTypeIPAddress   PDUType = "ipaddress"
+	PDUTypeOID         PDUType = "oid"
+
+	// Floats are sent wrapped in an Opaque, as Net-SNMP and some Eaton and
+	// Raritan devices expect
+	PDUTypeFloat  PDUType = "float"
+	PDUTypeDouble PDUType = "double"
+)
+
 // HAComponent represents Home Assistant component type
 type HAComponent string
 
@@ -42,11 +65,12 @@ const (
 
 // OIDMapping defines how to map an SNMP OID to Home Assistant
 type OIDMapping struct {
-	OID          string                 `json:"oid" yaml:"oid"`
-	Name         string                 `json:"name" yaml:"name"`
+	OID          string                 `json:"oid" yaml:"oid" binding:"required,oid"`
+	Name         string                 `json:"name" yaml:"name" binding:"required"`
 	Description  string                 `json:"description,omitempty" yaml:"description,omitempty"`
 	Type         OIDType                `json:"type" yaml:"type"`
 	Unit         string                 `json:"unit,omitempty" yaml:"unit,omitempty"`
+	SourceUnit   string                 `json:"source_unit,omitempty" yaml:"source_unit,omitempty"` // Unit the device reports in (after scale) when it differs from unit
 	Scale        float64                `json:"scale,omitempty" yaml:"scale,omitempty"`
 	HAComponent  HAComponent            `json:"ha_component" yaml:"ha_component"`
 	DeviceClass  string                 `json:"device_class,omitempty" yaml:"device_class,omitempty"`
@@ -55,13 +79,87 @@ type OIDMapping struct {
 	EnumValues   map[int]string         `json:"enum_values,omitempty" yaml:"enum_values,omitempty"`
 	Writable     bool                   `json:"writable,omitempty" yaml:"writable,omitempty"`
 	WriteOID     string                 `json:"write_oid,omitempty" yaml:"write_oid,omitempty"`
+	WriteType    PDUType                `json:"write_type,omitempty" yaml:"write_type,omitempty"` // PDU type for SET, guessed from the value when empty
+	Optimistic   bool                   `json:"optimistic,omitempty" yaml:"optimistic,omitempty"` // Echo commanded state at once instead of waiting for the poll after SET
 	PollGroup    string                 `json:"poll_group,omitempty" yaml:"poll_group,omitempty"` // "frequent" or "static"
 	Category     string                 `json:"category,omitempty" yaml:"category,omitempty"`     // HA entity category: config, diagnostic
 	Extra        map[string]interface{} `json:"extra,omitempty" yaml:"extra,omitempty"`
+	Alarms       []AlarmThreshold       `json:"alarms,omitempty" yaml:"alarms,omitempty"` // Threshold alarms exposed as binary sensors
+	Computed     bool                   `json:"computed,omitempty" yaml:"-"`              // Synthetic entity computed by the bridge (no OID)
+	Phase        int                    `json:"phase,omitempty" yaml:"phase,omitempty"`   // Electrical phase (1-3) for multi-phase devices
+	TrackDrift   bool                   `json:"track_drift,omitempty" yaml:"track_drift,omitempty"` // Include in configuration snapshots even when read-only here, e.g. outlet names
+	Inventory    string                 `json:"inventory,omitempty" yaml:"inventory,omitempty" binding:"omitempty,oneof=sys_descr firmware serial model"` // Inventory report field: sys_descr, firmware, serial or model
 
 	// Composite value handling (for Energenie-style comma-separated outlet status)
 	CompositeIndex     int    `json:"composite_index,omitempty" yaml:"composite_index,omitempty"`         // Index in comma-separated string (0-based)
 	CompositeSeparator string `json:"composite_separator,omitempty" yaml:"composite_separator,omitempty"` // Separator (default: ",")
+	WriteOIDFormat     string `json:"write_oid_format,omitempty" yaml:"write_oid_format,omitempty"`       // Per-index write OID, e.g. ".1.3.6.1.4.1.9999.2.{number}.0"
+
+	// Sanity filter: polled values outside the range are dropped, e.g. the
+	// 65535 or negative readings of a management card that is restarting
+	ValidMin *float64 `json:"valid_min,omitempty" yaml:"valid_min,omitempty"`
+	ValidMax *float64 `json:"valid_max,omitempty" yaml:"valid_max,omitempty"`
+
+	// Decimal places numeric values are rounded to and Home Assistant shows;
+	// by default 2, or 3 for fine scales and converted units
+	Precision *int `json:"precision,omitempty" yaml:"precision,omitempty" binding:"omitempty,min=0,max=10"`
+
+	// Switch command values, for devices whose state names do not say On/Off
+	OnValue  *int `json:"on_value,omitempty" yaml:"on_value,omitempty"`   // Integer written for ON, e.g. 1 on APC outlets
+	OffValue *int `json:"off_value,omitempty" yaml:"off_value,omitempty"` // Integer written for OFF, e.g. 2 on APC outlets, 0 on Energenie
+
+	// Templates
+	CommandTemplate string `json:"command_template,omitempty" yaml:"command_template,omitempty"` // Go template rendering the SET value from the HA payload, e.g. comma-separated values or bitfields
+	ValueTemplate   string `json:"value_template,omitempty" yaml:"value_template,omitempty"`     // Home Assistant template formatting the published state
+}
+
+// CompositeWriteOID returns the per-index write OID of a composite mapping,
+// or "" when writes go through read-modify-write of the composite value.
+// {index} is replaced with the composite index and {number} with index + 1.
+func (m *OIDMapping) CompositeWriteOID() string {
+	if m.Type != OIDTypeCompositeSwitch || m.WriteOIDFormat == "" {
+		return ""
+	}
+	return strings.NewReplacer(
+		"{index}", strconv.Itoa(m.CompositeIndex),
+		"{number}", strconv.Itoa(m.CompositeIndex+1),
+	).Replace(m.WriteOIDFormat)
+}
+
+// SwitchValue returns the integer a switch command writes for ON or OFF:
+// on_value/off_value, or else the enum_values key named On or Off. ok is
+// false when the mapping declares neither.
+func (m *OIDMapping) SwitchValue(on bool) (value int, ok bool) {
+	if on && m.OnValue != nil {
+		return *m.OnValue, true
+	}
+	if !on && m.OffValue != nil {
+		return *m.OffValue, true
+	}
+	name := "Off"
+	if on {
+		name = "On"
+	}
+	for k, v := range m.EnumValues {
+		if strings.EqualFold(v, name) {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// InValidRange reports whether a polled value (after scale and unit
+// conversion) lies within valid_min and valid_max; values that are not
+// numbers, and mappings without a range, always pass
+func (m *OIDMapping) InValidRange(value interface{}) bool {
+	if m.ValidMin == nil && m.ValidMax == nil {
+		return true
+	}
+	v, ok := Number(value)
+	if !ok {
+		return true
+	}
+	return (m.ValidMin == nil || v >= *m.ValidMin) && (m.ValidMax == nil || v <= *m.ValidMax)
 }
 
 // IndexedOIDMapping represents an OID mapping that should be polled with an index (e.g., outlets)
@@ -71,6 +169,17 @@ type IndexedOIDMapping struct {
 	IndexStart int    `json:"index_start" yaml:"index_start"`
 	IndexEnd   int    `json:"index_end" yaml:"index_end"`
 	NameFormat string `json:"name_format" yaml:"name_format"` // e.g., "Outlet %d"
+	PhaseFromIndex bool `json:"phase_from_index,omitempty" yaml:"phase_from_index,omitempty"` // Index N is phase N-IndexStart+1
+}
+
+// CompositeRangeMapping expands into one composite_switch mapping per index of
+// a single composite value (e.g., all outlet states in one "1,0,1,1" string)
+type CompositeRangeMapping struct {
+	OIDMapping `yaml:",inline"`
+	IndexStart int    `json:"index_start" yaml:"index_start"` // First composite index (0-based)
+	IndexEnd   int    `json:"index_end" yaml:"index_end"`
+	NameFormat string `json:"name_format" yaml:"name_format"`                   // e.g., "Outlet %d State"
+	NameStart  int    `json:"name_start,omitempty" yaml:"name_start,omitempty"` // Number used in the name for IndexStart (default 1)
 }
 
 // StringSlice is a slice of strings that can be stored in the database as JSON
@@ -137,8 +246,109 @@ type Profile struct {
 	Category     DeviceCategory `json:"category" gorm:"type:text"`
 	SysObjectID  string         `json:"sys_object_id,omitempty" gorm:"type:text"` // For auto-detection
 	SNMPVersions StringSlice    `json:"snmp_versions,omitempty" gorm:"type:text"` // Allowed SNMP versions (v1, v2c, v3)
-	OIDMappings  OIDMappings    `json:"oid_mappings" gorm:"type:text"`
+	OIDMappings  OIDMappings    `json:"oid_mappings" gorm:"type:text" binding:"dive"`
+	SelfTest     SelfTestConfig `json:"self_test" gorm:"type:text"` // Battery self-test support (UPS)
+	Actions      ProfileActions `json:"actions" gorm:"type:text"`   // Named one-shot commands (HA buttons)
+	Capabilities Capabilities   `json:"capabilities" gorm:"type:text"` // Generic controls (outlets, source switch)
+	Translations Translations   `json:"translations,omitempty" gorm:"type:text"` // Entity names and enum labels by locale
 	IsBuiltin    bool           `json:"is_builtin" gorm:"default:false"`
+	Warnings     []string       `json:"warnings,omitempty" gorm:"-"` // Problems Home Assistant would reject, e.g. unknown device classes
+}
+
+// WithCustomMappings returns a copy of the profile merged with the device's own
+// OID mappings. A custom mapping with the same name replaces the profile one.
+// Devices without a profile get a bare profile holding only their custom mappings.
+func (p *Profile) WithCustomMappings(device *Device) *Profile {
+	if device == nil || len(device.CustomMappings) == 0 {
+		return p
+	}
+
+	var merged Profile
+	if p != nil {
+		merged = *p
+	} else {
+		merged = Profile{ID: "custom", Name: "Custom"}
+	}
+
+	overrides := make(map[string]bool, len(device.CustomMappings))
+	for _, m := range device.CustomMappings {
+		overrides[m.Name] = true
+	}
+
+	merged.OIDMappings = make(OIDMappings, 0, len(merged.OIDMappings)+len(device.CustomMappings))
+	if p != nil {
+		for _, m := range p.OIDMappings {
+			if !overrides[m.Name] {
+				merged.OIDMappings = append(merged.OIDMappings, m)
+			}
+		}
+	}
+	for _, m := range device.CustomMappings {
+		m.Computed = false
+		merged.OIDMappings = append(merged.OIDMappings, m)
+	}
+
+	return &merged
+}
+
+// maxPrecision is the most decimal places a mapping may round to
+const maxPrecision = 10
+
+// Decimals returns the decimal places a scaled or converted value is rounded
+// to: precision, or else 3 for fine scales and converted units, e.g. mA
+// published as A, and 2 otherwise
+func (m *OIDMapping) Decimals(converted bool) int {
+	if m.Precision != nil {
+		return *m.Precision
+	}
+	if converted || m.Scale < 0.01 {
+		return 3
+	}
+	return 2
+}
+
+// ForDevice returns the profile as seen by a specific device: custom mappings
+// merged in, self-test and derived phase sensors added and threshold alarms
+// expanded into binary sensors
+func (p *Profile) ForDevice(device *Device) *Profile {
+	return p.WithCustomMappings(device).WithSelfTest().WithPhaseTotals().WithAlarms(device).WithLastTrap()
+}
+
+// Validate checks that a custom mapping can be polled and published
+func (m OIDMapping) Validate() error {
+	if m.OID == "" {
+		return errors.New("mapping oid is required")
+	}
+	if m.Name == "" {
+		return fmt.Errorf("mapping %s needs a name", m.OID)
+	}
+	switch m.HAComponent {
+	case HAComponentSensor, HAComponentBinarySensor, HAComponentSwitch,
+		HAComponentButton, HAComponentNumber, HAComponentSelect:
+	default:
+		return fmt.Errorf("mapping %s has unknown ha_component %q", m.Name, m.HAComponent)
+	}
+	if m.WriteOIDFormat != "" && m.Type != OIDTypeCompositeSwitch {
+		return fmt.Errorf("mapping %s: write_oid_format is only supported on composite_switch mappings", m.Name)
+	}
+	if m.ValidMin != nil && m.ValidMax != nil && *m.ValidMin > *m.ValidMax {
+		return fmt.Errorf("mapping %s: valid_min is above valid_max", m.Name)
+	}
+	if m.Precision != nil && (*m.Precision < 0 || *m.Precision > maxPrecision) {
+		return fmt.Errorf("mapping %s: precision must be between 0 and %d", m.Name, maxPrecision)
+	}
+	if m.OnValue != nil || m.OffValue != nil {
+		if m.HAComponent != HAComponentSwitch {
+			return fmt.Errorf("mapping %s: on_value and off_value are only supported on switch mappings", m.Name)
+		}
+		if m.OnValue == nil || m.OffValue == nil {
+			return fmt.Errorf("mapping %s needs both on_value and off_value", m.Name)
+		}
+		if *m.OnValue == *m.OffValue {
+			return fmt.Errorf("mapping %s: on_value and off_value must differ", m.Name)
+		}
+	}
+	return nil
 }
 
 // ProfileYAML represents the YAML structure for profile files
@@ -152,5 +362,10 @@ type ProfileYAML struct {
 	SNMPVersions   []string            `yaml:"snmp_versions,omitempty"` // Allowed SNMP versions (v1, v2c, v3)
 	OIDMappings    []OIDMapping        `yaml:"oid_mappings"`
 	IndexedOIDs    []IndexedOIDMapping `yaml:"indexed_oids,omitempty"`
+	CompositeRanges []CompositeRangeMapping `yaml:"composite_ranges,omitempty"`
 	PollGroups     map[string]int      `yaml:"poll_groups,omitempty"` // group name -> interval multiplier
+	SelfTest       SelfTestConfig      `yaml:"self_test,omitempty"`
+	Actions        []ProfileAction     `yaml:"actions,omitempty"`
+	Capabilities   Capabilities        `yaml:"capabilities,omitempty"`
+	Translations   Translations        `yaml:"translations,omitempty"`
 }
@@ -0,0 +1,174 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SceneStepType identifies what a scene step does
+type SceneStepType string
+
+const (
+	SceneStepOutlet SceneStepType = "outlet" // Switch or reboot PDU outlets
+	SceneStepSource SceneStepType = "source" // Select an ATS source
+	SceneStepAction SceneStepType = "action" // Run a profile action
+	SceneStepSet    SceneStepType = "set"    // Raw SNMP SET
+)
+
+// SceneStep is one operation of a scene on a single device
+type SceneStep struct {
+	DeviceID     string        `json:"device_id"`
+	Type         SceneStepType `json:"type"`
+	Outlets      []int         `json:"outlets,omitempty"`       // outlet: outlet numbers, empty = all
+	State        string        `json:"state,omitempty"`         // outlet: on, off or reboot
+	Source       int           `json:"source,omitempty"`        // source: source value from the profile
+	Action       string        `json:"action,omitempty"`        // action: profile action key
+	OID          string        `json:"oid,omitempty"`           // set: target OID
+	Value        interface{}   `json:"value,omitempty"`         // set: value to write
+	PDUType      PDUType       `json:"pdu_type,omitempty"`      // set: explicit PDU type
+	DelaySeconds float64       `json:"delay_seconds,omitempty"` // Pause before the step and between its outlets
+}
+
+// Validate checks that a step carries the fields its type needs
+func (s SceneStep) Validate() error {
+	if s.DeviceID == "" {
+		return errors.New("step device_id is required")
+	}
+	if s.DelaySeconds < 0 || s.DelaySeconds > 300 {
+		return errors.New("step delay_seconds must be between 0 and 300")
+	}
+
+	switch s.Type {
+	case SceneStepOutlet:
+		if s.State != "on" && s.State != "off" && s.State != "reboot" {
+			return errors.New("outlet step state must be on, off or reboot")
+		}
+	case SceneStepSource:
+		if s.Source < 1 {
+			return errors.New("source step requires source")
+		}
+	case SceneStepAction:
+		if s.Action == "" {
+			return errors.New("action step requires action")
+		}
+	case SceneStepSet:
+		if s.OID == "" || s.Value == nil {
+			return errors.New("set step requires oid and value")
+		}
+	default:
+		return fmt.Errorf("unknown step type %q", s.Type)
+	}
+	return nil
+}
+
+// Delay returns the pause before the step
+func (s SceneStep) Delay() time.Duration {
+	return time.Duration(s.DelaySeconds * float64(time.Second))
+}
+
+// SceneSteps is a slice of scene steps that can be stored in the database
+type SceneSteps []SceneStep
+
+func (s SceneSteps) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	return json.Marshal(s)
+}
+
+func (s *SceneSteps) Scan(value interface{}) error {
+	if value == nil {
+		*s = make(SceneSteps, 0)
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for SceneSteps")
+	}
+
+	return json.Unmarshal(data, s)
+}
+
+// Scene is a named sequence of commands spanning one or more devices,
+// e.g. "Lab shutdown" = outlets 3-8 off on PDU A, then source B on the ATS
+type Scene struct {
+	ID          string     `json:"id" gorm:"primaryKey;type:text"`
+	Name        string     `json:"name" gorm:"not null;type:text"`
+	Description string     `json:"description,omitempty" gorm:"type:text"`
+	Steps       SceneSteps `json:"steps" gorm:"type:text"`
+	HAButton    bool       `json:"ha_button" gorm:"default:false"` // Publish a Home Assistant button
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Validate checks the scene and all of its steps
+func (s *Scene) Validate() error {
+	if s.Name == "" {
+		return errors.New("scene name is required")
+	}
+	if len(s.Steps) == 0 {
+		return errors.New("scene needs at least one step")
+	}
+	for i, step := range s.Steps {
+		if err := step.Validate(); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// SceneCreateRequest is used for creating a new scene
+type SceneCreateRequest struct {
+	Name        string      `json:"name" binding:"required"`
+	Description string      `json:"description"`
+	Steps       []SceneStep `json:"steps" binding:"required"`
+	HAButton    bool        `json:"ha_button"`
+}
+
+// SceneUpdateRequest is used for updating an existing scene
+type SceneUpdateRequest struct {
+	Name        *string     `json:"name,omitempty"`
+	Description *string     `json:"description,omitempty"`
+	Steps       []SceneStep `json:"steps,omitempty"`
+	HAButton    *bool       `json:"ha_button,omitempty"`
+}
+
+// SceneStepResult is the outcome of one scene step
+type SceneStepResult struct {
+	Step     int             `json:"step"` // 1-based step number
+	DeviceID string          `json:"device_id"`
+	Type     SceneStepType   `json:"type"`
+	Success  bool            `json:"success"`
+	Error    string          `json:"error,omitempty"`
+	Commands []CommandResult `json:"commands,omitempty"`
+}
+
+// CommandResult is the outcome of one queued SNMP SET
+type CommandResult struct {
+	Description string `json:"description"`
+	OID         string `json:"oid"`
+	Success     bool   `json:"success"`
+	Error       string `json:"error,omitempty"`
+}
+
+// SceneRun is the progress or result of executing a scene
+type SceneRun struct {
+	ID         string            `json:"id"`
+	SceneID    string            `json:"scene_id"`
+	Name       string            `json:"name"`
+	Source     string            `json:"source"` // api or mqtt
+	Running    bool              `json:"running"`
+	Success    bool              `json:"success"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at,omitzero"`
+	Steps      []SceneStepResult `json:"steps"`
+}
@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// LastSelfTestResultName is the entity exposing the outcome of the last battery self-test
+const LastSelfTestResultName = "Last Self Test Result"
+
+// SelfTestConfig describes how to run a battery self-test on a device
+type SelfTestConfig struct {
+	TriggerOID      string         `json:"trigger_oid" yaml:"trigger_oid"`                                 // Writable OID that starts the test
+	TriggerValue    int            `json:"trigger_value" yaml:"trigger_value"`                             // Value written to start the test
+	ResultOID       string         `json:"result_oid" yaml:"result_oid"`                                   // OID holding the last test result
+	ResultValues    map[int]string `json:"result_values,omitempty" yaml:"result_values,omitempty"`         // Result enum mapping
+	InProgressValue int            `json:"in_progress_value,omitempty" yaml:"in_progress_value,omitempty"` // Result value while the test runs
+	ResultDelay     int            `json:"result_delay,omitempty" yaml:"result_delay,omitempty"`           // Seconds to wait before reading the result (default: 30)
+}
+
+// Supported reports whether the config describes a usable self-test
+func (c *SelfTestConfig) Supported() bool {
+	return c != nil && c.TriggerOID != "" && c.ResultOID != ""
+}
+
+// Delay returns how long to wait after triggering before reading the result
+func (c *SelfTestConfig) Delay() time.Duration {
+	if c.ResultDelay <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(c.ResultDelay) * time.Second
+}
+
+// ResultMapping returns the diagnostic sensor mapping for the last test result
+func (c *SelfTestConfig) ResultMapping() OIDMapping {
+	return OIDMapping{
+		OID:         c.ResultOID,
+		Name:        LastSelfTestResultName,
+		Type:        OIDTypeEnum,
+		HAComponent: HAComponentSensor,
+		Icon:        "mdi:battery-check",
+		EnumValues:  c.ResultValues,
+		PollGroup:   "static",
+		Category:    "diagnostic",
+	}
+}
+
+func (c SelfTestConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *SelfTestConfig) Scan(value interface{}) error {
+	if value == nil {
+		*c = SelfTestConfig{}
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for SelfTestConfig")
+	}
+
+	return json.Unmarshal(data, c)
+}
+
+// WithSelfTest returns a copy of the profile with the self-test result sensor
+// added, unless the profile already maps that OID
+func (p *Profile) WithSelfTest() *Profile {
+	if p == nil || !p.SelfTest.Supported() {
+		return p
+	}
+
+	resultOID := normalizeOIDPrefix(p.SelfTest.ResultOID)
+	for _, m := range p.OIDMappings {
+		if m.Name == LastSelfTestResultName || normalizeOIDPrefix(m.OID) == resultOID {
+			return p
+		}
+	}
+
+	expanded := *p
+	expanded.OIDMappings = make(OIDMappings, 0, len(p.OIDMappings)+1)
+	expanded.OIDMappings = append(expanded.OIDMappings, p.OIDMappings...)
+	expanded.OIDMappings = append(expanded.OIDMappings, p.SelfTest.ResultMapping())
+	return &expanded
+}
+
+func normalizeOIDPrefix(oid string) string {
+	if len(oid) > 0 && oid[0] == '.' {
+		return oid[1:]
+	}
+	return oid
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// SnapshotRedacted replaces snapshot values that identify a site, such as
+// sysName or the device's own address
+const SnapshotRedacted = "REDACTED"
+
+// SNMPSnapshot is a sanitized SNMP walk of a device, to attach to bug reports
+// and load into the test simulator to reproduce profile bugs
+type SNMPSnapshot struct {
+	ProfileID   string          `json:"profile_id,omitempty"`
+	SysObjectID string          `json:"sys_object_id,omitempty"`
+	SNMPVersion SNMPVersion     `json:"snmp_version"`
+	Roots       []string        `json:"roots"`               // Subtrees walked
+	Truncated   bool            `json:"truncated,omitempty"` // Stopped at the object limit
+	CreatedAt   time.Time       `json:"created_at"`
+	Entries     []SnapshotEntry `json:"entries"`
+}
+
+// SnapshotEntry is one object of a snapshot
+type SnapshotEntry struct {
+	OID      string      `json:"oid"`
+	Type     string      `json:"type"` // SNMP type, e.g. Integer, OctetString, Gauge32, OpaqueFloat
+	Value    interface{} `json:"value"`
+	Hex      bool        `json:"hex,omitempty"`      // Value is binary, hex encoded
+	Redacted bool        `json:"redacted,omitempty"` // Value was replaced
+}
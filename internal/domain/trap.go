@@ -7,6 +7,9 @@ import (
 	"time"
 )
 
+// LastTrapName is the diagnostic entity exposing when a device last sent a trap
+const LastTrapName = "Last Trap"
+
 // TrapSeverity represents the severity level of a trap
 type TrapSeverity string
 
@@ -46,16 +49,88 @@ func (v *TrapVariables) Scan(value interface{}) error {
 	return json.Unmarshal(data, v)
 }
 
+// ResolvedTrapVariable is a trap variable matched to an OID mapping of the
+// device's profile, with the mapping's scale and enum values applied
+type ResolvedTrapVariable struct {
+	OID   string      `json:"oid"`
+	Name  string      `json:"name"`
+	Raw   interface{} `json:"raw"`
+	Value interface{} `json:"value"`
+	Unit  string      `json:"unit,omitempty"`
+}
+
+// ResolvedTrapVariables stores resolved trap variables as JSON
+type ResolvedTrapVariables []ResolvedTrapVariable
+
+func (v ResolvedTrapVariables) Value() (driver.Value, error) {
+	if v == nil {
+		return "[]", nil
+	}
+	return json.Marshal(v)
+}
+
+func (v *ResolvedTrapVariables) Scan(value interface{}) error {
+	if value == nil {
+		*v = nil
+		return nil
+	}
+
+	var data []byte
+	switch val := value.(type) {
+	case []byte:
+		data = val
+	case string:
+		data = []byte(val)
+	default:
+		return errors.New("unsupported type for ResolvedTrapVariables")
+	}
+
+	return json.Unmarshal(data, v)
+}
+
 // TrapLog represents a received SNMP trap
 type TrapLog struct {
-	ID         string        `json:"id" gorm:"primaryKey;type:text"`
-	DeviceID   *string       `json:"device_id,omitempty" gorm:"type:text;index"`
-	SourceIP   string        `json:"source_ip" gorm:"not null;type:text"`
-	TrapOID    string        `json:"trap_oid" gorm:"not null;type:text"`
-	Variables  TrapVariables `json:"variables" gorm:"type:text"`
-	Severity   TrapSeverity  `json:"severity" gorm:"type:text"`
-	Message    string        `json:"message" gorm:"type:text"`
-	ReceivedAt time.Time     `json:"received_at" gorm:"index"`
+	ID         string                `json:"id" gorm:"primaryKey;type:text"`
+	DeviceID   *string               `json:"device_id,omitempty" gorm:"type:text;index"`
+	SourceIP   string                `json:"source_ip" gorm:"not null;type:text"`
+	TrapOID    string                `json:"trap_oid" gorm:"not null;type:text"`
+	Variables  TrapVariables         `json:"variables" gorm:"type:text"`
+	Resolved   ResolvedTrapVariables `json:"resolved,omitempty" gorm:"type:text"` // Variables found in the device profile
+	Severity   TrapSeverity          `json:"severity" gorm:"type:text"`
+	Message    string                `json:"message" gorm:"type:text"`
+	ReceivedAt time.Time             `json:"received_at" gorm:"index"`
+}
+
+// LastTrapMapping returns the diagnostic timestamp sensor for the latest trap
+func LastTrapMapping() OIDMapping {
+	return OIDMapping{
+		Name:        LastTrapName,
+		Description: "Time the device last sent a trap",
+		Type:        OIDTypeString,
+		HAComponent: HAComponentSensor,
+		DeviceClass: "timestamp",
+		Icon:        "mdi:bell-ring-outline",
+		Category:    "diagnostic",
+		Computed:    true,
+	}
+}
+
+// WithLastTrap returns a copy of the profile with the last trap sensor added
+func (p *Profile) WithLastTrap() *Profile {
+	if p == nil {
+		return p
+	}
+	for _, m := range p.OIDMappings {
+		if m.Name == LastTrapName {
+			return p
+		}
+	}
+
+	expanded := *p
+	expanded.OIDMappings = make(OIDMappings, 0, len(p.OIDMappings)+1)
+	expanded.OIDMappings = append(expanded.OIDMappings, p.OIDMappings...)
+	expanded.OIDMappings = append(expanded.OIDMappings, LastTrapMapping())
+	return &expanded
 }
 
 // TrapDefinition defines how to interpret a specific trap OID
@@ -67,12 +142,52 @@ type TrapDefinition struct {
 	Message     string       `json:"message,omitempty" yaml:"message,omitempty"` // Go template
 }
 
+// TestTrapRequest describes a synthesized trap injected through the API
+type TestTrapRequest struct {
+	TrapOID   string        `json:"trap_oid" binding:"required,oid"`
+	Variables TrapVariables `json:"variables"`
+	DeviceID  string        `json:"device_id"` // Optional; the trap appears to come from this device
+}
+
 // TrapFilter represents filter options for querying trap logs
 type TrapFilter struct {
 	DeviceID  string
+	DeviceIDs []string // Restricts results to these devices, e.g. those of one site
 	Severity  TrapSeverity
 	StartTime *time.Time
 	EndTime   *time.Time
 	Limit     int
 	Offset    int
 }
+
+// TrapStats summarizes the traps received since a point in time
+type TrapStats struct {
+	Since      time.Time              `json:"since"`
+	Total      int64                  `json:"total"`
+	BySeverity map[TrapSeverity]int64 `json:"by_severity"`
+	ByDevice   []TrapDeviceStats      `json:"by_device"`
+	ByOID      []TrapOIDCount         `json:"by_oid"`
+}
+
+// TrapDeviceStats counts the traps of one device. Known devices are listed
+// even without traps, so silent devices stand out.
+type TrapDeviceStats struct {
+	DeviceID     string     `json:"device_id,omitempty"` // Empty for traps from unknown sources
+	DeviceName   string     `json:"device_name,omitempty"`
+	Count        int64      `json:"count"`
+	LastReceived *time.Time `json:"last_received,omitempty"` // Latest trap ever received, not limited to the window
+}
+
+// TrapOIDCount counts the traps with one trap OID
+type TrapOIDCount struct {
+	TrapOID string `json:"trap_oid"`
+	Count   int64  `json:"count"`
+}
+
+// TrapCounts holds trap counts grouped by severity, device and trap OID.
+// Traps from unknown sources are counted under an empty device ID.
+type TrapCounts struct {
+	BySeverity map[TrapSeverity]int64
+	ByDevice   map[string]int64
+	ByOID      map[string]int64
+}
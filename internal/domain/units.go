@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"fmt"
+
+	"snmp-mqtt-bridge/internal/units"
+)
+
+// SettingTemperatureUnit overrides units.temperature of the configuration
+// with "C" or "F" while set
+const SettingTemperatureUnit = "units.temperature"
+
+// UnitPreferences are the units values are published in
+type UnitPreferences struct {
+	Temperature           string `json:"temperature"`            // °C or °F; empty keeps each profile's unit
+	ConfiguredTemperature string `json:"configured_temperature"` // From the configuration, used while no setting overrides it
+}
+
+// PublishedUnit returns the unit values are published in: the mapping's unit,
+// or the configured temperature unit for temperatures
+func (m *OIDMapping) PublishedUnit() string {
+	return units.Preferred(m.declaredUnit())
+}
+
+// declaredUnit returns the mapping's unit. Temperatures without one are in
+// °C, the unit MIBs report them in, so they follow the temperature unit too.
+func (m *OIDMapping) declaredUnit() string {
+	if m.Unit == "" && m.DeviceClass == "temperature" {
+		return units.Celsius
+	}
+	return m.Unit
+}
+
+// UnitConversion returns the units polled values are converted between
+func (m *OIDMapping) UnitConversion() (from, to string) {
+	from = m.SourceUnit
+	if from == "" {
+		from = m.declaredUnit()
+	}
+	return from, m.PublishedUnit()
+}
+
+// DeclaredValue converts a published value back to the mapping's unit, in
+// which its alarm thresholds are written
+func (m *OIDMapping) DeclaredValue(value float64) float64 {
+	if converted, err := units.Convert(value, m.PublishedUnit(), m.declaredUnit()); err == nil {
+		return converted
+	}
+	return value
+}
+
+// UnitWarnings describes mappings whose source unit cannot be converted to
+// the unit they are published in
+func (p *Profile) UnitWarnings() []string {
+	var warnings []string
+	for _, m := range p.OIDMappings {
+		from, to := m.UnitConversion()
+		if _, err := units.Convert(0, from, to); err != nil {
+			warnings = append(warnings, fmt.Sprintf("mapping %q: %v", m.Name, err))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// UpdateStatus is the result of checking the release feed for a newer bridge
+type UpdateStatus struct {
+	InstalledVersion string     `json:"installed_version"`
+	LatestVersion    string     `json:"latest_version,omitempty"`
+	UpdateAvailable  bool       `json:"update_available"`
+	Title            string     `json:"title,omitempty"`
+	ReleaseURL       string     `json:"release_url,omitempty"`
+	ReleaseSummary   string     `json:"release_summary,omitempty"`
+	CheckedAt        *time.Time `json:"checked_at,omitempty"`
+	Error            string     `json:"error,omitempty"` // Last check failure; the previous result is kept
+}
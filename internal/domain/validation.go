@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MinPollInterval is the shortest poll interval, in seconds, a device may set
+const MinPollInterval = 5
+
+// ValidOID reports whether oid is a numeric OID, e.g. .1.3.6.1.2.1.1.5.0;
+// the leading dot is optional
+func ValidOID(oid string) bool {
+	oid = strings.TrimPrefix(oid, ".")
+	if oid == "" {
+		return false
+	}
+	for _, part := range strings.Split(oid, ".") {
+		if _, err := strconv.ParseUint(part, 10, 32); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+// Package dryrun carries a request's dry run flag through contexts and
+// collects the SNMP SETs held back while handling it, so they can be
+// reported in the response instead of sent
+package dryrun
+
+import (
+	"context"
+	"sync"
+
+	"snmp-mqtt-bridge/internal/domain"
+)
+
+type contextKey struct{}
+
+// Recorder collects the SETs held back while handling one request
+type Recorder struct {
+	requested bool
+
+	mu     sync.Mutex
+	report *domain.DryRun
+}
+
+// NewRecorder creates a recorder; requested asks for a dry run
+func NewRecorder(requested bool) *Recorder {
+	return &Recorder{requested: requested}
+}
+
+// WithRecorder returns a copy of ctx collecting held back SETs in r
+func WithRecorder(ctx context.Context, r *Recorder) context.Context {
+	return context.WithValue(ctx, contextKey{}, r)
+}
+
+// FromContext returns the recorder of ctx, or nil
+func FromContext(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(contextKey{}).(*Recorder)
+	return r
+}
+
+// Requested reports whether ctx asks for a dry run
+func Requested(ctx context.Context) bool {
+	r := FromContext(ctx)
+	return r != nil && r.requested
+}
+
+// Record notes a SET held back, on the recorder of ctx if it has one
+func Record(ctx context.Context, set domain.PlannedSet, readOnly bool) {
+	r := FromContext(ctx)
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.report == nil {
+		r.report = &domain.DryRun{}
+	}
+	r.report.ReadOnly = r.report.ReadOnly || readOnly
+	r.report.Sets = append(r.report.Sets, set)
+}
+
+// Report returns a copy of the SETs held back so far, or nil when none were
+func (r *Recorder) Report() *domain.DryRun {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.report == nil {
+		return nil
+	}
+	return &domain.DryRun{
+		ReadOnly: r.report.ReadOnly,
+		Sets:     append([]domain.PlannedSet(nil), r.report.Sets...),
+	}
+}
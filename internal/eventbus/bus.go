@@ -0,0 +1,184 @@
+package eventbus
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// subscriberBufferSize is the channel buffer for each subscriber.
+// Events are dropped for a subscriber whose buffer is full so a slow
+// consumer can never stall the poller or other publishers. Control and
+// lifecycle events instead wait up to blockingTimeout for room, since
+// missing one leaves a subscriber out of step until restart.
+const (
+	subscriberBufferSize = 100
+	blockingTimeout      = time.Second
+)
+
+// blockingTypes are the control and lifecycle events Publish waits to
+// deliver rather than dropping them
+var blockingTypes = map[Type]bool{
+	TypeDeviceCreated:  true,
+	TypeDeviceUpdated:  true,
+	TypeDeviceDeleted:  true,
+	TypeProfileUpdated: true,
+	TypeProfileDeleted: true,
+	TypeSceneUpdated:   true,
+	TypeSceneDeleted:   true,
+	TypeMQTTStatus:     true,
+	TypeCommand:        true,
+	TypeWriteLock:      true,
+	TypeLocale:         true,
+	TypeUnits:          true,
+}
+
+// Bus is an in-process publish/subscribe hub for typed events.
+// A nil *Bus is valid and silently discards published events.
+type Bus struct {
+	subscribers map[*Subscription]struct{}
+	mu          sync.RWMutex
+	closed      bool
+	dropped     atomic.Uint64
+}
+
+// Subscription receives events of the requested types on C
+type Subscription struct {
+	C     <-chan Event
+	ch    chan Event
+	types map[Type]bool // empty = all types
+}
+
+// NewBus creates a new event bus
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe returns a subscription for the given event types.
+// With no types, the subscription receives every event. The subscription
+// of a nil or closed bus is closed right away.
+func (b *Bus) Subscribe(types ...Type) *Subscription {
+	ch := make(chan Event, subscriberBufferSize)
+	sub := &Subscription{
+		C:     ch,
+		ch:    ch,
+		types: make(map[Type]bool, len(types)),
+	}
+	for _, t := range types {
+		sub.types[t] = true
+	}
+	if b == nil {
+		close(ch)
+		return sub
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(ch)
+		return sub
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe removes a subscription and closes its channel
+func (b *Bus) Unsubscribe(sub *Subscription) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.subscribers[sub]; exists {
+		delete(b.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// Publish delivers an event to all matching subscribers. Events are dropped
+// for subscribers whose buffer is full, except control and lifecycle events,
+// which wait for room up to blockingTimeout in total.
+func (b *Bus) Publish(evt Event) {
+	if b == nil {
+		return
+	}
+	if evt.Timestamp.IsZero() {
+		evt.Timestamp = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return
+	}
+
+	var deadline <-chan time.Time // Shared by all full subscribers
+	expired := false
+	for sub := range b.subscribers {
+		if len(sub.types) > 0 && !sub.types[evt.Type] {
+			continue
+		}
+		select {
+		case sub.ch <- evt:
+			continue
+		default:
+		}
+
+		if !blockingTypes[evt.Type] {
+			// Subscriber buffer full, skip
+			if b.dropped.Add(1)%100 == 1 {
+				log.Printf("Event bus: subscriber buffer full, dropping %s events (%d dropped so far)", evt.Type, b.dropped.Load())
+			}
+			continue
+		}
+
+		if deadline == nil {
+			timer := time.NewTimer(blockingTimeout)
+			defer timer.Stop()
+			deadline = timer.C
+		}
+		if !expired {
+			select {
+			case sub.ch <- evt:
+				continue
+			case <-deadline:
+				expired = true
+			}
+		}
+		b.dropped.Add(1)
+		log.Printf("Event bus: subscriber did not take a %s event for device %q within %s, dropping it", evt.Type, evt.DeviceID, blockingTimeout)
+	}
+}
+
+// Dropped returns the number of events dropped due to full subscriber buffers
+func (b *Bus) Dropped() uint64 {
+	if b == nil {
+		return 0
+	}
+	return b.dropped.Load()
+}
+
+// Close closes all subscriber channels; further publishes are discarded
+func (b *Bus) Close() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+
+	for sub := range b.subscribers {
+		close(sub.ch)
+	}
+	b.subscribers = nil
+}
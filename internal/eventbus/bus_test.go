@@ -0,0 +1,73 @@
+package eventbus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPublishDropsStateUpdatesForFullSubscribers(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(TypeStateUpdate)
+	defer bus.Unsubscribe(sub)
+
+	for i := 0; i < subscriberBufferSize+3; i++ {
+		bus.Publish(Event{Type: TypeStateUpdate, DeviceID: "ups"})
+	}
+
+	if got := len(sub.C); got != subscriberBufferSize {
+		t.Errorf("subscriber holds %d events, want %d", got, subscriberBufferSize)
+	}
+	if got := bus.Dropped(); got != 3 {
+		t.Errorf("dropped %d events, want 3", got)
+	}
+}
+
+func TestPublishWaitsForRoomForLifecycleEvents(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe()
+	defer bus.Unsubscribe(sub)
+
+	for i := 0; i < subscriberBufferSize; i++ {
+		bus.Publish(Event{Type: TypeStateUpdate, DeviceID: "ups"})
+	}
+
+	// The subscriber catches up shortly after the buffer filled
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		<-sub.C
+	}()
+	bus.Publish(Event{Type: TypeDeviceDeleted, DeviceID: "ups"})
+
+	if got := bus.Dropped(); got != 0 {
+		t.Fatalf("dropped %d events, want the deletion delivered", got)
+	}
+	var last Event
+	for len(sub.C) > 0 {
+		last = <-sub.C
+	}
+	if last.Type != TypeDeviceDeleted {
+		t.Errorf("last event = %s, want %s", last.Type, TypeDeviceDeleted)
+	}
+}
+
+func TestPublishGivesUpOnStuckSubscribers(t *testing.T) {
+	bus := NewBus()
+	stuck := []*Subscription{bus.Subscribe(), bus.Subscribe()}
+	for i := 0; i < subscriberBufferSize; i++ {
+		bus.Publish(Event{Type: TypeStateUpdate})
+	}
+
+	start := time.Now()
+	bus.Publish(Event{Type: TypeDeviceUpdated, DeviceID: "ups"})
+
+	// Both subscribers share one timeout
+	if elapsed := time.Since(start); elapsed > blockingTimeout+500*time.Millisecond {
+		t.Errorf("Publish took %s with two stuck subscribers", elapsed)
+	}
+	if got := bus.Dropped(); got != 2 {
+		t.Errorf("dropped %d events, want 2", got)
+	}
+	for _, sub := range stuck {
+		bus.Unsubscribe(sub)
+	}
+}
@@ -0,0 +1,88 @@
+package eventbus
+
+import (
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+)
+
+// Type identifies the kind of event carried on the bus
+type Type string
+
+const (
+	TypeStateUpdate    Type = "state_update"    // Payload: StateUpdate
+	TypeTrapReceived   Type = "trap_received"   // Payload: *domain.TrapLog
+	TypeDeviceCreated  Type = "device_created"  // Payload: *domain.Device
+	TypeDeviceUpdated  Type = "device_updated"  // Payload: *domain.Device
+	TypeDeviceDeleted  Type = "device_deleted"  // Payload: *domain.Device
+	TypeMQTTStatus     Type = "mqtt_status"     // Payload: MQTTStatus
+	TypeCommand        Type = "command"         // Payload: Command
+	TypeDeviceEvent    Type = "device_event"    // Payload: *domain.DeviceEvent
+	TypeSceneUpdated   Type = "scene_updated"   // Payload: *domain.Scene (created or updated)
+	TypeSceneDeleted   Type = "scene_deleted"   // Payload: *domain.Scene
+	TypeSceneRun       Type = "scene_run"       // Payload: *domain.SceneRun
+	TypeUpdateStatus   Type = "update_status"   // Payload: *domain.UpdateStatus
+	TypeProfileUpdated Type = "profile_updated" // Payload: *domain.Profile
+	TypeProfileDeleted Type = "profile_deleted" // Payload: *domain.Profile
+	TypeWriteLock      Type = "write_lock"      // Payload: *domain.WriteLock
+	TypeLocale         Type = "locale"          // Payload: string, the selected locale
+	TypeUnits          Type = "units"           // Payload: *domain.UnitPreferences
+	TypeDiscovery      Type = "discovery"       // Payload: *domain.DiscoveryStatus
+)
+
+// Event is a single message published on the bus
+type Event struct {
+	Type      Type        `json:"type"`
+	DeviceID  string      `json:"device_id,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+	Payload   interface{} `json:"payload"`
+}
+
+// StateUpdate is sent when a device state changes
+type StateUpdate struct {
+	DeviceID  string                 `json:"device_id"`
+	Timestamp time.Time              `json:"timestamp"`
+	Values    map[string]interface{} `json:"values"`
+	Stale     []string               `json:"stale,omitempty"` // Values not refreshed for several polls
+	Online    bool                   `json:"online"`
+	Errors    []string               `json:"errors,omitempty"` // Errors of the latest poll
+}
+
+// MQTTStatus is sent when the MQTT broker connection changes
+type MQTTStatus struct {
+	Connected bool   `json:"connected"`
+	Broker    string `json:"broker,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Warning   string `json:"warning,omitempty"` // e.g. a suspected client ID collision
+}
+
+// Command source identifiers
+const (
+	CommandSourceAPI  = "api"
+	CommandSourceMQTT = "mqtt"
+)
+
+// Command is sent after an SNMP SET has been attempted on a device
+type Command struct {
+	DeviceID string      `json:"device_id"`
+	EntityID string      `json:"entity_id,omitempty"`
+	OID      string      `json:"oid"`
+	Value    interface{} `json:"value"`
+	Source   string      `json:"source"`
+	Actor    string      `json:"actor,omitempty"` // Who sent the command: the API user, or mqtt
+	Success  bool        `json:"success"`
+	Error    string      `json:"error,omitempty"`
+	DryRun   bool        `json:"dry_run,omitempty"` // Held back by a dry run or read-only mode, not sent
+}
+
+// DevicePayload returns the device carried by a device lifecycle event
+func (e Event) DevicePayload() (*domain.Device, bool) {
+	d, ok := e.Payload.(*domain.Device)
+	return d, ok
+}
+
+// ProfilePayload returns the profile carried by a profile event
+func (e Event) ProfilePayload() (*domain.Profile, bool) {
+	p, ok := e.Payload.(*domain.Profile)
+	return p, ok
+}
@@ -0,0 +1,85 @@
+// Package lifecycle starts the bridge's subsystems in order and stops them in
+// reverse, so shutdown is deterministic and bounded.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Component is a subsystem managed by the Manager. Start and Stop are both
+// optional; Stop is only called for components whose Start succeeded.
+type Component struct {
+	Name  string
+	Start func() error
+	Stop  func()
+
+	// Optional components log a warning when Start fails instead of aborting startup
+	Optional bool
+}
+
+// Manager runs components in registration order and stops them in reverse
+type Manager struct {
+	components []Component
+	started    []Component
+}
+
+// NewManager creates an empty lifecycle manager
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// Add registers a component; components are started in the order they are added
+func (m *Manager) Add(c Component) {
+	m.components = append(m.components, c)
+}
+
+// Start starts all components. If a required component fails, the ones
+// already started are stopped again and the error is returned.
+func (m *Manager) Start() error {
+	for _, c := range m.components {
+		if c.Start != nil {
+			if err := c.Start(); err != nil {
+				if c.Optional {
+					log.Printf("Warning: Failed to start %s: %v", c.Name, err)
+					continue
+				}
+				m.Stop(context.Background())
+				return fmt.Errorf("failed to start %s: %w", c.Name, err)
+			}
+		}
+		m.started = append(m.started, c)
+	}
+	return nil
+}
+
+// Stop stops started components in reverse order. Each component waits for
+// the previous one to finish; once ctx expires, remaining stops are still
+// issued but no longer awaited so shutdown cannot hang.
+func (m *Manager) Stop(ctx context.Context) {
+	for i := len(m.started) - 1; i >= 0; i-- {
+		c := m.started[i]
+		if c.Stop == nil {
+			continue
+		}
+
+		start := time.Now()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			c.Stop()
+		}()
+
+		select {
+		case <-done:
+			if elapsed := time.Since(start); elapsed > time.Second {
+				log.Printf("Stopped %s in %s", c.Name, elapsed.Round(time.Millisecond))
+			}
+		case <-ctx.Done():
+			log.Printf("Warning: %s did not stop before the shutdown deadline", c.Name)
+		}
+	}
+	m.started = nil
+}
@@ -0,0 +1,111 @@
+package mqtt
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"snmp-mqtt-bridge/internal/config"
+	"snmp-mqtt-bridge/internal/domain"
+)
+
+// Adapter publishes devices and their states in a layout for consumers other
+// than Home Assistant, alongside HA discovery
+type Adapter interface {
+	Name() string
+	// Start subscribes to the adapter's command topics; it is called after
+	// every (re)connect
+	Start(handler CommandHandler) error
+	// PublishDevice announces a device, or updates it after a change
+	PublishDevice(device *domain.Device, profile *domain.Profile) error
+	RemoveDevice(device *domain.Device, profile *domain.Profile) error
+	// PublishState publishes the values of a poll, keyed by mapping name and
+	// already converted as for Home Assistant: ON/OFF for binary sensors and
+	// switches
+	PublishState(device *domain.Device, profile *domain.Profile, values map[string]interface{}, online bool) error
+	// PublishOffline marks a device unavailable before the bridge goes down
+	PublishOffline(device *domain.Device) error
+}
+
+// Adapter names accepted by mqtt.adapters
+const (
+	AdapterOpenHAB  = "openhab"
+	AdapterDomoticz = "domoticz"
+)
+
+// NewAdapters creates the adapters named in the configuration; unknown names
+// are logged and skipped
+func NewAdapters(client *Client, cfg *config.MQTTConfig) []Adapter {
+	var adapters []Adapter
+	for _, name := range cfg.Adapters {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case AdapterOpenHAB:
+			adapters = append(adapters, NewHomieAdapter(client, cfg.OpenHAB.BaseTopic))
+		case AdapterDomoticz:
+			adapters = append(adapters, NewDomoticzAdapter(client, cfg.Domoticz.InTopic, cfg.Domoticz.OutTopic))
+		case "":
+		default:
+			log.Printf("Warning: unknown MQTT adapter %q, expected %s or %s", name, AdapterOpenHAB, AdapterDomoticz)
+		}
+	}
+	return adapters
+}
+
+// SetAdapters sets the adapters devices and states are published to besides
+// Home Assistant discovery
+func (p *Publisher) SetAdapters(adapters []Adapter) {
+	p.adapters = adapters
+}
+
+// startAdapters subscribes the adapters to their command topics
+func (p *Publisher) startAdapters() {
+	for _, a := range p.adapters {
+		if err := a.Start(p.handleCommand); err != nil {
+			log.Printf("Failed to start %s adapter: %v", a.Name(), err)
+		}
+	}
+}
+
+func (p *Publisher) publishAdapterDevice(info *deviceInfo) {
+	if info.profile == nil {
+		return
+	}
+	for _, a := range p.adapters {
+		if err := a.PublishDevice(info.device, info.profile); err != nil {
+			log.Printf("Failed to publish device %s to %s adapter: %v", info.device.ID, a.Name(), err)
+		}
+	}
+}
+
+func (p *Publisher) removeAdapterDevice(info *deviceInfo) {
+	if info.profile == nil {
+		return
+	}
+	for _, a := range p.adapters {
+		if err := a.RemoveDevice(info.device, info.profile); err != nil {
+			log.Printf("Failed to remove device %s from %s adapter: %v", info.device.ID, a.Name(), err)
+		}
+	}
+}
+
+func (p *Publisher) publishAdapterState(info *deviceInfo, values map[string]interface{}, online bool) {
+	for _, a := range p.adapters {
+		if err := a.PublishState(info.device, info.profile, values, online); err != nil {
+			log.Printf("Failed to publish state of %s to %s adapter: %v", info.device.ID, a.Name(), err)
+		}
+	}
+}
+
+// adapterValue formats a published value as text
+func adapterValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		if v {
+			return "ON"
+		}
+		return "OFF"
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -1,7 +1,7 @@
 package mqtt
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -9,10 +9,15 @@ import (
 
 	"snmp-mqtt-bridge/internal/config"
 	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+	"snmp-mqtt-bridge/internal/timefmt"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
+// ErrNotConnected is returned when publishing while the broker is unreachable
+var ErrNotConnected = errors.New("not connected to MQTT broker")
+
 // CommandHandler is a function that handles MQTT commands
 type CommandHandler func(deviceID, entityID string, payload []byte)
 
@@ -25,15 +30,53 @@ type Client struct {
 	topicPrefix   string
 	handlers      map[string]CommandHandler
 	handlersMu    sync.RWMutex
+	bus           *eventbus.Bus
+	stateFormat   StateFormat
+
+	queue     *publishQueue
+	retryOnce sync.Once
+	stopOnce  sync.Once
+	retryKick chan struct{}
+	retryStop chan struct{}
+
+	clientIDSuffix string
+	instanceID     string
+	node           string // Cluster node whose bridge topics this client publishes
+	collisions     collisionDetector
+
+	stateRequests StateRequestHandler // Handles <prefix>/+/get; guarded by handlersMu
 }
 
 // NewClient creates a new MQTT client
 func NewClient(cfg *config.MQTTConfig) *Client {
-	return &Client{
+	stateFormat := StateFormat(cfg.StateFormat)
+	if stateFormat == "" {
+		stateFormat = StateFormatRaw
+	} else if !stateFormat.Valid() {
+		log.Printf("Warning: unknown MQTT state format %q, using %q", cfg.StateFormat, StateFormatRaw)
+		stateFormat = StateFormatRaw
+	}
+
+	c := &Client{
 		cfg:         cfg,
-		topicPrefix: cfg.TopicPrefix,
+		topicPrefix: scopedTopicPrefix(cfg.TopicPrefix, cfg.InstanceID),
+		instanceID:  cfg.InstanceID,
 		handlers:    make(map[string]CommandHandler),
+		stateFormat: stateFormat,
+		queue:       newPublishQueue(cfg.RetryQueueSize, cfg.PublishRetries),
+		retryKick:   make(chan struct{}, 1),
+		retryStop:   make(chan struct{}),
 	}
+	// The suffix is chosen once so it survives reconnects with new settings
+	if cfg.ClientIDSuffix {
+		c.clientIDSuffix = clientIDSuffix()
+	}
+	return c
+}
+
+// SetEventBus sets the bus used to announce connection status changes
+func (c *Client) SetEventBus(bus *eventbus.Bus) {
+	c.bus = bus
 }
 
 // Connect establishes connection to the MQTT broker
@@ -42,7 +85,7 @@ func (c *Client) Connect() error {
 
 	opts := mqtt.NewClientOptions()
 	opts.AddBroker(broker)
-	opts.SetClientID(c.cfg.ClientID)
+	opts.SetClientID(c.ClientID())
 	opts.SetConnectTimeout(10 * time.Second)
 
 	if c.cfg.Username != "" {
@@ -55,17 +98,35 @@ func (c *Client) Connect() error {
 	opts.SetConnectRetryInterval(5 * time.Second)
 	opts.SetMaxReconnectInterval(5 * time.Minute)
 
+	// Ping the broker when idle so a dead connection is detected and
+	// reconnected instead of silently dropping publishes
+	if c.cfg.KeepAlive > 0 {
+		opts.SetKeepAlive(c.cfg.KeepAlive)
+		opts.SetPingTimeout(c.cfg.KeepAlive / 2)
+	}
+
 	opts.SetOnConnectHandler(func(client mqtt.Client) {
 		c.mu.Lock()
 		c.connected = true
 		c.mu.Unlock()
+		c.collisions.connected()
 		log.Printf("MQTT connected to %s", broker)
+		c.bus.Publish(eventbus.Event{
+			Type:    eventbus.TypeMQTTStatus,
+			Payload: eventbus.MQTTStatus{Connected: true, Broker: broker},
+		})
 
 		// Publish online status
-		c.Publish(fmt.Sprintf("%s/bridge/status", c.topicPrefix), "online", true)
+		c.Publish(bridgeTopic(c.topicPrefix, c.node, "status"), "online", true)
 
 		// Resubscribe to command topics
 		c.resubscribe()
+
+		// Republish messages that failed while disconnected
+		select {
+		case c.retryKick <- struct{}{}:
+		default:
+		}
 	})
 
 	opts.SetConnectionLostHandler(func(client mqtt.Client, err error) {
@@ -73,17 +134,25 @@ func (c *Client) Connect() error {
 		c.connected = false
 		c.mu.Unlock()
 		log.Printf("MQTT connection lost: %v", err)
+
+		status := eventbus.MQTTStatus{Connected: false, Broker: broker, Error: err.Error()}
+		if c.collisions.lost() {
+			status.Warning = CollisionWarning(c.ClientID())
+			log.Printf("Warning: %s", status.Warning)
+		}
+		c.bus.Publish(eventbus.Event{Type: eventbus.TypeMQTTStatus, Payload: status})
 	})
 
 	// Set LWT (Last Will and Testament)
 	opts.SetWill(
-		fmt.Sprintf("%s/bridge/status", c.topicPrefix),
+		bridgeTopic(c.topicPrefix, c.node, "status"),
 		"offline",
 		1,
 		true,
 	)
 
 	c.client = mqtt.NewClient(opts)
+	c.retryOnce.Do(func() { go c.retryLoop() })
 
 	token := c.client.Connect()
 	if token.WaitTimeout(10*time.Second) && token.Error() != nil {
@@ -93,11 +162,14 @@ func (c *Client) Connect() error {
 	return nil
 }
 
-// Disconnect closes the MQTT connection
+// Disconnect closes the MQTT connection, first republishing queued messages
 func (c *Client) Disconnect() {
+	c.stopOnce.Do(func() { close(c.retryStop) })
 	if c.client != nil && c.client.IsConnected() {
+		c.queue.drain(c.send)
+
 		// Publish offline status
-		c.Publish(fmt.Sprintf("%s/bridge/status", c.topicPrefix), "offline", true)
+		c.Publish(bridgeTopic(c.topicPrefix, c.node, "status"), "offline", true)
 		c.client.Disconnect(250)
 	}
 }
@@ -111,13 +183,61 @@ func (c *Client) Reconnect(cfg *config.MQTTConfig) error {
 	}
 	c.connected = false
 	c.cfg = cfg
-	c.topicPrefix = cfg.TopicPrefix
+	c.topicPrefix = scopedTopicPrefix(cfg.TopicPrefix, c.instanceID)
 	c.mu.Unlock()
 
+	// Queued topics may be under the old prefixes; everything is published
+	// again once connected
+	if n := c.queue.clear(); n > 0 {
+		log.Printf("MQTT: dropped %d queued messages for the new configuration", n)
+	}
+
 	// Connect with new config
 	return c.Connect()
 }
 
+// TopicPrefix returns the prefix of all bridge topics, including the instance ID
+func (c *Client) TopicPrefix() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.topicPrefix
+}
+
+// SetNode moves the bridge status and stats topics under
+// <prefix>/bridge/<node>, so each instance of a sharded cluster reports its
+// own availability, and appends the node to the client ID so instances
+// sharing a configuration do not disconnect each other. Must be called
+// before Connect.
+func (c *Client) SetNode(node string) {
+	c.node = node
+}
+
+// BridgeTopic returns the topic of a bridge-level message such as "status"
+// or "stats", under the cluster node if one is set
+func (c *Client) BridgeTopic(name string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return bridgeTopic(c.topicPrefix, c.node, name)
+}
+
+// ClientID returns the client ID used to connect, including the cluster node
+// and any generated suffix
+func (c *Client) ClientID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	clientID := c.cfg.ClientID
+	if c.node != "" {
+		clientID += "-" + c.node
+	}
+	return clientID + c.clientIDSuffix
+}
+
+// ConnectionStats returns reconnect counters and whether a client ID
+// collision is suspected
+func (c *Client) ConnectionStats() ConnectionStats {
+	return c.collisions.stats(c.ClientID())
+}
+
 // GetConfig returns the current MQTT configuration
 func (c *Client) GetConfig() *config.MQTTConfig {
 	c.mu.RLock()
@@ -132,12 +252,20 @@ func (c *Client) IsConnected() bool {
 	return c.connected
 }
 
-// Publish publishes a message to a topic
+// Publish publishes a message to a topic. A failed retained publish is
+// queued and retried once the connection is back; the error is still
+// returned. A failed message that is not retained is dropped.
 func (c *Client) Publish(topic string, payload interface{}, retain bool) error {
-	if !c.client.IsConnected() {
-		return fmt.Errorf("not connected to MQTT broker")
-	}
+	return c.publish(topic, payload, retain, retain)
+}
 
+// publishState publishes a message carrying state, queued for retry when it
+// fails even if it is not retained
+func (c *Client) publishState(topic string, payload interface{}, retain bool) error {
+	return c.publish(topic, payload, retain, true)
+}
+
+func (c *Client) publish(topic string, payload interface{}, retain, queue bool) error {
 	var data []byte
 	switch v := payload.(type) {
 	case string:
@@ -146,24 +274,100 @@ func (c *Client) Publish(topic string, payload interface{}, retain bool) error {
 		data = v
 	default:
 		var err error
-		data, err = json.Marshal(payload)
+		data, err = timefmt.Marshal(payload)
 		if err != nil {
 			return fmt.Errorf("failed to marshal payload: %w", err)
 		}
 	}
 
+	if err := c.send(topic, data, retain); err != nil {
+		if queue {
+			c.queue.add(topic, data, retain)
+		} else {
+			c.queue.discard()
+		}
+		return err
+	}
+	c.queue.succeeded(topic)
+	return nil
+}
+
+// PublishStats returns publish counters and the retry queue length
+func (c *Client) PublishStats() PublishStats {
+	return c.queue.stats()
+}
+
+func (c *Client) send(topic string, data []byte, retain bool) error {
+	if !c.client.IsConnected() {
+		return ErrNotConnected
+	}
+
 	token := c.client.Publish(topic, 0, retain, data)
-	token.Wait()
+	if !token.WaitTimeout(publishTimeout) {
+		return fmt.Errorf("publish to %s timed out", topic)
+	}
 	return token.Error()
 }
 
-// PublishState publishes device state to MQTT
-func (c *Client) PublishState(deviceID string, state *domain.DeviceState) error {
+// retryLoop republishes queued messages after a reconnect and periodically
+// while connected
+func (c *Client) retryLoop() {
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.retryStop:
+			return
+		case <-c.retryKick:
+		case <-ticker.C:
+		}
+		if c.client.IsConnected() {
+			c.queue.drain(c.send)
+		}
+	}
+}
+
+// PublishState publishes device state to MQTT, with values laid out in the
+// configured state format. The profile is used to group values when nested.
+func (c *Client) PublishState(deviceID string, state *domain.DeviceState, profile *domain.Profile) error {
 	topic := fmt.Sprintf("%s/%s/state", c.topicPrefix, deviceID)
-	return c.Publish(topic, state, false)
+
+	payload := *state
+	payload.Values = formatStateValues(c.stateFormat, profile, state.Values)
+	return c.publishState(topic, &payload, c.cfg.Retain.DeviceStateRetained())
+}
+
+// PublishDeviceAvailability publishes a device's availability, retained
+// unless configured otherwise
+func (c *Client) PublishDeviceAvailability(deviceID string, online bool) error {
+	payload := "offline"
+	if online {
+		payload = "online"
+	}
+	return c.publishState(deviceAvailabilityTopic(c.topicPrefix, deviceID), payload, c.cfg.Retain.AvailabilityRetained())
 }
 
-// PublishEntityState publishes a single entity state
+// ClearEntityState removes a retained entity state from the broker
+func (c *Client) ClearEntityState(deviceID, entityID string) error {
+	topic := fmt.Sprintf("%s/%s/%s/state", c.topicPrefix, deviceID, entityID)
+	return c.Publish(topic, "", true)
+}
+
+// ClearDeviceState removes a retained full device state from the broker
+func (c *Client) ClearDeviceState(deviceID string) error {
+	topic := fmt.Sprintf("%s/%s/state", c.topicPrefix, deviceID)
+	return c.Publish(topic, "", true)
+}
+
+// PublishTrap publishes a received trap to the traps topic
+func (c *Client) PublishTrap(trap *domain.TrapLog) error {
+	topic := fmt.Sprintf("%s/traps", c.topicPrefix)
+	return c.Publish(topic, trap, false)
+}
+
+// PublishEntityState publishes a single entity state, retained unless
+// configured otherwise
 func (c *Client) PublishEntityState(deviceID, entityID string, value interface{}) error {
 	topic := fmt.Sprintf("%s/%s/%s/state", c.topicPrefix, deviceID, entityID)
 
@@ -181,7 +385,7 @@ func (c *Client) PublishEntityState(deviceID, entityID string, value interface{}
 		payload = fmt.Sprintf("%v", v)
 	}
 
-	return c.Publish(topic, payload, true)
+	return c.publishState(topic, payload, c.cfg.Retain.EntityStatesRetained())
 }
 
 // Subscribe subscribes to a topic with a handler
@@ -191,6 +395,11 @@ func (c *Client) Subscribe(topic string, handler mqtt.MessageHandler) error {
 	return token.Error()
 }
 
+// Unsubscribe removes a subscription made with Subscribe
+func (c *Client) Unsubscribe(topic string) {
+	c.client.Unsubscribe(topic).WaitTimeout(publishTimeout)
+}
+
 // SubscribeCommands subscribes to command topics for a device
 func (c *Client) SubscribeCommands(deviceID string, handler CommandHandler) error {
 	topic := fmt.Sprintf("%s/%s/+/set", c.topicPrefix, deviceID)
@@ -228,6 +437,10 @@ func (c *Client) resubscribe() {
 	c.handlersMu.RLock()
 	defer c.handlersMu.RUnlock()
 
+	if c.stateRequests != nil {
+		c.client.Subscribe(c.stateRequestTopic(), 0, c.handleStateRequest)
+	}
+
 	for deviceID := range c.handlers {
 		topic := fmt.Sprintf("%s/%s/+/set", c.topicPrefix, deviceID)
 		c.client.Subscribe(topic, 0, func(client mqtt.Client, msg mqtt.Message) {
@@ -244,6 +457,28 @@ func (c *Client) resubscribe() {
 	}
 }
 
+// scopedTopicPrefix nests an instance's topics under the configured prefix
+func scopedTopicPrefix(topicPrefix, instanceID string) string {
+	if instanceID == "" {
+		return topicPrefix
+	}
+	return topicPrefix + "/" + instanceID
+}
+
+// bridgeTopic returns <prefix>/bridge/<name>, or <prefix>/bridge/<node>/<name>
+// when the bridge runs as a node of a sharded cluster
+func bridgeTopic(topicPrefix, node, name string) string {
+	if node == "" {
+		return fmt.Sprintf("%s/bridge/%s", topicPrefix, name)
+	}
+	return fmt.Sprintf("%s/bridge/%s/%s", topicPrefix, node, name)
+}
+
+// deviceAvailabilityTopic returns the topic a device's availability is published on
+func deviceAvailabilityTopic(topicPrefix, deviceID string) string {
+	return fmt.Sprintf("%s/%s/availability", topicPrefix, deviceID)
+}
+
 func extractEntityID(topic, prefix, deviceID string) string {
 	// Topic format: prefix/deviceID/entityID/set
 	// We need to extract entityID
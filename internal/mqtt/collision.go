@@ -0,0 +1,111 @@
+package mqtt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// A session shorter than this counts towards collision detection
+	shortSessionDuration = 10 * time.Second
+	// Short sessions are counted within this window
+	collisionWindow = 2 * time.Minute
+	// This many short sessions within the window suggest a client ID collision
+	collisionThreshold = 3
+)
+
+// ConnectionStats reports reconnect behaviour and suspected client ID collisions
+type ConnectionStats struct {
+	ClientID           string     `json:"client_id"`
+	Reconnects         int        `json:"reconnects"`
+	ShortSessions      int        `json:"short_sessions"`
+	CollisionSuspected bool       `json:"collision_suspected"`
+	LastDisconnect     *time.Time `json:"last_disconnect,omitempty"`
+}
+
+// collisionDetector watches for sessions that are dropped shortly after
+// connecting. Brokers disconnect the older session when a second client
+// connects with the same ID, so two instances sharing an ID keep kicking
+// each other off in a tight loop.
+type collisionDetector struct {
+	mu             sync.Mutex
+	connectedAt    time.Time
+	connects       int
+	shortLosses    []time.Time
+	lastDisconnect *time.Time
+	warned         bool
+}
+
+func (d *collisionDetector) connected() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.connectedAt = time.Now()
+	d.connects++
+}
+
+// lost records a dropped connection and reports whether this loss pushes the
+// short session count over the threshold for the first time in an episode
+func (d *collisionDetector) lost() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	d.lastDisconnect = &now
+	if !d.connectedAt.IsZero() && now.Sub(d.connectedAt) < shortSessionDuration {
+		d.shortLosses = append(d.shortLosses, now)
+	}
+	d.pruneLocked(now)
+
+	suspected := len(d.shortLosses) >= collisionThreshold
+	if !suspected {
+		d.warned = false
+		return false
+	}
+	if d.warned {
+		return false
+	}
+	d.warned = true
+	return true
+}
+
+func (d *collisionDetector) pruneLocked(now time.Time) {
+	i := 0
+	for i < len(d.shortLosses) && now.Sub(d.shortLosses[i]) > collisionWindow {
+		i++
+	}
+	d.shortLosses = d.shortLosses[i:]
+}
+
+func (d *collisionDetector) stats(clientID string) ConnectionStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pruneLocked(time.Now())
+	reconnects := d.connects - 1
+	if reconnects < 0 {
+		reconnects = 0
+	}
+	return ConnectionStats{
+		ClientID:           clientID,
+		Reconnects:         reconnects,
+		ShortSessions:      len(d.shortLosses),
+		CollisionSuspected: len(d.shortLosses) >= collisionThreshold,
+		LastDisconnect:     d.lastDisconnect,
+	}
+}
+
+// CollisionWarning explains a suspected client ID collision and how to fix it
+func CollisionWarning(clientID string) string {
+	return fmt.Sprintf("MQTT connection keeps dropping shortly after connecting; another client may be using client ID %q. "+
+		"Set a unique mqtt.client_id or enable mqtt.client_id_suffix", clientID)
+}
+
+// clientIDSuffix returns a random suffix that makes a client ID unique per process
+func clientIDSuffix() string {
+	b := make([]byte, 3)
+	rand.Read(b)
+	return "-" + hex.EncodeToString(b)
+}
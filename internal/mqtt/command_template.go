@@ -0,0 +1,92 @@
+package mqtt
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"snmp-mqtt-bridge/internal/domain"
+)
+
+// commandTemplateFuncs are the helpers available to command templates
+var commandTemplateFuncs = template.FuncMap{
+	// onOff returns on for an ON payload and off otherwise
+	"onOff": func(payload, on, off string) string {
+		if convertToSwitchValue(payload) == "ON" {
+			return on
+		}
+		return off
+	},
+	// setField replaces one element of a separated value, e.g. "1,0,0" -> "1,1,0"
+	"setField": func(value interface{}, sep string, index int, field string) (string, error) {
+		parts := strings.Split(fmt.Sprintf("%v", value), sep)
+		if index < 0 || index >= len(parts) {
+			return "", fmt.Errorf("field index %d out of range (len=%d)", index, len(parts))
+		}
+		parts[index] = field
+		return strings.Join(parts, sep), nil
+	},
+	// setBit sets or clears one bit of an integer value
+	"setBit": func(value interface{}, bit int, on bool) (int64, error) {
+		n, err := strconv.ParseInt(fmt.Sprintf("%v", value), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		if on {
+			return n | 1<<bit, nil
+		}
+		return n &^ (1 << bit), nil
+	},
+	"atoi": func(value interface{}) (int, error) {
+		return strconv.Atoi(strings.TrimSpace(fmt.Sprintf("%v", value)))
+	},
+}
+
+// commandTemplateData is passed to a mapping's command template
+type commandTemplateData struct {
+	Payload string                 // Payload received from Home Assistant
+	Values  map[string]interface{} // Last polled values by mapping name and OID
+
+	read    func() (interface{}, error)
+	current interface{}
+	fetched bool
+}
+
+// Current returns the mapping's value read from the device, so templates can
+// modify it. The device is only queried when the template uses it.
+func (d *commandTemplateData) Current() (interface{}, error) {
+	if !d.fetched {
+		value, err := d.read()
+		if err != nil {
+			return nil, err
+		}
+		d.current = value
+		d.fetched = true
+	}
+	return d.current, nil
+}
+
+// renderCommandTemplate evaluates a mapping's command template into the value
+// written with SNMP SET
+func (p *Publisher) renderCommandTemplate(device *domain.Device, payload string, mapping *domain.OIDMapping) (string, error) {
+	tmpl, err := template.New(mapping.Name).Funcs(commandTemplateFuncs).Parse(mapping.CommandTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid command template: %w", err)
+	}
+
+	data := &commandTemplateData{
+		Payload: payload,
+		Values:  p.poller.GetDeviceValues(device.ID),
+		read: func() (interface{}, error) {
+			return p.readSNMPValue(device, mapping.OID)
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("render command template: %w", err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
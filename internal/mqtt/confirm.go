@@ -0,0 +1,220 @@
+package mqtt
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+)
+
+// Entities of devices that confirm commands: commands are staged on the
+// pending sensor and only sent once the confirm button is pressed
+const (
+	pendingEntity = "pending_command"
+	confirmEntity = "confirm_command"
+	cancelEntity  = "cancel_command"
+)
+
+// noPendingCommand is the pending sensor's state while nothing is staged
+const noPendingCommand = "None"
+
+// defaultConfirmTimeout applies when no confirm timeout is configured
+const defaultConfirmTimeout = 30 * time.Second
+
+// stagedCommand is a command waiting for confirmation
+type stagedCommand struct {
+	command *preparedCommand
+	timer   *time.Timer // Drops the command once the confirm timeout has passed
+}
+
+// confirmsCommands reports whether a device stages commands for confirmation:
+// all of them with confirm_writes, otherwise those of actions marked confirm
+func confirmsCommands(device *domain.Device, profile *domain.Profile) bool {
+	if device.ConfirmWrites {
+		return true
+	}
+	if profile == nil {
+		return false
+	}
+	for _, action := range profile.Actions {
+		if action.Confirm {
+			return true
+		}
+	}
+	return false
+}
+
+// SetConfirmTimeout sets how long a command to a device with confirm_writes
+// waits for the Confirm Command button before it is dropped
+func (p *Publisher) SetConfirmTimeout(timeout time.Duration) {
+	p.confirmTimeout = timeout
+}
+
+// resolveStaged sends the staged command of a device on confirm and drops it
+// on cancel
+func (p *Publisher) resolveStaged(info *deviceInfo, entityID string) {
+	deviceID := info.device.ID
+
+	p.devicesMu.Lock()
+	staged := info.staged
+	info.staged = nil
+	if staged != nil {
+		staged.timer.Stop()
+	}
+	p.devicesMu.Unlock()
+
+	if staged == nil {
+		log.Printf("No command pending confirmation for %s", deviceID)
+		return
+	}
+	p.publishPendingCommand(deviceID, noPendingCommand)
+	if entityID == cancelEntity {
+		log.Printf("Cancelled command for %s/%s", deviceID, staged.command.entityID)
+		return
+	}
+	log.Printf("Confirmed command for %s/%s", deviceID, staged.command.entityID)
+	p.executeCommand(info, staged.command)
+}
+
+// stageCommand holds a command back until it is confirmed, replacing the
+// one staged before
+func (p *Publisher) stageCommand(info *deviceInfo, cmd *preparedCommand) {
+	deviceID := info.device.ID
+
+	timeout := p.confirmTimeout
+	if timeout <= 0 {
+		timeout = defaultConfirmTimeout
+	}
+	staged := &stagedCommand{command: cmd}
+
+	p.devicesMu.Lock()
+	if info.staged != nil {
+		info.staged.timer.Stop()
+	}
+	info.staged = staged
+	staged.timer = time.AfterFunc(timeout, func() {
+		p.expireStaged(deviceID, staged)
+	})
+	p.devicesMu.Unlock()
+
+	log.Printf("Command for %s/%s awaits confirmation for %s", deviceID, cmd.entityID, timeout)
+	p.publishPendingCommand(deviceID, p.describeCommand(info, cmd))
+}
+
+// expireStaged drops a staged command nobody confirmed in time. The device is
+// looked up again as an update may have carried the command over.
+func (p *Publisher) expireStaged(deviceID string, staged *stagedCommand) {
+	p.devicesMu.Lock()
+	info := p.devices[deviceID]
+	current := info != nil && info.staged == staged
+	if current {
+		info.staged = nil
+	}
+	p.devicesMu.Unlock()
+
+	if !current || p.ctx.Err() != nil {
+		return
+	}
+	log.Printf("Command for %s/%s was not confirmed in time, dropping it", deviceID, staged.command.entityID)
+	p.publishPendingCommand(deviceID, noPendingCommand)
+}
+
+// dropStaged stops the timer of a device's staged command, for a device
+// being unregistered
+func (p *Publisher) dropStaged(info *deviceInfo) {
+	p.devicesMu.Lock()
+	defer p.devicesMu.Unlock()
+	if info.staged != nil {
+		info.staged.timer.Stop()
+		info.staged = nil
+	}
+}
+
+// describeCommand names a command for the pending sensor, e.g. "Outlet 1: OFF"
+func (p *Publisher) describeCommand(info *deviceInfo, cmd *preparedCommand) string {
+	if cmd.action != nil {
+		return p.translation(info).Name(cmd.action.Name)
+	}
+	name := p.translation(info).Name(cmd.mapping.Name)
+	if label, ok := info.device.Labels[cmd.mapping.Name]; ok {
+		name = label
+	}
+	return fmt.Sprintf("%s: %s", name, cmd.payload)
+}
+
+// publishPendingCommand publishes the state of a device's pending sensor
+func (p *Publisher) publishPendingCommand(deviceID, state string) {
+	if err := p.client.PublishEntityState(deviceID, pendingEntity, state); err != nil {
+		log.Printf("Failed to publish pending command for %s: %v", deviceID, err)
+	}
+}
+
+// publishConfirmEntities publishes the pending sensor and the confirm and
+// cancel buttons of a device that confirms commands
+func (d *Discovery) publishConfirmEntities(device *domain.Device, haDevice *DiscoveryDevice, devicePrefix string) error {
+	for _, entity := range []struct {
+		id        string
+		name      string
+		component domain.HAComponent
+		icon      string
+	}{
+		{pendingEntity, "Pending Command", domain.HAComponentSensor, "mdi:timer-sand"},
+		{confirmEntity, "Confirm Command", domain.HAComponentButton, "mdi:check-circle"},
+		{cancelEntity, "Cancel Command", domain.HAComponentButton, "mdi:close-circle"},
+	} {
+		config := &DiscoveryConfig{
+			Name:                entity.name,
+			UniqueID:            d.uniqueID(device.ID, entity.id),
+			ObjectID:            fmt.Sprintf("%s_%s", devicePrefix, entity.id),
+			Device:              haDevice,
+			AvailabilityTopic:   d.statusTopic(),
+			PayloadAvailable:    "online",
+			PayloadNotAvailable: "offline",
+			Icon:                entity.icon,
+		}
+		d.applyDeviceAvailability(config, device.ID)
+		if entity.component == domain.HAComponentSensor {
+			config.StateTopic = fmt.Sprintf("%s/%s/%s/state", d.topicPrefix, device.ID, entity.id)
+			config.EntityCategory = "diagnostic"
+		} else {
+			config.CommandTopic = fmt.Sprintf("%s/%s/%s/set", d.topicPrefix, device.ID, entity.id)
+			config.Extra = map[string]interface{}{"payload_press": "PRESS"}
+		}
+
+		topic := fmt.Sprintf("%s/%s/%s/%s/config",
+			d.discoveryPrefix,
+			componentToString(entity.component),
+			d.nodeID(device.ID),
+			entity.id,
+		)
+		if err := d.publishConfig(topic, config); err != nil {
+			return fmt.Errorf("failed to publish discovery for %s: %w", entity.name, err)
+		}
+	}
+	return nil
+}
+
+// RemoveConfirmEntities removes the pending sensor and the confirm and cancel
+// buttons of a device
+func (d *Discovery) RemoveConfirmEntities(deviceID string) error {
+	for _, entity := range []struct {
+		id        string
+		component domain.HAComponent
+	}{
+		{pendingEntity, domain.HAComponentSensor},
+		{confirmEntity, domain.HAComponentButton},
+		{cancelEntity, domain.HAComponentButton},
+	} {
+		topic := fmt.Sprintf("%s/%s/%s/%s/config",
+			d.discoveryPrefix,
+			componentToString(entity.component),
+			d.nodeID(deviceID),
+			entity.id,
+		)
+		if err := d.removeConfig(topic); err != nil {
+			return fmt.Errorf("failed to remove discovery for %s: %w", entity.id, err)
+		}
+	}
+	return nil
+}
@@ -3,52 +3,72 @@ package mqtt
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"strings"
+	"sync"
 
+	"snmp-mqtt-bridge/internal/buildinfo"
 	"snmp-mqtt-bridge/internal/domain"
 )
 
 // DiscoveryConfig represents Home Assistant MQTT discovery payload
 type DiscoveryConfig struct {
-	Name              string            `json:"name"`
-	UniqueID          string            `json:"unique_id"`
-	ObjectID          string            `json:"object_id,omitempty"`
-	StateTopic        string            `json:"state_topic,omitempty"`
-	CommandTopic      string            `json:"command_topic,omitempty"`
-	AvailabilityTopic string            `json:"availability_topic,omitempty"`
-	PayloadAvailable  string            `json:"payload_available,omitempty"`
-	PayloadNotAvailable string          `json:"payload_not_available,omitempty"`
-	Device            *DiscoveryDevice  `json:"device,omitempty"`
-	DeviceClass       string            `json:"device_class,omitempty"`
-	StateClass        string            `json:"state_class,omitempty"`
-	UnitOfMeasurement string            `json:"unit_of_measurement,omitempty"`
-	Icon              string            `json:"icon,omitempty"`
-	EntityCategory    string            `json:"entity_category,omitempty"`
-	ValueTemplate     string            `json:"value_template,omitempty"`
-	PayloadOn         string            `json:"payload_on,omitempty"`
-	PayloadOff        string            `json:"payload_off,omitempty"`
-	Options           []string          `json:"options,omitempty"`
-	Min               float64           `json:"min,omitempty"`
-	Max               float64           `json:"max,omitempty"`
-	Step              float64           `json:"step,omitempty"`
-	Extra             map[string]interface{} `json:"-"` // For any extra fields
+	Name                string                  `json:"name"`
+	UniqueID            string                  `json:"unique_id"`
+	ObjectID            string                  `json:"object_id,omitempty"`
+	StateTopic          string                  `json:"state_topic,omitempty"`
+	CommandTopic        string                  `json:"command_topic,omitempty"`
+	AvailabilityTopic   string                  `json:"availability_topic,omitempty"`
+	Availability        []DiscoveryAvailability `json:"availability,omitempty"`
+	AvailabilityMode    string                  `json:"availability_mode,omitempty"`
+	PayloadAvailable    string                  `json:"payload_available,omitempty"`
+	PayloadNotAvailable string                  `json:"payload_not_available,omitempty"`
+	Device              *DiscoveryDevice        `json:"device,omitempty"`
+	DeviceClass         string                  `json:"device_class,omitempty"`
+	StateClass          string                  `json:"state_class,omitempty"`
+	UnitOfMeasurement   string                  `json:"unit_of_measurement,omitempty"`
+	Icon                string                  `json:"icon,omitempty"`
+	EntityCategory      string                  `json:"entity_category,omitempty"`
+	ValueTemplate       string                  `json:"value_template,omitempty"`
+	PayloadOn           string                  `json:"payload_on,omitempty"`
+	PayloadOff          string                  `json:"payload_off,omitempty"`
+	Options             []string                `json:"options,omitempty"`
+	Min                 float64                 `json:"min,omitempty"`
+	Max                 float64                 `json:"max,omitempty"`
+	Step                float64                 `json:"step,omitempty"`
+	Extra               map[string]interface{}  `json:"-"` // For any extra fields
+}
+
+// DiscoveryAvailability is one entry of an entity's availability list
+type DiscoveryAvailability struct {
+	Topic string `json:"topic"`
 }
 
 // DiscoveryDevice represents device information in discovery payload
 type DiscoveryDevice struct {
-	Identifiers  []string `json:"identifiers"`
-	Name         string   `json:"name"`
-	Manufacturer string   `json:"manufacturer,omitempty"`
-	Model        string   `json:"model,omitempty"`
-	SwVersion    string   `json:"sw_version,omitempty"`
-	ViaDevice    string   `json:"via_device,omitempty"`
+	Identifiers   []string `json:"identifiers"`
+	Name          string   `json:"name"`
+	Manufacturer  string   `json:"manufacturer,omitempty"`
+	Model         string   `json:"model,omitempty"`
+	SwVersion     string   `json:"sw_version,omitempty"`
+	ViaDevice     string   `json:"via_device,omitempty"`
+	SuggestedArea string   `json:"suggested_area,omitempty"`
 }
 
 // Discovery manages Home Assistant MQTT auto-discovery
 type Discovery struct {
-	client          *Client
-	discoveryPrefix string
-	topicPrefix     string
+	client             *Client
+	discoveryPrefix    string
+	topicPrefix        string
+	suggestedArea      bool
+	deviceAvailability bool
+	fixStateClasses    bool
+	instanceID         string // Sanitized for use in IDs
+	instanceName       string
+	configs            configRegistry
+
+	localeMu sync.RWMutex
+	locale   string // Entity names and enum labels are translated to; empty for English
 }
 
 // NewDiscovery creates a new discovery manager
@@ -60,21 +80,137 @@ func NewDiscovery(client *Client, discoveryPrefix, topicPrefix string) *Discover
 	}
 }
 
-// PublishDevice publishes discovery configs for all entities of a device
-func (d *Discovery) PublishDevice(device *domain.Device, profile *domain.Profile) error {
-	if profile == nil {
-		return nil
+// SetSuggestedArea controls whether a device's location is sent as the HA suggested area
+func (d *Discovery) SetSuggestedArea(enabled bool) {
+	d.suggestedArea = enabled
+}
+
+// SetFixStateClasses publishes the state class Home Assistant long-term
+// statistics need for sensors whose state class is wrong or missing
+func (d *Discovery) SetFixStateClasses(enabled bool) {
+	d.fixStateClasses = enabled
+}
+
+// SetLocale selects the locale entity names and select options are
+// published in; profiles without a translation for it stay English
+func (d *Discovery) SetLocale(locale string) {
+	d.localeMu.Lock()
+	d.locale = locale
+	d.localeMu.Unlock()
+}
+
+// Locale returns the selected locale, empty for English
+func (d *Discovery) Locale() string {
+	d.localeMu.RLock()
+	defer d.localeMu.RUnlock()
+	return d.locale
+}
+
+// SetInstanceID scopes unique IDs, discovery topics and the bridge device to
+// an instance, so several bridges can share a Home Assistant instance
+func (d *Discovery) SetInstanceID(instanceID string) {
+	d.instanceID = sanitizeEntityID(instanceID)
+	d.instanceName = instanceID
+}
+
+// uniqueID joins parts into a unique ID under the instance, e.g.
+// snmp_bridge_<instance>_<device>_<entity>
+func (d *Discovery) uniqueID(parts ...string) string {
+	return d.scopedID("snmp_bridge", parts)
+}
+
+// objectID joins parts into an object ID under the instance, e.g.
+// snmp_mqtt_<instance>_<name>_<short id>
+func (d *Discovery) objectID(parts ...string) string {
+	return d.scopedID("snmp_mqtt", parts)
+}
+
+// bridgeID identifies the bridge device that devices are connected via
+func (d *Discovery) bridgeID() string {
+	return d.scopedID("snmp_mqtt_bridge", nil)
+}
+
+// nodeID is the discovery topic node for a device or the scenes node
+func (d *Discovery) nodeID(id string) string {
+	if d.instanceID == "" {
+		return id
+	}
+	return d.instanceID + "_" + id
+}
+
+func (d *Discovery) scopedID(prefix string, parts []string) string {
+	if d.instanceID != "" {
+		prefix += "_" + d.instanceID
+	}
+	if len(parts) == 0 {
+		return prefix
+	}
+	return prefix + "_" + strings.Join(parts, "_")
+}
+
+// statusTopic is the bridge status topic entities are available through
+func (d *Discovery) statusTopic() string {
+	return bridgeTopic(d.topicPrefix, d.client.node, "status")
+}
+
+// SetDeviceAvailability controls whether entities also follow their device's
+// availability topic, in addition to the bridge status
+func (d *Discovery) SetDeviceAvailability(enabled bool) {
+	d.deviceAvailability = enabled
+}
+
+// applyDeviceAvailability replaces the bridge status topic with an
+// availability list that also requires the device's own topic to be online
+func (d *Discovery) applyDeviceAvailability(config *DiscoveryConfig, deviceID string) {
+	if !d.deviceAvailability {
+		return
+	}
+	config.Availability = []DiscoveryAvailability{
+		{Topic: config.AvailabilityTopic},
+		{Topic: deviceAvailabilityTopic(d.topicPrefix, deviceID)},
 	}
+	config.AvailabilityMode = "all"
+	config.AvailabilityTopic = ""
+}
 
+// haDevice builds the discovery device block shared by all entities of a device
+func (d *Discovery) haDevice(device *domain.Device, profile *domain.Profile) *DiscoveryDevice {
 	haDevice := &DiscoveryDevice{
-		Identifiers:  []string{fmt.Sprintf("snmp_bridge_%s", device.ID)},
+		Identifiers:  []string{d.uniqueID(device.ID)},
 		Name:         device.Name,
 		Manufacturer: profile.Manufacturer,
 		Model:        profile.Model,
-		ViaDevice:    "snmp_mqtt_bridge",
+		ViaDevice:    d.bridgeID(),
+	}
+	// Per-device overrides for rebranded or generic hardware
+	if device.HAName != "" {
+		haDevice.Name = device.HAName
 	}
+	if device.Manufacturer != "" {
+		haDevice.Manufacturer = device.Manufacturer
+	}
+	if device.Model != "" {
+		haDevice.Model = device.Model
+	}
+	if d.suggestedArea {
+		haDevice.SuggestedArea = device.Location
+		if haDevice.SuggestedArea == "" {
+			haDevice.SuggestedArea = device.Site
+		}
+	}
+	return haDevice
+}
+
+// PublishDevice publishes discovery configs for all entities of a device
+func (d *Discovery) PublishDevice(device *domain.Device, profile *domain.Profile) error {
+	if profile == nil {
+		return nil
+	}
+
+	haDevice := d.haDevice(device, profile)
+	translation := profile.Translation(d.Locale())
 
-	availabilityTopic := fmt.Sprintf("%s/bridge/status", d.topicPrefix)
+	availabilityTopic := d.statusTopic()
 
 	// Create device prefix for entity IDs using name + short ID for uniqueness
 	// e.g., "snmp_mqtt_pdu_001_a7a66242" ensures unique entity IDs even with duplicate names
@@ -82,24 +218,26 @@ func (d *Discovery) PublishDevice(device *domain.Device, profile *domain.Profile
 	if len(shortID) > 8 {
 		shortID = shortID[:8]
 	}
-	devicePrefix := fmt.Sprintf("snmp_mqtt_%s_%s", sanitizeEntityID(device.Name), shortID)
+	devicePrefix := d.objectID(sanitizeEntityID(device.Name), shortID)
 
 	for _, mapping := range profile.OIDMappings {
 		entityID := sanitizeEntityID(mapping.Name)
-		uniqueID := fmt.Sprintf("snmp_bridge_%s_%s", device.ID, entityID)
+		uniqueID := d.uniqueID(device.ID, entityID)
 		// Object ID includes device name + short ID for uniqueness and easier searching in HA
 		objectID := fmt.Sprintf("%s_%s", devicePrefix, entityID)
 
 		config := &DiscoveryConfig{
-			Name:              mapping.Name,
-			UniqueID:          uniqueID,
-			ObjectID:          objectID,
-			Device:            haDevice,
-			AvailabilityTopic: availabilityTopic,
-			PayloadAvailable:  "online",
+			Name:                translation.Name(mapping.Name),
+			UniqueID:            uniqueID,
+			ObjectID:            objectID,
+			Device:              haDevice,
+			AvailabilityTopic:   availabilityTopic,
+			PayloadAvailable:    "online",
 			PayloadNotAvailable: "offline",
 		}
 
+		d.applyDeviceAvailability(config, device.ID)
+
 		// Apply custom label if available
 		if device.Labels != nil {
 			if label, ok := device.Labels[mapping.Name]; ok {
@@ -108,14 +246,23 @@ func (d *Discovery) PublishDevice(device *domain.Device, profile *domain.Profile
 		}
 
 		// Set common properties
-		if mapping.DeviceClass != "" {
+		// Home Assistant rejects the whole entity for an unknown class, so
+		// invalid ones are left out
+		for _, warning := range mapping.ClassWarnings() {
+			log.Printf("Warning: device %s: %s, omitting it from discovery", device.Name, warning)
+		}
+		if domain.ValidDeviceClass(mapping.HAComponent, mapping.DeviceClass) {
 			config.DeviceClass = mapping.DeviceClass
 		}
-		if mapping.StateClass != "" {
+		if domain.ValidStateClass(mapping.HAComponent, mapping.StateClass) {
 			config.StateClass = mapping.StateClass
 		}
-		if mapping.Unit != "" {
-			config.UnitOfMeasurement = mapping.Unit
+		if d.fixStateClasses && mapping.HAComponent == domain.HAComponentSensor {
+			config.StateClass = mapping.StatisticsStateClass()
+		}
+		config.UnitOfMeasurement = mapping.PublishedUnit()
+		if mapping.Precision != nil && mapping.HAComponent == domain.HAComponentSensor {
+			config.Extra = map[string]interface{}{"suggested_display_precision": *mapping.Precision}
 		}
 		if mapping.Icon != "" {
 			config.Icon = mapping.Icon
@@ -123,6 +270,9 @@ func (d *Discovery) PublishDevice(device *domain.Device, profile *domain.Profile
 		if mapping.Category != "" {
 			config.EntityCategory = mapping.Category
 		}
+		if mapping.ValueTemplate != "" {
+			config.ValueTemplate = mapping.ValueTemplate
+		}
 
 		// Build topics based on component type
 		stateTopic := fmt.Sprintf("%s/%s/%s/state", d.topicPrefix, device.ID, entityID)
@@ -165,6 +315,9 @@ func (d *Discovery) PublishDevice(device *domain.Device, profile *domain.Profile
 						options = append(options, v)
 					}
 				}
+				for i, option := range options {
+					options[i] = translation.Label(option)
+				}
 				config.Options = options
 			}
 
@@ -178,48 +331,80 @@ func (d *Discovery) PublishDevice(device *domain.Device, profile *domain.Profile
 		topic := fmt.Sprintf("%s/%s/%s/%s/config",
 			d.discoveryPrefix,
 			componentToString(mapping.HAComponent),
-			device.ID,
+			d.nodeID(device.ID),
 			entityID,
 		)
 
-		if err := d.client.Publish(topic, config, true); err != nil {
+		if err := d.publishConfig(topic, config); err != nil {
 			return fmt.Errorf("failed to publish discovery for %s: %w", mapping.Name, err)
 		}
 	}
 
+	// Profile actions are exposed as buttons
+	for _, action := range profile.Actions {
+		entityID := actionEntityID(action)
+
+		config := &DiscoveryConfig{
+			Name:                translation.Name(action.Name),
+			UniqueID:            d.uniqueID(device.ID, entityID),
+			ObjectID:            fmt.Sprintf("%s_%s", devicePrefix, entityID),
+			Device:              haDevice,
+			CommandTopic:        fmt.Sprintf("%s/%s/%s/set", d.topicPrefix, device.ID, entityID),
+			AvailabilityTopic:   availabilityTopic,
+			PayloadAvailable:    "online",
+			PayloadNotAvailable: "offline",
+			Icon:                action.Icon,
+			EntityCategory:      "config",
+			Extra:               map[string]interface{}{"payload_press": "PRESS"},
+		}
+		d.applyDeviceAvailability(config, device.ID)
+
+		topic := fmt.Sprintf("%s/%s/%s/%s/config",
+			d.discoveryPrefix,
+			componentToString(domain.HAComponentButton),
+			d.nodeID(device.ID),
+			entityID,
+		)
+
+		if err := d.publishConfig(topic, config); err != nil {
+			return fmt.Errorf("failed to publish discovery for action %s: %w", action.Name, err)
+		}
+	}
+
+	if err := d.publishLastErrorSensor(device.ID, haDevice, devicePrefix); err != nil {
+		return err
+	}
+	if confirmsCommands(device, profile) {
+		return d.publishConfirmEntities(device, haDevice, devicePrefix)
+	}
 	return nil
 }
 
 // UpdateSelectOptions updates the options for a select entity
 func (d *Discovery) UpdateSelectOptions(device *domain.Device, profile *domain.Profile, mapping domain.OIDMapping, options []string) error {
 	entityID := sanitizeEntityID(mapping.Name)
-	uniqueID := fmt.Sprintf("snmp_bridge_%s_%s", device.ID, entityID)
+	uniqueID := d.uniqueID(device.ID, entityID)
 	shortID := device.ID
 	if len(shortID) > 8 {
 		shortID = shortID[:8]
 	}
-	devicePrefix := fmt.Sprintf("snmp_mqtt_%s_%s", sanitizeEntityID(device.Name), shortID)
+	devicePrefix := d.objectID(sanitizeEntityID(device.Name), shortID)
 	objectID := fmt.Sprintf("%s_%s", devicePrefix, entityID)
 
-	haDevice := &DiscoveryDevice{
-		Identifiers:  []string{fmt.Sprintf("snmp_bridge_%s", device.ID)},
-		Name:         device.Name,
-		Manufacturer: profile.Manufacturer,
-		Model:        profile.Model,
-		ViaDevice:    "snmp_mqtt_bridge",
-	}
+	haDevice := d.haDevice(device, profile)
 
 	config := &DiscoveryConfig{
-		Name:     mapping.Name,
-		UniqueID: uniqueID,
-		ObjectID: objectID,
-		Device:   haDevice,
-		AvailabilityTopic:   fmt.Sprintf("%s/bridge/status", d.topicPrefix),
+		Name:                profile.Translation(d.Locale()).Name(mapping.Name),
+		UniqueID:            uniqueID,
+		ObjectID:            objectID,
+		Device:              haDevice,
+		AvailabilityTopic:   d.statusTopic(),
 		PayloadAvailable:    "online",
 		PayloadNotAvailable: "offline",
 		StateTopic:          fmt.Sprintf("%s/%s/%s/state", d.topicPrefix, device.ID, entityID),
 		Options:             options,
 	}
+	d.applyDeviceAvailability(config, device.ID)
 
 	if mapping.Writable {
 		config.CommandTopic = fmt.Sprintf("%s/%s/%s/set", d.topicPrefix, device.ID, entityID)
@@ -228,15 +413,18 @@ func (d *Discovery) UpdateSelectOptions(device *domain.Device, profile *domain.P
 	if mapping.Icon != "" {
 		config.Icon = mapping.Icon
 	}
+	if mapping.ValueTemplate != "" {
+		config.ValueTemplate = mapping.ValueTemplate
+	}
 
 	topic := fmt.Sprintf("%s/%s/%s/%s/config",
 		d.discoveryPrefix,
 		componentToString(mapping.HAComponent),
-		device.ID,
+		d.nodeID(device.ID),
 		entityID,
 	)
 
-	return d.client.Publish(topic, config, true)
+	return d.publishConfig(topic, config)
 }
 
 // RemoveDevice removes all discovery configs for a device
@@ -251,19 +439,199 @@ func (d *Discovery) RemoveDevice(deviceID string, profile *domain.Profile) error
 		topic := fmt.Sprintf("%s/%s/%s/%s/config",
 			d.discoveryPrefix,
 			componentToString(mapping.HAComponent),
-			deviceID,
+			d.nodeID(deviceID),
 			entityID,
 		)
 
 		// Publish empty payload to remove discovery
-		if err := d.client.Publish(topic, "", true); err != nil {
+		if err := d.removeConfig(topic); err != nil {
 			return fmt.Errorf("failed to remove discovery for %s: %w", mapping.Name, err)
 		}
 	}
 
+	for _, action := range profile.Actions {
+		topic := fmt.Sprintf("%s/%s/%s/%s/config",
+			d.discoveryPrefix,
+			componentToString(domain.HAComponentButton),
+			d.nodeID(deviceID),
+			actionEntityID(action),
+		)
+
+		if err := d.removeConfig(topic); err != nil {
+			return fmt.Errorf("failed to remove discovery for action %s: %w", action.Name, err)
+		}
+	}
+
+	if err := d.removeConfig(d.lastErrorTopic(deviceID)); err != nil {
+		return fmt.Errorf("failed to remove discovery for Last Error: %w", err)
+	}
 	return nil
 }
 
+// RemoveStaleConfigs removes the published configs of a device's entities
+// that it no longer has with the given profile, e.g. after a mapping was
+// removed or confirm_writes turned off. Configs of remaining entities stay,
+// so Home Assistant keeps them and their customizations.
+func (d *Discovery) RemoveStaleConfigs(device *domain.Device, profile *domain.Profile) error {
+	current := make(map[string]bool)
+	entityTopic := func(component domain.HAComponent, entityID string) string {
+		return fmt.Sprintf("%s/%s/%s/%s/config",
+			d.discoveryPrefix,
+			componentToString(component),
+			d.nodeID(device.ID),
+			entityID,
+		)
+	}
+	if profile != nil {
+		for _, mapping := range profile.OIDMappings {
+			current[entityTopic(mapping.HAComponent, sanitizeEntityID(mapping.Name))] = true
+		}
+		for _, action := range profile.Actions {
+			current[entityTopic(domain.HAComponentButton, actionEntityID(action))] = true
+		}
+		current[d.lastErrorTopic(device.ID)] = true
+		if confirmsCommands(device, profile) {
+			current[entityTopic(domain.HAComponentSensor, pendingEntity)] = true
+			current[entityTopic(domain.HAComponentButton, confirmEntity)] = true
+			current[entityTopic(domain.HAComponentButton, cancelEntity)] = true
+		}
+	}
+
+	for _, topic := range d.configs.node(d.nodeID(device.ID)) {
+		if current[topic] {
+			continue
+		}
+		if err := d.removeConfig(topic); err != nil {
+			return fmt.Errorf("failed to remove discovery %s: %w", topic, err)
+		}
+	}
+	return nil
+}
+
+// ForgetDevice stops checking a device's discovery configs without removing
+// them, for a device another cluster node now publishes
+func (d *Discovery) ForgetDevice(deviceID string) {
+	d.configs.deleteNode(d.nodeID(deviceID))
+}
+
+// bridgeDevice is the discovery device block for bridge-level entities such as scenes
+func (d *Discovery) bridgeDevice() *DiscoveryDevice {
+	name := "SNMP-MQTT Bridge"
+	if d.instanceID != "" {
+		name = fmt.Sprintf("SNMP-MQTT Bridge (%s)", d.instanceName)
+	}
+	return &DiscoveryDevice{
+		Identifiers:  []string{d.bridgeID()},
+		Name:         name,
+		Manufacturer: "SNMP-MQTT Bridge",
+		SwVersion:    buildinfo.Get().Version,
+	}
+}
+
+// PublishBridgeUpdate publishes an update entity on the bridge device that
+// shows whether a newer release is available
+func (d *Discovery) PublishBridgeUpdate() error {
+	config := &DiscoveryConfig{
+		Name:                "Bridge Update",
+		UniqueID:            d.uniqueID("bridge_update"),
+		ObjectID:            d.objectID("bridge_update"),
+		Device:              d.bridgeDevice(),
+		StateTopic:          fmt.Sprintf("%s/bridge/update", d.topicPrefix),
+		AvailabilityTopic:   d.statusTopic(),
+		PayloadAvailable:    "online",
+		PayloadNotAvailable: "offline",
+		EntityCategory:      "diagnostic",
+	}
+
+	topic := fmt.Sprintf("%s/update/%s/bridge_update/config", d.discoveryPrefix, d.nodeID("bridge"))
+	return d.publishConfig(topic, config)
+}
+
+// PublishBridgeDatabaseSize publishes a diagnostic sensor on the bridge
+// device that shows the database size reported in the bridge statistics
+func (d *Discovery) PublishBridgeDatabaseSize(statsTopic string) error {
+	config := &DiscoveryConfig{
+		Name:                "Database Size",
+		UniqueID:            d.uniqueID("bridge_database_size"),
+		ObjectID:            d.objectID("bridge_database_size"),
+		Device:              d.bridgeDevice(),
+		StateTopic:          statsTopic,
+		ValueTemplate:       "{{ value_json.database_size }}",
+		UnitOfMeasurement:   "B",
+		DeviceClass:         "data_size",
+		StateClass:          "measurement",
+		AvailabilityTopic:   d.statusTopic(),
+		PayloadAvailable:    "online",
+		PayloadNotAvailable: "offline",
+		EntityCategory:      "diagnostic",
+	}
+
+	topic := fmt.Sprintf("%s/sensor/%s/database_size/config", d.discoveryPrefix, d.nodeID("bridge"))
+	return d.publishConfig(topic, config)
+}
+
+// PublishBridgeWriteLock publishes a switch on the bridge device that holds
+// back all SNMP SETs while on
+func (d *Discovery) PublishBridgeWriteLock() error {
+	config := &DiscoveryConfig{
+		Name:                "Write Lock",
+		UniqueID:            d.uniqueID("bridge_write_lock"),
+		ObjectID:            d.objectID("bridge_write_lock"),
+		Device:              d.bridgeDevice(),
+		StateTopic:          writeLockTopic(d.topicPrefix),
+		CommandTopic:        writeLockTopic(d.topicPrefix) + "/set",
+		AvailabilityTopic:   d.statusTopic(),
+		PayloadAvailable:    "online",
+		PayloadNotAvailable: "offline",
+		EntityCategory:      "config",
+		Icon:                "mdi:lock",
+	}
+
+	topic := fmt.Sprintf("%s/switch/%s/write_lock/config", d.discoveryPrefix, d.nodeID("bridge"))
+	return d.publishConfig(topic, config)
+}
+
+// PublishScene publishes a button that runs a scene
+func (d *Discovery) PublishScene(scene *domain.Scene) error {
+	entityID := sanitizeEntityID(scene.ID)
+
+	config := &DiscoveryConfig{
+		Name:                scene.Name,
+		UniqueID:            d.uniqueID("scene", entityID),
+		ObjectID:            d.objectID("scene", sanitizeEntityID(scene.Name)),
+		Device:              d.bridgeDevice(),
+		CommandTopic:        fmt.Sprintf("%s/%s/%s/set", d.topicPrefix, sceneTopicNode, scene.ID),
+		AvailabilityTopic:   d.statusTopic(),
+		PayloadAvailable:    "online",
+		PayloadNotAvailable: "offline",
+		Icon:                "mdi:play-box-multiple",
+		Extra:               map[string]interface{}{"payload_press": "PRESS"},
+	}
+
+	return d.publishConfig(d.sceneTopic(scene), config)
+}
+
+// RemoveScene removes the button of a scene
+func (d *Discovery) RemoveScene(scene *domain.Scene) error {
+	return d.removeConfig(d.sceneTopic(scene))
+}
+
+func (d *Discovery) sceneTopic(scene *domain.Scene) string {
+	return fmt.Sprintf("%s/%s/%s/%s/config",
+		d.discoveryPrefix,
+		componentToString(domain.HAComponentButton),
+		d.nodeID(sceneTopicNode),
+		sanitizeEntityID(scene.ID),
+	)
+}
+
+// actionEntityPrefix marks entity IDs that refer to profile actions
+const actionEntityPrefix = "action_"
+
+func actionEntityID(action domain.ProfileAction) string {
+	return actionEntityPrefix + action.Key()
+}
+
 // MarshalJSON customizes JSON marshaling to include extra fields
 func (c *DiscoveryConfig) MarshalJSON() ([]byte, error) {
 	type Alias DiscoveryConfig
@@ -0,0 +1,168 @@
+package mqtt
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// discoveryCheckWait is how long retained configs are collected after
+// subscribing to the discovery topics
+const discoveryCheckWait = 5 * time.Second
+
+// configRegistry remembers the retained discovery configs the bridge has
+// published, so configs a broker lost can be published again
+type configRegistry struct {
+	mu       sync.Mutex
+	configs  map[string]interface{}
+	checking bool
+}
+
+func (r *configRegistry) set(topic string, config interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.configs == nil {
+		r.configs = make(map[string]interface{})
+	}
+	r.configs[topic] = config
+}
+
+func (r *configRegistry) delete(topic string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.configs, topic)
+}
+
+// deleteNode forgets the configs under a discovery topic node, i.e. those of
+// <discovery prefix>/<component>/<node>/<object>/config
+func (r *configRegistry) deleteNode(node string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for topic := range r.configs {
+		parts := strings.Split(topic, "/")
+		if len(parts) >= 4 && parts[len(parts)-3] == node {
+			delete(r.configs, topic)
+		}
+	}
+}
+
+// node returns the topics of the configs under a discovery topic node
+func (r *configRegistry) node(node string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var topics []string
+	for topic := range r.configs {
+		parts := strings.Split(topic, "/")
+		if len(parts) >= 4 && parts[len(parts)-3] == node {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// missing returns the registered configs whose topic is not in seen
+func (r *configRegistry) missing(seen map[string]bool) map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	missing := make(map[string]interface{})
+	for topic, config := range r.configs {
+		if !seen[topic] {
+			missing[topic] = config
+		}
+	}
+	return missing
+}
+
+func (r *configRegistry) size() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.configs)
+}
+
+// begin marks a check as running; false if one already is
+func (r *configRegistry) begin() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.checking {
+		return false
+	}
+	r.checking = true
+	return true
+}
+
+func (r *configRegistry) end() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checking = false
+}
+
+// publishConfig publishes a retained discovery config and remembers it
+func (d *Discovery) publishConfig(topic string, config interface{}) error {
+	d.configs.set(topic, config)
+	return d.client.Publish(topic, config, true)
+}
+
+// removeConfig clears a retained discovery config and forgets it
+func (d *Discovery) removeConfig(topic string) error {
+	d.configs.delete(topic)
+	return d.client.Publish(topic, "", true)
+}
+
+// CheckConfigs republishes discovery configs the broker no longer retains,
+// e.g. after a restart of a broker without persistent storage. It subscribes
+// to the discovery topics, collects the retained configs delivered within
+// discoveryCheckWait and republishes every published config not among them.
+// Returns the number of configs republished.
+func (d *Discovery) CheckConfigs(ctx context.Context) (int, error) {
+	if d.configs.size() == 0 || !d.client.IsConnected() || !d.configs.begin() {
+		return 0, nil
+	}
+	defer d.configs.end()
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	filter := d.discoveryPrefix + "/+/+/+/config"
+	err := d.client.Subscribe(filter, func(_ mqtt.Client, msg mqtt.Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		// A config cleared while collecting counts as missing
+		if len(msg.Payload()) == 0 {
+			delete(seen, msg.Topic())
+		} else if msg.Retained() {
+			seen[msg.Topic()] = true
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(discoveryCheckWait):
+	}
+	d.client.Unsubscribe(filter)
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+
+	mu.Lock()
+	missing := d.configs.missing(seen)
+	mu.Unlock()
+
+	republished := 0
+	for topic, config := range missing {
+		if err := d.client.Publish(topic, config, true); err != nil {
+			log.Printf("Failed to republish discovery config %s: %v", topic, err)
+			continue
+		}
+		republished++
+	}
+	if republished > 0 {
+		log.Printf("Republished %d discovery configs missing from the broker", republished)
+	}
+	return republished, nil
+}
@@ -0,0 +1,85 @@
+package mqtt
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+)
+
+// ErrNotRegistered is returned for a device this bridge does not publish,
+// e.g. a disabled one or one another cluster node publishes
+var ErrNotRegistered = errors.New("device is not published over MQTT")
+
+// publishDiscovery publishes the discovery configs of a device and records
+// the outcome; a change of outcome is sent as a discovery event
+func (p *Publisher) publishDiscovery(info *deviceInfo) error {
+	device := info.device
+	status := &domain.DiscoveryStatus{DeviceID: device.ID, LastAttempt: time.Now()}
+
+	var err error
+	switch {
+	case info.profile == nil && device.ProfileID == "":
+		err = errors.New("no profile assigned, so there are no entities to publish")
+	case info.profile == nil:
+		err = fmt.Errorf("profile %s could not be loaded", device.ProfileID)
+	default:
+		if err = p.discovery.PublishDevice(device, info.profile); err != nil {
+			log.Printf("Failed to publish discovery for device %s: %v", device.ID, err)
+		}
+	}
+	if err != nil {
+		status.Error = err.Error()
+	} else {
+		status.Published = true
+	}
+
+	p.devicesMu.Lock()
+	previous := info.discovery
+	info.discovery = status
+	p.devicesMu.Unlock()
+
+	if previous == nil || previous.Published != status.Published || previous.Error != status.Error {
+		p.bus.Publish(eventbus.Event{Type: eventbus.TypeDiscovery, DeviceID: device.ID, Payload: status})
+	}
+	return err
+}
+
+// DiscoveryStatus returns the outcome of the last discovery publish of a
+// device; false if the device is not published by this bridge
+func (p *Publisher) DiscoveryStatus(deviceID string) (*domain.DiscoveryStatus, bool) {
+	p.devicesMu.RLock()
+	defer p.devicesMu.RUnlock()
+
+	info := p.devices[deviceID]
+	if info == nil {
+		return nil, false
+	}
+	if info.discovery == nil {
+		return &domain.DiscoveryStatus{DeviceID: deviceID}, true
+	}
+	status := *info.discovery
+	return &status, true
+}
+
+// RetryDiscovery publishes the discovery configs of a device again and
+// returns the outcome
+func (p *Publisher) RetryDiscovery(deviceID string) (*domain.DiscoveryStatus, error) {
+	p.devicesMu.RLock()
+	info := p.devices[deviceID]
+	p.devicesMu.RUnlock()
+
+	if info == nil {
+		return nil, ErrNotRegistered
+	}
+	if !p.client.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	p.publishDiscovery(info)
+	status, _ := p.DiscoveryStatus(deviceID)
+	return status, nil
+}
@@ -0,0 +1,207 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"sync"
+
+	"snmp-mqtt-bridge/internal/domain"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// domoticzUpdate is a device update sent to Domoticz
+type domoticzUpdate struct {
+	Idx    int    `json:"idx"`
+	NValue int    `json:"nvalue"`
+	SValue string `json:"svalue"`
+}
+
+// domoticzOut is the part of a device change published by Domoticz the
+// adapter reads
+type domoticzOut struct {
+	Idx     int    `json:"idx"`
+	NValue  int    `json:"nvalue"`
+	SValue1 string `json:"svalue1"`
+}
+
+// domoticzTarget is the mapping a Domoticz device idx is bound to
+type domoticzTarget struct {
+	deviceID string
+	mapping  *domain.OIDMapping
+}
+
+// DomoticzAdapter sends values to the Domoticz devices set in each device's
+// domoticz_idx, and turns changes Domoticz makes to writable ones into
+// commands. Switches and binary sensors set nvalue 1 (on) or 0 (off), other
+// values the svalue.
+type DomoticzAdapter struct {
+	client   *Client
+	inTopic  string
+	outTopic string
+
+	mu      sync.Mutex
+	handler CommandHandler
+	targets map[int]domoticzTarget
+	last    map[int]domoticzUpdate // Last update sent per idx, to ignore its echo on the out topic
+}
+
+// NewDomoticzAdapter creates the domoticz adapter
+func NewDomoticzAdapter(client *Client, inTopic, outTopic string) *DomoticzAdapter {
+	if inTopic == "" {
+		inTopic = "domoticz/in"
+	}
+	if outTopic == "" {
+		outTopic = "domoticz/out"
+	}
+	return &DomoticzAdapter{
+		client:   client,
+		inTopic:  inTopic,
+		outTopic: outTopic,
+		targets:  make(map[int]domoticzTarget),
+		last:     make(map[int]domoticzUpdate),
+	}
+}
+
+// Name returns the adapter name
+func (a *DomoticzAdapter) Name() string {
+	return AdapterDomoticz
+}
+
+// Start subscribes to the devices changes Domoticz publishes
+func (a *DomoticzAdapter) Start(handler CommandHandler) error {
+	a.mu.Lock()
+	a.handler = handler
+	a.mu.Unlock()
+
+	return a.client.Subscribe(a.outTopic, func(client mqtt.Client, msg mqtt.Message) {
+		var out domoticzOut
+		if err := json.Unmarshal(msg.Payload(), &out); err != nil || out.Idx == 0 {
+			return
+		}
+
+		a.mu.Lock()
+		target, ok := a.targets[out.Idx]
+		last, sent := a.last[out.Idx]
+		handler := a.handler
+		a.mu.Unlock()
+		if !ok || !target.mapping.Writable || handler == nil {
+			return
+		}
+
+		var payload string
+		if domoticzSwitch(target.mapping) {
+			if sent && out.NValue == last.NValue {
+				return
+			}
+			payload = "OFF"
+			if out.NValue != 0 {
+				payload = "ON"
+			}
+		} else {
+			if sent && out.SValue1 == last.SValue {
+				return
+			}
+			payload = out.SValue1
+		}
+		log.Printf("Domoticz changed idx %d, sending %s to %s/%s", out.Idx, payload, target.deviceID, target.mapping.Name)
+		handler(target.deviceID, sanitizeEntityID(target.mapping.Name), []byte(payload))
+	})
+}
+
+// domoticzSwitch reports whether a mapping is sent as an on/off nvalue
+func domoticzSwitch(mapping *domain.OIDMapping) bool {
+	return mapping.HAComponent == domain.HAComponentSwitch || mapping.HAComponent == domain.HAComponentBinarySensor
+}
+
+// PublishDevice binds the device's Domoticz indexes to its mappings; mapping
+// names without a mapping are logged and skipped
+func (a *DomoticzAdapter) PublishDevice(device *domain.Device, profile *domain.Profile) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.unbind(device.ID)
+	for name, idx := range device.DomoticzIdx {
+		var mapping *domain.OIDMapping
+		for i := range profile.OIDMappings {
+			if profile.OIDMappings[i].Name == name {
+				mapping = &profile.OIDMappings[i]
+				break
+			}
+		}
+		if mapping == nil {
+			log.Printf("Domoticz idx %d of device %s names unknown mapping %q", idx, device.Name, name)
+			continue
+		}
+		if other, ok := a.targets[idx]; ok {
+			log.Printf("Domoticz idx %d of device %s is already used by device %s", idx, device.Name, other.deviceID)
+			continue
+		}
+		a.targets[idx] = domoticzTarget{deviceID: device.ID, mapping: mapping}
+	}
+	return nil
+}
+
+// RemoveDevice unbinds the device's Domoticz indexes; the Domoticz devices
+// are left in place
+func (a *DomoticzAdapter) RemoveDevice(device *domain.Device, profile *domain.Profile) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.unbind(device.ID)
+	return nil
+}
+
+// unbind removes a device's indexes; a.mu must be held
+func (a *DomoticzAdapter) unbind(deviceID string) {
+	for idx, target := range a.targets {
+		if target.deviceID == deviceID {
+			delete(a.targets, idx)
+			delete(a.last, idx)
+		}
+	}
+}
+
+// PublishState sends the values of bound mappings to Domoticz. Nothing is
+// sent while the device does not answer polls, so Domoticz shows the
+// devices as not updated.
+func (a *DomoticzAdapter) PublishState(device *domain.Device, profile *domain.Profile, values map[string]interface{}, online bool) error {
+	if !online {
+		return nil
+	}
+
+	a.mu.Lock()
+	var updates []domoticzUpdate
+	for idx, target := range a.targets {
+		if target.deviceID != device.ID {
+			continue
+		}
+		value, ok := values[target.mapping.Name]
+		if !ok {
+			continue
+		}
+		update := domoticzUpdate{Idx: idx}
+		if domoticzSwitch(target.mapping) {
+			if adapterValue(value) == "ON" {
+				update.NValue = 1
+			}
+			update.SValue = strconv.Itoa(update.NValue)
+		} else {
+			update.SValue = adapterValue(value)
+		}
+		a.last[idx] = update
+		updates = append(updates, update)
+	}
+	a.mu.Unlock()
+
+	for _, update := range updates {
+		if err := a.client.Publish(a.inTopic, update, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PublishOffline does nothing: Domoticz devices have no availability
+func (a *DomoticzAdapter) PublishOffline(device *domain.Device) error {
+	return nil
+}
@@ -0,0 +1,85 @@
+package mqtt
+
+import (
+	"log"
+	"time"
+)
+
+// SetStateExpiry makes retained states of a device age out: once it has gone
+// this long without answering a poll, its retained entity states, and the
+// full state if retained, are cleared. 0 keeps them until replaced.
+//
+// The client speaks MQTT 3.1.1, which has no message expiry interval, so
+// the bridge clears the states itself; states left behind while the bridge
+// is down only age out after it restarts.
+func (p *Publisher) SetStateExpiry(expiry time.Duration) {
+	p.stateExpiry = expiry
+}
+
+// markFresh records a state update of a device and reports whether its
+// values should be published
+func (p *Publisher) markFresh(info *deviceInfo, online bool) bool {
+	p.devicesMu.Lock()
+	defer p.devicesMu.Unlock()
+	if online {
+		info.freshAt = time.Now()
+		info.expired = false
+	}
+	return !info.expired
+}
+
+func (p *Publisher) expireStatesLoop() {
+	defer p.wg.Done()
+
+	// Check often enough to clear states close to their expiry
+	interval := p.stateExpiry / 10
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			p.expireStates(time.Now())
+		}
+	}
+}
+
+// expireStates clears the retained states of devices without fresh values
+// for longer than the state expiry
+func (p *Publisher) expireStates(now time.Time) {
+	if !p.client.IsConnected() {
+		return
+	}
+
+	p.devicesMu.Lock()
+	var expired []*deviceInfo
+	for _, info := range p.devices {
+		if info.profile != nil && !info.expired && now.Sub(info.freshAt) > p.stateExpiry {
+			info.expired = true
+			expired = append(expired, info)
+		}
+	}
+	p.devicesMu.Unlock()
+
+	for _, info := range expired {
+		deviceID := info.device.ID
+		log.Printf("Clearing retained states of device %s: no fresh values for %s", deviceID, p.stateExpiry)
+		if p.client.cfg.Retain.EntityStatesRetained() {
+			for _, mapping := range info.profile.OIDMappings {
+				if err := p.client.ClearEntityState(deviceID, sanitizeEntityID(mapping.Name)); err != nil {
+					log.Printf("Failed to clear state for %s/%s: %v", deviceID, sanitizeEntityID(mapping.Name), err)
+				}
+			}
+		}
+		if p.client.cfg.Retain.DeviceStateRetained() {
+			if err := p.client.ClearDeviceState(deviceID); err != nil {
+				log.Printf("Failed to clear full state for %s: %v", deviceID, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package mqtt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"snmp-mqtt-bridge/internal/domain"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// homieNode is the single node all of a device's properties are published under
+const homieNode = "snmp"
+
+// Homie device states
+const (
+	homieReady        = "ready"
+	homieLost         = "lost" // The device does not answer polls
+	homieDisconnected = "disconnected"
+)
+
+// HomieAdapter publishes devices following the Homie 4 convention, which
+// openHAB's MQTT binding discovers as Things with one channel per mapping:
+//
+//	<base>/<device_id>/$homie, $name, $state, $nodes
+//	<base>/<device_id>/snmp/<property>          value
+//	<base>/<device_id>/snmp/<property>/set      commands for writable mappings
+type HomieAdapter struct {
+	client    *Client
+	baseTopic string
+
+	mu       sync.Mutex
+	handler  CommandHandler
+	entities map[string]map[string]string // Device ID -> property ID -> entity ID
+	states   map[string]string            // Last $state published per device
+}
+
+// NewHomieAdapter creates the openhab adapter publishing under baseTopic
+func NewHomieAdapter(client *Client, baseTopic string) *HomieAdapter {
+	if baseTopic == "" {
+		baseTopic = "homie"
+	}
+	return &HomieAdapter{
+		client:    client,
+		baseTopic: strings.TrimSuffix(baseTopic, "/"),
+		entities:  make(map[string]map[string]string),
+		states:    make(map[string]string),
+	}
+}
+
+// Name returns the adapter name
+func (a *HomieAdapter) Name() string {
+	return AdapterOpenHAB
+}
+
+// Start subscribes to the set topics of all devices
+func (a *HomieAdapter) Start(handler CommandHandler) error {
+	a.mu.Lock()
+	a.handler = handler
+	a.mu.Unlock()
+
+	topic := fmt.Sprintf("%s/+/%s/+/set", a.baseTopic, homieNode)
+	return a.client.Subscribe(topic, func(client mqtt.Client, msg mqtt.Message) {
+		// <base>/<device_id>/snmp/<property>/set
+		parts := strings.Split(strings.TrimPrefix(msg.Topic(), a.baseTopic+"/"), "/")
+		if len(parts) != 4 {
+			return
+		}
+		deviceID, propertyID := parts[0], parts[2]
+
+		a.mu.Lock()
+		entityID, ok := a.entities[deviceID][propertyID]
+		handler := a.handler
+		a.mu.Unlock()
+		if !ok || handler == nil {
+			return
+		}
+
+		payload := strings.TrimSpace(string(msg.Payload()))
+		switch strings.ToLower(payload) {
+		case "true":
+			payload = "ON"
+		case "false":
+			payload = "OFF"
+		}
+		handler(deviceID, entityID, []byte(payload))
+	})
+}
+
+// homieID converts a name to a Homie topic ID: lowercase letters, digits
+// and hyphens
+func homieID(name string) string {
+	return strings.Trim(strings.ReplaceAll(sanitizeEntityID(name), "_", "-"), "-")
+}
+
+// homieDatatype returns the Homie datatype of a mapping's published values
+func homieDatatype(mapping *domain.OIDMapping) string {
+	switch mapping.HAComponent {
+	case domain.HAComponentBinarySensor, domain.HAComponentSwitch:
+		return "boolean"
+	case domain.HAComponentSensor, domain.HAComponentNumber:
+		switch mapping.Type {
+		case domain.OIDTypeInteger, domain.OIDTypeGauge, domain.OIDTypeCounter:
+			if len(mapping.EnumValues) == 0 {
+				return "float"
+			}
+		}
+	}
+	return "string"
+}
+
+// homieMappings returns the mappings published as properties, by property ID
+func homieMappings(profile *domain.Profile) ([]string, map[string]*domain.OIDMapping) {
+	var ids []string
+	mappings := make(map[string]*domain.OIDMapping)
+	for i := range profile.OIDMappings {
+		mapping := &profile.OIDMappings[i]
+		id := homieID(mapping.Name)
+		if mapping.HAComponent == domain.HAComponentButton || id == "" || mappings[id] != nil {
+			continue
+		}
+		ids = append(ids, id)
+		mappings[id] = mapping
+	}
+	return ids, mappings
+}
+
+func (a *HomieAdapter) deviceTopic(deviceID string) string {
+	return a.baseTopic + "/" + deviceID
+}
+
+// PublishDevice publishes a device's Homie attributes and properties
+func (a *HomieAdapter) PublishDevice(device *domain.Device, profile *domain.Profile) error {
+	ids, mappings := homieMappings(profile)
+	entities := make(map[string]string, len(ids))
+	for id, mapping := range mappings {
+		entities[id] = sanitizeEntityID(mapping.Name)
+	}
+
+	a.mu.Lock()
+	a.entities[device.ID] = entities
+	state := a.states[device.ID]
+	a.mu.Unlock()
+	if state == "" {
+		state = homieReady
+	}
+
+	name := device.HAName
+	if name == "" {
+		name = device.Name
+	}
+
+	deviceTopic := a.deviceTopic(device.ID)
+	nodeTopic := deviceTopic + "/" + homieNode
+	attributes := [][2]string{
+		{deviceTopic + "/$homie", "4.0"},
+		{deviceTopic + "/$name", name},
+		{deviceTopic + "/$nodes", homieNode},
+		{nodeTopic + "/$name", profile.Name},
+		{nodeTopic + "/$type", "SNMP"},
+		{nodeTopic + "/$properties", strings.Join(ids, ",")},
+	}
+	for _, id := range ids {
+		mapping := mappings[id]
+		propertyTopic := nodeTopic + "/" + id
+		attributes = append(attributes,
+			[2]string{propertyTopic + "/$name", mapping.Name},
+			[2]string{propertyTopic + "/$datatype", homieDatatype(mapping)},
+		)
+		if unit := mapping.PublishedUnit(); unit != "" {
+			attributes = append(attributes, [2]string{propertyTopic + "/$unit", unit})
+		}
+		if mapping.Writable {
+			attributes = append(attributes, [2]string{propertyTopic + "/$settable", "true"})
+		}
+	}
+	attributes = append(attributes, [2]string{deviceTopic + "/$state", state})
+
+	for _, attr := range attributes {
+		if err := a.client.Publish(attr[0], attr[1], true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveDevice clears a device's retained Homie topics
+func (a *HomieAdapter) RemoveDevice(device *domain.Device, profile *domain.Profile) error {
+	a.mu.Lock()
+	delete(a.entities, device.ID)
+	delete(a.states, device.ID)
+	a.mu.Unlock()
+
+	deviceTopic := a.deviceTopic(device.ID)
+	nodeTopic := deviceTopic + "/" + homieNode
+	topics := []string{
+		deviceTopic + "/$homie", deviceTopic + "/$name", deviceTopic + "/$nodes", deviceTopic + "/$state",
+		nodeTopic + "/$name", nodeTopic + "/$type", nodeTopic + "/$properties",
+	}
+	ids, _ := homieMappings(profile)
+	for _, id := range ids {
+		propertyTopic := nodeTopic + "/" + id
+		topics = append(topics, propertyTopic, propertyTopic+"/$name", propertyTopic+"/$datatype",
+			propertyTopic+"/$unit", propertyTopic+"/$settable")
+	}
+
+	for _, topic := range topics {
+		if err := a.client.Publish(topic, "", true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PublishState publishes property values and the device state: ready while
+// the device answers polls, lost while it does not
+func (a *HomieAdapter) PublishState(device *domain.Device, profile *domain.Profile, values map[string]interface{}, online bool) error {
+	nodeTopic := a.deviceTopic(device.ID) + "/" + homieNode
+	ids, mappings := homieMappings(profile)
+	for _, id := range ids {
+		value, ok := values[mappings[id].Name]
+		if !ok {
+			continue
+		}
+		payload := adapterValue(value)
+		if homieDatatype(mappings[id]) == "boolean" {
+			payload = strconv.FormatBool(payload == "ON")
+		}
+		if err := a.client.Publish(nodeTopic+"/"+id, payload, true); err != nil {
+			return err
+		}
+	}
+
+	state := homieLost
+	if online {
+		state = homieReady
+	}
+	return a.publishDeviceState(device.ID, state)
+}
+
+// PublishOffline sets the device's state to disconnected
+func (a *HomieAdapter) PublishOffline(device *domain.Device) error {
+	return a.publishDeviceState(device.ID, homieDisconnected)
+}
+
+func (a *HomieAdapter) publishDeviceState(deviceID, state string) error {
+	a.mu.Lock()
+	changed := a.states[deviceID] != state
+	a.states[deviceID] = state
+	a.mu.Unlock()
+
+	if !changed {
+		return nil
+	}
+	return a.client.Publish(a.deviceTopic(deviceID)+"/$state", state, true)
+}
@@ -0,0 +1,68 @@
+package mqtt
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// lastErrorEntity is the diagnostic sensor showing a device's poll errors
+const lastErrorEntity = "last_error"
+
+// noPollError is the Last Error state while the device polls fine; Home
+// Assistant shows it as unknown
+const noPollError = "None"
+
+// maxStateLength is the longest state Home Assistant accepts
+const maxStateLength = 255
+
+// publishLastError publishes the errors of a device's latest poll to its
+// Last Error sensor when they changed, or always when forced
+func (p *Publisher) publishLastError(info *deviceInfo, errs []string, force bool) {
+	state := noPollError
+	if len(errs) > 0 {
+		state = strings.Join(errs, "; ")
+		if runes := []rune(state); len(runes) > maxStateLength {
+			state = string(runes[:maxStateLength-1]) + "…"
+		}
+	}
+
+	p.devicesMu.Lock()
+	changed := force || info.lastError != state
+	info.lastError = state
+	p.devicesMu.Unlock()
+
+	if !changed {
+		return
+	}
+	if err := p.client.PublishEntityState(info.device.ID, lastErrorEntity, state); err != nil {
+		log.Printf("Failed to publish last error for %s: %v", info.device.ID, err)
+	}
+}
+
+// publishLastErrorSensor publishes the Last Error sensor of a device. It only
+// follows the bridge status, not the device's availability, so the error
+// stays visible while the device is unavailable.
+func (d *Discovery) publishLastErrorSensor(deviceID string, haDevice *DiscoveryDevice, devicePrefix string) error {
+	config := &DiscoveryConfig{
+		Name:                "Last Error",
+		UniqueID:            d.uniqueID(deviceID, lastErrorEntity),
+		ObjectID:            fmt.Sprintf("%s_%s", devicePrefix, lastErrorEntity),
+		Device:              haDevice,
+		StateTopic:          fmt.Sprintf("%s/%s/%s/state", d.topicPrefix, deviceID, lastErrorEntity),
+		AvailabilityTopic:   d.statusTopic(),
+		PayloadAvailable:    "online",
+		PayloadNotAvailable: "offline",
+		EntityCategory:      "diagnostic",
+		Icon:                "mdi:alert-circle-outline",
+	}
+
+	if err := d.publishConfig(d.lastErrorTopic(deviceID), config); err != nil {
+		return fmt.Errorf("failed to publish discovery for Last Error: %w", err)
+	}
+	return nil
+}
+
+func (d *Discovery) lastErrorTopic(deviceID string) string {
+	return fmt.Sprintf("%s/sensor/%s/%s/config", d.discoveryPrefix, d.nodeID(deviceID), lastErrorEntity)
+}
@@ -0,0 +1,84 @@
+package mqtt
+
+import (
+	"log"
+
+	"snmp-mqtt-bridge/internal/domain"
+)
+
+// SetOwnership limits the publisher to the devices owns returns true for, so
+// each device of a sharded cluster is discovered and commanded through one
+// instance. A nil func publishes every enabled device.
+func (p *Publisher) SetOwnership(owns func(*domain.Device) bool) {
+	p.ownership = owns
+}
+
+func (p *Publisher) owns(device *domain.Device) bool {
+	return p.ownership == nil || p.ownership(device)
+}
+
+// ReleaseDevice stops publishing a device another instance took over: its
+// commands are unsubscribed, but its discovery configs, availability and
+// retained states are left to the new owner
+func (p *Publisher) ReleaseDevice(deviceID string) {
+	p.devicesMu.Lock()
+	_, ok := p.devices[deviceID]
+	delete(p.devices, deviceID)
+	p.devicesMu.Unlock()
+	if !ok {
+		return
+	}
+
+	p.discovery.ForgetDevice(deviceID)
+	p.client.UnsubscribeCommands(deviceID)
+}
+
+// ReleaseAll releases all registered devices, for a node whose devices the
+// other nodes have taken over. SyncDevices registers the devices owned again.
+func (p *Publisher) ReleaseAll() {
+	p.devicesMu.RLock()
+	ids := make([]string, 0, len(p.devices))
+	for id := range p.devices {
+		ids = append(ids, id)
+	}
+	p.devicesMu.RUnlock()
+
+	for _, id := range ids {
+		p.ReleaseDevice(id)
+	}
+}
+
+// SyncDevices brings the registered devices in line with the enabled devices:
+// owned devices missing since registration are registered, changed ones updated,
+// devices now owned elsewhere released and devices no longer enabled
+// unregistered. It catches up with changes made through another instance.
+func (p *Publisher) SyncDevices(devices []domain.Device) {
+	p.devicesMu.RLock()
+	registered := make(map[string]*domain.Device, len(p.devices))
+	for id, info := range p.devices {
+		registered[id] = info.device
+	}
+	p.devicesMu.RUnlock()
+
+	for i := range devices {
+		device := &devices[i]
+		current, ok := registered[device.ID]
+		delete(registered, device.ID)
+		switch {
+		case !p.owns(device):
+			if ok {
+				log.Printf("Releasing device %s (%s) to another instance", device.Name, device.ID)
+				p.ReleaseDevice(device.ID)
+			}
+		case !ok:
+			p.RegisterDevice(device)
+		case device.UpdatedAt.After(current.UpdatedAt):
+			p.UpdateDevice(device)
+		}
+	}
+
+	// What is left is registered but deleted or disabled
+	for id := range registered {
+		p.UnregisterDevice(id)
+	}
+}
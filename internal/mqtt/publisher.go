@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"snmp-mqtt-bridge/internal/actor"
 	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
 	"snmp-mqtt-bridge/internal/repository"
 	"snmp-mqtt-bridge/internal/service"
 
@@ -19,6 +22,23 @@ import (
 type deviceInfo struct {
 	device  *domain.Device
 	profile *domain.Profile
+	online  bool // Last availability published for the device
+
+	freshAt time.Time // Last state with fresh values, or registration
+	expired bool      // Retained states cleared after state expiry
+
+	translation *domain.Translation // Enum labels in the selected locale; nil for English
+
+	// Publish interval
+	publishedAt     time.Time
+	publishedValues map[string]interface{}
+	pending         *service.StateUpdateEvent // Latest state held back
+	flush           *time.Timer               // Publishes pending once the interval has passed
+
+	staged *stagedCommand // Command awaiting confirmation, for devices with confirm_writes
+
+	discovery *domain.DiscoveryStatus // Outcome of the last discovery publish; nil until attempted
+	lastError string                  // Last Error sensor state last published
 }
 
 // Publisher handles publishing device states to MQTT
@@ -27,10 +47,37 @@ type Publisher struct {
 	discovery   *Discovery
 	poller      *service.PollerService
 	profileRepo repository.ProfileRepository
+	bus         *eventbus.Bus
 	devices     map[string]*deviceInfo
 	devicesMu   sync.RWMutex
 	ctx         context.Context
 	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+
+	deviceAvailability bool
+	clearStates        bool
+	stateExpiry        time.Duration
+	confirmTimeout     time.Duration
+
+	discoveryCheckInterval time.Duration
+
+	statsInterval time.Duration
+	version       string
+	commandQueue  *service.CommandQueue // Sends commands received over MQTT
+	maintenance   *service.MaintenanceService // Reports the database size; nil when unavailable
+	startedAt     time.Time
+
+	adapters []Adapter // Output layouts besides HA discovery
+
+	lastUpdate *domain.UpdateStatus // Last release check, republished after reconnecting; guarded by devicesMu
+
+	resyncMu      sync.Mutex
+	resyncing     bool // A resync runs in the background
+	resyncPending bool // Another resync was requested while one ran
+
+	ownership func(*domain.Device) bool // Devices this instance publishes; nil publishes all
+
+	writeLock *service.WriteLockService // Read-only mode switch; nil never holds commands back
 }
 
 // NewPublisher creates a new MQTT publisher
@@ -38,39 +85,171 @@ func NewPublisher(
 	client *Client,
 	discovery *Discovery,
 	poller *service.PollerService,
+	commandQueue *service.CommandQueue,
 	profileRepo repository.ProfileRepository,
+	bus *eventbus.Bus,
 ) *Publisher {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Publisher{
-		client:      client,
-		discovery:   discovery,
-		poller:      poller,
-		profileRepo: profileRepo,
-		devices:     make(map[string]*deviceInfo),
-		ctx:         ctx,
-		cancel:      cancel,
+		client:       client,
+		discovery:    discovery,
+		poller:       poller,
+		commandQueue: commandQueue,
+		profileRepo:  profileRepo,
+		bus:          bus,
+		devices:      make(map[string]*deviceInfo),
+		ctx:          ctx,
+		cancel:       cancel,
+		startedAt:    time.Now(),
 	}
 }
 
+// SetDeviceAvailability controls whether each device's availability topic is
+// published, following whether the device answers polls, and set offline on
+// shutdown
+func (p *Publisher) SetDeviceAvailability(enabled bool) {
+	p.deviceAvailability = enabled
+}
+
+// SetClearStatesOnShutdown controls whether retained entity states are
+// removed on shutdown so stale values are not shown as current
+func (p *Publisher) SetClearStatesOnShutdown(enabled bool) {
+	p.clearStates = enabled
+}
+
+// SetDiscoveryCheckInterval sets how often retained discovery configs are
+// checked and missing ones republished; 0 checks only after reconnecting
+func (p *Publisher) SetDiscoveryCheckInterval(interval time.Duration) {
+	p.discoveryCheckInterval = interval
+}
+
 // Start starts the publisher
 func (p *Publisher) Start() error {
-	// Subscribe to poller events
-	eventChan := p.poller.Subscribe()
+	// Subscribe to state, trap, device lifecycle and connection events
+	sub := p.bus.Subscribe(
+		eventbus.TypeStateUpdate,
+		eventbus.TypeTrapReceived,
+		eventbus.TypeDeviceCreated,
+		eventbus.TypeDeviceUpdated,
+		eventbus.TypeDeviceDeleted,
+		eventbus.TypeProfileUpdated,
+		eventbus.TypeProfileDeleted,
+		eventbus.TypeMQTTStatus,
+		eventbus.TypeUpdateStatus,
+		eventbus.TypeWriteLock,
+		eventbus.TypeLocale,
+		eventbus.TypeUnits,
+	)
+
+	p.wg.Add(1)
+	go p.handleEvents(sub)
+
+	if p.discoveryCheckInterval > 0 {
+		p.wg.Add(1)
+		go p.checkDiscoveryLoop()
+	}
 
-	go p.handleEvents(eventChan)
+	if p.statsInterval > 0 {
+		p.wg.Add(1)
+		go p.publishStatsLoop()
+	}
+
+	if p.stateExpiry > 0 {
+		p.wg.Add(1)
+		go p.expireStatesLoop()
+	}
+
+	p.subscribeWriteLock()
+	p.subscribeStateRequests()
+	if p.client.IsConnected() {
+		p.startAdapters()
+		p.publishBridgeDiagnostics()
+		p.syncWriteLock()
+	}
 
 	log.Println("MQTT publisher started")
 	return nil
 }
 
-// Stop stops the publisher
+// Stop stops the publisher: it finishes the event being handled and stops
+// accepting commands. Discovery configs are left in place; if enabled, device
+// availability goes offline and retained entity states are cleared.
 func (p *Publisher) Stop() {
 	p.cancel()
+	p.wg.Wait()
+
+	if p.writeLock != nil && p.client.IsConnected() {
+		p.client.UnsubscribeCommands(bridgeTopicNode)
+	}
+	if p.client.IsConnected() {
+		p.client.UnsubscribeStateRequests()
+	}
+
+	p.devicesMu.RLock()
+	defer p.devicesMu.RUnlock()
+	if p.client.IsConnected() {
+		for deviceID, info := range p.devices {
+			p.client.UnsubscribeCommands(deviceID)
+			p.publishOffline(info)
+			for _, a := range p.adapters {
+				if err := a.PublishOffline(info.device); err != nil {
+					log.Printf("Failed to publish offline of %s to %s adapter: %v", deviceID, a.Name(), err)
+				}
+			}
+		}
+	}
+
+	log.Println("MQTT publisher stopped")
 }
 
 // RegisterDevice registers a device for MQTT publishing and discovery
 func (p *Publisher) RegisterDevice(device *domain.Device) error {
+	profile := p.loadProfile(device)
+
+	// Devices are available until a poll fails
+	online := true
+	if state := p.poller.GetDeviceState(device.ID); state != nil && !state.LastPoll.IsZero() {
+		online = state.Online
+	}
+
+	info := &deviceInfo{
+		device:      device,
+		profile:     profile,
+		online:      online,
+		freshAt:     time.Now(),
+		translation: profile.Translation(p.discovery.Locale()),
+	}
+	p.devicesMu.Lock()
+	p.devices[device.ID] = info
+	p.devicesMu.Unlock()
+
+	// Publish discovery config
+	if p.client.IsConnected() {
+		p.publishDiscovery(info)
+		p.publishAdapterDevice(info)
+	}
+	if confirmsCommands(device, profile) && p.client.IsConnected() {
+		p.publishPendingCommand(device.ID, noPendingCommand)
+	}
+
+	if p.deviceAvailability && p.client.IsConnected() {
+		if err := p.client.PublishDeviceAvailability(device.ID, online); err != nil {
+			log.Printf("Failed to publish availability for device %s: %v", device.ID, err)
+		}
+	}
+
+	// Subscribe to commands
+	if err := p.client.SubscribeCommands(device.ID, p.handleCommand); err != nil {
+		log.Printf("Failed to subscribe to commands for device %s: %v", device.ID, err)
+	}
+
+	return nil
+}
+
+// loadProfile returns the profile a device is published with; nil if it has
+// none or it cannot be loaded
+func (p *Publisher) loadProfile(device *domain.Device) *domain.Profile {
 	var profile *domain.Profile
 	if device.ProfileID != "" {
 		var err error
@@ -80,26 +259,100 @@ func (p *Publisher) RegisterDevice(device *domain.Device) error {
 		}
 	}
 
+	// Merge per-device mappings; threshold alarms become additional binary sensors
+	return profile.ForDevice(device)
+}
+
+// UpdateDevice publishes a changed device. A registered device's discovery
+// configs are republished in place and only those of entities it no longer
+// has removed, so Home Assistant keeps the entities; its availability and a
+// staged command carry over. A disabled device is unregistered and one not
+// registered yet registered.
+func (p *Publisher) UpdateDevice(device *domain.Device) error {
+	if !device.Enabled {
+		return p.UnregisterDevice(device.ID)
+	}
+	profile := p.loadProfile(device)
+
 	p.devicesMu.Lock()
-	p.devices[device.ID] = &deviceInfo{
-		device:  device,
-		profile: profile,
+	previous := p.devices[device.ID]
+	if previous == nil {
+		p.devicesMu.Unlock()
+		return p.RegisterDevice(device)
+	}
+	info := &deviceInfo{
+		device:      device,
+		profile:     profile,
+		online:      previous.online,
+		freshAt:     previous.freshAt,
+		expired:     previous.expired,
+		translation: profile.Translation(p.discovery.Locale()),
+		discovery:   previous.discovery,
+		lastError:   previous.lastError,
+	}
+	// The next state is published at once, for the new mappings
+	if previous.flush != nil {
+		previous.flush.Stop()
+		previous.flush = nil
+	}
+	staged := previous.staged
+	previous.staged = nil
+	if !confirmsCommands(device, profile) && staged != nil {
+		staged.timer.Stop()
+		staged = nil
 	}
+	info.staged = staged
+	p.devices[device.ID] = info
 	p.devicesMu.Unlock()
 
-	// Publish discovery config
-	if profile != nil && p.client.IsConnected() {
-		if err := p.discovery.PublishDevice(device, profile); err != nil {
-			log.Printf("Failed to publish discovery for device %s: %v", device.ID, err)
+	if !p.client.IsConnected() {
+		return nil
+	}
+	// Without a loadable profile the entities are kept until it is back
+	if profile != nil || device.ProfileID == "" {
+		if err := p.discovery.RemoveStaleConfigs(device, profile); err != nil {
+			log.Printf("Failed to remove discovery for device %s: %v", device.ID, err)
 		}
 	}
+	p.publishDiscovery(info)
+	p.publishAdapterDevice(info)
+	if confirmsCommands(device, profile) && staged == nil {
+		p.publishPendingCommand(device.ID, noPendingCommand)
+	}
+	return nil
+}
 
-	// Subscribe to commands
-	if err := p.client.SubscribeCommands(device.ID, p.handleCommand); err != nil {
-		log.Printf("Failed to subscribe to commands for device %s: %v", device.ID, err)
+// publishOffline marks a device unavailable and clears its retained entity
+// states, as configured, before the bridge goes down
+func (p *Publisher) publishOffline(info *deviceInfo) {
+	deviceID := info.device.ID
+	if p.deviceAvailability {
+		if err := p.client.PublishDeviceAvailability(deviceID, false); err != nil {
+			log.Printf("Failed to publish availability for device %s: %v", deviceID, err)
+		}
 	}
+	if p.clearStates && info.profile != nil {
+		for _, mapping := range info.profile.OIDMappings {
+			if err := p.client.ClearEntityState(deviceID, sanitizeEntityID(mapping.Name)); err != nil {
+				log.Printf("Failed to clear state for %s/%s: %v", deviceID, sanitizeEntityID(mapping.Name), err)
+			}
+		}
+	}
+}
 
-	return nil
+// publishAvailability publishes a device's availability when it changed
+func (p *Publisher) publishAvailability(info *deviceInfo, online bool) {
+	p.devicesMu.Lock()
+	changed := info.online != online
+	info.online = online
+	p.devicesMu.Unlock()
+
+	if !changed {
+		return
+	}
+	if err := p.client.PublishDeviceAvailability(info.device.ID, online); err != nil {
+		log.Printf("Failed to publish availability for device %s: %v", info.device.ID, err)
+	}
 }
 
 // UnregisterDevice removes a device from MQTT publishing
@@ -115,6 +368,17 @@ func (p *Publisher) UnregisterDevice(deviceID string) error {
 			log.Printf("Failed to remove discovery for device %s: %v", deviceID, err)
 		}
 	}
+	if info != nil && confirmsCommands(info.device, info.profile) {
+		p.dropStaged(info)
+		if p.client.IsConnected() {
+			if err := p.discovery.RemoveConfirmEntities(deviceID); err != nil {
+				log.Printf("Failed to remove discovery for device %s: %v", deviceID, err)
+			}
+		}
+	}
+	if info != nil && p.client.IsConnected() {
+		p.removeAdapterDevice(info)
+	}
 
 	// Unsubscribe from commands
 	p.client.UnsubscribeCommands(deviceID)
@@ -122,21 +386,264 @@ func (p *Publisher) UnregisterDevice(deviceID string) error {
 	return nil
 }
 
-func (p *Publisher) handleEvents(eventChan chan service.StateUpdateEvent) {
+// SetLocale selects the locale entity names and enum labels are published in.
+// Discovery configs and states are republished with the next resync.
+func (p *Publisher) SetLocale(locale string) {
+	p.discovery.SetLocale(locale)
+
+	p.devicesMu.Lock()
+	defer p.devicesMu.Unlock()
+	for _, info := range p.devices {
+		info.translation = info.profile.Translation(locale)
+	}
+}
+
+// translation returns the translation of a device's profile in the selected locale
+func (p *Publisher) translation(info *deviceInfo) *domain.Translation {
+	p.devicesMu.RLock()
+	defer p.devicesMu.RUnlock()
+	return info.translation
+}
+
+// RefreshProfile republishes the devices using a profile: discovery configs of
+// removed mappings are removed and those of the current ones published
+func (p *Publisher) RefreshProfile(profileID string) {
+	p.devicesMu.RLock()
+	var devices []*domain.Device
+	for _, info := range p.devices {
+		if info.device.ProfileID == profileID {
+			devices = append(devices, info.device)
+		}
+	}
+	p.devicesMu.RUnlock()
+
+	for _, device := range devices {
+		p.UpdateDevice(device)
+	}
+}
+
+func (p *Publisher) handleEvents(sub *eventbus.Subscription) {
+	defer p.wg.Done()
+	defer p.bus.Unsubscribe(sub)
+
 	for {
 		select {
 		case <-p.ctx.Done():
 			return
-		case event, ok := <-eventChan:
+		case evt, ok := <-sub.C:
 			if !ok {
 				return
 			}
-			p.publishState(event)
+			p.handleEvent(evt)
 		}
 	}
 }
 
-func (p *Publisher) publishState(event service.StateUpdateEvent) {
+func (p *Publisher) handleEvent(evt eventbus.Event) {
+	switch evt.Type {
+	case eventbus.TypeStateUpdate:
+		if state, ok := evt.Payload.(service.StateUpdateEvent); ok {
+			p.publishState(state, false)
+		}
+
+	case eventbus.TypeTrapReceived:
+		if trapLog, ok := evt.Payload.(*domain.TrapLog); ok && p.client.IsConnected() {
+			if err := p.client.PublishTrap(trapLog); err != nil {
+				log.Printf("Failed to publish trap %s: %v", trapLog.ID, err)
+			}
+		}
+
+	case eventbus.TypeDeviceCreated:
+		if device, ok := evt.DevicePayload(); ok && device.Enabled && p.owns(device) {
+			p.RegisterDevice(device)
+		}
+
+	case eventbus.TypeDeviceUpdated:
+		if device, ok := evt.DevicePayload(); ok {
+			if !p.owns(device) {
+				p.ReleaseDevice(device.ID)
+				break
+			}
+			p.UpdateDevice(device)
+		}
+
+	case eventbus.TypeDeviceDeleted:
+		p.UnregisterDevice(evt.DeviceID)
+
+	case eventbus.TypeProfileUpdated, eventbus.TypeProfileDeleted:
+		if profile, ok := evt.ProfilePayload(); ok {
+			p.RefreshProfile(profile.ID)
+		}
+
+	case eventbus.TypeUpdateStatus:
+		if status, ok := evt.Payload.(*domain.UpdateStatus); ok {
+			p.devicesMu.Lock()
+			p.lastUpdate = status
+			p.devicesMu.Unlock()
+			p.publishUpdate(status)
+		}
+
+	case eventbus.TypeWriteLock:
+		if lock, ok := evt.Payload.(*domain.WriteLock); ok {
+			p.publishWriteLock(lock)
+		}
+
+	case eventbus.TypeLocale:
+		if locale, ok := evt.Payload.(string); ok {
+			p.SetLocale(locale)
+			p.requestResync()
+		}
+
+	case eventbus.TypeUnits:
+		p.refreshUnits()
+
+	case eventbus.TypeMQTTStatus:
+		if status, ok := evt.Payload.(eventbus.MQTTStatus); ok && status.Connected {
+			p.startAdapters()
+			p.requestResync()
+		}
+	}
+}
+
+// requestResync runs a resync in the background, so commands and state
+// updates keep flowing while a large fleet is republished. A request made
+// while one runs queues a single follow-up, as the change behind it may
+// have come after some devices were already republished.
+func (p *Publisher) requestResync() {
+	p.resyncMu.Lock()
+	defer p.resyncMu.Unlock()
+
+	if p.resyncing {
+		p.resyncPending = true
+		return
+	}
+	p.resyncing = true
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for {
+			p.resync()
+
+			p.resyncMu.Lock()
+			if !p.resyncPending || p.ctx.Err() != nil {
+				p.resyncing = false
+				p.resyncMu.Unlock()
+				return
+			}
+			p.resyncPending = false
+			p.resyncMu.Unlock()
+		}
+	}()
+}
+
+// resync republishes the discovery configs, availability and latest states of
+// every device after (re)connecting: retained messages may have been lost with
+// a broker restart, and devices registered while the broker was unreachable
+// never had their discovery published
+func (p *Publisher) resync() {
+	p.devicesMu.RLock()
+	infos := make([]*deviceInfo, 0, len(p.devices))
+	for _, info := range p.devices {
+		infos = append(infos, info)
+	}
+	p.devicesMu.RUnlock()
+
+	for _, info := range infos {
+		if p.ctx.Err() != nil || !p.client.IsConnected() {
+			return
+		}
+		p.publishDiscovery(info)
+		p.publishAdapterDevice(info)
+		p.republishState(info)
+	}
+
+	p.devicesMu.RLock()
+	lastUpdate := p.lastUpdate
+	p.devicesMu.RUnlock()
+	if lastUpdate != nil {
+		p.publishUpdate(lastUpdate)
+	}
+	p.publishBridgeDiagnostics()
+	p.syncWriteLock()
+	log.Printf("MQTT resync: republished discovery, availability and state of %d device(s)", len(infos))
+}
+
+// republishState publishes a device's availability and the values of its
+// last poll again, regardless of the publish interval
+func (p *Publisher) republishState(info *deviceInfo) {
+	deviceID := info.device.ID
+
+	if p.deviceAvailability {
+		p.devicesMu.RLock()
+		online := info.online
+		p.devicesMu.RUnlock()
+		if err := p.client.PublishDeviceAvailability(deviceID, online); err != nil {
+			log.Printf("Failed to publish availability for device %s: %v", deviceID, err)
+		}
+	}
+
+	// Entity states are retained too; republish the last poll's values
+	if state := p.poller.GetDeviceState(deviceID); state != nil && !state.LastPoll.IsZero() {
+		p.publishState(service.StateUpdateEvent{
+			DeviceID:  deviceID,
+			Timestamp: state.LastPoll,
+			Values:    p.poller.GetDeviceValues(deviceID),
+			Stale:     state.Stale,
+			Online:    state.Online,
+			Errors:    state.Errors,
+		}, true)
+	}
+}
+
+// refreshUnits republishes discovery with the units values are now published
+// in and polls every device, as polled values are converted when polled
+func (p *Publisher) refreshUnits() {
+	p.devicesMu.RLock()
+	infos := make([]*deviceInfo, 0, len(p.devices))
+	for _, info := range p.devices {
+		infos = append(infos, info)
+	}
+	p.devicesMu.RUnlock()
+
+	for _, info := range infos {
+		if info.profile != nil && p.client.IsConnected() {
+			p.publishDiscovery(info)
+			p.publishAdapterDevice(info)
+		}
+		p.poller.TriggerPoll(info.device.ID)
+	}
+}
+
+// checkDiscoveryLoop periodically republishes discovery configs the broker lost
+func (p *Publisher) checkDiscoveryLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.discoveryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			p.checkDiscovery()
+		}
+	}
+}
+
+func (p *Publisher) checkDiscovery() {
+	if _, err := p.discovery.CheckConfigs(p.ctx); err != nil && p.ctx.Err() == nil {
+		log.Printf("Failed to check discovery configs: %v", err)
+	}
+}
+
+// staleState is the entity state published for a stale value
+const staleState = "None"
+
+// publishState publishes a device's state; force skips the device's publish
+// interval, e.g. to restore retained states after a reconnect
+func (p *Publisher) publishState(event service.StateUpdateEvent, force bool) {
 	if !p.client.IsConnected() {
 		return
 	}
@@ -145,15 +652,32 @@ func (p *Publisher) publishState(event service.StateUpdateEvent) {
 	info := p.devices[event.DeviceID]
 	p.devicesMu.RUnlock()
 
-	if info == nil || info.profile == nil {
+	if info == nil {
+		return
+	}
+
+	// Entities go unavailable while the device does not answer polls
+	if p.deviceAvailability {
+		p.publishAvailability(info, event.Online)
+	}
+
+	if info.profile == nil {
+		return
+	}
+	p.publishLastError(info, event.Errors, force)
+
+	// Polls of an offline device repeat its last values; once expired they
+	// stay cleared until the device answers again
+	if !p.markFresh(info, event.Online) {
+		return
+	}
+	if !p.publishDue(info, event, force) {
 		return
 	}
 
 	device := info.device
 	profile := info.profile
-
-	// Calculate Active Power if it's 0 or missing (P = V × I)
-	p.calculatePowerIfNeeded(event.Values)
+	translation := p.translation(info)
 
 	// Check for source names to update select options
 	sourceAName, hasSourceA := event.Values["Source A Name"]
@@ -163,8 +687,22 @@ func (p *Publisher) publishState(event service.StateUpdateEvent) {
 			fmt.Sprintf("%v", sourceAName), fmt.Sprintf("%v", sourceBName))
 	}
 
+	stale := make(map[string]bool, len(event.Stale))
+	for _, name := range event.Stale {
+		stale[name] = true
+	}
+
 	// Publish individual entity states
+	published := make(map[string]interface{}, len(profile.OIDMappings))
 	for _, mapping := range profile.OIDMappings {
+		// Home Assistant shows entities whose state is None as unknown
+		if stale[mapping.Name] {
+			if err := p.client.PublishEntityState(event.DeviceID, sanitizeEntityID(mapping.Name), staleState); err != nil {
+				log.Printf("Failed to publish stale state for %s/%s: %v", event.DeviceID, mapping.Name, err)
+			}
+			continue
+		}
+
 		value, exists := event.Values[mapping.Name]
 		if !exists {
 			// Try by OID
@@ -179,7 +717,7 @@ func (p *Publisher) publishState(event service.StateUpdateEvent) {
 			if mapping.HAComponent == domain.HAComponentBinarySensor {
 				publishValue = convertToBinarySensorValue(value, mapping.DeviceClass)
 			} else if mapping.HAComponent == domain.HAComponentSwitch {
-				publishValue = convertToSwitchValue(value)
+				publishValue = switchState(value, &mapping)
 			}
 
 			// For select entities showing "Selected Source" or "Preferred Source",
@@ -196,11 +734,18 @@ func (p *Publisher) publishState(event service.StateUpdateEvent) {
 				}
 			}
 
+			// Enum labels are shown in the selected locale
+			if label, ok := publishValue.(string); ok && mapping.EnumValues != nil {
+				publishValue = translation.Label(label)
+			}
+
+			published[mapping.Name] = publishValue
 			if err := p.client.PublishEntityState(event.DeviceID, entityID, publishValue); err != nil {
 				log.Printf("Failed to publish state for %s/%s: %v", event.DeviceID, entityID, err)
 			}
 		}
 	}
+	p.publishAdapterState(info, published, event.Online)
 
 	// Publish full state
 	state := &domain.DeviceState{
@@ -208,9 +753,13 @@ func (p *Publisher) publishState(event service.StateUpdateEvent) {
 		Online:   event.Online,
 		LastPoll: event.Timestamp,
 		Values:   event.Values,
+		Stale:    event.Stale,
+	}
+	if attrs := device.AssetAttributes(); len(attrs) > 0 {
+		state.Attributes = attrs
 	}
 
-	if err := p.client.PublishState(event.DeviceID, state); err != nil {
+	if err := p.client.PublishState(event.DeviceID, state, profile); err != nil {
 		log.Printf("Failed to publish full state for %s: %v", event.DeviceID, err)
 	}
 }
@@ -227,9 +776,62 @@ func (p *Publisher) handleCommand(deviceID, entityID string, payload []byte) {
 		log.Printf("Device or profile not found for %s", deviceID)
 		return
 	}
+	if !p.owns(info.device) {
+		log.Printf("Ignoring command for %s/%s: another node owns the device", deviceID, entityID)
+		return
+	}
+
+	if (entityID == confirmEntity || entityID == cancelEntity) && confirmsCommands(info.device, info.profile) {
+		p.resolveStaged(info, entityID)
+		return
+	}
+
+	cmd, err := p.prepareCommand(info, entityID, payload)
+	if err != nil {
+		log.Printf("Ignoring command for %s/%s: %v", deviceID, entityID, err)
+		return
+	}
+
+	// Sensitive devices and disruptive actions only get commands confirmed
+	// in Home Assistant
+	if info.device.ConfirmWrites || (cmd.action != nil && cmd.action.Confirm) {
+		p.stageCommand(info, cmd)
+		return
+	}
+	p.executeCommand(info, cmd)
+}
 
+// preparedCommand is a command received over MQTT, validated and converted to
+// the SNMP SET it makes
+type preparedCommand struct {
+	entityID string
+	payload  string                // Payload as received, trimmed
+	mapping  *domain.OIDMapping    // Entity written; nil for an action
+	action   *domain.ProfileAction // Action run; nil for an entity
+	oid      string
+	value    interface{}
+	pduType  domain.PDUType
+}
+
+// prepareCommand looks up the entity or action of a command and converts its
+// payload; an error means the command cannot be sent
+func (p *Publisher) prepareCommand(info *deviceInfo, entityID string, payload []byte) (*preparedCommand, error) {
 	device := info.device
 	profile := info.profile
+	cmd := &preparedCommand{entityID: entityID, payload: strings.TrimSpace(string(payload))}
+
+	// Button presses for profile actions
+	if strings.HasPrefix(entityID, actionEntityPrefix) {
+		action, ok := profile.Action(strings.TrimPrefix(entityID, actionEntityPrefix))
+		if !ok {
+			return nil, fmt.Errorf("action not found for entity %s", entityID)
+		}
+		cmd.action = action
+		cmd.oid = action.OID
+		cmd.value = action.Value
+		cmd.pduType = action.Type
+		return cmd, nil
+	}
 
 	// Find the mapping for this entity
 	var mapping *domain.OIDMapping
@@ -242,75 +844,109 @@ func (p *Publisher) handleCommand(deviceID, entityID string, payload []byte) {
 	}
 
 	if mapping == nil {
-		log.Printf("Mapping not found for entity %s", entityID)
-		return
+		return nil, fmt.Errorf("mapping not found for entity %s", entityID)
 	}
 
 	if !mapping.Writable {
-		log.Printf("Mapping %s is not writable", mapping.Name)
-		return
+		return nil, fmt.Errorf("mapping %s is not writable", mapping.Name)
 	}
+	cmd.mapping = mapping
+	cmd.pduType = mapping.WriteType
 
 	// Determine the OID to write to
-	writeOID := mapping.WriteOID
-	if writeOID == "" {
-		writeOID = mapping.OID
+	cmd.oid = mapping.WriteOID
+	if cmd.oid == "" {
+		cmd.oid = mapping.OID
+	}
+
+	// Select options may be localized; the conversions take the profile's labels
+	value := cmd.payload
+	if mapping.EnumValues != nil {
+		value = p.translation(info).Unlabel(cmd.payload)
 	}
 
 	// Convert payload to SNMP value
-	payloadStr := strings.TrimSpace(string(payload))
-	var snmpValue interface{}
 	var err error
-
-	// Handle composite_switch type (Energenie-style comma-separated outlet status)
-	if mapping.Type == domain.OIDTypeCompositeSwitch {
-		snmpValue, err = p.convertCompositePayloadToSNMPValue(device, payloadStr, mapping)
+	// Templates encode payloads the built-in conversions cannot
+	if mapping.CommandTemplate != "" {
+		cmd.value, err = p.renderCommandTemplate(device, value, mapping)
 		if err != nil {
-			log.Printf("Failed to convert composite payload: %v", err)
-			return
+			return nil, fmt.Errorf("failed to convert payload for %s: %w", mapping.Name, err)
+		}
+	} else if compositeOID := mapping.CompositeWriteOID(); compositeOID != "" {
+		cmd.oid = compositeOID
+		// Element values are enum keys, so always integers
+		cmd.value, _ = strconv.Atoi(compositeElementValue(value, mapping))
+	} else if mapping.Type == domain.OIDTypeCompositeSwitch {
+		// Handle composite_switch type (Energenie-style comma-separated outlet status)
+		cmd.value, err = p.convertCompositePayloadToSNMPValue(device, value, mapping)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert composite payload: %w", err)
 		}
 	} else {
-		snmpValue, err = p.convertPayloadToSNMPValue(payloadStr, mapping)
+		cmd.value, err = p.convertPayloadToSNMPValue(value, mapping)
 		if err != nil {
-			log.Printf("Failed to convert payload: %v", err)
-			return
+			return nil, fmt.Errorf("failed to convert payload: %w", err)
 		}
 	}
+	return cmd, nil
+}
 
-	// Send SNMP SET command
-	if err := p.sendSNMPSet(device, writeOID, snmpValue); err != nil {
-		log.Printf("Failed to send SNMP SET: %v", err)
-		return
+// executeCommand sends a prepared command to the device through the command
+// queue, which checks the write lock and the cooldown and reports the command
+func (p *Publisher) executeCommand(info *deviceInfo, cmd *preparedCommand) {
+	device := info.device
+	queued := service.QueuedCommand{
+		Description: cmd.entityID,
+		OID:         cmd.oid,
+		Value:       cmd.value,
+		Type:        cmd.pduType,
+		Entity:      service.CooldownEntity(cmd.oid, cmd.mapping),
+		EntityID:    cmd.entityID,
+		Source:      eventbus.CommandSourceMQTT,
 	}
 
-	log.Printf("SNMP SET successful for %s/%s: %s -> %v", deviceID, entityID, payloadStr, snmpValue)
+	// Optimistic entities show the commanded state before the SET is
+	// confirmed; the poll after the SET reverts it if it was not sent
+	if cmd.mapping != nil && cmd.mapping.Optimistic {
+		p.echoCommandState(device.ID, cmd.entityID, cmd.payload, cmd.mapping)
+	}
+
+	err := p.commandQueue.Send(actor.WithName(p.ctx, domain.AuditSourceMQTT), device.ID, queued)
+	switch {
+	case err != nil && cmd.action != nil:
+		log.Printf("Failed to run action %s on %s: %v", cmd.action.Name, device.Name, err)
+	case err != nil:
+		log.Printf("Failed to send SNMP SET: %v", err)
+	case cmd.action != nil:
+		log.Printf("Action %s executed on %s", cmd.action.Name, device.Name)
+	default:
+		log.Printf("SNMP SET successful for %s/%s: %s -> %v", device.ID, cmd.entityID, cmd.payload, cmd.value)
+	}
+}
 
-	// Trigger immediate poll to confirm state change
-	p.poller.TriggerPoll(deviceID)
+// echoCommandState publishes a commanded value as the entity's state
+func (p *Publisher) echoCommandState(deviceID, entityID, payload string, mapping *domain.OIDMapping) {
+	var state interface{} = payload
+	if mapping.HAComponent == domain.HAComponentSwitch {
+		state = convertToSwitchValue(payload)
+	}
+	if err := p.client.PublishEntityState(deviceID, entityID, state); err != nil {
+		log.Printf("Failed to publish optimistic state for %s/%s: %v", deviceID, entityID, err)
+	}
 }
 
 // convertPayloadToSNMPValue converts MQTT payload to appropriate SNMP value
 func (p *Publisher) convertPayloadToSNMPValue(payload string, mapping *domain.OIDMapping) (interface{}, error) {
 	payloadUpper := strings.ToUpper(payload)
 
-	// For switches (ON/OFF -> integer)
+	// For switches (ON/OFF -> integer), from on_value/off_value or enum_values;
+	// there is no default, as devices disagree on what OFF is
 	if mapping.HAComponent == domain.HAComponentSwitch {
-		// Check enum_values to find the correct integer value
-		if mapping.EnumValues != nil {
-			for k, v := range mapping.EnumValues {
-				if strings.EqualFold(v, "On") && payloadUpper == "ON" {
-					return k, nil
-				}
-				if strings.EqualFold(v, "Off") && payloadUpper == "OFF" {
-					return k, nil
-				}
-			}
-		}
-		// Default: ON=1, OFF=2 (common for APC PDUs)
-		if payloadUpper == "ON" {
-			return 1, nil
+		if value, ok := mapping.SwitchValue(payloadUpper == "ON"); ok {
+			return value, nil
 		}
-		return 2, nil
+		return nil, fmt.Errorf("switch %s has no on_value/off_value or On/Off enum_values", mapping.Name)
 	}
 
 	// For select entities, find the enum value
@@ -325,8 +961,15 @@ func (p *Publisher) convertPayloadToSNMPValue(payload string, mapping *domain.OI
 		return nil, fmt.Errorf("unknown select value: %s", payload)
 	}
 
-	// For numbers, return as integer
+	// For numbers, return as integer, or as a float for float writes
 	if mapping.HAComponent == domain.HAComponentNumber {
+		if mapping.WriteType == domain.PDUTypeFloat || mapping.WriteType == domain.PDUTypeDouble {
+			val, err := strconv.ParseFloat(strings.TrimSpace(payload), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number: %s", payload)
+			}
+			return val, nil
+		}
 		var val int
 		if _, err := fmt.Sscanf(payload, "%d", &val); err != nil {
 			return nil, fmt.Errorf("invalid number: %s", payload)
@@ -338,32 +981,24 @@ func (p *Publisher) convertPayloadToSNMPValue(payload string, mapping *domain.OI
 	return payload, nil
 }
 
-// convertCompositePayloadToSNMPValue handles composite_switch type - modifies specific index in comma-separated string
-func (p *Publisher) convertCompositePayloadToSNMPValue(device *domain.Device, payload string, mapping *domain.OIDMapping) (string, error) {
-	payloadUpper := strings.ToUpper(payload)
+// compositeElementValue returns the value of one composite element for an ON/OFF payload
+func compositeElementValue(payload string, mapping *domain.OIDMapping) string {
+	on := strings.ToUpper(payload) == "ON"
 
 	// Determine the value to set at the index
-	var newValue string
-	if mapping.EnumValues != nil {
-		for k, v := range mapping.EnumValues {
-			if strings.EqualFold(v, "On") && payloadUpper == "ON" {
-				newValue = fmt.Sprintf("%d", k)
-				break
-			}
-			if strings.EqualFold(v, "Off") && payloadUpper == "OFF" {
-				newValue = fmt.Sprintf("%d", k)
-				break
-			}
-		}
+	if value, ok := mapping.SwitchValue(on); ok {
+		return strconv.Itoa(value)
 	}
-	if newValue == "" {
-		// Default: ON=1, OFF=0 (Energenie style)
-		if payloadUpper == "ON" {
-			newValue = "1"
-		} else {
-			newValue = "0"
-		}
+	// Default: ON=1, OFF=0 (Energenie style)
+	if on {
+		return "1"
 	}
+	return "0"
+}
+
+// convertCompositePayloadToSNMPValue handles composite_switch type - modifies specific index in comma-separated string
+func (p *Publisher) convertCompositePayloadToSNMPValue(device *domain.Device, payload string, mapping *domain.OIDMapping) (string, error) {
+	newValue := compositeElementValue(payload, mapping)
 
 	// Read current value from device
 	currentValue, err := p.readSNMPValue(device, mapping.OID)
@@ -394,12 +1029,11 @@ func (p *Publisher) convertCompositePayloadToSNMPValue(device *domain.Device, pa
 
 // readSNMPValue reads a single OID value from the device
 func (p *Publisher) readSNMPValue(device *domain.Device, oid string) (interface{}, error) {
-	client := p.createSNMPClient(device)
-
-	if err := client.Connect(); err != nil {
+	client, err := service.OpenSNMP(device, device.ReadCommunity(), service.SNMPConnGet)
+	if err != nil {
 		return nil, fmt.Errorf("failed to connect: %w", err)
 	}
-	defer client.Conn.Close()
+	defer client.Close()
 
 	result, err := client.Get([]string{oid})
 	if err != nil {
@@ -421,80 +1055,6 @@ func (p *Publisher) readSNMPValue(device *domain.Device, oid string) (interface{
 	}
 }
 
-// sendSNMPSet sends an SNMP SET command to the device
-func (p *Publisher) sendSNMPSet(device *domain.Device, oid string, value interface{}) error {
-	client := p.createSNMPClientForWrite(device)
-
-	if err := client.Connect(); err != nil {
-		return fmt.Errorf("failed to connect: %w", err)
-	}
-	defer client.Conn.Close()
-
-	// Determine PDU type based on value type
-	var pdu gosnmp.SnmpPDU
-	pdu.Name = oid
-
-	switch v := value.(type) {
-	case int:
-		pdu.Type = gosnmp.Integer
-		pdu.Value = v
-	case string:
-		pdu.Type = gosnmp.OctetString
-		pdu.Value = v
-	default:
-		return fmt.Errorf("unsupported value type: %T", value)
-	}
-
-	_, err := client.Set([]gosnmp.SnmpPDU{pdu})
-	if err != nil {
-		return fmt.Errorf("SNMP SET failed: %w", err)
-	}
-
-	return nil
-}
-
-// createSNMPClient creates an SNMP client for the device (for reading)
-func (p *Publisher) createSNMPClient(device *domain.Device) *gosnmp.GoSNMP {
-	return p.createSNMPClientWithCommunity(device, device.Community)
-}
-
-// createSNMPClientForWrite creates an SNMP client for SET operations, using write community if available
-func (p *Publisher) createSNMPClientForWrite(device *domain.Device) *gosnmp.GoSNMP {
-	community := device.Community
-	if device.WriteCommunity != "" {
-		community = device.WriteCommunity
-	}
-	return p.createSNMPClientWithCommunity(device, community)
-}
-
-// createSNMPClientWithCommunity creates an SNMP client with specified community
-func (p *Publisher) createSNMPClientWithCommunity(device *domain.Device, community string) *gosnmp.GoSNMP {
-	client := &gosnmp.GoSNMP{
-		Target:  device.IPAddress,
-		Port:    uint16(device.Port),
-		Timeout: time.Second * 5,
-		Retries: 2,
-	}
-
-	switch device.SNMPVersion {
-	case domain.SNMPv1:
-		client.Version = gosnmp.Version1
-		client.Community = community
-	case domain.SNMPv3:
-		client.Version = gosnmp.Version3
-		client.SecurityModel = gosnmp.UserSecurityModel
-		client.MsgFlags = gosnmp.NoAuthNoPriv
-		client.SecurityParameters = &gosnmp.UsmSecurityParameters{
-			UserName: community,
-		}
-	default:
-		client.Version = gosnmp.Version2c
-		client.Community = community
-	}
-
-	return client
-}
-
 // updateSelectOptionsWithSourceNames updates the discovery config for select entities
 // to use actual source names instead of generic "Source A"/"Source B"
 func (p *Publisher) updateSelectOptionsWithSourceNames(device *domain.Device, profile *domain.Profile, sourceAName, sourceBName string) {
@@ -535,60 +1095,33 @@ func convertToSwitchValue(value interface{}) string {
 	return "OFF"
 }
 
-// calculatePowerIfNeeded calculates Active Power from Voltage × Current if not available
-func (p *Publisher) calculatePowerIfNeeded(values map[string]interface{}) {
-	// Check if Active Power is 0 or missing
-	activePower, hasPower := values["Active Power"]
-	powerIsZero := !hasPower
-
-	if hasPower {
-		switch v := activePower.(type) {
-		case float64:
-			powerIsZero = v == 0
-		case int:
-			powerIsZero = v == 0
-		case string:
-			powerIsZero = v == "0" || v == ""
-		}
-	}
-
-	if powerIsZero {
-		// Try to calculate from Voltage × Total Current
-		voltage := toFloat64(values["Voltage"])
-		current := toFloat64(values["Total Current"])
-
-		if voltage > 0 && current > 0 {
-			calculatedPower := voltage * current
-			// Round to 1 decimal place
-			values["Active Power"] = float64(int(calculatedPower*10)) / 10
+// switchState converts a polled switch value to ON/OFF, comparing integers
+// with on_value/off_value where the mapping declares them
+func switchState(value interface{}, mapping *domain.OIDMapping) string {
+	if mapping.OnValue != nil && mapping.OffValue != nil {
+		if n, err := strconv.Atoi(fmt.Sprintf("%v", value)); err == nil {
+			switch n {
+			case *mapping.OnValue:
+				return "ON"
+			case *mapping.OffValue:
+				return "OFF"
+			}
 		}
 	}
-}
-
-// toFloat64 converts various types to float64
-func toFloat64(v interface{}) float64 {
-	if v == nil {
-		return 0
-	}
-	switch val := v.(type) {
-	case float64:
-		return val
-	case int:
-		return float64(val)
-	case int64:
-		return float64(val)
-	case string:
-		var f float64
-		fmt.Sscanf(val, "%f", &f)
-		return f
-	default:
-		return 0
-	}
+	return convertToSwitchValue(value)
 }
 
 // convertToBinarySensorValue converts a value to ON/OFF for binary sensors
 // For device_class: problem, safety, power - "good" states should be OFF, "bad" states should be ON
 func convertToBinarySensorValue(value interface{}, deviceClass string) string {
+	// Computed alarms are already booleans
+	if b, ok := value.(bool); ok {
+		if b {
+			return "ON"
+		}
+		return "OFF"
+	}
+
 	strValue := fmt.Sprintf("%v", value)
 	strLower := strings.ToLower(strValue)
 
@@ -0,0 +1,183 @@
+package mqtt
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	publishTimeout     = 10 * time.Second
+	retryInterval      = 5 * time.Second
+	defaultMaxAttempts = 5
+)
+
+// PublishStats reports publish outcomes and the state of the retry queue
+type PublishStats struct {
+	Published       uint64 `json:"published"`
+	PublishFailures uint64 `json:"publish_failures"`
+	Retried         uint64 `json:"retried"`
+	Dropped         uint64 `json:"dropped"`
+	Queued          int    `json:"queued"`
+	QueueSize       int    `json:"queue_size"`
+}
+
+// pendingPublish is a failed publish waiting to be retried
+type pendingPublish struct {
+	topic    string
+	payload  []byte
+	retain   bool
+	attempts int
+}
+
+// publishQueue holds failed publishes for retry. Only the latest message per
+// topic is kept, since a retried older value would overwrite a newer one.
+// When full, the oldest message is dropped. Only messages that carry state
+// are queued: one-off events such as traps are stale by the time they would
+// be retried.
+type publishQueue struct {
+	capacity    int
+	maxAttempts int
+
+	mu       sync.Mutex
+	pending  []*pendingPublish
+	draining sync.Mutex
+
+	published atomic.Uint64
+	failures  atomic.Uint64
+	retried   atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+func newPublishQueue(capacity, maxAttempts int) *publishQueue {
+	if capacity < 0 {
+		capacity = 0
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	return &publishQueue{capacity: capacity, maxAttempts: maxAttempts}
+}
+
+// add queues a failed publish, replacing any queued message on the same topic
+func (q *publishQueue) add(topic string, payload []byte, retain bool) {
+	q.failures.Add(1)
+	if q.capacity == 0 {
+		q.dropped.Add(1)
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.removeLocked(topic)
+	q.pending = append(q.pending, &pendingPublish{topic: topic, payload: payload, retain: retain, attempts: 1})
+	if excess := len(q.pending) - q.capacity; excess > 0 {
+		q.pending = q.pending[excess:]
+		q.dropped.Add(uint64(excess))
+	}
+}
+
+// discard records a failed publish that is not retried
+func (q *publishQueue) discard() {
+	q.failures.Add(1)
+	q.dropped.Add(1)
+}
+
+// succeeded records a successful publish and discards a stale queued message
+// on the same topic
+func (q *publishQueue) succeeded(topic string) {
+	q.published.Add(1)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) > 0 {
+		q.removeLocked(topic)
+	}
+}
+
+// clear drops every queued message
+func (q *publishQueue) clear() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := len(q.pending)
+	q.pending = nil
+	q.dropped.Add(uint64(n))
+	return n
+}
+
+func (q *publishQueue) removeLocked(topic string) {
+	for i, p := range q.pending {
+		if p.topic == topic {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// drain retries queued messages in order with send, stopping at the first
+// failure since the connection is then most likely down again. Messages
+// that fail maxAttempts times are dropped.
+func (q *publishQueue) drain(send func(topic string, payload []byte, retain bool) error) {
+	q.draining.Lock()
+	defer q.draining.Unlock()
+
+	var sent int
+	defer func() {
+		if sent > 0 {
+			log.Printf("MQTT: republished %d queued messages", sent)
+		}
+	}()
+
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.mu.Unlock()
+			return
+		}
+		msg := q.pending[0]
+		q.mu.Unlock()
+
+		err := send(msg.topic, msg.payload, msg.retain)
+
+		q.mu.Lock()
+		// A newer message on the topic may have replaced this one meanwhile
+		queued := len(q.pending) > 0 && q.pending[0] == msg
+		if err == nil {
+			if queued {
+				q.pending = q.pending[1:]
+			}
+			q.mu.Unlock()
+			q.published.Add(1)
+			q.retried.Add(1)
+			sent++
+			continue
+		}
+
+		q.failures.Add(1)
+		msg.attempts++
+		if queued && msg.attempts >= q.maxAttempts {
+			q.pending = q.pending[1:]
+			q.dropped.Add(1)
+			log.Printf("MQTT: dropping publish to %s after %d attempts: %v", msg.topic, msg.attempts, err)
+		}
+		q.mu.Unlock()
+		return
+	}
+}
+
+func (q *publishQueue) stats() PublishStats {
+	q.mu.Lock()
+	queued := len(q.pending)
+	q.mu.Unlock()
+
+	return PublishStats{
+		Published:       q.published.Load(),
+		PublishFailures: q.failures.Load(),
+		Retried:         q.retried.Load(),
+		Dropped:         q.dropped.Load(),
+		Queued:          queued,
+		QueueSize:       q.capacity,
+	}
+}
@@ -0,0 +1,157 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"snmp-mqtt-bridge/internal/actor"
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+	"snmp-mqtt-bridge/internal/service"
+)
+
+// sceneTopicNode is the topic level under the prefix used for scenes:
+// <prefix>/scenes/<scene_id>/set runs a scene, <prefix>/scenes/<scene_id>/result
+// carries the outcome of the last run
+const sceneTopicNode = "scenes"
+
+// ScenePublisher exposes scenes over MQTT: a command topic per scene and,
+// for scenes that ask for it, a Home Assistant button
+type ScenePublisher struct {
+	client    *Client
+	discovery *Discovery
+	scenes    *service.SceneService
+	bus       *eventbus.Bus
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// NewScenePublisher creates a new scene publisher
+func NewScenePublisher(client *Client, discovery *Discovery, scenes *service.SceneService, bus *eventbus.Bus) *ScenePublisher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &ScenePublisher{
+		client:    client,
+		discovery: discovery,
+		scenes:    scenes,
+		bus:       bus,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start subscribes to scene commands and publishes scene discovery
+func (p *ScenePublisher) Start() error {
+	sub := p.bus.Subscribe(
+		eventbus.TypeSceneUpdated,
+		eventbus.TypeSceneDeleted,
+		eventbus.TypeSceneRun,
+		eventbus.TypeMQTTStatus,
+	)
+	p.wg.Add(1)
+	go p.handleEvents(sub)
+
+	// Scene commands share the device command plumbing, with "scenes" in place of a device ID
+	if err := p.client.SubscribeCommands(sceneTopicNode, p.handleCommand); err != nil {
+		log.Printf("Failed to subscribe to scene commands: %v", err)
+	}
+
+	p.publishAll()
+	return nil
+}
+
+// Stop stops the scene publisher and stops accepting scene commands. Runs
+// already in progress are left to the command queue's shutdown.
+func (p *ScenePublisher) Stop() {
+	p.cancel()
+	p.wg.Wait()
+
+	if p.client.IsConnected() {
+		p.client.UnsubscribeCommands(sceneTopicNode)
+	}
+}
+
+func (p *ScenePublisher) handleEvents(sub *eventbus.Subscription) {
+	defer p.wg.Done()
+	defer p.bus.Unsubscribe(sub)
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case evt, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			p.handleEvent(evt)
+		}
+	}
+}
+
+func (p *ScenePublisher) handleEvent(evt eventbus.Event) {
+	switch evt.Type {
+	case eventbus.TypeSceneUpdated:
+		if scene, ok := evt.Payload.(*domain.Scene); ok {
+			p.publishScene(scene)
+		}
+
+	case eventbus.TypeSceneDeleted:
+		if scene, ok := evt.Payload.(*domain.Scene); ok && p.client.IsConnected() {
+			if err := p.discovery.RemoveScene(scene); err != nil {
+				log.Printf("Failed to remove discovery for scene %s: %v", scene.Name, err)
+			}
+		}
+
+	case eventbus.TypeSceneRun:
+		if run, ok := evt.Payload.(*domain.SceneRun); ok && p.client.IsConnected() {
+			topic := fmt.Sprintf("%s/%s/%s/result", p.client.topicPrefix, sceneTopicNode, run.SceneID)
+			if err := p.client.Publish(topic, run, false); err != nil {
+				log.Printf("Failed to publish result for scene %s: %v", run.Name, err)
+			}
+		}
+
+	case eventbus.TypeMQTTStatus:
+		// Republish buttons after a reconnect
+		if status, ok := evt.Payload.(eventbus.MQTTStatus); ok && status.Connected {
+			p.publishAll()
+		}
+	}
+}
+
+func (p *ScenePublisher) publishAll() {
+	scenes, err := p.scenes.GetAll(p.ctx)
+	if err != nil {
+		log.Printf("Failed to load scenes: %v", err)
+		return
+	}
+	for i := range scenes {
+		p.publishScene(&scenes[i])
+	}
+}
+
+func (p *ScenePublisher) publishScene(scene *domain.Scene) {
+	if !p.client.IsConnected() {
+		return
+	}
+
+	var err error
+	if scene.HAButton {
+		err = p.discovery.PublishScene(scene)
+	} else {
+		err = p.discovery.RemoveScene(scene)
+	}
+	if err != nil {
+		log.Printf("Failed to publish discovery for scene %s: %v", scene.Name, err)
+	}
+}
+
+func (p *ScenePublisher) handleCommand(_, sceneID string, payload []byte) {
+	log.Printf("Received scene command for %s: %s", sceneID, string(payload))
+
+	if _, err := p.scenes.Run(actor.WithName(p.ctx, domain.AuditSourceMQTT), sceneID, eventbus.CommandSourceMQTT); err != nil {
+		log.Printf("Failed to run scene %s: %v", sceneID, err)
+	}
+}
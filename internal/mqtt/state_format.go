@@ -0,0 +1,77 @@
+package mqtt
+
+import (
+	"regexp"
+
+	"snmp-mqtt-bridge/internal/domain"
+)
+
+// StateFormat selects how values are laid out in the full device state payload
+type StateFormat string
+
+const (
+	// StateFormatRaw publishes every polled value, keyed by mapping name and by raw OID
+	StateFormatRaw StateFormat = "raw"
+	// StateFormatFlatNames publishes mapped and computed names only
+	StateFormatFlatNames StateFormat = "flat-names"
+	// StateFormatNested groups mapped names by entity category or poll group
+	StateFormatNested StateFormat = "nested"
+)
+
+// defaultStateGroup holds values without an entity category or poll group
+const defaultStateGroup = "main"
+
+var rawOIDKey = regexp.MustCompile(`^\.?\d+(\.\d+)+$`)
+
+// Valid reports whether the format is known
+func (f StateFormat) Valid() bool {
+	switch f {
+	case StateFormatRaw, StateFormatFlatNames, StateFormatNested:
+		return true
+	}
+	return false
+}
+
+// formatStateValues lays out polled values for the full state payload
+func formatStateValues(format StateFormat, profile *domain.Profile, values map[string]interface{}) map[string]interface{} {
+	if format != StateFormatFlatNames && format != StateFormatNested {
+		return values
+	}
+
+	named := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if !rawOIDKey.MatchString(key) {
+			named[key] = value
+		}
+	}
+	if format == StateFormatFlatNames {
+		return named
+	}
+
+	groups := make(map[string]string)
+	if profile != nil {
+		for _, m := range profile.OIDMappings {
+			switch {
+			case m.Category != "":
+				groups[m.Name] = m.Category
+			case m.PollGroup != "":
+				groups[m.Name] = m.PollGroup
+			}
+		}
+	}
+
+	nested := make(map[string]interface{})
+	for key, value := range named {
+		group, ok := groups[key]
+		if !ok {
+			group = defaultStateGroup
+		}
+		members, _ := nested[group].(map[string]interface{})
+		if members == nil {
+			members = make(map[string]interface{})
+			nested[group] = members
+		}
+		members[key] = value
+	}
+	return nested
+}
@@ -0,0 +1,76 @@
+package mqtt
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// StateRequestHandler handles a request to republish a device's states
+type StateRequestHandler func(deviceID string)
+
+// stateRequestTopic matches <prefix>/<device_id>/get, on which the current
+// states of a device are requested
+func (c *Client) stateRequestTopic() string {
+	return fmt.Sprintf("%s/+/get", c.topicPrefix)
+}
+
+// SubscribeStateRequests subscribes to state requests of all devices
+func (c *Client) SubscribeStateRequests(handler StateRequestHandler) error {
+	c.handlersMu.Lock()
+	c.stateRequests = handler
+	c.handlersMu.Unlock()
+
+	return c.Subscribe(c.stateRequestTopic(), c.handleStateRequest)
+}
+
+func (c *Client) handleStateRequest(_ mqtt.Client, msg mqtt.Message) {
+	// A request left retained would be answered on every connect
+	if msg.Retained() {
+		return
+	}
+	deviceID := strings.TrimSuffix(strings.TrimPrefix(msg.Topic(), c.topicPrefix+"/"), "/get")
+
+	c.handlersMu.RLock()
+	h := c.stateRequests
+	c.handlersMu.RUnlock()
+
+	if h != nil {
+		h(deviceID)
+	}
+}
+
+// subscribeStateRequests answers <prefix>/<device_id>/get by republishing the
+// device's current states, e.g. after Home Assistant reloaded the MQTT
+// integration or retained states were cleared
+func (p *Publisher) subscribeStateRequests() {
+	if err := p.client.SubscribeStateRequests(p.handleStateRequest); err != nil {
+		log.Printf("Failed to subscribe to state requests: %v", err)
+	}
+}
+
+// handleStateRequest republishes the availability and latest polled states
+// of a device; requests for devices this bridge does not publish are ignored,
+// as another bridge sharing the prefix may
+func (p *Publisher) handleStateRequest(deviceID string) {
+	p.devicesMu.RLock()
+	info := p.devices[deviceID]
+	p.devicesMu.RUnlock()
+
+	if info == nil {
+		return
+	}
+	log.Printf("Republishing state of device %s on request", deviceID)
+	p.republishState(info)
+}
+
+// UnsubscribeStateRequests stops answering state requests
+func (c *Client) UnsubscribeStateRequests() {
+	c.Unsubscribe(c.stateRequestTopic())
+
+	c.handlersMu.Lock()
+	c.stateRequests = nil
+	c.handlersMu.Unlock()
+}
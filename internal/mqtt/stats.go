@@ -0,0 +1,188 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/service"
+	"snmp-mqtt-bridge/internal/timefmt"
+)
+
+// BridgeStats is the payload of the retained <prefix>/bridge/stats topic, or
+// <prefix>/bridge/<node>/stats on a node of a sharded cluster
+type BridgeStats struct {
+	Version    string            `json:"version"`
+	StartedAt  string            `json:"started_at"`
+	Uptime     int64             `json:"uptime"` // Seconds
+	Devices    BridgeDeviceStats `json:"devices"`
+	Polls      uint64            `json:"polls"`       // Since start
+	PollErrors uint64            `json:"poll_errors"` // Failed polls since start
+	PollRate   float64           `json:"poll_rate"`   // Polls per minute over the last interval
+	ErrorRate  float64           `json:"error_rate"`  // Share of failed polls over the last interval, 0-1
+	Queues     BridgeQueueStats  `json:"queues"`
+	Timestamp  string            `json:"timestamp"`
+
+	DatabaseSize      int64  `json:"database_size,omitempty"` // Bytes
+	DiscardedReadings uint64 `json:"discarded_readings"`      // Out-of-range readings dropped since start
+	DroppedEvents     uint64 `json:"dropped_events"`          // Internal events lost to slow subscribers since start
+}
+
+// BridgeDeviceStats counts the devices the bridge publishes
+type BridgeDeviceStats struct {
+	Total   int `json:"total"`
+	Online  int `json:"online"`
+	Offline int `json:"offline"`
+	Pending int `json:"pending"` // Not polled yet
+}
+
+// BridgeQueueStats reports the depth of the bridge's queues
+type BridgeQueueStats struct {
+	PublishRetry     int `json:"publish_retry"` // Failed publishes waiting for retry
+	PublishRetrySize int `json:"publish_retry_size"`
+	Commands         int `json:"commands"` // SNMP SET jobs waiting across devices
+}
+
+// SetStatsInterval sets how often bridge statistics are published; 0
+// disables them
+func (p *Publisher) SetStatsInterval(interval time.Duration) {
+	p.statsInterval = interval
+}
+
+// SetVersion sets the bridge version reported in the statistics
+func (p *Publisher) SetVersion(version string) {
+	p.version = version
+}
+
+// SetMaintenance sets the maintenance service whose database size the
+// statistics report, shown as a diagnostic sensor of the bridge device
+func (p *Publisher) SetMaintenance(maintenance *service.MaintenanceService) {
+	p.maintenance = maintenance
+}
+
+// publishBridgeDiagnostics publishes the discovery configs of the bridge
+// device's sensors read from the statistics
+func (p *Publisher) publishBridgeDiagnostics() {
+	if p.statsInterval <= 0 || p.maintenance == nil {
+		return
+	}
+	if err := p.discovery.PublishBridgeDatabaseSize(p.client.BridgeTopic("stats")); err != nil {
+		log.Printf("Failed to publish bridge database size discovery: %v", err)
+	}
+}
+
+// publishStatsLoop periodically publishes bridge statistics
+func (p *Publisher) publishStatsLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.statsInterval)
+	defer ticker.Stop()
+
+	lastAt := p.startedAt
+	var lastPolls, lastErrors uint64
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case now := <-ticker.C:
+			stats := p.bridgeStats(now)
+			polls, failures := stats.Polls-lastPolls, stats.PollErrors-lastErrors
+			stats.PollRate = roundRate(float64(polls) / now.Sub(lastAt).Minutes())
+			if polls > 0 {
+				stats.ErrorRate = roundRate(float64(failures) / float64(polls))
+			}
+			lastAt, lastPolls, lastErrors = now, stats.Polls, stats.PollErrors
+
+			if !p.client.IsConnected() {
+				continue
+			}
+			if err := p.client.Publish(p.client.BridgeTopic("stats"), stats, p.client.cfg.Retain.StatsRetained()); err != nil {
+				log.Printf("Failed to publish bridge stats: %v", err)
+			}
+		}
+	}
+}
+
+// bridgeStats collects the statistics except the rates, which depend on the
+// previous publish
+func (p *Publisher) bridgeStats(now time.Time) *BridgeStats {
+	polls, failures := p.poller.PollCounts()
+	stats := &BridgeStats{
+		Version:    p.version,
+		StartedAt:  timefmt.Format(p.startedAt),
+		Uptime:     int64(now.Sub(p.startedAt).Seconds()),
+		Polls:      polls,
+		PollErrors: failures,
+		Timestamp:  timefmt.Format(now),
+
+		DiscardedReadings: p.poller.DiscardedReadings(),
+		DroppedEvents:     p.bus.Dropped(),
+	}
+
+	states := p.poller.GetAllDeviceStates()
+	p.devicesMu.RLock()
+	stats.Devices.Total = len(p.devices)
+	for deviceID := range p.devices {
+		state, ok := states[deviceID]
+		switch {
+		case !ok || state.LastPoll.IsZero():
+			stats.Devices.Pending++
+		case state.Online:
+			stats.Devices.Online++
+		default:
+			stats.Devices.Offline++
+		}
+	}
+	p.devicesMu.RUnlock()
+
+	publish := p.client.PublishStats()
+	stats.Queues.PublishRetry = publish.Queued
+	stats.Queues.PublishRetrySize = publish.QueueSize
+	if p.commandQueue != nil {
+		stats.Queues.Commands = p.commandQueue.Depth()
+	}
+	if p.maintenance != nil {
+		ctx, cancel := context.WithTimeout(p.ctx, 5*time.Second)
+		size, err := p.maintenance.Size(ctx)
+		cancel()
+		if err != nil {
+			log.Printf("Failed to read database size for bridge stats: %v", err)
+		}
+		stats.DatabaseSize = size
+	}
+	return stats
+}
+
+func roundRate(rate float64) float64 {
+	return math.Round(rate*100) / 100
+}
+
+// publishUpdate publishes the result of a release check to the bridge's
+// update entity
+func (p *Publisher) publishUpdate(status *domain.UpdateStatus) {
+	if !p.client.IsConnected() {
+		return
+	}
+	if err := p.discovery.PublishBridgeUpdate(); err != nil {
+		log.Printf("Failed to publish bridge update discovery: %v", err)
+	}
+	// Home Assistant shows an update whenever the versions differ, so a
+	// newer but unparsable tag or a development build reports up to date
+	latest := status.InstalledVersion
+	if status.UpdateAvailable {
+		latest = status.LatestVersion
+	}
+	payload := map[string]interface{}{
+		"installed_version": status.InstalledVersion,
+		"latest_version":    latest,
+		"title":             status.Title,
+		"release_url":       status.ReleaseURL,
+		"release_summary":   status.ReleaseSummary,
+	}
+	if err := p.client.Publish(fmt.Sprintf("%s/bridge/update", p.client.TopicPrefix()), payload, p.client.cfg.Retain.StatsRetained()); err != nil {
+		log.Printf("Failed to publish bridge update status: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package mqtt
+
+import (
+	"time"
+
+	"snmp-mqtt-bridge/internal/service"
+)
+
+// publishDue reports whether a polled state is published now under the
+// device's publish interval. States within the interval are held back, and the
+// latest of them is published once it has passed, so the broker ends up with
+// the last values either way. Switch, select and binary sensor changes are
+// never held back, so commands and transfers show at once.
+func (p *Publisher) publishDue(info *deviceInfo, event service.StateUpdateEvent, force bool) bool {
+	p.devicesMu.Lock()
+	defer p.devicesMu.Unlock()
+
+	interval := time.Duration(info.device.PublishInterval) * time.Second
+	now := time.Now()
+	if force || interval <= 0 || now.Sub(info.publishedAt) >= interval ||
+		service.DiscreteChanged(info.profile, info.publishedValues, event.Values) {
+		info.publishedAt = now
+		info.publishedValues = event.Values
+		info.pending = nil
+		if info.flush != nil {
+			info.flush.Stop()
+			info.flush = nil
+		}
+		return true
+	}
+
+	info.pending = &event
+	if info.flush == nil {
+		deviceID := info.device.ID
+		info.flush = time.AfterFunc(interval-now.Sub(info.publishedAt), func() {
+			p.flushPending(deviceID, info)
+		})
+	}
+	return false
+}
+
+// flushPending publishes the state held back for a device, unless the device
+// was re-registered or the publisher stopped meanwhile
+func (p *Publisher) flushPending(deviceID string, info *deviceInfo) {
+	p.devicesMu.Lock()
+	pending := info.pending
+	info.pending = nil
+	info.flush = nil
+	current := p.devices[deviceID] == info
+	p.devicesMu.Unlock()
+
+	if pending == nil || !current || p.ctx.Err() != nil {
+		return
+	}
+	p.publishState(*pending, true)
+}
@@ -0,0 +1,119 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"snmp-mqtt-bridge/internal/actor"
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/service"
+)
+
+// bridgeTopicNode is the topic level under the prefix for bridge-wide
+// commands: <prefix>/bridge/lock/set switches the write lock, whose state is
+// kept retained on <prefix>/bridge/lock
+const bridgeTopicNode = "bridge"
+
+// writeLockEntity is the entity ID of the write lock under bridgeTopicNode
+const writeLockEntity = "lock"
+
+// SetWriteLock sets the write lock commands are checked against and that is
+// switched through <prefix>/bridge/lock/set; commands received while it is
+// held are logged but not sent to devices
+func (p *Publisher) SetWriteLock(lock *service.WriteLockService) {
+	p.writeLock = lock
+}
+
+func writeLockTopic(topicPrefix string) string {
+	return fmt.Sprintf("%s/%s/%s", topicPrefix, bridgeTopicNode, writeLockEntity)
+}
+
+// subscribeWriteLock accepts write lock commands, including one left
+// retained on the command topic, which is applied on every connect
+func (p *Publisher) subscribeWriteLock() {
+	if p.writeLock == nil {
+		return
+	}
+	if err := p.client.SubscribeCommands(bridgeTopicNode, p.handleBridgeCommand); err != nil {
+		log.Printf("Failed to subscribe to bridge commands: %v", err)
+	}
+}
+
+// syncWriteLock publishes the write lock switch and its current state
+func (p *Publisher) syncWriteLock() {
+	if p.writeLock == nil || !p.client.IsConnected() {
+		return
+	}
+	if err := p.discovery.PublishBridgeWriteLock(); err != nil {
+		log.Printf("Failed to publish write lock discovery: %v", err)
+	}
+	lock, err := p.writeLock.Status(p.ctx)
+	if err != nil {
+		log.Printf("Failed to read write lock: %v", err)
+		return
+	}
+	p.publishWriteLockState(lock)
+}
+
+// publishWriteLock publishes a change of the write lock. A change made
+// elsewhere clears any command left retained, so it is not undone on the
+// next connect.
+func (p *Publisher) publishWriteLock(lock *domain.WriteLock) {
+	if !p.client.IsConnected() {
+		return
+	}
+	p.publishWriteLockState(lock)
+	if lock.ChangedBy != domain.AuditSourceMQTT {
+		if err := p.client.Publish(writeLockTopic(p.client.TopicPrefix())+"/set", "", true); err != nil {
+			log.Printf("Failed to clear retained write lock command: %v", err)
+		}
+	}
+}
+
+func (p *Publisher) publishWriteLockState(lock *domain.WriteLock) {
+	state := "OFF"
+	if lock.Locked {
+		state = "ON"
+	}
+	if err := p.client.Publish(writeLockTopic(p.client.TopicPrefix()), state, true); err != nil {
+		log.Printf("Failed to publish write lock state: %v", err)
+	}
+}
+
+// handleBridgeCommand switches the write lock from ON/OFF or a JSON payload
+// like {"locked": true, "reason": "change freeze"}
+func (p *Publisher) handleBridgeCommand(_, entityID string, payload []byte) {
+	if entityID != writeLockEntity {
+		return
+	}
+	// An empty payload clears a retained command
+	text := strings.TrimSpace(string(payload))
+	if text == "" {
+		return
+	}
+
+	var req struct {
+		Locked *bool  `json:"locked"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(payload, &req); err != nil || req.Locked == nil {
+		locked := convertToSwitchValue(text) == "ON"
+		req.Locked = &locked
+		req.Reason = ""
+	}
+
+	// A retained command is delivered again on every connect
+	if current, err := p.writeLock.Status(p.ctx); err == nil && current.Locked == *req.Locked && current.Reason == req.Reason {
+		p.publishWriteLockState(current)
+		return
+	}
+
+	ctx := actor.WithName(p.ctx, domain.AuditSourceMQTT)
+	if _, err := p.writeLock.Set(ctx, *req.Locked, req.Reason); err != nil {
+		log.Printf("Failed to switch write lock: %v", err)
+		return
+	}
+	log.Printf("Write lock switched over MQTT: locked=%v", *req.Locked)
+}
@@ -0,0 +1,62 @@
+// Package factory builds the repository set selected by the database config.
+package factory
+
+import (
+	"fmt"
+
+	"snmp-mqtt-bridge/internal/config"
+	"snmp-mqtt-bridge/internal/repository"
+	"snmp-mqtt-bridge/internal/repository/memory"
+	"snmp-mqtt-bridge/internal/repository/sqlite"
+)
+
+// DriverMemory keeps all data in memory; it is lost on restart
+const DriverMemory = "memory"
+
+// New creates repositories for the configured driver: "memory", "postgres" or
+// "sqlite" (the default)
+func New(cfg *config.DatabaseConfig) (*repository.Repositories, error) {
+	if cfg.Driver == DriverMemory {
+		return NewMemory(), nil
+	}
+
+	db, err := sqlite.NewDB(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize %s database: %w", cfg.Driver, err)
+	}
+
+	return &repository.Repositories{
+		Device:     sqlite.NewDeviceRepository(db),
+		Profile:    sqlite.NewProfileRepository(db),
+		TrapLog:    sqlite.NewTrapLogRepository(db),
+		Setting:    sqlite.NewSettingRepository(db),
+		Event:      sqlite.NewEventRepository(db),
+		Scene:      sqlite.NewSceneRepository(db),
+		Credential: sqlite.NewCredentialRepository(db),
+
+		ConfigSnapshot: sqlite.NewConfigSnapshotRepository(db),
+		Lease:          sqlite.NewLeaseRepository(db),
+		ClusterNode:    sqlite.NewClusterNodeRepository(db),
+		Audit:          sqlite.NewAuditRepository(db),
+		Health:         sqlite.MonitorOf(db),
+		Maintenance:    sqlite.NewMaintenanceRepository(db),
+	}, nil
+}
+
+// NewMemory creates empty in-memory repositories
+func NewMemory() *repository.Repositories {
+	return &repository.Repositories{
+		Device:     memory.NewDeviceRepository(),
+		Profile:    memory.NewProfileRepository(),
+		TrapLog:    memory.NewTrapLogRepository(),
+		Setting:    memory.NewSettingRepository(),
+		Event:      memory.NewEventRepository(),
+		Scene:      memory.NewSceneRepository(),
+		Credential: memory.NewCredentialRepository(),
+
+		ConfigSnapshot: memory.NewConfigSnapshotRepository(),
+		Lease:          memory.NewLeaseRepository(),
+		ClusterNode:    memory.NewClusterNodeRepository(),
+		Audit:          memory.NewAuditRepository(),
+	}
+}
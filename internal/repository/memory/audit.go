@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+type auditRepository struct {
+	entries *table[domain.AuditEntry]
+}
+
+// NewAuditRepository creates a new in-memory audit trail repository
+func NewAuditRepository() repository.AuditRepository {
+	return &auditRepository{entries: newTable[domain.AuditEntry]()}
+}
+
+func (r *auditRepository) Create(ctx context.Context, entry *domain.AuditEntry) error {
+	return r.entries.insert(entry.ID, *entry)
+}
+
+func (r *auditRepository) GetAll(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, int64, error) {
+	entries := r.entries.find(func(e *domain.AuditEntry) bool {
+		if filter.Actor != "" && e.Actor != filter.Actor {
+			return false
+		}
+		if filter.Target != "" && e.Target != filter.Target {
+			return false
+		}
+		if filter.Source != "" && e.Source != filter.Source {
+			return false
+		}
+		if filter.StartTime != nil && e.CreatedAt.Before(*filter.StartTime) {
+			return false
+		}
+		if filter.EndTime != nil && e.CreatedAt.After(*filter.EndTime) {
+			return false
+		}
+		return true
+	})
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].CreatedAt.After(entries[j].CreatedAt) })
+	return paginate(entries, filter.Limit, filter.Offset), int64(len(entries)), nil
+}
+
+func (r *auditRepository) DeleteOlderThan(ctx context.Context, days int) (int64, error) {
+	cutoff := time.Now().AddDate(0, 0, -days)
+	return r.entries.deleteWhere(func(e *domain.AuditEntry) bool { return e.CreatedAt.Before(cutoff) }), nil
+}
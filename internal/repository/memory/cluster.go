@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+type clusterNodeRepository struct {
+	nodes *table[domain.ClusterNode]
+}
+
+// NewClusterNodeRepository creates a new in-memory cluster node repository
+func NewClusterNodeRepository() repository.ClusterNodeRepository {
+	return &clusterNodeRepository{nodes: newTable[domain.ClusterNode]()}
+}
+
+func (r *clusterNodeRepository) Heartbeat(ctx context.Context, node *domain.ClusterNode) error {
+	if existing, err := r.nodes.get(node.ID); err == nil {
+		existing.Tags = node.Tags
+		existing.SeenAt = node.SeenAt
+		r.nodes.save(node.ID, existing)
+		return nil
+	}
+	r.nodes.save(node.ID, *node)
+	return nil
+}
+
+func (r *clusterNodeRepository) GetAlive(ctx context.Context, since time.Time) ([]domain.ClusterNode, error) {
+	nodes := r.nodes.find(func(n *domain.ClusterNode) bool {
+		return !n.SeenAt.Before(since)
+	})
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes, nil
+}
+
+func (r *clusterNodeRepository) Delete(ctx context.Context, id string) error {
+	r.nodes.delete(id)
+	return nil
+}
@@ -0,0 +1,33 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+type configSnapshotRepository struct {
+	snapshots *table[domain.ConfigSnapshot]
+}
+
+// NewConfigSnapshotRepository creates a new in-memory configuration snapshot repository
+func NewConfigSnapshotRepository() repository.ConfigSnapshotRepository {
+	return &configSnapshotRepository{snapshots: newTable[domain.ConfigSnapshot]()}
+}
+
+func (r *configSnapshotRepository) Create(ctx context.Context, snapshot *domain.ConfigSnapshot) error {
+	return r.snapshots.insert(snapshot.ID, *snapshot)
+}
+
+func (r *configSnapshotRepository) GetByDevice(ctx context.Context, deviceID string, limit int) ([]domain.ConfigSnapshot, error) {
+	snapshots := r.snapshots.find(func(s *domain.ConfigSnapshot) bool { return s.DeviceID == deviceID })
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt) })
+	return paginate(snapshots, limit, 0), nil
+}
+
+func (r *configSnapshotRepository) DeleteByDevice(ctx context.Context, deviceID string) error {
+	r.snapshots.deleteWhere(func(s *domain.ConfigSnapshot) bool { return s.DeviceID == deviceID })
+	return nil
+}
@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+type credentialRepository struct {
+	credentials *table[domain.Credential]
+}
+
+// NewCredentialRepository creates a new in-memory credential repository
+func NewCredentialRepository() repository.CredentialRepository {
+	return &credentialRepository{credentials: newTable[domain.Credential]()}
+}
+
+func (r *credentialRepository) Create(ctx context.Context, credential *domain.Credential) error {
+	return r.credentials.insert(credential.ID, *credential)
+}
+
+func (r *credentialRepository) GetByID(ctx context.Context, id string) (*domain.Credential, error) {
+	credential, err := r.credentials.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r *credentialRepository) GetAll(ctx context.Context) ([]domain.Credential, error) {
+	credentials := r.credentials.find(nil)
+	sort.SliceStable(credentials, func(i, j int) bool { return credentials[i].Name < credentials[j].Name })
+	return credentials, nil
+}
+
+func (r *credentialRepository) Update(ctx context.Context, credential *domain.Credential) error {
+	r.credentials.save(credential.ID, *credential)
+	return nil
+}
+
+func (r *credentialRepository) Delete(ctx context.Context, id string) error {
+	r.credentials.delete(id)
+	return nil
+}
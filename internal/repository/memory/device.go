@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+type deviceRepository struct {
+	devices *table[domain.Device]
+}
+
+// NewDeviceRepository creates a new in-memory device repository
+func NewDeviceRepository() repository.DeviceRepository {
+	return &deviceRepository{devices: newTable[domain.Device]()}
+}
+
+func (r *deviceRepository) Create(ctx context.Context, device *domain.Device) error {
+	return r.devices.insert(device.ID, *device)
+}
+
+func (r *deviceRepository) GetByID(ctx context.Context, id string) (*domain.Device, error) {
+	device, err := r.devices.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (r *deviceRepository) GetAll(ctx context.Context) ([]domain.Device, error) {
+	return r.devices.find(nil), nil
+}
+
+func (r *deviceRepository) GetEnabled(ctx context.Context) ([]domain.Device, error) {
+	return r.devices.find(func(d *domain.Device) bool { return d.Enabled }), nil
+}
+
+func (r *deviceRepository) Update(ctx context.Context, device *domain.Device) error {
+	r.devices.save(device.ID, *device)
+	return nil
+}
+
+func (r *deviceRepository) Delete(ctx context.Context, id string) error {
+	r.devices.delete(id)
+	return nil
+}
+
+func (r *deviceRepository) UpdateLastSeen(ctx context.Context, id string) error {
+	now := time.Now()
+	r.devices.update(id, func(d *domain.Device) { d.LastSeen = &now })
+	return nil
+}
+
+func (r *deviceRepository) RecordError(ctx context.Context, id, message string) error {
+	now := time.Now()
+	r.devices.update(id, func(d *domain.Device) {
+		d.LastError = message
+		d.LastErrorAt = &now
+		d.ErrorCount++
+	})
+	return nil
+}
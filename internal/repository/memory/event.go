@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+type eventRepository struct {
+	events *table[domain.DeviceEvent]
+}
+
+// NewEventRepository creates a new in-memory device event repository
+func NewEventRepository() repository.EventRepository {
+	return &eventRepository{events: newTable[domain.DeviceEvent]()}
+}
+
+func (r *eventRepository) Create(ctx context.Context, event *domain.DeviceEvent) error {
+	return r.events.insert(event.ID, *event)
+}
+
+func (r *eventRepository) GetAll(ctx context.Context, filter domain.EventFilter) ([]domain.DeviceEvent, int64, error) {
+	events := r.events.find(func(e *domain.DeviceEvent) bool {
+		if filter.DeviceID != "" && e.DeviceID != filter.DeviceID {
+			return false
+		}
+		if len(filter.DeviceIDs) > 0 && !slices.Contains(filter.DeviceIDs, e.DeviceID) {
+			return false
+		}
+		if filter.Type != "" && e.Type != filter.Type {
+			return false
+		}
+		if filter.StartTime != nil && e.CreatedAt.Before(*filter.StartTime) {
+			return false
+		}
+		if filter.EndTime != nil && e.CreatedAt.After(*filter.EndTime) {
+			return false
+		}
+		return true
+	})
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].CreatedAt.After(events[j].CreatedAt) })
+	return paginate(events, filter.Limit, filter.Offset), int64(len(events)), nil
+}
+
+func (r *eventRepository) DeleteOlderThan(ctx context.Context, days int) (int64, error) {
+	cutoff := time.Now().AddDate(0, 0, -days)
+	return r.events.deleteWhere(func(e *domain.DeviceEvent) bool { return e.CreatedAt.Before(cutoff) }), nil
+}
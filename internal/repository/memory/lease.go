@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+type leaseRepository struct {
+	mu     sync.Mutex // Makes Acquire's check and update atomic
+	leases *table[domain.Lease]
+}
+
+// NewLeaseRepository creates a new in-memory lease repository
+func NewLeaseRepository() repository.LeaseRepository {
+	return &leaseRepository{leases: newTable[domain.Lease]()}
+}
+
+func (r *leaseRepository) Acquire(ctx context.Context, name, holder string, now time.Time, ttl time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if lease, err := r.leases.get(name); err == nil && lease.Holder != holder && !lease.ExpiresAt.Before(now) {
+		return false, nil
+	}
+	r.leases.save(name, domain.Lease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl), UpdatedAt: now})
+	return true, nil
+}
+
+func (r *leaseRepository) Release(ctx context.Context, name, holder string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if lease, err := r.leases.get(name); err == nil && lease.Holder == holder {
+		r.leases.delete(name)
+	}
+	return nil
+}
+
+func (r *leaseRepository) Get(ctx context.Context, name string) (*domain.Lease, error) {
+	lease, err := r.leases.get(name)
+	if err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
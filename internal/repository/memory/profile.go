@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type profileRepository struct {
+	profiles *table[domain.Profile]
+}
+
+// NewProfileRepository creates a new in-memory profile repository
+func NewProfileRepository() repository.ProfileRepository {
+	return &profileRepository{profiles: newTable[domain.Profile]()}
+}
+
+func (r *profileRepository) Create(ctx context.Context, profile *domain.Profile) error {
+	return r.profiles.insert(profile.ID, *profile)
+}
+
+func (r *profileRepository) GetByID(ctx context.Context, id string) (*domain.Profile, error) {
+	profile, err := r.profiles.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (r *profileRepository) GetAll(ctx context.Context) ([]domain.Profile, error) {
+	return r.profiles.find(nil), nil
+}
+
+func (r *profileRepository) GetBuiltin(ctx context.Context) ([]domain.Profile, error) {
+	return r.profiles.find(func(p *domain.Profile) bool { return p.IsBuiltin }), nil
+}
+
+func (r *profileRepository) GetBySysObjectID(ctx context.Context, sysOID string) (*domain.Profile, error) {
+	matches := r.profiles.find(func(p *domain.Profile) bool { return p.SysObjectID == sysOID })
+	if len(matches) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &matches[0], nil
+}
+
+func (r *profileRepository) Update(ctx context.Context, profile *domain.Profile) error {
+	r.profiles.save(profile.ID, *profile)
+	return nil
+}
+
+func (r *profileRepository) Delete(ctx context.Context, id string) error {
+	r.profiles.delete(id)
+	return nil
+}
+
+func (r *profileRepository) Upsert(ctx context.Context, profile *domain.Profile) error {
+	r.profiles.save(profile.ID, *profile)
+	return nil
+}
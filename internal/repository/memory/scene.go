@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+type sceneRepository struct {
+	scenes *table[domain.Scene]
+}
+
+// NewSceneRepository creates a new in-memory scene repository
+func NewSceneRepository() repository.SceneRepository {
+	return &sceneRepository{scenes: newTable[domain.Scene]()}
+}
+
+func (r *sceneRepository) Create(ctx context.Context, scene *domain.Scene) error {
+	return r.scenes.insert(scene.ID, *scene)
+}
+
+func (r *sceneRepository) GetByID(ctx context.Context, id string) (*domain.Scene, error) {
+	scene, err := r.scenes.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return &scene, nil
+}
+
+func (r *sceneRepository) GetAll(ctx context.Context) ([]domain.Scene, error) {
+	scenes := r.scenes.find(nil)
+	sort.SliceStable(scenes, func(i, j int) bool { return scenes[i].Name < scenes[j].Name })
+	return scenes, nil
+}
+
+func (r *sceneRepository) Update(ctx context.Context, scene *domain.Scene) error {
+	r.scenes.save(scene.ID, *scene)
+	return nil
+}
+
+func (r *sceneRepository) Delete(ctx context.Context, id string) error {
+	r.scenes.delete(id)
+	return nil
+}
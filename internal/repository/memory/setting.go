@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+type settingRepository struct {
+	settings *table[domain.Setting]
+}
+
+// NewSettingRepository creates a new in-memory setting repository
+func NewSettingRepository() repository.SettingRepository {
+	return &settingRepository{settings: newTable[domain.Setting]()}
+}
+
+func (r *settingRepository) Get(ctx context.Context, key string) (string, error) {
+	// Missing keys read as empty, like the SQLite implementation
+	setting, err := r.settings.get(key)
+	if err != nil {
+		return "", nil
+	}
+	return setting.Value, nil
+}
+
+func (r *settingRepository) Set(ctx context.Context, key, value string) error {
+	r.settings.save(key, domain.Setting{Key: key, Value: value})
+	return nil
+}
+
+func (r *settingRepository) GetAll(ctx context.Context) ([]domain.Setting, error) {
+	return r.settings.find(nil), nil
+}
+
+func (r *settingRepository) Delete(ctx context.Context, key string) error {
+	r.settings.delete(key)
+	return nil
+}
@@ -0,0 +1,124 @@
+// Package memory provides in-memory repository implementations for tests and
+// ephemeral deployments. Nothing is persisted across restarts. Errors mirror
+// the SQLite implementation (gorm.ErrRecordNotFound, gorm.ErrDuplicatedKey).
+package memory
+
+import (
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// table is an insertion-ordered map of rows keyed by primary key
+type table[T any] struct {
+	mu    sync.RWMutex
+	rows  map[string]T
+	order []string
+}
+
+func newTable[T any]() *table[T] {
+	return &table[T]{rows: make(map[string]T)}
+}
+
+func (t *table[T]) insert(key string, row T) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, exists := t.rows[key]; exists {
+		return gorm.ErrDuplicatedKey
+	}
+	t.rows[key] = row
+	t.order = append(t.order, key)
+	return nil
+}
+
+// save inserts or replaces a row
+func (t *table[T]) save(key string, row T) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, exists := t.rows[key]; !exists {
+		t.order = append(t.order, key)
+	}
+	t.rows[key] = row
+}
+
+func (t *table[T]) get(key string) (T, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	row, ok := t.rows[key]
+	if !ok {
+		return row, gorm.ErrRecordNotFound
+	}
+	return row, nil
+}
+
+// update applies fn to an existing row
+func (t *table[T]) update(key string, fn func(*T)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if row, ok := t.rows[key]; ok {
+		fn(&row)
+		t.rows[key] = row
+	}
+}
+
+// find returns the rows matching keep in insertion order; a nil keep matches all
+func (t *table[T]) find(keep func(*T) bool) []T {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	rows := make([]T, 0, len(t.order))
+	for _, key := range t.order {
+		row := t.rows[key]
+		if keep == nil || keep(&row) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// deleteWhere removes the rows matching drop and returns how many were removed
+func (t *table[T]) deleteWhere(drop func(*T) bool) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var removed int64
+	kept := t.order[:0]
+	for _, key := range t.order {
+		row := t.rows[key]
+		if drop(&row) {
+			delete(t.rows, key)
+			removed++
+			continue
+		}
+		kept = append(kept, key)
+	}
+	t.order = kept
+	return removed
+}
+
+func (t *table[T]) delete(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.rows[key]; !ok {
+		return
+	}
+	delete(t.rows, key)
+	for i, k := range t.order {
+		if k == key {
+			t.order = append(t.order[:i], t.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// paginate applies offset and limit like SQL OFFSET/LIMIT, where 0 means unset
+func paginate[T any](rows []T, limit, offset int) []T {
+	if offset > 0 {
+		if offset >= len(rows) {
+			return rows[:0]
+		}
+		rows = rows[offset:]
+	}
+	if limit > 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+type trapLogRepository struct {
+	traps *table[domain.TrapLog]
+}
+
+// NewTrapLogRepository creates a new in-memory trap log repository
+func NewTrapLogRepository() repository.TrapLogRepository {
+	return &trapLogRepository{traps: newTable[domain.TrapLog]()}
+}
+
+func (r *trapLogRepository) Create(ctx context.Context, trap *domain.TrapLog) error {
+	return r.traps.insert(trap.ID, *trap)
+}
+
+func (r *trapLogRepository) GetByID(ctx context.Context, id string) (*domain.TrapLog, error) {
+	trap, err := r.traps.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return &trap, nil
+}
+
+func (r *trapLogRepository) GetByDeviceID(ctx context.Context, deviceID string, limit, offset int) ([]domain.TrapLog, error) {
+	traps := r.traps.find(func(t *domain.TrapLog) bool {
+		return t.DeviceID != nil && *t.DeviceID == deviceID
+	})
+	sortTrapsNewestFirst(traps)
+	return paginate(traps, limit, offset), nil
+}
+
+func (r *trapLogRepository) GetAll(ctx context.Context, filter domain.TrapFilter) ([]domain.TrapLog, int64, error) {
+	traps := r.traps.find(func(t *domain.TrapLog) bool { return matchTrap(t, filter) })
+
+	sortTrapsNewestFirst(traps)
+	return paginate(traps, filter.Limit, filter.Offset), int64(len(traps)), nil
+}
+
+func (r *trapLogRepository) Stream(ctx context.Context, filter domain.TrapFilter, fn func(*domain.TrapLog) error) error {
+	traps := r.traps.find(func(t *domain.TrapLog) bool { return matchTrap(t, filter) })
+	sortTrapsNewestFirst(traps)
+	for _, trap := range paginate(traps, filter.Limit, filter.Offset) {
+		if err := fn(&trap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *trapLogRepository) Counts(ctx context.Context, filter domain.TrapFilter) (*domain.TrapCounts, error) {
+	counts := &domain.TrapCounts{
+		BySeverity: make(map[domain.TrapSeverity]int64),
+		ByDevice:   make(map[string]int64),
+		ByOID:      make(map[string]int64),
+	}
+	for _, t := range r.traps.find(func(t *domain.TrapLog) bool { return matchTrap(t, filter) }) {
+		deviceID := ""
+		if t.DeviceID != nil {
+			deviceID = *t.DeviceID
+		}
+		counts.BySeverity[t.Severity]++
+		counts.ByDevice[deviceID]++
+		counts.ByOID[t.TrapOID]++
+	}
+	return counts, nil
+}
+
+func (r *trapLogRepository) LastReceived(ctx context.Context) (map[string]time.Time, error) {
+	last := make(map[string]time.Time)
+	for _, t := range r.traps.find(nil) {
+		if t.DeviceID != nil && t.ReceivedAt.After(last[*t.DeviceID]) {
+			last[*t.DeviceID] = t.ReceivedAt
+		}
+	}
+	return last, nil
+}
+
+func (r *trapLogRepository) DeleteOlderThan(ctx context.Context, days int) (int64, error) {
+	cutoff := time.Now().AddDate(0, 0, -days)
+	return r.traps.deleteWhere(func(t *domain.TrapLog) bool { return t.ReceivedAt.Before(cutoff) }), nil
+}
+
+func sortTrapsNewestFirst(traps []domain.TrapLog) {
+	sort.SliceStable(traps, func(i, j int) bool { return traps[i].ReceivedAt.After(traps[j].ReceivedAt) })
+}
+
+func matchTrap(t *domain.TrapLog, filter domain.TrapFilter) bool {
+	if filter.DeviceID != "" && (t.DeviceID == nil || *t.DeviceID != filter.DeviceID) {
+		return false
+	}
+	if len(filter.DeviceIDs) > 0 && (t.DeviceID == nil || !slices.Contains(filter.DeviceIDs, *t.DeviceID)) {
+		return false
+	}
+	if filter.Severity != "" && t.Severity != filter.Severity {
+		return false
+	}
+	if filter.StartTime != nil && t.ReceivedAt.Before(*filter.StartTime) {
+		return false
+	}
+	if filter.EndTime != nil && t.ReceivedAt.After(*filter.EndTime) {
+		return false
+	}
+	return true
+}
@@ -2,10 +2,54 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"snmp-mqtt-bridge/internal/domain"
+
+	"gorm.io/gorm"
 )
 
+// ErrNotFound is returned by every implementation when a record doesn't
+// exist. It is gorm's sentinel, so callers outside the repositories can match
+// it with errors.Is without depending on gorm.
+var ErrNotFound = gorm.ErrRecordNotFound
+
+// Repositories groups one implementation of every repository interface
+type Repositories struct {
+	Device     DeviceRepository
+	Profile    ProfileRepository
+	TrapLog    TrapLogRepository
+	Setting    SettingRepository
+	Event      EventRepository
+	Scene      SceneRepository
+	Credential CredentialRepository
+
+	ConfigSnapshot ConfigSnapshotRepository
+	Lease          LeaseRepository
+	ClusterNode    ClusterNodeRepository
+	Audit          AuditRepository
+
+	// Health is nil for drivers without a connection to monitor
+	Health HealthMonitor
+	// Maintenance is nil for drivers without storage to compact
+	Maintenance MaintenanceRepository
+}
+
+// HealthMonitor watches database connectivity
+type HealthMonitor interface {
+	Start()
+	Stop()
+	Status() domain.DatabaseStatus
+}
+
+// MaintenanceRepository runs storage maintenance on the database
+type MaintenanceRepository interface {
+	// Size returns the bytes the database occupies
+	Size(ctx context.Context) (int64, error)
+	// Vacuum reclaims the space of deleted rows
+	Vacuum(ctx context.Context) error
+}
+
 // DeviceRepository defines the interface for device persistence
 type DeviceRepository interface {
 	Create(ctx context.Context, device *domain.Device) error
@@ -15,6 +59,7 @@ type DeviceRepository interface {
 	Update(ctx context.Context, device *domain.Device) error
 	Delete(ctx context.Context, id string) error
 	UpdateLastSeen(ctx context.Context, id string) error
+	RecordError(ctx context.Context, id, message string) error
 }
 
 // ProfileRepository defines the interface for profile persistence
@@ -35,6 +80,9 @@ type TrapLogRepository interface {
 	GetByID(ctx context.Context, id string) (*domain.TrapLog, error)
 	GetByDeviceID(ctx context.Context, deviceID string, limit, offset int) ([]domain.TrapLog, error)
 	GetAll(ctx context.Context, filter domain.TrapFilter) ([]domain.TrapLog, int64, error)
+	Stream(ctx context.Context, filter domain.TrapFilter, fn func(*domain.TrapLog) error) error
+	Counts(ctx context.Context, filter domain.TrapFilter) (*domain.TrapCounts, error)
+	LastReceived(ctx context.Context) (map[string]time.Time, error)
 	DeleteOlderThan(ctx context.Context, days int) (int64, error)
 }
 
@@ -45,3 +93,65 @@ type SettingRepository interface {
 	GetAll(ctx context.Context) ([]domain.Setting, error)
 	Delete(ctx context.Context, key string) error
 }
+
+// EventRepository defines the interface for device event persistence
+type EventRepository interface {
+	Create(ctx context.Context, event *domain.DeviceEvent) error
+	GetAll(ctx context.Context, filter domain.EventFilter) ([]domain.DeviceEvent, int64, error)
+	DeleteOlderThan(ctx context.Context, days int) (int64, error)
+}
+
+// AuditRepository defines the interface for audit trail persistence
+type AuditRepository interface {
+	Create(ctx context.Context, entry *domain.AuditEntry) error
+	GetAll(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, int64, error)
+	DeleteOlderThan(ctx context.Context, days int) (int64, error)
+}
+
+// SceneRepository defines the interface for scene persistence
+type SceneRepository interface {
+	Create(ctx context.Context, scene *domain.Scene) error
+	GetByID(ctx context.Context, id string) (*domain.Scene, error)
+	GetAll(ctx context.Context) ([]domain.Scene, error)
+	Update(ctx context.Context, scene *domain.Scene) error
+	Delete(ctx context.Context, id string) error
+}
+
+// CredentialRepository defines the interface for shared credential persistence
+type CredentialRepository interface {
+	Create(ctx context.Context, credential *domain.Credential) error
+	GetByID(ctx context.Context, id string) (*domain.Credential, error)
+	GetAll(ctx context.Context) ([]domain.Credential, error)
+	Update(ctx context.Context, credential *domain.Credential) error
+	Delete(ctx context.Context, id string) error
+}
+
+// ConfigSnapshotRepository defines the interface for device configuration
+// snapshot persistence
+type ConfigSnapshotRepository interface {
+	Create(ctx context.Context, snapshot *domain.ConfigSnapshot) error
+	// GetByDevice returns a device's snapshots, newest first; limit 0 returns all
+	GetByDevice(ctx context.Context, deviceID string, limit int) ([]domain.ConfigSnapshot, error)
+	DeleteByDevice(ctx context.Context, deviceID string) error
+}
+
+// LeaseRepository defines the interface for leases shared by bridge instances
+type LeaseRepository interface {
+	// Acquire takes the named lease for holder until now+ttl, or renews it when
+	// holder already has it; it reports false while another holder's lease
+	// has not expired
+	Acquire(ctx context.Context, name, holder string, now time.Time, ttl time.Duration) (bool, error)
+	// Release gives up the lease if holder has it
+	Release(ctx context.Context, name, holder string) error
+	Get(ctx context.Context, name string) (*domain.Lease, error)
+}
+
+// ClusterNodeRepository defines the interface for the heartbeats of sharded
+// cluster nodes
+type ClusterNodeRepository interface {
+	// Heartbeat creates the node or updates its tags and last seen time
+	Heartbeat(ctx context.Context, node *domain.ClusterNode) error
+	// GetAlive returns the nodes seen since the given time, by ID
+	GetAlive(ctx context.Context, since time.Time) ([]domain.ClusterNode, error)
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type auditRepository struct {
+	db     *gorm.DB
+	health *Monitor
+}
+
+// NewAuditRepository creates a new audit trail repository
+func NewAuditRepository(db *gorm.DB) repository.AuditRepository {
+	return &auditRepository{db: db, health: MonitorOf(db)}
+}
+
+// Create stores an entry, buffering it in memory while the database is down
+func (r *auditRepository) Create(ctx context.Context, entry *domain.AuditEntry) error {
+	row := *entry
+	return r.health.write(ctx, func(ctx context.Context) error {
+		return r.db.WithContext(ctx).Create(&row).Error
+	})
+}
+
+func (r *auditRepository) GetAll(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, int64, error) {
+	var entries []domain.AuditEntry
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.AuditEntry{})
+
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Target != "" {
+		query = query.Where("target = ?", filter.Target)
+	}
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if filter.StartTime != nil {
+		query = query.Where("created_at >= ?", filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		query = query.Where("created_at <= ?", filter.EndTime)
+	}
+
+	if err := r.health.retry(ctx, func() error { return query.Count(&total).Error }); err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Order("created_at DESC")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+
+	if err := r.health.retry(ctx, func() error { return query.Find(&entries).Error }); err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+func (r *auditRepository) DeleteOlderThan(ctx context.Context, days int) (int64, error) {
+	cutoff := time.Now().AddDate(0, 0, -days)
+	var deleted int64
+	err := r.health.retry(ctx, func() error {
+		result := r.db.WithContext(ctx).Where("created_at < ?", cutoff).Delete(&domain.AuditEntry{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
@@ -0,0 +1,47 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type clusterNodeRepository struct {
+	db     *gorm.DB
+	health *Monitor
+}
+
+// NewClusterNodeRepository creates a new cluster node repository
+func NewClusterNodeRepository(db *gorm.DB) repository.ClusterNodeRepository {
+	return &clusterNodeRepository{db: db, health: MonitorOf(db)}
+}
+
+func (r *clusterNodeRepository) Heartbeat(ctx context.Context, node *domain.ClusterNode) error {
+	return r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"tags", "seen_at"}),
+		}).Create(node).Error
+	})
+}
+
+func (r *clusterNodeRepository) GetAlive(ctx context.Context, since time.Time) ([]domain.ClusterNode, error) {
+	var nodes []domain.ClusterNode
+	if err := r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Where("seen_at >= ?", since.UTC()).Order("id").Find(&nodes).Error
+	}); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+func (r *clusterNodeRepository) Delete(ctx context.Context, id string) error {
+	return r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Delete(&domain.ClusterNode{}, "id = ?", id).Error
+	})
+}
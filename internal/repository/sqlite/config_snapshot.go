@@ -0,0 +1,46 @@
+package sqlite
+
+import (
+	"context"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type configSnapshotRepository struct {
+	db     *gorm.DB
+	health *Monitor
+}
+
+// NewConfigSnapshotRepository creates a new configuration snapshot repository
+func NewConfigSnapshotRepository(db *gorm.DB) repository.ConfigSnapshotRepository {
+	return &configSnapshotRepository{db: db, health: MonitorOf(db)}
+}
+
+func (r *configSnapshotRepository) Create(ctx context.Context, snapshot *domain.ConfigSnapshot) error {
+	return r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Create(snapshot).Error
+	})
+}
+
+func (r *configSnapshotRepository) GetByDevice(ctx context.Context, deviceID string, limit int) ([]domain.ConfigSnapshot, error) {
+	var snapshots []domain.ConfigSnapshot
+	if err := r.health.retry(ctx, func() error {
+		query := r.db.WithContext(ctx).Where("device_id = ?", deviceID).Order("created_at DESC")
+		if limit > 0 {
+			query = query.Limit(limit)
+		}
+		return query.Find(&snapshots).Error
+	}); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+func (r *configSnapshotRepository) DeleteByDevice(ctx context.Context, deviceID string) error {
+	return r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Delete(&domain.ConfigSnapshot{}, "device_id = ?", deviceID).Error
+	})
+}
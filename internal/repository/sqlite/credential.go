@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"context"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type credentialRepository struct {
+	db     *gorm.DB
+	health *Monitor
+}
+
+// NewCredentialRepository creates a new credential repository
+func NewCredentialRepository(db *gorm.DB) repository.CredentialRepository {
+	return &credentialRepository{db: db, health: MonitorOf(db)}
+}
+
+func (r *credentialRepository) Create(ctx context.Context, credential *domain.Credential) error {
+	return r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Create(credential).Error
+	})
+}
+
+func (r *credentialRepository) GetByID(ctx context.Context, id string) (*domain.Credential, error) {
+	var credential domain.Credential
+	if err := r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).First(&credential, "id = ?", id).Error
+	}); err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r *credentialRepository) GetAll(ctx context.Context) ([]domain.Credential, error) {
+	var credentials []domain.Credential
+	if err := r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Order("name").Find(&credentials).Error
+	}); err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+func (r *credentialRepository) Update(ctx context.Context, credential *domain.Credential) error {
+	return r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Save(credential).Error
+	})
+}
+
+func (r *credentialRepository) Delete(ctx context.Context, id string) error {
+	return r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Delete(&domain.Credential{}, "id = ?", id).Error
+	})
+}
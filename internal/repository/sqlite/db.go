@@ -3,6 +3,8 @@ package sqlite
 import (
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"snmp-mqtt-bridge/internal/config"
 	"snmp-mqtt-bridge/internal/domain"
@@ -29,7 +31,7 @@ func NewDB(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 				return nil, err
 			}
 		}
-		dialector = sqlite.Open(dsn)
+		dialector = sqlite.Open(withBusyTimeout(dsn))
 	}
 
 	db, err := gorm.Open(dialector, &gorm.Config{
@@ -39,6 +41,21 @@ func NewDB(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 		return nil, err
 	}
 
+	// Track connectivity and retry transient errors in repositories
+	if err := db.Use(newMonitor(cfg)); err != nil {
+		return nil, err
+	}
+
+	// Recycle connections so ones broken by a server restart are not kept around
+	if cfg.Driver == "postgres" {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetConnMaxLifetime(30 * time.Minute)
+		sqlDB.SetConnMaxIdleTime(5 * time.Minute)
+	}
+
 	// Run migrations
 	if err := migrate(db); err != nil {
 		return nil, err
@@ -47,11 +64,32 @@ func NewDB(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 	return db, nil
 }
 
+// withBusyTimeout makes SQLite wait for a lock held by another connection
+// instead of failing at once with "database is locked", unless the DSN
+// already sets a busy timeout
+func withBusyTimeout(dsn string) string {
+	if strings.Contains(dsn, "busy_timeout") {
+		return dsn
+	}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + "_pragma=busy_timeout(5000)"
+}
+
 func migrate(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&domain.Device{},
 		&domain.Profile{},
 		&domain.TrapLog{},
 		&domain.Setting{},
+		&domain.DeviceEvent{},
+		&domain.Scene{},
+		&domain.Credential{},
+		&domain.ConfigSnapshot{},
+		&domain.Lease{},
+		&domain.ClusterNode{},
+		&domain.AuditEntry{},
 	)
 }
@@ -11,21 +11,26 @@ import (
 )
 
 type deviceRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	health *Monitor
 }
 
 // NewDeviceRepository creates a new device repository
 func NewDeviceRepository(db *gorm.DB) repository.DeviceRepository {
-	return &deviceRepository{db: db}
+	return &deviceRepository{db: db, health: MonitorOf(db)}
 }
 
 func (r *deviceRepository) Create(ctx context.Context, device *domain.Device) error {
-	return r.db.WithContext(ctx).Create(device).Error
+	return r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Create(device).Error
+	})
 }
 
 func (r *deviceRepository) GetByID(ctx context.Context, id string) (*domain.Device, error) {
 	var device domain.Device
-	if err := r.db.WithContext(ctx).First(&device, "id = ?", id).Error; err != nil {
+	if err := r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).First(&device, "id = ?", id).Error
+	}); err != nil {
 		return nil, err
 	}
 	return &device, nil
@@ -33,7 +38,9 @@ func (r *deviceRepository) GetByID(ctx context.Context, id string) (*domain.Devi
 
 func (r *deviceRepository) GetAll(ctx context.Context) ([]domain.Device, error) {
 	var devices []domain.Device
-	if err := r.db.WithContext(ctx).Find(&devices).Error; err != nil {
+	if err := r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Find(&devices).Error
+	}); err != nil {
 		return nil, err
 	}
 	return devices, nil
@@ -41,21 +48,47 @@ func (r *deviceRepository) GetAll(ctx context.Context) ([]domain.Device, error)
 
 func (r *deviceRepository) GetEnabled(ctx context.Context) ([]domain.Device, error) {
 	var devices []domain.Device
-	if err := r.db.WithContext(ctx).Where("enabled = ?", true).Find(&devices).Error; err != nil {
+	if err := r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Where("enabled = ?", true).Find(&devices).Error
+	}); err != nil {
 		return nil, err
 	}
 	return devices, nil
 }
 
 func (r *deviceRepository) Update(ctx context.Context, device *domain.Device) error {
-	return r.db.WithContext(ctx).Save(device).Error
+	return r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Save(device).Error
+	})
 }
 
 func (r *deviceRepository) Delete(ctx context.Context, id string) error {
-	return r.db.WithContext(ctx).Delete(&domain.Device{}, "id = ?", id).Error
+	return r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Delete(&domain.Device{}, "id = ?", id).Error
+	})
 }
 
+// UpdateLastSeen is called on every successful poll, so it is not retried:
+// a failure here must not delay polling while the database is down
 func (r *deviceRepository) UpdateLastSeen(ctx context.Context, id string) error {
+	if !r.health.Healthy() {
+		r.health.skip()
+		return nil
+	}
 	now := time.Now()
 	return r.db.WithContext(ctx).Model(&domain.Device{}).Where("id = ?", id).Update("last_seen", &now).Error
 }
+
+// RecordError stores a failed poll. Like UpdateLastSeen it is not retried.
+func (r *deviceRepository) RecordError(ctx context.Context, id, message string) error {
+	if !r.health.Healthy() {
+		r.health.skip()
+		return nil
+	}
+	now := time.Now()
+	return r.db.WithContext(ctx).Model(&domain.Device{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_error":    message,
+		"last_error_at": &now,
+		"error_count":   gorm.Expr("error_count + 1"),
+	}).Error
+}
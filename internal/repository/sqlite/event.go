@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type eventRepository struct {
+	db     *gorm.DB
+	health *Monitor
+}
+
+// NewEventRepository creates a new device event repository
+func NewEventRepository(db *gorm.DB) repository.EventRepository {
+	return &eventRepository{db: db, health: MonitorOf(db)}
+}
+
+// Create stores an event, buffering it in memory while the database is down
+func (r *eventRepository) Create(ctx context.Context, event *domain.DeviceEvent) error {
+	row := *event
+	return r.health.write(ctx, func(ctx context.Context) error {
+		return r.db.WithContext(ctx).Create(&row).Error
+	})
+}
+
+func (r *eventRepository) GetAll(ctx context.Context, filter domain.EventFilter) ([]domain.DeviceEvent, int64, error) {
+	var events []domain.DeviceEvent
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.DeviceEvent{})
+
+	if filter.DeviceID != "" {
+		query = query.Where("device_id = ?", filter.DeviceID)
+	}
+	if len(filter.DeviceIDs) > 0 {
+		query = query.Where("device_id IN ?", filter.DeviceIDs)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.StartTime != nil {
+		query = query.Where("created_at >= ?", filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		query = query.Where("created_at <= ?", filter.EndTime)
+	}
+
+	if err := r.health.retry(ctx, func() error { return query.Count(&total).Error }); err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Order("created_at DESC")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+
+	if err := r.health.retry(ctx, func() error { return query.Find(&events).Error }); err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+func (r *eventRepository) DeleteOlderThan(ctx context.Context, days int) (int64, error) {
+	cutoff := time.Now().AddDate(0, 0, -days)
+	var deleted int64
+	err := r.health.retry(ctx, func() error {
+		result := r.db.WithContext(ctx).Where("created_at < ?", cutoff).Delete(&domain.DeviceEvent{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
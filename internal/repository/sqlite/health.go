@@ -0,0 +1,400 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"snmp-mqtt-bridge/internal/config"
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+const (
+	monitorPluginName = "snmp-bridge:health"
+	pingTimeout       = 5 * time.Second
+	flushTimeout      = 30 * time.Second
+	maxRetryBackoff   = 2 * time.Second
+)
+
+// Monitor tracks database connectivity. While the database is up, transient
+// errors are retried with backoff; once it is down, operations fail fast so
+// polling is not held up, and event and trap writes are buffered in memory
+// until a health check sees the database again. It is registered on the
+// gorm.DB as a plugin so every repository created from it shares one monitor.
+type Monitor struct {
+	db       *gorm.DB
+	driver   string
+	interval time.Duration
+	attempts int
+	capacity int
+
+	healthy   atomic.Bool
+	retries   atomic.Uint64
+	flushed   atomic.Uint64
+	dropped   atomic.Uint64
+	mu        sync.Mutex
+	lastError string
+	lastCheck *time.Time
+	downSince *time.Time
+	pending   []func(ctx context.Context) error
+	flushing  bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+var _ repository.HealthMonitor = (*Monitor)(nil)
+
+func newMonitor(cfg *config.DatabaseConfig) *Monitor {
+	driver := cfg.Driver
+	if driver == "" {
+		driver = "sqlite"
+	}
+	interval := cfg.HealthCheckInterval
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	capacity := cfg.WriteBufferSize
+	if capacity < 0 {
+		capacity = 0
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Monitor{
+		driver:   driver,
+		interval: interval,
+		attempts: cfg.RetryAttempts,
+		capacity: capacity,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	m.healthy.Store(true)
+	return m
+}
+
+// Name implements gorm.Plugin
+func (m *Monitor) Name() string {
+	return monitorPluginName
+}
+
+// Initialize implements gorm.Plugin
+func (m *Monitor) Initialize(db *gorm.DB) error {
+	m.db = db
+	return nil
+}
+
+// MonitorOf returns the monitor registered on a database opened by NewDB
+func MonitorOf(db *gorm.DB) *Monitor {
+	m, _ := db.Config.Plugins[monitorPluginName].(*Monitor)
+	return m
+}
+
+// Start starts the periodic health checks
+func (m *Monitor) Start() {
+	m.wg.Add(1)
+	go m.run()
+}
+
+// Stop stops the health checks. Writes still buffered are dropped.
+func (m *Monitor) Stop() {
+	m.cancel()
+	m.wg.Wait()
+
+	m.mu.Lock()
+	if n := len(m.pending); n > 0 {
+		log.Printf("Database: dropping %d buffered writes on shutdown", n)
+		m.dropped.Add(uint64(n))
+		m.pending = nil
+	}
+	m.mu.Unlock()
+}
+
+// Healthy reports whether the database was reachable at the last check
+func (m *Monitor) Healthy() bool {
+	return m == nil || m.healthy.Load()
+}
+
+// Status returns connectivity and buffering counters
+func (m *Monitor) Status() domain.DatabaseStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return domain.DatabaseStatus{
+		Driver:         m.driver,
+		Healthy:        m.healthy.Load(),
+		LastError:      m.lastError,
+		LastCheck:      m.lastCheck,
+		DownSince:      m.downSince,
+		Retries:        m.retries.Load(),
+		BufferedWrites: len(m.pending),
+		FlushedWrites:  m.flushed.Load(),
+		DroppedWrites:  m.dropped.Load(),
+	}
+}
+
+func (m *Monitor) run() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.check()
+		}
+	}
+}
+
+// check pings the database and flushes buffered writes once it is back
+func (m *Monitor) check() {
+	err := m.ping()
+
+	now := time.Now()
+	m.mu.Lock()
+	m.lastCheck = &now
+	m.mu.Unlock()
+
+	if err != nil {
+		m.markDown(err)
+		return
+	}
+	m.markUp()
+	m.flush()
+}
+
+func (m *Monitor) ping() error {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(m.ctx, pingTimeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
+
+func (m *Monitor) markDown(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastError = err.Error()
+	if m.healthy.Swap(false) {
+		now := time.Now()
+		m.downSince = &now
+		log.Printf("Database unavailable, continuing in degraded mode: %v", err)
+	}
+}
+
+func (m *Monitor) markUp() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.healthy.Swap(true) {
+		log.Printf("Database connection restored after %s", time.Since(*m.downSince).Round(time.Second))
+		m.downSince = nil
+		m.lastError = ""
+	}
+}
+
+// retry runs op, retrying transient errors with exponential backoff while the
+// database is considered healthy. A transient failure marks it down; a busy
+// SQLite database is retried the same way but stays up, since it answered.
+func (m *Monitor) retry(ctx context.Context, op func() error) error {
+	err := op()
+	if m == nil {
+		return err
+	}
+
+	for attempt := 0; err != nil && m.retryable(err) && attempt < m.attempts; attempt++ {
+		m.retries.Add(1)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(retryBackoff(attempt)):
+		}
+		err = op()
+	}
+
+	if err != nil && isTransient(err) {
+		m.markDown(err)
+	}
+	return err
+}
+
+func (m *Monitor) retryable(err error) bool {
+	return isBusy(err) || (isTransient(err) && m.Healthy())
+}
+
+// write runs an insert through retry and buffers it if the database is
+// unavailable. While writes are pending, new ones queue behind them to keep
+// their order. When the buffer is full the oldest write is dropped.
+func (m *Monitor) write(ctx context.Context, op func(ctx context.Context) error) error {
+	if m == nil {
+		return op(ctx)
+	}
+
+	m.mu.Lock()
+	queued := len(m.pending) > 0 || m.flushing
+	m.mu.Unlock()
+
+	if !queued {
+		err := m.retry(ctx, func() error { return op(ctx) })
+		if err == nil || !isTransient(err) || m.capacity == 0 {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending = append(m.pending, op)
+	m.trimPending()
+	return nil
+}
+
+// skip counts a write deliberately not attempted while the database is down
+func (m *Monitor) skip() {
+	if m != nil {
+		m.dropped.Add(1)
+	}
+}
+
+// trimPending drops the oldest buffered writes beyond capacity; m.mu must be held
+func (m *Monitor) trimPending() {
+	if excess := len(m.pending) - m.capacity; excess > 0 {
+		m.pending = m.pending[excess:]
+		m.dropped.Add(uint64(excess))
+	}
+}
+
+// flush replays buffered writes in order, stopping at the first transient
+// failure and putting the rest back in front of writes queued meanwhile
+func (m *Monitor) flush() {
+	var stored uint64
+	defer func() {
+		if stored > 0 {
+			log.Printf("Database: stored %d buffered writes", stored)
+		}
+	}()
+
+	for {
+		m.mu.Lock()
+		batch := m.pending
+		m.pending = nil
+		m.flushing = len(batch) > 0
+		m.mu.Unlock()
+
+		if len(batch) == 0 {
+			return
+		}
+
+		for i, op := range batch {
+			ctx, cancel := context.WithTimeout(m.ctx, flushTimeout)
+			err := op(ctx)
+			cancel()
+
+			if err != nil && (isTransient(err) || isBusy(err)) {
+				if isTransient(err) {
+					m.markDown(err)
+				}
+				m.mu.Lock()
+				m.pending = append(batch[i:], m.pending...)
+				m.trimPending()
+				m.flushing = false
+				m.mu.Unlock()
+				return
+			}
+			if err != nil {
+				log.Printf("Database: dropping buffered write: %v", err)
+				m.dropped.Add(1)
+				continue
+			}
+			m.flushed.Add(1)
+			stored++
+		}
+	}
+}
+
+func retryBackoff(attempt int) time.Duration {
+	backoff := 100 * time.Millisecond << attempt
+	if backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return backoff
+}
+
+// isTransient reports whether an error is a connectivity problem that may
+// succeed on retry, as opposed to a query or constraint error
+func isTransient(err error) bool {
+	if err == nil || errors.Is(err, gorm.ErrRecordNotFound) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	// Class 08 is connection exception; 57P01-03 are server shutdown and startup
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, "08") ||
+			pgErr.Code == "57P01" || pgErr.Code == "57P02" || pgErr.Code == "57P03"
+	}
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"connection refused", "connection reset", "broken pipe", "bad connection", "conn closed"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// SQLite result codes for a lock held by another connection or by another
+// statement on the same connection
+const (
+	sqliteBusy   = 5
+	sqliteLocked = 6
+)
+
+// isBusy reports whether SQLite refused an operation because the database was
+// locked. It is reachable, so the operation is worth retrying, but the
+// database must not be treated as down.
+func isBusy(err error) bool {
+	if err == nil {
+		return false
+	}
+	var coded interface{ Code() int }
+	if errors.As(err, &coded) {
+		// Extended result codes keep the primary code in the low byte
+		if code := coded.Code() & 0xff; code == sqliteBusy || code == sqliteLocked {
+			return true
+		}
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "database table is locked")
+}
@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+
+	"snmp-mqtt-bridge/internal/config"
+)
+
+// codedError mimics the driver's error, which carries the SQLite result code
+type codedError struct{ code int }
+
+func (e *codedError) Error() string { return fmt.Sprintf("sqlite error %d", e.code) }
+func (e *codedError) Code() int     { return e.code }
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		transient bool
+		busy      bool
+	}{
+		{"locked message", errors.New("database is locked (5) (SQLITE_BUSY)"), false, true},
+		{"busy code", &codedError{code: sqliteBusy}, false, true},
+		{"extended busy code", &codedError{code: sqliteBusy | 2<<8}, false, true},
+		{"constraint", &codedError{code: 19}, false, false},
+		{"connection refused", fmt.Errorf("dial: %w", syscall.ECONNREFUSED), true, false},
+		{"canceled", context.Canceled, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.err); got != tt.transient {
+				t.Errorf("isTransient = %v, want %v", got, tt.transient)
+			}
+			if got := isBusy(tt.err); got != tt.busy {
+				t.Errorf("isBusy = %v, want %v", got, tt.busy)
+			}
+		})
+	}
+}
+
+func TestRetryKeepsBusyDatabaseUp(t *testing.T) {
+	m := newMonitor(&config.DatabaseConfig{RetryAttempts: 3})
+
+	calls := 0
+	err := m.retry(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("database is locked")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("op ran %d times, want 3", calls)
+	}
+	if !m.Healthy() {
+		t.Error("a busy database was marked down")
+	}
+
+	err = m.retry(context.Background(), func() error { return errors.New("database is locked") })
+	if err == nil {
+		t.Fatal("retry succeeded although the database stayed locked")
+	}
+	if !m.Healthy() {
+		t.Error("a database still locked after every retry was marked down")
+	}
+}
+
+func TestRetryMarksConnectionFailureDown(t *testing.T) {
+	m := newMonitor(&config.DatabaseConfig{RetryAttempts: 1})
+
+	err := m.retry(context.Background(), func() error { return syscall.ECONNREFUSED })
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Fatalf("retry error = %v, want connection refused", err)
+	}
+	if m.Healthy() {
+		t.Error("database still healthy after a connection failure")
+	}
+}
+
+func TestWithBusyTimeout(t *testing.T) {
+	tests := map[string]string{
+		"data/bridge.db":                          "data/bridge.db?_pragma=busy_timeout(5000)",
+		"data/bridge.db?_pragma=foreign_keys(1)":  "data/bridge.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)",
+		"data/bridge.db?_pragma=busy_timeout(50)": "data/bridge.db?_pragma=busy_timeout(50)",
+	}
+	for dsn, want := range tests {
+		if got := withBusyTimeout(dsn); got != want {
+			t.Errorf("withBusyTimeout(%q) = %q, want %q", dsn, got, want)
+		}
+	}
+}
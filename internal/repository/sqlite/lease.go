@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type leaseRepository struct {
+	db     *gorm.DB
+	health *Monitor
+}
+
+// NewLeaseRepository creates a new lease repository
+func NewLeaseRepository(db *gorm.DB) repository.LeaseRepository {
+	return &leaseRepository{db: db, health: MonitorOf(db)}
+}
+
+// Acquire relies on single-statement atomicity: the conditional update only
+// matches a lease that is ours or expired, and the insert only succeeds when
+// nobody has created the lease yet, so two instances cannot both win
+func (r *leaseRepository) Acquire(ctx context.Context, name, holder string, now time.Time, ttl time.Duration) (bool, error) {
+	now = now.UTC()
+	var acquired bool
+	err := r.health.retry(ctx, func() error {
+		result := r.db.WithContext(ctx).Model(&domain.Lease{}).
+			Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now).
+			Updates(map[string]interface{}{"holder": holder, "expires_at": now.Add(ttl), "updated_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			acquired = true
+			return nil
+		}
+
+		result = r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&domain.Lease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl), UpdatedAt: now})
+		acquired = result.RowsAffected > 0
+		return result.Error
+	})
+	return acquired, err
+}
+
+func (r *leaseRepository) Release(ctx context.Context, name, holder string) error {
+	return r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Delete(&domain.Lease{}, "name = ? AND holder = ?", name, holder).Error
+	})
+}
+
+func (r *leaseRepository) Get(ctx context.Context, name string) (*domain.Lease, error) {
+	var lease domain.Lease
+	if err := r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).First(&lease, "name = ?", name).Error
+	}); err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"context"
+
+	"snmp-mqtt-bridge/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type maintenanceRepository struct {
+	db       *gorm.DB
+	health   *Monitor
+	postgres bool
+}
+
+// NewMaintenanceRepository creates a new maintenance repository
+func NewMaintenanceRepository(db *gorm.DB) repository.MaintenanceRepository {
+	return &maintenanceRepository{db: db, health: MonitorOf(db), postgres: db.Dialector.Name() == "postgres"}
+}
+
+// Size returns the size of the SQLite file's pages, or of the PostgreSQL
+// database on disk
+func (r *maintenanceRepository) Size(ctx context.Context) (int64, error) {
+	var size int64
+	err := r.health.retry(ctx, func() error {
+		if r.postgres {
+			return r.db.WithContext(ctx).Raw("SELECT pg_database_size(current_database())").Scan(&size).Error
+		}
+		var pages, pageSize int64
+		if err := r.db.WithContext(ctx).Raw("PRAGMA page_count").Scan(&pages).Error; err != nil {
+			return err
+		}
+		if err := r.db.WithContext(ctx).Raw("PRAGMA page_size").Scan(&pageSize).Error; err != nil {
+			return err
+		}
+		size = pages * pageSize
+		return nil
+	})
+	return size, err
+}
+
+// Vacuum rebuilds the SQLite file without its free pages. On PostgreSQL a
+// plain VACUUM marks dead rows reusable without locking tables; the files
+// only shrink when their trailing pages are freed.
+func (r *maintenanceRepository) Vacuum(ctx context.Context) error {
+	return r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Exec("VACUUM").Error
+	})
+}
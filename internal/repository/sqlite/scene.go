@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"context"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type sceneRepository struct {
+	db     *gorm.DB
+	health *Monitor
+}
+
+// NewSceneRepository creates a new scene repository
+func NewSceneRepository(db *gorm.DB) repository.SceneRepository {
+	return &sceneRepository{db: db, health: MonitorOf(db)}
+}
+
+func (r *sceneRepository) Create(ctx context.Context, scene *domain.Scene) error {
+	return r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Create(scene).Error
+	})
+}
+
+func (r *sceneRepository) GetByID(ctx context.Context, id string) (*domain.Scene, error) {
+	var scene domain.Scene
+	if err := r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).First(&scene, "id = ?", id).Error
+	}); err != nil {
+		return nil, err
+	}
+	return &scene, nil
+}
+
+func (r *sceneRepository) GetAll(ctx context.Context) ([]domain.Scene, error) {
+	var scenes []domain.Scene
+	if err := r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Order("name").Find(&scenes).Error
+	}); err != nil {
+		return nil, err
+	}
+	return scenes, nil
+}
+
+func (r *sceneRepository) Update(ctx context.Context, scene *domain.Scene) error {
+	return r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Save(scene).Error
+	})
+}
+
+func (r *sceneRepository) Delete(ctx context.Context, id string) error {
+	return r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).Delete(&domain.Scene{}, "id = ?", id).Error
+	})
+}
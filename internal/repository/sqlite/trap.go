@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"snmp-mqtt-bridge/internal/domain"
@@ -11,21 +12,28 @@ import (
 )
 
 type trapLogRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	health *Monitor
 }
 
 // NewTrapLogRepository creates a new trap log repository
 func NewTrapLogRepository(db *gorm.DB) repository.TrapLogRepository {
-	return &trapLogRepository{db: db}
+	return &trapLogRepository{db: db, health: MonitorOf(db)}
 }
 
+// Create stores a trap, buffering it in memory while the database is down
 func (r *trapLogRepository) Create(ctx context.Context, trap *domain.TrapLog) error {
-	return r.db.WithContext(ctx).Create(trap).Error
+	row := *trap
+	return r.health.write(ctx, func(ctx context.Context) error {
+		return r.db.WithContext(ctx).Create(&row).Error
+	})
 }
 
 func (r *trapLogRepository) GetByID(ctx context.Context, id string) (*domain.TrapLog, error) {
 	var trap domain.TrapLog
-	if err := r.db.WithContext(ctx).First(&trap, "id = ?", id).Error; err != nil {
+	if err := r.health.retry(ctx, func() error {
+		return r.db.WithContext(ctx).First(&trap, "id = ?", id).Error
+	}); err != nil {
 		return nil, err
 	}
 	return &trap, nil
@@ -42,7 +50,7 @@ func (r *trapLogRepository) GetByDeviceID(ctx context.Context, deviceID string,
 		query = query.Offset(offset)
 	}
 
-	if err := query.Find(&traps).Error; err != nil {
+	if err := r.health.retry(ctx, func() error { return query.Find(&traps).Error }); err != nil {
 		return nil, err
 	}
 	return traps, nil
@@ -52,23 +60,10 @@ func (r *trapLogRepository) GetAll(ctx context.Context, filter domain.TrapFilter
 	var traps []domain.TrapLog
 	var total int64
 
-	query := r.db.WithContext(ctx).Model(&domain.TrapLog{})
-
-	if filter.DeviceID != "" {
-		query = query.Where("device_id = ?", filter.DeviceID)
-	}
-	if filter.Severity != "" {
-		query = query.Where("severity = ?", filter.Severity)
-	}
-	if filter.StartTime != nil {
-		query = query.Where("received_at >= ?", filter.StartTime)
-	}
-	if filter.EndTime != nil {
-		query = query.Where("received_at <= ?", filter.EndTime)
-	}
+	query := r.filtered(ctx, filter)
 
 	// Get total count
-	if err := query.Count(&total).Error; err != nil {
+	if err := r.health.retry(ctx, func() error { return query.Count(&total).Error }); err != nil {
 		return nil, 0, err
 	}
 
@@ -81,15 +76,144 @@ func (r *trapLogRepository) GetAll(ctx context.Context, filter domain.TrapFilter
 		query = query.Offset(filter.Offset)
 	}
 
-	if err := query.Find(&traps).Error; err != nil {
+	if err := r.health.retry(ctx, func() error { return query.Find(&traps).Error }); err != nil {
 		return nil, 0, err
 	}
 
 	return traps, total, nil
 }
 
+// Stream calls fn for each trap matching filter, newest first, reading rows
+// one at a time so large exports do not load the whole history into memory
+func (r *trapLogRepository) Stream(ctx context.Context, filter domain.TrapFilter, fn func(*domain.TrapLog) error) error {
+	query := r.filtered(ctx, filter).Order("received_at DESC")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+
+	var rows *sql.Rows
+	if err := r.health.retry(ctx, func() error {
+		var err error
+		rows, err = query.Rows()
+		return err
+	}); err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var trap domain.TrapLog
+		if err := r.db.ScanRows(rows, &trap); err != nil {
+			return err
+		}
+		if err := fn(&trap); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Counts groups the traps matching filter by severity, device and trap OID
+func (r *trapLogRepository) Counts(ctx context.Context, filter domain.TrapFilter) (*domain.TrapCounts, error) {
+	counts := &domain.TrapCounts{
+		BySeverity: make(map[domain.TrapSeverity]int64),
+		ByDevice:   make(map[string]int64),
+		ByOID:      make(map[string]int64),
+	}
+
+	type group struct {
+		Name  string
+		Count int64
+	}
+	countBy := func(column string, add func(name string, count int64)) error {
+		var groups []group
+		if err := r.health.retry(ctx, func() error {
+			groups = nil
+			return r.filtered(ctx, filter).
+				Select(column + " AS name, COUNT(*) AS count").
+				Group(column).
+				Scan(&groups).Error
+		}); err != nil {
+			return err
+		}
+		for _, g := range groups {
+			add(g.Name, g.Count)
+		}
+		return nil
+	}
+
+	if err := countBy("severity", func(name string, count int64) {
+		counts.BySeverity[domain.TrapSeverity(name)] = count
+	}); err != nil {
+		return nil, err
+	}
+	if err := countBy("COALESCE(device_id, '')", func(name string, count int64) {
+		counts.ByDevice[name] = count
+	}); err != nil {
+		return nil, err
+	}
+	// GORM's naming strategy stores TrapOID as trap_o_id
+	if err := countBy("trap_o_id", func(name string, count int64) {
+		counts.ByOID[name] = count
+	}); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// LastReceived returns when each device last sent a trap
+func (r *trapLogRepository) LastReceived(ctx context.Context) (map[string]time.Time, error) {
+	var traps []domain.TrapLog
+	if err := r.health.retry(ctx, func() error {
+		// Latest trap per device, without relying on MAX() keeping the column type
+		return r.db.WithContext(ctx).
+			Where("device_id IS NOT NULL AND received_at = (SELECT MAX(t.received_at) FROM trap_logs t WHERE t.device_id = trap_logs.device_id)").
+			Select("device_id, received_at").
+			Find(&traps).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	last := make(map[string]time.Time, len(traps))
+	for _, t := range traps {
+		if t.DeviceID != nil {
+			last[*t.DeviceID] = t.ReceivedAt
+		}
+	}
+	return last, nil
+}
+
+func (r *trapLogRepository) filtered(ctx context.Context, filter domain.TrapFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&domain.TrapLog{})
+
+	if filter.DeviceID != "" {
+		query = query.Where("device_id = ?", filter.DeviceID)
+	}
+	if len(filter.DeviceIDs) > 0 {
+		query = query.Where("device_id IN ?", filter.DeviceIDs)
+	}
+	if filter.Severity != "" {
+		query = query.Where("severity = ?", filter.Severity)
+	}
+	if filter.StartTime != nil {
+		query = query.Where("received_at >= ?", filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		query = query.Where("received_at <= ?", filter.EndTime)
+	}
+	return query
+}
+
 func (r *trapLogRepository) DeleteOlderThan(ctx context.Context, days int) (int64, error) {
 	cutoff := time.Now().AddDate(0, 0, -days)
-	result := r.db.WithContext(ctx).Where("received_at < ?", cutoff).Delete(&domain.TrapLog{})
-	return result.RowsAffected, result.Error
+	var deleted int64
+	err := r.health.retry(ctx, func() error {
+		result := r.db.WithContext(ctx).Where("received_at < ?", cutoff).Delete(&domain.TrapLog{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
 }
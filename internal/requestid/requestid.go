@@ -0,0 +1,52 @@
+// Package requestid carries the ID of the API request being served through
+// contexts, so errors logged while serving it can be matched with the
+// access log and the response
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Header is the HTTP header carrying request IDs in both directions
+const Header = "X-Request-ID"
+
+// maxLength bounds IDs accepted from clients
+const maxLength = 64
+
+type contextKey struct{}
+
+// New returns a random request ID
+func New() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Valid reports whether an ID sent by a client may be reused: short and
+// limited to characters safe to log and echo in a header
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// WithID returns a copy of ctx carrying id
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, or "" outside requests
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
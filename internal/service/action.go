@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+var (
+	// ErrActionNotFound is returned when the device profile has no such action
+	ErrActionNotFound = errors.New("action not found")
+	// ErrConfirmationRequired is returned when running a confirm-flagged action without confirmation
+	ErrConfirmationRequired = errors.New("action requires confirmation")
+)
+
+// ActionService runs profile-defined named actions on devices
+type ActionService struct {
+	deviceRepo  repository.DeviceRepository
+	profileRepo repository.ProfileRepository
+	queue       *CommandQueue
+	poller      *PollerService
+}
+
+// NewActionService creates a new action service
+func NewActionService(
+	deviceRepo repository.DeviceRepository,
+	profileRepo repository.ProfileRepository,
+	queue *CommandQueue,
+	poller *PollerService,
+) *ActionService {
+	return &ActionService{
+		deviceRepo:  deviceRepo,
+		profileRepo: profileRepo,
+		queue:       queue,
+		poller:      poller,
+	}
+}
+
+// List returns the actions available on a device
+func (s *ActionService) List(ctx context.Context, deviceID string) ([]domain.ProfileAction, error) {
+	profile, err := s.profile(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return []domain.ProfileAction{}, nil
+	}
+
+	actions := make([]domain.ProfileAction, 0, len(profile.Actions))
+	for _, a := range profile.Actions {
+		a.ID = a.Key()
+		actions = append(actions, a)
+	}
+	return actions, nil
+}
+
+// Run executes an action on a device. Actions flagged with confirm only run
+// when confirmed is true.
+func (s *ActionService) Run(ctx context.Context, deviceID, key string, confirmed bool) (*domain.ProfileAction, error) {
+	profile, err := s.profile(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	action, ok := profile.Action(key)
+	if !ok {
+		return nil, ErrActionNotFound
+	}
+	if action.Confirm && !confirmed {
+		return action, ErrConfirmationRequired
+	}
+
+	cmd := QueuedCommand{Description: action.Name, OID: action.OID, Value: action.Value, Type: action.Type}
+	if err := s.queue.Send(ctx, deviceID, cmd); err != nil {
+		return action, err
+	}
+
+	log.Printf("Action %q executed on device %s", action.Name, deviceID)
+
+	// Refresh state so any effect of the action shows up quickly
+	s.poller.TriggerPoll(deviceID)
+
+	return action, nil
+}
+
+func (s *ActionService) profile(ctx context.Context, deviceID string) (*domain.Profile, error) {
+	device, err := s.deviceRepo.GetByID(ctx, deviceID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %w", ErrDeviceNotFound, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if device.ProfileID == "" {
+		return nil, nil
+	}
+	profile, err := s.profileRepo.GetByID(ctx, device.ProfileID)
+	if err != nil {
+		return nil, nil
+	}
+	return profile, nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+	"snmp-mqtt-bridge/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// AuditService keeps the audit trail: changes made through the API are
+// recorded by the API as they complete, commands and scene runs received over
+// MQTT, and write lock switches, are picked up from the event bus
+type AuditService struct {
+	repo repository.AuditRepository
+	bus  *eventbus.Bus
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(repo repository.AuditRepository, bus *eventbus.Bus) *AuditService {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &AuditService{
+		repo:   repo,
+		bus:    bus,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start starts recording commands, scene runs and write lock switches
+// received over MQTT
+func (s *AuditService) Start() {
+	sub := s.bus.Subscribe(eventbus.TypeCommand, eventbus.TypeSceneRun, eventbus.TypeWriteLock)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.bus.Unsubscribe(sub)
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case evt, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				switch payload := evt.Payload.(type) {
+				case eventbus.Command:
+					if payload.Source == eventbus.CommandSourceMQTT {
+						s.record(commandEntry(payload))
+					}
+				case *domain.SceneRun:
+					if payload.Source == eventbus.CommandSourceMQTT {
+						s.record(sceneRunEntry(payload))
+					}
+				case *domain.WriteLock:
+					if payload.ChangedBy == domain.AuditSourceMQTT {
+						s.record(writeLockEntry(payload))
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops recording MQTT commands
+func (s *AuditService) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Record stores an audit entry
+func (s *AuditService) Record(ctx context.Context, entry *domain.AuditEntry) error {
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	return s.repo.Create(ctx, entry)
+}
+
+// GetAll retrieves audit entries with filtering
+func (s *AuditService) GetAll(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, int64, error) {
+	return s.repo.GetAll(ctx, filter)
+}
+
+// DeleteOlderThan deletes audit entries older than specified days
+func (s *AuditService) DeleteOlderThan(ctx context.Context, days int) (int64, error) {
+	return s.repo.DeleteOlderThan(ctx, days)
+}
+
+func (s *AuditService) record(entry *domain.AuditEntry) {
+	if err := s.Record(s.ctx, entry); err != nil {
+		log.Printf("Failed to record audit entry for %s %s: %v", entry.Route, entry.Target, err)
+	}
+}
+
+// commandEntry describes a command received over MQTT
+func commandEntry(cmd eventbus.Command) *domain.AuditEntry {
+	entry := &domain.AuditEntry{
+		Actor:   domain.AuditSourceMQTT,
+		Source:  domain.AuditSourceMQTT,
+		Route:   "command",
+		Target:  cmd.DeviceID,
+		Detail:  fmt.Sprintf("%s = %v", cmd.EntityID, cmd.Value),
+		Success: cmd.Success,
+	}
+	if cmd.DryRun {
+		entry.Detail += " (read-only mode, not sent)"
+	}
+	if cmd.Error != "" {
+		entry.Detail += ": " + cmd.Error
+	}
+	return entry
+}
+
+// sceneRunEntry describes a scene run triggered over MQTT
+func sceneRunEntry(run *domain.SceneRun) *domain.AuditEntry {
+	return &domain.AuditEntry{
+		Actor:     domain.AuditSourceMQTT,
+		Source:    domain.AuditSourceMQTT,
+		Route:     "scene",
+		Target:    run.SceneID,
+		Detail:    fmt.Sprintf("%s (%d steps)", run.Name, len(run.Steps)),
+		Success:   run.Success,
+		CreatedAt: run.StartedAt,
+	}
+}
+
+// writeLockEntry describes the write lock switched over MQTT
+func writeLockEntry(lock *domain.WriteLock) *domain.AuditEntry {
+	entry := &domain.AuditEntry{
+		Actor:   domain.AuditSourceMQTT,
+		Source:  domain.AuditSourceMQTT,
+		Route:   "lock",
+		Detail:  "unlocked",
+		Success: true,
+	}
+	if lock.Locked {
+		entry.Detail = "locked"
+	}
+	if lock.Reason != "" {
+		entry.Detail += ": " + lock.Reason
+	}
+	return entry
+}
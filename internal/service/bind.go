@@ -0,0 +1,58 @@
+package service
+
+import (
+	"fmt"
+	"net"
+)
+
+// ResolveBindAddress turns a bind address into a local IP. An interface name
+// (e.g. "eth0.20" on a VLAN) resolves to its first IPv4 address, or its first
+// address if it has no IPv4 one. Empty stays empty.
+func ResolveBindAddress(bind string) (string, error) {
+	if bind == "" || net.ParseIP(bind) != nil {
+		return bind, nil
+	}
+
+	iface, err := net.InterfaceByName(bind)
+	if err != nil {
+		return "", fmt.Errorf("bind address %q is neither an IP nor an interface: %w", bind, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", fmt.Errorf("interface %s: %w", bind, err)
+	}
+
+	var first net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP.String(), nil
+		}
+		if first == nil {
+			first = ipNet.IP
+		}
+	}
+	if first == nil {
+		return "", fmt.Errorf("interface %s has no IP address", bind)
+	}
+	return first.String(), nil
+}
+
+// snmpLocalAddr returns the gosnmp LocalAddr for a device bind address,
+// falling back to the bridge-wide one. Unresolvable addresses are passed on
+// unchanged so the connection attempt reports them.
+func snmpLocalAddr(bind, fallback string) string {
+	if bind == "" {
+		bind = fallback
+	}
+	if bind == "" {
+		return ""
+	}
+	if ip, err := ResolveBindAddress(bind); err == nil {
+		bind = ip
+	}
+	return net.JoinHostPort(bind, "0")
+}
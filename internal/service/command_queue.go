@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"snmp-mqtt-bridge/internal/actor"
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/dryrun"
+	"snmp-mqtt-bridge/internal/eventbus"
+
+	"github.com/google/uuid"
+)
+
+// commandQueueSize is the number of jobs that can wait per device
+const commandQueueSize = 16
+
+// commandWorkerIdleTimeout is how long a device worker waits for a job
+// before exiting, so removed or idle devices do not keep a goroutine
+const commandWorkerIdleTimeout = 5 * time.Minute
+
+var (
+	// ErrCommandQueueFull is returned when a device already has too many pending jobs
+	ErrCommandQueueFull = errors.New("command queue full")
+	// ErrCommandQueueStopped is returned when enqueueing after shutdown
+	ErrCommandQueueStopped = errors.New("command queue stopped")
+)
+
+// QueuedCommand is a single SNMP SET executed as part of a job
+type QueuedCommand struct {
+	Description string
+	OID         string
+	Value       interface{}
+	Type        domain.PDUType
+	Delay       time.Duration // Wait before sending, used to stagger outlet switching
+
+	Entity   string // What the SET changes for the command cooldown, see CooldownEntity; the OID if empty
+	EntityID string // Home Assistant entity of a command received over MQTT
+	Source   string // eventbus.CommandSourceAPI or CommandSourceMQTT; api if empty
+}
+
+// CommandJob is an ordered batch of commands for one device
+type CommandJob struct {
+	ID       string
+	DeviceID string
+	Results  []domain.CommandResult // Filled in once the job is done
+
+	actor        string           // Who the commands are sent for
+	dryRun       *dryrun.Recorder // Dry run of the request that queued the job, if any
+	waitCooldown bool             // Steps wait out the cooldown instead of failing
+	commands     []QueuedCommand
+	errs         []error // Error of each command, nil if it succeeded
+	done         chan struct{}
+}
+
+// Wait blocks until the job has run or ctx is done
+func (j *CommandJob) Wait(ctx context.Context) ([]domain.CommandResult, error) {
+	select {
+	case <-j.done:
+		return j.Results, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// CommandQueue runs SNMP SET jobs one at a time per device, so multi-step
+// operations never interleave on the same hardware. Every SET of the bridge
+// goes through it, so the command cooldown is enforced in one place.
+type CommandQueue struct {
+	snmp      *SNMPService
+	poller    *PollerService
+	cooldown  *CommandCooldown  // nil allows every write
+	writeLock *WriteLockService // Switches read-only mode; nil never holds SETs back
+
+	queues      map[string]chan *CommandJob
+	mu          sync.Mutex
+	idleTimeout time.Duration // commandWorkerIdleTimeout, shortened in tests
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewCommandQueue creates a new command queue
+func NewCommandQueue(snmp *SNMPService, poller *PollerService) *CommandQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &CommandQueue{
+		snmp:        snmp,
+		poller:      poller,
+		queues:      make(map[string]chan *CommandJob),
+		idleTimeout: commandWorkerIdleTimeout,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// SetCooldown sets the minimum interval between writes to the same entity
+// of a device
+func (q *CommandQueue) SetCooldown(cooldown *CommandCooldown) {
+	q.cooldown = cooldown
+}
+
+// SetWriteLock sets the write lock that switches read-only mode
+func (q *CommandQueue) SetWriteLock(writeLock *WriteLockService) {
+	q.writeLock = writeLock
+}
+
+// readOnly reports whether read-only mode holds back SETs
+func (q *CommandQueue) readOnly(ctx context.Context) (bool, error) {
+	if q.writeLock == nil {
+		return false, nil
+	}
+	return q.writeLock.Locked(ctx)
+}
+
+// DryRun reports whether SETs made with ctx are held back, because ctx asks
+// for a dry run or read-only mode is on. A write lock that cannot be read
+// counts as not held; the SETs then fail with ErrWriteLockUnavailable.
+func (q *CommandQueue) DryRun(ctx context.Context) bool {
+	readOnly, _ := q.readOnly(ctx)
+	return dryrun.Requested(ctx) || readOnly
+}
+
+// Stop cancels pending jobs and waits for the device workers to exit
+func (q *CommandQueue) Stop() {
+	// Cancel under the lock so no job slips in after the workers drain
+	q.mu.Lock()
+	q.cancel()
+	q.mu.Unlock()
+
+	q.wg.Wait()
+}
+
+// Depth returns the number of jobs waiting across all devices
+func (q *CommandQueue) Depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	depth := 0
+	for _, ch := range q.queues {
+		depth += len(ch)
+	}
+	return depth
+}
+
+// Enqueue schedules commands to run in order on a device, on behalf of the
+// actor of ctx and as part of its dry run. A step writing an entity too soon
+// after the last write waits out the cooldown. The job outlives ctx; use Wait
+// to bound waiting for it.
+func (q *CommandQueue) Enqueue(ctx context.Context, deviceID string, commands []QueuedCommand) (*CommandJob, error) {
+	return q.enqueue(ctx, deviceID, commands, true)
+}
+
+// Send runs a single command on a device and waits for it. A command within
+// the cooldown fails with a *CooldownError instead of waiting. Once queued,
+// the SET is sent even if ctx is done first.
+func (q *CommandQueue) Send(ctx context.Context, deviceID string, cmd QueuedCommand) error {
+	job, err := q.enqueue(ctx, deviceID, []QueuedCommand{cmd}, false)
+	if err != nil {
+		return err
+	}
+	if _, err := job.Wait(ctx); err != nil {
+		return err
+	}
+	return job.errs[0]
+}
+
+func (q *CommandQueue) enqueue(ctx context.Context, deviceID string, commands []QueuedCommand, waitCooldown bool) (*CommandJob, error) {
+	job := &CommandJob{
+		ID:           uuid.New().String(),
+		DeviceID:     deviceID,
+		actor:        actor.FromContext(ctx),
+		dryRun:       dryrun.FromContext(ctx),
+		waitCooldown: waitCooldown,
+		commands:     commands,
+		done:         make(chan struct{}),
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.ctx.Err() != nil {
+		return nil, ErrCommandQueueStopped
+	}
+
+	ch, exists := q.queues[deviceID]
+	if !exists {
+		ch = make(chan *CommandJob, commandQueueSize)
+		q.queues[deviceID] = ch
+		q.wg.Add(1)
+		go q.worker(deviceID, ch)
+	}
+
+	select {
+	case ch <- job:
+		return job, nil
+	default:
+		return nil, ErrCommandQueueFull
+	}
+}
+
+func (q *CommandQueue) worker(deviceID string, ch chan *CommandJob) {
+	defer q.wg.Done()
+
+	idle := time.NewTimer(q.idleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case <-q.ctx.Done():
+			// Complete pending jobs as cancelled so waiters are released
+			for {
+				select {
+				case job := <-ch:
+					q.run(job)
+				default:
+					return
+				}
+			}
+		case job := <-ch:
+			q.run(job)
+			idle.Reset(q.idleTimeout)
+		case <-idle.C:
+			// Jobs are only sent under the lock, so none can arrive once
+			// the queue is unregistered; the next job starts a new worker
+			q.mu.Lock()
+			if len(ch) > 0 {
+				q.mu.Unlock()
+				idle.Reset(q.idleTimeout)
+				continue
+			}
+			delete(q.queues, deviceID)
+			q.mu.Unlock()
+			return
+		}
+	}
+}
+
+func (q *CommandQueue) run(job *CommandJob) {
+	defer close(job.done)
+
+	ctx := actor.WithName(q.ctx, job.actor)
+	if job.dryRun != nil {
+		ctx = dryrun.WithRecorder(ctx, job.dryRun)
+	}
+
+	job.Results = make([]domain.CommandResult, 0, len(job.commands))
+	job.errs = make([]error, 0, len(job.commands))
+	failed := 0
+	for _, cmd := range job.commands {
+		result := domain.CommandResult{Description: cmd.Description, OID: cmd.OID}
+		if cmd.Entity == "" {
+			cmd.Entity = CooldownEntity(cmd.OID, nil)
+		}
+
+		// Nothing is switched in a dry run, so there is nothing to stagger
+		dryRun := q.DryRun(ctx)
+		if cmd.Delay > 0 && !dryRun {
+			q.sleep(cmd.Delay)
+		}
+		// A job's commands are meant to run in sequence, so a step writing an
+		// entity too soon after the last write waits instead of failing
+		if job.waitCooldown && !dryRun {
+			q.sleep(q.cooldown.Remaining(job.DeviceID, cmd.Entity))
+		}
+
+		var err error
+		if q.ctx.Err() != nil {
+			err = ErrCommandQueueStopped
+			result.Error = "cancelled"
+		} else if err = q.set(ctx, job.DeviceID, cmd); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Success = true
+		}
+
+		if !result.Success {
+			failed++
+		}
+		job.Results = append(job.Results, result)
+		job.errs = append(job.errs, err)
+	}
+
+	log.Printf("Command job %s on device %s finished: %d/%d succeeded",
+		job.ID, job.DeviceID, len(job.commands)-failed, len(job.commands))
+
+	if q.poller != nil {
+		q.poller.TriggerPoll(job.DeviceID)
+	}
+}
+
+// set sends a command's SET and announces it on the bus. In a dry run or
+// read-only mode the SET is validated and recorded but not sent, and while the
+// write lock cannot be read it fails. Otherwise the cooldown of its entity is
+// reserved first and released if the SET fails, so a failed write can be
+// retried at once.
+func (q *CommandQueue) set(ctx context.Context, deviceID string, cmd QueuedCommand) error {
+	readOnly, err := q.readOnly(ctx)
+	dryRun := readOnly || dryrun.Requested(ctx)
+
+	switch {
+	case err != nil:
+		// Without knowing the write lock nothing is sent
+	case dryRun:
+		err = q.snmp.setValue(ctx, deviceID, cmd.OID, cmd.Value, cmd.Type, true)
+		if err == nil {
+			dryrun.Record(ctx, domain.PlannedSet{DeviceID: deviceID, OID: cmd.OID, Value: cmd.Value, Type: cmd.Type}, readOnly)
+		}
+	default:
+		var release func()
+		if release, err = q.cooldown.Reserve(deviceID, cmd.Entity); err == nil {
+			if err = q.snmp.setValue(ctx, deviceID, cmd.OID, cmd.Value, cmd.Type, false); err != nil {
+				release()
+			}
+		}
+	}
+
+	event := eventbus.Command{
+		DeviceID: deviceID,
+		EntityID: cmd.EntityID,
+		OID:      cmd.OID,
+		Value:    cmd.Value,
+		Source:   cmd.Source,
+		Actor:    actor.FromContext(ctx),
+		Success:  err == nil,
+		DryRun:   dryRun,
+	}
+	if event.Source == "" {
+		event.Source = eventbus.CommandSourceAPI
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	q.snmp.bus.Publish(eventbus.Event{Type: eventbus.TypeCommand, DeviceID: deviceID, Payload: event})
+	return err
+}
+
+// sleep waits d or until the queue stops
+func (q *CommandQueue) sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	select {
+	case <-q.ctx.Done():
+	case <-time.After(d):
+	}
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCommandQueueReapsIdleWorkers(t *testing.T) {
+	q := NewCommandQueue(nil, nil)
+	q.idleTimeout = 20 * time.Millisecond
+	defer q.Stop()
+
+	workers := func() int {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		return len(q.queues)
+	}
+
+	for round := 0; round < 2; round++ {
+		job, err := q.Enqueue(context.Background(), "pdu-1", nil)
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		if _, err := job.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+
+		deadline := time.Now().Add(time.Second)
+		for workers() > 0 {
+			if time.Now().After(deadline) {
+				t.Fatalf("round %d: worker of an idle device still running", round)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+	"snmp-mqtt-bridge/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// configDriftFirstCheck is how long after start the first snapshots are
+// taken, so devices have been polled
+const configDriftFirstCheck = time.Minute
+
+// ConfigDriftService periodically snapshots each device's configuration
+// values, e.g. outlet names and transfer thresholds, from its latest poll.
+// Changes between snapshots are recorded in the device timeline, catching
+// manual changes made on the device's own web UI.
+type ConfigDriftService struct {
+	repo        repository.ConfigSnapshotRepository
+	deviceRepo  repository.DeviceRepository
+	profileRepo repository.ProfileRepository
+	poller      *PollerService
+	events      *EventService
+	bus         *eventbus.Bus
+	interval    time.Duration
+
+	checked map[string]time.Time // Last snapshot attempt per device
+	mu      sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewConfigDriftService creates a configuration drift service snapshotting
+// every interval; 0 only takes snapshots on request
+func NewConfigDriftService(
+	repo repository.ConfigSnapshotRepository,
+	deviceRepo repository.DeviceRepository,
+	profileRepo repository.ProfileRepository,
+	poller *PollerService,
+	events *EventService,
+	bus *eventbus.Bus,
+	interval time.Duration,
+) *ConfigDriftService {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &ConfigDriftService{
+		repo:        repo,
+		deviceRepo:  deviceRepo,
+		profileRepo: profileRepo,
+		poller:      poller,
+		events:      events,
+		bus:         bus,
+		interval:    interval,
+		checked:     make(map[string]time.Time),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Start starts taking snapshots and deleting those of deleted devices
+func (s *ConfigDriftService) Start() {
+	sub := s.bus.Subscribe(eventbus.TypeDeviceDeleted)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.bus.Unsubscribe(sub)
+
+		// A nil channel never fires, so snapshots are only taken on request
+		var tick <-chan time.Time
+		var first <-chan time.Time
+		if s.interval > 0 {
+			ticker := time.NewTicker(s.interval)
+			defer ticker.Stop()
+			tick = ticker.C
+			first = time.After(configDriftFirstCheck)
+		}
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-first:
+				s.SnapshotAll()
+			case <-tick:
+				s.SnapshotAll()
+			case evt, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				if err := s.repo.DeleteByDevice(s.ctx, evt.DeviceID); err != nil {
+					log.Printf("Failed to delete configuration snapshots of device %s: %v", evt.DeviceID, err)
+				}
+				s.mu.Lock()
+				delete(s.checked, evt.DeviceID)
+				s.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// Stop stops taking snapshots
+func (s *ConfigDriftService) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// SnapshotAll takes a snapshot of every enabled device
+func (s *ConfigDriftService) SnapshotAll() {
+	devices, err := s.deviceRepo.GetEnabled(s.ctx)
+	if err != nil {
+		log.Printf("Configuration snapshots failed to load devices: %v", err)
+		return
+	}
+	for i := range devices {
+		if _, err := s.Snapshot(s.ctx, &devices[i]); err != nil && s.ctx.Err() == nil {
+			log.Printf("Failed to snapshot configuration of device %s: %v", devices[i].Name, err)
+		}
+	}
+}
+
+// Snapshot stores the device's configuration values from its latest poll
+// when they differ from its previous snapshot, and returns what changed.
+// Devices that are offline or have no configuration mappings are skipped.
+func (s *ConfigDriftService) Snapshot(ctx context.Context, device *domain.Device) ([]domain.ConfigChange, error) {
+	state := s.poller.GetDeviceState(device.ID)
+	if state == nil || !state.Online || state.LastPoll.IsZero() {
+		return nil, nil
+	}
+
+	var profile *domain.Profile
+	if device.ProfileID != "" {
+		var err error
+		if profile, err = s.profileRepo.GetByID(ctx, device.ProfileID); err != nil {
+			return nil, fmt.Errorf("failed to load profile: %w", err)
+		}
+	}
+	profile = profile.WithCustomMappings(device)
+	if profile == nil {
+		return nil, nil
+	}
+
+	values := s.poller.GetDeviceValues(device.ID)
+	current := make(domain.ConfigValues)
+	for i := range profile.OIDMappings {
+		mapping := &profile.OIDMappings[i]
+		if value, ok := values[mapping.Name]; ok && mapping.TracksConfig() {
+			current[mapping.Name] = fmt.Sprint(value)
+		}
+	}
+	if len(current) == 0 {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	s.checked[device.ID] = time.Now()
+	s.mu.Unlock()
+
+	previous, err := s.repo.GetByDevice(ctx, device.ID, 1)
+	if err != nil {
+		return nil, err
+	}
+	var changes []domain.ConfigChange
+	if len(previous) > 0 {
+		if changes = domain.DiffConfig(previous[0].Values, current); len(changes) == 0 {
+			return nil, nil
+		}
+	}
+
+	snapshot := &domain.ConfigSnapshot{
+		ID:        uuid.New().String(),
+		DeviceID:  device.ID,
+		Values:    current,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.Create(ctx, snapshot); err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		log.Printf("Configuration of device %s changed: %s", device.Name, change.Name)
+		event := &domain.DeviceEvent{
+			DeviceID: device.ID,
+			Type:     domain.EventTypeConfigDrift,
+			Entity:   change.Name,
+			Message:  fmt.Sprintf("Configuration changed: %s", change.Name),
+		}
+		if change.Old != nil {
+			event.OldValue = *change.Old
+		}
+		if change.New != nil {
+			event.NewValue = *change.New
+		}
+		if s.events != nil {
+			if err := s.events.Record(ctx, event); err != nil {
+				log.Printf("Failed to record configuration change of device %s: %v", device.Name, err)
+			}
+		}
+	}
+	return changes, nil
+}
+
+// Drift returns a device's current configuration and up to limit changes
+// between its snapshots, newest first
+func (s *ConfigDriftService) Drift(ctx context.Context, deviceID string, limit int) (*domain.ConfigDrift, error) {
+	if _, err := s.deviceRepo.GetByID(ctx, deviceID); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDeviceNotFound, err)
+	}
+
+	// One more snapshot than changes: each change compares two
+	fetch := 0
+	if limit > 0 {
+		fetch = limit + 1
+	}
+	snapshots, err := s.repo.GetByDevice(ctx, deviceID, fetch)
+	if err != nil {
+		return nil, err
+	}
+
+	drift := &domain.ConfigDrift{
+		DeviceID: deviceID,
+		Current:  domain.ConfigValues{},
+		Changes:  []domain.ConfigDriftEntry{},
+	}
+	s.mu.Lock()
+	if checked, ok := s.checked[deviceID]; ok {
+		drift.CheckedAt = &checked
+	}
+	s.mu.Unlock()
+	if len(snapshots) == 0 {
+		return drift, nil
+	}
+
+	drift.Current = snapshots[0].Values
+	baseline := snapshots[len(snapshots)-1].CreatedAt
+	drift.BaselineAt = &baseline
+	for i := 0; i+1 < len(snapshots); i++ {
+		drift.Changes = append(drift.Changes, domain.ConfigDriftEntry{
+			DetectedAt: snapshots[i].CreatedAt,
+			PreviousAt: snapshots[i+1].CreatedAt,
+			Changes:    domain.DiffConfig(snapshots[i+1].Values, snapshots[i].Values),
+		})
+	}
+	return drift, nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConfirmationTokens issues single-use tokens that guard disruptive
+// operations. A token is bound to a scope describing the exact operation, so
+// it cannot be replayed for a different one.
+type ConfirmationTokens struct {
+	ttl    time.Duration
+	tokens map[string]confirmation
+	mu     sync.Mutex
+}
+
+type confirmation struct {
+	scope   string
+	expires time.Time
+}
+
+// NewConfirmationTokens creates a token store whose tokens expire after ttl
+func NewConfirmationTokens(ttl time.Duration) *ConfirmationTokens {
+	return &ConfirmationTokens{
+		ttl:    ttl,
+		tokens: make(map[string]confirmation),
+	}
+}
+
+// Issue creates a token for scope and returns it with its expiry
+func (t *ConfirmationTokens) Issue(scope string) (string, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for token, c := range t.tokens {
+		if now.After(c.expires) {
+			delete(t.tokens, token)
+		}
+	}
+
+	token := uuid.New().String()
+	expires := now.Add(t.ttl)
+	t.tokens[token] = confirmation{scope: scope, expires: expires}
+	return token, expires
+}
+
+// Consume reports whether token is valid for scope and invalidates it
+func (t *ConfirmationTokens) Consume(token, scope string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.tokens[token]
+	if !ok {
+		return false
+	}
+	delete(t.tokens, token)
+	return c.scope == scope && time.Now().Before(c.expires)
+}
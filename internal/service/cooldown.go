@@ -0,0 +1,148 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+)
+
+// ErrCommandCooldown is returned for a write to an entity written less than
+// the command cooldown ago
+var ErrCommandCooldown = errors.New("command cooldown")
+
+// CooldownError is a write refused by the command cooldown
+type CooldownError struct {
+	RetryAfter time.Duration // Until the entity may be written again
+}
+
+func (e *CooldownError) Error() string {
+	return fmt.Sprintf("written too recently, retry in %.1fs", e.RetryAfter.Seconds())
+}
+
+// Is makes CooldownError match ErrCommandCooldown
+func (e *CooldownError) Is(target error) bool {
+	return target == ErrCommandCooldown
+}
+
+// cooldownPruneSize is the number of tracked entities above which entries
+// past their cooldown are dropped
+const cooldownPruneSize = 1024
+
+// CommandCooldown enforces a minimum interval between writes to the same
+// entity, so relays and ATS transfer mechanisms are not toggled rapidly by
+// a misbehaving automation. Entities are identified by device and
+// CooldownEntity; the command queue reserves them before every SET.
+type CommandCooldown struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// NewCommandCooldown creates a cooldown of interval; 0 allows every write
+func NewCommandCooldown(interval time.Duration) *CommandCooldown {
+	return &CommandCooldown{
+		interval: interval,
+		last:     make(map[string]time.Time),
+	}
+}
+
+// Remaining returns how long until an entity may be written again, 0 if now
+func (c *CommandCooldown) Remaining(deviceID, entity string) time.Duration {
+	if c == nil || c.interval <= 0 {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.remaining(deviceID, entity, time.Now())
+}
+
+// Reserve starts the cooldown of an entity for a write about to be sent, or
+// returns a *CooldownError when it was written less than the interval ago.
+// Checking and starting the cooldown is one step, so of two concurrent writes
+// only one passes. release undoes the reservation, for a write that failed
+// and may be retried at once.
+func (c *CommandCooldown) Reserve(deviceID, entity string) (release func(), err error) {
+	if c == nil || c.interval <= 0 {
+		return func() {}, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if wait := c.remaining(deviceID, entity, now); wait > 0 {
+		return nil, &CooldownError{RetryAfter: wait}
+	}
+	if len(c.last) >= cooldownPruneSize {
+		for k, at := range c.last {
+			if now.Sub(at) >= c.interval {
+				delete(c.last, k)
+			}
+		}
+	}
+
+	key := cooldownKey(deviceID, entity)
+	previous, hadPrevious := c.last[key]
+	c.last[key] = now
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if !c.last[key].Equal(now) {
+			return
+		}
+		if hadPrevious {
+			c.last[key] = previous
+		} else {
+			delete(c.last, key)
+		}
+	}, nil
+}
+
+// remaining returns the longest wait of the writes an entity conflicts with:
+// its own, and for an element of a composite value the writes of the whole
+// value, or for a whole value the writes of its elements
+func (c *CommandCooldown) remaining(deviceID, entity string, now time.Time) time.Duration {
+	wait := c.wait(cooldownKey(deviceID, entity), now)
+	if i := strings.IndexByte(entity, '['); i >= 0 {
+		return max(wait, c.wait(cooldownKey(deviceID, entity[:i]), now))
+	}
+	prefix := cooldownKey(deviceID, entity) + "["
+	for key := range c.last {
+		if strings.HasPrefix(key, prefix) {
+			wait = max(wait, c.wait(key, now))
+		}
+	}
+	return wait
+}
+
+func (c *CommandCooldown) wait(key string, now time.Time) time.Duration {
+	at, ok := c.last[key]
+	if !ok {
+		return 0
+	}
+	if wait := c.interval - now.Sub(at); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+func cooldownKey(deviceID, entity string) string {
+	return deviceID + "|" + entity
+}
+
+// CooldownEntity identifies what a write changes for the command cooldown:
+// its OID, or for a composite value written whole, the element of mapping it
+// changes, so switching several outlets in a row is not refused. mapping is
+// nil for writes not made through a profile mapping.
+func CooldownEntity(oid string, mapping *domain.OIDMapping) string {
+	if mapping != nil && mapping.Type == domain.OIDTypeCompositeSwitch && mapping.CompositeWriteOID() == "" {
+		return fmt.Sprintf("%s[%d]", oid, mapping.CompositeIndex)
+	}
+	return oid
+}
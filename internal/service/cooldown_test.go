@@ -0,0 +1,93 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+)
+
+func TestCooldownReservesOnlyOneConcurrentWrite(t *testing.T) {
+	cooldown := NewCommandCooldown(time.Minute)
+
+	var passed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cooldown.Reserve("ats-1", ".1.3.6.1.4.1.318.1.1.8.4.2.0"); err == nil {
+				passed.Add(1)
+			} else if !errors.Is(err, ErrCommandCooldown) {
+				t.Errorf("Reserve: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := passed.Load(); n != 1 {
+		t.Errorf("%d concurrent writes passed the cooldown, want 1", n)
+	}
+}
+
+func TestCooldownReleaseAllowsRetry(t *testing.T) {
+	cooldown := NewCommandCooldown(time.Minute)
+
+	release, err := cooldown.Reserve("pdu-1", ".1.2.3")
+	if err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	var cooldownErr *CooldownError
+	if _, err := cooldown.Reserve("pdu-1", ".1.2.3"); !errors.As(err, &cooldownErr) || cooldownErr.RetryAfter <= 0 {
+		t.Fatalf("second write = %v, want a CooldownError with a retry time", err)
+	}
+
+	// A failed SET releases its reservation
+	release()
+	if _, err := cooldown.Reserve("pdu-1", ".1.2.3"); err != nil {
+		t.Errorf("write after a released reservation: %v", err)
+	}
+	if _, err := cooldown.Reserve("pdu-2", ".1.2.3"); err != nil {
+		t.Errorf("the same OID of another device: %v", err)
+	}
+}
+
+func TestCooldownCompositeElementsAndWholeValueConflict(t *testing.T) {
+	oid := ".1.3.6.1.4.1.17420.1.2.9.1.13.0"
+	outlet := func(index int) string {
+		return CooldownEntity(oid, &domain.OIDMapping{Type: domain.OIDTypeCompositeSwitch, CompositeIndex: index})
+	}
+
+	cooldown := NewCommandCooldown(time.Minute)
+	if _, err := cooldown.Reserve("pdu-1", outlet(0)); err != nil {
+		t.Fatalf("outlet 1: %v", err)
+	}
+	if _, err := cooldown.Reserve("pdu-1", outlet(1)); err != nil {
+		t.Errorf("outlet 2 right after outlet 1: %v", err)
+	}
+	// The API writes the whole value under the bare OID
+	if _, err := cooldown.Reserve("pdu-1", CooldownEntity(oid, nil)); !errors.Is(err, ErrCommandCooldown) {
+		t.Errorf("whole value after an outlet = %v, want the cooldown", err)
+	}
+
+	cooldown = NewCommandCooldown(time.Minute)
+	if _, err := cooldown.Reserve("pdu-1", CooldownEntity(oid, nil)); err != nil {
+		t.Fatalf("whole value: %v", err)
+	}
+	if _, err := cooldown.Reserve("pdu-1", outlet(2)); !errors.Is(err, ErrCommandCooldown) {
+		t.Errorf("outlet after the whole value = %v, want the cooldown", err)
+	}
+}
+
+func TestCooldownDisabled(t *testing.T) {
+	for _, cooldown := range []*CommandCooldown{nil, NewCommandCooldown(0)} {
+		for i := 0; i < 2; i++ {
+			if _, err := cooldown.Reserve("pdu-1", ".1.2.3"); err != nil {
+				t.Errorf("write %d with the cooldown disabled: %v", i+1, err)
+			}
+		}
+	}
+}
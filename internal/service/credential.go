@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidCredential is returned when a credential set fails validation
+	ErrInvalidCredential = errors.New("invalid credential")
+	// ErrCredentialNotFound is returned when a device references an unknown credential set
+	ErrCredentialNotFound = errors.New("credential not found")
+	// ErrCredentialInUse is returned when deleting a credential set devices still reference
+	ErrCredentialInUse = errors.New("credential is in use")
+)
+
+// CredentialService manages shared credential sets. Devices referencing a
+// set get its values copied in, so pollers and commands read them as usual;
+// a change is pushed to every referencing device.
+type CredentialService struct {
+	repo    repository.CredentialRepository
+	devices *DeviceService
+}
+
+// NewCredentialService creates a new credential service
+func NewCredentialService(repo repository.CredentialRepository, devices *DeviceService) *CredentialService {
+	return &CredentialService{repo: repo, devices: devices}
+}
+
+// Create creates a new credential set
+func (s *CredentialService) Create(ctx context.Context, req *domain.CredentialCreateRequest) (*domain.Credential, error) {
+	credential := &domain.Credential{
+		ID:             uuid.New().String(),
+		Name:           req.Name,
+		SNMPVersion:    req.SNMPVersion,
+		Community:      req.Community,
+		WriteCommunity: req.WriteCommunity,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	if err := credential.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, err)
+	}
+	if err := s.repo.Create(ctx, credential); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+// GetByID retrieves a credential set by ID
+func (s *CredentialService) GetByID(ctx context.Context, id string) (*domain.Credential, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// GetAll retrieves all credential sets
+func (s *CredentialService) GetAll(ctx context.Context) ([]domain.Credential, error) {
+	return s.repo.GetAll(ctx)
+}
+
+// Update updates a credential set and re-applies it to every device that
+// references it, restarting their pollers with the new values
+func (s *CredentialService) Update(ctx context.Context, id string, req *domain.CredentialUpdateRequest) (*domain.Credential, error) {
+	credential, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		credential.Name = *req.Name
+	}
+	if req.SNMPVersion != nil {
+		credential.SNMPVersion = *req.SNMPVersion
+	}
+	if req.Community != nil {
+		credential.Community = *req.Community
+	}
+	if req.WriteCommunity != nil {
+		credential.WriteCommunity = *req.WriteCommunity
+	}
+	credential.UpdatedAt = time.Now()
+
+	if err := credential.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, err)
+	}
+	if err := s.repo.Update(ctx, credential); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.devices.ApplyCredential(ctx, credential); err != nil {
+		return nil, err
+	}
+
+	return credential, nil
+}
+
+// Delete deletes a credential set that no device references
+func (s *CredentialService) Delete(ctx context.Context, id string) error {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return err
+	}
+
+	devices, err := s.devices.GetByCredential(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(devices) > 0 {
+		return fmt.Errorf("%w by %d device(s)", ErrCredentialInUse, len(devices))
+	}
+
+	return s.repo.Delete(ctx, id)
+}
@@ -3,9 +3,11 @@ package service
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
 	"snmp-mqtt-bridge/internal/repository"
 
 	"github.com/google/uuid"
@@ -14,56 +16,144 @@ import (
 
 // DeviceService handles device business logic
 type DeviceService struct {
-	repo repository.DeviceRepository
+	repo        repository.DeviceRepository
+	credentials repository.CredentialRepository
+	bus         *eventbus.Bus
+	site        string
 }
 
 // NewDeviceService creates a new device service
-func NewDeviceService(repo repository.DeviceRepository) *DeviceService {
-	return &DeviceService{repo: repo}
+func NewDeviceService(repo repository.DeviceRepository, credentials repository.CredentialRepository, bus *eventbus.Bus) *DeviceService {
+	return &DeviceService{repo: repo, credentials: credentials, bus: bus}
+}
+
+// SetSite sets the bridge site, inherited by devices that do not set their own
+func (s *DeviceService) SetSite(site string) {
+	s.site = site
+}
+
+// withSite fills in the bridge site for devices without one. The stored
+// device is left empty so a later change of the bridge site applies to it.
+func (s *DeviceService) withSite(device *domain.Device) *domain.Device {
+	if device != nil && device.Site == "" {
+		device.Site = s.site
+	}
+	return device
 }
 
 // Create creates a new device
 func (s *DeviceService) Create(ctx context.Context, req *domain.DeviceCreateRequest) (*domain.Device, error) {
 	device := &domain.Device{
-		ID:             uuid.New().String(),
-		Name:           req.Name,
-		IPAddress:      req.IPAddress,
-		Port:           req.Port,
-		Community:      req.Community,
-		WriteCommunity: req.WriteCommunity,
-		SNMPVersion:    req.SNMPVersion,
-		ProfileID:      req.ProfileID,
-		PollInterval:   req.PollInterval,
-		Enabled:        req.Enabled,
-		Labels:         req.Labels,
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
+		ID:              uuid.New().String(),
+		Name:            req.Name,
+		IPAddress:       req.IPAddress,
+		Port:            req.Port,
+		Community:       req.Community,
+		WriteCommunity:  req.WriteCommunity,
+		CredentialID:    req.CredentialID,
+		BindAddress:     req.BindAddress,
+		ProxyHost:       req.ProxyHost,
+		ProxyPort:       req.ProxyPort,
+		ProxyCommunity:  req.ProxyCommunity,
+		ContextName:     req.ContextName,
+		ContextEngineID: req.ContextEngineID,
+		SNMPVersion:     req.SNMPVersion,
+		ProfileID:       req.ProfileID,
+		PollInterval:    req.PollInterval,
+		PublishInterval: req.PublishInterval,
+		BatchSize:       req.BatchSize,
+		RequestDelay:    req.RequestDelay,
+		MaxRepetitions:  req.MaxRepetitions,
+		ConfirmWrites:   req.ConfirmWrites,
+		Enabled:         req.Enabled,
+		Labels:          req.Labels,
+		Notes:           req.Notes,
+		Site:            req.Site,
+		Location:        req.Location,
+		Rack:            req.Rack,
+		AssetTag:        req.AssetTag,
+		Contact:         req.Contact,
+		HAName:          req.HAName,
+		Manufacturer:    req.Manufacturer,
+		Model:           req.Model,
+		SelfTestDays:    req.SelfTestDays,
+		CustomMappings:  req.CustomMappings,
+		Alarms:          req.Alarms,
+		DomoticzIdx:     req.DomoticzIdx,
+		Shard:           req.Shard,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
 	}
 
 	if device.Port == 0 {
 		device.Port = 161
 	}
+	if err := s.applyCredential(ctx, device); err != nil {
+		return nil, err
+	}
 
 	if err := s.repo.Create(ctx, device); err != nil {
 		return nil, err
 	}
+	s.withSite(device)
+
+	s.bus.Publish(eventbus.Event{Type: eventbus.TypeDeviceCreated, DeviceID: device.ID, Payload: device})
 
 	return device, nil
 }
 
 // GetByID retrieves a device by ID
 func (s *DeviceService) GetByID(ctx context.Context, id string) (*domain.Device, error) {
-	return s.repo.GetByID(ctx, id)
+	device, err := s.repo.GetByID(ctx, id)
+	return s.withSite(device), err
 }
 
 // GetAll retrieves all devices
 func (s *DeviceService) GetAll(ctx context.Context) ([]domain.Device, error) {
-	return s.repo.GetAll(ctx)
+	devices, err := s.repo.GetAll(ctx)
+	for i := range devices {
+		s.withSite(&devices[i])
+	}
+	return devices, err
+}
+
+// GetBySite retrieves the devices of a site, matched case-insensitively
+func (s *DeviceService) GetBySite(ctx context.Context, site string) ([]domain.Device, error) {
+	devices, err := s.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]domain.Device, 0, len(devices))
+	for _, d := range devices {
+		if strings.EqualFold(d.Site, site) {
+			matched = append(matched, d)
+		}
+	}
+	return matched, nil
+}
+
+// DeviceIDsBySite returns the IDs of a site's devices, for filtering traps and events
+func (s *DeviceService) DeviceIDsBySite(ctx context.Context, site string) ([]string, error) {
+	devices, err := s.GetBySite(ctx, site)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(devices))
+	for i, d := range devices {
+		ids[i] = d.ID
+	}
+	return ids, nil
 }
 
 // GetEnabled retrieves all enabled devices
 func (s *DeviceService) GetEnabled(ctx context.Context) ([]domain.Device, error) {
-	return s.repo.GetEnabled(ctx)
+	devices, err := s.repo.GetEnabled(ctx)
+	for i := range devices {
+		s.withSite(&devices[i])
+	}
+	return devices, err
 }
 
 // Update updates an existing device
@@ -88,6 +178,27 @@ func (s *DeviceService) Update(ctx context.Context, id string, req *domain.Devic
 	if req.WriteCommunity != nil {
 		device.WriteCommunity = *req.WriteCommunity
 	}
+	if req.CredentialID != nil {
+		device.CredentialID = *req.CredentialID
+	}
+	if req.BindAddress != nil {
+		device.BindAddress = *req.BindAddress
+	}
+	if req.ProxyHost != nil {
+		device.ProxyHost = *req.ProxyHost
+	}
+	if req.ProxyPort != nil {
+		device.ProxyPort = *req.ProxyPort
+	}
+	if req.ProxyCommunity != nil {
+		device.ProxyCommunity = *req.ProxyCommunity
+	}
+	if req.ContextName != nil {
+		device.ContextName = *req.ContextName
+	}
+	if req.ContextEngineID != nil {
+		device.ContextEngineID = *req.ContextEngineID
+	}
 	if req.SNMPVersion != nil {
 		device.SNMPVersion = *req.SNMPVersion
 	}
@@ -97,25 +208,227 @@ func (s *DeviceService) Update(ctx context.Context, id string, req *domain.Devic
 	if req.PollInterval != nil {
 		device.PollInterval = *req.PollInterval
 	}
+	if req.PublishInterval != nil {
+		device.PublishInterval = *req.PublishInterval
+	}
+	if req.BatchSize != nil {
+		device.BatchSize = *req.BatchSize
+	}
+	if req.RequestDelay != nil {
+		device.RequestDelay = *req.RequestDelay
+	}
+	if req.MaxRepetitions != nil {
+		device.MaxRepetitions = *req.MaxRepetitions
+	}
+	if req.ConfirmWrites != nil {
+		device.ConfirmWrites = *req.ConfirmWrites
+	}
 	if req.Enabled != nil {
 		device.Enabled = *req.Enabled
 	}
 	if req.Labels != nil {
 		device.Labels = req.Labels
 	}
+	if req.Notes != nil {
+		device.Notes = *req.Notes
+	}
+	if req.Site != nil {
+		device.Site = *req.Site
+	}
+	if req.Location != nil {
+		device.Location = *req.Location
+	}
+	if req.Rack != nil {
+		device.Rack = *req.Rack
+	}
+	if req.AssetTag != nil {
+		device.AssetTag = *req.AssetTag
+	}
+	if req.Contact != nil {
+		device.Contact = *req.Contact
+	}
+	if req.HAName != nil {
+		device.HAName = *req.HAName
+	}
+	if req.Manufacturer != nil {
+		device.Manufacturer = *req.Manufacturer
+	}
+	if req.Model != nil {
+		device.Model = *req.Model
+	}
+	if req.SelfTestDays != nil {
+		device.SelfTestDays = *req.SelfTestDays
+	}
+	if req.CustomMappings != nil {
+		device.CustomMappings = req.CustomMappings
+	}
+	if req.Alarms != nil {
+		device.Alarms = req.Alarms
+	}
+	if req.DomoticzIdx != nil {
+		device.DomoticzIdx = req.DomoticzIdx
+	}
+	if req.Shard != nil {
+		device.Shard = *req.Shard
+	}
 
 	device.UpdatedAt = time.Now()
+	if err := s.applyCredential(ctx, device); err != nil {
+		return nil, err
+	}
 
 	if err := s.repo.Update(ctx, device); err != nil {
 		return nil, err
 	}
+	s.withSite(device)
+
+	s.bus.Publish(eventbus.Event{Type: eventbus.TypeDeviceUpdated, DeviceID: device.ID, Payload: device})
 
 	return device, nil
 }
 
 // Delete deletes a device
 func (s *DeviceService) Delete(ctx context.Context, id string) error {
-	return s.repo.Delete(ctx, id)
+	device, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.bus.Publish(eventbus.Event{Type: eventbus.TypeDeviceDeleted, DeviceID: id, Payload: device})
+
+	return nil
+}
+
+// GetByCredential retrieves the devices referencing a credential set
+func (s *DeviceService) GetByCredential(ctx context.Context, credentialID string) ([]domain.Device, error) {
+	devices, err := s.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	using := make([]domain.Device, 0)
+	for _, device := range devices {
+		if device.CredentialID == credentialID {
+			using = append(using, device)
+		}
+	}
+	return using, nil
+}
+
+// SetEnabled enables or disables a device. The update event starts or stops
+// its poller and publishes or removes its discovery configs right away.
+// Setting the current state again is a no-op.
+func (s *DeviceService) SetEnabled(ctx context.Context, id string, enabled bool) (*domain.Device, error) {
+	device, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if device.Enabled == enabled {
+		s.withSite(device)
+		return device, nil
+	}
+
+	device.Enabled = enabled
+	device.UpdatedAt = time.Now()
+	if err := s.applyCredential(ctx, device); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, device); err != nil {
+		return nil, err
+	}
+	s.withSite(device)
+
+	s.bus.Publish(eventbus.Event{Type: eventbus.TypeDeviceUpdated, DeviceID: device.ID, Payload: device})
+
+	return device, nil
+}
+
+// GetByProfile retrieves the devices referencing a profile
+func (s *DeviceService) GetByProfile(ctx context.Context, profileID string) ([]domain.Device, error) {
+	devices, err := s.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	using := make([]domain.Device, 0)
+	for _, device := range devices {
+		if device.ProfileID == profileID {
+			using = append(using, device)
+		}
+	}
+	return using, nil
+}
+
+// DetachProfile clears the profile of every device referencing it. The
+// devices keep their custom mappings; pollers and the MQTT publisher drop the
+// profile's entities on the device update events. Returns the number of
+// devices updated.
+func (s *DeviceService) DetachProfile(ctx context.Context, profileID string) (int, error) {
+	devices, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for i := range devices {
+		device := &devices[i]
+		if device.ProfileID != profileID {
+			continue
+		}
+		device.ProfileID = ""
+		device.UpdatedAt = time.Now()
+		if err := s.repo.Update(ctx, device); err != nil {
+			return updated, fmt.Errorf("device %s: %w", device.ID, err)
+		}
+		s.withSite(device)
+		s.bus.Publish(eventbus.Event{Type: eventbus.TypeDeviceUpdated, DeviceID: device.ID, Payload: device})
+		updated++
+	}
+	return updated, nil
+}
+
+// ApplyCredential copies an updated credential set onto every device that
+// references it. Pollers and the MQTT publisher pick up the new values from
+// the device update events. Returns the number of devices updated.
+func (s *DeviceService) ApplyCredential(ctx context.Context, credential *domain.Credential) (int, error) {
+	devices, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for i := range devices {
+		device := &devices[i]
+		if device.CredentialID != credential.ID {
+			continue
+		}
+		credential.ApplyTo(device)
+		device.UpdatedAt = time.Now()
+		if err := s.repo.Update(ctx, device); err != nil {
+			return updated, fmt.Errorf("device %s: %w", device.ID, err)
+		}
+		s.withSite(device)
+		s.bus.Publish(eventbus.Event{Type: eventbus.TypeDeviceUpdated, DeviceID: device.ID, Payload: device})
+		updated++
+	}
+	return updated, nil
+}
+
+// applyCredential copies the device's credential set, if any, onto it
+func (s *DeviceService) applyCredential(ctx context.Context, device *domain.Device) error {
+	if device.CredentialID == "" {
+		return nil
+	}
+	credential, err := s.credentials.GetByID(ctx, device.CredentialID)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrCredentialNotFound, device.CredentialID)
+	}
+	credential.ApplyTo(device)
+	return nil
 }
 
 // UpdateLastSeen updates the device's last seen timestamp
@@ -125,22 +438,28 @@ func (s *DeviceService) UpdateLastSeen(ctx context.Context, id string) error {
 
 // TestConnection tests SNMP connection to a device
 func (s *DeviceService) TestConnection(ctx context.Context, req *domain.TestConnectionRequest) (*domain.TestConnectionResponse, error) {
-	port := req.Port
-	if port == 0 {
-		port = 161
+	device := &domain.Device{
+		IPAddress:       req.IPAddress,
+		Port:            req.Port,
+		Community:       req.Community,
+		SNMPVersion:     req.SNMPVersion,
+		BindAddress:     req.BindAddress,
+		ProxyHost:       req.ProxyHost,
+		ProxyPort:       req.ProxyPort,
+		ProxyCommunity:  req.ProxyCommunity,
+		ContextName:     req.ContextName,
+		ContextEngineID: req.ContextEngineID,
 	}
-
-	snmpClient := createSNMPClient(req.IPAddress, port, req.Community, req.SNMPVersion)
-
 	start := time.Now()
 
-	if err := snmpClient.Connect(); err != nil {
+	snmpClient, err := OpenSNMP(device, device.ReadCommunity(), SNMPConnTest)
+	if err != nil {
 		return &domain.TestConnectionResponse{
 			Success: false,
 			Message: fmt.Sprintf("Connection failed: %v", err),
 		}, nil
 	}
-	defer snmpClient.Conn.Close()
+	defer snmpClient.Close()
 
 	// Query system OIDs
 	oids := []string{
@@ -190,23 +509,34 @@ func snmpVersionToGoSNMP(v domain.SNMPVersion) gosnmp.SnmpVersion {
 	}
 }
 
-// createSNMPClient creates a properly configured SNMP client based on device settings
-func createSNMPClient(target string, port int, community string, version domain.SNMPVersion) *gosnmp.GoSNMP {
+// NewSNMPClient creates a properly configured SNMP client for a device, sent
+// through its proxy when one is set. defaultBind is the local IP or interface
+// used when the device doesn't set its own.
+func NewSNMPClient(device *domain.Device, community, defaultBind string) *gosnmp.GoSNMP {
+	target, port := device.SNMPTarget()
 	client := &gosnmp.GoSNMP{
-		Target:  target,
-		Port:    uint16(port),
-		Version: snmpVersionToGoSNMP(version),
-		Timeout: time.Second * 5,
-		Retries: 2,
+		Target:    target,
+		Port:      uint16(port),
+		Version:   snmpVersionToGoSNMP(device.SNMPVersion),
+		Timeout:   time.Second * 5,
+		Retries:   2,
+		LocalAddr: snmpLocalAddr(device.BindAddress, defaultBind),
+	}
+	if device.MaxRepetitions > 0 {
+		client.MaxRepetitions = uint32(device.MaxRepetitions)
 	}
 
-	if version == domain.SNMPv3 {
+	if device.SNMPVersion == domain.SNMPv3 {
 		// For SNMPv3, use community field as username (noAuthNoPriv mode)
 		client.SecurityModel = gosnmp.UserSecurityModel
 		client.MsgFlags = gosnmp.NoAuthNoPriv
 		client.SecurityParameters = &gosnmp.UsmSecurityParameters{
 			UserName: community,
 		}
+		// Context fields select the device behind a proxy; the engine ID is
+		// validated on save
+		client.ContextName = device.ContextName
+		client.ContextEngineID, _ = domain.DecodeEngineID(device.ContextEngineID)
 	} else {
 		// For v1/v2c, use community string
 		client.Community = community
@@ -0,0 +1,414 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+// ErrInvalidSeedList is returned for seed lists that cannot be parsed
+var ErrInvalidSeedList = errors.New("invalid seed list")
+
+// importConcurrency bounds the seeds resolved and probed at once
+const importConcurrency = 8
+
+// DeviceImporter bulk-creates devices from seed lists exported by other SNMP
+// monitoring tools, picking each device's profile from its sysObjectID
+type DeviceImporter struct {
+	devices  *DeviceService
+	profiles repository.ProfileRepository
+	resolver *net.Resolver
+}
+
+// NewDeviceImporter creates a device importer
+func NewDeviceImporter(devices *DeviceService, profiles repository.ProfileRepository) *DeviceImporter {
+	return &DeviceImporter{devices: devices, profiles: profiles, resolver: net.DefaultResolver}
+}
+
+// Import parses the seed list and creates a device for every seed whose
+// address and port are not in use yet
+func (i *DeviceImporter) Import(ctx context.Context, req *domain.DeviceImportRequest) (*domain.DeviceImportResult, error) {
+	seeds, err := ParseSeedList([]byte(req.Content), req.Format)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSeedList, err)
+	}
+	if len(seeds) == 0 {
+		return nil, fmt.Errorf("%w: no devices", ErrInvalidSeedList)
+	}
+
+	existing, err := i.devices.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(existing))
+	for _, device := range existing {
+		known[net.JoinHostPort(device.IPAddress, strconv.Itoa(device.Port))] = true
+	}
+
+	profiles, err := i.profiles.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve and probe concurrently, create in seed order
+	detect := req.DetectProfile == nil || *req.DetectProfile
+	planned := make([]plannedDevice, len(seeds))
+	sem := make(chan struct{}, importConcurrency)
+	var wg sync.WaitGroup
+	for n := range seeds {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(n int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			planned[n] = i.plan(ctx, &seeds[n], req, profiles, detect)
+		}(n)
+	}
+	wg.Wait()
+
+	result := &domain.DeviceImportResult{DryRun: req.DryRun, Items: make([]domain.DeviceImportItem, 0, len(seeds))}
+	for n := range planned {
+		item := planned[n].item
+		create := planned[n].create
+
+		switch {
+		case item.Status == domain.DeviceImportFailed:
+		case known[net.JoinHostPort(create.IPAddress, strconv.Itoa(create.Port))]:
+			item.Status = domain.DeviceImportSkipped
+			item.Message = "A device with this address and port already exists"
+		case req.DryRun:
+			item.Status = domain.DeviceImportPlanned
+		default:
+			device, err := i.devices.Create(ctx, create)
+			if err != nil {
+				item.Status = domain.DeviceImportFailed
+				item.Message = err.Error()
+				break
+			}
+			item.Status = domain.DeviceImportCreated
+			item.DeviceID = device.ID
+		}
+
+		if item.Status == domain.DeviceImportCreated || item.Status == domain.DeviceImportPlanned {
+			// Later duplicates in the same list are skipped too
+			known[net.JoinHostPort(create.IPAddress, strconv.Itoa(create.Port))] = true
+			result.Created++
+		} else if item.Status == domain.DeviceImportSkipped {
+			result.Skipped++
+		} else {
+			result.Failed++
+		}
+		result.Items = append(result.Items, item)
+	}
+	return result, nil
+}
+
+// plannedDevice is a resolved seed ready to be created
+type plannedDevice struct {
+	item   domain.DeviceImportItem
+	create *domain.DeviceCreateRequest
+}
+
+func (i *DeviceImporter) plan(ctx context.Context, seed *domain.DeviceSeed, req *domain.DeviceImportRequest, profiles []domain.Profile, detect bool) plannedDevice {
+	item := domain.DeviceImportItem{Line: seed.Line, Hostname: seed.Hostname}
+	fail := func(format string, args ...interface{}) plannedDevice {
+		item.Status = domain.DeviceImportFailed
+		item.Message = fmt.Sprintf(format, args...)
+		return plannedDevice{item: item}
+	}
+
+	community := firstNonEmpty(seed.Community, req.Community)
+	version := seed.SNMPVersion
+	if version == "" {
+		version = req.SNMPVersion
+	}
+	if version == "" {
+		version = domain.SNMPv2c
+	}
+	if community == "" {
+		return fail("No community set and no default given")
+	}
+	port := seed.Port
+	if port == 0 {
+		port = 161
+	}
+	if port < 1 || port > 65535 {
+		return fail("Invalid port %d", port)
+	}
+
+	ip, err := i.resolve(ctx, seed.Hostname)
+	if err != nil {
+		return fail("%v", err)
+	}
+	item.IPAddress = ip
+
+	enabled := req.Enabled == nil || *req.Enabled
+	create := &domain.DeviceCreateRequest{
+		Name:        firstNonEmpty(seed.Name, seed.Hostname),
+		IPAddress:   ip,
+		Port:        port,
+		Community:   community,
+		SNMPVersion: version,
+		Enabled:     enabled,
+		Site:        req.Site,
+	}
+
+	if detect {
+		probe, err := i.devices.TestConnection(ctx, &domain.TestConnectionRequest{
+			IPAddress:   ip,
+			Port:        port,
+			Community:   community,
+			SNMPVersion: version,
+		})
+		switch {
+		case err != nil:
+			item.Message = fmt.Sprintf("Profile detection failed: %v", err)
+		case !probe.Success:
+			item.Message = "Profile detection failed: " + probe.Message
+		default:
+			if profile := matchProfile(profiles, probe.SysObjectID); profile != nil {
+				create.ProfileID = profile.ID
+				item.ProfileID = profile.ID
+			} else {
+				item.Message = fmt.Sprintf("No profile matches sysObjectID %s", probe.SysObjectID)
+			}
+			// An IP address says less than the device's own name
+			if seed.Name == "" && net.ParseIP(seed.Hostname) != nil && probe.SysName != "" {
+				create.Name = probe.SysName
+			}
+		}
+	}
+
+	return plannedDevice{item: item, create: create}
+}
+
+// resolve returns the IPv4 address of a host name, or the address itself
+func (i *DeviceImporter) resolve(ctx context.Context, host string) (string, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+	addrs, err := i.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if ip4 := addr.IP.To4(); ip4 != nil {
+			return ip4.String(), nil
+		}
+	}
+	if len(addrs) > 0 {
+		return addrs[0].IP.String(), nil
+	}
+	return "", fmt.Errorf("cannot resolve %s: no addresses", host)
+}
+
+// matchProfile returns the profile whose sysObjectID equals sysOID or is the
+// longest prefix of it, so vendor-wide profiles match every model
+func matchProfile(profiles []domain.Profile, sysOID string) *domain.Profile {
+	sysOID = "." + strings.TrimPrefix(sysOID, ".")
+	var best *domain.Profile
+	bestLen := 0
+	for n := range profiles {
+		candidate := profiles[n].SysObjectID
+		if candidate == "" {
+			continue
+		}
+		candidate = "." + strings.TrimPrefix(candidate, ".")
+		if sysOID != candidate && !strings.HasPrefix(sysOID, candidate+".") {
+			continue
+		}
+		if len(candidate) > bestLen {
+			best, bestLen = &profiles[n], len(candidate)
+		}
+	}
+	return best
+}
+
+// ParseSeedList parses a seed list in the given format; empty or "auto"
+// picks JSON for a leading '[', CSV for a header row with commas, else text
+func ParseSeedList(data []byte, format string) ([]domain.DeviceSeed, error) {
+	trimmed := bytes.TrimSpace(data)
+	if format == "" || format == "auto" {
+		firstLine, _, _ := strings.Cut(string(trimmed), "\n")
+		switch {
+		case strings.HasPrefix(string(trimmed), "["):
+			format = "json"
+		case strings.Contains(firstLine, ","):
+			format = "csv"
+		default:
+			format = "text"
+		}
+	}
+
+	var seeds []domain.DeviceSeed
+	var err error
+	switch format {
+	case "json":
+		err = json.Unmarshal(trimmed, &seeds)
+		for n := range seeds {
+			seeds[n].Line = n + 1
+		}
+	case "csv":
+		seeds, err = parseSeedCSV(trimmed)
+	case "text":
+		seeds, err = parseSeedText(trimmed)
+	default:
+		return nil, fmt.Errorf("unknown seed list format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for n := range seeds {
+		seed := &seeds[n]
+		seed.Hostname = strings.TrimSpace(seed.Hostname)
+		if seed.Hostname == "" {
+			return nil, fmt.Errorf("line %d: missing hostname", seed.Line)
+		}
+		if seed.SNMPVersion != "" {
+			version, ok := parseSeedVersion(string(seed.SNMPVersion))
+			if !ok {
+				return nil, fmt.Errorf("line %d: unknown SNMP version %q", seed.Line, seed.SNMPVersion)
+			}
+			seed.SNMPVersion = version
+		}
+	}
+	return seeds, nil
+}
+
+// parseSeedText parses add_device.php / addhost.php argument lines:
+// "hostname [community] [version] [port] [transport]"; '#' starts a comment
+func parseSeedText(data []byte) ([]domain.DeviceSeed, error) {
+	var seeds []domain.DeviceSeed
+	for n, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) > 5 {
+			return nil, fmt.Errorf("line %d: expected \"hostname [community] [version] [port] [transport]\"", n+1)
+		}
+		seed := domain.DeviceSeed{Line: n + 1, Hostname: fields[0]}
+		if len(fields) > 1 {
+			seed.Community = fields[1]
+		}
+		if len(fields) > 2 {
+			seed.SNMPVersion = domain.SNMPVersion(fields[2])
+		}
+		if len(fields) > 3 {
+			port, err := strconv.Atoi(fields[3])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid port %q", n+1, fields[3])
+			}
+			seed.Port = port
+		}
+		if len(fields) > 4 && !strings.HasPrefix(strings.ToLower(fields[4]), "udp") {
+			return nil, fmt.Errorf("line %d: unsupported transport %q", n+1, fields[4])
+		}
+		seeds = append(seeds, seed)
+	}
+	return seeds, nil
+}
+
+// parseSeedCSV parses a CSV file whose header names its columns
+func parseSeedCSV(data []byte) ([]domain.DeviceSeed, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+	columns := make(map[string]int)
+	for n, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = n
+	}
+	column := func(names ...string) int {
+		for _, name := range names {
+			if n, ok := columns[name]; ok {
+				return n
+			}
+		}
+		return -1
+	}
+	hostCol := column("hostname", "host", "ip", "ip_address", "address")
+	if hostCol < 0 {
+		return nil, fmt.Errorf("CSV header has no hostname column")
+	}
+	communityCol := column("community", "snmp_community")
+	versionCol := column("version", "snmp_version", "snmpver")
+	portCol := column("port", "snmp_port")
+	nameCol := column("name", "sysname", "display")
+
+	var seeds []domain.DeviceSeed
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(n int) string {
+			if n < 0 || n >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[n])
+		}
+
+		seed := domain.DeviceSeed{
+			Line:        line,
+			Hostname:    field(hostCol),
+			Name:        field(nameCol),
+			Community:   field(communityCol),
+			SNMPVersion: domain.SNMPVersion(field(versionCol)),
+		}
+		if port := field(portCol); port != "" {
+			if seed.Port, err = strconv.Atoi(port); err != nil {
+				return nil, fmt.Errorf("line %d: invalid port %q", line, port)
+			}
+		}
+		seeds = append(seeds, seed)
+	}
+	return seeds, nil
+}
+
+// parseSeedVersion accepts the version spellings of common tools: v2c, 2c, 2
+func parseSeedVersion(version string) (domain.SNMPVersion, bool) {
+	switch strings.ToLower(version) {
+	case "v1", "1":
+		return domain.SNMPv1, true
+	case "v2c", "2c", "v2", "2":
+		return domain.SNMPv2c, true
+	case "v3", "3":
+		return domain.SNMPv3, true
+	}
+	return "", false
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+	"snmp-mqtt-bridge/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// EventService records discrete device state changes into the device timeline
+type EventService struct {
+	repo        repository.EventRepository
+	deviceRepo  repository.DeviceRepository
+	profileRepo repository.ProfileRepository
+	bus         *eventbus.Bus
+
+	snapshots map[string]*deviceSnapshot
+	commands  map[string][]recentCommand // By device, to attribute the state changes they cause
+	mu        sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// deviceSnapshot holds the last seen discrete values of a device
+type deviceSnapshot struct {
+	profile *domain.Profile
+	seen    bool
+	online  bool
+	values  map[string]string
+}
+
+// commandWindow is how long after a command a state change of the entity it
+// wrote is attributed to whoever sent it
+const commandWindow = 2 * time.Minute
+
+// recentCommand is a command sent to a device, kept for commandWindow
+type recentCommand struct {
+	oid   string
+	actor string
+	at    time.Time
+}
+
+// NewEventService creates a new event service
+func NewEventService(repo repository.EventRepository, deviceRepo repository.DeviceRepository, profileRepo repository.ProfileRepository, bus *eventbus.Bus) *EventService {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &EventService{
+		repo:        repo,
+		deviceRepo:  deviceRepo,
+		profileRepo: profileRepo,
+		bus:         bus,
+		snapshots:   make(map[string]*deviceSnapshot),
+		commands:    make(map[string][]recentCommand),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Start starts recording events from state updates
+func (s *EventService) Start() {
+	sub := s.bus.Subscribe(eventbus.TypeStateUpdate, eventbus.TypeCommand, eventbus.TypeDeviceUpdated, eventbus.TypeDeviceDeleted)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.bus.Unsubscribe(sub)
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case evt, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				switch evt.Type {
+				case eventbus.TypeStateUpdate:
+					if update, ok := evt.Payload.(StateUpdateEvent); ok {
+						s.handleStateUpdate(update)
+					}
+				case eventbus.TypeCommand:
+					if cmd, ok := evt.Payload.(eventbus.Command); ok {
+						s.handleCommand(cmd)
+					}
+				case eventbus.TypeDeviceUpdated, eventbus.TypeDeviceDeleted:
+					// Profile or device may have changed, rebuild snapshot on next update
+					s.mu.Lock()
+					delete(s.snapshots, evt.DeviceID)
+					if evt.Type == eventbus.TypeDeviceDeleted {
+						delete(s.commands, evt.DeviceID)
+					}
+					s.mu.Unlock()
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops recording events
+func (s *EventService) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// GetAll retrieves device events with filtering
+func (s *EventService) GetAll(ctx context.Context, filter domain.EventFilter) ([]domain.DeviceEvent, int64, error) {
+	return s.repo.GetAll(ctx, filter)
+}
+
+// DeleteOlderThan deletes events older than specified days
+func (s *EventService) DeleteOlderThan(ctx context.Context, days int) (int64, error) {
+	return s.repo.DeleteOlderThan(ctx, days)
+}
+
+// Record stores an event and announces it on the bus
+func (s *EventService) Record(ctx context.Context, event *domain.DeviceEvent) error {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	if err := s.repo.Create(ctx, event); err != nil {
+		return err
+	}
+
+	s.bus.Publish(eventbus.Event{Type: eventbus.TypeDeviceEvent, DeviceID: event.DeviceID, Payload: event})
+	return nil
+}
+
+func (s *EventService) handleStateUpdate(update StateUpdateEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap, exists := s.snapshots[update.DeviceID]
+	if !exists {
+		snap = &deviceSnapshot{values: make(map[string]string)}
+		if device, err := s.deviceRepo.GetByID(s.ctx, update.DeviceID); err == nil {
+			var profile *domain.Profile
+			if device.ProfileID != "" {
+				profile, _ = s.profileRepo.GetByID(s.ctx, device.ProfileID)
+			}
+			snap.profile = profile.ForDevice(device)
+		}
+		s.snapshots[update.DeviceID] = snap
+	}
+
+	// Online/offline transitions
+	if snap.seen && snap.online != update.Online {
+		event := &domain.DeviceEvent{
+			DeviceID:  update.DeviceID,
+			Type:      domain.EventTypeOffline,
+			Message:   "Device stopped responding",
+			CreatedAt: update.Timestamp,
+		}
+		if update.Online {
+			event.Type = domain.EventTypeOnline
+			event.Message = "Device is responding again"
+		}
+		s.record(event)
+	}
+	snap.seen = true
+	snap.online = update.Online
+
+	if snap.profile == nil {
+		return
+	}
+
+	for _, mapping := range snap.profile.OIDMappings {
+		if !isDiscreteMapping(&mapping) {
+			continue
+		}
+
+		value, ok := update.Values[mapping.Name]
+		if !ok || value == nil {
+			continue
+		}
+		newValue := fmt.Sprintf("%v", value)
+
+		oldValue, had := snap.values[mapping.Name]
+		snap.values[mapping.Name] = newValue
+
+		// First observation only establishes the baseline
+		if !had || oldValue == newValue {
+			continue
+		}
+
+		s.record(&domain.DeviceEvent{
+			DeviceID:  update.DeviceID,
+			Type:      domain.EventTypeStateChange,
+			Entity:    mapping.Name,
+			OldValue:  oldValue,
+			NewValue:  newValue,
+			Message:   fmt.Sprintf("%s changed from %s to %s", mapping.Name, oldValue, newValue),
+			Actor:     s.commandActor(update.DeviceID, &mapping, update.Timestamp),
+			CreatedAt: update.Timestamp,
+		})
+	}
+}
+
+// handleCommand remembers who sent a successful command, until its effect
+// shows in a state update or commandWindow passes
+func (s *EventService) handleCommand(cmd eventbus.Command) {
+	if !cmd.Success || cmd.DryRun || cmd.Actor == "" || cmd.OID == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	kept := s.commands[cmd.DeviceID][:0]
+	for _, c := range s.commands[cmd.DeviceID] {
+		if now.Sub(c.at) < commandWindow {
+			kept = append(kept, c)
+		}
+	}
+	s.commands[cmd.DeviceID] = append(kept, recentCommand{oid: cmd.OID, actor: cmd.Actor, at: now})
+}
+
+// commandActor returns who sent the latest command writing the mapping
+// within commandWindow before at, or "" when the change was not commanded.
+// Callers hold s.mu.
+func (s *EventService) commandActor(deviceID string, mapping *domain.OIDMapping, at time.Time) string {
+	commands := s.commands[deviceID]
+	for i := len(commands) - 1; i >= 0; i-- {
+		c := commands[i]
+		if at.Sub(c.at) > commandWindow {
+			break
+		}
+		if c.oid == mapping.OID || c.oid == mapping.WriteOID || c.oid == mapping.CompositeWriteOID() {
+			return c.actor
+		}
+	}
+	return ""
+}
+
+func (s *EventService) record(event *domain.DeviceEvent) {
+	if err := s.Record(s.ctx, event); err != nil {
+		log.Printf("Failed to record event for device %s: %v", event.DeviceID, err)
+	}
+}
+
+// isDiscreteMapping reports whether a mapping represents a discrete state worth
+// recording on change (switches, status enums), as opposed to continuous measurements
+func isDiscreteMapping(mapping *domain.OIDMapping) bool {
+	switch mapping.HAComponent {
+	case domain.HAComponentSwitch, domain.HAComponentBinarySensor, domain.HAComponentSelect:
+		return true
+	}
+	switch mapping.Type {
+	case domain.OIDTypeEnum, domain.OIDTypeBool:
+		return true
+	}
+	return false
+}
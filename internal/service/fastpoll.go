@@ -0,0 +1,50 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+)
+
+// SetFastPolling makes devices poll every interval for duration after a
+// command or a change of a switch, select or binary sensor, so transitions
+// show up promptly. A zero duration disables fast polling.
+func (s *PollerService) SetFastPolling(interval, duration time.Duration) {
+	s.fastInterval = interval
+	s.fastDuration = duration
+}
+
+// startFastPolling starts or extends a device's fast polling window
+func (s *PollerService) startFastPolling(dp *devicePoller) {
+	if s.fastDuration <= 0 || s.fastInterval <= 0 || s.fastInterval >= dp.interval {
+		return
+	}
+	dp.fastUntil.Store(time.Now().Add(s.fastDuration).UnixNano())
+}
+
+// fastPolling reports whether the device is within its fast polling window
+func (dp *devicePoller) fastPolling() bool {
+	return time.Now().UnixNano() < dp.fastUntil.Load()
+}
+
+// DiscreteChanged reports whether a switch, select or binary sensor value
+// differs from the previous poll, e.g. a source transfer or outlet toggle
+func DiscreteChanged(profile *domain.Profile, previous, current map[string]interface{}) bool {
+	if profile == nil {
+		return false
+	}
+	for _, mapping := range profile.OIDMappings {
+		switch mapping.HAComponent {
+		case domain.HAComponentSwitch, domain.HAComponentSelect, domain.HAComponentBinarySensor:
+		default:
+			continue
+		}
+		before, hadBefore := previous[mapping.Name]
+		now, hasNow := current[mapping.Name]
+		if hadBefore && hasNow && fmt.Sprint(before) != fmt.Sprint(now) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+// StatisticsAuditService checks the sensors published for every enabled
+// device against what Home Assistant needs to keep long-term statistics
+type StatisticsAuditService struct {
+	deviceRepo  repository.DeviceRepository
+	profileRepo repository.ProfileRepository
+	autoFix     bool
+}
+
+// NewStatisticsAuditService creates a new statistics audit service
+func NewStatisticsAuditService(deviceRepo repository.DeviceRepository, profileRepo repository.ProfileRepository) *StatisticsAuditService {
+	return &StatisticsAuditService{deviceRepo: deviceRepo, profileRepo: profileRepo}
+}
+
+// SetAutoFix reports whether discovery publishes suggested state classes
+func (s *StatisticsAuditService) SetAutoFix(autoFix bool) {
+	s.autoFix = autoFix
+}
+
+// Audit returns the statistics issues of all published sensors
+func (s *StatisticsAuditService) Audit(ctx context.Context) (*domain.StatisticsReport, error) {
+	devices, err := s.deviceRepo.GetEnabled(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &domain.StatisticsReport{AutoFix: s.autoFix, Issues: []domain.StatisticsIssue{}}
+	profiles := make(map[string]*domain.Profile)
+	for i := range devices {
+		device := &devices[i]
+		var profile *domain.Profile
+		if device.ProfileID != "" {
+			var ok bool
+			if profile, ok = profiles[device.ProfileID]; !ok {
+				profile, err = s.profileRepo.GetByID(ctx, device.ProfileID)
+				if err != nil {
+					log.Printf("Statistics audit: failed to get profile %s: %v", device.ProfileID, err)
+				}
+				profiles[device.ProfileID] = profile
+			}
+		}
+		published := profile.ForDevice(device)
+		if published == nil {
+			continue
+		}
+
+		// Audit what discovery publishes, including per-device mappings
+		for _, mapping := range published.OIDMappings {
+			if mapping.HAComponent != domain.HAComponentSensor {
+				continue
+			}
+			report.Sensors++
+			if mapping.KeepsStatistics(s.autoFix) {
+				report.WithStatistics++
+			}
+			for _, issue := range mapping.StatisticsIssues() {
+				issue.DeviceID = device.ID
+				issue.DeviceName = device.Name
+				issue.AutoFixed = s.autoFix && issue.SuggestedStateClass != nil
+				report.Issues = append(report.Issues, issue)
+			}
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+)
+
+// StateHistoryService records the numeric values of every poll in memory,
+// for charting recent history without an external time series database.
+// History is lost on restart.
+type StateHistoryService struct {
+	bus       *eventbus.Bus
+	retention time.Duration
+
+	series map[string]map[string][]domain.HistoryPoint // Device ID -> metric -> points, oldest first
+	mu     sync.RWMutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewStateHistoryService creates a history keeping values for retention
+func NewStateHistoryService(bus *eventbus.Bus, retention time.Duration) *StateHistoryService {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &StateHistoryService{
+		bus:       bus,
+		retention: retention,
+		series:    make(map[string]map[string][]domain.HistoryPoint),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start starts recording state updates
+func (s *StateHistoryService) Start() {
+	sub := s.bus.Subscribe(eventbus.TypeStateUpdate, eventbus.TypeDeviceDeleted)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.bus.Unsubscribe(sub)
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case evt, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				switch evt.Type {
+				case eventbus.TypeStateUpdate:
+					if update, ok := evt.Payload.(StateUpdateEvent); ok {
+						s.Record(update)
+					}
+				case eventbus.TypeDeviceDeleted:
+					s.mu.Lock()
+					delete(s.series, evt.DeviceID)
+					s.mu.Unlock()
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops recording
+func (s *StateHistoryService) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Record stores the numeric values of a state update by mapping name.
+// Updates of offline devices repeat the last values and are skipped.
+func (s *StateHistoryService) Record(update StateUpdateEvent) {
+	if !update.Online {
+		return
+	}
+	at := update.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	cutoff := at.Add(-s.retention)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	device := s.series[update.DeviceID]
+	if device == nil {
+		device = make(map[string][]domain.HistoryPoint)
+		s.series[update.DeviceID] = device
+	}
+	for name, value := range update.Values {
+		// Values are also keyed by OID
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		number, ok := historyValue(value)
+		if !ok {
+			continue
+		}
+		points := append(device[name], domain.HistoryPoint{Time: at, Value: number})
+
+		// Points are appended in time order, so expired ones lead
+		expired := sort.Search(len(points), func(i int) bool { return !points[i].Time.Before(cutoff) })
+		device[name] = points[expired:]
+	}
+}
+
+// historyValue converts a polled value to a number; booleans record as 1 or 0
+func historyValue(v interface{}) (float64, bool) {
+	if b, ok := v.(bool); ok {
+		if b {
+			return 1, true
+		}
+		return 0, true
+	}
+	return domain.Number(v)
+}
+
+// Metrics returns the names of a device's recorded metrics, sorted
+func (s *StateHistoryService) Metrics(deviceID string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	metrics := make([]string, 0, len(s.series[deviceID]))
+	for name := range s.series[deviceID] {
+		metrics = append(metrics, name)
+	}
+	sort.Strings(metrics)
+	return metrics
+}
+
+// Query returns a metric's points from from to to, both inclusive
+func (s *StateHistoryService) Query(deviceID, metric string, from, to time.Time) []domain.HistoryPoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	points := s.series[deviceID][metric]
+	start := sort.Search(len(points), func(i int) bool { return !points[i].Time.Before(from) })
+	end := sort.Search(len(points), func(i int) bool { return points[i].Time.After(to) })
+	if start >= end {
+		return nil
+	}
+	return append([]domain.HistoryPoint(nil), points[start:end]...)
+}
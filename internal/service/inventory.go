@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+// InventoryService reports the hardware and firmware of all devices from
+// their latest polls: sysDescr and the mappings profiles mark with an
+// inventory field
+type InventoryService struct {
+	devices     *DeviceService
+	profileRepo repository.ProfileRepository
+	poller      *PollerService
+}
+
+// NewInventoryService creates a new inventory service
+func NewInventoryService(devices *DeviceService, profileRepo repository.ProfileRepository, poller *PollerService) *InventoryService {
+	return &InventoryService{
+		devices:     devices,
+		profileRepo: profileRepo,
+		poller:      poller,
+	}
+}
+
+// List returns the inventory of all devices, or of one site's devices, by
+// name. Values are those of the last successful poll; devices not polled
+// since the bridge started have only their model and manufacturer.
+func (s *InventoryService) List(ctx context.Context, site string) ([]domain.InventoryItem, error) {
+	var devices []domain.Device
+	var err error
+	if site != "" {
+		devices, err = s.devices.GetBySite(ctx, site)
+	} else {
+		devices, err = s.devices.GetAll(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make(map[string]*domain.Profile)
+	items := make([]domain.InventoryItem, 0, len(devices))
+	for i := range devices {
+		device := &devices[i]
+
+		profile, ok := profiles[device.ProfileID]
+		if !ok && device.ProfileID != "" {
+			if profile, err = s.profileRepo.GetByID(ctx, device.ProfileID); err != nil {
+				return nil, fmt.Errorf("failed to load profile %s: %w", device.ProfileID, err)
+			}
+			profiles[device.ProfileID] = profile
+		}
+
+		items = append(items, s.item(device, profile.WithCustomMappings(device)))
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return strings.ToLower(items[i].Name) < strings.ToLower(items[j].Name)
+	})
+	return items, nil
+}
+
+func (s *InventoryService) item(device *domain.Device, profile *domain.Profile) domain.InventoryItem {
+	item := domain.InventoryItem{
+		DeviceID:     device.ID,
+		Name:         device.Name,
+		IPAddress:    device.IPAddress,
+		Site:         device.Site,
+		Location:     device.Location,
+		Rack:         device.Rack,
+		AssetTag:     device.AssetTag,
+		ProfileID:    device.ProfileID,
+		Manufacturer: device.Manufacturer,
+		Model:        device.Model,
+	}
+	if profile != nil {
+		if item.Manufacturer == "" {
+			item.Manufacturer = profile.Manufacturer
+		}
+		if item.Model == "" {
+			item.Model = profile.Model
+		}
+	}
+
+	state := s.poller.GetDeviceState(device.ID)
+	if state == nil || profile == nil {
+		return item
+	}
+	item.Online = state.Online
+
+	var found bool
+	for i := range profile.OIDMappings {
+		mapping := &profile.OIDMappings[i]
+		field := mapping.InventoryField()
+		value, ok := state.Values[mapping.Name]
+		if field == "" || !ok {
+			continue
+		}
+		text := strings.TrimSpace(fmt.Sprint(value))
+		if text == "" {
+			continue
+		}
+		found = true
+		switch field {
+		case domain.InventorySysDescr:
+			item.SysDescr = text
+		case domain.InventoryFirmware:
+			item.Firmware = text
+		case domain.InventorySerial:
+			item.Serial = text
+		case domain.InventoryModel:
+			// The model the device reports beats the profile's generic one
+			item.Model = text
+		}
+	}
+	if found {
+		item.UpdatedAt = device.LastSeen
+	}
+	return item
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+// LeaderElector elects the active instance of a warm standby pair, or the
+// coordinator of a sharded cluster, through a lease in the shared database.
+// The leader renews the lease three times per TTL; a standby tries to take it
+// as often, so it takes over within about one TTL after the leader stops
+// renewing. A node that loses the lease stops competing. Instance clocks must
+// be in sync.
+type LeaderElector struct {
+	repo   repository.LeaseRepository
+	nodeID string
+	ttl    time.Duration
+
+	onElected func() error // Starts the subsystems only the leader runs
+	onLost    func()       // Called once when the lease is lost after being held
+
+	mu         sync.RWMutex
+	leader     bool
+	since      time.Time
+	validUntil time.Time // Expiry of the last successful renewal
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewLeaderElector creates a leader elector for the node
+func NewLeaderElector(repo repository.LeaseRepository, nodeID string, ttl time.Duration) *LeaderElector {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &LeaderElector{
+		repo:   repo,
+		nodeID: nodeID,
+		ttl:    ttl,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// SetCallbacks sets what runs when the node takes the lease, and when it
+// loses it again
+func (e *LeaderElector) SetCallbacks(onElected func() error, onLost func()) {
+	e.onElected = onElected
+	e.onLost = onLost
+}
+
+// Start starts competing for the lease
+func (e *LeaderElector) Start() {
+	log.Printf("Cluster node %s competing for the leader lease", e.nodeID)
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		ticker := time.NewTicker(e.ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			if !e.tick() {
+				return
+			}
+			select {
+			case <-e.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops competing for the lease; a leader keeps it until Release
+func (e *LeaderElector) Stop() {
+	e.cancel()
+	e.wg.Wait()
+}
+
+// Release gives up the lease after the leader's subsystems have stopped, so
+// another node takes over at once instead of waiting for it to expire
+func (e *LeaderElector) Release() {
+	if !e.IsLeader() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.repo.Release(ctx, domain.LeaseLeader, e.nodeID); err != nil {
+		log.Printf("Failed to release leader lease: %v", err)
+		return
+	}
+	log.Printf("Cluster node %s released the leader lease", e.nodeID)
+}
+
+// tick takes or renews the lease; it returns false once leadership is lost
+func (e *LeaderElector) tick() bool {
+	now := time.Now()
+	ctx, cancel := context.WithTimeout(e.ctx, e.ttl/3)
+	acquired, err := e.repo.Acquire(ctx, domain.LeaseLeader, e.nodeID, now, e.ttl)
+	cancel()
+
+	leader := e.IsLeader()
+	switch {
+	case err != nil && e.ctx.Err() != nil:
+		return false
+	case err != nil:
+		// Keep leading through short database outages while our lease is
+		// valid; past it, the standby may already have taken over
+		log.Printf("Failed to renew leader lease: %v", err)
+		if leader && time.Now().After(e.validUntil) {
+			e.lose()
+			return false
+		}
+	case acquired:
+		e.mu.Lock()
+		e.validUntil = now.Add(e.ttl)
+		e.mu.Unlock()
+		if !leader {
+			return e.elect(now)
+		}
+	case leader:
+		// The lease expired and the standby took it
+		e.lose()
+		return false
+	}
+	return true
+}
+
+// elect becomes leader and starts the leader's subsystems in the background,
+// as connecting to the broker may take longer than the renewal interval
+func (e *LeaderElector) elect(now time.Time) bool {
+	log.Printf("Cluster node %s took the leader lease", e.nodeID)
+	e.mu.Lock()
+	e.leader = true
+	e.since = now
+	e.mu.Unlock()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		if err := e.onElected(); err != nil {
+			log.Printf("Failed to start as leader: %v", err)
+			e.cancel()
+			e.lose()
+		}
+	}()
+	return true
+}
+
+func (e *LeaderElector) lose() {
+	e.mu.Lock()
+	wasLeader := e.leader
+	e.leader = false
+	e.mu.Unlock()
+
+	if wasLeader {
+		log.Printf("Cluster node %s lost the leader lease", e.nodeID)
+		e.onLost()
+	}
+}
+
+// IsLeader reports whether this node holds the leader lease
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// Status returns this node's role and the current leader
+func (e *LeaderElector) Status(ctx context.Context) (*domain.LeaderStatus, error) {
+	e.mu.RLock()
+	status := &domain.LeaderStatus{
+		Enabled:    true,
+		InstanceID: e.nodeID,
+		Leader:     e.leader,
+	}
+	if e.leader {
+		since := e.since
+		status.Since = &since
+	}
+	e.mu.RUnlock()
+
+	lease, err := e.repo.Get(ctx, domain.LeaseLeader)
+	if errors.Is(err, repository.ErrNotFound) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lease.ExpiresAt.After(time.Now()) {
+		status.Holder = lease.Holder
+		expiresAt := lease.ExpiresAt
+		status.ExpiresAt = &expiresAt
+	}
+	return status, nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+// ErrInvalidLocale is returned for a locale that is not a language tag
+var ErrInvalidLocale = errors.New("invalid locale, expected a language tag such as de or pt-BR")
+
+// localePattern matches language tags such as "de", "pt-BR" or "zh_Hant"
+var localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}([-_][a-zA-Z0-9]{2,8})*$`)
+
+// LocaleService selects the language entity names and enum labels are
+// published to Home Assistant in, kept in the settings
+type LocaleService struct {
+	settings    *SettingService
+	profileRepo repository.ProfileRepository
+	bus         *eventbus.Bus
+}
+
+// NewLocaleService creates a locale selection kept in the settings
+func NewLocaleService(settings *SettingService, profileRepo repository.ProfileRepository, bus *eventbus.Bus) *LocaleService {
+	return &LocaleService{settings: settings, profileRepo: profileRepo, bus: bus}
+}
+
+// Locale returns the selected locale, empty for English
+func (s *LocaleService) Locale(ctx context.Context) string {
+	locale, _ := s.settings.Get(ctx, domain.SettingLocale)
+	return locale
+}
+
+// Status returns the selected locale and the locales any profile is translated to
+func (s *LocaleService) Status(ctx context.Context) (*domain.LocaleStatus, error) {
+	profiles, err := s.profileRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	available := []string{}
+	for _, profile := range profiles {
+		for _, locale := range profile.Translations.Locales() {
+			if !seen[locale] {
+				seen[locale] = true
+				available = append(available, locale)
+			}
+		}
+	}
+	sort.Strings(available)
+
+	return &domain.LocaleStatus{Locale: s.Locale(ctx), Available: available}, nil
+}
+
+// Set selects a locale, or English when empty, and announces it on the bus so
+// discovery and states are republished
+func (s *LocaleService) Set(ctx context.Context, locale string) (*domain.LocaleStatus, error) {
+	if locale != "" && !localePattern.MatchString(locale) {
+		return nil, ErrInvalidLocale
+	}
+
+	var err error
+	if locale == "" {
+		err = s.settings.Delete(ctx, domain.SettingLocale)
+	} else {
+		err = s.settings.Set(ctx, domain.SettingLocale, locale)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.bus.Publish(eventbus.Event{Type: eventbus.TypeLocale, Payload: locale})
+	return s.Status(ctx)
+}
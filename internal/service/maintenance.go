@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+// ErrVacuumRunning is returned when a vacuum is requested while one runs
+var ErrVacuumRunning = errors.New("a database vacuum is already running")
+
+// MaintenanceService reclaims the space trap and event purges leave in the
+// database, on a schedule and on request
+type MaintenanceService struct {
+	repo     repository.MaintenanceRepository
+	interval time.Duration
+
+	mu         sync.Mutex
+	running    bool
+	lastVacuum *domain.VacuumResult
+	nextVacuum time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewMaintenanceService creates a maintenance service that vacuums the
+// database every interval; 0 only vacuums on request
+func NewMaintenanceService(repo repository.MaintenanceRepository, interval time.Duration) *MaintenanceService {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &MaintenanceService{
+		repo:     repo,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start starts the vacuum schedule
+func (s *MaintenanceService) Start() {
+	if s.interval <= 0 {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		s.schedule(time.Now())
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.schedule(now)
+				result, err := s.Vacuum(s.ctx)
+				switch {
+				case err != nil && s.ctx.Err() == nil && !errors.Is(err, ErrVacuumRunning):
+					log.Printf("Scheduled database vacuum failed: %v", err)
+				case err == nil:
+					log.Printf("Scheduled database vacuum reclaimed %d bytes in %.1fs", result.Reclaimed, result.Duration)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the vacuum schedule, waiting for a running scheduled vacuum
+func (s *MaintenanceService) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *MaintenanceService) schedule(now time.Time) {
+	s.mu.Lock()
+	s.nextVacuum = now.Add(s.interval)
+	s.mu.Unlock()
+}
+
+// Vacuum reclaims free space and reports the database size before and after
+func (s *MaintenanceService) Vacuum(ctx context.Context) (*domain.VacuumResult, error) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil, ErrVacuumRunning
+	}
+	s.running = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	before, err := s.repo.Size(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database size: %w", err)
+	}
+	startedAt := time.Now()
+	if err := s.repo.Vacuum(ctx); err != nil {
+		return nil, fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	after, err := s.repo.Size(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database size: %w", err)
+	}
+
+	result := &domain.VacuumResult{
+		StartedAt:  startedAt.UTC(),
+		Duration:   time.Since(startedAt).Seconds(),
+		SizeBefore: before,
+		SizeAfter:  after,
+		Reclaimed:  before - after,
+	}
+	s.mu.Lock()
+	s.lastVacuum = result
+	s.mu.Unlock()
+	return result, nil
+}
+
+// Size returns the bytes the database occupies
+func (s *MaintenanceService) Size(ctx context.Context) (int64, error) {
+	return s.repo.Size(ctx)
+}
+
+// Status returns the database size, the last vacuum and when the next is due
+func (s *MaintenanceService) Status(ctx context.Context) (*domain.MaintenanceStatus, error) {
+	size, err := s.repo.Size(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := &domain.MaintenanceStatus{Size: size, LastVacuum: s.lastVacuum}
+	if !s.nextVacuum.IsZero() {
+		next := s.nextVacuum.UTC()
+		status.NextVacuum = &next
+	}
+	return status, nil
+}
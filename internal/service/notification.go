@@ -0,0 +1,772 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+)
+
+const (
+	defaultSMTPPort          = 587
+	defaultNotifyMaxPerHour  = 20
+	defaultNotifyMinSeverity = domain.SeverityWarning
+	notificationWindow       = time.Hour
+	escalationCheckInterval  = 30 * time.Second
+
+	// Events wait in a bounded queue so slow channels never hold up the event
+	// bus; sends run on a few workers, each bounded by its own timeout
+	notificationQueueSize   = 256
+	notificationWorkers     = 3
+	notificationSendTimeout = time.Minute
+
+	// maxHeldNotifications bounds what quiet hours hold back; the oldest are
+	// dropped beyond it
+	maxHeldNotifications = 100
+)
+
+// ErrUnknownChannel is returned for a notification channel that doesn't exist
+var ErrUnknownChannel = errors.New("unknown notification channel")
+
+// Notifier delivers notifications over one channel
+type Notifier interface {
+	Name() string
+	Notify(notification *domain.Notification) error
+}
+
+// NotificationStats reports delivery outcomes of a notification channel
+type NotificationStats struct {
+	Sent       uint64     `json:"sent"`
+	Failed     uint64     `json:"failed"`
+	Suppressed uint64     `json:"suppressed"` // Dropped by the hourly rate limit
+	LastSent   *time.Time `json:"last_sent,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+// PolicyStats counts notifications affected by the notification policy
+type PolicyStats struct {
+	QuietHours   uint64 `json:"quiet_hours"`  // Held back by quiet hours
+	Deduplicated uint64 `json:"deduplicated"` // Dropped as repeats within the dedup window
+	Escalated    uint64 `json:"escalated"`    // Re-sent because they stayed unresolved
+	Dropped      uint64 `json:"dropped"`      // Lost because the notification queue or the quiet hours backlog was full
+}
+
+// delivery is one notification waiting to be sent over one channel
+type delivery struct {
+	channel      string
+	notifier     Notifier
+	notification *domain.Notification
+}
+
+// pendingEscalation is an offline notification re-sent unless the device
+// comes back online before it is due
+type pendingEscalation struct {
+	notification *domain.Notification
+	due          time.Time
+}
+
+// channelState tracks rate limiting and delivery counters of one channel
+type channelState struct {
+	sent       []time.Time // Send times within the rate limit window
+	suppressed int         // Suppressed since the last sent notification
+	stats      NotificationStats
+}
+
+// NotificationService turns traps and device events into notifications and
+// sends them over the enabled channels (email, Telegram, Pushover). Quiet
+// hours, deduplication and escalation are applied here, before any channel;
+// notifications held back by quiet hours are sent once they end.
+// Settings are read on every notification, so changes made through the
+// settings API apply immediately. The event bus subscriber only queues
+// events: one goroutine applies the policy in order and hands the sends to
+// a pool of workers.
+type NotificationService struct {
+	settings *SettingService
+	devices  *DeviceService
+	bus      *eventbus.Bus
+
+	mu          sync.Mutex
+	channels    map[string]*channelState
+	recent      map[string]time.Time          // Dedup key -> last sent
+	escalations map[string]*pendingEscalation // Device ID -> pending offline escalation
+	held        []*domain.Notification        // Held back by quiet hours, oldest first
+	policyStats PolicyStats
+	events      chan eventbus.Event
+	deliveries  chan delivery
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewNotificationService creates a new notification service
+func NewNotificationService(settings *SettingService, devices *DeviceService, bus *eventbus.Bus) *NotificationService {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	channels := make(map[string]*channelState, len(domain.NotificationChannels))
+	for _, name := range domain.NotificationChannels {
+		channels[name] = &channelState{}
+	}
+
+	return &NotificationService{
+		settings:    settings,
+		devices:     devices,
+		bus:         bus,
+		channels:    channels,
+		recent:      make(map[string]time.Time),
+		escalations: make(map[string]*pendingEscalation),
+		events:      make(chan eventbus.Event, notificationQueueSize),
+		deliveries:  make(chan delivery, notificationQueueSize),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Start starts sending notifications for traps and device events
+func (s *NotificationService) Start() {
+	sub := s.bus.Subscribe(eventbus.TypeTrapReceived, eventbus.TypeDeviceEvent)
+
+	s.wg.Add(2 + notificationWorkers)
+	go func() {
+		defer s.wg.Done()
+		defer s.bus.Unsubscribe(sub)
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case evt, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				s.enqueue(evt)
+			}
+		}
+	}()
+	go s.runDispatcher()
+	for i := 0; i < notificationWorkers; i++ {
+		go s.runWorker()
+	}
+}
+
+// enqueue queues an event without waiting; when the queue is full the event
+// is counted and dropped
+func (s *NotificationService) enqueue(evt eventbus.Event) {
+	select {
+	case s.events <- evt:
+	default:
+		s.mu.Lock()
+		s.policyStats.Dropped++
+		s.mu.Unlock()
+		log.Printf("Notification queue full, dropping %s event for device %s", evt.Type, evt.DeviceID)
+	}
+}
+
+// runDispatcher turns queued events into notifications and applies the
+// policy to them in the order they were published
+func (s *NotificationService) runDispatcher() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(escalationCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.escalate(now)
+			s.releaseHeld(now)
+		case evt := <-s.events:
+			switch evt.Type {
+			case eventbus.TypeTrapReceived:
+				if trap, ok := evt.Payload.(*domain.TrapLog); ok {
+					s.dispatch(s.trapNotification(trap), time.Now())
+				}
+			case eventbus.TypeDeviceEvent:
+				if event, ok := evt.Payload.(*domain.DeviceEvent); ok {
+					s.dispatch(s.eventNotification(event), time.Now())
+				}
+			}
+		}
+	}
+}
+
+// runWorker sends queued deliveries until the service stops
+func (s *NotificationService) runWorker() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case d := <-s.deliveries:
+			s.deliver(d)
+		}
+	}
+}
+
+// deliver sends one notification, giving up after notificationSendTimeout.
+// A send that times out keeps running in the background until the
+// notifier's own network timeouts end it, but no longer holds up the worker.
+func (s *NotificationService) deliver(d delivery) {
+	done := make(chan error, 1)
+	go func() {
+		done <- d.notifier.Notify(d.notification)
+	}()
+
+	timer := time.NewTimer(notificationSendTimeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-timer.C:
+		err = fmt.Errorf("timed out after %s", notificationSendTimeout)
+	}
+
+	s.record(d.channel, err)
+	if err != nil {
+		log.Printf("Failed to send %s notification %q: %v", d.notifier.Name(), d.notification.Title, err)
+	}
+}
+
+// Stop stops sending notifications
+func (s *NotificationService) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Stats returns delivery counters by channel
+func (s *NotificationService) Stats() map[string]NotificationStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make(map[string]NotificationStats, len(s.channels))
+	for name, state := range s.channels {
+		stats[name] = state.stats
+	}
+	return stats
+}
+
+// PolicyStats returns how many notifications the policy held back, dropped
+// or escalated
+func (s *NotificationService) PolicyStats() PolicyStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.policyStats
+}
+
+// Policy returns the current notification policy
+func (s *NotificationService) Policy(ctx context.Context) (*domain.NotificationPolicy, error) {
+	values, err := s.loadSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return notificationPolicy(values), nil
+}
+
+// Channels returns the common settings of every channel
+func (s *NotificationService) Channels(ctx context.Context) ([]domain.NotificationChannel, error) {
+	values, err := s.loadSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	channels := make([]domain.NotificationChannel, 0, len(domain.NotificationChannels))
+	for _, name := range domain.NotificationChannels {
+		channels = append(channels, *channelConfig(values, name))
+	}
+	return channels, nil
+}
+
+// Test sends a test notification over one channel with the current settings,
+// bypassing filters, severity routing and rate limiting. The channel doesn't
+// need to be enabled.
+func (s *NotificationService) Test(ctx context.Context, channel string) error {
+	if !slices.Contains(domain.NotificationChannels, channel) {
+		return ErrUnknownChannel
+	}
+
+	values, err := s.loadSettings(ctx)
+	if err != nil {
+		return err
+	}
+	notifier, err := newNotifier(channel, values)
+	if err != nil {
+		return err
+	}
+	if smtp, ok := notifier.(*SMTPNotifier); ok {
+		smtp.settings.SeverityTo = nil
+	}
+
+	notification := &domain.Notification{
+		Severity:  domain.SeverityInfo,
+		Source:    domain.NotificationSourceTest,
+		EventType: domain.NotificationSourceTest,
+		Title:     "Test notification",
+		Message:   fmt.Sprintf("%s notifications from the SNMP-MQTT bridge are working.", channelLabel(channel)),
+		Time:      time.Now(),
+	}
+	err = notifier.Notify(notification)
+	s.record(channel, err)
+	return err
+}
+
+func (s *NotificationService) loadSettings(ctx context.Context) (map[string]string, error) {
+	all, err := s.settings.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(all))
+	for _, setting := range all {
+		values[setting.Key] = setting.Value
+	}
+	return values, nil
+}
+
+// dispatch applies the notification policy at now, then sends the
+// notification or holds it back until the quiet hours end
+func (s *NotificationService) dispatch(notification *domain.Notification, now time.Time) {
+	values, err := s.loadSettings(s.ctx)
+	if err != nil {
+		log.Printf("Failed to load notification settings: %v", err)
+		return
+	}
+
+	policy := notificationPolicy(values)
+	if !notification.Escalated {
+		s.trackEscalation(policy, notification, now)
+	}
+	if !policy.Allows(notification, now) {
+		s.hold(notification)
+		return
+	}
+	s.send(values, policy, notification, now)
+}
+
+// hold keeps a notification until the quiet hours end
+func (s *NotificationService) hold(notification *domain.Notification) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.policyStats.QuietHours++
+	s.held = append(s.held, notification)
+	if excess := len(s.held) - maxHeldNotifications; excess > 0 {
+		s.held = s.held[excess:]
+		s.policyStats.Dropped += uint64(excess)
+	}
+}
+
+// releaseHeld sends the notifications held back by quiet hours once they
+// are over, oldest first
+func (s *NotificationService) releaseHeld(now time.Time) {
+	s.mu.Lock()
+	empty := len(s.held) == 0
+	s.mu.Unlock()
+	if empty {
+		return
+	}
+
+	values, err := s.loadSettings(s.ctx)
+	if err != nil {
+		log.Printf("Failed to load notification settings: %v", err)
+		return
+	}
+	policy := notificationPolicy(values)
+	if policy.InQuietHours(now) {
+		return
+	}
+
+	s.mu.Lock()
+	held := s.held
+	s.held = nil
+	s.mu.Unlock()
+
+	log.Printf("Quiet hours over, sending %d held notifications", len(held))
+	for _, notification := range held {
+		notification.Message += "\n\nHeld back by quiet hours."
+		s.send(values, policy, notification, now)
+	}
+}
+
+// send queues a notification for every enabled channel whose filter matches
+// and whose hourly limit allows it, unless it repeats one sent within the
+// dedup window
+func (s *NotificationService) send(values map[string]string, policy *domain.NotificationPolicy, notification *domain.Notification, now time.Time) {
+	if !s.dedup(policy.DedupWindow, notification, now) {
+		return
+	}
+
+	for _, name := range domain.NotificationChannels {
+		channel := channelConfig(values, name)
+		if !channel.Enabled || !channel.Filter.Matches(notification) {
+			continue
+		}
+
+		notifier, err := newNotifier(name, values)
+		if err != nil {
+			log.Printf("%s notifications misconfigured: %v", channelLabel(name), err)
+			continue
+		}
+		// Email can route severities to nobody
+		if smtp, ok := notifier.(*SMTPNotifier); ok && len(smtp.settings.Recipients(notification.Severity)) == 0 {
+			continue
+		}
+
+		// Each channel gets its own copy so rate limit notes don't leak across channels
+		n := *notification
+		if !s.allow(name, channel.MaxPerHour, &n) {
+			log.Printf("%s notification rate limit reached, suppressed %q", channelLabel(name), n.Title)
+			continue
+		}
+
+		select {
+		case s.deliveries <- delivery{channel: name, notifier: notifier, notification: &n}:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// dedup reports whether a notification is new within the window and
+// remembers it. Escalations are never deduplicated.
+func (s *NotificationService) dedup(window time.Duration, notification *domain.Notification, now time.Time) bool {
+	if window <= 0 || notification.Escalated {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, at := range s.recent {
+		if now.Sub(at) >= window {
+			delete(s.recent, key)
+		}
+	}
+
+	key := notification.DedupKey()
+	if _, ok := s.recent[key]; ok {
+		s.policyStats.Deduplicated++
+		return false
+	}
+	s.recent[key] = now
+	return true
+}
+
+// trackEscalation schedules an escalation for offline events and cancels it
+// when the device comes back online
+func (s *NotificationService) trackEscalation(policy *domain.NotificationPolicy, notification *domain.Notification, now time.Time) {
+	if notification.DeviceID == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch domain.EventType(notification.EventType) {
+	case domain.EventTypeOnline:
+		delete(s.escalations, notification.DeviceID)
+	case domain.EventTypeOffline:
+		if policy.EscalateAfter <= 0 {
+			return
+		}
+		if _, pending := s.escalations[notification.DeviceID]; pending {
+			return
+		}
+		escalated := *notification
+		escalated.Escalated = true
+		escalated.Severity = notification.Severity.Escalate()
+		escalated.Title = "Unresolved: " + notification.Title
+		escalated.Message += fmt.Sprintf("\n\nStill unresolved after %d minutes.", int(policy.EscalateAfter/time.Minute))
+		s.escalations[notification.DeviceID] = &pendingEscalation{
+			notification: &escalated,
+			due:          now.Add(policy.EscalateAfter),
+		}
+	}
+}
+
+// escalate sends the escalations that are due
+func (s *NotificationService) escalate(now time.Time) {
+	var due []*domain.Notification
+
+	s.mu.Lock()
+	for deviceID, pending := range s.escalations {
+		if !now.Before(pending.due) {
+			pending.notification.Time = now
+			due = append(due, pending.notification)
+			delete(s.escalations, deviceID)
+			s.policyStats.Escalated++
+		}
+	}
+	s.mu.Unlock()
+
+	for _, notification := range due {
+		s.dispatch(notification, now)
+	}
+}
+
+// allow applies a channel's hourly rate limit. Notifications that pass
+// mention how many were suppressed since the last one went out.
+func (s *NotificationService) allow(channel string, maxPerHour int, notification *domain.Notification) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.channels[channel]
+	now := time.Now()
+	i := 0
+	for i < len(state.sent) && now.Sub(state.sent[i]) > notificationWindow {
+		i++
+	}
+	state.sent = state.sent[i:]
+
+	if maxPerHour > 0 && len(state.sent) >= maxPerHour {
+		state.suppressed++
+		state.stats.Suppressed++
+		return false
+	}
+
+	state.sent = append(state.sent, now)
+	if state.suppressed > 0 {
+		notification.Message += fmt.Sprintf("\n\n%d earlier notifications were suppressed by the hourly rate limit.", state.suppressed)
+		state.suppressed = 0
+	}
+	return true
+}
+
+func (s *NotificationService) record(channel string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := &s.channels[channel].stats
+	if err != nil {
+		stats.Failed++
+		stats.LastError = err.Error()
+		return
+	}
+	now := time.Now()
+	stats.Sent++
+	stats.LastSent = &now
+	stats.LastError = ""
+}
+
+func (s *NotificationService) trapNotification(trap *domain.TrapLog) *domain.Notification {
+	notification := &domain.Notification{
+		Severity:   trap.Severity,
+		Source:     domain.NotificationSourceTrap,
+		EventType:  domain.NotificationSourceTrap,
+		Title:      trap.Message,
+		DeviceName: trap.SourceIP,
+		Time:       trap.ReceivedAt,
+	}
+	if trap.DeviceID != nil {
+		s.describeDevice(notification, *trap.DeviceID)
+	}
+
+	lines := []string{trap.Message, "", "Trap OID: " + trap.TrapOID, "Source:   " + trap.SourceIP}
+	for _, v := range trap.Resolved {
+		line := fmt.Sprintf("%s: %v", v.Name, v.Value)
+		if v.Unit != "" {
+			line += " " + v.Unit
+		}
+		lines = append(lines, line)
+	}
+	notification.Message = strings.Join(lines, "\n")
+	return notification
+}
+
+func (s *NotificationService) eventNotification(event *domain.DeviceEvent) *domain.Notification {
+	notification := &domain.Notification{
+		Severity:  event.Severity(),
+		Source:    domain.NotificationSourceEvent,
+		EventType: string(event.Type),
+		Title:     event.Message,
+		Message:   event.Message,
+		Time:      event.CreatedAt,
+	}
+	s.describeDevice(notification, event.DeviceID)
+	return notification
+}
+
+func (s *NotificationService) describeDevice(notification *domain.Notification, deviceID string) {
+	notification.DeviceID = deviceID
+	if device, err := s.devices.GetByID(s.ctx, deviceID); err == nil {
+		notification.DeviceName = device.Name
+		notification.Site = device.Site
+	}
+}
+
+// channelConfig reads the settings shared by all channels
+func channelConfig(values map[string]string, name string) *domain.NotificationChannel {
+	key := func(setting string) string { return domain.NotificationSettingKey(name, setting) }
+
+	channel := &domain.NotificationChannel{
+		Name:    name,
+		Enabled: values[key(domain.ChannelSettingEnabled)] == "true",
+		Filter: domain.ChannelFilter{
+			MinSeverity: defaultNotifyMinSeverity,
+			Devices:     splitList(values[key(domain.ChannelSettingDevices)]),
+			EventTypes:  splitList(values[key(domain.ChannelSettingEventTypes)]),
+		},
+		MaxPerHour: defaultNotifyMaxPerHour,
+	}
+	if severity := values[key(domain.ChannelSettingMinSeverity)]; severity != "" {
+		channel.Filter.MinSeverity = domain.TrapSeverity(strings.ToLower(severity))
+	}
+	if max, err := strconv.Atoi(values[key(domain.ChannelSettingMaxPerHour)]); err == nil && max >= 0 {
+		channel.MaxPerHour = max
+	}
+	return channel
+}
+
+// newNotifier builds and validates the notifier of a channel
+func newNotifier(name string, values map[string]string) (Notifier, error) {
+	switch name {
+	case domain.NotificationChannelSMTP:
+		cfg := smtpSettings(values)
+		if err := validateSMTPSettings(cfg); err != nil {
+			return nil, err
+		}
+		return NewSMTPNotifier(cfg), nil
+	case domain.NotificationChannelTelegram:
+		cfg := &domain.TelegramSettings{
+			BotToken: values[domain.SettingTelegramBotToken],
+			ChatID:   values[domain.SettingTelegramChatID],
+		}
+		switch {
+		case cfg.BotToken == "":
+			return nil, fmt.Errorf("%s is not set", domain.SettingTelegramBotToken)
+		case cfg.ChatID == "":
+			return nil, fmt.Errorf("%s is not set", domain.SettingTelegramChatID)
+		}
+		return NewTelegramNotifier(cfg), nil
+	case domain.NotificationChannelPushover:
+		cfg := &domain.PushoverSettings{
+			Token:  values[domain.SettingPushoverToken],
+			User:   values[domain.SettingPushoverUser],
+			Device: values[domain.SettingPushoverDevice],
+		}
+		switch {
+		case cfg.Token == "":
+			return nil, fmt.Errorf("%s is not set", domain.SettingPushoverToken)
+		case cfg.User == "":
+			return nil, fmt.Errorf("%s is not set", domain.SettingPushoverUser)
+		}
+		return NewPushoverNotifier(cfg), nil
+	}
+	return nil, ErrUnknownChannel
+}
+
+// notificationPolicy reads the dispatcher rules. Quiet hours are only active
+// when both start and end parse as local "15:04" times.
+func notificationPolicy(values map[string]string) *domain.NotificationPolicy {
+	policy := &domain.NotificationPolicy{QuietMinSeverity: domain.SeverityCritical}
+
+	start, startErr := time.Parse("15:04", values[domain.SettingQuietHoursStart])
+	end, endErr := time.Parse("15:04", values[domain.SettingQuietHoursEnd])
+	if startErr == nil && endErr == nil {
+		policy.QuietHours = true
+		policy.QuietStart = time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute
+		policy.QuietEnd = time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute
+	}
+	if severity := values[domain.SettingQuietHoursMinSeverity]; severity != "" {
+		policy.QuietMinSeverity = domain.TrapSeverity(strings.ToLower(severity))
+	}
+	if minutes, err := strconv.Atoi(values[domain.SettingDedupMinutes]); err == nil && minutes > 0 {
+		policy.DedupWindow = time.Duration(minutes) * time.Minute
+	}
+	if minutes, err := strconv.Atoi(values[domain.SettingEscalationMinutes]); err == nil && minutes > 0 {
+		policy.EscalateAfter = time.Duration(minutes) * time.Minute
+	}
+	return policy
+}
+
+func smtpSettings(values map[string]string) *domain.SMTPSettings {
+	cfg := &domain.SMTPSettings{
+		Host:       values[domain.SettingSMTPHost],
+		Port:       defaultSMTPPort,
+		Username:   values[domain.SettingSMTPUsername],
+		Password:   values[domain.SettingSMTPPassword],
+		Security:   strings.ToLower(values[domain.SettingSMTPSecurity]),
+		From:       values[domain.SettingSMTPFrom],
+		To:         splitList(values[domain.SettingSMTPTo]),
+		SeverityTo: make(map[domain.TrapSeverity][]string),
+		Subject:    values[domain.SettingSMTPSubject],
+		Body:       values[domain.SettingSMTPBody],
+	}
+	if port, err := strconv.Atoi(values[domain.SettingSMTPPort]); err == nil && port > 0 {
+		cfg.Port = port
+	}
+	for _, severity := range []domain.TrapSeverity{domain.SeverityInfo, domain.SeverityWarning, domain.SeverityError, domain.SeverityCritical} {
+		if to, ok := values[domain.SettingSMTPTo+"."+string(severity)]; ok {
+			cfg.SeverityTo[severity] = splitList(to)
+		}
+	}
+	return cfg
+}
+
+func validateSMTPSettings(cfg *domain.SMTPSettings) error {
+	switch {
+	case cfg.Host == "":
+		return fmt.Errorf("%s is not set", domain.SettingSMTPHost)
+	case cfg.From == "":
+		return fmt.Errorf("%s is not set", domain.SettingSMTPFrom)
+	case len(cfg.To) == 0 && len(cfg.SeverityTo) == 0:
+		return fmt.Errorf("%s is not set", domain.SettingSMTPTo)
+	}
+	switch cfg.Security {
+	case "", "starttls", "tls", "none":
+	default:
+		return fmt.Errorf("%s must be starttls, tls or none", domain.SettingSMTPSecurity)
+	}
+	return nil
+}
+
+func channelLabel(name string) string {
+	switch name {
+	case domain.NotificationChannelSMTP:
+		return "Email"
+	case domain.NotificationChannelTelegram:
+		return "Telegram"
+	case domain.NotificationChannelPushover:
+		return "Pushover"
+	}
+	return name
+}
+
+// notificationTitle prefixes the title with the device name
+func notificationTitle(n *domain.Notification) string {
+	if n.DeviceName != "" {
+		return n.DeviceName + ": " + n.Title
+	}
+	return n.Title
+}
+
+// notificationDetails is the plain text body used by the push channels
+func notificationDetails(n *domain.Notification) string {
+	lines := []string{n.Message, ""}
+	if n.Site != "" {
+		lines = append(lines, "Site: "+n.Site)
+	}
+	lines = append(lines, "Time: "+n.Time.Format("2006-01-02 15:04:05 MST"))
+	return strings.Join(lines, "\n")
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+	"snmp-mqtt-bridge/internal/repository/memory"
+)
+
+func TestNotificationEnqueueNeverBlocks(t *testing.T) {
+	s := NewNotificationService(nil, nil, nil)
+
+	// Nothing drains the queue, so everything past its capacity is dropped
+	for i := 0; i < notificationQueueSize+5; i++ {
+		s.enqueue(eventbus.Event{Type: eventbus.TypeDeviceEvent, DeviceID: "ups"})
+	}
+
+	if got := len(s.events); got != notificationQueueSize {
+		t.Errorf("queued %d events, want %d", got, notificationQueueSize)
+	}
+	if got := s.PolicyStats().Dropped; got != 5 {
+		t.Errorf("dropped %d events, want 5", got)
+	}
+}
+
+func TestQuietHoursHoldNotificationsUntilTheyEnd(t *testing.T) {
+	ctx := context.Background()
+	settings := NewSettingService(memory.NewSettingRepository())
+	settings.Set(ctx, domain.SettingQuietHoursStart, "22:00")
+	settings.Set(ctx, domain.SettingQuietHoursEnd, "07:00")
+	settings.Set(ctx, domain.SettingQuietHoursMinSeverity, "critical")
+	settings.Set(ctx, domain.SettingDedupMinutes, "60")
+	s := NewNotificationService(settings, nil, nil)
+
+	night := time.Date(2026, 1, 10, 23, 0, 0, 0, time.Local)
+	warning := &domain.Notification{Severity: domain.SeverityWarning, Source: domain.NotificationSourceEvent, EventType: "offline", DeviceID: "ups", Title: "UPS offline", Time: night}
+	critical := &domain.Notification{Severity: domain.SeverityCritical, Source: domain.NotificationSourceTrap, DeviceID: "ups", Title: "On battery", Time: night}
+
+	s.dispatch(warning, night)
+	s.dispatch(critical, night)
+
+	if len(s.held) != 1 || s.held[0] != warning {
+		t.Fatalf("held %d notifications, want only the warning", len(s.held))
+	}
+	if _, sent := s.recent[critical.DedupKey()]; !sent {
+		t.Error("critical notification was not sent during quiet hours")
+	}
+
+	// Still quiet: nothing is released
+	s.releaseHeld(night.Add(time.Hour))
+	if len(s.held) != 1 {
+		t.Fatalf("released %d notifications during quiet hours", 1-len(s.held))
+	}
+
+	morning := time.Date(2026, 1, 11, 7, 0, 30, 0, time.Local)
+	s.releaseHeld(morning)
+	if len(s.held) != 0 {
+		t.Fatalf("%d notifications still held after quiet hours", len(s.held))
+	}
+	if _, sent := s.recent[warning.DedupKey()]; !sent {
+		t.Error("held notification was not sent after quiet hours")
+	}
+	if got := s.PolicyStats().QuietHours; got != 1 {
+		t.Errorf("quiet hours count = %d, want 1", got)
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SetOIDBudget limits how many OIDs a single poll requests. OIDs due beyond
+// the budget are queued and polled in the following cycles, so huge profiles
+// (e.g. 48 outlets with per-outlet current) rotate through their mappings
+// with short polls. 0 polls every due OID at once.
+func (s *PollerService) SetOIDBudget(max int) {
+	s.oidBudget = max
+}
+
+// oidQueue holds the OIDs of a device waiting to be polled within the budget
+type oidQueue struct {
+	pending []string
+	queued  map[string]bool
+}
+
+// take queues the due OIDs not already waiting and returns up to max OIDs,
+// oldest first. Poll groups still decide when an OID becomes due.
+func (q *oidQueue) take(due []string, max int) []string {
+	if q.queued == nil {
+		q.queued = make(map[string]bool)
+	}
+
+	sort.Strings(due)
+	for _, oid := range due {
+		if !q.queued[oid] {
+			q.queued[oid] = true
+			q.pending = append(q.pending, oid)
+		}
+	}
+
+	n := max
+	if n > len(q.pending) {
+		n = len(q.pending)
+	}
+	batch := q.pending[:n:n]
+	q.pending = q.pending[n:]
+	for _, oid := range batch {
+		delete(q.queued, oid)
+	}
+	return batch
+}
+
+// deriveValues calculates derived values. Polls within a budget return only
+// part of the profile, so the last known values are filled in first; values
+// derived in the previous poll are left out so they are derived again from
+// fresh inputs.
+func (s *PollerService) deriveValues(dp *devicePoller, values map[string]interface{}) {
+	if s.oidBudget <= 0 {
+		s.calculateDerivedValues(dp.profile, values)
+		return
+	}
+
+	for k, v := range s.GetDeviceValues(dp.device.ID) {
+		if _, polled := values[k]; !polled && !dp.derived[k] {
+			values[k] = v
+		}
+	}
+
+	before := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		before[k] = v
+	}
+	s.calculateDerivedValues(dp.profile, values)
+
+	dp.derived = make(map[string]bool)
+	for k, v := range values {
+		if old, ok := before[k]; !ok || fmt.Sprint(old) != fmt.Sprint(v) {
+			dp.derived[k] = true
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// OID health statuses
+const (
+	OIDStatusOK      = "ok"      // Last poll returned a value
+	OIDStatusFailing = "failing" // Last poll failed, e.g. timeout or unparseable value
+	OIDStatusMissing = "missing" // Device has no such object; no longer polled
+	OIDStatusPending = "pending" // Not polled yet, e.g. a static group not due
+)
+
+// OIDHealth summarizes the poll results of one profile mapping
+type OIDHealth struct {
+	Name        string     `json:"name"`
+	OID         string     `json:"oid"`
+	Status      string     `json:"status"`
+	Success     int        `json:"success"`
+	Failure     int        `json:"failure"`
+	Missing     int        `json:"missing"`
+	Discarded   int        `json:"discarded"` // Readings outside the valid range, not published
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// OIDHealthReport summarizes which mappings of a device's profile return
+// data, since the device's poller was (re)started
+type OIDHealthReport struct {
+	DeviceID string         `json:"device_id"`
+	Polls    int            `json:"polls"`
+	Summary  map[string]int `json:"summary"` // Mappings per status
+	NoData   []string       `json:"no_data"` // Polled mappings that never returned a value
+	Mappings []OIDHealth    `json:"mappings"`
+}
+
+// oidStats counts the poll results of one OID
+type oidStats struct {
+	success     int
+	failure     int
+	missing     int
+	discarded   int
+	lastStatus  string
+	lastSuccess time.Time
+	lastError   string
+}
+
+// oidHealth tracks per-OID poll results of a device. It is written by the
+// device's poll goroutine and read by API requests.
+type oidHealth struct {
+	mu    sync.Mutex
+	polls int
+	stats map[string]*oidStats // by normalized OID
+}
+
+func newOIDHealth() *oidHealth {
+	return &oidHealth{stats: make(map[string]*oidStats)}
+}
+
+func (h *oidHealth) poll() {
+	h.mu.Lock()
+	h.polls++
+	h.mu.Unlock()
+}
+
+func (h *oidHealth) get(oid string) *oidStats {
+	st, ok := h.stats[oid]
+	if !ok {
+		st = &oidStats{}
+		h.stats[oid] = st
+	}
+	return st
+}
+
+func (h *oidHealth) success(oid string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st := h.get(normalizeOID(oid))
+	st.success++
+	st.lastStatus = OIDStatusOK
+	st.lastSuccess = time.Now()
+}
+
+func (h *oidHealth) failure(oid, message string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st := h.get(normalizeOID(oid))
+	st.failure++
+	st.lastStatus = OIDStatusFailing
+	st.lastError = message
+}
+
+func (h *oidHealth) missing(oid string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st := h.get(normalizeOID(oid))
+	st.missing++
+	st.lastStatus = OIDStatusMissing
+	st.lastError = "no such object"
+}
+
+// discard counts a reading dropped by the sanity filter; the OID still
+// answers, so its status is left alone
+func (h *oidHealth) discard(oid string, value interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st := h.get(normalizeOID(oid))
+	st.discarded++
+	st.lastError = fmt.Sprintf("discarded out-of-range value %v", value)
+}
+
+// CachedValue returns the raw value of an OID from the last poll that read
+// it, if that was at most maxAge ago, and when it was read
+func (s *PollerService) CachedValue(deviceID, oid string, maxAge time.Duration) (interface{}, time.Time, bool) {
+	s.devicesMu.RLock()
+	dp, exists := s.devices[deviceID]
+	s.devicesMu.RUnlock()
+	if !exists {
+		return nil, time.Time{}, false
+	}
+
+	dp.health.mu.Lock()
+	st, ok := dp.health.stats[normalizeOID(oid)]
+	var readAt time.Time
+	if ok {
+		readAt = st.lastSuccess
+	}
+	dp.health.mu.Unlock()
+	if readAt.IsZero() || time.Since(readAt) > maxAge {
+		return nil, time.Time{}, false
+	}
+
+	value, ok := s.GetDeviceValues(deviceID)["."+normalizeOID(oid)]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	return value, readAt, true
+}
+
+// OIDHealth reports per-mapping poll results for a device being polled
+func (s *PollerService) OIDHealth(deviceID string) (*OIDHealthReport, error) {
+	s.devicesMu.RLock()
+	dp, exists := s.devices[deviceID]
+	s.devicesMu.RUnlock()
+	if !exists {
+		return nil, ErrDeviceNotFound
+	}
+
+	h := dp.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	report := &OIDHealthReport{
+		DeviceID: deviceID,
+		Polls:    h.polls,
+		Summary:  map[string]int{},
+		NoData:   []string{},
+		Mappings: []OIDHealth{},
+	}
+	if dp.profile == nil {
+		return report, nil
+	}
+
+	for _, mapping := range dp.profile.OIDMappings {
+		if mapping.Computed {
+			continue
+		}
+
+		entry := OIDHealth{Name: mapping.Name, OID: mapping.OID, Status: OIDStatusPending}
+		if st, ok := h.stats[normalizeOID(mapping.OID)]; ok {
+			entry.Status = st.lastStatus
+			entry.Success = st.success
+			entry.Failure = st.failure
+			entry.Missing = st.missing
+			entry.Discarded = st.discarded
+			entry.LastError = st.lastError
+			if !st.lastSuccess.IsZero() {
+				lastSuccess := st.lastSuccess
+				entry.LastSuccess = &lastSuccess
+			}
+			if st.success == 0 {
+				report.NoData = append(report.NoData, mapping.Name)
+			}
+		}
+
+		report.Summary[entry.Status]++
+		report.Mappings = append(report.Mappings, entry)
+	}
+	return report, nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import "snmp-mqtt-bridge/internal/domain"
+
+// SetOwnership limits the poller to the devices owns returns true for, so
+// several instances can split a fleet. Must be called before Start; a nil
+// func polls every enabled device. Reconcile applies ownership changes.
+func (s *PollerService) SetOwnership(owns func(*domain.Device) bool) {
+	s.ownership = owns
+}
+
+func (s *PollerService) owns(device *domain.Device) bool {
+	return s.ownership == nil || s.ownership(device)
+}
+
+// ReleaseAll stops the pollers of all devices without reading the
+// repository, for a node whose devices the other nodes have taken over.
+// Reconcile starts the pollers of the devices owned again.
+func (s *PollerService) ReleaseAll() {
+	s.devicesMu.RLock()
+	ids := make([]string, 0, len(s.devices))
+	for id := range s.devices {
+		ids = append(ids, id)
+	}
+	s.devicesMu.RUnlock()
+
+	for _, id := range ids {
+		s.RemoveDevice(id)
+	}
+}
+
+// Polling reports whether the device currently has a poller on this instance
+func (s *PollerService) Polling(id string) bool {
+	s.devicesMu.RLock()
+	defer s.devicesMu.RUnlock()
+	_, ok := s.devices[id]
+	return ok
+}
+
+// PolledDevices returns the number of devices this instance polls
+func (s *PollerService) PolledDevices() int {
+	s.devicesMu.RLock()
+	defer s.devicesMu.RUnlock()
+	return len(s.devices)
+}
@@ -8,21 +8,20 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
 	"snmp-mqtt-bridge/internal/repository"
+	"snmp-mqtt-bridge/internal/timefmt"
+	"snmp-mqtt-bridge/internal/units"
 
 	"github.com/gosnmp/gosnmp"
 )
 
 // StateUpdateEvent is sent when a device state changes
-type StateUpdateEvent struct {
-	DeviceID  string                 `json:"device_id"`
-	Timestamp time.Time              `json:"timestamp"`
-	Values    map[string]interface{} `json:"values"`
-	Online    bool                   `json:"online"`
-}
+type StateUpdateEvent = eventbus.StateUpdate
 
 // PollerService manages SNMP polling for all devices
 type PollerService struct {
@@ -35,10 +34,25 @@ type PollerService struct {
 	states   map[string]*domain.DeviceState
 	statesMu sync.RWMutex
 
-	subscribers []chan StateUpdateEvent
-	subMu       sync.RWMutex
+	lastTraps map[string]time.Time // device ID -> latest trap, guarded by statesMu
+
+	bus *eventbus.Bus
+
+	polls      atomic.Uint64 // Polls since start
+	pollErrors atomic.Uint64 // Polls that failed since start
+	discarded  atomic.Uint64 // Readings dropped by the sanity filter since start
+
+	reconcileInterval time.Duration // How often pollers are checked against the repository; 0 disables it
+
+	ownership func(*domain.Device) bool // Devices this instance polls; nil polls all
 
 	defaultInterval time.Duration
+	fastInterval    time.Duration // Poll interval right after commands and state changes
+	fastDuration    time.Duration // How long fast polling lasts
+	oidBudget       int           // Max OIDs per poll; 0 is unlimited
+	staleAfter      int           // Polls without a value before it is stale; 0 disables it
+	warmup          time.Duration // Window the first polls after startup are spread over
+	startedAt       time.Time     // Guarded by devicesMu
 	ctx             context.Context
 	cancel          context.CancelFunc
 	wg              sync.WaitGroup
@@ -47,16 +61,24 @@ type PollerService struct {
 type devicePoller struct {
 	device      *domain.Device
 	profile     *domain.Profile
-	client      *gosnmp.GoSNMP
+	client      *SNMPConn
 	interval    time.Duration
 	stopCh      chan struct{}
 	triggerCh   chan struct{}
 	pollCount   int
 	missingOIDs map[string]bool // OIDs that returned NoSuchInstance - skip polling these
+	alarms      []domain.AlarmThreshold
+	alarmActive map[string]bool // alarm entity name -> currently active (for hysteresis)
+	health      *oidHealth      // Per-OID poll results
+	fastUntil   atomic.Int64    // Unix nanoseconds until which the device is polled fast
+	queue       oidQueue        // Due OIDs beyond the per-poll budget
+	derived     map[string]bool // Values derived in the last poll within a budget
+	startDelay  time.Duration   // Wait before the first poll, staggering startup
+	misses      map[string]int  // Mapping name -> consecutive polls requesting it without a value
 }
 
 // NewPollerService creates a new poller service
-func NewPollerService(deviceRepo repository.DeviceRepository, profileRepo repository.ProfileRepository, defaultInterval time.Duration) *PollerService {
+func NewPollerService(deviceRepo repository.DeviceRepository, profileRepo repository.ProfileRepository, bus *eventbus.Bus, defaultInterval time.Duration) *PollerService {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &PollerService{
@@ -64,7 +86,8 @@ func NewPollerService(deviceRepo repository.DeviceRepository, profileRepo reposi
 		profileRepo:     profileRepo,
 		devices:         make(map[string]*devicePoller),
 		states:          make(map[string]*domain.DeviceState),
-		subscribers:     make([]chan StateUpdateEvent, 0),
+		lastTraps:       make(map[string]time.Time),
+		bus:             bus,
 		defaultInterval: defaultInterval,
 		ctx:             ctx,
 		cancel:          cancel,
@@ -79,14 +102,78 @@ func (s *PollerService) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to load devices: %w", err)
 	}
 
+	var owned []*domain.Device
 	for i := range devices {
-		s.AddDevice(&devices[i])
+		if s.owns(&devices[i]) {
+			owned = append(owned, &devices[i])
+		}
+	}
+	s.devicesMu.Lock()
+	s.startedAt = time.Now()
+	s.devicesMu.Unlock()
+	for i, device := range owned {
+		s.addDevice(device, s.startDelay(i, len(owned)))
+	}
+	polled := len(owned)
+
+	// Follow device and profile changes made through the API
+	sub := s.bus.Subscribe(
+		eventbus.TypeDeviceCreated,
+		eventbus.TypeDeviceUpdated,
+		eventbus.TypeDeviceDeleted,
+		eventbus.TypeProfileUpdated,
+		eventbus.TypeProfileDeleted,
+	)
+	s.wg.Add(1)
+	go s.handleDeviceEvents(sub)
+
+	if s.reconcileInterval > 0 {
+		s.wg.Add(1)
+		go s.reconcileLoop()
 	}
 
-	log.Printf("Poller started with %d devices", len(devices))
+	if s.warmup > 0 && polled > 1 {
+		log.Printf("Poller started with %d devices, first polls spread over %s", polled, s.warmup)
+	} else {
+		log.Printf("Poller started with %d devices", polled)
+	}
 	return nil
 }
 
+func (s *PollerService) handleDeviceEvents(sub *eventbus.Subscription) {
+	defer s.wg.Done()
+	defer s.bus.Unsubscribe(sub)
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case evt, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if profile, ok := evt.ProfilePayload(); ok {
+				s.RefreshProfile(profile.ID)
+				continue
+			}
+			device, ok := evt.DevicePayload()
+			if !ok {
+				continue
+			}
+			switch evt.Type {
+			case eventbus.TypeDeviceCreated:
+				if device.Enabled && s.owns(device) {
+					s.AddDevice(device)
+				}
+			case eventbus.TypeDeviceUpdated:
+				s.UpdateDevice(device)
+			case eventbus.TypeDeviceDeleted:
+				s.RemoveDevice(device.ID)
+			}
+		}
+	}
+}
+
 // Stop stops the poller service
 func (s *PollerService) Stop() {
 	s.cancel()
@@ -99,28 +186,16 @@ func (s *PollerService) Stop() {
 
 	s.wg.Wait()
 
-	// Close subscriber channels
-	s.subMu.Lock()
-	for _, ch := range s.subscribers {
-		close(ch)
-	}
-	s.subscribers = nil
-	s.subMu.Unlock()
-
 	log.Println("Poller stopped")
 }
 
-// Subscribe returns a channel for state update events
-func (s *PollerService) Subscribe() chan StateUpdateEvent {
-	ch := make(chan StateUpdateEvent, 100)
-	s.subMu.Lock()
-	s.subscribers = append(s.subscribers, ch)
-	s.subMu.Unlock()
-	return ch
-}
-
 // AddDevice adds a device to the poller
 func (s *PollerService) AddDevice(device *domain.Device) {
+	s.addDevice(device, 0)
+}
+
+// addDevice adds a device whose first poll waits delay
+func (s *PollerService) addDevice(device *domain.Device, delay time.Duration) {
 	s.devicesMu.Lock()
 	defer s.devicesMu.Unlock()
 
@@ -136,6 +211,7 @@ func (s *PollerService) AddDevice(device *domain.Device) {
 			profile = p
 		}
 	}
+	profile = profile.ForDevice(device)
 
 	interval := s.defaultInterval
 	if device.PollInterval > 0 {
@@ -149,6 +225,10 @@ func (s *PollerService) AddDevice(device *domain.Device) {
 		stopCh:      make(chan struct{}),
 		triggerCh:   make(chan struct{}, 1),
 		missingOIDs: make(map[string]bool),
+		alarms:      domain.ResolveAlarms(profile, device),
+		alarmActive: make(map[string]bool),
+		health:      newOIDHealth(),
+		startDelay:  delay,
 	}
 
 	s.devices[device.ID] = dp
@@ -170,11 +250,33 @@ func (s *PollerService) AddDevice(device *domain.Device) {
 // UpdateDevice updates a device in the poller
 func (s *PollerService) UpdateDevice(device *domain.Device) {
 	s.RemoveDevice(device.ID)
-	if device.Enabled {
+	if device.Enabled && s.owns(device) {
 		s.AddDevice(device)
 	}
 }
 
+// RefreshProfile rebuilds the pollers of the devices using a profile, so they
+// poll its current mappings and retry OIDs earlier found missing. Returns the
+// number of devices refreshed.
+func (s *PollerService) RefreshProfile(profileID string) int {
+	s.devicesMu.RLock()
+	var devices []*domain.Device
+	for _, dp := range s.devices {
+		if dp.device.ProfileID == profileID {
+			devices = append(devices, dp.device)
+		}
+	}
+	s.devicesMu.RUnlock()
+
+	for _, device := range devices {
+		s.UpdateDevice(device)
+	}
+	if len(devices) > 0 {
+		log.Printf("Profile %s changed, reloaded %d device poller(s)", profileID, len(devices))
+	}
+	return len(devices)
+}
+
 // RemoveDevice removes a device from the poller
 func (s *PollerService) RemoveDevice(id string) {
 	s.devicesMu.Lock()
@@ -213,13 +315,50 @@ func (s *PollerService) GetAllDeviceStates() map[string]*domain.DeviceState {
 	return result
 }
 
-// TriggerPoll triggers an immediate poll for a device
+// GetDeviceValues returns a copy of a device's last polled values
+func (s *PollerService) GetDeviceValues(id string) map[string]interface{} {
+	s.statesMu.RLock()
+	defer s.statesMu.RUnlock()
+
+	values := make(map[string]interface{})
+	if state, exists := s.states[id]; exists {
+		for k, v := range state.Values {
+			values[k] = v
+		}
+	}
+	return values
+}
+
+// PollCounts returns how many polls ran and how many failed since start
+func (s *PollerService) PollCounts() (polls, failures uint64) {
+	return s.polls.Load(), s.pollErrors.Load()
+}
+
+// DiscardedReadings returns how many readings the sanity filter dropped
+// since start
+func (s *PollerService) DiscardedReadings() uint64 {
+	return s.discarded.Load()
+}
+
+// RecordTrap notes when a device last sent a trap; it is published as the
+// Last Trap diagnostic value with the device's next poll
+func (s *PollerService) RecordTrap(deviceID string, at time.Time) {
+	s.statesMu.Lock()
+	defer s.statesMu.Unlock()
+	if at.After(s.lastTraps[deviceID]) {
+		s.lastTraps[deviceID] = at
+	}
+}
+
+// TriggerPoll triggers an immediate poll for a device. It is called after
+// commands, so the device is then polled fast for a while to follow the change.
 func (s *PollerService) TriggerPoll(deviceID string) {
 	s.devicesMu.RLock()
 	dp, exists := s.devices[deviceID]
 	s.devicesMu.RUnlock()
 
 	if exists {
+		s.startFastPolling(dp)
 		select {
 		case dp.triggerCh <- struct{}{}:
 		default:
@@ -231,45 +370,96 @@ func (s *PollerService) TriggerPoll(deviceID string) {
 func (s *PollerService) pollDevice(dp *devicePoller) {
 	defer s.wg.Done()
 
+	if !s.awaitFirstPoll(dp) {
+		return
+	}
+
 	ticker := time.NewTicker(dp.interval)
 	defer ticker.Stop()
 
+	// Extra ticker running only during the fast polling window
+	var fastTicker *time.Ticker
+	var fastC <-chan time.Time
+	defer func() {
+		if fastTicker != nil {
+			fastTicker.Stop()
+		}
+	}()
+	adjustInterval := func() {
+		fast := dp.fastPolling()
+		if fast && fastTicker == nil {
+			log.Printf("[DEBUG] Fast polling device %s every %s", dp.device.ID, s.fastInterval)
+			fastTicker = time.NewTicker(s.fastInterval)
+			fastC = fastTicker.C
+		} else if !fast && fastTicker != nil {
+			fastTicker.Stop()
+			fastTicker, fastC = nil, nil
+		}
+	}
+
 	// Initial poll
 	s.doPoll(dp)
+	adjustInterval()
 
 	for {
 		select {
 		case <-dp.stopCh:
-			if dp.client != nil && dp.client.Conn != nil {
-				dp.client.Conn.Close()
+			if dp.client != nil {
+				dp.client.Close()
 			}
 			return
 		case <-s.ctx.Done():
+			if dp.client != nil {
+				dp.client.Close()
+			}
 			return
 		case <-dp.triggerCh:
 			s.doPoll(dp)
 		case <-ticker.C:
 			s.doPoll(dp)
+		case <-fastC:
+			s.doPoll(dp)
 		}
+		adjustInterval()
 	}
 }
 
-func (s *PollerService) doPoll(dp *devicePoller) {
-	dp.pollCount++
+// pace waits the device's request delay between the SNMP requests of a poll,
+// so slow embedded agents are not flooded; it returns early when stopping
+func (s *PollerService) pace(dp *devicePoller) {
+	delay := time.Duration(dp.device.RequestDelay) * time.Millisecond
+	if delay <= 0 {
+		return
+	}
+	select {
+	case <-time.After(delay):
+	case <-dp.stopCh:
+	case <-s.ctx.Done():
+	}
+}
 
-	// Create SNMP client if not exists
-	if dp.client == nil {
-		dp.client = createSNMPClient(dp.device.IPAddress, dp.device.Port, dp.device.Community, dp.device.SNMPVersion)
+func (s *PollerService) doPoll(dp *devicePoller) {
+	// A device taken over by another node is not polled while Reconcile has
+	// yet to stop its poller
+	if !s.owns(dp.device) {
+		return
 	}
+	dp.pollCount++
+	s.polls.Add(1)
 
-	// Connect if not connected
-	if dp.client.Conn == nil {
-		if err := dp.client.Connect(); err != nil {
-			s.updateState(dp.device.ID, nil, false, []string{err.Error()})
+	// Connect if not connected, or if the connection was closed while idle
+	if dp.client == nil || dp.client.Closed() {
+		client, err := OpenSNMP(dp.device, dp.device.ReadCommunity(), SNMPConnPoll)
+		if err != nil {
+			s.updateState(dp.device.ID, nil, nil, false, []string{err.Error()})
+			s.recordPollError(dp.device.ID, []string{err.Error()})
 			return
 		}
+		dp.client = client
 	}
 
+	dp.health.poll()
+
 	// Get OIDs to poll
 	oids := s.getOIDsToPoll(dp)
 	if len(oids) == 0 {
@@ -296,13 +486,7 @@ func (s *PollerService) doPoll(dp *devicePoller) {
 		}
 	}
 
-	// Determine batch size based on SNMP version
-	// SNMPv1 devices often have trouble with batch requests (packet sanity errors)
-	// so we use individual queries for maximum compatibility
-	batchSize := 10
-	if dp.client.Version == gosnmp.Version1 {
-		batchSize = 1 // Individual queries for SNMP v1 - more reliable
-	}
+	batchSize := dp.device.PollBatchSize()
 
 	// Poll in batches
 	values := make(map[string]interface{})
@@ -315,6 +499,10 @@ func (s *PollerService) doPoll(dp *devicePoller) {
 		}
 
 		batchOIDs := oids[i:end]
+		if i > 0 {
+			s.pace(dp)
+		}
+		dp.client.Touch()
 		result, err := dp.client.Get(batchOIDs)
 		if err != nil {
 			log.Printf("[DEBUG] SNMP GET error for device %s batch %d-%d: %v", dp.device.ID, i, end, err)
@@ -323,42 +511,52 @@ func (s *PollerService) doPoll(dp *devicePoller) {
 			if dp.client.Version == gosnmp.Version1 {
 				log.Printf("[DEBUG] Falling back to individual OID queries for batch %d-%d", i, end)
 				// Close and reopen connection to ensure clean state
-				if dp.client.Conn != nil {
-					dp.client.Conn.Close()
-					dp.client.Conn = nil
-				}
-				if connErr := dp.client.Connect(); connErr != nil {
+				dp.client.Close()
+				client, connErr := OpenSNMP(dp.device, dp.device.ReadCommunity(), SNMPConnPoll)
+				if connErr != nil {
 					errors = append(errors, connErr.Error())
+					for _, oid := range batchOIDs {
+						dp.health.failure(oid, connErr.Error())
+					}
 					continue
 				}
-				for _, singleOID := range batchOIDs {
+				dp.client = client
+				for j, singleOID := range batchOIDs {
+					if j > 0 {
+						s.pace(dp)
+					}
+					dp.client.Touch()
 					singleResult, singleErr := dp.client.Get([]string{singleOID})
 					if singleErr != nil {
 						// Skip this OID silently - it may not exist on this device
 						log.Printf("[DEBUG] OID %s not available: %v", singleOID, singleErr)
+						dp.health.failure(singleOID, singleErr.Error())
 						continue
 					}
 					for _, variable := range singleResult.Variables {
 						value := s.parseValue(variable)
-						if value != nil {
+						if value == nil {
+							// SNMPv1 agents report missing objects as noSuchName
+							if singleResult.Error == gosnmp.NoSuchName {
+								dp.health.missing(singleOID)
+							} else {
+								dp.health.failure(singleOID, "no value returned")
+							}
+						} else {
+							dp.health.success(singleOID)
 							normalizedOID := normalizeOID(variable.Name)
 							// Apply transformations for all mappings that use this OID
-							if mappings, exists := oidToMappings[normalizedOID]; exists {
-								for _, mapping := range mappings {
-									transformedValue := s.transformValue(value, mapping)
-									values[mapping.Name] = transformedValue
-								}
-							}
+							s.storeMapped(dp, oidToMappings[normalizedOID], value, values)
 							values[variable.Name] = value
 						}
 					}
 				}
 			} else {
 				errors = append(errors, err.Error())
-				if dp.client.Conn != nil {
-					dp.client.Conn.Close()
-					dp.client.Conn = nil
+				for _, oid := range batchOIDs {
+					dp.health.failure(oid, err.Error())
 				}
+				dp.client.Close()
 			}
 			continue
 		}
@@ -372,40 +570,86 @@ func (s *PollerService) doPoll(dp *devicePoller) {
 					log.Printf("[INFO] OID %s not available on device %s - will skip in future polls", normalizedOID, dp.device.Name)
 					dp.missingOIDs[normalizedOID] = true
 				}
+				dp.health.missing(normalizedOID)
 				continue
 			}
 
 			value := s.parseValue(variable)
 			if value == nil {
+				dp.health.failure(normalizedOID, "no value returned")
 				continue
 			}
+			dp.health.success(normalizedOID)
 
 			// Apply profile transformations for all mappings that use this OID
-			if mappings, exists := oidToMappings[normalizedOID]; exists {
-				for _, mapping := range mappings {
-					transformedValue := s.transformValue(value, mapping)
-					values[mapping.Name] = transformedValue
-				}
-			}
+			s.storeMapped(dp, oidToMappings[normalizedOID], value, values)
 			values[variable.Name] = value
 		}
 	}
 
 	// Calculate derived values (e.g., Active Power = Voltage × Current)
-	s.calculateDerivedValues(values)
+	s.deriveValues(dp, values)
+
+	// Evaluate threshold alarms on top of the polled values
+	s.evaluateAlarms(dp, values)
+
+	s.statesMu.RLock()
+	if at, ok := s.lastTraps[dp.device.ID]; ok {
+		values[domain.LastTrapName] = timefmt.Format(at)
+	}
+	s.statesMu.RUnlock()
+
+	// Follow transitions such as a source transfer closely
+	if DiscreteChanged(dp.profile, s.GetDeviceValues(dp.device.ID), values) {
+		s.startFastPolling(dp)
+	}
+
+	stale := s.trackStale(dp, oids, values)
 
 	online := len(errors) == 0
-	s.updateState(dp.device.ID, values, online, errors)
+	s.updateState(dp.device.ID, values, stale, online, errors)
 
 	// Update last seen
 	if online {
 		_ = s.deviceRepo.UpdateLastSeen(context.Background(), dp.device.ID)
+	} else {
+		s.recordPollError(dp.device.ID, errors)
+	}
+}
+
+// storeMapped transforms a polled value for each mapping of its OID. Values
+// outside a mapping's valid range are dropped and counted, so the last good
+// value stays published.
+func (s *PollerService) storeMapped(dp *devicePoller, mappings []*domain.OIDMapping, value interface{}, values map[string]interface{}) {
+	for _, mapping := range mappings {
+		transformed := s.transformValue(value, mapping)
+		if !mapping.InValidRange(transformed) {
+			s.discarded.Add(1)
+			dp.health.discard(mapping.OID, transformed)
+			log.Printf("[DEBUG] Discarded out-of-range value %v for %s on device %s", transformed, mapping.Name, dp.device.ID)
+			continue
+		}
+		values[mapping.Name] = transformed
+	}
+}
+
+// recordPollError persists a failed poll on the device row, so the device
+// list shows its health without a live state
+func (s *PollerService) recordPollError(deviceID string, errors []string) {
+	s.pollErrors.Add(1)
+	if err := s.deviceRepo.RecordError(context.Background(), deviceID, strings.Join(errors, "; ")); err != nil {
+		log.Printf("Failed to record poll error for device %s: %v", deviceID, err)
 	}
 }
 
 // calculateDerivedValues computes values that can be derived from other measurements
-func (s *PollerService) calculateDerivedValues(values map[string]interface{}) {
-	// Calculate Active Power if it's 0 or missing (P = V × I)
+func (s *PollerService) calculateDerivedValues(profile *domain.Profile, values map[string]interface{}) {
+	if groups := profile.PhaseGroups(); len(groups) > 0 {
+		s.calculatePhaseValues(groups, values)
+		return
+	}
+
+	// Single-phase: calculate Active Power if it's 0 or missing (P = V × I)
 	activePower := toFloat64(values["Active Power"])
 	if activePower == 0 {
 		voltage := toFloat64(values["Voltage"])
@@ -417,25 +661,101 @@ func (s *PollerService) calculateDerivedValues(values map[string]interface{}) {
 	}
 }
 
+// calculatePhaseValues derives per-phase power (P = V × I) where the device does not
+// report it, and totals across phases for multi-phase devices
+func (s *PollerService) calculatePhaseValues(groups []domain.PhaseGroup, values map[string]interface{}) {
+	var totalPower, totalCurrent float64
+	var hasPower, hasCurrent bool
+
+	for _, g := range groups {
+		var current float64
+		if g.Current != nil {
+			if v, ok := toNumeric(values[g.Current.Name]); ok {
+				current = v
+				totalCurrent += v
+				hasCurrent = true
+			}
+		}
+
+		power, ok := 0.0, false
+		if g.Power != nil {
+			power, ok = toNumeric(values[g.Power.Name])
+		}
+		if (!ok || power == 0) && g.Voltage != nil {
+			if voltage, vok := toNumeric(values[g.Voltage.Name]); vok && voltage > 0 && current > 0 {
+				power, ok = math.Round(voltage*current*10)/10, true
+				if g.Power == nil {
+					values[domain.PhasePowerName(g.Phase)] = power
+				} else {
+					values[g.Power.Name] = power
+				}
+			}
+		}
+		if ok {
+			totalPower += power
+			hasPower = true
+		}
+	}
+
+	if len(groups) < 2 {
+		return
+	}
+	if hasPower {
+		values[domain.TotalPowerName] = math.Round(totalPower*10) / 10
+	}
+	if _, reported := values[domain.TotalCurrentName]; hasCurrent && !reported {
+		values[domain.TotalCurrentName] = math.Round(totalCurrent*100) / 100
+	}
+}
+
+// evaluateAlarms computes threshold alarm states from numeric values.
+// Alarms whose source value is missing from this poll keep their previous state.
+func (s *PollerService) evaluateAlarms(dp *devicePoller, values map[string]interface{}) {
+	for _, alarm := range dp.alarms {
+		value, exists := values[alarm.Source]
+		if !exists {
+			continue
+		}
+		numeric, ok := toNumeric(value)
+		if !ok {
+			continue
+		}
+		// Thresholds are in the mapping's own unit, not the preferred temperature unit
+		if dp.profile != nil {
+			for i := range dp.profile.OIDMappings {
+				if mapping := &dp.profile.OIDMappings[i]; mapping.Name == alarm.Source {
+					numeric = mapping.DeclaredValue(numeric)
+					break
+				}
+			}
+		}
+
+		name := alarm.EntityName()
+		active := alarm.Evaluate(numeric, dp.alarmActive[name])
+		if active && !dp.alarmActive[name] {
+			log.Printf("Alarm %q raised on device %s (%s = %v)", name, dp.device.Name, alarm.Source, value)
+		} else if !active && dp.alarmActive[name] {
+			log.Printf("Alarm %q cleared on device %s (%s = %v)", name, dp.device.Name, alarm.Source, value)
+		}
+		dp.alarmActive[name] = active
+		values[name] = active
+	}
+}
+
+// toNumeric converts a polled value to float64, reporting whether it was numeric
+func toNumeric(v interface{}) (float64, bool) {
+	// Some devices return numbers as strings
+	if str, ok := v.(string); ok {
+		f, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+		return f, err == nil
+	}
+	return domain.Number(v)
+}
+
 // toFloat64 converts various types to float64
 func toFloat64(v interface{}) float64 {
-	if v == nil {
-		return 0
-	}
-	switch val := v.(type) {
-	case float64:
-		return val
-	case int:
-		return float64(val)
-	case int64:
-		return float64(val)
-	case string:
-		var f float64
-		fmt.Sscanf(val, "%f", &f)
-		return f
-	default:
-		return 0
-	}
+	f, _ := toNumeric(v)
+	return f
 }
 
 func (s *PollerService) getOIDsToPoll(dp *devicePoller) []string {
@@ -451,6 +771,10 @@ func (s *PollerService) getOIDsToPoll(dp *devicePoller) []string {
 	}
 
 	for _, mapping := range dp.profile.OIDMappings {
+		if mapping.Computed {
+			continue
+		}
+
 		group := mapping.PollGroup
 		if group == "" {
 			group = "frequent"
@@ -477,6 +801,9 @@ func (s *PollerService) getOIDsToPoll(dp *devicePoller) []string {
 		oids = append(oids, oid)
 	}
 
+	if s.oidBudget > 0 {
+		return dp.queue.take(oids, s.oidBudget)
+	}
 	return oids
 }
 
@@ -488,6 +815,8 @@ func (s *PollerService) parseValue(variable gosnmp.SnmpPDU) interface{} {
 		return variable.Value
 	case gosnmp.ObjectIdentifier:
 		return variable.Value.(string)
+	case gosnmp.Opaque, gosnmp.OpaqueFloat, gosnmp.OpaqueDouble:
+		return DecodeOpaque(variable)
 	case gosnmp.NoSuchObject, gosnmp.NoSuchInstance:
 		// OID doesn't exist on this device - normal for optional features
 		return nil
@@ -504,62 +833,50 @@ func (s *PollerService) transformValue(value interface{}, mapping *domain.OIDMap
 		return s.extractCompositeValue(value, mapping)
 	}
 
-	// Apply scale
-	if mapping.Scale != 0 {
-		var numericValue float64
-		var hasNumeric bool
-
-		switch v := value.(type) {
-		case int:
-			numericValue = float64(v)
-			hasNumeric = true
-		case int64:
-			numericValue = float64(v)
-			hasNumeric = true
-		case uint:
-			numericValue = float64(v)
-			hasNumeric = true
-		case uint64:
-			numericValue = float64(v)
-			hasNumeric = true
-		case float64:
-			numericValue = v
-			hasNumeric = true
-		case string:
-			// Try to parse string as number (some devices return numbers as strings)
-			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
-				numericValue = parsed
-				hasNumeric = true
-			}
-		}
+	// Apply scale and unit conversion
+	from, to := mapping.UnitConversion()
+	convertUnit := units.Normalize(from) != units.Normalize(to)
+	if mapping.Scale != 0 || convertUnit {
+		numericValue, hasNumeric := toNumeric(value)
 
 		if hasNumeric {
-			scaled := numericValue * mapping.Scale
-			// Round to appropriate decimal places based on scale
-			if mapping.Scale < 0.01 {
-				return math.Round(scaled*1000) / 1000
+			scaled := numericValue
+			if mapping.Scale != 0 {
+				scaled *= mapping.Scale
 			}
-			return math.Round(scaled*100) / 100
+			if convertUnit {
+				// Inconvertible units are reported as profile warnings
+				if converted, err := units.Convert(scaled, from, to); err == nil {
+					scaled = converted
+				}
+			}
+			return roundDecimals(scaled, mapping.Decimals(convertUnit))
 		}
 	}
 
+	// Floats the device reports as such are only rounded to an explicit precision
+	if f, ok := value.(float64); ok && mapping.Precision != nil {
+		return roundDecimals(f, *mapping.Precision)
+	}
+
 	// Apply enum mapping
 	if mapping.Type == domain.OIDTypeEnum && mapping.EnumValues != nil {
-		switch v := value.(type) {
-		case int:
+		if v, ok := toInt(value); ok {
 			if name, ok := mapping.EnumValues[v]; ok {
 				return name
 			}
-		case int64:
-			if name, ok := mapping.EnumValues[int(v)]; ok {
-				return name
-			}
 		}
 	}
 
 	return value
 }
 
+// roundDecimals rounds value to a number of decimal places
+func roundDecimals(value float64, decimals int) float64 {
+	factor := math.Pow10(decimals)
+	return math.Round(value*factor) / factor
+}
+
 // extractCompositeValue extracts a value from a comma-separated string at the specified index
 // Used for Energenie PDU style outlet status (e.g., "1,1,0,-1,-1,-1,-1,-1")
 func (s *PollerService) extractCompositeValue(value interface{}, mapping *domain.OIDMapping) interface{} {
@@ -596,7 +913,9 @@ func (s *PollerService) extractCompositeValue(value interface{}, mapping *domain
 	return partValue
 }
 
-func (s *PollerService) updateState(deviceID string, values map[string]interface{}, online bool, errors []string) {
+// updateState merges the values of a poll into a device's state; stale lists
+// the values that the poll left stale
+func (s *PollerService) updateState(deviceID string, values map[string]interface{}, stale []string, online bool, errors []string) {
 	s.statesMu.Lock()
 	state, exists := s.states[deviceID]
 	if !exists {
@@ -607,15 +926,22 @@ func (s *PollerService) updateState(deviceID string, values map[string]interface
 		s.states[deviceID] = state
 	}
 
+	now := time.Now()
 	state.Online = online
-	state.LastPoll = time.Now()
+	state.LastPoll = now
 	state.Errors = errors
 
 	if values != nil {
+		if state.UpdatedAt == nil {
+			state.UpdatedAt = make(map[string]time.Time, len(values))
+		}
 		for k, v := range values {
 			state.Values[k] = v
+			state.UpdatedAt[k] = now
 		}
+		state.Stale = stale
 	}
+	stale = state.Stale
 
 	// Copy the full accumulated state values for the event
 	fullValues := make(map[string]interface{}, len(state.Values))
@@ -625,22 +951,19 @@ func (s *PollerService) updateState(deviceID string, values map[string]interface
 	s.statesMu.Unlock()
 
 	// Notify subscribers with full accumulated state
-	event := StateUpdateEvent{
+	s.bus.Publish(eventbus.Event{
+		Type:      eventbus.TypeStateUpdate,
 		DeviceID:  deviceID,
-		Timestamp: time.Now(),
-		Values:    fullValues,
-		Online:    online,
-	}
-
-	s.subMu.RLock()
-	for _, ch := range s.subscribers {
-		select {
-		case ch <- event:
-		default:
-			// Channel full, skip
-		}
-	}
-	s.subMu.RUnlock()
+		Timestamp: now,
+		Payload: StateUpdateEvent{
+			DeviceID:  deviceID,
+			Timestamp: now,
+			Values:    fullValues,
+			Stale:     stale,
+			Online:    online,
+			Errors:    errors,
+		},
+	})
 }
 
 // normalizeOID strips leading dot from OID for consistent comparison
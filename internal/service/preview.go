@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"snmp-mqtt-bridge/internal/domain"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+var (
+	// ErrDeviceNotFound is returned when the target device doesn't exist
+	ErrDeviceNotFound = errors.New("device not found")
+	// ErrInvalidOID is returned for malformed OIDs
+	ErrInvalidOID = errors.New("invalid OID")
+)
+
+// MappingPreview is the live result of polling a single mapping
+type MappingPreview struct {
+	OID       string      `json:"oid"`
+	Available bool        `json:"available"`      // False when the device has no such object
+	Discarded bool        `json:"discarded"`      // Outside the valid range; a poll would drop it
+	Type      string      `json:"type,omitempty"` // SNMP type of the raw value, e.g. "Integer"
+	Raw       interface{} `json:"raw"`
+	Value     interface{} `json:"value"` // After scale, enum and composite transforms
+	Unit      string      `json:"unit,omitempty"`
+}
+
+// PreviewMapping performs an SNMP GET for a mapping that is not (yet) part of
+// a profile and transforms the result exactly like a poll would, so profile
+// editors can check a mapping before saving it
+func (s *PollerService) PreviewMapping(ctx context.Context, deviceID string, mapping *domain.OIDMapping) (*MappingPreview, error) {
+	if !domain.ValidOID(mapping.OID) {
+		return nil, ErrInvalidOID
+	}
+
+	device, err := s.deviceRepo.GetByID(ctx, deviceID)
+	if err != nil {
+		return nil, ErrDeviceNotFound
+	}
+
+	client, err := OpenSNMP(device, device.ReadCommunity(), SNMPConnPreview)
+	if err != nil {
+		return nil, &SNMPError{Op: "connect", Err: err}
+	}
+	defer client.Close()
+
+	result, err := client.Get([]string{mapping.OID})
+	if err != nil {
+		return nil, &SNMPError{Op: "GET", Err: err}
+	}
+
+	preview := &MappingPreview{OID: mapping.OID, Unit: mapping.PublishedUnit()}
+	// SNMPv1 agents report missing objects as a noSuchName error status
+	if result.Error != gosnmp.NoError || len(result.Variables) == 0 {
+		return preview, nil
+	}
+
+	variable := result.Variables[0]
+	raw := s.parseValue(variable)
+	if raw == nil {
+		return preview, nil
+	}
+
+	preview.Available = true
+	preview.Type = variable.Type.String()
+	preview.Raw = raw
+	preview.Value = s.transformValue(raw, mapping)
+	preview.Discarded = !mapping.InValidRange(preview.Value)
+	return preview, nil
+}
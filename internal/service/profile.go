@@ -2,43 +2,77 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 
 	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
 	"snmp-mqtt-bridge/internal/repository"
 
 	"github.com/google/uuid"
 	"gopkg.in/yaml.v3"
 )
 
+// ErrProfileInUse is returned when deleting a profile devices still reference
+var ErrProfileInUse = errors.New("profile is in use")
+
+// ProfileCascade selects what happens to the devices of a deleted profile
+type ProfileCascade string
+
+const (
+	ProfileCascadeNone   ProfileCascade = ""       // Refuse to delete a profile in use
+	ProfileCascadeDetach ProfileCascade = "detach" // Clear the devices' profile
+	ProfileCascadeDelete ProfileCascade = "delete" // Delete the devices
+)
+
 // ProfileService handles profile business logic
 type ProfileService struct {
-	repo repository.ProfileRepository
+	repo    repository.ProfileRepository
+	devices *DeviceService
+	bus     *eventbus.Bus
 }
 
 // NewProfileService creates a new profile service
-func NewProfileService(repo repository.ProfileRepository) *ProfileService {
-	return &ProfileService{repo: repo}
+func NewProfileService(repo repository.ProfileRepository, devices *DeviceService, bus *eventbus.Bus) *ProfileService {
+	return &ProfileService{repo: repo, devices: devices, bus: bus}
 }
 
-// Create creates a new profile
+// Create creates a new profile. Invalid Home Assistant classes do not stop
+// the save; they are logged and returned in the profile's warnings.
 func (s *ProfileService) Create(ctx context.Context, profile *domain.Profile) error {
 	if profile.ID == "" {
 		profile.ID = uuid.New().String()
 	}
-	return s.repo.Create(ctx, profile)
+	if err := s.repo.Create(ctx, profile); err != nil {
+		return err
+	}
+	s.checkMappings(profile)
+	return nil
 }
 
 // GetByID retrieves a profile by ID
 func (s *ProfileService) GetByID(ctx context.Context, id string) (*domain.Profile, error) {
-	return s.repo.GetByID(ctx, id)
+	profile, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	profile.Warnings = mappingWarnings(profile)
+	return profile, nil
 }
 
 // GetAll retrieves all profiles
 func (s *ProfileService) GetAll(ctx context.Context) ([]domain.Profile, error) {
-	return s.repo.GetAll(ctx)
+	profiles, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range profiles {
+		profiles[i].Warnings = mappingWarnings(&profiles[i])
+	}
+	return profiles, nil
 }
 
 // GetBySysObjectID finds a profile by SNMP sysObjectID
@@ -46,14 +80,52 @@ func (s *ProfileService) GetBySysObjectID(ctx context.Context, sysOID string) (*
 	return s.repo.GetBySysObjectID(ctx, sysOID)
 }
 
-// Update updates an existing profile
+// Update updates an existing profile, reporting invalid classes like Create
 func (s *ProfileService) Update(ctx context.Context, profile *domain.Profile) error {
-	return s.repo.Update(ctx, profile)
+	if err := s.repo.Update(ctx, profile); err != nil {
+		return err
+	}
+	s.checkMappings(profile)
+	s.bus.Publish(eventbus.Event{Type: eventbus.TypeProfileUpdated, Payload: profile})
+	return nil
 }
 
-// Delete deletes a profile
-func (s *ProfileService) Delete(ctx context.Context, id string) error {
-	return s.repo.Delete(ctx, id)
+// Delete deletes a profile. Devices referencing it block the delete unless
+// cascade detaches or deletes them first.
+func (s *ProfileService) Delete(ctx context.Context, id string, cascade ProfileCascade) error {
+	profile, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	devices, err := s.devices.GetByProfile(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(devices) > 0 {
+		switch cascade {
+		case ProfileCascadeDetach:
+			if _, err := s.devices.DetachProfile(ctx, id); err != nil {
+				return err
+			}
+			log.Printf("Detached %d device(s) from deleted profile %s", len(devices), id)
+		case ProfileCascadeDelete:
+			for _, device := range devices {
+				if err := s.devices.Delete(ctx, device.ID); err != nil {
+					return fmt.Errorf("device %s: %w", device.ID, err)
+				}
+			}
+			log.Printf("Deleted %d device(s) with profile %s", len(devices), id)
+		default:
+			return fmt.Errorf("%w by %d device(s)", ErrProfileInUse, len(devices))
+		}
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.bus.Publish(eventbus.Event{Type: eventbus.TypeProfileDeleted, Payload: profile})
+	return nil
 }
 
 // LoadBuiltinProfiles loads profiles from YAML files in the profiles directory
@@ -92,6 +164,27 @@ func (s *ProfileService) loadProfileFile(ctx context.Context, path string) error
 			mapping := indexed.OIDMapping
 			mapping.OID = fmt.Sprintf("%s.%d", indexed.BaseOID, i)
 			mapping.Name = fmt.Sprintf(indexed.NameFormat, i)
+			if indexed.PhaseFromIndex {
+				mapping.Phase = i - indexed.IndexStart + 1
+			}
+			oidMappings = append(oidMappings, mapping)
+		}
+	}
+
+	// Expand composite ranges: every index of one composite value becomes a mapping
+	for _, composite := range profileYAML.CompositeRanges {
+		if composite.IndexEnd < composite.IndexStart {
+			return fmt.Errorf("composite range %q: index_end %d is before index_start %d", composite.NameFormat, composite.IndexEnd, composite.IndexStart)
+		}
+		nameStart := composite.NameStart
+		if nameStart == 0 {
+			nameStart = 1
+		}
+		for i := composite.IndexStart; i <= composite.IndexEnd; i++ {
+			mapping := composite.OIDMapping
+			mapping.Type = domain.OIDTypeCompositeSwitch
+			mapping.CompositeIndex = i
+			mapping.Name = fmt.Sprintf(composite.NameFormat, i-composite.IndexStart+nameStart)
 			oidMappings = append(oidMappings, mapping)
 		}
 	}
@@ -105,8 +198,27 @@ func (s *ProfileService) loadProfileFile(ctx context.Context, path string) error
 		SysObjectID:  profileYAML.SysObjectID,
 		SNMPVersions: profileYAML.SNMPVersions,
 		OIDMappings:  oidMappings,
+		SelfTest:     profileYAML.SelfTest,
+		Actions:      profileYAML.Actions,
+		Capabilities: profileYAML.Capabilities,
+		Translations: profileYAML.Translations,
 		IsBuiltin:    true,
 	}
 
+	s.checkMappings(profile)
 	return s.repo.Upsert(ctx, profile)
 }
+
+// checkMappings records and logs the device and state classes of a profile
+// that Home Assistant would reject and units that cannot be converted
+func (s *ProfileService) checkMappings(profile *domain.Profile) {
+	profile.Warnings = mappingWarnings(profile)
+	for _, warning := range profile.Warnings {
+		log.Printf("Warning: profile %s: %s", profile.ID, warning)
+	}
+}
+
+// mappingWarnings lists a profile's mapping problems that do not stop it from being saved
+func mappingWarnings(profile *domain.Profile) []string {
+	return append(profile.ClassWarnings(), profile.UnitWarnings()...)
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+	"snmp-mqtt-bridge/internal/repository"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// profileCacheTTL is how long a cached profile is used before it is loaded
+// again, so changes made directly in a shared database show up
+const profileCacheTTL = 5 * time.Minute
+
+// profileLoadTimeout bounds a profile load shared by concurrent readers
+const profileLoadTimeout = 10 * time.Second
+
+// cachedProfile is a profile with the time it was loaded
+type cachedProfile struct {
+	profile  *domain.Profile
+	loadedAt time.Time
+}
+
+// ProfileCache is a read-through cache in front of a profile repository.
+// Profiles read by ID are kept in memory for profileCacheTTL, and concurrent
+// reads of a profile not cached yet share one database query, so a burst of
+// device registrations loads each profile once. Writes through the cache
+// invalidate the profile at once; profile events on the bus invalidate
+// changes made around it.
+type ProfileCache struct {
+	repository.ProfileRepository
+
+	bus    *eventbus.Bus
+	loads  singleflight.Group
+	mu     sync.RWMutex
+	byID   map[string]cachedProfile
+	epochs map[string]uint64 // Bumped on invalidation, so loads racing a change are not cached
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewProfileCache creates a profile cache over repo
+func NewProfileCache(repo repository.ProfileRepository, bus *eventbus.Bus) *ProfileCache {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &ProfileCache{
+		ProfileRepository: repo,
+		bus:               bus,
+		byID:              make(map[string]cachedProfile),
+		epochs:            make(map[string]uint64),
+		ctx:               ctx,
+		cancel:            cancel,
+	}
+}
+
+// Start follows profile events to invalidate cached profiles
+func (c *ProfileCache) Start() {
+	sub := c.bus.Subscribe(eventbus.TypeProfileUpdated, eventbus.TypeProfileDeleted)
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer c.bus.Unsubscribe(sub)
+
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case evt, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				if profile, ok := evt.ProfilePayload(); ok {
+					c.Invalidate(profile.ID)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops following profile events
+func (c *ProfileCache) Stop() {
+	c.cancel()
+	c.wg.Wait()
+}
+
+// GetByID returns a copy of the cached profile, loading it on a miss or once
+// it is older than profileCacheTTL. The load runs on its own context, so a
+// reader giving up does not fail the others sharing it.
+func (c *ProfileCache) GetByID(ctx context.Context, id string) (*domain.Profile, error) {
+	c.mu.RLock()
+	cached, ok := c.byID[id]
+	c.mu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < profileCacheTTL {
+		return copyProfile(cached.profile), nil
+	}
+
+	loaded := c.loads.DoChan(id, func() (interface{}, error) {
+		c.mu.RLock()
+		epoch := c.epochs[id]
+		c.mu.RUnlock()
+
+		loadCtx, cancel := context.WithTimeout(context.Background(), profileLoadTimeout)
+		defer cancel()
+		profile, err := c.ProfileRepository.GetByID(loadCtx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		if c.epochs[id] == epoch {
+			c.byID[id] = cachedProfile{profile: profile, loadedAt: time.Now()}
+		}
+		c.mu.Unlock()
+		return profile, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-loaded:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return copyProfile(result.Val.(*domain.Profile)), nil
+	}
+}
+
+// Create creates a profile and drops any cached one with its ID
+func (c *ProfileCache) Create(ctx context.Context, profile *domain.Profile) error {
+	defer c.Invalidate(profile.ID)
+	return c.ProfileRepository.Create(ctx, profile)
+}
+
+// Update updates a profile and drops its cached copy
+func (c *ProfileCache) Update(ctx context.Context, profile *domain.Profile) error {
+	defer c.Invalidate(profile.ID)
+	return c.ProfileRepository.Update(ctx, profile)
+}
+
+// Upsert creates or updates a profile and drops its cached copy
+func (c *ProfileCache) Upsert(ctx context.Context, profile *domain.Profile) error {
+	defer c.Invalidate(profile.ID)
+	return c.ProfileRepository.Upsert(ctx, profile)
+}
+
+// Delete deletes a profile and drops its cached copy
+func (c *ProfileCache) Delete(ctx context.Context, id string) error {
+	defer c.Invalidate(id)
+	return c.ProfileRepository.Delete(ctx, id)
+}
+
+// Invalidate drops a cached profile, so the next read loads it again
+func (c *ProfileCache) Invalidate(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.byID, id)
+	c.epochs[id]++
+	c.loads.Forget(id)
+}
+
+// copyProfile deep-copies a cached profile so callers can change any of its
+// fields, mappings and maps without affecting other readers
+func copyProfile(p *domain.Profile) *domain.Profile {
+	cp := *p
+	cp.SNMPVersions = append(domain.StringSlice(nil), p.SNMPVersions...)
+	cp.Warnings = append([]string(nil), p.Warnings...)
+
+	cp.OIDMappings = make(domain.OIDMappings, len(p.OIDMappings))
+	for i, m := range p.OIDMappings {
+		m.EnumValues = maps.Clone(m.EnumValues)
+		m.Extra = maps.Clone(m.Extra)
+		m.Alarms = append([]domain.AlarmThreshold(nil), m.Alarms...)
+		for j := range m.Alarms {
+			m.Alarms[j].Above = copyPtr(m.Alarms[j].Above)
+			m.Alarms[j].Below = copyPtr(m.Alarms[j].Below)
+		}
+		m.ValidMin = copyPtr(m.ValidMin)
+		m.ValidMax = copyPtr(m.ValidMax)
+		m.Precision = copyPtr(m.Precision)
+		m.OnValue = copyPtr(m.OnValue)
+		m.OffValue = copyPtr(m.OffValue)
+		cp.OIDMappings[i] = m
+	}
+	cp.Actions = append(domain.ProfileActions(nil), p.Actions...)
+	cp.SelfTest.ResultValues = maps.Clone(p.SelfTest.ResultValues)
+
+	if p.Capabilities.OutletControl != nil {
+		outlets := *p.Capabilities.OutletControl
+		cp.Capabilities.OutletControl = &outlets
+	}
+	if p.Capabilities.SourceSwitch != nil {
+		sources := *p.Capabilities.SourceSwitch
+		sources.Sources = maps.Clone(sources.Sources)
+		cp.Capabilities.SourceSwitch = &sources
+	}
+
+	if p.Translations != nil {
+		cp.Translations = make(domain.Translations, len(p.Translations))
+		for locale, t := range p.Translations {
+			cp.Translations[locale] = domain.Translation{Names: maps.Clone(t.Names), Values: maps.Clone(t.Values)}
+		}
+	}
+	return &cp
+}
+
+// copyPtr copies the value behind a pointer; nil stays nil
+func copyPtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"snmp-mqtt-bridge/internal/domain"
+)
+
+// pushoverAPIURL is the Pushover message endpoint
+var pushoverAPIURL = "https://api.pushover.net/1/messages.json"
+
+// PushoverNotifier sends notifications through Pushover
+type PushoverNotifier struct {
+	settings *domain.PushoverSettings
+}
+
+// NewPushoverNotifier creates a Pushover notifier for the given settings
+func NewPushoverNotifier(settings *domain.PushoverSettings) *PushoverNotifier {
+	return &PushoverNotifier{settings: settings}
+}
+
+// Name identifies the channel in logs and status
+func (n *PushoverNotifier) Name() string {
+	return domain.NotificationChannelPushover
+}
+
+// Notify sends the notification with a priority derived from its severity
+func (n *PushoverNotifier) Notify(notification *domain.Notification) error {
+	form := url.Values{
+		"token":     {n.settings.Token},
+		"user":      {n.settings.User},
+		"title":     {notificationTitle(notification)},
+		"message":   {notificationDetails(notification)},
+		"priority":  {strconv.Itoa(pushoverPriority(notification.Severity))},
+		"timestamp": {strconv.FormatInt(notification.Time.Unix(), 10)},
+	}
+	if n.settings.Device != "" {
+		form.Set("device", n.settings.Device)
+	}
+
+	resp, err := notificationHTTPClient.PostForm(pushoverAPIURL, form)
+	if err != nil {
+		return redactURL(err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Status int      `json:"status"`
+		Errors []string `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("pushover: unexpected response (HTTP %d)", resp.StatusCode)
+	}
+	if result.Status != 1 {
+		return fmt.Errorf("pushover: %s", strings.Join(result.Errors, "; "))
+	}
+	return nil
+}
+
+// pushoverPriority maps severities to Pushover priorities: info is quiet,
+// error and critical bypass the user's quiet hours. Emergency priority is not
+// used because it repeats until acknowledged.
+func pushoverPriority(severity domain.TrapSeverity) int {
+	switch severity {
+	case domain.SeverityInfo:
+		return -1
+	case domain.SeverityError, domain.SeverityCritical:
+		return 1
+	default:
+		return 0
+	}
+}
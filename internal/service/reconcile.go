@@ -0,0 +1,76 @@
+package service
+
+import (
+	"log"
+	"time"
+)
+
+// SetReconcileInterval makes the poller compare its running device pollers
+// against the enabled devices in the repository every interval, so a missed
+// device event cannot leave a device unpolled until restart. 0 disables it.
+func (s *PollerService) SetReconcileInterval(interval time.Duration) {
+	s.reconcileInterval = interval
+}
+
+func (s *PollerService) reconcileLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.reconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.Reconcile()
+		}
+	}
+}
+
+// Reconcile starts pollers for enabled devices without one, stops pollers of
+// devices deleted, disabled or owned by another instance, and reloads pollers of devices changed since
+// their poller started. Returns the number of corrections made.
+func (s *PollerService) Reconcile() int {
+	devices, err := s.deviceRepo.GetEnabled(s.ctx)
+	if err != nil {
+		log.Printf("Poller reconciliation failed to load devices: %v", err)
+		return 0
+	}
+
+	s.devicesMu.RLock()
+	running := make(map[string]time.Time, len(s.devices))
+	for id, dp := range s.devices {
+		running[id] = dp.device.UpdatedAt
+	}
+	s.devicesMu.RUnlock()
+
+	corrections := 0
+	for i := range devices {
+		device := &devices[i]
+		if !s.owns(device) {
+			continue
+		}
+		updatedAt, ok := running[device.ID]
+		delete(running, device.ID)
+		switch {
+		case !ok:
+			log.Printf("Reconcile: starting missing poller for device %s (%s)", device.Name, device.ID)
+			s.AddDevice(device)
+		case device.UpdatedAt.After(updatedAt):
+			log.Printf("Reconcile: reloading stale poller for device %s (%s)", device.Name, device.ID)
+			s.UpdateDevice(device)
+		default:
+			continue
+		}
+		corrections++
+	}
+
+	// What is left runs for devices no longer enabled or no longer owned
+	for id := range running {
+		log.Printf("Reconcile: stopping poller for deleted, disabled or reassigned device %s", id)
+		s.RemoveDevice(id)
+		corrections++
+	}
+	return corrections
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+	"snmp-mqtt-bridge/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidScene is returned when a scene fails validation
+var ErrInvalidScene = errors.New("invalid scene")
+
+// ErrSceneRunNotFound is returned for unknown or expired scene run IDs
+var ErrSceneRunNotFound = errors.New("scene run not found")
+
+// maxSceneRuns bounds how many finished runs are kept for GetRun
+const maxSceneRuns = 100
+
+// SceneService manages scenes and runs them through the command queue
+type SceneService struct {
+	repo        repository.SceneRepository
+	deviceRepo  repository.DeviceRepository
+	profileRepo repository.ProfileRepository
+	queue       *CommandQueue
+	bus         *eventbus.Bus
+
+	runsMu sync.Mutex
+	runs   map[string]*domain.SceneRun
+	order  []string // run IDs, oldest first
+}
+
+// NewSceneService creates a new scene service
+func NewSceneService(
+	repo repository.SceneRepository,
+	deviceRepo repository.DeviceRepository,
+	profileRepo repository.ProfileRepository,
+	queue *CommandQueue,
+	bus *eventbus.Bus,
+) *SceneService {
+	return &SceneService{
+		repo:        repo,
+		deviceRepo:  deviceRepo,
+		profileRepo: profileRepo,
+		queue:       queue,
+		bus:         bus,
+		runs:        make(map[string]*domain.SceneRun),
+	}
+}
+
+// Create creates a new scene
+func (s *SceneService) Create(ctx context.Context, req *domain.SceneCreateRequest) (*domain.Scene, error) {
+	scene := &domain.Scene{
+		ID:          uuid.New().String(),
+		Name:        req.Name,
+		Description: req.Description,
+		Steps:       req.Steps,
+		HAButton:    req.HAButton,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	if err := validateScene(scene); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, scene); err != nil {
+		return nil, err
+	}
+
+	s.bus.Publish(eventbus.Event{Type: eventbus.TypeSceneUpdated, Payload: scene})
+	return scene, nil
+}
+
+// GetByID retrieves a scene by ID
+func (s *SceneService) GetByID(ctx context.Context, id string) (*domain.Scene, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// GetAll retrieves all scenes
+func (s *SceneService) GetAll(ctx context.Context) ([]domain.Scene, error) {
+	return s.repo.GetAll(ctx)
+}
+
+// Update updates an existing scene
+func (s *SceneService) Update(ctx context.Context, id string, req *domain.SceneUpdateRequest) (*domain.Scene, error) {
+	scene, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		scene.Name = *req.Name
+	}
+	if req.Description != nil {
+		scene.Description = *req.Description
+	}
+	if req.Steps != nil {
+		scene.Steps = req.Steps
+	}
+	if req.HAButton != nil {
+		scene.HAButton = *req.HAButton
+	}
+	scene.UpdatedAt = time.Now()
+
+	if err := validateScene(scene); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, scene); err != nil {
+		return nil, err
+	}
+
+	s.bus.Publish(eventbus.Event{Type: eventbus.TypeSceneUpdated, Payload: scene})
+	return scene, nil
+}
+
+// Delete deletes a scene
+func (s *SceneService) Delete(ctx context.Context, id string) error {
+	scene, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.bus.Publish(eventbus.Event{Type: eventbus.TypeSceneDeleted, Payload: scene})
+	return nil
+}
+
+// Run starts a scene in the background and returns the running run, whose
+// progress is available from GetRun and whose result is published as a
+// scene_run event. Each step is queued on its device and awaited before the
+// next one starts; a failing step does not stop the scene. The run is not
+// tied to the caller's context, so a dropped API request cannot leave a
+// half-executed scene.
+func (s *SceneService) Run(ctx context.Context, id, source string) (*domain.SceneRun, error) {
+	scene, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &domain.SceneRun{
+		ID:        uuid.NewString(),
+		SceneID:   scene.ID,
+		Name:      scene.Name,
+		Source:    source,
+		Running:   true,
+		Success:   true,
+		StartedAt: time.Now(),
+		Steps:     make([]domain.SceneStepResult, 0, len(scene.Steps)),
+	}
+	s.trackRun(run)
+	started := s.copyRun(run)
+
+	go s.execute(context.WithoutCancel(ctx), scene, run)
+
+	return started, nil
+}
+
+// GetRun returns the progress or result of a recent run of a scene
+func (s *SceneService) GetRun(sceneID, runID string) (*domain.SceneRun, error) {
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+
+	run, ok := s.runs[runID]
+	if !ok || run.SceneID != sceneID {
+		return nil, ErrSceneRunNotFound
+	}
+	return s.copyRunLocked(run), nil
+}
+
+func (s *SceneService) execute(ctx context.Context, scene *domain.Scene, run *domain.SceneRun) {
+	for i, step := range scene.Steps {
+		result := s.runStep(ctx, step)
+		result.Step = i + 1
+
+		s.runsMu.Lock()
+		if !result.Success {
+			run.Success = false
+		}
+		run.Steps = append(run.Steps, result)
+		s.runsMu.Unlock()
+	}
+
+	s.runsMu.Lock()
+	run.Running = false
+	run.FinishedAt = time.Now()
+	s.runsMu.Unlock()
+
+	log.Printf("Scene %q finished (success=%v, %d steps)", scene.Name, run.Success, len(run.Steps))
+	s.bus.Publish(eventbus.Event{Type: eventbus.TypeSceneRun, Payload: s.copyRun(run)})
+}
+
+// trackRun records a run for GetRun, forgetting the oldest finished runs
+// beyond maxSceneRuns
+func (s *SceneService) trackRun(run *domain.SceneRun) {
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+
+	s.runs[run.ID] = run
+	s.order = append(s.order, run.ID)
+
+	kept := s.order[:0]
+	excess := len(s.order) - maxSceneRuns
+	for _, id := range s.order {
+		if excess > 0 && !s.runs[id].Running {
+			delete(s.runs, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.order = kept
+}
+
+func (s *SceneService) copyRun(run *domain.SceneRun) *domain.SceneRun {
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+	return s.copyRunLocked(run)
+}
+
+func (s *SceneService) copyRunLocked(run *domain.SceneRun) *domain.SceneRun {
+	c := *run
+	c.Steps = append([]domain.SceneStepResult(nil), run.Steps...)
+	return &c
+}
+
+func (s *SceneService) runStep(ctx context.Context, step domain.SceneStep) domain.SceneStepResult {
+	result := domain.SceneStepResult{DeviceID: step.DeviceID, Type: step.Type}
+
+	commands, err := s.resolveStep(ctx, step)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	job, err := s.queue.Enqueue(ctx, step.DeviceID, commands)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Commands, _ = job.Wait(ctx)
+	result.Success = true
+	for _, cmd := range result.Commands {
+		if !cmd.Success {
+			result.Success = false
+			result.Error = cmd.Error
+		}
+	}
+	return result
+}
+
+// resolveStep turns a step into SNMP SET commands using the device profile
+func (s *SceneService) resolveStep(ctx context.Context, step domain.SceneStep) ([]QueuedCommand, error) {
+	device, err := s.deviceRepo.GetByID(ctx, step.DeviceID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDeviceNotFound, err)
+	}
+
+	profile := &domain.Profile{}
+	if device.ProfileID != "" {
+		if p, err := s.profileRepo.GetByID(ctx, device.ProfileID); err == nil {
+			profile = p
+		}
+	}
+
+	delay := step.Delay()
+
+	switch step.Type {
+	case domain.SceneStepOutlet:
+		control := profile.Capabilities.OutletControl
+		outlets := step.Outlets
+		if len(outlets) == 0 && control != nil {
+			for n := 1; n <= control.Count; n++ {
+				outlets = append(outlets, n)
+			}
+		}
+		if len(outlets) == 0 {
+			return nil, fmt.Errorf("outlet control %w", domain.ErrCapabilityUnsupported)
+		}
+
+		commands := make([]QueuedCommand, 0, len(outlets))
+		for _, n := range outlets {
+			var oid string
+			var value interface{}
+			if step.State == "reboot" {
+				oid, value, err = control.RebootCommand(n)
+			} else {
+				oid, value, err = control.StateCommand(n, step.State == "on")
+			}
+			if err != nil {
+				return nil, err
+			}
+			commands = append(commands, QueuedCommand{
+				Description: fmt.Sprintf("Outlet %d %s", n, step.State),
+				OID:         oid,
+				Value:       value,
+				Type:        control.Type,
+				Delay:       delay,
+			})
+		}
+		return commands, nil
+
+	case domain.SceneStepSource:
+		oid, value, label, err := profile.Capabilities.SourceSwitch.SwitchCommand(step.Source)
+		if err != nil {
+			return nil, err
+		}
+		return []QueuedCommand{{Description: "Switch to " + label, OID: oid, Value: value, Delay: delay}}, nil
+
+	case domain.SceneStepAction:
+		action, ok := profile.Action(step.Action)
+		if !ok {
+			return nil, ErrActionNotFound
+		}
+		return []QueuedCommand{{Description: action.Name, OID: action.OID, Value: action.Value, Type: action.Type, Delay: delay}}, nil
+
+	case domain.SceneStepSet:
+		return []QueuedCommand{{Description: "Set " + step.OID, OID: step.OID, Value: step.Value, Type: step.PDUType, Delay: delay}}, nil
+	}
+
+	return nil, fmt.Errorf("unknown step type %q", step.Type)
+}
+
+// validateScene checks the scene structure and that raw SET values fit their PDU type
+func validateScene(scene *domain.Scene) error {
+	if err := scene.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidScene, err)
+	}
+	for i, step := range scene.Steps {
+		if step.Type != domain.SceneStepSet {
+			continue
+		}
+		if _, err := BuildSetPDU(step.OID, step.Value, step.PDUType); err != nil {
+			return fmt.Errorf("%w: step %d: %v", ErrInvalidScene, i+1, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+	"snmp-mqtt-bridge/internal/repository/memory"
+)
+
+func TestSceneRunRunsInBackground(t *testing.T) {
+	repo := memory.NewSceneRepository()
+	scene := &domain.Scene{
+		ID:    "scene-1",
+		Name:  "Lab shutdown",
+		Steps: []domain.SceneStep{{DeviceID: "missing", Type: domain.SceneStepOutlet, State: "off"}},
+	}
+	if err := repo.Create(context.Background(), scene); err != nil {
+		t.Fatal(err)
+	}
+
+	bus := eventbus.NewBus()
+	sub := bus.Subscribe(eventbus.TypeSceneRun)
+	defer bus.Unsubscribe(sub)
+
+	svc := NewSceneService(repo, memory.NewDeviceRepository(), memory.NewProfileRepository(), nil, bus)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run, err := svc.Run(ctx, scene.ID, eventbus.CommandSourceAPI)
+	cancel()
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if run.ID == "" || !run.Running {
+		t.Fatalf("Run returned %+v; want a running run with an ID", run)
+	}
+
+	select {
+	case evt := <-sub.C:
+		if done := evt.Payload.(*domain.SceneRun); done.ID != run.ID {
+			t.Errorf("scene_run event for run %q, want %q", done.ID, run.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no scene_run event after the caller's context was cancelled")
+	}
+
+	done, err := svc.GetRun(scene.ID, run.ID)
+	if err != nil {
+		t.Fatalf("GetRun: %v", err)
+	}
+	if done.Running || done.Success || len(done.Steps) != 1 {
+		t.Errorf("finished run = %+v; want one failed step", done)
+	}
+
+	if _, err := svc.GetRun("other-scene", run.ID); !errors.Is(err, ErrSceneRunNotFound) {
+		t.Errorf("GetRun of another scene = %v; want ErrSceneRunNotFound", err)
+	}
+	if _, err := svc.GetRun(scene.ID, "unknown"); !errors.Is(err, ErrSceneRunNotFound) {
+		t.Errorf("GetRun of an unknown run = %v; want ErrSceneRunNotFound", err)
+	}
+}
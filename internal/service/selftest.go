@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+var (
+	// ErrSelfTestUnsupported is returned when the device profile has no self-test OIDs
+	ErrSelfTestUnsupported = errors.New("self-test not supported by device profile")
+	// ErrSelfTestRunning is returned when a self-test is already in progress
+	ErrSelfTestRunning = errors.New("self-test already in progress")
+)
+
+// selfTestPollAttempts is how many times the result is re-read while the device reports the test in progress
+const selfTestPollAttempts = 10
+
+// SelfTestStatus describes the self-test state of a device
+type SelfTestStatus struct {
+	DeviceID     string              `json:"device_id"`
+	Supported    bool                `json:"supported"`
+	Running      bool                `json:"running"`
+	IntervalDays int                 `json:"interval_days"`
+	LastTest     *domain.DeviceEvent `json:"last_test,omitempty"`
+	NextTest     *time.Time          `json:"next_test,omitempty"`
+}
+
+// SelfTestService triggers UPS battery self-tests and tracks their results
+type SelfTestService struct {
+	deviceRepo  repository.DeviceRepository
+	profileRepo repository.ProfileRepository
+	snmp        *SNMPService
+	queue       *CommandQueue
+	events      *EventService
+	poller      *PollerService
+
+	running   map[string]bool
+	runningMu sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewSelfTestService creates a new self-test service
+func NewSelfTestService(
+	deviceRepo repository.DeviceRepository,
+	profileRepo repository.ProfileRepository,
+	snmp *SNMPService,
+	queue *CommandQueue,
+	events *EventService,
+	poller *PollerService,
+) *SelfTestService {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &SelfTestService{
+		deviceRepo:  deviceRepo,
+		profileRepo: profileRepo,
+		snmp:        snmp,
+		queue:       queue,
+		events:      events,
+		poller:      poller,
+		running:     make(map[string]bool),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Start starts the scheduler that runs self-tests on devices with an interval configured
+func (s *SelfTestService) Start() {
+	s.wg.Add(1)
+	go s.schedule()
+	log.Println("Self-test scheduler started")
+}
+
+// Stop stops the scheduler and waits for running tests to finish tracking
+func (s *SelfTestService) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Run triggers a self-test on a device. The result is read in the background
+// and recorded in the device timeline once available.
+func (s *SelfTestService) Run(ctx context.Context, deviceID string) error {
+	device, config, err := s.load(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	if !config.Supported() {
+		return ErrSelfTestUnsupported
+	}
+
+	s.runningMu.Lock()
+	if s.running[deviceID] {
+		s.runningMu.Unlock()
+		return ErrSelfTestRunning
+	}
+	s.running[deviceID] = true
+	s.runningMu.Unlock()
+
+	trigger := QueuedCommand{Description: "Start self-test", OID: config.TriggerOID, Value: config.TriggerValue}
+	if err := s.queue.Send(ctx, deviceID, trigger); err != nil {
+		s.finish(deviceID)
+		return fmt.Errorf("failed to start self-test: %w", err)
+	}
+	if s.queue.DryRun(ctx) {
+		// The trigger was held back, so no result will come
+		s.finish(deviceID)
+		return nil
+	}
+
+	log.Printf("Self-test started on device %s", device.Name)
+	s.record(deviceID, "", "started", "Battery self-test started")
+
+	s.wg.Add(1)
+	go s.trackResult(deviceID, config)
+
+	return nil
+}
+
+// Status returns the self-test status of a device
+func (s *SelfTestService) Status(ctx context.Context, deviceID string) (*SelfTestStatus, error) {
+	device, config, err := s.load(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.runningMu.Lock()
+	running := s.running[deviceID]
+	s.runningMu.Unlock()
+
+	status := &SelfTestStatus{
+		DeviceID:     deviceID,
+		Supported:    config.Supported(),
+		Running:      running,
+		IntervalDays: device.SelfTestDays,
+		LastTest:     s.lastResult(ctx, deviceID),
+	}
+
+	if status.Supported && device.SelfTestDays > 0 {
+		next := time.Now()
+		if status.LastTest != nil {
+			next = status.LastTest.CreatedAt.Add(time.Duration(device.SelfTestDays) * 24 * time.Hour)
+		}
+		status.NextTest = &next
+	}
+
+	return status, nil
+}
+
+func (s *SelfTestService) load(ctx context.Context, deviceID string) (*domain.Device, *domain.SelfTestConfig, error) {
+	device, err := s.deviceRepo.GetByID(ctx, deviceID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrDeviceNotFound, err)
+	}
+
+	if device.ProfileID == "" {
+		return device, nil, nil
+	}
+	profile, err := s.profileRepo.GetByID(ctx, device.ProfileID)
+	if err != nil {
+		return device, nil, nil
+	}
+	return device, &profile.SelfTest, nil
+}
+
+func (s *SelfTestService) trackResult(deviceID string, config *domain.SelfTestConfig) {
+	defer s.wg.Done()
+	defer s.finish(deviceID)
+
+	var result string
+	var err error
+	for attempt := 0; attempt < selfTestPollAttempts; attempt++ {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(config.Delay()):
+		}
+
+		var value interface{}
+		value, err = s.snmp.GetValue(s.ctx, deviceID, config.ResultOID)
+		if err != nil {
+			continue
+		}
+
+		code, isInt := toInt(value)
+		if isInt && config.InProgressValue != 0 && code == config.InProgressValue {
+			continue
+		}
+
+		result = fmt.Sprintf("%v", value)
+		if name, ok := config.ResultValues[code]; isInt && ok {
+			result = name
+		}
+		break
+	}
+
+	if result == "" {
+		message := "Battery self-test result not available"
+		if err != nil {
+			message = fmt.Sprintf("%s: %v", message, err)
+		}
+		s.record(deviceID, "started", "unknown", message)
+		return
+	}
+
+	log.Printf("Self-test on device %s finished: %s", deviceID, result)
+	s.record(deviceID, "started", result, fmt.Sprintf("Battery self-test finished: %s", result))
+
+	// Refresh the Last Self Test Result entity
+	s.poller.TriggerPoll(deviceID)
+}
+
+func (s *SelfTestService) finish(deviceID string) {
+	s.runningMu.Lock()
+	delete(s.running, deviceID)
+	s.runningMu.Unlock()
+}
+
+func (s *SelfTestService) record(deviceID, oldValue, newValue, message string) {
+	event := &domain.DeviceEvent{
+		DeviceID: deviceID,
+		Type:     domain.EventTypeSelfTest,
+		Entity:   domain.LastSelfTestResultName,
+		OldValue: oldValue,
+		NewValue: newValue,
+		Message:  message,
+	}
+	if err := s.events.Record(s.ctx, event); err != nil {
+		log.Printf("Failed to record self-test event for device %s: %v", deviceID, err)
+	}
+}
+
+// lastResult returns the most recent self-test event for a device
+func (s *SelfTestService) lastResult(ctx context.Context, deviceID string) *domain.DeviceEvent {
+	events, _, err := s.events.GetAll(ctx, domain.EventFilter{
+		DeviceID: deviceID,
+		Type:     domain.EventTypeSelfTest,
+		Limit:    1,
+	})
+	if err != nil || len(events) == 0 {
+		return nil
+	}
+	return &events[0]
+}
+
+// schedule periodically runs self-tests on devices whose interval has elapsed
+func (s *SelfTestService) schedule() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.runDue()
+		}
+	}
+}
+
+func (s *SelfTestService) runDue() {
+	// Held back triggers would be retried every check
+	if s.queue.DryRun(s.ctx) {
+		return
+	}
+
+	devices, err := s.deviceRepo.GetEnabled(s.ctx)
+	if err != nil {
+		log.Printf("Self-test scheduler: failed to load devices: %v", err)
+		return
+	}
+
+	for i := range devices {
+		device := &devices[i]
+		if device.SelfTestDays <= 0 || !s.poller.Polling(device.ID) {
+			continue
+		}
+
+		if last := s.lastResult(s.ctx, device.ID); last != nil &&
+			time.Since(last.CreatedAt) < time.Duration(device.SelfTestDays)*24*time.Hour {
+			continue
+		}
+
+		err := s.Run(s.ctx, device.ID)
+		if err != nil && !errors.Is(err, ErrSelfTestRunning) && !errors.Is(err, ErrSelfTestUnsupported) {
+			log.Printf("Scheduled self-test on device %s failed: %v", device.Name, err)
+		}
+	}
+}
+
+// toInt converts integer SNMP values to int
+func toInt(v interface{}) (int, bool) {
+	n, ok := domain.Number(v)
+	if !ok || n != math.Trunc(n) {
+		return 0, false
+	}
+	return int(n), true
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+)
+
+// ShardService splits the devices of a sharded cluster between its nodes.
+// Each node writes a heartbeat to the shared database three times per TTL;
+// nodes without one for a TTL are considered gone and their devices move to
+// the remaining nodes.
+type ShardService struct {
+	repo         repository.ClusterNodeRepository
+	node         domain.ClusterNode
+	ttl          time.Duration
+	syncInterval time.Duration
+
+	coordinator *LeaderElector // Elects the node running cluster-wide subsystems
+
+	mu        sync.RWMutex
+	nodes     []domain.ClusterNode // Live nodes, by ID
+	heartbeat time.Time            // Last heartbeat written; zero until joined
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewShardService creates the shard service of a node serving the given
+// tags; devices changed through other nodes are picked up every syncInterval
+func NewShardService(repo repository.ClusterNodeRepository, nodeID string, tags []string, ttl, syncInterval time.Duration) *ShardService {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &ShardService{
+		repo:         repo,
+		node:         domain.ClusterNode{ID: nodeID, Tags: tags, StartedAt: time.Now().UTC()},
+		ttl:          ttl,
+		syncInterval: syncInterval,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Join announces the node and loads the live nodes, so ownership is known
+// before polling starts
+func (s *ShardService) Join() error {
+	if _, err := s.refresh(); err != nil {
+		return fmt.Errorf("failed to join cluster: %w", err)
+	}
+	log.Printf("Cluster node %s joined with nodes: %s", s.node.ID, strings.Join(s.nodeIDs(), ", "))
+	return nil
+}
+
+// Leave removes the node's heartbeat, so the other nodes take over its
+// devices at once instead of after a TTL
+func (s *ShardService) Leave() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.repo.Delete(ctx, s.node.ID); err != nil {
+		log.Printf("Failed to leave cluster: %v", err)
+	}
+}
+
+// Start keeps the heartbeat going and calls onChange when nodes join or leave,
+// and every sync interval. Once the heartbeat is a TTL old onExpire is
+// called, which must release all devices without reading the database as it
+// is likely unreachable; onChange follows when the heartbeat is restored.
+func (s *ShardService) Start(onChange, onExpire func()) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		heartbeat := time.NewTicker(s.ttl / 3)
+		defer heartbeat.Stop()
+		var resync <-chan time.Time
+		if s.syncInterval > 0 {
+			ticker := time.NewTicker(s.syncInterval)
+			defer ticker.Stop()
+			resync = ticker.C
+		}
+
+		expired := false
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-heartbeat.C:
+				changed, err := s.refresh()
+				if err != nil {
+					log.Printf("Cluster heartbeat failed: %v", err)
+					// The other nodes take over once the heartbeat is a TTL old
+					if !expired && s.expired() {
+						expired = true
+						log.Printf("Cluster heartbeat older than %s; releasing all devices", s.ttl)
+						onExpire()
+					}
+					continue
+				}
+				if expired {
+					expired = false
+					log.Printf("Cluster heartbeat restored; rebalancing devices")
+					changed = true
+				}
+				if changed {
+					log.Printf("Cluster nodes changed: %s; rebalancing devices", strings.Join(s.nodeIDs(), ", "))
+					onChange()
+				}
+			case <-resync:
+				if !expired {
+					onChange()
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the heartbeat
+func (s *ShardService) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// refresh writes the heartbeat and reloads the live nodes; it reports
+// whether they changed
+func (s *ShardService) refresh() (bool, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, s.ttl/3)
+	defer cancel()
+
+	now := time.Now().UTC()
+	node := s.node
+	node.SeenAt = now
+	if err := s.repo.Heartbeat(ctx, &node); err != nil {
+		return false, err
+	}
+	nodes, err := s.repo.GetAlive(ctx, now.Add(-s.ttl))
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.heartbeat = now
+	changed := len(nodes) != len(s.nodes)
+	for i := 0; !changed && i < len(nodes); i++ {
+		changed = nodes[i].ID != s.nodes[i].ID || strings.Join(nodes[i].Tags, ",") != strings.Join(s.nodes[i].Tags, ",")
+	}
+	s.nodes = nodes
+	return changed, nil
+}
+
+func (s *ShardService) nodeIDs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]string, len(s.nodes))
+	for i, node := range s.nodes {
+		ids[i] = node.ID
+	}
+	return ids
+}
+
+// SetCoordinator sets the election of the node that runs the subsystems
+// only one node of the cluster may run, reported by Status
+func (s *ShardService) SetCoordinator(coordinator *LeaderElector) {
+	s.coordinator = coordinator
+}
+
+// expired reports whether the last heartbeat is a TTL old, so the other
+// nodes no longer count this one as alive
+func (s *ShardService) expired() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.expiredLocked()
+}
+
+func (s *ShardService) expiredLocked() bool {
+	return !s.heartbeat.IsZero() && time.Since(s.heartbeat) >= s.ttl
+}
+
+// Owns reports whether this node polls and publishes the device. A node
+// whose heartbeat expired owns none, as the other nodes have taken over.
+func (s *ShardService) Owns(device *domain.Device) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.nodes) == 0 {
+		// Not joined yet
+		return true
+	}
+	if s.expiredLocked() {
+		return false
+	}
+	return domain.ShardOwner(device.ID, device.Shard, s.nodes) == s.node.ID
+}
+
+// Status returns the live nodes, the coordinator and how many devices this
+// node polls
+func (s *ShardService) Status(ctx context.Context, devices int) (*domain.ClusterStatus, error) {
+	s.mu.RLock()
+	status := &domain.ClusterStatus{
+		Mode:    "sharded",
+		NodeID:  s.node.ID,
+		Nodes:   append([]domain.ClusterNode(nil), s.nodes...),
+		Devices: devices,
+	}
+	s.mu.RUnlock()
+
+	if s.coordinator != nil {
+		leader, err := s.coordinator.Status(ctx)
+		if err != nil {
+			return nil, err
+		}
+		status.Coordinator = leader.Holder
+	}
+	return status, nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/repository"
+	"snmp-mqtt-bridge/internal/repository/memory"
+)
+
+// unreachableNodes fails every call while down is set, as a database that
+// cannot be reached
+type unreachableNodes struct {
+	repository.ClusterNodeRepository
+	down atomic.Bool
+}
+
+func (r *unreachableNodes) Heartbeat(ctx context.Context, node *domain.ClusterNode) error {
+	if r.down.Load() {
+		return errors.New("connection refused")
+	}
+	return r.ClusterNodeRepository.Heartbeat(ctx, node)
+}
+
+func (r *unreachableNodes) GetAlive(ctx context.Context, since time.Time) ([]domain.ClusterNode, error) {
+	if r.down.Load() {
+		return nil, errors.New("connection refused")
+	}
+	return r.ClusterNodeRepository.GetAlive(ctx, since)
+}
+
+func TestShardReleasesDevicesOnceHeartbeatExpires(t *testing.T) {
+	repo := &unreachableNodes{ClusterNodeRepository: memory.NewClusterNodeRepository()}
+	shard := NewShardService(repo, "node-a", nil, 150*time.Millisecond, 0)
+	if err := shard.Join(); err != nil {
+		t.Fatalf("Join: %v", err)
+	}
+
+	changed := make(chan struct{}, 10)
+	expired := make(chan struct{}, 10)
+	shard.Start(func() { changed <- struct{}{} }, func() { expired <- struct{}{} })
+	defer shard.Stop()
+
+	device := &domain.Device{ID: "pdu-1"}
+	if !shard.Owns(device) {
+		t.Fatal("the only live node does not own the device")
+	}
+
+	repo.down.Store(true)
+	select {
+	case <-expired:
+	case <-time.After(time.Second):
+		t.Fatal("devices were not released once the heartbeat expired")
+	}
+	if shard.Owns(device) {
+		t.Error("a node with an expired heartbeat still owns the device")
+	}
+	select {
+	case <-changed:
+		t.Error("onChange was called while the database was unreachable")
+	default:
+	}
+
+	repo.down.Store(false)
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("devices were not rebalanced once the heartbeat was restored")
+	}
+	if !shard.Owns(device) {
+		t.Error("the device is not owned again after the heartbeat was restored")
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+)
+
+const (
+	smtpDialTimeout = 10 * time.Second
+	smtpSendTimeout = 30 * time.Second
+
+	defaultSMTPSubject = "[{{.Severity}}] {{if .DeviceName}}{{.DeviceName}}: {{end}}{{.Title}}"
+	defaultSMTPBody    = `{{.Message}}
+
+Severity: {{.Severity}}
+{{- if .DeviceName}}
+Device:   {{.DeviceName}}{{end}}
+{{- if .Site}}
+Site:     {{.Site}}{{end}}
+Time:     {{.Time.Format "2006-01-02 15:04:05 MST"}}
+`
+)
+
+// SMTPNotifier sends notifications as plain text email
+type SMTPNotifier struct {
+	settings *domain.SMTPSettings
+}
+
+// NewSMTPNotifier creates an SMTP notifier for the given settings
+func NewSMTPNotifier(settings *domain.SMTPSettings) *SMTPNotifier {
+	return &SMTPNotifier{settings: settings}
+}
+
+// Name identifies the channel in logs and status
+func (n *SMTPNotifier) Name() string {
+	return "smtp"
+}
+
+// Notify renders the subject and body templates and sends the email to the
+// recipients configured for the notification's severity
+func (n *SMTPNotifier) Notify(notification *domain.Notification) error {
+	cfg := n.settings
+	to := cfg.Recipients(notification.Severity)
+	if len(to) == 0 {
+		return fmt.Errorf("no recipients for severity %s", notification.Severity)
+	}
+
+	subject, err := renderTemplate("subject", cfg.Subject, defaultSMTPSubject, notification)
+	if err != nil {
+		return err
+	}
+	body, err := renderTemplate("body", cfg.Body, defaultSMTPBody, notification)
+	if err != nil {
+		return err
+	}
+
+	return n.send(to, buildMessage(cfg.From, to, subject, body))
+}
+
+func (n *SMTPNotifier) send(to []string, msg []byte) error {
+	cfg := n.settings
+	addr := net.JoinHostPort(cfg.Host, fmt.Sprint(cfg.Port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+	if cfg.Security == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpSendTimeout))
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if cfg.Security != "tls" && cfg.Security != "none" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS; set %s to none to send unencrypted", cfg.Host, domain.SettingSMTPSecurity)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(cfg.From); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("recipient %s: %w", rcpt, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func renderTemplate(name, text, fallback string, data interface{}) (string, error) {
+	if text == "" {
+		text = fallback
+	}
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("render %s template: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+func buildMessage(from string, to []string, subject, body string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject)))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return buf.Bytes()
+}
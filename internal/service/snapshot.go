@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"snmp-mqtt-bridge/internal/domain"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// snapshotLimit caps the objects in a snapshot, so walking a huge
+// enterprise subtree cannot run away
+const snapshotLimit = 10000
+
+// snapshotSystemRoot is the MIB-2 system group, walked for every snapshot
+const snapshotSystemRoot = ".1.3.6.1.2.1.1"
+
+// snapshotRedactedOIDs identify the site, so their values are always
+// replaced: sysContact, sysName and sysLocation
+var snapshotRedactedOIDs = map[string]bool{
+	".1.3.6.1.2.1.1.4.0": true,
+	".1.3.6.1.2.1.1.5.0": true,
+	".1.3.6.1.2.1.1.6.0": true,
+}
+
+// snapshotRedactedIP replaces IpAddress values, keeping the object loadable
+const snapshotRedactedIP = "192.0.2.1"
+
+// errSnapshotFull stops a walk at snapshotLimit
+var errSnapshotFull = errors.New("snapshot full")
+
+// Snapshot walks a device and returns the objects found, sanitized for
+// sharing: site names, addresses and communities are replaced. Without roots
+// it walks the system group and the subtrees of the device's mappings.
+func (s *PollerService) Snapshot(ctx context.Context, deviceID string, roots []string) (*domain.SNMPSnapshot, error) {
+	for _, root := range roots {
+		if !domain.ValidOID(root) {
+			return nil, ErrInvalidOID
+		}
+	}
+
+	device, err := s.deviceRepo.GetByID(ctx, deviceID)
+	if err != nil {
+		return nil, ErrDeviceNotFound
+	}
+	var profile *domain.Profile
+	if device.ProfileID != "" {
+		if profile, err = s.profileRepo.GetByID(ctx, device.ProfileID); err != nil {
+			return nil, fmt.Errorf("failed to load profile %s: %w", device.ProfileID, err)
+		}
+	}
+	if len(roots) == 0 {
+		roots = snapshotRoots(profile.WithCustomMappings(device))
+	}
+
+	client, err := OpenSNMP(device, device.ReadCommunity(), SNMPConnSnapshot)
+	if err != nil {
+		return nil, &SNMPError{Op: "WALK", Err: err}
+	}
+	defer client.Close()
+
+	snapshot := &domain.SNMPSnapshot{
+		ProfileID:   device.ProfileID,
+		SNMPVersion: device.SNMPVersion,
+		Roots:       roots,
+		CreatedAt:   time.Now(),
+		Entries:     []domain.SnapshotEntry{},
+	}
+	secrets := snapshotSecrets(device)
+	seen := make(map[string]bool)
+	collect := func(pdu gosnmp.SnmpPDU) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if len(snapshot.Entries) >= snapshotLimit {
+			return errSnapshotFull
+		}
+		oid := "." + normalizeOID(pdu.Name)
+		if seen[oid] {
+			return nil
+		}
+		seen[oid] = true
+		entry := snapshotEntry(oid, pdu, secrets)
+		if oid == ".1.3.6.1.2.1.1.2.0" {
+			snapshot.SysObjectID, _ = entry.Value.(string)
+		}
+		snapshot.Entries = append(snapshot.Entries, entry)
+		return nil
+	}
+
+	for _, root := range roots {
+		client.Touch()
+		if device.SNMPVersion == domain.SNMPv1 {
+			err = client.Walk(root, collect)
+		} else {
+			err = client.BulkWalk(root, collect)
+		}
+		if errors.Is(err, errSnapshotFull) {
+			snapshot.Truncated = true
+			break
+		}
+		if err != nil {
+			return nil, &SNMPError{Op: "WALK", Err: err}
+		}
+	}
+	return snapshot, nil
+}
+
+// snapshotRoots returns the system group and the subtrees holding a
+// profile's OIDs: the vendor branch of enterprise OIDs, e.g. APC's rPDU
+// (.1.3.6.1.4.1.318.1.1.12), and the MIB of standard ones, e.g. UPS-MIB
+func snapshotRoots(profile *domain.Profile) []string {
+	roots := []string{snapshotSystemRoot}
+	if profile == nil {
+		return roots
+	}
+
+	for _, mapping := range profile.OIDMappings {
+		if mapping.Computed || mapping.OID == "" {
+			continue
+		}
+		arcs := strings.Split(normalizeOID(mapping.OID), ".")
+		depth := len(arcs) - 1
+		switch {
+		case strings.HasPrefix(mapping.OID, ".1.3.6.1.4.1.") && depth > 10:
+			depth = 10
+		case strings.HasPrefix(mapping.OID, ".1.3.6.1.2.1.") && depth > 7:
+			depth = 7
+		}
+		root := "." + strings.Join(arcs[:depth], ".")
+
+		covered := false
+		for i, existing := range roots {
+			if within(root, existing) {
+				covered = true
+				break
+			}
+			if within(existing, root) {
+				roots[i] = root
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
+// within reports whether oid lies in the subtree of root
+func within(oid, root string) bool {
+	return oid == root || strings.HasPrefix(oid, root+".")
+}
+
+// snapshotSecrets returns the strings of a device that must not appear in a
+// snapshot; very short ones are left, as they would match everywhere
+func snapshotSecrets(device *domain.Device) []string {
+	var secrets []string
+	for _, s := range []string{
+		device.IPAddress, device.Name, device.HAName, device.ProxyHost,
+		device.ReadCommunity(), device.SetCommunity(),
+		device.Location, device.Rack, device.AssetTag, device.Contact,
+	} {
+		if len(s) >= 3 {
+			secrets = append(secrets, s)
+		}
+	}
+	return secrets
+}
+
+// snapshotEntry converts a walked object into a snapshot entry, replacing
+// values that identify the site
+func snapshotEntry(oid string, pdu gosnmp.SnmpPDU, secrets []string) domain.SnapshotEntry {
+	entry := domain.SnapshotEntry{OID: oid, Type: pdu.Type.String(), Value: pdu.Value}
+
+	switch pdu.Type {
+	case gosnmp.OctetString:
+		b, _ := pdu.Value.([]byte)
+		switch {
+		case snapshotRedactedOIDs[oid]:
+			entry.Value = domain.SnapshotRedacted
+			entry.Redacted = true
+		case printable(b):
+			text := string(b)
+			for _, secret := range secrets {
+				if strings.Contains(text, secret) {
+					text = strings.ReplaceAll(text, secret, domain.SnapshotRedacted)
+					entry.Redacted = true
+				}
+			}
+			entry.Value = text
+		default:
+			entry.Value = hex.EncodeToString(b)
+			entry.Hex = true
+		}
+	case gosnmp.IPAddress:
+		entry.Value = snapshotRedactedIP
+		entry.Redacted = true
+	case gosnmp.Opaque, gosnmp.OpaqueFloat, gosnmp.OpaqueDouble:
+		if b, ok := pdu.Value.([]byte); ok {
+			entry.Value = hex.EncodeToString(b)
+			entry.Hex = true
+		} else {
+			entry.Value = DecodeOpaque(pdu)
+		}
+	}
+	return entry
+}
+
+// printable reports whether b is text that reads fine in JSON
+func printable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
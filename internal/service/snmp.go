@@ -2,93 +2,343 @@ package service
 
 import (
 	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
+	"math"
+	"net"
+	"strconv"
+	"strings"
 
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
 	"snmp-mqtt-bridge/internal/repository"
 
 	"github.com/gosnmp/gosnmp"
 )
 
+// SNMPError is an SNMP request to a device that failed or timed out
+type SNMPError struct {
+	Op  string // connect, GET or SET
+	Err error
+}
+
+func (e *SNMPError) Error() string {
+	if e.Op == "connect" {
+		return fmt.Sprintf("failed to connect: %v", e.Err)
+	}
+	return fmt.Sprintf("SNMP %s failed: %v", e.Op, e.Err)
+}
+
+func (e *SNMPError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports whether the device did not answer in time
+func (e *SNMPError) Timeout() bool {
+	var netErr net.Error
+	if errors.As(e.Err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	// gosnmp reports exhausted retries as a plain error
+	return errors.Is(e.Err, context.DeadlineExceeded) || strings.Contains(e.Err.Error(), "timeout")
+}
+
 // SNMPService handles SNMP operations
 type SNMPService struct {
 	deviceRepo  repository.DeviceRepository
 	profileRepo repository.ProfileRepository
+	bus         *eventbus.Bus
 }
 
 // NewSNMPService creates a new SNMP service
-func NewSNMPService(deviceRepo repository.DeviceRepository, profileRepo repository.ProfileRepository) *SNMPService {
+func NewSNMPService(deviceRepo repository.DeviceRepository, profileRepo repository.ProfileRepository, bus *eventbus.Bus) *SNMPService {
 	return &SNMPService{
 		deviceRepo:  deviceRepo,
 		profileRepo: profileRepo,
+		bus:         bus,
 	}
 }
 
-// SetValue sets an SNMP value on a device
-func (s *SNMPService) SetValue(ctx context.Context, deviceID, oid string, value interface{}) error {
+// setValue builds and sends a SET to a device; in a dry run it is only
+// validated. Called by the command queue, which every SET goes through.
+func (s *SNMPService) setValue(ctx context.Context, deviceID, oid string, value interface{}, pduType domain.PDUType, dryRun bool) error {
+	pdu, err := BuildSetPDU(oid, value, pduType)
+	if err != nil {
+		return err
+	}
+
 	device, err := s.deviceRepo.GetByID(ctx, deviceID)
 	if err != nil {
-		return fmt.Errorf("device not found: %w", err)
+		return fmt.Errorf("%w: %w", ErrDeviceNotFound, err)
+	}
+
+	if dryRun {
+		log.Printf("Dry run: not sending SNMP SET %s = %v to %s", oid, value, device.Name)
+		return nil
 	}
 
 	// Use write community if set, otherwise use read community
-	community := device.Community
-	if device.WriteCommunity != "" {
-		community = device.WriteCommunity
+	client, err := OpenSNMP(device, device.SetCommunity(), SNMPConnSet)
+	if err != nil {
+		return &SNMPError{Op: "connect", Err: err}
 	}
+	defer client.Close()
+
+	_, err = client.Set([]gosnmp.SnmpPDU{pdu})
+	if err != nil {
+		return &SNMPError{Op: "SET", Err: err}
+	}
+	return nil
+}
+
+// unsigned32PDUTypes maps the 32-bit unsigned PDU types to their ASN.1 tags
+var unsigned32PDUTypes = map[domain.PDUType]gosnmp.Asn1BER{
+	domain.PDUTypeGauge32:    gosnmp.Gauge32,
+	domain.PDUTypeCounter32:  gosnmp.Counter32,
+	domain.PDUTypeUnsigned32: gosnmp.Uinteger32,
+	domain.PDUTypeTimeTicks:  gosnmp.TimeTicks,
+}
+
+// BuildSetPDU builds a SET varbind for value. With an explicit pduType the value
+// is converted to that SNMP type; otherwise the type is guessed from the Go type
+// (numbers become Integer, strings OctetString).
+func BuildSetPDU(oid string, value interface{}, pduType domain.PDUType) (gosnmp.SnmpPDU, error) {
+	pdu := gosnmp.SnmpPDU{Name: oid}
+
+	switch pduType {
+	case "":
+		switch v := value.(type) {
+		case int:
+			pdu.Type = gosnmp.Integer
+			pdu.Value = v
+		case int64:
+			pdu.Type = gosnmp.Integer
+			pdu.Value = int(v)
+		case float64:
+			pdu.Type = gosnmp.Integer
+			pdu.Value = int(v)
+		case string:
+			pdu.Type = gosnmp.OctetString
+			pdu.Value = v
+		default:
+			return pdu, fmt.Errorf("unsupported value type: %T", value)
+		}
+
+	case domain.PDUTypeInteger:
+		n, err := toInt64(value)
+		if err != nil {
+			return pdu, err
+		}
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return pdu, fmt.Errorf("value %d out of range for integer", n)
+		}
+		pdu.Type = gosnmp.Integer
+		pdu.Value = int(n)
+
+	case domain.PDUTypeGauge32, domain.PDUTypeCounter32, domain.PDUTypeUnsigned32, domain.PDUTypeTimeTicks:
+		n, err := toInt64(value)
+		if err != nil {
+			return pdu, err
+		}
+		if n < 0 || n > math.MaxUint32 {
+			return pdu, fmt.Errorf("value %d out of range for %s", n, pduType)
+		}
+		pdu.Type = unsigned32PDUTypes[pduType]
+		pdu.Value = uint32(n)
+
+	case domain.PDUTypeCounter64:
+		n, err := toUint64(value)
+		if err != nil {
+			return pdu, err
+		}
+		pdu.Type = gosnmp.Counter64
+		pdu.Value = n
+
+	case domain.PDUTypeOctetString:
+		pdu.Type = gosnmp.OctetString
+		pdu.Value = fmt.Sprintf("%v", value)
+
+	case domain.PDUTypeIPAddress:
+		str := fmt.Sprintf("%v", value)
+		ip := net.ParseIP(str)
+		if ip == nil || ip.To4() == nil {
+			return pdu, fmt.Errorf("invalid IPv4 address: %s", str)
+		}
+		pdu.Type = gosnmp.IPAddress
+		pdu.Value = ip.To4().String()
+
+	case domain.PDUTypeOID:
+		str := fmt.Sprintf("%v", value)
+		if !domain.ValidOID(str) {
+			return pdu, fmt.Errorf("invalid OID value: %s", str)
+		}
+		pdu.Type = gosnmp.ObjectIdentifier
+		pdu.Value = str
+
+	case domain.PDUTypeFloat:
+		f, err := toFloat(value)
+		if err != nil {
+			return pdu, err
+		}
+		if math.Abs(f) > math.MaxFloat32 {
+			return pdu, fmt.Errorf("value %v out of range for float", f)
+		}
+		pdu.Type = gosnmp.OpaqueFloat
+		pdu.Value = float32(f)
 
-	client := createSNMPClient(device.IPAddress, device.Port, community, device.SNMPVersion)
+	case domain.PDUTypeDouble:
+		f, err := toFloat(value)
+		if err != nil {
+			return pdu, err
+		}
+		pdu.Type = gosnmp.OpaqueDouble
+		pdu.Value = f
 
-	if err := client.Connect(); err != nil {
-		return fmt.Errorf("failed to connect: %w", err)
+	default:
+		return pdu, fmt.Errorf("unsupported PDU type: %s", pduType)
 	}
-	defer client.Conn.Close()
 
-	// Determine PDU type based on value type
-	var pdu gosnmp.SnmpPDU
-	pdu.Name = oid
+	return pdu, nil
+}
 
+// toInt64 converts JSON/MQTT payload values to an integer
+func toInt64(value interface{}) (int64, error) {
 	switch v := value.(type) {
 	case int:
-		pdu.Type = gosnmp.Integer
-		pdu.Value = v
+		return int64(v), nil
 	case int64:
-		pdu.Type = gosnmp.Integer
-		pdu.Value = int(v)
+		return v, nil
+	case uint:
+		if uint64(v) > math.MaxInt64 {
+			return 0, fmt.Errorf("value %d out of range", v)
+		}
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, fmt.Errorf("value %d out of range", v)
+		}
+		return int64(v), nil
 	case float64:
-		pdu.Type = gosnmp.Integer
-		pdu.Value = int(v)
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("value %v is not an integer", v)
+		}
+		return int64(v), nil
 	case string:
-		pdu.Type = gosnmp.OctetString
-		pdu.Value = v
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("value %q is not an integer", v)
+		}
+		return n, nil
 	default:
-		return fmt.Errorf("unsupported value type: %T", value)
+		return 0, fmt.Errorf("unsupported value type: %T", value)
 	}
+}
 
-	_, err = client.Set([]gosnmp.SnmpPDU{pdu})
-	if err != nil {
-		return fmt.Errorf("SNMP SET failed: %w", err)
+// toUint64 converts JSON/MQTT payload values to an unsigned integer covering
+// the full Counter64 range. JSON numbers above 2^53 lose precision as floats,
+// so such values should be sent as strings.
+func toUint64(value interface{}) (uint64, error) {
+	switch v := value.(type) {
+	case int:
+		if v < 0 {
+			return 0, fmt.Errorf("value %d out of range for counter64", v)
+		}
+		return uint64(v), nil
+	case int64:
+		if v < 0 {
+			return 0, fmt.Errorf("value %d out of range for counter64", v)
+		}
+		return uint64(v), nil
+	case uint:
+		return uint64(v), nil
+	case uint32:
+		return uint64(v), nil
+	case uint64:
+		return v, nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("value %v is not an integer", v)
+		}
+		// 2^64 itself is representable as a float but not as a uint64
+		if v < 0 || v >= math.MaxUint64 {
+			return 0, fmt.Errorf("value %v out of range for counter64", v)
+		}
+		return uint64(v), nil
+	case string:
+		n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("value %q is not a counter64", v)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("unsupported value type: %T", value)
 	}
+}
 
-	return nil
+// toFloat converts JSON/MQTT payload values to a float
+func toFloat(value interface{}) (float64, error) {
+	if str, ok := value.(string); ok {
+		f, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+		if err != nil {
+			return 0, fmt.Errorf("value %q is not a number", str)
+		}
+		return f, nil
+	}
+	if f, ok := domain.Number(value); ok {
+		return f, nil
+	}
+	return 0, fmt.Errorf("unsupported value type: %T", value)
+}
+
+// DecodeOpaque returns the number in an Opaque value. gosnmp decodes floats
+// wrapped the Net-SNMP way; a bare 4 or 8 byte Opaque, as some devices send,
+// is read as an IEEE float, and anything else is returned as hex.
+func DecodeOpaque(variable gosnmp.SnmpPDU) interface{} {
+	switch v := variable.Value.(type) {
+	case float32:
+		return float32Value(v)
+	case float64:
+		return v
+	case []byte:
+		switch len(v) {
+		case 4:
+			return float32Value(math.Float32frombits(binary.BigEndian.Uint32(v)))
+		case 8:
+			return math.Float64frombits(binary.BigEndian.Uint64(v))
+		}
+		return hex.EncodeToString(v)
+	}
+	return variable.Value
+}
+
+// float32Value widens f in its shortest decimal form, so 230.1 is not
+// published as 230.10000610351562
+func float32Value(f float32) float64 {
+	v, _ := strconv.ParseFloat(strconv.FormatFloat(float64(f), 'g', -1, 32), 64)
+	return v
 }
 
 // GetValue gets a single SNMP value from a device
 func (s *SNMPService) GetValue(ctx context.Context, deviceID, oid string) (interface{}, error) {
 	device, err := s.deviceRepo.GetByID(ctx, deviceID)
 	if err != nil {
-		return nil, fmt.Errorf("device not found: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrDeviceNotFound, err)
 	}
 
-	client := createSNMPClient(device.IPAddress, device.Port, device.Community, device.SNMPVersion)
-
-	if err := client.Connect(); err != nil {
-		return nil, fmt.Errorf("failed to connect: %w", err)
+	client, err := OpenSNMP(device, device.ReadCommunity(), SNMPConnGet)
+	if err != nil {
+		return nil, &SNMPError{Op: "connect", Err: err}
 	}
-	defer client.Conn.Close()
+	defer client.Close()
 
 	result, err := client.Get([]string{oid})
 	if err != nil {
-		return nil, fmt.Errorf("SNMP GET failed: %w", err)
+		return nil, &SNMPError{Op: "GET", Err: err}
 	}
 
 	if len(result.Variables) == 0 {
@@ -101,6 +351,8 @@ func (s *SNMPService) GetValue(ctx context.Context, deviceID, oid string) (inter
 		return string(variable.Value.([]byte)), nil
 	case gosnmp.Integer, gosnmp.Counter32, gosnmp.Counter64, gosnmp.Gauge32, gosnmp.TimeTicks, gosnmp.Uinteger32:
 		return variable.Value, nil
+	case gosnmp.Opaque, gosnmp.OpaqueFloat, gosnmp.OpaqueDouble:
+		return DecodeOpaque(variable), nil
 	default:
 		return variable.Value, nil
 	}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"snmp-mqtt-bridge/internal/domain"
+)
+
+func TestBuildSetPDUCounter64FullRange(t *testing.T) {
+	for _, value := range []interface{}{"18446744073709551615", uint64(math.MaxUint64)} {
+		pdu, err := BuildSetPDU("1.3.6.1.4.1.1.1", value, domain.PDUTypeCounter64)
+		if err != nil {
+			t.Fatalf("BuildSetPDU(%v): %v", value, err)
+		}
+		if pdu.Value != uint64(math.MaxUint64) {
+			t.Errorf("BuildSetPDU(%v) = %v; want %d", value, pdu.Value, uint64(math.MaxUint64))
+		}
+	}
+
+	for _, value := range []interface{}{-1, "-1", "18446744073709551616", float64(1 << 64)} {
+		if _, err := BuildSetPDU("1.3.6.1.4.1.1.1", value, domain.PDUTypeCounter64); err == nil {
+			t.Errorf("BuildSetPDU(%v) accepted an out of range counter64", value)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// ErrSNMPConnectionLimit is returned when no connection slot frees up in time
+var ErrSNMPConnectionLimit = errors.New("too many open SNMP connections")
+
+// SNMP connection purposes
+const (
+	SNMPConnPoll     = "poll"     // Kept open between polls of a device
+	SNMPConnGet      = "get"      // One-off GET, e.g. the API or a command read-back
+	SNMPConnSet      = "set"      // One-off SET
+	SNMPConnTest     = "test"     // Connection test
+	SNMPConnPreview  = "preview"  // Mapping preview
+	SNMPConnSnapshot = "snapshot" // Walk snapshot for a bug report
+)
+
+// snmpConnWait is how long opening a connection waits for a free slot
+const snmpConnWait = 5 * time.Second
+
+// SNMPConn is an SNMP client whose socket is tracked by the connection
+// manager. Close it on every path; closing twice is harmless.
+type SNMPConn struct {
+	*gosnmp.GoSNMP
+	deviceID string
+	purpose  string
+	openedAt time.Time
+	lastUsed atomic.Int64
+	closed   atomic.Bool
+	slots    chan struct{} // Slot to release on close; nil when unlimited
+}
+
+// SNMPConnInfo describes an open SNMP connection
+type SNMPConnInfo struct {
+	DeviceID string    `json:"device_id,omitempty"`
+	Target   string    `json:"target"`
+	Purpose  string    `json:"purpose"`
+	OpenedAt time.Time `json:"opened_at"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// SNMPConnStats reports the SNMP sockets the bridge holds
+type SNMPConnStats struct {
+	Open        int            `json:"open"`
+	MaxOpen     int            `json:"max_open"` // 0 is unlimited
+	IdleTimeout string         `json:"idle_timeout"`
+	Opened      uint64         `json:"opened"`
+	Closed      uint64         `json:"closed"`
+	Rejected    uint64         `json:"rejected"` // Opens that found no free slot
+	Reaped      uint64         `json:"reaped"`   // Poll connections closed while idle
+	Leaked      uint64         `json:"leaked"`   // One-off connections the manager had to close
+	OpenFDs     int            `json:"open_fds"` // File descriptors of the process; -1 if unknown
+	ByPurpose   map[string]int `json:"by_purpose"`
+	Connections []SNMPConnInfo `json:"connections"`
+}
+
+// snmpConnManager owns the lifecycle of all outgoing SNMP sockets: it caps
+// how many are open at once, closes poll connections left idle and closes
+// one-off connections held past the idle timeout as leaks
+type snmpConnManager struct {
+	mu          sync.Mutex
+	slots       chan struct{} // nil when unlimited
+	idleTimeout time.Duration
+	bind        string // Local address for devices without their own
+	conns       map[*SNMPConn]struct{}
+
+	opened, closed, rejected, reaped, leaked uint64
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+var snmpConns = &snmpConnManager{
+	idleTimeout: 5 * time.Minute,
+	conns:       make(map[*SNMPConn]struct{}),
+}
+
+// SNMPConnOptions configures the outgoing SNMP sockets
+type SNMPConnOptions struct {
+	MaxOpen     int           // Open sockets at once; 0 is unlimited
+	IdleTimeout time.Duration // How long a connection may go unused before it is closed
+	BindAddress string        // Local IP or interface for devices without their own; empty lets the OS choose
+}
+
+// ConfigureSNMPConnections applies the connection options. Call it once,
+// before any connection is opened.
+func ConfigureSNMPConnections(opts SNMPConnOptions) error {
+	if _, err := ResolveBindAddress(opts.BindAddress); err != nil {
+		return err
+	}
+
+	m := snmpConns
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.slots = nil
+	if opts.MaxOpen > 0 {
+		m.slots = make(chan struct{}, opts.MaxOpen)
+	}
+	if opts.IdleTimeout > 0 {
+		m.idleTimeout = opts.IdleTimeout
+	}
+	m.bind = opts.BindAddress
+	return nil
+}
+
+// OpenSNMP connects an SNMP client for a device, waiting briefly for a free
+// slot when the connection limit is reached
+func OpenSNMP(device *domain.Device, community, purpose string) (*SNMPConn, error) {
+	m := snmpConns
+	m.mu.Lock()
+	slots := m.slots
+	bind := m.bind
+	m.mu.Unlock()
+
+	if slots != nil {
+		select {
+		case slots <- struct{}{}:
+		case <-time.After(snmpConnWait):
+			m.mu.Lock()
+			m.rejected++
+			m.mu.Unlock()
+			log.Printf("[WARN] SNMP connection limit (%d) reached, not connecting to device %s", cap(slots), device.ID)
+			return nil, fmt.Errorf("%w (limit %d)", ErrSNMPConnectionLimit, cap(slots))
+		}
+	}
+
+	client := NewSNMPClient(device, community, bind)
+	if err := client.Connect(); err != nil {
+		if slots != nil {
+			<-slots
+		}
+		return nil, err
+	}
+
+	conn := &SNMPConn{
+		GoSNMP:   client,
+		deviceID: device.ID,
+		purpose:  purpose,
+		openedAt: time.Now(),
+		slots:    slots,
+	}
+	conn.Touch()
+
+	m.mu.Lock()
+	m.conns[conn] = struct{}{}
+	m.opened++
+	m.mu.Unlock()
+	return conn, nil
+}
+
+// Touch marks the connection as in use, keeping it from being closed idle
+func (c *SNMPConn) Touch() {
+	c.lastUsed.Store(time.Now().UnixNano())
+}
+
+// Closed reports whether the connection was closed, possibly by the manager
+func (c *SNMPConn) Closed() bool {
+	return c.closed.Load()
+}
+
+// Close closes the socket and frees its slot
+func (c *SNMPConn) Close() {
+	if !c.closed.CompareAndSwap(false, true) {
+		return
+	}
+	if c.Conn != nil {
+		c.Conn.Close()
+	}
+	if c.slots != nil {
+		<-c.slots
+	}
+
+	m := snmpConns
+	m.mu.Lock()
+	delete(m.conns, c)
+	m.closed++
+	m.mu.Unlock()
+}
+
+// StartSNMPConnectionReaper starts closing idle and leaked connections
+func StartSNMPConnectionReaper() {
+	m := snmpConns
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopCh != nil {
+		return
+	}
+	m.stopCh = make(chan struct{})
+
+	interval := m.idleTimeout / 2
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	m.wg.Add(1)
+	go m.reapLoop(interval, m.stopCh)
+}
+
+// StopSNMPConnectionReaper stops the reaper and closes the connections
+// still open, e.g. those of pollers that exited on shutdown
+func StopSNMPConnectionReaper() {
+	m := snmpConns
+	m.mu.Lock()
+	stopCh := m.stopCh
+	m.stopCh = nil
+	m.mu.Unlock()
+	if stopCh == nil {
+		return
+	}
+	close(stopCh)
+	m.wg.Wait()
+
+	for _, conn := range m.snapshot() {
+		conn.Close()
+	}
+}
+
+func (m *snmpConnManager) reapLoop(interval time.Duration, stopCh chan struct{}) {
+	defer m.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			m.reap()
+		}
+	}
+}
+
+// reap closes poll connections unused for the idle timeout and one-off
+// connections open that long, which their owner should have closed
+func (m *snmpConnManager) reap() {
+	m.mu.Lock()
+	idleTimeout := m.idleTimeout
+	m.mu.Unlock()
+
+	now := time.Now()
+	for _, conn := range m.snapshot() {
+		if conn.purpose == SNMPConnPoll {
+			if now.Sub(time.Unix(0, conn.lastUsed.Load())) < idleTimeout {
+				continue
+			}
+			log.Printf("[DEBUG] Closing idle SNMP poll connection to device %s", conn.deviceID)
+			conn.Close()
+			m.mu.Lock()
+			m.reaped++
+			m.mu.Unlock()
+			continue
+		}
+
+		if now.Sub(conn.openedAt) < idleTimeout {
+			continue
+		}
+		log.Printf("[WARN] SNMP %s connection to %s (device %s) open for %s, closing leaked connection",
+			conn.purpose, conn.Target, conn.deviceID, now.Sub(conn.openedAt).Round(time.Second))
+		conn.Close()
+		m.mu.Lock()
+		m.leaked++
+		m.mu.Unlock()
+	}
+}
+
+func (m *snmpConnManager) snapshot() []*SNMPConn {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	conns := make([]*SNMPConn, 0, len(m.conns))
+	for conn := range m.conns {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// SNMPConnectionStats reports the open SNMP connections and the process's
+// file descriptor count, to spot socket leaks
+func SNMPConnectionStats() SNMPConnStats {
+	m := snmpConns
+	m.mu.Lock()
+	stats := SNMPConnStats{
+		Open:        len(m.conns),
+		IdleTimeout: m.idleTimeout.String(),
+		Opened:      m.opened,
+		Closed:      m.closed,
+		Rejected:    m.rejected,
+		Reaped:      m.reaped,
+		Leaked:      m.leaked,
+		ByPurpose:   map[string]int{},
+		Connections: make([]SNMPConnInfo, 0, len(m.conns)),
+	}
+	if m.slots != nil {
+		stats.MaxOpen = cap(m.slots)
+	}
+	for conn := range m.conns {
+		stats.ByPurpose[conn.purpose]++
+		stats.Connections = append(stats.Connections, SNMPConnInfo{
+			DeviceID: conn.deviceID,
+			Target:   conn.Target,
+			Purpose:  conn.purpose,
+			OpenedAt: conn.openedAt,
+			LastUsed: time.Unix(0, conn.lastUsed.Load()),
+		})
+	}
+	m.mu.Unlock()
+
+	sort.Slice(stats.Connections, func(i, j int) bool {
+		return stats.Connections[i].OpenedAt.Before(stats.Connections[j].OpenedAt)
+	})
+	stats.OpenFDs = openFDCount()
+	return stats
+}
+
+// openFDCount counts the process's open file descriptors, or returns -1
+// where /proc is unavailable
+func openFDCount() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
@@ -0,0 +1,50 @@
+package service
+
+import "sort"
+
+// SetStaleAfter marks a value stale once its OID was requested in that many
+// consecutive polls without returning it, e.g. after a firmware update drops
+// the object or while the device keeps answering with garbage. Stale values
+// are listed in the device state and published as unknown. 0 disables it.
+func (s *PollerService) SetStaleAfter(polls int) {
+	s.staleAfter = polls
+}
+
+// trackStale counts, per mapping requested in a poll, the consecutive polls
+// that returned no value for it, and returns the stale mapping names. OIDs
+// found missing are no longer polled, so their values are stale at once.
+func (s *PollerService) trackStale(dp *devicePoller, requested []string, values map[string]interface{}) []string {
+	if s.staleAfter <= 0 || dp.profile == nil {
+		return nil
+	}
+	if dp.misses == nil {
+		dp.misses = make(map[string]int)
+	}
+
+	polled := make(map[string]bool, len(requested))
+	for _, oid := range requested {
+		polled[normalizeOID(oid)] = true
+	}
+
+	var stale []string
+	for _, mapping := range dp.profile.OIDMappings {
+		if mapping.Computed {
+			continue
+		}
+		oid := normalizeOID(mapping.OID)
+		switch {
+		case dp.missingOIDs[oid]:
+			dp.misses[mapping.Name] = s.staleAfter
+		case !polled[oid]:
+		case values[mapping.Name] != nil:
+			delete(dp.misses, mapping.Name)
+		default:
+			dp.misses[mapping.Name]++
+		}
+		if dp.misses[mapping.Name] >= s.staleAfter {
+			stale = append(stale, mapping.Name)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
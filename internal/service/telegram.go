@@ -0,0 +1,74 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+)
+
+const notificationHTTPTimeout = 15 * time.Second
+
+var (
+	// telegramAPIURL is the Bot API base URL
+	telegramAPIURL = "https://api.telegram.org"
+
+	notificationHTTPClient = &http.Client{Timeout: notificationHTTPTimeout}
+)
+
+// TelegramNotifier sends notifications as Telegram bot messages
+type TelegramNotifier struct {
+	settings *domain.TelegramSettings
+}
+
+// NewTelegramNotifier creates a Telegram notifier for the given settings
+func NewTelegramNotifier(settings *domain.TelegramSettings) *TelegramNotifier {
+	return &TelegramNotifier{settings: settings}
+}
+
+// Name identifies the channel in logs and status
+func (n *TelegramNotifier) Name() string {
+	return domain.NotificationChannelTelegram
+}
+
+// Notify sends the notification to the configured chat
+func (n *TelegramNotifier) Notify(notification *domain.Notification) error {
+	text := fmt.Sprintf("[%s] %s\n\n%s", strings.ToUpper(string(notification.Severity)), notificationTitle(notification), notificationDetails(notification))
+
+	resp, err := notificationHTTPClient.PostForm(telegramAPIURL+"/bot"+n.settings.BotToken+"/sendMessage", url.Values{
+		"chat_id":                  {n.settings.ChatID},
+		"text":                     {text},
+		"disable_web_page_preview": {"true"},
+	})
+	if err != nil {
+		return redactURL(err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("telegram: unexpected response (HTTP %d)", resp.StatusCode)
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram: %s", result.Description)
+	}
+	return nil
+}
+
+// redactURL drops the request URL from HTTP client errors so credentials
+// embedded in it don't end up in logs or the status API
+func redactURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
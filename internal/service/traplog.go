@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	"snmp-mqtt-bridge/internal/domain"
 	"snmp-mqtt-bridge/internal/repository"
@@ -37,6 +39,84 @@ func (s *TrapLogService) GetAll(ctx context.Context, filter domain.TrapFilter) (
 	return s.repo.GetAll(ctx, filter)
 }
 
+// Stream calls fn for each trap matching filter, newest first
+func (s *TrapLogService) Stream(ctx context.Context, filter domain.TrapFilter, fn func(*domain.TrapLog) error) error {
+	return s.repo.Stream(ctx, filter, fn)
+}
+
+// Stats counts the traps received since the given time by severity, device
+// and trap OID. Every device passed in is listed, including those that sent
+// no traps, together with the time of its latest trap. With only is set,
+// counts are limited to the passed devices, e.g. those of one site.
+func (s *TrapLogService) Stats(ctx context.Context, since time.Time, devices []domain.Device, only bool) (*domain.TrapStats, error) {
+	filter := domain.TrapFilter{StartTime: &since}
+	if only {
+		filter.DeviceIDs = make([]string, len(devices))
+		for i, d := range devices {
+			filter.DeviceIDs[i] = d.ID
+		}
+	}
+
+	counts := &domain.TrapCounts{}
+	if !only || len(devices) > 0 {
+		var err error
+		if counts, err = s.repo.Counts(ctx, filter); err != nil {
+			return nil, err
+		}
+	}
+	last, err := s.repo.LastReceived(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &domain.TrapStats{
+		Since:      since,
+		BySeverity: make(map[domain.TrapSeverity]int64),
+		ByDevice:   make([]domain.TrapDeviceStats, 0, len(devices)+1),
+		ByOID:      make([]domain.TrapOIDCount, 0, len(counts.ByOID)),
+	}
+	for severity, count := range counts.BySeverity {
+		stats.BySeverity[severity] = count
+		stats.Total += count
+	}
+
+	listed := make(map[string]bool, len(devices))
+	for _, d := range devices {
+		listed[d.ID] = true
+		entry := domain.TrapDeviceStats{DeviceID: d.ID, DeviceName: d.Name, Count: counts.ByDevice[d.ID]}
+		if t, ok := last[d.ID]; ok {
+			entry.LastReceived = &t
+		}
+		stats.ByDevice = append(stats.ByDevice, entry)
+	}
+	// Traps from unknown sources and from deleted devices
+	for deviceID, count := range counts.ByDevice {
+		if listed[deviceID] {
+			continue
+		}
+		entry := domain.TrapDeviceStats{DeviceID: deviceID, Count: count}
+		if t, ok := last[deviceID]; ok {
+			entry.LastReceived = &t
+		}
+		stats.ByDevice = append(stats.ByDevice, entry)
+	}
+	sort.SliceStable(stats.ByDevice, func(i, j int) bool {
+		return stats.ByDevice[i].Count > stats.ByDevice[j].Count
+	})
+
+	for oid, count := range counts.ByOID {
+		stats.ByOID = append(stats.ByOID, domain.TrapOIDCount{TrapOID: oid, Count: count})
+	}
+	sort.Slice(stats.ByOID, func(i, j int) bool {
+		if stats.ByOID[i].Count != stats.ByOID[j].Count {
+			return stats.ByOID[i].Count > stats.ByOID[j].Count
+		}
+		return stats.ByOID[i].TrapOID < stats.ByOID[j].TrapOID
+	})
+
+	return stats, nil
+}
+
 // DeleteOlderThan deletes trap logs older than specified days
 func (s *TrapLogService) DeleteOlderThan(ctx context.Context, days int) (int64, error) {
 	return s.repo.DeleteOlderThan(ctx, days)
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"sort"
+	"strings"
+
+	"snmp-mqtt-bridge/internal/domain"
+)
+
+// ResolveTrapVariables matches trap variables against the OID mappings of a
+// polled device's profile and applies their scale and enum values, so e.g.
+// upsBasicOutputStatus=3 reads as "On Battery". Variables without a mapping
+// are left out; they remain available in the raw variables.
+func (s *PollerService) ResolveTrapVariables(deviceID string, variables domain.TrapVariables) domain.ResolvedTrapVariables {
+	s.devicesMu.RLock()
+	dp, exists := s.devices[deviceID]
+	s.devicesMu.RUnlock()
+
+	if !exists || dp.profile == nil || len(variables) == 0 {
+		return nil
+	}
+
+	mappings := make(map[string]*domain.OIDMapping, len(dp.profile.OIDMappings))
+	for i := range dp.profile.OIDMappings {
+		mapping := &dp.profile.OIDMappings[i]
+		if mapping.OID == "" || mapping.Type == domain.OIDTypeCompositeSwitch {
+			continue
+		}
+		oid := normalizeOID(mapping.OID)
+		if _, taken := mappings[oid]; !taken {
+			mappings[oid] = mapping
+		}
+	}
+
+	var resolved domain.ResolvedTrapVariables
+	for oid, raw := range variables {
+		mapping := lookupTrapMapping(mappings, normalizeOID(oid))
+		if mapping == nil {
+			continue
+		}
+		resolved = append(resolved, domain.ResolvedTrapVariable{
+			OID:   oid,
+			Name:  mapping.Name,
+			Raw:   raw,
+			Value: s.transformValue(raw, mapping),
+			Unit:  mapping.PublishedUnit(),
+		})
+	}
+
+	sort.Slice(resolved, func(i, j int) bool { return resolved[i].OID < resolved[j].OID })
+	return resolved
+}
+
+// lookupTrapMapping finds the mapping for a trap variable OID. Traps often
+// carry the object without the ".0" instance suffix used for polling, or
+// the other way around.
+func lookupTrapMapping(mappings map[string]*domain.OIDMapping, oid string) *domain.OIDMapping {
+	if mapping, ok := mappings[oid]; ok {
+		return mapping
+	}
+	if trimmed, ok := strings.CutSuffix(oid, ".0"); ok {
+		return mappings[trimmed]
+	}
+	return mappings[oid+".0"]
+}
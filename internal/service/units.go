@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+	"snmp-mqtt-bridge/internal/units"
+)
+
+// ErrInvalidUnit is returned for a temperature unit other than C or F
+var ErrInvalidUnit = errors.New("invalid unit")
+
+// UnitService switches the unit temperatures are published in while running.
+// The unit is kept in the settings, over units.temperature of the configuration.
+type UnitService struct {
+	settings   *SettingService
+	bus        *eventbus.Bus
+	configured string // units.temperature of the configuration
+}
+
+// NewUnitService creates a temperature unit preference over the configured one
+func NewUnitService(settings *SettingService, bus *eventbus.Bus, configured string) *UnitService {
+	return &UnitService{settings: settings, bus: bus, configured: configured}
+}
+
+// Load applies the temperature unit stored in the settings, if any
+func (s *UnitService) Load(ctx context.Context) error {
+	unit, _ := s.settings.Get(ctx, domain.SettingTemperatureUnit)
+	if unit == "" {
+		return nil
+	}
+	if err := units.Configure(unit); err != nil {
+		return fmt.Errorf("setting %s: %w", domain.SettingTemperatureUnit, err)
+	}
+	return nil
+}
+
+// Preferences returns the units values are published in
+func (s *UnitService) Preferences() *domain.UnitPreferences {
+	return &domain.UnitPreferences{
+		Temperature:           units.Temperature(),
+		ConfiguredTemperature: units.Normalize(s.configured),
+	}
+}
+
+// SetTemperature publishes all temperatures in unit ("C" or "F") from now on.
+// Empty falls back to the configured unit. The change is announced on the
+// bus, so discovery is republished and devices are polled again.
+func (s *UnitService) SetTemperature(ctx context.Context, unit string) (*domain.UnitPreferences, error) {
+	applied := unit
+	if applied == "" {
+		applied = s.configured
+	}
+	if err := units.Configure(applied); err != nil {
+		return nil, fmt.Errorf("%w %q, use C or F", ErrInvalidUnit, applied)
+	}
+
+	var err error
+	if unit == "" {
+		err = s.settings.Delete(ctx, domain.SettingTemperatureUnit)
+	} else {
+		err = s.settings.Set(ctx, domain.SettingTemperatureUnit, units.Normalize(unit))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	preferences := s.Preferences()
+	s.bus.Publish(eventbus.Event{Type: eventbus.TypeUnits, Payload: preferences})
+	return preferences, nil
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"snmp-mqtt-bridge/internal/buildinfo"
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+)
+
+// releaseSummaryLimit is the longest release summary Home Assistant accepts
+const releaseSummaryLimit = 255
+
+// defaultUpdateCheckInterval is used when no positive interval is configured
+const defaultUpdateCheckInterval = 24 * time.Hour
+
+// UpdateChecker periodically reads a release feed (a GitHub "latest release"
+// API URL or any JSON document with tag_name, name, html_url and body) and
+// reports whether a newer bridge version is available
+type UpdateChecker struct {
+	feedURL  string
+	interval time.Duration
+	bus      *eventbus.Bus
+	client   *http.Client
+
+	status domain.UpdateStatus
+	mu     sync.RWMutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewUpdateChecker creates an update checker for the given release feed
+func NewUpdateChecker(feedURL string, interval time.Duration, bus *eventbus.Bus) *UpdateChecker {
+	if interval <= 0 {
+		interval = defaultUpdateCheckInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &UpdateChecker{
+		feedURL:  feedURL,
+		interval: interval,
+		bus:      bus,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		status:   domain.UpdateStatus{InstalledVersion: buildinfo.Get().Version},
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start checks the feed now and then every interval
+func (u *UpdateChecker) Start() {
+	u.wg.Add(1)
+	go u.run()
+	log.Println("Update checker started")
+}
+
+// Stop stops the periodic checks
+func (u *UpdateChecker) Stop() {
+	u.cancel()
+	u.wg.Wait()
+}
+
+// Status returns the result of the last check
+func (u *UpdateChecker) Status() domain.UpdateStatus {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return u.status
+}
+
+func (u *UpdateChecker) run() {
+	defer u.wg.Done()
+
+	ticker := time.NewTicker(u.interval)
+	defer ticker.Stop()
+
+	for {
+		u.Check(u.ctx)
+		select {
+		case <-u.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check reads the release feed once and publishes the resulting status
+func (u *UpdateChecker) Check(ctx context.Context) domain.UpdateStatus {
+	release, err := u.fetch(ctx)
+	if ctx.Err() != nil {
+		return u.Status()
+	}
+
+	now := time.Now()
+	u.mu.Lock()
+	u.status.CheckedAt = &now
+	u.status.Error = ""
+	if err != nil {
+		log.Printf("Update check failed: %v", err)
+		u.status.Error = err.Error()
+	} else {
+		latest := strings.TrimPrefix(release.TagName, "v")
+		u.status.LatestVersion = latest
+		u.status.UpdateAvailable = newerVersion(latest, u.status.InstalledVersion)
+		u.status.Title = release.Name
+		u.status.ReleaseURL = release.HTMLURL
+		u.status.ReleaseSummary = truncateSummary(release.Body)
+		if u.status.UpdateAvailable {
+			log.Printf("Update available: %s (installed %s)", latest, u.status.InstalledVersion)
+		}
+	}
+	status := u.status
+	u.mu.Unlock()
+
+	u.bus.Publish(eventbus.Event{Type: eventbus.TypeUpdateStatus, Payload: &status})
+	return status
+}
+
+// release is the part of a GitHub release the checker reads
+type release struct {
+	TagName string `json:"tag_name"`
+	Name    string `json:"name"`
+	HTMLURL string `json:"html_url"`
+	Body    string `json:"body"`
+}
+
+func (u *UpdateChecker) fetch(ctx context.Context) (*release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.feedURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "snmp-mqtt-bridge/"+buildinfo.Get().Version)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, redactURL(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("release feed returned HTTP %d", resp.StatusCode)
+	}
+
+	var rel release
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return nil, fmt.Errorf("invalid release feed: %w", err)
+	}
+	if rel.TagName == "" {
+		return nil, fmt.Errorf("release feed has no tag_name")
+	}
+	return &rel, nil
+}
+
+// newerVersion reports whether latest is a higher x.y.z version than
+// installed. Development builds without a version never report updates.
+func newerVersion(latest, installed string) bool {
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	i, ok := parseVersion(installed)
+	if !ok {
+		return false
+	}
+	for n := range l {
+		if l[n] != i[n] {
+			return l[n] > i[n]
+		}
+	}
+	return false
+}
+
+// parseVersion parses "1.2.3", "v1.2" or "1.2.3-rc1" (pre-release ignored)
+func parseVersion(version string) ([3]int, bool) {
+	var parsed [3]int
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) > 3 {
+		return parsed, false
+	}
+	for n, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil || v < 0 {
+			return parsed, false
+		}
+		parsed[n] = v
+	}
+	return parsed, true
+}
+
+func truncateSummary(body string) string {
+	body = strings.TrimSpace(body)
+	if len(body) <= releaseSummaryLimit {
+		return body
+	}
+	cut := releaseSummaryLimit - 3
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return body[:cut] + "..."
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"time"
+
+	"snmp-mqtt-bridge/internal/domain"
+)
+
+// SetStartupWarmup spreads the first polls of the devices loaded at startup
+// evenly over a window, so a bridge with hundreds of devices does not send
+// them all requests at once. Devices added later are polled at once. 0 polls
+// all devices at once.
+func (s *PollerService) SetStartupWarmup(warmup time.Duration) {
+	s.warmup = warmup
+}
+
+// startDelay returns the wait before the first poll of the i-th of n devices
+func (s *PollerService) startDelay(i, n int) time.Duration {
+	if s.warmup <= 0 || n <= 1 {
+		return 0
+	}
+	return s.warmup * time.Duration(i) / time.Duration(n)
+}
+
+// awaitFirstPoll waits out a device's start delay; a triggered poll, e.g.
+// after a command, ends the wait early. Returns false if the poller stopped.
+func (s *PollerService) awaitFirstPoll(dp *devicePoller) bool {
+	if dp.startDelay <= 0 {
+		return true
+	}
+	timer := time.NewTimer(dp.startDelay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-dp.triggerCh:
+	case <-dp.stopCh:
+		return false
+	case <-s.ctx.Done():
+		return false
+	}
+	return true
+}
+
+// Status reports the progress of the first polls after startup
+func (s *PollerService) Status() *domain.PollerStatus {
+	status := &domain.PollerStatus{}
+
+	s.devicesMu.RLock()
+	status.StartedAt = s.startedAt
+	ids := make([]string, 0, len(s.devices))
+	for id := range s.devices {
+		ids = append(ids, id)
+	}
+	s.devicesMu.RUnlock()
+
+	s.statesMu.RLock()
+	for _, id := range ids {
+		if state, ok := s.states[id]; ok && !state.LastPoll.IsZero() {
+			status.Polled++
+		}
+	}
+	s.statesMu.RUnlock()
+
+	status.Devices = len(ids)
+	status.Pending = status.Devices - status.Polled
+	status.Progress = 1
+	if status.Devices > 0 {
+		status.Progress = float64(status.Polled) / float64(status.Devices)
+	}
+	if s.warmup > 0 && !status.StartedAt.IsZero() {
+		until := status.StartedAt.Add(s.warmup)
+		status.WarmupUntil = &until
+		status.WarmingUp = status.Pending > 0 && time.Now().Before(until)
+	}
+	return status
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"snmp-mqtt-bridge/internal/actor"
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+)
+
+// writeLockRefresh is how long the write lock is taken from memory before it
+// is read from the settings again, to follow changes by other instances
+const writeLockRefresh = 5 * time.Second
+
+// ErrWriteLockUnavailable is returned for a SET while the write lock cannot
+// be read, so it is unknown whether a change freeze holds
+var ErrWriteLockUnavailable = errors.New("write lock cannot be read")
+
+// WriteLockService switches the bridge-wide write lock for change freezes:
+// while locked, read-only mode holds back every SNMP SET and polling goes on
+type WriteLockService struct {
+	settings *SettingService
+	bus      *eventbus.Bus
+
+	mu       sync.Mutex
+	locked   bool
+	loadedAt time.Time // Zero until read from the settings
+}
+
+// NewWriteLockService creates a write lock kept in the settings
+func NewWriteLockService(settings *SettingService, bus *eventbus.Bus) *WriteLockService {
+	return &WriteLockService{settings: settings, bus: bus}
+}
+
+// Locked reports whether the write lock is held. The lock is kept in memory
+// and read from the settings every writeLockRefresh, outside the lock so a
+// slow database does not hold up other callers. While the settings cannot be
+// read, ErrWriteLockUnavailable is returned.
+func (s *WriteLockService) Locked(ctx context.Context) (bool, error) {
+	s.mu.Lock()
+	if !s.loadedAt.IsZero() && time.Since(s.loadedAt) < writeLockRefresh {
+		locked := s.locked
+		s.mu.Unlock()
+		return locked, nil
+	}
+	s.mu.Unlock()
+
+	readAt := time.Now()
+	value, err := s.settings.Get(ctx, domain.SettingReadOnly)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrWriteLockUnavailable, err)
+	}
+	locked, _ := strconv.ParseBool(value)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// A lock switched or read while this read was running is as recent
+	if s.loadedAt.After(readAt) {
+		return s.locked, nil
+	}
+	s.locked = locked
+	s.loadedAt = time.Now()
+	return locked, nil
+}
+
+// Status returns the write lock and its last change
+func (s *WriteLockService) Status(ctx context.Context) (*domain.WriteLock, error) {
+	all, err := s.settings.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &domain.WriteLock{}
+	for _, setting := range all {
+		switch setting.Key {
+		case domain.SettingReadOnly:
+			lock.Locked, _ = strconv.ParseBool(setting.Value)
+		case domain.SettingReadOnlyReason:
+			lock.Reason = setting.Value
+		case domain.SettingReadOnlyChangedBy:
+			lock.ChangedBy = setting.Value
+		case domain.SettingReadOnlyChangedAt:
+			if t, err := time.Parse(time.RFC3339, setting.Value); err == nil {
+				lock.ChangedAt = &t
+			}
+		}
+	}
+	return lock, nil
+}
+
+// Set takes or releases the write lock on behalf of the actor of ctx and
+// announces the change on the bus
+func (s *WriteLockService) Set(ctx context.Context, locked bool, reason string) (*domain.WriteLock, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	lock := &domain.WriteLock{
+		Locked:    locked,
+		Reason:    reason,
+		ChangedBy: actor.FromContext(ctx),
+		ChangedAt: &now,
+	}
+
+	// The lock itself is written last, so its details are in place once it holds
+	for _, setting := range []domain.Setting{
+		{Key: domain.SettingReadOnlyReason, Value: lock.Reason},
+		{Key: domain.SettingReadOnlyChangedBy, Value: lock.ChangedBy},
+		{Key: domain.SettingReadOnlyChangedAt, Value: now.Format(time.RFC3339)},
+		{Key: domain.SettingReadOnly, Value: strconv.FormatBool(locked)},
+	} {
+		if err := s.settings.Set(ctx, setting.Key, setting.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	s.locked = locked
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+
+	s.bus.Publish(eventbus.Event{Type: eventbus.TypeWriteLock, Payload: lock})
+	return lock, nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+	"snmp-mqtt-bridge/internal/repository"
+	"snmp-mqtt-bridge/internal/repository/memory"
+)
+
+// unreachableSettings fails every read while down is set
+type unreachableSettings struct {
+	repository.SettingRepository
+	down atomic.Bool
+}
+
+func (r *unreachableSettings) Get(ctx context.Context, key string) (string, error) {
+	if r.down.Load() {
+		return "", errors.New("database is unreachable")
+	}
+	return r.SettingRepository.Get(ctx, key)
+}
+
+func TestWriteLockUnreadableIsAnError(t *testing.T) {
+	repo := &unreachableSettings{SettingRepository: memory.NewSettingRepository()}
+	repo.down.Store(true)
+	lock := NewWriteLockService(NewSettingService(repo), eventbus.NewBus())
+
+	locked, err := lock.Locked(context.Background())
+	if !errors.Is(err, ErrWriteLockUnavailable) {
+		t.Fatalf("Locked = %v, %v; want ErrWriteLockUnavailable", locked, err)
+	}
+	if locked {
+		t.Error("an unreadable write lock reports held")
+	}
+}
+
+func TestWriteLockKeptInMemory(t *testing.T) {
+	repo := &unreachableSettings{SettingRepository: memory.NewSettingRepository()}
+	lock := NewWriteLockService(NewSettingService(repo), eventbus.NewBus())
+	ctx := context.Background()
+
+	if _, err := lock.Set(ctx, true, "change freeze"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	// Read from memory until the refresh is due
+	repo.down.Store(true)
+	if locked, err := lock.Locked(ctx); err != nil || !locked {
+		t.Errorf("Locked = %v, %v; want held from memory", locked, err)
+	}
+}
+
+func TestCommandQueueFailsWhileWriteLockUnreadable(t *testing.T) {
+	ctx := context.Background()
+	devices := memory.NewDeviceRepository()
+	device := &domain.Device{ID: "ups-1", Name: "UPS", IPAddress: "192.0.2.10", Port: 161, Community: "public", Enabled: true}
+	if err := devices.Create(ctx, device); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	bus := eventbus.NewBus()
+	commands := bus.Subscribe(eventbus.TypeCommand)
+	settings := &unreachableSettings{SettingRepository: memory.NewSettingRepository()}
+	settings.down.Store(true)
+
+	queue := NewCommandQueue(NewSNMPService(devices, memory.NewProfileRepository(), bus), nil)
+	queue.SetWriteLock(NewWriteLockService(NewSettingService(settings), bus))
+	defer queue.Stop()
+
+	err := queue.Send(ctx, device.ID, QueuedCommand{OID: ".1.3.6.1.4.1.318.1.1.1.7.2.2.0", Value: 2})
+	if !errors.Is(err, ErrWriteLockUnavailable) {
+		t.Fatalf("Send = %v, want ErrWriteLockUnavailable", err)
+	}
+
+	evt := <-commands.C
+	cmd, _ := evt.Payload.(eventbus.Command)
+	if cmd.Success || cmd.DryRun || cmd.Error == "" {
+		t.Errorf("command event = %+v, want a failure that is not a dry run", cmd)
+	}
+}
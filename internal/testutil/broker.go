@@ -0,0 +1,460 @@
+package testutil
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MQTT 3.1.1 control packet types handled by the broker
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetPuback      = 4
+	packetPubrec      = 5
+	packetPubrel      = 6
+	packetPubcomp     = 7
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetUnsubscribe = 10
+	packetUnsuback    = 11
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+)
+
+// Message is a PUBLISH seen by the broker
+type Message struct {
+	Topic    string
+	Payload  []byte
+	Retained bool
+}
+
+// Broker is a minimal in-process MQTT 3.1.1 broker for tests. It supports
+// QoS 0 delivery (QoS 1/2 publishes are acknowledged and delivered at QoS 0),
+// retained messages and + / # wildcards, and records every message it sees.
+type Broker struct {
+	listener net.Listener
+
+	clients  map[*brokerClient]struct{}
+	retained map[string]Message
+	messages []Message
+	mu       sync.Mutex
+	notify   chan struct{} // closed and replaced whenever a message arrives or a subscription is acknowledged
+
+	wg sync.WaitGroup
+}
+
+type brokerClient struct {
+	conn          net.Conn
+	subscriptions map[string]bool
+	writeMu       sync.Mutex
+}
+
+// NewBroker creates a new broker
+func NewBroker() *Broker {
+	return &Broker{
+		clients:  make(map[*brokerClient]struct{}),
+		retained: make(map[string]Message),
+		notify:   make(chan struct{}),
+	}
+}
+
+// Start listens on a random localhost TCP port
+func (b *Broker) Start() error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	b.listener = listener
+
+	b.wg.Add(1)
+	go b.accept()
+	return nil
+}
+
+// Stop closes the listener and all client connections
+func (b *Broker) Stop() {
+	if b.listener != nil {
+		b.listener.Close()
+	}
+
+	b.mu.Lock()
+	for c := range b.clients {
+		c.conn.Close()
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
+
+// Port returns the TCP port the broker listens on
+func (b *Broker) Port() int {
+	return b.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Publish delivers a message to subscribers as if a client had sent it,
+// e.g. to simulate a Home Assistant command
+func (b *Broker) Publish(topic string, payload []byte, retain bool) {
+	b.route(Message{Topic: topic, Payload: payload, Retained: retain})
+}
+
+// Messages returns every message published so far whose topic matches filter
+func (b *Broker) Messages(filter string) []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var matched []Message
+	for _, m := range b.messages {
+		if topicMatches(filter, m.Topic) {
+			matched = append(matched, m)
+		}
+	}
+	return matched
+}
+
+// Retained returns the retained message on a topic
+func (b *Broker) Retained(topic string) (Message, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	m, ok := b.retained[topic]
+	return m, ok
+}
+
+// WaitForMessage waits until a message matching filter has been published
+// (including earlier ones) and returns the latest such message
+func (b *Broker) WaitForMessage(filter string, timeout time.Duration) (Message, error) {
+	deadline := time.After(timeout)
+	for {
+		b.mu.Lock()
+		notify := b.notify
+		for i := len(b.messages) - 1; i >= 0; i-- {
+			if topicMatches(filter, b.messages[i].Topic) {
+				m := b.messages[i]
+				b.mu.Unlock()
+				return m, nil
+			}
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-deadline:
+			return Message{}, fmt.Errorf("no message on %s within %s", filter, timeout)
+		}
+	}
+}
+
+// WaitForSubscription waits until a client has an acknowledged subscription
+// whose filter matches topic, e.g. before publishing a command to it
+func (b *Broker) WaitForSubscription(topic string, timeout time.Duration) error {
+	deadline := time.After(timeout)
+	for {
+		b.mu.Lock()
+		notify := b.notify
+		for c := range b.clients {
+			for f := range c.subscriptions {
+				if topicMatches(f, topic) {
+					b.mu.Unlock()
+					return nil
+				}
+			}
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-deadline:
+			return fmt.Errorf("no subscription for %s within %s", topic, timeout)
+		}
+	}
+}
+
+// wake releases everyone waiting on the broker; b.mu must be held
+func (b *Broker) wake() {
+	close(b.notify)
+	b.notify = make(chan struct{})
+}
+
+func (b *Broker) accept() {
+	defer b.wg.Done()
+
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		client := &brokerClient{conn: conn, subscriptions: make(map[string]bool)}
+		b.mu.Lock()
+		b.clients[client] = struct{}{}
+		b.mu.Unlock()
+
+		b.wg.Add(1)
+		go b.serve(client)
+	}
+}
+
+func (b *Broker) serve(c *brokerClient) {
+	defer b.wg.Done()
+	defer func() {
+		b.mu.Lock()
+		delete(b.clients, c)
+		b.mu.Unlock()
+		c.conn.Close()
+	}()
+
+	r := bufio.NewReader(c.conn)
+	for {
+		header, body, err := readPacket(r)
+		if err != nil {
+			return
+		}
+
+		switch header >> 4 {
+		case packetConnect:
+			// Session present = 0, return code = accepted
+			c.write(packetConnack<<4, []byte{0, 0})
+
+		case packetPublish:
+			msg, qos, packetID, err := parsePublish(header, body)
+			if err != nil {
+				return
+			}
+			switch qos {
+			case 1:
+				c.write(packetPuback<<4, packetID)
+			case 2:
+				c.write(packetPubrec<<4, packetID)
+			}
+			b.route(msg)
+
+		case packetPubrel:
+			if len(body) < 2 {
+				return
+			}
+			c.write(packetPubcomp<<4, body[:2])
+
+		case packetSubscribe:
+			if len(body) < 2 {
+				return
+			}
+			var filters []string
+			granted := []byte{body[0], body[1]}
+			for rest := body[2:]; len(rest) > 0; {
+				filter, n, err := readString(rest)
+				if err != nil || len(rest) < n+1 {
+					return
+				}
+				rest = rest[n+1:]
+				filters = append(filters, filter)
+				granted = append(granted, 0)
+			}
+
+			// Subscriptions count once acknowledged, so WaitForSubscription
+			// only returns when the client is ready for messages
+			c.write(packetSuback<<4, granted)
+			b.mu.Lock()
+			for _, f := range filters {
+				c.subscriptions[f] = true
+			}
+			b.wake()
+
+			// Deliver retained messages for the new subscriptions
+			var pending []Message
+			for _, m := range b.retained {
+				for _, f := range filters {
+					if topicMatches(f, m.Topic) {
+						pending = append(pending, m)
+						break
+					}
+				}
+			}
+			b.mu.Unlock()
+			for _, m := range pending {
+				c.deliver(m, true)
+			}
+
+		case packetUnsubscribe:
+			if len(body) < 2 {
+				return
+			}
+			b.mu.Lock()
+			for rest := body[2:]; len(rest) > 0; {
+				filter, n, err := readString(rest)
+				if err != nil {
+					break
+				}
+				delete(c.subscriptions, filter)
+				rest = rest[n:]
+			}
+			b.mu.Unlock()
+			c.write(packetUnsuback<<4, body[:2])
+
+		case packetPingreq:
+			c.write(packetPingresp<<4, nil)
+
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+// route records a message, updates retained state and fans it out
+func (b *Broker) route(msg Message) {
+	b.mu.Lock()
+	b.messages = append(b.messages, msg)
+	if msg.Retained {
+		if len(msg.Payload) == 0 {
+			delete(b.retained, msg.Topic)
+		} else {
+			b.retained[msg.Topic] = msg
+		}
+	}
+	b.wake()
+
+	var targets []*brokerClient
+	for c := range b.clients {
+		for f := range c.subscriptions {
+			if topicMatches(f, msg.Topic) {
+				targets = append(targets, c)
+				break
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	for _, c := range targets {
+		c.deliver(msg, false)
+	}
+}
+
+func (c *brokerClient) deliver(msg Message, retained bool) {
+	header := byte(packetPublish << 4)
+	if retained {
+		header |= 0x01
+	}
+	body := appendString(nil, msg.Topic)
+	body = append(body, msg.Payload...)
+	c.write(header, body)
+}
+
+func (c *brokerClient) write(header byte, body []byte) {
+	packet := []byte{header}
+	packet = appendLength(packet, len(body))
+	packet = append(packet, body...)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.Write(packet)
+}
+
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7f) * multiplier
+		multiplier *= 128
+		if digit&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+func parsePublish(header byte, body []byte) (Message, byte, []byte, error) {
+	qos := (header >> 1) & 0x03
+	topic, n, err := readString(body)
+	if err != nil {
+		return Message{}, 0, nil, err
+	}
+	rest := body[n:]
+
+	var packetID []byte
+	if qos > 0 {
+		if len(rest) < 2 {
+			return Message{}, 0, nil, errors.New("missing packet identifier")
+		}
+		packetID = rest[:2]
+		rest = rest[2:]
+	}
+
+	msg := Message{
+		Topic:    topic,
+		Payload:  append([]byte(nil), rest...),
+		Retained: header&0x01 != 0,
+	}
+	return msg, qos, packetID, nil
+}
+
+// readString reads a length-prefixed UTF-8 string and returns the bytes consumed
+func readString(data []byte) (string, int, error) {
+	if len(data) < 2 {
+		return "", 0, errors.New("short string")
+	}
+	n := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+n {
+		return "", 0, errors.New("short string")
+	}
+	return string(data[2 : 2+n]), 2 + n, nil
+}
+
+func appendString(dst []byte, s string) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, uint16(len(s)))
+	return append(dst, s...)
+}
+
+func appendLength(dst []byte, length int) []byte {
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		dst = append(dst, digit)
+		if length == 0 {
+			return dst
+		}
+	}
+}
+
+// topicMatches reports whether a topic matches a subscription filter with + and # wildcards
+func topicMatches(filter, topic string) bool {
+	f := strings.Split(filter, "/")
+	t := strings.Split(topic, "/")
+	for i, level := range f {
+		if level == "#" {
+			return true
+		}
+		if i >= len(t) {
+			return false
+		}
+		if level != "+" && level != t[i] {
+			return false
+		}
+	}
+	return len(f) == len(t)
+}
@@ -0,0 +1,203 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"snmp-mqtt-bridge/internal/config"
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+	"snmp-mqtt-bridge/internal/mqtt"
+	"snmp-mqtt-bridge/internal/repository/sqlite"
+	"snmp-mqtt-bridge/internal/service"
+)
+
+// Topic prefixes used by the pipeline's MQTT client
+const (
+	TopicPrefix     = "snmp-bridge"
+	DiscoveryPrefix = "homeassistant"
+)
+
+// Pipeline wires the real bridge services against an in-process broker,
+// an SNMP simulator and a throwaway SQLite database, started in the same
+// order as cmd/snmp-bridge.
+type Pipeline struct {
+	Broker *Broker
+	Agent  *SNMPSimulator
+	Bus    *eventbus.Bus
+
+	Devices  *service.DeviceService
+	Profiles *service.ProfileService
+	Poller   *service.PollerService
+	SNMP     *service.SNMPService
+	Queue    *service.CommandQueue
+
+	MQTT      *mqtt.Client
+	Publisher *mqtt.Publisher
+
+	dir string
+}
+
+// NewPipeline starts a broker, a simulator and the bridge services.
+// Devices are polled every pollInterval.
+func NewPipeline(pollInterval time.Duration) (*Pipeline, error) {
+	p := &Pipeline{Broker: NewBroker(), Agent: NewSNMPSimulator()}
+
+	if err := p.Broker.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start broker: %w", err)
+	}
+	if err := p.Agent.Start(); err != nil {
+		p.Broker.Stop()
+		return nil, fmt.Errorf("failed to start SNMP simulator: %w", err)
+	}
+
+	if err := p.start(pollInterval); err != nil {
+		p.Stop()
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Pipeline) start(pollInterval time.Duration) error {
+	dir, err := os.MkdirTemp("", "snmp-bridge-test-")
+	if err != nil {
+		return err
+	}
+	p.dir = dir
+
+	db, err := sqlite.NewDB(&config.DatabaseConfig{Driver: "sqlite", DSN: filepath.Join(dir, "bridge.db")})
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	deviceRepo := sqlite.NewDeviceRepository(db)
+	profileRepo := sqlite.NewProfileRepository(db)
+
+	p.Bus = eventbus.NewBus()
+	p.Devices = service.NewDeviceService(deviceRepo, sqlite.NewCredentialRepository(db), p.Bus)
+	p.Profiles = service.NewProfileService(profileRepo, p.Devices, p.Bus)
+
+	ctx := context.Background()
+	if err := p.Profiles.LoadBuiltinProfiles(ctx, profilesDir()); err != nil {
+		return err
+	}
+
+	p.Poller = service.NewPollerService(deviceRepo, profileRepo, p.Bus, pollInterval)
+	p.SNMP = service.NewSNMPService(deviceRepo, profileRepo, p.Bus)
+	p.Queue = service.NewCommandQueue(p.SNMP, p.Poller)
+
+	mqttConfig := &config.MQTTConfig{
+		Broker:          "127.0.0.1",
+		Port:            p.Broker.Port(),
+		ClientID:        fmt.Sprintf("snmp-bridge-test-%d", time.Now().UnixNano()),
+		TopicPrefix:     TopicPrefix,
+		Discovery:       true,
+		DiscoveryPrefix: DiscoveryPrefix,
+	}
+	p.MQTT = mqtt.NewClient(mqttConfig)
+	p.MQTT.SetEventBus(p.Bus)
+	if err := p.MQTT.Connect(); err != nil {
+		return err
+	}
+
+	discovery := mqtt.NewDiscovery(p.MQTT, DiscoveryPrefix, TopicPrefix)
+	p.Publisher = mqtt.NewPublisher(p.MQTT, discovery, p.Poller, p.Queue, profileRepo, p.Bus)
+
+	if err := p.Poller.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start poller: %w", err)
+	}
+	return p.Publisher.Start()
+}
+
+// Stop shuts everything down in reverse order and removes the database
+func (p *Pipeline) Stop() {
+	if p.Publisher != nil {
+		p.Publisher.Stop()
+	}
+	if p.Queue != nil {
+		p.Queue.Stop()
+	}
+	if p.Poller != nil {
+		p.Poller.Stop()
+	}
+	if p.Bus != nil {
+		p.Bus.Close()
+	}
+	if p.MQTT != nil {
+		p.MQTT.Disconnect()
+	}
+	p.Agent.Stop()
+	p.Broker.Stop()
+	if p.dir != "" {
+		os.RemoveAll(p.dir)
+	}
+}
+
+// AddDevice seeds the simulator with the profile's OIDs and creates an
+// enabled device pointing at it
+func (p *Pipeline) AddDevice(ctx context.Context, name, profileID string) (*domain.Device, error) {
+	profile, err := p.Profiles.GetByID(ctx, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %w", profileID, err)
+	}
+	p.Agent.LoadProfile(profile)
+
+	version := domain.SNMPVersion("v2c")
+	if len(profile.SNMPVersions) > 0 && profile.SNMPVersions[0] != "" {
+		version = domain.SNMPVersion(profile.SNMPVersions[0])
+	}
+
+	return p.Devices.Create(ctx, &domain.DeviceCreateRequest{
+		Name:        name,
+		IPAddress:   "127.0.0.1",
+		Port:        p.Agent.Port(),
+		Community:   "public",
+		SNMPVersion: version,
+		ProfileID:   profileID,
+		Enabled:     true,
+	})
+}
+
+// WaitForState waits until a polled value is available for a device entity
+func (p *Pipeline) WaitForState(deviceID, entity string, timeout time.Duration) (interface{}, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if value, ok := p.Poller.GetDeviceValues(deviceID)[entity]; ok {
+			return value, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no value for %s on device %s within %s", entity, deviceID, timeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// StateTopic returns the topic a device's state is published on
+func StateTopic(deviceID string) string {
+	return fmt.Sprintf("%s/%s/state", TopicPrefix, deviceID)
+}
+
+// EntityStateTopic returns the topic a single entity's state is published on
+func EntityStateTopic(deviceID, entityID string) string {
+	return fmt.Sprintf("%s/%s/%s/state", TopicPrefix, deviceID, entityID)
+}
+
+// CommandTopic returns the topic Home Assistant sends an entity's commands to
+func CommandTopic(deviceID, entityID string) string {
+	return fmt.Sprintf("%s/%s/%s/set", TopicPrefix, deviceID, entityID)
+}
+
+// DiscoveryTopic returns the topic of an entity's Home Assistant discovery config
+func DiscoveryTopic(component, deviceID, entityID string) string {
+	return fmt.Sprintf("%s/%s/%s/%s/config", DiscoveryPrefix, component, deviceID, entityID)
+}
+
+// profilesDir locates the built-in profiles at the repository root
+func profilesDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "profiles")
+}
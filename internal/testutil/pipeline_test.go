@@ -0,0 +1,118 @@
+package testutil_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"snmp-mqtt-bridge/internal/testutil"
+)
+
+const (
+	pduProfile    = "apc-pdu-ap7921"
+	outletEntity  = "outlet_1_state"
+	outletSetOID  = ".1.3.6.1.4.1.318.1.1.12.3.3.1.1.4.1"
+	outletOffCode = 2 // immediateOff
+	waitTimeout   = 5 * time.Second
+)
+
+func startPipeline(t *testing.T) *testutil.Pipeline {
+	t.Helper()
+	p, err := testutil.NewPipeline(200 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewPipeline: %v", err)
+	}
+	t.Cleanup(p.Stop)
+	return p
+}
+
+func TestPipelinePublishesDiscoveryAndState(t *testing.T) {
+	p := startPipeline(t)
+
+	device, err := p.AddDevice(context.Background(), "Rack PDU", pduProfile)
+	if err != nil {
+		t.Fatalf("AddDevice: %v", err)
+	}
+
+	msg, err := p.Broker.WaitForMessage(testutil.DiscoveryTopic("switch", device.ID, outletEntity), waitTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !msg.Retained {
+		t.Errorf("discovery config is not retained")
+	}
+	var config struct {
+		Name         string `json:"name"`
+		StateTopic   string `json:"state_topic"`
+		CommandTopic string `json:"command_topic"`
+	}
+	if err := json.Unmarshal(msg.Payload, &config); err != nil {
+		t.Fatalf("discovery config: %v", err)
+	}
+	if config.Name != "Outlet 1 State" {
+		t.Errorf("name = %q, want %q", config.Name, "Outlet 1 State")
+	}
+	if want := testutil.EntityStateTopic(device.ID, outletEntity); config.StateTopic != want {
+		t.Errorf("state_topic = %q, want %q", config.StateTopic, want)
+	}
+	if want := testutil.CommandTopic(device.ID, outletEntity); config.CommandTopic != want {
+		t.Errorf("command_topic = %q, want %q", config.CommandTopic, want)
+	}
+
+	value, err := p.WaitForState(device.ID, "Outlet 1 State", waitTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "On" {
+		t.Errorf("polled value = %v, want On", value)
+	}
+
+	msg, err = p.Broker.WaitForMessage(testutil.EntityStateTopic(device.ID, outletEntity), waitTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Payload) != "ON" {
+		t.Errorf("entity state = %q, want ON", msg.Payload)
+	}
+}
+
+func TestPipelineCommandSetsDevice(t *testing.T) {
+	p := startPipeline(t)
+
+	device, err := p.AddDevice(context.Background(), "Rack PDU", pduProfile)
+	if err != nil {
+		t.Fatalf("AddDevice: %v", err)
+	}
+	if _, err := p.Broker.WaitForMessage(testutil.EntityStateTopic(device.ID, outletEntity), waitTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	// The command subscription is made while the device registers
+	commandTopic := testutil.CommandTopic(device.ID, outletEntity)
+	if err := p.Broker.WaitForSubscription(commandTopic, waitTimeout); err != nil {
+		t.Fatal(err)
+	}
+	p.Broker.Publish(commandTopic, []byte("OFF"), false)
+
+	deadline := time.Now().Add(waitTimeout)
+	for len(p.Agent.Sets()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	sets := p.Agent.Sets()
+	if len(sets) != 1 {
+		t.Fatalf("received %d SETs within %s, want 1", len(sets), waitTimeout)
+	}
+	set := sets[0]
+	if set.OID != outletSetOID {
+		t.Errorf("SET OID = %s, want %s", set.OID, outletSetOID)
+	}
+	if fmt.Sprint(set.Value) != fmt.Sprint(outletOffCode) {
+		t.Errorf("SET value = %v, want %d", set.Value, outletOffCode)
+	}
+	if value, _ := p.Agent.Get(outletSetOID); fmt.Sprint(value) != fmt.Sprint(outletOffCode) {
+		t.Errorf("simulator holds %v after the SET, want %d", value, outletOffCode)
+	}
+}
@@ -0,0 +1,397 @@
+// Package testutil provides in-process stand-ins for the bridge's external
+// dependencies (an MQTT broker and SNMP devices) so the full pipeline can be
+// exercised in tests without Docker or real hardware.
+package testutil
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"snmp-mqtt-bridge/internal/domain"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// SetRecord is an SNMP SET received by the simulator
+type SetRecord struct {
+	OID       string
+	Type      gosnmp.Asn1BER
+	Value     interface{}
+	Community string
+}
+
+// SNMPSimulator is a UDP SNMP v1/v2c agent serving a mutable OID table.
+// It answers GET, GETNEXT, GETBULK and SET; SETs update the table and are
+// recorded for assertions. Any community is accepted.
+type SNMPSimulator struct {
+	conn    *net.UDPConn
+	decoder *gosnmp.GoSNMP
+
+	values map[string]gosnmp.SnmpPDU
+	sets   []SetRecord
+	mu     sync.Mutex
+
+	wg sync.WaitGroup
+}
+
+// NewSNMPSimulator creates a simulator with an empty OID table
+func NewSNMPSimulator() *SNMPSimulator {
+	return &SNMPSimulator{
+		decoder: &gosnmp.GoSNMP{Version: gosnmp.Version2c, MaxOids: gosnmp.MaxOids},
+		values:  make(map[string]gosnmp.SnmpPDU),
+	}
+}
+
+// Start listens on a random localhost UDP port
+func (s *SNMPSimulator) Start() error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+
+	s.wg.Add(1)
+	go s.serve()
+	return nil
+}
+
+// Stop closes the listener
+func (s *SNMPSimulator) Stop() {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.wg.Wait()
+}
+
+// Port returns the UDP port the simulator listens on
+func (s *SNMPSimulator) Port() int {
+	return s.conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// Set stores a value; ints become INTEGER and strings OCTET STRING
+func (s *SNMPSimulator) Set(oid string, value interface{}) {
+	pdu := gosnmp.SnmpPDU{Name: normalizeOID(oid), Value: value}
+	switch v := value.(type) {
+	case int:
+		pdu.Type = gosnmp.Integer
+	case uint32:
+		pdu.Type = gosnmp.Gauge32
+	case string:
+		pdu.Type = gosnmp.OctetString
+		pdu.Value = []byte(v)
+	default:
+		pdu.Type = gosnmp.OctetString
+		pdu.Value = []byte(fmt.Sprintf("%v", v))
+	}
+	s.SetPDU(pdu)
+}
+
+// SetPDU stores a value with an explicit type
+func (s *SNMPSimulator) SetPDU(pdu gosnmp.SnmpPDU) {
+	pdu.Name = normalizeOID(pdu.Name)
+	s.mu.Lock()
+	s.values[pdu.Name] = pdu
+	s.mu.Unlock()
+}
+
+// Get returns the current value of an OID
+func (s *SNMPSimulator) Get(oid string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pdu, ok := s.values[normalizeOID(oid)]
+	if !ok {
+		return nil, false
+	}
+	if b, isBytes := pdu.Value.([]byte); isBytes {
+		return string(b), true
+	}
+	return pdu.Value, true
+}
+
+// LoadProfile seeds a plausible value for every OID a profile polls:
+// the first enum value for enums, 1 for numbers and the mapping name for strings
+func (s *SNMPSimulator) LoadProfile(profile *domain.Profile) {
+	for _, m := range profile.OIDMappings {
+		if m.OID == "" || m.Computed {
+			continue
+		}
+		switch m.Type {
+		case domain.OIDTypeString:
+			s.Set(m.OID, m.Name)
+		case domain.OIDTypeCompositeSwitch:
+			s.Set(m.OID, "1,-1,-1,-1,-1,-1,-1,-1")
+		case domain.OIDTypeEnum:
+			keys := make([]int, 0, len(m.EnumValues))
+			for k := range m.EnumValues {
+				keys = append(keys, k)
+			}
+			sort.Ints(keys)
+			value := 1
+			if len(keys) > 0 {
+				value = keys[0]
+			}
+			s.Set(m.OID, value)
+		default:
+			s.Set(m.OID, 1)
+		}
+	}
+}
+
+// LoadSnapshotFile loads a snapshot downloaded from
+// GET /api/devices/:id/snapshot, see LoadSnapshot
+func (s *SNMPSimulator) LoadSnapshotFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var snapshot domain.SNMPSnapshot
+	decoder := json.NewDecoder(f)
+	decoder.UseNumber()
+	if err := decoder.Decode(&snapshot); err != nil {
+		return fmt.Errorf("failed to parse snapshot %s: %w", path, err)
+	}
+	return s.LoadSnapshot(&snapshot)
+}
+
+// LoadSnapshot seeds the OID table with the objects of a device snapshot,
+// keeping their SNMP types, to reproduce a device from a bug report
+func (s *SNMPSimulator) LoadSnapshot(snapshot *domain.SNMPSnapshot) error {
+	for _, entry := range snapshot.Entries {
+		pdu, err := snapshotPDU(entry)
+		if err != nil {
+			return fmt.Errorf("snapshot entry %s: %w", entry.OID, err)
+		}
+		s.SetPDU(pdu)
+	}
+	return nil
+}
+
+// snapshotTypes maps the SNMP type names used in snapshots to their types
+var snapshotTypes = map[string]gosnmp.Asn1BER{
+	gosnmp.Integer.String():          gosnmp.Integer,
+	gosnmp.OctetString.String():      gosnmp.OctetString,
+	gosnmp.ObjectIdentifier.String(): gosnmp.ObjectIdentifier,
+	gosnmp.IPAddress.String():        gosnmp.IPAddress,
+	gosnmp.Counter32.String():        gosnmp.Counter32,
+	gosnmp.Gauge32.String():          gosnmp.Gauge32,
+	gosnmp.TimeTicks.String():        gosnmp.TimeTicks,
+	gosnmp.Opaque.String():           gosnmp.Opaque,
+	gosnmp.Counter64.String():        gosnmp.Counter64,
+	gosnmp.Uinteger32.String():       gosnmp.Uinteger32,
+	gosnmp.OpaqueFloat.String():      gosnmp.OpaqueFloat,
+	gosnmp.OpaqueDouble.String():     gosnmp.OpaqueDouble,
+}
+
+// snapshotPDU converts a snapshot entry back into the PDU a device returned.
+// Numbers may be decoded from JSON as float64 or json.Number.
+func snapshotPDU(entry domain.SnapshotEntry) (gosnmp.SnmpPDU, error) {
+	pdu := gosnmp.SnmpPDU{Name: entry.OID}
+	typ, ok := snapshotTypes[entry.Type]
+	if !ok {
+		return pdu, fmt.Errorf("unsupported type %q", entry.Type)
+	}
+	pdu.Type = typ
+
+	if entry.Hex {
+		text, _ := entry.Value.(string)
+		b, err := hex.DecodeString(text)
+		if err != nil {
+			return pdu, fmt.Errorf("invalid hex value: %w", err)
+		}
+		pdu.Value = b
+		if typ == gosnmp.OpaqueFloat || typ == gosnmp.OpaqueDouble {
+			pdu.Type = gosnmp.Opaque
+		}
+		return pdu, nil
+	}
+
+	switch typ {
+	case gosnmp.OctetString, gosnmp.Opaque:
+		pdu.Value = []byte(fmt.Sprint(entry.Value))
+	case gosnmp.ObjectIdentifier, gosnmp.IPAddress:
+		pdu.Value = fmt.Sprint(entry.Value)
+	default:
+		text := fmt.Sprint(entry.Value)
+		if f, isFloat := entry.Value.(float64); isFloat {
+			text = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		var err error
+		switch typ {
+		case gosnmp.Integer:
+			pdu.Value, err = strconv.Atoi(text)
+		case gosnmp.Counter64:
+			pdu.Value, err = strconv.ParseUint(text, 10, 64)
+		case gosnmp.OpaqueFloat:
+			var f float64
+			f, err = strconv.ParseFloat(text, 32)
+			pdu.Value = float32(f)
+		case gosnmp.OpaqueDouble:
+			pdu.Value, err = strconv.ParseFloat(text, 64)
+		default:
+			var u uint64
+			u, err = strconv.ParseUint(text, 10, 32)
+			pdu.Value = uint32(u)
+		}
+		if err != nil {
+			return pdu, fmt.Errorf("invalid %s value %v", entry.Type, entry.Value)
+		}
+	}
+	return pdu, nil
+}
+
+// Sets returns every SET received so far
+func (s *SNMPSimulator) Sets() []SetRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SetRecord(nil), s.sets...)
+}
+
+func (s *SNMPSimulator) serve() {
+	defer s.wg.Done()
+
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		response, err := s.handle(buf[:n])
+		if err != nil || response == nil {
+			continue
+		}
+		s.conn.WriteToUDP(response, addr)
+	}
+}
+
+func (s *SNMPSimulator) handle(data []byte) ([]byte, error) {
+	request, err := s.decoder.SnmpDecodePacket(data)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &gosnmp.SnmpPacket{
+		Version:   request.Version,
+		Community: request.Community,
+		PDUType:   gosnmp.GetResponse,
+		RequestID: request.RequestID,
+		Logger:    gosnmp.NewLogger(nil),
+	}
+	v1 := request.Version == gosnmp.Version1
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch request.PDUType {
+	case gosnmp.GetRequest:
+		for i, v := range request.Variables {
+			pdu, ok := s.values[normalizeOID(v.Name)]
+			if !ok {
+				if v1 {
+					return errorResponse(response, request, gosnmp.NoSuchName, i)
+				}
+				pdu = gosnmp.SnmpPDU{Name: v.Name, Type: gosnmp.NoSuchObject}
+			}
+			response.Variables = append(response.Variables, pdu)
+		}
+
+	case gosnmp.GetNextRequest, gosnmp.GetBulkRequest:
+		repetitions := 1
+		if request.PDUType == gosnmp.GetBulkRequest && request.MaxRepetitions > 0 {
+			repetitions = int(request.MaxRepetitions)
+		}
+		sorted := s.sortedOIDs()
+		for _, v := range request.Variables {
+			cursor := v.Name
+			for r := 0; r < repetitions; r++ {
+				pdu, ok := s.next(sorted, cursor)
+				if !ok {
+					response.Variables = append(response.Variables, gosnmp.SnmpPDU{Name: cursor, Type: gosnmp.EndOfMibView})
+					break
+				}
+				response.Variables = append(response.Variables, pdu)
+				cursor = pdu.Name
+			}
+		}
+
+	case gosnmp.SetRequest:
+		for _, v := range request.Variables {
+			name := normalizeOID(v.Name)
+			s.values[name] = gosnmp.SnmpPDU{Name: name, Type: v.Type, Value: v.Value}
+
+			value := v.Value
+			if b, ok := value.([]byte); ok {
+				value = string(b)
+			}
+			s.sets = append(s.sets, SetRecord{OID: name, Type: v.Type, Value: value, Community: request.Community})
+			response.Variables = append(response.Variables, v)
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported PDU type %v", request.PDUType)
+	}
+
+	return response.MarshalMsg()
+}
+
+func (s *SNMPSimulator) sortedOIDs() []string {
+	oids := make([]string, 0, len(s.values))
+	for oid := range s.values {
+		oids = append(oids, oid)
+	}
+	sort.Slice(oids, func(i, j int) bool { return compareOID(oids[i], oids[j]) < 0 })
+	return oids
+}
+
+func (s *SNMPSimulator) next(sorted []string, oid string) (gosnmp.SnmpPDU, bool) {
+	oid = normalizeOID(oid)
+	i := sort.Search(len(sorted), func(i int) bool { return compareOID(sorted[i], oid) > 0 })
+	if i < len(sorted) {
+		return s.values[sorted[i]], true
+	}
+	return gosnmp.SnmpPDU{}, false
+}
+
+func errorResponse(response, request *gosnmp.SnmpPacket, status gosnmp.SNMPError, index int) ([]byte, error) {
+	response.Error = status
+	response.ErrorIndex = uint8(index + 1)
+	response.Variables = make([]gosnmp.SnmpPDU, 0, len(request.Variables))
+	for _, v := range request.Variables {
+		response.Variables = append(response.Variables, gosnmp.SnmpPDU{Name: v.Name, Type: gosnmp.Null})
+	}
+	return response.MarshalMsg()
+}
+
+func normalizeOID(oid string) string {
+	return "." + strings.TrimPrefix(strings.TrimSpace(oid), ".")
+}
+
+// compareOID orders dotted OIDs numerically, arc by arc
+func compareOID(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "."), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "."), ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, _ := strconv.Atoi(pa[i])
+		nb, _ := strconv.Atoi(pb[i])
+		if na != nb {
+			if na < nb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(pa) - len(pb)
+}
@@ -0,0 +1,86 @@
+// Package timefmt normalizes timestamps in JSON payloads (MQTT, WebSocket and
+// API responses) to one configured timezone and RFC3339 layout.
+package timefmt
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Supported timestamp formats
+const (
+	FormatRFC3339      = "rfc3339"     // 2006-01-02T15:04:05Z
+	FormatRFC3339Milli = "rfc3339ms"   // 2006-01-02T15:04:05.000Z
+	FormatRFC3339Nano  = "rfc3339nano" // Go's default, trailing zeros trimmed
+)
+
+var layouts = map[string]string{
+	FormatRFC3339:      time.RFC3339,
+	FormatRFC3339Milli: "2006-01-02T15:04:05.000Z07:00",
+	FormatRFC3339Nano:  time.RFC3339Nano,
+}
+
+// timestampLiteral matches a JSON string holding an RFC3339 timestamp, as
+// produced by time.Time's MarshalJSON
+var timestampLiteral = regexp.MustCompile(`"\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})"`)
+
+var (
+	mu       sync.RWMutex
+	location = time.UTC
+	layout   = time.RFC3339
+)
+
+// Configure sets the timezone (IANA name, "UTC" or "Local") and format used
+// for all serialized timestamps. Empty values keep UTC and RFC3339.
+func Configure(timezone, format string) error {
+	loc := time.UTC
+	if timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q: %w", timezone, err)
+		}
+	}
+
+	l := time.RFC3339
+	if format != "" {
+		var ok bool
+		if l, ok = layouts[format]; !ok {
+			return fmt.Errorf("invalid timestamp format %q (use %s, %s or %s)", format, FormatRFC3339, FormatRFC3339Milli, FormatRFC3339Nano)
+		}
+	}
+
+	mu.Lock()
+	location, layout = loc, l
+	mu.Unlock()
+	return nil
+}
+
+// Format formats a timestamp in the configured timezone and layout
+func Format(t time.Time) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return t.In(location).Format(layout)
+}
+
+// Marshal is json.Marshal with timestamps normalized
+func Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return Normalize(data), nil
+}
+
+// Normalize rewrites every RFC3339 timestamp string in a JSON document
+func Normalize(data []byte) []byte {
+	return timestampLiteral.ReplaceAllFunc(data, func(literal []byte) []byte {
+		t, err := time.Parse(time.RFC3339Nano, string(literal[1:len(literal)-1]))
+		if err != nil {
+			return literal
+		}
+		return []byte(`"` + Format(t) + `"`)
+	})
+}
@@ -0,0 +1,171 @@
+// Package units converts polled values between units of the same quantity
+// (W and kW, minutes and seconds, °C and °F) and holds the configured
+// temperature unit preference.
+package units
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// linearUnits maps each unit to its quantity and its factor to the quantity's base unit
+var linearUnits = map[string]struct {
+	quantity string
+	factor   float64
+}{
+	"mW":   {"power", 0.001},
+	"W":    {"power", 1},
+	"kW":   {"power", 1000},
+	"MW":   {"power", 1e6},
+	"VA":   {"apparent_power", 1},
+	"kVA":  {"apparent_power", 1000},
+	"var":  {"reactive_power", 1},
+	"kvar": {"reactive_power", 1000},
+	"Wh":   {"energy", 1},
+	"kWh":  {"energy", 1000},
+	"MWh":  {"energy", 1e6},
+	"mA":   {"current", 0.001},
+	"cA":   {"current", 0.01},
+	"dA":   {"current", 0.1},
+	"A":    {"current", 1},
+	"mV":   {"voltage", 0.001},
+	"dV":   {"voltage", 0.1},
+	"V":    {"voltage", 1},
+	"kV":   {"voltage", 1000},
+	"Hz":   {"frequency", 1},
+	"kHz":  {"frequency", 1000},
+	"ms":   {"duration", 0.001},
+	"cs":   {"duration", 0.01},
+	"s":    {"duration", 1},
+	"min":  {"duration", 60},
+	"h":    {"duration", 3600},
+	"d":    {"duration", 86400},
+}
+
+// Temperature units
+const (
+	Celsius    = "°C"
+	Fahrenheit = "°F"
+	Kelvin     = "K"
+)
+
+// aliases are alternative spellings accepted in profiles and configuration
+var aliases = map[string]string{
+	"C":          Celsius,
+	"F":          Fahrenheit,
+	"degC":       Celsius,
+	"degF":       Fahrenheit,
+	"celsius":    Celsius,
+	"fahrenheit": Fahrenheit,
+	"sec":        "s",
+	"minutes":    "min",
+	"hours":      "h",
+	"days":       "d",
+}
+
+var (
+	mu          sync.RWMutex
+	temperature string
+)
+
+// Configure sets the unit temperatures are published in ("C" or "F").
+// Empty publishes temperatures in the unit the profile declares.
+func Configure(temperatureUnit string) error {
+	unit := ""
+	if temperatureUnit != "" {
+		unit = Normalize(temperatureUnit)
+		if unit != Celsius && unit != Fahrenheit {
+			return fmt.Errorf("invalid temperature unit %q (use C or F)", temperatureUnit)
+		}
+	}
+
+	mu.Lock()
+	temperature = unit
+	mu.Unlock()
+	return nil
+}
+
+// Temperature returns the unit temperatures are published in, empty when
+// each profile's unit is kept
+func Temperature() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return temperature
+}
+
+// Normalize returns the canonical spelling of a unit
+func Normalize(unit string) string {
+	unit = strings.TrimSpace(unit)
+	if canonical, ok := aliases[unit]; ok {
+		return canonical
+	}
+	if canonical, ok := aliases[strings.ToLower(unit)]; ok {
+		return canonical
+	}
+	return unit
+}
+
+// IsTemperature reports whether unit is a temperature unit
+func IsTemperature(unit string) bool {
+	switch Normalize(unit) {
+	case Celsius, Fahrenheit, Kelvin:
+		return true
+	}
+	return false
+}
+
+// Preferred returns the unit a value in unit is published in: the configured
+// temperature unit for temperatures, unit itself otherwise. Temperatures keep
+// their unit in its canonical spelling when none is configured, as Home
+// Assistant only accepts °C, °F and K.
+func Preferred(unit string) string {
+	if !IsTemperature(unit) {
+		return unit
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	if temperature == "" {
+		return Normalize(unit)
+	}
+	return temperature
+}
+
+// Convert converts value from one unit to another of the same quantity
+func Convert(value float64, from, to string) (float64, error) {
+	from, to = Normalize(from), Normalize(to)
+	if from == to {
+		return value, nil
+	}
+
+	if IsTemperature(from) && IsTemperature(to) {
+		return fromKelvin(toKelvin(value, from), to), nil
+	}
+
+	f, fromOK := linearUnits[from]
+	t, toOK := linearUnits[to]
+	if !fromOK || !toOK || f.quantity != t.quantity {
+		return 0, fmt.Errorf("cannot convert %s to %s", from, to)
+	}
+	return value * f.factor / t.factor, nil
+}
+
+func toKelvin(value float64, unit string) float64 {
+	switch unit {
+	case Celsius:
+		return value + 273.15
+	case Fahrenheit:
+		return (value-32)*5/9 + 273.15
+	}
+	return value
+}
+
+func fromKelvin(value float64, unit string) float64 {
+	switch unit {
+	case Celsius:
+		return value - 273.15
+	case Fahrenheit:
+		return (value-273.15)*9/5 + 32
+	}
+	return value
+}
@@ -0,0 +1,300 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// mdnsGroup is the IPv4 multicast DNS group and port
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+const (
+	// mdnsTTL is the lifetime of advertised records, refreshed by every answer
+	mdnsTTL = 120
+	// mdnsCacheFlush marks records this responder owns exclusively
+	mdnsCacheFlush = 1 << 15
+	// mdnsUnicastResponse is the question class bit requesting a direct reply
+	mdnsUnicastResponse = 1 << 15
+)
+
+// MDNSResponder advertises the web UI and API as an _http._tcp service over
+// multicast DNS, so the bridge can be found at <hostname>.local on the LAN
+type MDNSResponder struct {
+	instance string
+	hostname string
+	port     int
+	txt      []string
+	bindIP   net.IP // Only this address is advertised when set
+
+	serviceName  dnsmessage.Name
+	instanceName dnsmessage.Name
+	hostName     dnsmessage.Name
+	servicesName dnsmessage.Name
+
+	conn *net.UDPConn
+	wg   sync.WaitGroup
+}
+
+// NewMDNSResponder creates a responder advertising the given instance name and
+// host name (without .local) for an HTTP server on port
+func NewMDNSResponder(instance, hostname string, port int, txt []string) *MDNSResponder {
+	// Dots would split the instance label, which is limited to 63 bytes
+	instance = strings.ReplaceAll(instance, ".", "-")
+	if len(instance) > 63 {
+		instance = strings.ToValidUTF8(instance[:63], "")
+	}
+	hostname = strings.TrimSuffix(strings.TrimSuffix(hostname, "."), ".local")
+
+	return &MDNSResponder{
+		instance:     instance,
+		hostname:     hostname,
+		port:         port,
+		txt:          txt,
+		serviceName:  dnsmessage.MustNewName("_http._tcp.local."),
+		instanceName: dnsmessage.MustNewName(instance + "._http._tcp.local."),
+		hostName:     dnsmessage.MustNewName(hostname + ".local."),
+		servicesName: dnsmessage.MustNewName("_services._dns-sd._udp.local."),
+	}
+}
+
+// SetBindAddress limits the advertised addresses to the HTTP server's listen
+// address; empty or unspecified advertises every interface address
+func (m *MDNSResponder) SetBindAddress(host string) {
+	if ip := net.ParseIP(host); ip != nil && !ip.IsUnspecified() {
+		m.bindIP = ip.To4()
+	}
+}
+
+// Start joins the multicast group, announces the service and answers queries
+func (m *MDNSResponder) Start() error {
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		return fmt.Errorf("mDNS listen: %w", err)
+	}
+	m.conn = conn
+
+	m.wg.Add(2)
+	go m.serve()
+	go func() {
+		defer m.wg.Done()
+		// Announce twice, as RFC 6762 recommends
+		m.announce(mdnsTTL)
+		time.Sleep(time.Second)
+		m.announce(mdnsTTL)
+	}()
+
+	log.Printf("Advertising %q over mDNS as %s.local:%d", m.instance, m.hostname, m.port)
+	return nil
+}
+
+// Stop withdraws the advertisement and closes the socket
+func (m *MDNSResponder) Stop() {
+	if m.conn == nil {
+		return
+	}
+	m.announce(0)
+	m.conn.Close()
+	m.wg.Wait()
+	log.Println("mDNS responder stopped")
+}
+
+func (m *MDNSResponder) serve() {
+	defer m.wg.Done()
+
+	buf := make([]byte, 9000)
+	for {
+		n, src, err := m.conn.ReadFromUDP(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("mDNS read error: %v", err)
+			}
+			return
+		}
+		m.handleQuery(buf[:n], src)
+	}
+}
+
+func (m *MDNSResponder) handleQuery(packet []byte, src *net.UDPAddr) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(packet)
+	if err != nil || header.Response {
+		return
+	}
+	questions, err := parser.AllQuestions()
+	if err != nil {
+		return
+	}
+
+	var answers, additionals []dnsmessage.Resource
+	var answered []dnsmessage.Question
+	unicast := false
+	for _, q := range questions {
+		a, extra := m.answer(q, mdnsTTL)
+		if len(a) == 0 {
+			continue
+		}
+		answers = append(answers, a...)
+		additionals = append(additionals, extra...)
+		answered = append(answered, q)
+		if uint16(q.Class)&mdnsUnicastResponse != 0 {
+			unicast = true
+		}
+	}
+	if len(answers) == 0 {
+		return
+	}
+
+	response := dnsmessage.Message{
+		Header:      dnsmessage.Header{Response: true, Authoritative: true},
+		Answers:     answers,
+		Additionals: additionals,
+	}
+	dst := mdnsGroup
+	if unicast {
+		dst = src
+	}
+	// Legacy resolvers querying from another port expect a classic DNS reply
+	if src.Port != mdnsGroup.Port {
+		response.Header.ID = header.ID
+		response.Questions = answered
+		dst = src
+	}
+	m.send(&response, dst)
+}
+
+// answer returns the records answering q and the records worth adding to
+// spare the querier follow-up questions
+func (m *MDNSResponder) answer(q dnsmessage.Question, ttl uint32) (answers, additionals []dnsmessage.Resource) {
+	all := q.Type == dnsmessage.TypeALL
+	switch {
+	case sameName(q.Name, m.servicesName) && (all || q.Type == dnsmessage.TypePTR):
+		return []dnsmessage.Resource{m.ptr(m.servicesName, m.serviceName, ttl)}, nil
+
+	case sameName(q.Name, m.serviceName) && (all || q.Type == dnsmessage.TypePTR):
+		additionals = append([]dnsmessage.Resource{m.srv(ttl), m.txtRecord(ttl)}, m.addresses(ttl)...)
+		return []dnsmessage.Resource{m.ptr(m.serviceName, m.instanceName, ttl)}, additionals
+
+	case sameName(q.Name, m.instanceName):
+		switch {
+		case all:
+			return []dnsmessage.Resource{m.srv(ttl), m.txtRecord(ttl)}, m.addresses(ttl)
+		case q.Type == dnsmessage.TypeSRV:
+			return []dnsmessage.Resource{m.srv(ttl)}, append([]dnsmessage.Resource{m.txtRecord(ttl)}, m.addresses(ttl)...)
+		case q.Type == dnsmessage.TypeTXT:
+			return []dnsmessage.Resource{m.txtRecord(ttl)}, nil
+		}
+
+	case sameName(q.Name, m.hostName) && (all || q.Type == dnsmessage.TypeA):
+		return m.addresses(ttl), nil
+	}
+	return nil, nil
+}
+
+// announce multicasts every record unsolicited; a TTL of 0 withdraws them
+func (m *MDNSResponder) announce(ttl uint32) {
+	answers := append([]dnsmessage.Resource{
+		m.ptr(m.serviceName, m.instanceName, ttl),
+		m.srv(ttl),
+		m.txtRecord(ttl),
+	}, m.addresses(ttl)...)
+	m.send(&dnsmessage.Message{
+		Header:  dnsmessage.Header{Response: true, Authoritative: true},
+		Answers: answers,
+	}, mdnsGroup)
+}
+
+func (m *MDNSResponder) send(msg *dnsmessage.Message, dst *net.UDPAddr) {
+	packet, err := msg.Pack()
+	if err != nil {
+		log.Printf("mDNS pack error: %v", err)
+		return
+	}
+	if _, err := m.conn.WriteToUDP(packet, dst); err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Printf("mDNS send to %s failed: %v", dst, err)
+	}
+}
+
+func (m *MDNSResponder) ptr(name, target dnsmessage.Name, ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   &dnsmessage.PTRResource{PTR: target},
+	}
+}
+
+func (m *MDNSResponder) srv(ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: m.instanceName, Class: dnsmessage.ClassINET | mdnsCacheFlush, TTL: ttl},
+		Body:   &dnsmessage.SRVResource{Target: m.hostName, Port: uint16(m.port)},
+	}
+}
+
+func (m *MDNSResponder) txtRecord(ttl uint32) dnsmessage.Resource {
+	txt := m.txt
+	if len(txt) == 0 {
+		txt = []string{""}
+	}
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: m.instanceName, Class: dnsmessage.ClassINET | mdnsCacheFlush, TTL: ttl},
+		Body:   &dnsmessage.TXTResource{TXT: txt},
+	}
+}
+
+// addresses returns an A record for every address the HTTP server is reachable on
+func (m *MDNSResponder) addresses(ttl uint32) []dnsmessage.Resource {
+	var ips []net.IP
+	if m.bindIP != nil {
+		ips = []net.IP{m.bindIP}
+	} else {
+		ips = localIPv4Addresses()
+	}
+
+	records := make([]dnsmessage.Resource, 0, len(ips))
+	for _, ip := range ips {
+		var a [4]byte
+		copy(a[:], ip.To4())
+		records = append(records, dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: m.hostName, Class: dnsmessage.ClassINET | mdnsCacheFlush, TTL: ttl},
+			Body:   &dnsmessage.AResource{A: a},
+		})
+	}
+	return records
+}
+
+// localIPv4Addresses lists the IPv4 addresses of all up, non-loopback interfaces
+func localIPv4Addresses() []net.IP {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	var ips []net.IP
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				if ip := ipNet.IP.To4(); ip != nil {
+					ips = append(ips, ip)
+				}
+			}
+		}
+	}
+	return ips
+}
+
+// sameName compares DNS names case-insensitively
+func sameName(a, b dnsmessage.Name) bool {
+	return strings.EqualFold(a.String(), b.String())
+}
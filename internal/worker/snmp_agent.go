@@ -0,0 +1,534 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"log"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"snmp-mqtt-bridge/internal/buildinfo"
+	"snmp-mqtt-bridge/internal/config"
+	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
+	"snmp-mqtt-bridge/internal/mqtt"
+	"snmp-mqtt-bridge/internal/repository"
+	"snmp-mqtt-bridge/internal/service"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	// maxBulkRepetitions caps GETBULK responses to keep them within a single UDP datagram
+	maxBulkRepetitions = 50
+	// agentRefreshInterval reloads the device list in case a device event was
+	// missed or a load failed
+	agentRefreshInterval = 5 * time.Minute
+)
+
+// SNMPAgent is a read-only SNMP v1/v2c agent exposing bridge health and
+// aggregated device states under a private enterprise OID.
+//
+// Layout below the enterprise OID:
+//
+//	.1.1.0  bridge version (string)
+//	.1.2.0  bridge uptime (timeticks)
+//	.1.3.0  number of devices (integer)
+//	.1.4.0  number of online devices (integer)
+//	.1.5.0  MQTT connected (1 = true, 2 = false)
+//	.2.1.<column>.<device>  device table: 1 index, 2 name, 3 IP address,
+//	                        4 profile, 5 online (1/2), 6 last poll (unix seconds)
+//	.3.1.<column>.<device>.<value>  value table: 1 name, 2 value (string),
+//	                                3 value × 100 (integer, numeric values only)
+//
+// Device indices are derived from the device ID, so a device keeps its row
+// across restarts and when other devices are added or removed. The device
+// list is cached and reloaded on device events, so requests never wait on
+// the database.
+type SNMPAgent struct {
+	cfg        config.SNMPAgentConfig
+	deviceRepo repository.DeviceRepository
+	poller     *service.PollerService
+	mqttClient *mqtt.Client
+	bus        *eventbus.Bus
+	bind       string
+
+	devicesMu sync.RWMutex
+	devices   []agentDevice // Ordered by index
+
+	base    []int
+	started time.Time
+	conn    *net.UDPConn
+	decoder *gosnmp.GoSNMP
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// agentEntry is a single OID/value pair in the agent's MIB view
+type agentEntry struct {
+	oid   []int
+	typ   gosnmp.Asn1BER
+	value interface{}
+}
+
+// agentDevice is a device row of the agent with its table index
+type agentDevice struct {
+	index  int
+	device domain.Device
+}
+
+// NewSNMPAgent creates an SNMP agent listening on bind, an IP address or
+// interface name; empty listens on all interfaces
+func NewSNMPAgent(
+	cfg config.SNMPAgentConfig,
+	bind string,
+	deviceRepo repository.DeviceRepository,
+	poller *service.PollerService,
+	mqttClient *mqtt.Client,
+	bus *eventbus.Bus,
+) *SNMPAgent {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &SNMPAgent{
+		cfg:        cfg,
+		bind:       bind,
+		deviceRepo: deviceRepo,
+		poller:     poller,
+		mqttClient: mqttClient,
+		bus:        bus,
+		decoder:    &gosnmp.GoSNMP{Version: gosnmp.Version2c, MaxOids: gosnmp.MaxOids},
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Start starts listening for SNMP requests
+func (a *SNMPAgent) Start() error {
+	base, err := parseOID(a.cfg.EnterpriseOID)
+	if err != nil {
+		return fmt.Errorf("invalid agent enterprise OID: %w", err)
+	}
+	a.base = base
+	a.started = time.Now()
+
+	host, err := service.ResolveBindAddress(a.bind)
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		host = "0.0.0.0"
+	}
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(a.cfg.Port)))
+	if err != nil {
+		return err
+	}
+	a.conn, err = net.ListenUDP("udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	log.Printf("Starting SNMP agent on %s (enterprise OID %s)", addr, a.cfg.EnterpriseOID)
+
+	// Subscribe before the first load so no change slips in between
+	sub := a.bus.Subscribe(eventbus.TypeDeviceCreated, eventbus.TypeDeviceUpdated, eventbus.TypeDeviceDeleted)
+	a.loadDevices()
+
+	a.wg.Add(2)
+	go a.serve()
+	go a.watchDevices(sub)
+
+	return nil
+}
+
+// Stop stops the agent
+func (a *SNMPAgent) Stop() {
+	a.cancel()
+	if a.conn != nil {
+		a.conn.Close()
+	}
+	a.wg.Wait()
+	log.Println("SNMP agent stopped")
+}
+
+// watchDevices reloads the device list whenever a device changes
+func (a *SNMPAgent) watchDevices(sub *eventbus.Subscription) {
+	defer a.wg.Done()
+	defer a.bus.Unsubscribe(sub)
+
+	ticker := time.NewTicker(agentRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+			a.loadDevices()
+		case _, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			a.loadDevices()
+		}
+	}
+}
+
+// loadDevices refreshes the cached device list. Devices keep the index they
+// had; on failure the previous list stays in place.
+func (a *SNMPAgent) loadDevices() {
+	list, err := a.deviceRepo.GetAll(a.ctx)
+	if err != nil {
+		log.Printf("SNMP agent: failed to load devices: %v", err)
+		return
+	}
+
+	a.devicesMu.Lock()
+	defer a.devicesMu.Unlock()
+
+	previous := make(map[string]int, len(a.devices))
+	for _, d := range a.devices {
+		previous[d.device.ID] = d.index
+	}
+	indices := assignIndices(list, previous)
+
+	devices := make([]agentDevice, 0, len(list))
+	for _, device := range list {
+		devices = append(devices, agentDevice{index: indices[device.ID], device: device})
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].index < devices[j].index })
+	a.devices = devices
+}
+
+// assignIndices gives every device a table index. Devices in previous keep
+// theirs; others get one derived from a hash of their ID, probing upwards on
+// a collision, so the index doesn't depend on the other devices.
+func assignIndices(devices []domain.Device, previous map[string]int) map[string]int {
+	indices := make(map[string]int, len(devices))
+	taken := make(map[int]bool, len(devices))
+
+	ids := make([]string, 0, len(devices))
+	for _, device := range devices {
+		if index, ok := previous[device.ID]; ok {
+			indices[device.ID] = index
+			taken[index] = true
+			continue
+		}
+		ids = append(ids, device.ID)
+	}
+
+	// Sorted so collisions resolve the same way on every start
+	sort.Strings(ids)
+	for _, id := range ids {
+		index := int(crc32.ChecksumIEEE([]byte(id)) & math.MaxInt32)
+		for index == 0 || taken[index] {
+			index = (index + 1) & math.MaxInt32
+		}
+		indices[id] = index
+		taken[index] = true
+	}
+	return indices
+}
+
+func (a *SNMPAgent) serve() {
+	defer a.wg.Done()
+
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := a.conn.ReadFromUDP(buf)
+		if err != nil {
+			if a.ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("SNMP agent read error: %v", err)
+			continue
+		}
+
+		response, err := a.handle(buf[:n])
+		if err != nil {
+			log.Printf("SNMP agent: ignoring request from %s: %v", addr.IP, err)
+			continue
+		}
+		if response == nil {
+			continue
+		}
+
+		if _, err := a.conn.WriteToUDP(response, addr); err != nil {
+			log.Printf("SNMP agent write error: %v", err)
+		}
+	}
+}
+
+// handle decodes a request packet and returns the encoded response, or nil
+// when the request should be silently dropped (wrong community)
+func (a *SNMPAgent) handle(data []byte) ([]byte, error) {
+	request, err := a.decoder.SnmpDecodePacket(data)
+	if err != nil {
+		return nil, err
+	}
+	if request.Version == gosnmp.Version3 {
+		return nil, errors.New("SNMPv3 is not supported")
+	}
+	if request.Community != a.cfg.Community {
+		return nil, nil
+	}
+
+	response := &gosnmp.SnmpPacket{
+		Version:   request.Version,
+		Community: request.Community,
+		PDUType:   gosnmp.GetResponse,
+		RequestID: request.RequestID,
+		Logger:    gosnmp.NewLogger(nil),
+	}
+
+	view := a.snapshot()
+	v1 := request.Version == gosnmp.Version1
+
+	switch request.PDUType {
+	case gosnmp.GetRequest:
+		for i, v := range request.Variables {
+			entry, found := view.get(v.Name)
+			if !found {
+				if v1 {
+					return a.errorResponse(response, request, gosnmp.NoSuchName, i)
+				}
+				response.Variables = append(response.Variables, gosnmp.SnmpPDU{Name: v.Name, Type: gosnmp.NoSuchObject})
+				continue
+			}
+			response.Variables = append(response.Variables, entry.pdu())
+		}
+
+	case gosnmp.GetNextRequest:
+		for i, v := range request.Variables {
+			entry, found := view.next(v.Name)
+			if !found {
+				if v1 {
+					return a.errorResponse(response, request, gosnmp.NoSuchName, i)
+				}
+				response.Variables = append(response.Variables, gosnmp.SnmpPDU{Name: v.Name, Type: gosnmp.EndOfMibView})
+				continue
+			}
+			response.Variables = append(response.Variables, entry.pdu())
+		}
+
+	case gosnmp.GetBulkRequest:
+		nonRepeaters := int(request.NonRepeaters)
+		if nonRepeaters > len(request.Variables) {
+			nonRepeaters = len(request.Variables)
+		}
+		for _, v := range request.Variables[:nonRepeaters] {
+			response.Variables = append(response.Variables, view.nextPDU(v.Name))
+		}
+
+		repetitions := int(request.MaxRepetitions)
+		if repetitions > maxBulkRepetitions {
+			repetitions = maxBulkRepetitions
+		}
+		cursors := make([]string, 0, len(request.Variables)-nonRepeaters)
+		for _, v := range request.Variables[nonRepeaters:] {
+			cursors = append(cursors, v.Name)
+		}
+		for r := 0; r < repetitions && len(cursors) > 0; r++ {
+			for i, name := range cursors {
+				pdu := view.nextPDU(name)
+				response.Variables = append(response.Variables, pdu)
+				cursors[i] = pdu.Name
+			}
+		}
+
+	case gosnmp.SetRequest:
+		status := gosnmp.NotWritable
+		if v1 {
+			status = gosnmp.ReadOnly
+		}
+		return a.errorResponse(response, request, status, 0)
+
+	default:
+		return nil, fmt.Errorf("unsupported PDU type %v", request.PDUType)
+	}
+
+	return response.MarshalMsg()
+}
+
+// errorResponse echoes the request varbinds with an error status
+func (a *SNMPAgent) errorResponse(response, request *gosnmp.SnmpPacket, status gosnmp.SNMPError, index int) ([]byte, error) {
+	response.Error = status
+	response.ErrorIndex = uint8(index + 1)
+	response.Variables = make([]gosnmp.SnmpPDU, 0, len(request.Variables))
+	for _, v := range request.Variables {
+		response.Variables = append(response.Variables, gosnmp.SnmpPDU{Name: v.Name, Type: gosnmp.Null})
+	}
+	return response.MarshalMsg()
+}
+
+// agentView is a sorted snapshot of everything the agent exposes
+type agentView []agentEntry
+
+// snapshot builds the MIB view from current bridge and device state
+func (a *SNMPAgent) snapshot() agentView {
+	var view agentView
+	add := func(typ gosnmp.Asn1BER, value interface{}, suffix ...int) {
+		oid := make([]int, 0, len(a.base)+len(suffix))
+		oid = append(oid, a.base...)
+		oid = append(oid, suffix...)
+		view = append(view, agentEntry{oid: oid, typ: typ, value: value})
+	}
+
+	a.devicesMu.RLock()
+	devices := a.devices
+	a.devicesMu.RUnlock()
+	states := a.poller.GetAllDeviceStates()
+
+	online := 0
+	for _, state := range states {
+		if state.Online {
+			online++
+		}
+	}
+
+	// Bridge scalars
+	add(gosnmp.OctetString, buildinfo.Get().Version, 1, 1, 0)
+	add(gosnmp.TimeTicks, uint32(time.Since(a.started)/(10*time.Millisecond)), 1, 2, 0)
+	add(gosnmp.Integer, len(devices), 1, 3, 0)
+	add(gosnmp.Integer, online, 1, 4, 0)
+	add(gosnmp.Integer, truthValue(a.mqttClient != nil && a.mqttClient.IsConnected()), 1, 5, 0)
+
+	for i := range devices {
+		device := &devices[i].device
+		idx := devices[i].index
+		state := states[device.ID]
+
+		add(gosnmp.Integer, idx, 2, 1, 1, idx)
+		add(gosnmp.OctetString, device.Name, 2, 1, 2, idx)
+		add(gosnmp.OctetString, device.IPAddress, 2, 1, 3, idx)
+		add(gosnmp.OctetString, device.ProfileID, 2, 1, 4, idx)
+		add(gosnmp.Integer, truthValue(state != nil && state.Online), 2, 1, 5, idx)
+		lastPoll := 0
+		if state != nil && !state.LastPoll.IsZero() {
+			lastPoll = int(state.LastPoll.Unix())
+		}
+		add(gosnmp.Integer, lastPoll, 2, 1, 6, idx)
+
+		if state == nil {
+			continue
+		}
+
+		names := make([]string, 0, len(state.Values))
+		for name := range state.Values {
+			// Skip raw OID-keyed values, only named entities are exposed
+			if strings.HasPrefix(name, ".") || (name != "" && name[0] >= '0' && name[0] <= '9') {
+				continue
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for v, name := range names {
+			value := state.Values[name]
+			add(gosnmp.OctetString, name, 3, 1, 1, idx, v+1)
+			add(gosnmp.OctetString, fmt.Sprintf("%v", value), 3, 1, 2, idx, v+1)
+			if numeric, ok := numericValue(value); ok {
+				scaled := math.Max(math.MinInt32, math.Min(math.MaxInt32, math.Round(numeric*100)))
+				add(gosnmp.Integer, int(scaled), 3, 1, 3, idx, v+1)
+			}
+		}
+	}
+
+	sort.Slice(view, func(i, j int) bool { return compareOID(view[i].oid, view[j].oid) < 0 })
+	return view
+}
+
+func (v agentView) get(name string) (agentEntry, bool) {
+	oid, err := parseOID(name)
+	if err != nil {
+		return agentEntry{}, false
+	}
+	i := sort.Search(len(v), func(i int) bool { return compareOID(v[i].oid, oid) >= 0 })
+	if i < len(v) && compareOID(v[i].oid, oid) == 0 {
+		return v[i], true
+	}
+	return agentEntry{}, false
+}
+
+func (v agentView) next(name string) (agentEntry, bool) {
+	oid, err := parseOID(name)
+	if err != nil {
+		return agentEntry{}, false
+	}
+	i := sort.Search(len(v), func(i int) bool { return compareOID(v[i].oid, oid) > 0 })
+	if i < len(v) {
+		return v[i], true
+	}
+	return agentEntry{}, false
+}
+
+// nextPDU returns the next varbind, or endOfMibView at the end of the view
+func (v agentView) nextPDU(name string) gosnmp.SnmpPDU {
+	if entry, found := v.next(name); found {
+		return entry.pdu()
+	}
+	return gosnmp.SnmpPDU{Name: name, Type: gosnmp.EndOfMibView}
+}
+
+func (e agentEntry) pdu() gosnmp.SnmpPDU {
+	parts := make([]string, len(e.oid))
+	for i, n := range e.oid {
+		parts[i] = strconv.Itoa(n)
+	}
+	return gosnmp.SnmpPDU{Name: "." + strings.Join(parts, "."), Type: e.typ, Value: e.value}
+}
+
+func parseOID(oid string) ([]int, error) {
+	oid = strings.TrimPrefix(strings.TrimSpace(oid), ".")
+	if oid == "" {
+		return []int{}, nil
+	}
+	parts := strings.Split(oid, ".")
+	result := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid OID %q", oid)
+		}
+		result[i] = n
+	}
+	return result, nil
+}
+
+func compareOID(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+// truthValue encodes a bool as SNMPv2-TC TruthValue (1 = true, 2 = false)
+func truthValue(b bool) int {
+	if b {
+		return 1
+	}
+	return 2
+}
+
+func numericValue(v interface{}) (float64, bool) {
+	if b, ok := v.(bool); ok {
+		if b {
+			return 1, true
+		}
+		return 0, true
+	}
+	return domain.Number(v)
+}
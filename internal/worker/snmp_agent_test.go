@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"testing"
+
+	"snmp-mqtt-bridge/internal/domain"
+)
+
+func TestAgentDeviceIndicesAreStable(t *testing.T) {
+	devices := []domain.Device{{ID: "ups-1"}, {ID: "pdu-1"}, {ID: "ups-2"}}
+	first := assignIndices(devices, nil)
+
+	// A fresh start without ups-1 gives the others the same indices
+	restarted := assignIndices(devices[1:], nil)
+	for _, id := range []string{"pdu-1", "ups-2"} {
+		if restarted[id] != first[id] {
+			t.Errorf("%s moved from index %d to %d after a restart", id, first[id], restarted[id])
+		}
+	}
+
+	// A running agent keeps the indices it handed out
+	added := assignIndices(append(devices, domain.Device{ID: "ats-1"}), first)
+	for id, index := range first {
+		if added[id] != index {
+			t.Errorf("%s moved from index %d to %d when a device was added", id, index, added[id])
+		}
+	}
+
+	seen := make(map[int]string)
+	for id, index := range added {
+		if index <= 0 {
+			t.Errorf("%s has index %d, want a positive one", id, index)
+		}
+		if other, ok := seen[index]; ok {
+			t.Errorf("%s and %s share index %d", id, other, index)
+		}
+		seen[index] = id
+	}
+}
+
+func TestAgentDeviceIndexCollisionsProbe(t *testing.T) {
+	// "a" already holds the index b's hash points at
+	hashed := assignIndices([]domain.Device{{ID: "b"}}, nil)["b"]
+	previous := map[string]int{"a": hashed}
+	devices := []domain.Device{{ID: "a"}, {ID: "b"}}
+
+	indices := assignIndices(devices, previous)
+	if indices["a"] == indices["b"] {
+		t.Fatalf("a and b share index %d", indices["a"])
+	}
+	if indices["b"] != indices["a"]+1 {
+		t.Errorf("b got index %d, want the next free one %d", indices["b"], indices["a"]+1)
+	}
+}
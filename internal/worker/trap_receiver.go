@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"snmp-mqtt-bridge/internal/domain"
+	"snmp-mqtt-bridge/internal/eventbus"
 	"snmp-mqtt-bridge/internal/repository"
 	"snmp-mqtt-bridge/internal/service"
 
@@ -19,9 +22,11 @@ import (
 // TrapReceiver listens for SNMP traps
 type TrapReceiver struct {
 	port       int
+	bind       string
 	deviceRepo repository.DeviceRepository
 	trapRepo   repository.TrapLogRepository
 	poller     *service.PollerService
+	bus        *eventbus.Bus
 
 	listener *gosnmp.TrapListener
 	ctx      context.Context
@@ -32,20 +37,25 @@ type TrapReceiver struct {
 	onTrap func(*domain.TrapLog)
 }
 
-// NewTrapReceiver creates a new trap receiver
+// NewTrapReceiver creates a trap receiver listening on bind, an IP address or
+// interface name; empty listens on all interfaces
 func NewTrapReceiver(
 	port int,
+	bind string,
 	deviceRepo repository.DeviceRepository,
 	trapRepo repository.TrapLogRepository,
 	poller *service.PollerService,
+	bus *eventbus.Bus,
 ) *TrapReceiver {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &TrapReceiver{
 		port:       port,
+		bind:       bind,
 		deviceRepo: deviceRepo,
 		trapRepo:   trapRepo,
 		poller:     poller,
+		bus:        bus,
 		ctx:        ctx,
 		cancel:     cancel,
 	}
@@ -58,12 +68,19 @@ func (r *TrapReceiver) OnTrap(handler func(*domain.TrapLog)) {
 
 // Start starts the trap receiver
 func (r *TrapReceiver) Start() error {
+	host, err := service.ResolveBindAddress(r.bind)
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		host = "0.0.0.0"
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(r.port))
+
 	r.listener = gosnmp.NewTrapListener()
 	r.listener.OnNewTrap = r.handleTrap
 	r.listener.Params = gosnmp.Default
 
-	addr := fmt.Sprintf("0.0.0.0:%d", r.port)
-
 	r.wg.Add(1)
 	go func() {
 		defer r.wg.Done()
@@ -95,7 +112,7 @@ func (r *TrapReceiver) handleTrap(packet *gosnmp.SnmpPacket, addr *net.UDPAddr)
 
 	// Find device by IP
 	var deviceID *string
-	devices, err := r.deviceRepo.GetAll(context.Background())
+	devices, err := r.deviceRepo.GetAll(r.ctx)
 	if err == nil {
 		for _, d := range devices {
 			if d.IPAddress == addr.IP.String() {
@@ -124,23 +141,56 @@ func (r *TrapReceiver) handleTrap(packet *gosnmp.SnmpPacket, addr *net.UDPAddr)
 		}
 	}
 
+	r.processTrap(addr.IP.String(), deviceID, trapOID, variables)
+}
+
+// InjectTrap runs a synthesized trap through the same pipeline as received
+// ones: severity mapping, storage, MQTT publishing and notifications. When
+// deviceID is set the trap appears to come from that device.
+func (r *TrapReceiver) InjectTrap(ctx context.Context, deviceID, trapOID string, variables domain.TrapVariables) (*domain.TrapLog, error) {
+	sourceIP := "127.0.0.1"
+	var trapDeviceID *string
+	if deviceID != "" {
+		device, err := r.deviceRepo.GetByID(ctx, deviceID)
+		if err != nil {
+			return nil, fmt.Errorf("device %s: %w", deviceID, err)
+		}
+		sourceIP = device.IPAddress
+		trapDeviceID = &device.ID
+	}
+	if variables == nil {
+		variables = make(domain.TrapVariables)
+	}
+
+	log.Printf("Injecting test trap %s from %s", trapOID, sourceIP)
+	return r.processTrap(sourceIP, trapDeviceID, trapOID, variables), nil
+}
+
+func (r *TrapReceiver) processTrap(sourceIP string, deviceID *string, trapOID string, variables domain.TrapVariables) *domain.TrapLog {
 	// Determine severity based on trap OID
 	severity := r.determineSeverity(trapOID, variables)
 
+	// Resolve variables the device's profile knows, e.g. enum names
+	var resolved domain.ResolvedTrapVariables
+	if deviceID != nil && r.poller != nil {
+		resolved = r.poller.ResolveTrapVariables(*deviceID, variables)
+	}
+
 	// Create trap log
 	trapLog := &domain.TrapLog{
 		ID:         uuid.New().String(),
 		DeviceID:   deviceID,
-		SourceIP:   addr.IP.String(),
+		SourceIP:   sourceIP,
 		TrapOID:    trapOID,
 		Variables:  variables,
+		Resolved:   resolved,
 		Severity:   severity,
-		Message:    r.formatMessage(trapOID, variables),
+		Message:    r.formatMessage(trapOID, variables, resolved),
 		ReceivedAt: time.Now(),
 	}
 
 	// Save to database
-	if err := r.trapRepo.Create(context.Background(), trapLog); err != nil {
+	if err := r.trapRepo.Create(r.ctx, trapLog); err != nil {
 		log.Printf("Failed to save trap: %v", err)
 	}
 
@@ -148,13 +198,22 @@ func (r *TrapReceiver) handleTrap(packet *gosnmp.SnmpPacket, addr *net.UDPAddr)
 	if deviceID != nil && r.poller != nil {
 		// The poller will update state on next poll cycle
 		// For immediate update, we could add a TriggerPoll method to PollerService
+		r.poller.RecordTrap(*deviceID, trapLog.ReceivedAt)
 		log.Printf("Trap received for device %s, will be reflected in next poll", *deviceID)
 	}
 
 	// Notify handlers
+	eventDeviceID := ""
+	if deviceID != nil {
+		eventDeviceID = *deviceID
+	}
+	r.bus.Publish(eventbus.Event{Type: eventbus.TypeTrapReceived, DeviceID: eventDeviceID, Payload: trapLog})
+
 	if r.onTrap != nil {
 		r.onTrap(trapLog)
 	}
+
+	return trapLog
 }
 
 func (r *TrapReceiver) parseVariable(variable gosnmp.SnmpPDU) interface{} {
@@ -165,6 +224,8 @@ func (r *TrapReceiver) parseVariable(variable gosnmp.SnmpPDU) interface{} {
 		return variable.Value
 	case gosnmp.ObjectIdentifier:
 		return variable.Value.(string)
+	case gosnmp.Opaque, gosnmp.OpaqueFloat, gosnmp.OpaqueDouble:
+		return service.DecodeOpaque(variable)
 	case gosnmp.IPAddress:
 		if ip, ok := variable.Value.(string); ok {
 			return ip
@@ -207,7 +268,7 @@ func (r *TrapReceiver) determineSeverity(trapOID string, variables domain.TrapVa
 	return domain.SeverityInfo
 }
 
-func (r *TrapReceiver) formatMessage(trapOID string, variables domain.TrapVariables) string {
+func (r *TrapReceiver) formatMessage(trapOID string, variables domain.TrapVariables, resolved domain.ResolvedTrapVariables) string {
 	// Basic message formatting
 	// Real implementation would use templates from profile
 
@@ -221,6 +282,16 @@ func (r *TrapReceiver) formatMessage(trapOID string, variables domain.TrapVariab
 	case ".1.3.6.1.4.1.318.2.3.4":
 		return "Communication lost with UPS"
 	default:
+		if len(resolved) > 0 {
+			parts := make([]string, len(resolved))
+			for i, v := range resolved {
+				parts[i] = fmt.Sprintf("%s: %v", v.Name, v.Value)
+				if v.Unit != "" {
+					parts[i] += " " + v.Unit
+				}
+			}
+			return fmt.Sprintf("Trap %s (%s)", trapOID, strings.Join(parts, ", "))
+		}
 		if len(variables) > 0 {
 			return fmt.Sprintf("Trap %s with %d variables", trapOID, len(variables))
 		}